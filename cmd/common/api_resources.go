@@ -2,7 +2,6 @@ package common
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"log"
 	"os"
@@ -10,14 +9,11 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/cloudforet-io/cfctl/internal/grpcconn"
 	"github.com/cloudforet-io/cfctl/pkg/configs"
 	"github.com/cloudforet-io/cfctl/pkg/format"
 	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"gopkg.in/yaml.v3"
@@ -66,11 +62,11 @@ func ListAPIResources(serviceName string) error {
 }
 
 func loadShortNames() (map[string]string, error) {
-	home, err := os.UserHomeDir()
+	settingDir, err := configs.SettingDir()
 	if err != nil {
 		return nil, fmt.Errorf("unable to find home directory: %v", err)
 	}
-	shortNamesFile := filepath.Join(home, ".cfctl", "short_names.yaml")
+	shortNamesFile := filepath.Join(settingDir, "short_names.yaml")
 	shortNamesMap := make(map[string]string)
 	if _, err := os.Stat(shortNamesFile); err == nil {
 		file, err := os.Open(shortNamesFile)
@@ -88,31 +84,14 @@ func loadShortNames() (map[string]string, error) {
 }
 
 func FetchServiceResources(serviceName, endpoint string, shortNamesMap map[string]string, config *configs.Environments) ([][]string, error) {
-	parts := strings.Split(endpoint, "://")
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("invalid endpoint format: %s", endpoint)
+	if !strings.HasPrefix(endpoint, "grpc://") && !strings.HasPrefix(endpoint, "grpc+ssl://") {
+		return nil, fmt.Errorf("unsupported endpoint: %s", endpoint)
 	}
-	scheme := parts[0]
-	hostPort := parts[1]
 
-	var opts []grpc.DialOption
-	if scheme == "grpc+ssl" {
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: false,
-		}
-		creds := credentials.NewTLS(tlsConfig)
-		opts = append(opts, grpc.WithTransportCredentials(creds))
-	} else if scheme == "grpc" {
-		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	} else {
-		return nil, fmt.Errorf("unsupported scheme: %s", scheme)
-	}
-
-	conn, err := grpc.Dial(hostPort, opts...)
+	conn, err := grpcconn.GetEndpoint(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("connection failed: unable to connect to %s: %v", endpoint, err)
 	}
-	defer conn.Close()
 
 	ctx := metadata.AppendToOutgoingContext(context.Background(), "token", config.Environments[config.Environment].Token)
 
@@ -125,18 +104,8 @@ func FetchServiceResources(serviceName, endpoint string, shortNamesMap map[strin
 	}
 
 	// Load short names from setting.yaml
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %v", err)
-	}
-
-	settingPath := filepath.Join(home, ".cfctl", "setting.yaml")
-	v := viper.New()
-	v.SetConfigFile(settingPath)
-	v.SetConfigType("yaml")
-
 	serviceShortNames := make(map[string]string)
-	if err := v.ReadInConfig(); err == nil {
+	if v, err := configs.Setting(); err == nil {
 		// Get short names for this service
 		shortNamesSection := v.GetStringMap(fmt.Sprintf("short_names.%s", serviceName))
 		for shortName, cmd := range shortNamesSection {
@@ -11,10 +11,10 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
 	"github.com/cloudforet-io/cfctl/pkg/configs"
 	"github.com/cloudforet-io/cfctl/pkg/format"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
 
 	"github.com/pterm/pterm"
@@ -22,14 +22,12 @@ import (
 
 var endpoints string
 
-func loadEndpointsFromCache(currentEnv string) (map[string]string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("unable to find home directory: %v", err)
-	}
+// showFullName shows the fully qualified gRPC service names instead of the resource names
+var showFullName bool
 
+func loadEndpointsFromCache(currentEnv string) (map[string]string, error) {
 	// Read from environment-specific cache file
-	cacheFile := filepath.Join(home, ".cfctl", "cache", currentEnv, "endpoints.yaml")
+	cacheFile := filepath.Join(configs.CacheDir(currentEnv), "endpoints.yaml")
 	data, err := os.ReadFile(cacheFile)
 	if err != nil {
 		return nil, err
@@ -44,8 +42,11 @@ func loadEndpointsFromCache(currentEnv string) (map[string]string, error) {
 }
 
 var ApiResourcesCmd = &cobra.Command{
-	Use:   "api_resources",
-	Short: "Displays supported API resources",
+	Use:     "api_resources",
+	Aliases: []string{"api-resources"},
+	Short:   "Displays supported API resources",
+	Long: `Connects to the services of the current environment, walks their gRPC reflection data
+and prints every resource with the verbs that can be called on it.`,
 	Example: `  # List all API resources for all services
   $ cfctl api_resources
 
@@ -53,20 +54,13 @@ var ApiResourcesCmd = &cobra.Command{
   $ cfctl api_resources -s identity
 
   # List API resources for multiple services
-  $ cfctl api_resources -s identity,inventory,repository`,
-	Run: func(cmd *cobra.Command, args []string) {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			log.Fatalf("Unable to find home directory: %v", err)
-		}
-
-		settingPath := filepath.Join(home, ".cfctl", "setting.yaml")
+  $ cfctl api_resources -s identity,inventory,repository
 
+  # Show fully qualified service names (e.g. spaceone.api.inventory.v1.CloudService)
+  $ cfctl api-resources -s inventory --full-name`,
+	Run: func(cmd *cobra.Command, args []string) {
 		// Read main setting file
-		mainV := viper.New()
-		mainV.SetConfigFile(settingPath)
-		mainV.SetConfigType("yaml")
-		mainConfigErr := mainV.ReadInConfig()
+		mainV, mainConfigErr := configs.Setting()
 
 		var currentEnv string
 		var envConfig map[string]interface{}
@@ -93,23 +87,26 @@ var ApiResourcesCmd = &cobra.Command{
 
 			endpointsMap, err = configs.FetchEndpointsMap(endpointName)
 			if err != nil {
-				log.Fatalf("Failed to fetch endpointsMap from '%s': %v", endpointName, err)
+				pterm.Error.Printf("Failed to fetch endpointsMap from '%s': %v\n", endpointName, err)
+				cleanup.Exit(1)
 			}
 		}
 
 		// Load short names configuration
-		shortNamesFile := filepath.Join(home, ".cfctl", "short_names.yaml")
+		shortNamesFile := filepath.Join(GetSettingDir(), "short_names.yaml")
 		shortNamesMap := make(map[string]string)
 		if _, err := os.Stat(shortNamesFile); err == nil {
 			file, err := os.Open(shortNamesFile)
 			if err != nil {
-				log.Fatalf("Failed to open short_names.yaml file: %v", err)
+				pterm.Error.Printf("Failed to open short_names.yaml file: %v\n", err)
+				cleanup.Exit(1)
 			}
 			defer file.Close()
 
 			err = yaml.NewDecoder(file).Decode(&shortNamesMap)
 			if err != nil {
-				log.Fatalf("Failed to decode short_names.yaml: %v", err)
+				pterm.Error.Printf("Failed to decode short_names.yaml: %v\n", err)
+				cleanup.Exit(1)
 			}
 		}
 
@@ -119,7 +116,7 @@ var ApiResourcesCmd = &cobra.Command{
 			for i := range selectedEndpoints {
 				selectedEndpoints[i] = strings.TrimSpace(selectedEndpoints[i])
 			}
-			var allData [][]string
+			var allData []format.ServiceResource
 
 			for _, endpointName := range selectedEndpoints {
 				serviceEndpoint, ok := endpointsMap[endpointName]
@@ -138,7 +135,7 @@ var ApiResourcesCmd = &cobra.Command{
 			}
 
 			sort.Slice(allData, func(i, j int) bool {
-				return allData[i][0] < allData[j][0]
+				return allData[i].Service < allData[j].Service
 			})
 
 			renderTable(allData)
@@ -147,7 +144,7 @@ var ApiResourcesCmd = &cobra.Command{
 
 		// If no specific endpoints are provided, list all services
 		var wg sync.WaitGroup
-		dataChan := make(chan [][]string, len(endpointsMap))
+		dataChan := make(chan []format.ServiceResource, len(endpointsMap))
 		errorChan := make(chan error, len(endpointsMap))
 
 		for service, endpoint := range endpointsMap {
@@ -173,20 +170,20 @@ var ApiResourcesCmd = &cobra.Command{
 			}
 		}
 
-		var allData [][]string
+		var allData []format.ServiceResource
 		for data := range dataChan {
 			allData = append(allData, data...)
 		}
 
 		sort.Slice(allData, func(i, j int) bool {
-			return allData[i][0] < allData[j][0]
+			return allData[i].Service < allData[j].Service
 		})
 
 		renderTable(allData)
 	},
 }
 
-func renderTable(data [][]string) {
+func renderTable(data []format.ServiceResource) {
 	// Calculate the dynamic width for the "Verb" column
 	terminalWidth := pterm.GetTerminalWidth()
 	usedWidth := 30 + 20 + 15 // Estimated widths for Service, Resource, and Alias columns
@@ -204,10 +201,14 @@ func renderTable(data [][]string) {
 	currentColorIndex := 0
 	previousService := ""
 
-	table := pterm.TableData{{"Service", "Verb", "Resource", "Alias"}}
+	resourceHeader := "Resource"
+	if showFullName {
+		resourceHeader = "Full Name"
+	}
+	table := pterm.TableData{{"Service", "Verb", resourceHeader, "Alias"}}
 
-	for _, row := range data {
-		service := row[0]
+	for _, resource := range data {
+		service := resource.Service
 
 		if service != previousService {
 			currentColorIndex = (currentColorIndex + 1) % len(alternateColors)
@@ -217,12 +218,15 @@ func renderTable(data [][]string) {
 		color := alternateColors[currentColorIndex]
 		coloredStyle := pterm.NewStyle(color)
 
-		serviceColored := coloredStyle.Sprint(row[0])
-		resourceColored := coloredStyle.Sprint(row[2])
-		aliasColored := coloredStyle.Sprint(row[3])
+		serviceColored := coloredStyle.Sprint(resource.Service)
+		resourceColored := coloredStyle.Sprint(resource.Resource)
+		if showFullName {
+			resourceColored = coloredStyle.Sprint(resource.FullName)
+		}
+		aliasColored := coloredStyle.Sprint(resource.Alias)
 
 		// Split verbs into multiple lines if needed
-		verbs := splitIntoLinesWithComma(row[1], verbColumnWidth)
+		verbs := splitIntoLinesWithComma(strings.Join(resource.Verbs, ", "), verbColumnWidth)
 		for i, line := range verbs {
 			if i == 0 {
 				table = append(table, []string{
@@ -270,4 +274,5 @@ func splitIntoLinesWithComma(text string, maxWidth int) []string {
 
 func init() {
 	ApiResourcesCmd.Flags().StringVarP(&endpoints, "service", "s", "", "Specify the services to connect to, separated by commas (e.g., 'identity', 'identity,inventory')")
+	ApiResourcesCmd.Flags().BoolVar(&showFullName, "full-name", false, "Show fully qualified gRPC service names instead of resource names")
 }
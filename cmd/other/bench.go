@@ -0,0 +1,184 @@
+package other
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// benchPercentiles are the latency percentiles reported by bench
+var benchPercentiles = []float64{50, 90, 95, 99}
+
+// BenchCmd load tests an endpoint with a read-only method
+var BenchCmd = &cobra.Command{
+	Use:   "bench <service> <resource> <verb>",
+	Short: "Load test the current endpoint with a read-only method",
+	Long: `Call a read-only method (list, get, stat, analyze or search) many times concurrently and
+report the throughput, latency percentiles and error rate, e.g. to check the capacity of a
+gateway before a rollout. Parameters are given like for 'cfctl exec', and the same request is
+sent by every call. Ctrl+C stops early and reports the calls made so far.`,
+	Example: `  $ cfctl bench identity Project list -n 500 -c 20
+  $ cfctl bench inventory CloudService list -p provider=aws --duration 1m -c 50
+  $ cfctl bench identity Workspace get -p workspace_id=workspace-1234567890ab -o json`,
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		parameters, _ := cmd.Flags().GetStringArray("parameter")
+		jsonParameter, _ := cmd.Flags().GetString("json-parameter")
+		fileParameter, _ := cmd.Flags().GetString("file-parameter")
+		refresh, _ := cmd.Flags().GetBool("refresh")
+		requests, _ := cmd.Flags().GetInt("requests")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		duration, _ := cmd.Flags().GetDuration("duration")
+		outputFormat, _ := cmd.Flags().GetString("output")
+
+		if outputFormat != "table" && outputFormat != "json" {
+			pterm.Error.Printf("Unsupported output format '%s', use table or json.\n", outputFormat)
+			cleanup.Exit(1)
+		}
+		if concurrency < 1 {
+			pterm.Error.Println("--concurrency must be at least 1.")
+			cleanup.Exit(1)
+		}
+
+		serviceName, resourceName, verb := args[0], args[1], args[2]
+		if !transport.IsBenchVerb(verb) {
+			pterm.Error.Printf("Only read-only methods can be benchmarked, '%s' could change resources.\n", verb)
+			cleanup.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		defer cleanup.Intercept()()
+
+		target := fmt.Sprintf("%d calls", requests)
+		if duration > 0 {
+			target = duration.String()
+		}
+		spinner, _ := pterm.DefaultSpinner.WithWriter(os.Stderr).Start(fmt.Sprintf("Benchmarking %s.%s.%s for %s with %d workers", serviceName, resourceName, verb, target, concurrency))
+		result, err := transport.Bench(ctx, serviceName, resourceName, verb, &transport.FetchOptions{
+			Parameters:    parameters,
+			JSONParameter: jsonParameter,
+			FileParameter: fileParameter,
+			Refresh:       refresh,
+		}, transport.BenchOptions{
+			Requests:    requests,
+			Concurrency: concurrency,
+			Duration:    duration,
+			Progress: func(done int) {
+				if done%100 == 0 {
+					spinner.UpdateText(fmt.Sprintf("Benchmarking %s.%s.%s, %d calls done", serviceName, resourceName, verb, done))
+				}
+			},
+		})
+		if err != nil {
+			spinner.Fail(err.Error())
+			cleanup.Exit(1)
+		}
+		spinner.Success(fmt.Sprintf("%d calls in %s", result.Requests, result.Elapsed.Round(time.Millisecond)))
+
+		if outputFormat == "json" {
+			printBenchJSON(result)
+		} else {
+			printBenchTable(result)
+		}
+		if result.Requests > 0 && result.ErrorCount() == result.Requests {
+			cleanup.Exit(1)
+		}
+	},
+}
+
+func printBenchTable(result *transport.BenchResult) {
+	tableData := pterm.TableData{
+		{"Method", result.Method},
+		{"Concurrency", fmt.Sprint(result.Concurrency)},
+		{"Requests", fmt.Sprint(result.Requests)},
+		{"Duration", result.Elapsed.Round(time.Millisecond).String()},
+		{"Throughput", fmt.Sprintf("%.1f req/s", result.Throughput())},
+		{"Errors", fmt.Sprintf("%d (%.2f%%)", result.ErrorCount(), result.ErrorRate()*100)},
+	}
+	if result.Requests > 0 {
+		tableData = append(tableData,
+			[]string{"Latency min", formatLatency(result.Latencies[0])},
+			[]string{"Latency mean", formatLatency(result.Mean())},
+		)
+		for _, p := range benchPercentiles {
+			tableData = append(tableData, []string{fmt.Sprintf("Latency p%g", p), formatLatency(result.Percentile(p))})
+		}
+		tableData = append(tableData, []string{"Latency max", formatLatency(result.Latencies[len(result.Latencies)-1])})
+	}
+	pterm.DefaultTable.WithData(tableData).Render()
+
+	if len(result.Errors) > 0 {
+		codes := make([]string, 0, len(result.Errors))
+		for code := range result.Errors {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		errorData := pterm.TableData{{"Status", "Count"}}
+		for _, code := range codes {
+			errorData = append(errorData, []string{code, fmt.Sprint(result.Errors[code])})
+		}
+		fmt.Println()
+		pterm.DefaultTable.WithHasHeader().WithData(errorData).Render()
+	}
+}
+
+func printBenchJSON(result *transport.BenchResult) {
+	latency := map[string]float64{}
+	if result.Requests > 0 {
+		latency["min_ms"] = milliseconds(result.Latencies[0])
+		latency["mean_ms"] = milliseconds(result.Mean())
+		for _, p := range benchPercentiles {
+			latency[fmt.Sprintf("p%g_ms", p)] = milliseconds(result.Percentile(p))
+		}
+		latency["max_ms"] = milliseconds(result.Latencies[len(result.Latencies)-1])
+	}
+
+	data, err := json.MarshalIndent(struct {
+		*transport.BenchResult
+		DurationSeconds float64            `json:"duration_seconds"`
+		Throughput      float64            `json:"throughput"`
+		ErrorRate       float64            `json:"error_rate"`
+		Latency         map[string]float64 `json:"latency"`
+	}{
+		BenchResult:     result,
+		DurationSeconds: result.Elapsed.Seconds(),
+		Throughput:      result.Throughput(),
+		ErrorRate:       result.ErrorRate(),
+		Latency:         latency,
+	}, "", "  ")
+	if err != nil {
+		pterm.Error.Println(err)
+		return
+	}
+	fmt.Println(string(data))
+}
+
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.2f ms", milliseconds(d))
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func init() {
+	BenchCmd.Flags().IntP("requests", "n", 100, "Number of calls, unless --duration is given")
+	BenchCmd.Flags().IntP("concurrency", "c", 10, "Number of calls in flight at once")
+	BenchCmd.Flags().Duration("duration", 0, "Keep calling for this long instead of a number of calls, e.g. 30s")
+	BenchCmd.Flags().StringArrayP("parameter", "p", []string{}, "Input Parameter (-p <key>=<value> -p ..., nested fields: -p query.filter[0].k=name)")
+	BenchCmd.Flags().StringP("json-parameter", "j", "", "JSON type parameter, '-' reads it from stdin")
+	BenchCmd.Flags().StringP("file-parameter", "f", "", "YAML or JSON file with the request body, '-' reads it from stdin")
+	BenchCmd.Flags().Bool("refresh", false, "Resolve the service descriptors again instead of using the cached ones")
+	BenchCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+	BenchCmd.RegisterFlagCompletionFunc("output", completeFrom([]string{"table", "json"}))
+}
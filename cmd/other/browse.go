@@ -0,0 +1,145 @@
+//go:build !notui
+
+package other
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudforet-io/cfctl/internal/ui"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/output"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+)
+
+// browsePageSize is how many results the browser shows per page
+const browsePageSize = 15
+
+// browseActions are the operations offered for the item picked in the browser
+var browseActions = []string{"get", "describe", "delete", "back to list"}
+
+// BrowseResults lets the user pick an item of a list response and run get, describe or
+// delete on it, returning to the list afterwards until the browser is quit.
+func BrowseResults(serviceName, resourceName string, data map[string]interface{}) {
+	idField := transport.ResourceIDField(resourceName)
+
+	var items []map[string]interface{}
+	var labels []string
+	if results, ok := data["results"].([]interface{}); ok {
+		for _, result := range results {
+			item, ok := result.(map[string]interface{})
+			if !ok || stringValue(item[idField]) == "" {
+				continue
+			}
+			items = append(items, item)
+			labels = append(labels, browseLabel(item, idField))
+		}
+	}
+	if len(items) == 0 {
+		pterm.Info.Printf("No %s with %s to browse.\n", resourceName, idField)
+		return
+	}
+
+	selected := 0
+	for {
+		index, ok := browseSelect(fmt.Sprintf("%s %s (%d)", serviceName, resourceName, len(items)), labels, selected)
+		if !ok {
+			return
+		}
+		selected = index
+		id := stringValue(items[index][idField])
+
+		action, ok := browseSelect(fmt.Sprintf("%s %s", resourceName, id), browseActions, 0)
+		if !ok || browseActions[action] == "back to list" {
+			continue
+		}
+
+		fmt.Print("\033[H\033[2J")
+		deleted := runBrowseAction(serviceName, resourceName, idField, id, browseActions[action])
+		if deleted {
+			items = append(items[:index], items[index+1:]...)
+			labels = append(labels[:index], labels[index+1:]...)
+			if len(items) == 0 {
+				return
+			}
+			if selected >= len(items) {
+				selected = len(items) - 1
+			}
+		}
+
+		if err := ui.Pause("Press any key to return to the list"); err != nil {
+			return
+		}
+	}
+}
+
+// runBrowseAction runs the action on the item and reports whether it was deleted
+func runBrowseAction(serviceName, resourceName, idField, id, action string) bool {
+	parameters := []string{fmt.Sprintf("%s=%s", idField, id)}
+
+	switch action {
+	case "get":
+		resp, err := transport.FetchService(serviceName, "get", resourceName, &transport.FetchOptions{Parameters: parameters})
+		if err != nil {
+			pterm.Error.Printf("Failed to get %s: %v\n", id, err)
+			return false
+		}
+		if resp != nil {
+			output.Print(resp, output.Spec{Format: output.YAML})
+		}
+
+	case "describe":
+		resp, err := transport.FetchService(serviceName, "get", resourceName, &transport.FetchOptions{Parameters: parameters})
+		if err != nil {
+			pterm.Error.Printf("Failed to get %s: %v\n", id, err)
+			return false
+		}
+		if resp != nil {
+			tableData := append(pterm.TableData{{"Key", "Value"}}, flattenFields("", resp)...)
+			pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		}
+
+	case "delete":
+		if !configs.Interactive() {
+			pterm.Error.Printf("No terminal to confirm the delete, run 'cfctl %s delete %s -p %s=%s' instead.\n", serviceName, resourceName, idField, id)
+			return false
+		}
+		confirmed, _ := ui.Confirm(fmt.Sprintf("Delete %s '%s'?", resourceName, id), false)
+		if !confirmed {
+			pterm.Info.Println("Delete cancelled.")
+			return false
+		}
+		if _, err := transport.FetchService(serviceName, "delete", resourceName, &transport.FetchOptions{Parameters: parameters}); err != nil {
+			pterm.Error.Printf("Failed to delete %s: %v\n", id, err)
+			return false
+		}
+		pterm.Success.Printf("Deleted %s '%s'.\n", resourceName, id)
+		return true
+	}
+
+	return false
+}
+
+// browseLabel is the line shown for an item, its ID followed by its name when it has one
+func browseLabel(item map[string]interface{}, idField string) string {
+	label := stringValue(item[idField])
+	if name := stringValue(item["name"]); name != "" {
+		label += "  " + name
+	}
+	return label
+}
+
+// browseSelect shows a selector of options and returns the chosen index, or false when the
+// user quits.
+func browseSelect(title string, options []string, selectedIndex int) (int, bool) {
+	index, err := ui.Select[string]{Title: title, Items: options, Selected: selectedIndex, PageSize: browsePageSize}.Run()
+	if err != nil {
+		if !errors.Is(err, ui.ErrCancelled) {
+			pterm.Error.Println(err)
+		}
+		fmt.Print("\033[H\033[2J")
+		return 0, false
+	}
+	return index, true
+}
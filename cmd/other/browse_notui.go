@@ -0,0 +1,13 @@
+//go:build notui
+
+package other
+
+import (
+	"github.com/cloudforet-io/cfctl/internal/features"
+	"github.com/pterm/pterm"
+)
+
+// BrowseResults reports that the result browser was left out of this build
+func BrowseResults(serviceName, resourceName string, data map[string]interface{}) {
+	pterm.Warning.Println(features.Unavailable(features.TUI))
+}
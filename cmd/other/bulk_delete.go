@@ -0,0 +1,98 @@
+package other
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/output"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+)
+
+// DefaultBulkDeleteThreshold is the number of IDs above which a bulk delete asks for a typed confirmation
+const DefaultBulkDeleteThreshold = 10
+
+// bulkDeleteSampleSize is how many of the items to delete are shown before the confirmation
+const bulkDeleteSampleSize = 5
+
+// bulkDeleteSampleFields are the fields shown for each sampled item when it has them
+var bulkDeleteSampleFields = []string{"name", "state", "provider", "cloud_service_type"}
+
+// ConfirmBulkDelete shows a sample of the items about to be deleted and asks to type their number.
+// The answer is read from the terminal, since stdin carries the IDs when idsFromStdin is set.
+func ConfirmBulkDelete(serviceName, resourceName string, ids []string, idsFromStdin bool) bool {
+	if configs.PromptsDisabled() || (!idsFromStdin && !configs.Interactive()) {
+		pterm.Error.WithWriter(os.Stderr).Println("No terminal to confirm the delete, use --yes to skip the confirmation")
+		return false
+	}
+
+	sample := sampleIDs(ids, bulkDeleteSampleSize)
+	results, err := transport.ExecBatch(serviceName, resourceName, "get", sample, &transport.FetchOptions{}, len(sample))
+	if err != nil {
+		pterm.Warning.WithWriter(os.Stderr).Printf("Failed to fetch the sample: %v\n", err)
+	}
+
+	header := []string{"ID"}
+	var fields []string
+	for _, field := range bulkDeleteSampleFields {
+		for _, result := range results {
+			if _, ok := result.Response[field]; ok {
+				fields = append(fields, field)
+				header = append(header, strings.ToUpper(field))
+				break
+			}
+		}
+	}
+
+	tableData := pterm.TableData{header}
+	for _, result := range results {
+		row := []string{result.ID}
+		for _, field := range fields {
+			if result.Err != nil {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, output.Value(result.Response[field]))
+		}
+		if result.Err != nil {
+			row[0] = fmt.Sprintf("%s %s", result.ID, pterm.Red("(not found)"))
+		}
+		tableData = append(tableData, row)
+	}
+
+	pterm.Warning.WithWriter(os.Stderr).Printf("About to delete %d %s items. Sample of %d:\n", len(ids), resourceName, len(sample))
+	pterm.DefaultTable.WithHasHeader().WithWriter(os.Stderr).WithData(tableData).Render()
+
+	in, out, err := openTerminal()
+	if err != nil {
+		pterm.Error.WithWriter(os.Stderr).Println("No terminal to confirm the delete, use --yes to skip the confirmation")
+		return false
+	}
+	defer in.Close()
+	defer out.Close()
+
+	fmt.Fprintf(out, "Type the number of items to delete (%d) to continue: ", len(ids))
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	count, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || count != len(ids) {
+		pterm.Warning.WithWriter(os.Stderr).Println("Cancelled.")
+		return false
+	}
+	return true
+}
+
+// sampleIDs picks up to size IDs spread evenly over the list, always including the first and the last
+func sampleIDs(ids []string, size int) []string {
+	if len(ids) <= size {
+		return ids
+	}
+	sample := make([]string, 0, size)
+	for i := 0; i < size; i++ {
+		sample = append(sample, ids[i*(len(ids)-1)/(size-1)])
+	}
+	return sample
+}
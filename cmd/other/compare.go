@@ -0,0 +1,296 @@
+package other
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/format"
+	"github.com/cloudforet-io/cfctl/pkg/output"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Results of comparing a resource between two environments
+const (
+	compareOnlyLeft  = "only in %s"
+	compareDiffers   = "differs"
+	compareIdentical = "identical"
+)
+
+// compareKind is a resource type that can be compared. Resources are matched by KeyField as
+// their IDs differ between environments.
+type compareKind struct {
+	Service  string
+	Resource string
+	KeyField string
+	IDField  string
+}
+
+// compareKinds are the resource types of 'cfctl compare --resources'
+var compareKinds = map[string]compareKind{
+	"roles":          {Service: "identity", Resource: "Role", KeyField: "name", IDField: "role_id"},
+	"providers":      {Service: "identity", Resource: "Provider", KeyField: "provider"},
+	"schemas":        {Service: "identity", Resource: "Schema", KeyField: "schema_id"},
+	"workspaces":     {Service: "identity", Resource: "Workspace", KeyField: "name", IDField: "workspace_id"},
+	"plugins":        {Service: "repository", Resource: "Plugin", KeyField: "name", IDField: "plugin_id"},
+	"collectors":     {Service: "inventory", Resource: "Collector", KeyField: "name", IDField: "collector_id"},
+	"data_sources":   {Service: "cost_analysis", Resource: "DataSource", KeyField: "name", IDField: "data_source_id"},
+	"domain_configs": {Service: "config", Resource: "DomainConfig", KeyField: "name"},
+}
+
+// compareIgnoredFields differ between environments by nature and are never compared
+var compareIgnoredFields = []string{"domain_id", "workspace_id", "created_at", "updated_at", "created_by", "updated_by", "last_collected_at"}
+
+// CompareCmd compares the resources of two environments
+var CompareCmd = &cobra.Command{
+	Use:   "compare <environment> <environment>",
+	Short: "Compare the configuration of two environments",
+	Long: `Fetch resource types from two environments of setting.yaml and report, per resource, whether
+it exists in both and which fields differ. Resources are matched by name, or by their
+provider or schema ID, as their IDs differ between environments. IDs, timestamps and the
+domain and workspace of the resources are not compared.
+
+Resource types: ` + strings.Join(compareKindNames(), ", "),
+	Example: `  # Check that staging has the roles and providers of dev before promoting
+  $ cfctl compare --resources roles,providers,plugins dev-user stg-user
+
+  # Fail a pipeline when the collectors differ, ignoring their schedule
+  $ cfctl compare --resources collectors --ignore schedule --exit-code dev-app stg-app`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		resources, _ := cmd.Flags().GetStringSlice("resources")
+		ignore, _ := cmd.Flags().GetStringSlice("ignore")
+		showAll, _ := cmd.Flags().GetBool("all")
+		exitCode, _ := cmd.Flags().GetBool("exit-code")
+
+		for _, name := range resources {
+			if _, ok := compareKinds[name]; !ok {
+				pterm.Error.Printf("Unknown resource type '%s', use one of %s.\n", name, strings.Join(compareKindNames(), ", "))
+				return
+			}
+		}
+		spec, err := output.Parse(cmd.Flag("output").Value.String())
+		if err == nil {
+			err = spec.Validate()
+		}
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		left, right := args[0], args[1]
+		leftItems, err := fetchForCompare(left, resources)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		rightItems, err := fetchForCompare(right, resources)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		transport.UnpinSession()
+
+		ignore = append(ignore, compareIgnoredFields...)
+		var report []interface{}
+		differences := 0
+		for _, name := range resources {
+			rows := compareResources(name, compareKinds[name], left, right, leftItems[name], rightItems[name], ignore)
+			for _, row := range rows {
+				if row["status"] != compareIdentical {
+					differences++
+				} else if !showAll {
+					continue
+				}
+				report = append(report, row)
+			}
+		}
+
+		if len(report) == 0 {
+			pterm.Success.Printf("%s and %s have the same %s.\n", left, right, strings.Join(resources, ", "))
+			return
+		}
+		if spec.Format == output.Table && len(spec.Columns) == 0 {
+			spec.Columns = []string{"resource", "name", "status", "field", left, right}
+		}
+		if err := output.Print(report, spec); err != nil {
+			pterm.Error.Printf("Failed to format the result: %v\n", err)
+			return
+		}
+		if exitCode && differences > 0 {
+			cleanup.Exit(1)
+		}
+	},
+}
+
+// fetchForCompare lists the resource types in the environment, keyed by resource type name
+func fetchForCompare(env string, resources []string) (map[string][]map[string]interface{}, error) {
+	if _, err := transport.PinEnvironment(env); err != nil {
+		return nil, err
+	}
+
+	items := make(map[string][]map[string]interface{})
+	for _, name := range resources {
+		kind := compareKinds[name]
+		results, err := fetchResults(kind.Service, kind.Resource, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s of %s: %v", name, env, err)
+		}
+		items[name] = results
+	}
+	return items, nil
+}
+
+// compareResources matches the resources of both environments by their key and returns a row
+// per resource only in one environment, per differing field, or per identical resource
+func compareResources(name string, kind compareKind, left, right string, leftItems, rightItems []map[string]interface{}, ignore []string) []map[string]interface{} {
+	leftByKey := compareIndex(leftItems, kind.KeyField)
+	rightByKey := compareIndex(rightItems, kind.KeyField)
+
+	keys := make([]string, 0, len(leftByKey)+len(rightByKey))
+	for key := range leftByKey {
+		keys = append(keys, key)
+	}
+	for key := range rightByKey {
+		if _, ok := leftByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	ignored := map[string]bool{kind.IDField: true}
+	for _, field := range ignore {
+		ignored[field] = true
+	}
+
+	var rows []map[string]interface{}
+	row := func(key, status string) map[string]interface{} {
+		return map[string]interface{}{"resource": name, "name": key, "status": status}
+	}
+	for _, key := range keys {
+		leftItem, inLeft := leftByKey[key]
+		rightItem, inRight := rightByKey[key]
+		switch {
+		case !inRight:
+			rows = append(rows, row(key, fmt.Sprintf(compareOnlyLeft, left)))
+			continue
+		case !inLeft:
+			rows = append(rows, row(key, fmt.Sprintf(compareOnlyLeft, right)))
+			continue
+		}
+
+		leftFields := format.Flatten(leftItem, 10)
+		rightFields := format.Flatten(rightItem, 10)
+		fields := make([]string, 0, len(leftFields))
+		for field := range leftFields {
+			fields = append(fields, field)
+		}
+		for field := range rightFields {
+			if _, ok := leftFields[field]; !ok {
+				fields = append(fields, field)
+			}
+		}
+		sort.Strings(fields)
+
+		identical := true
+		for _, field := range fields {
+			if compareFieldIgnored(field, ignored) {
+				continue
+			}
+			leftValue, rightValue := compareValue(leftFields[field]), compareValue(rightFields[field])
+			if leftValue == rightValue {
+				continue
+			}
+			identical = false
+			diff := row(key, compareDiffers)
+			diff["field"] = field
+			diff[left] = leftValue
+			diff[right] = rightValue
+			rows = append(rows, diff)
+		}
+		if identical {
+			rows = append(rows, row(key, compareIdentical))
+		}
+	}
+	return rows
+}
+
+// compareIndex keys the items by the key field, suffixing repeated keys so none is lost
+func compareIndex(items []map[string]interface{}, keyField string) map[string]map[string]interface{} {
+	byKey := make(map[string]map[string]interface{}, len(items))
+	for _, item := range items {
+		key := stringValue(item[keyField])
+		for n := 2; ; n++ {
+			if _, exists := byKey[key]; !exists {
+				break
+			}
+			key = fmt.Sprintf("%s (%d)", stringValue(item[keyField]), n)
+		}
+		byKey[key] = item
+	}
+	return byKey
+}
+
+// compareFieldIgnored reports whether the field or one of its parents is ignored
+func compareFieldIgnored(field string, ignored map[string]bool) bool {
+	for {
+		if ignored[field] {
+			return true
+		}
+		i := strings.LastIndex(field, ".")
+		if i < 0 {
+			return false
+		}
+		field = field[:i]
+	}
+}
+
+// compareValue renders a value for comparison. Lists of strings, like permissions, are sorted
+// as their order is not significant.
+func compareValue(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		strs := make([]string, 0, len(list))
+		for _, v := range list {
+			s, ok := v.(string)
+			if !ok {
+				return output.Value(value)
+			}
+			strs = append(strs, s)
+		}
+		sort.Strings(strs)
+		sorted := make([]interface{}, len(strs))
+		for i, s := range strs {
+			sorted[i] = s
+		}
+		return output.Value(sorted)
+	}
+	return output.Value(value)
+}
+
+func compareKindNames() []string {
+	names := make([]string, 0, len(compareKinds))
+	for name := range compareKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	CompareCmd.Flags().StringSlice("resources", []string{"roles", "providers", "plugins"}, "Resource types to compare")
+	CompareCmd.Flags().StringSlice("ignore", nil, "Fields not to compare, e.g. schedule or tags.owner")
+	CompareCmd.Flags().Bool("all", false, "Also list the resources that are identical")
+	CompareCmd.Flags().Bool("exit-code", false, "Exit with code 1 when the environments differ")
+	CompareCmd.Flags().StringP("output", "o", "table", "Output format (table, json, yaml, csv)")
+	CompareCmd.RegisterFlagCompletionFunc("resources", completeFrom(compareKindNames()))
+
+	CompareCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= 2 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeEnvironments(cmd, args, toComplete)
+	}
+}
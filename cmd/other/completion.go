@@ -0,0 +1,68 @@
+package other
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/spf13/cobra"
+)
+
+// completeEnvironments completes the environment names of setting.yaml
+func completeEnvironments(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	v, err := configs.Setting()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, 0)
+	for name := range v.GetStringMap("environments") {
+		names = append(names, name)
+	}
+	return filterCompletions(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWorkspaces completes the names of the workspaces of the user, with their ID as description
+func completeWorkspaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	resp, err := transport.FetchService("identity", "get_workspaces", "UserProfile", &transport.FetchOptions{})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	results, _ := resp["results"].([]interface{})
+	for _, result := range results {
+		workspace, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := stringValue(workspace["name"])
+		if name != "" && strings.HasPrefix(strings.ToLower(name), strings.ToLower(toComplete)) {
+			completions = append(completions, name+"\t"+stringValue(workspace["workspace_id"]))
+		}
+	}
+	sort.Strings(completions)
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFrom returns a completion function suggesting the given values
+func completeFrom(values []string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return filterCompletions(values, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// filterCompletions returns the sorted values starting with toComplete
+func filterCompletions(values []string, toComplete string) []string {
+	var completions []string
+	for _, value := range values {
+		if strings.HasPrefix(value, toComplete) {
+			completions = append(completions, value)
+		}
+	}
+	sort.Strings(completions)
+	return completions
+}
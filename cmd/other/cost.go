@@ -0,0 +1,169 @@
+package other
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/format"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// CostCmd represents the cost command
+var CostCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Check budgets and costs",
+	Long:  `Quick checks on top of the cost_analysis service.`,
+}
+
+var costCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check budget spend against a threshold",
+	Long: `Compare the spend of a budget with its limit.
+Exits with code 1 when the spend exceeds the threshold, so it can be used for cron-based cost alerting.`,
+	Example: `  # Fail when more than 80% of the budget is spent
+  $ cfctl cost check --budget budget-123456789012 --threshold 80%`,
+	Run: func(cmd *cobra.Command, args []string) {
+		budgetID, _ := cmd.Flags().GetString("budget")
+		thresholdFlag, _ := cmd.Flags().GetString("threshold")
+		displayCurrency, _ := cmd.Flags().GetString("currency")
+
+		threshold, err := parsePercentage(thresholdFlag)
+		if err != nil {
+			pterm.Error.Printf("Invalid threshold: %v\n", err)
+			cleanup.Exit(2)
+		}
+
+		budget, err := transport.FetchService("cost_analysis", "get", "Budget", &transport.FetchOptions{
+			Parameters: []string{fmt.Sprintf("budget_id=%s", budgetID)},
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to get budget: %v\n", err)
+			cleanup.Exit(2)
+		}
+		if budget == nil {
+			cleanup.Exit(2)
+		}
+
+		usages, err := transport.FetchService("cost_analysis", "list", "BudgetUsage", &transport.FetchOptions{
+			Parameters: []string{fmt.Sprintf("budget_id=%s", budgetID)},
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to list budget usage: %v\n", err)
+			cleanup.Exit(2)
+		}
+
+		limit := budgetLimit(budget)
+		if limit <= 0 {
+			pterm.Error.Printf("Budget '%s' has no limit set.\n", budgetID)
+			cleanup.Exit(2)
+		}
+
+		spend := 0.0
+		if results, ok := usages["results"].([]interface{}); ok {
+			for _, result := range results {
+				if usage, ok := result.(map[string]interface{}); ok {
+					spend += toFloat(usage["cost"])
+				}
+			}
+		}
+
+		usedRate := spend / limit * 100
+		currency, _ := budget["currency"].(string)
+		name, _ := budget["name"].(string)
+
+		converter, err := format.NewCurrencyConverter(displayCurrency)
+		if err != nil {
+			pterm.Warning.Printf("Showing amounts in %s: %v\n", currency, err)
+			converter = nil
+		}
+
+		tableData := pterm.TableData{
+			{"Field", "Value"},
+			{"Budget", fmt.Sprintf("%s (%s)", name, budgetID)},
+			{"Spend", formatCostAmount(spend, currency, converter)},
+			{"Limit", formatCostAmount(limit, currency, converter)},
+			{"Used", fmt.Sprintf("%.1f%%", usedRate)},
+			{"Threshold", fmt.Sprintf("%.1f%%", threshold)},
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		if converter.Converts(currency) {
+			pterm.Info.Printf("Converted amounts are approximate: %s\n", converter.Note(currency))
+		}
+
+		if usedRate > threshold {
+			pterm.Error.Printf("Budget '%s' exceeded the threshold: %.1f%% > %.1f%%\n", budgetID, usedRate, threshold)
+			cleanup.Exit(1)
+		}
+
+		pterm.Success.Printf("Budget '%s' is within the threshold: %.1f%% <= %.1f%%\n", budgetID, usedRate, threshold)
+	},
+}
+
+// formatCostAmount renders an amount in its currency, followed by the amount in the display
+// currency marked with '≈' when a display currency is configured
+func formatCostAmount(amount float64, currency string, converter *format.CurrencyConverter) string {
+	formatted := format.FormatCurrency(amount, currency)
+	if !converter.Converts(currency) {
+		return formatted
+	}
+	converted, ok := converter.Convert(amount, currency)
+	if !ok {
+		return formatted + " (no exchange rate)"
+	}
+	return fmt.Sprintf("%s (≈ %s)", formatted, format.FormatCurrency(converted, converter.Display))
+}
+
+// budgetLimit returns the budget limit, summing planned limits when no total limit is set
+func budgetLimit(budget map[string]interface{}) float64 {
+	if limit := toFloat(budget["limit"]); limit > 0 {
+		return limit
+	}
+
+	total := 0.0
+	if plannedLimits, ok := budget["planned_limits"].([]interface{}); ok {
+		for _, planned := range plannedLimits {
+			if plannedMap, ok := planned.(map[string]interface{}); ok {
+				total += toFloat(plannedMap["limit"])
+			}
+		}
+	}
+	return total
+}
+
+// parsePercentage parses values like '80%' or '80'
+func parsePercentage(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	percentage, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a percentage", value)
+	}
+	if percentage < 0 {
+		return 0, fmt.Errorf("percentage must not be negative")
+	}
+	return percentage, nil
+}
+
+// toFloat converts JSON numbers and numeric strings to float64
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+func init() {
+	CostCmd.AddCommand(costCheckCmd)
+
+	costCheckCmd.Flags().String("budget", "", "Budget ID to check")
+	costCheckCmd.Flags().String("threshold", "100%", "Maximum allowed spend relative to the budget limit (e.g. 80%)")
+	costCheckCmd.Flags().String("currency", "", "Also show amounts converted to this currency (default currency.display setting)")
+	costCheckCmd.MarkFlagRequired("budget")
+}
@@ -0,0 +1,379 @@
+package other
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/format"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// costReport is the data passed to the report templates
+type costReport struct {
+	Month       string
+	GroupBy     string
+	Total       float64
+	Rows        []costReportRow
+	GeneratedAt string
+	// Currency of the amounts, and how they were converted when a display currency is set
+	Currency       string
+	ConversionNote string
+}
+
+type costReportRow struct {
+	Key   string
+	Cost  float64
+	Share float64
+}
+
+const defaultMarkdownCostReport = `# Cost Report {{ .Month }}
+
+Grouped by **{{ .GroupBy }}** · Generated at {{ .GeneratedAt }}
+
+| {{ .GroupBy }} | Cost{{ with .Currency }} ({{ . }}){{ end }} | Share |
+|---|---:|---:|
+{{- range .Rows }}
+| {{ .Key }} | {{ printf "%.2f" .Cost }} | {{ printf "%.1f" .Share }}% |
+{{- end }}
+| **Total** | **{{ printf "%.2f" .Total }}** | |
+{{- with .ConversionNote }}
+
+_{{ . }}_
+{{- end }}
+`
+
+const defaultHTMLCostReport = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Cost Report {{ .Month }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 12px; }
+td.number { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Cost Report {{ .Month }}</h1>
+<p>Grouped by <b>{{ .GroupBy }}</b> · Generated at {{ .GeneratedAt }}</p>
+<table>
+<tr><th>{{ .GroupBy }}</th><th>Cost{{ with .Currency }} ({{ . }}){{ end }}</th><th>Share</th></tr>
+{{- range .Rows }}
+<tr><td>{{ .Key }}</td><td class="number">{{ printf "%.2f" .Cost }}</td><td class="number">{{ printf "%.1f" .Share }}%</td></tr>
+{{- end }}
+<tr><th>Total</th><th class="number">{{ printf "%.2f" .Total }}</th><th></th></tr>
+</table>
+{{- with .ConversionNote }}
+<p><i>{{ . }}</i></p>
+{{- end }}
+</body>
+</html>
+`
+
+// costGroupByFields maps short group-by names to cost fields
+var costGroupByFields = map[string]string{
+	"project":         "project_id",
+	"workspace":       "workspace_id",
+	"service_account": "service_account_id",
+	"provider":        "provider",
+	"region":          "region_code",
+	"product":         "product",
+}
+
+var costReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate a monthly cost report",
+	Long: `Aggregate cost-analysis data of a month into a markdown or HTML report.
+
+The report is rendered with a Go template. To customize it, place a template at
+~/.cfctl/templates/cost-report.md.tmpl or ~/.cfctl/templates/cost-report.html.tmpl,
+or pass one with --template.`,
+	Example: `  # Generate an HTML report grouped by project
+  $ cfctl cost report --month 2024-06 --group-by project --format html
+
+  # Use a custom template
+  $ cfctl cost report --month 2024-06 --group-by provider --format markdown --template my-report.tmpl`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		month, _ := cmd.Flags().GetString("month")
+		groupBy, _ := cmd.Flags().GetString("group-by")
+		reportFormat, _ := cmd.Flags().GetString("format")
+		templateFile, _ := cmd.Flags().GetString("template")
+		outputFile, _ := cmd.Flags().GetString("output-file")
+		dataSourceID, _ := cmd.Flags().GetString("data-source")
+		displayCurrency, _ := cmd.Flags().GetString("currency")
+
+		if _, err := time.Parse("2006-01", month); err != nil {
+			return fmt.Errorf("invalid month '%s', expected YYYY-MM", month)
+		}
+
+		var extension string
+		switch reportFormat {
+		case "markdown", "md":
+			extension = "md"
+		case "html":
+			extension = "html"
+		default:
+			return fmt.Errorf("unsupported format '%s', use markdown or html", reportFormat)
+		}
+
+		groupField := groupBy
+		if field, ok := costGroupByFields[groupBy]; ok {
+			groupField = field
+		}
+
+		dataSources, err := listCostDataSources(dataSourceID)
+		if err != nil {
+			return fmt.Errorf("failed to list data sources: %v", err)
+		}
+
+		converter, err := format.NewCurrencyConverter(displayCurrency)
+		if err != nil {
+			return fmt.Errorf("failed to set up currency conversion: %v", err)
+		}
+
+		costs := make(map[string]float64)
+		currencies := make(map[string]bool)
+		var notes []string
+		for _, dataSource := range dataSources {
+			sourceCosts := make(map[string]float64)
+			if err := analyzeMonthlyCost(dataSource.ID, month, groupField, sourceCosts); err != nil {
+				return fmt.Errorf("failed to analyze costs of data source '%s': %v", dataSource.ID, err)
+			}
+
+			// Convert each data source from its own currency, so mixed currencies add up
+			if converter.Converts(dataSource.Currency) {
+				if _, ok := converter.Convert(1, dataSource.Currency); !ok {
+					return fmt.Errorf("no exchange rate from %s to %s for data source '%s'", dataSource.Currency, converter.Display, dataSource.ID)
+				}
+				for key, cost := range sourceCosts {
+					sourceCosts[key], _ = converter.Convert(cost, dataSource.Currency)
+				}
+				if note := converter.Note(dataSource.Currency); !containsString(notes, note) {
+					notes = append(notes, note)
+				}
+			}
+
+			for key, cost := range sourceCosts {
+				costs[key] += cost
+			}
+			currencies[dataSource.Currency] = true
+		}
+
+		report := buildCostReport(month, groupBy, costs)
+		if converter != nil {
+			report.Currency = converter.Display
+		} else if len(currencies) == 1 {
+			for currency := range currencies {
+				report.Currency = currency
+			}
+		} else {
+			pterm.Warning.Println("Data sources use different currencies. Set currency.display or --currency to convert them.")
+		}
+		if len(notes) > 0 {
+			report.ConversionNote = "Converted to " + converter.Display + " at approximate rates: " + strings.Join(notes, ", ")
+		}
+
+		templateText, err := loadCostReportTemplate(templateFile, extension)
+		if err != nil {
+			return fmt.Errorf("failed to load template: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if extension == "html" {
+			tmpl, parseErr := htmltemplate.New("cost-report").Parse(templateText)
+			if parseErr != nil {
+				return fmt.Errorf("failed to parse template: %v", parseErr)
+			}
+			err = tmpl.Execute(&buf, report)
+		} else {
+			tmpl, parseErr := template.New("cost-report").Parse(templateText)
+			if parseErr != nil {
+				return fmt.Errorf("failed to parse template: %v", parseErr)
+			}
+			err = tmpl.Execute(&buf, report)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to render report: %v", err)
+		}
+
+		if outputFile == "" {
+			outputFile = fmt.Sprintf("cost-report-%s.%s", month, extension)
+		}
+		if err := configs.WriteFileAtomic(outputFile, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write report: %v", err)
+		}
+
+		pterm.Success.Printf("Cost report for %s written to %s (total %.2f, %d groups).\n", month, outputFile, report.Total, len(report.Rows))
+		return nil
+	},
+}
+
+// costDataSource is a cost data source and the currency of its costs
+type costDataSource struct {
+	ID       string
+	Currency string
+}
+
+// listCostDataSources returns all cost data sources, or only the given one
+func listCostDataSources(dataSourceID string) ([]costDataSource, error) {
+	resp, err := transport.FetchService("cost_analysis", "list", "DataSource", &transport.FetchOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var dataSources []costDataSource
+	if results, ok := resp["results"].([]interface{}); ok {
+		for _, result := range results {
+			dataSource, ok := result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := dataSource["data_source_id"].(string)
+			if id == "" || (dataSourceID != "" && id != dataSourceID) {
+				continue
+			}
+			dataSources = append(dataSources, costDataSource{ID: id, Currency: dataSourceCurrency(dataSource)})
+		}
+	}
+
+	if len(dataSources) == 0 {
+		if dataSourceID != "" {
+			return nil, fmt.Errorf("data source '%s' not found", dataSourceID)
+		}
+		return nil, fmt.Errorf("no data sources found")
+	}
+	return dataSources, nil
+}
+
+// dataSourceCurrency returns the currency reported by the data source plugin
+func dataSourceCurrency(dataSource map[string]interface{}) string {
+	if pluginInfo, ok := dataSource["plugin_info"].(map[string]interface{}); ok {
+		if metadata, ok := pluginInfo["metadata"].(map[string]interface{}); ok {
+			if currency, ok := metadata["currency"].(string); ok && currency != "" {
+				return strings.ToUpper(currency)
+			}
+		}
+	}
+	if currency, ok := dataSource["currency"].(string); ok {
+		return strings.ToUpper(currency)
+	}
+	return ""
+}
+
+// containsString reports whether the list contains the value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeMonthlyCost adds the monthly cost of the data source grouped by the field to costs
+func analyzeMonthlyCost(dataSourceID, month, groupField string, costs map[string]float64) error {
+	query := fmt.Sprintf(`{"granularity":"MONTHLY","start":"%s","end":"%s","group_by":["%s"],"fields":{"cost":{"key":"cost","operator":"sum"}}}`,
+		month, month, groupField)
+
+	resp, err := transport.FetchService("cost_analysis", "analyze", "Cost", &transport.FetchOptions{
+		Parameters: []string{
+			fmt.Sprintf("data_source_id=%s", dataSourceID),
+			fmt.Sprintf("query=%s", query),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if results, ok := resp["results"].([]interface{}); ok {
+		for _, result := range results {
+			row, ok := result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key := fmt.Sprintf("%v", row[groupField])
+			if row[groupField] == nil || key == "" {
+				key = "(none)"
+			}
+			costs[key] += toFloat(row["cost"])
+		}
+	}
+
+	return nil
+}
+
+// buildCostReport sorts the groups by cost and calculates their share of the total
+func buildCostReport(month, groupBy string, costs map[string]float64) costReport {
+	report := costReport{
+		Month:       month,
+		GroupBy:     groupBy,
+		GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}
+
+	for key, cost := range costs {
+		report.Total += cost
+		report.Rows = append(report.Rows, costReportRow{Key: key, Cost: cost})
+	}
+
+	sort.Slice(report.Rows, func(i, j int) bool {
+		if report.Rows[i].Cost == report.Rows[j].Cost {
+			return report.Rows[i].Key < report.Rows[j].Key
+		}
+		return report.Rows[i].Cost > report.Rows[j].Cost
+	})
+
+	if report.Total > 0 {
+		for i := range report.Rows {
+			report.Rows[i].Share = report.Rows[i].Cost / report.Total * 100
+		}
+	}
+
+	return report
+}
+
+// loadCostReportTemplate returns the given template, the user template in ~/.cfctl/templates or the default one
+func loadCostReportTemplate(templateFile, extension string) (string, error) {
+	if templateFile != "" {
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	if settingDir, err := configs.SettingDir(); err == nil {
+		userTemplate := filepath.Join(settingDir, "templates", fmt.Sprintf("cost-report.%s.tmpl", extension))
+		if data, err := os.ReadFile(userTemplate); err == nil {
+			return string(data), nil
+		}
+	}
+
+	if extension == "html" {
+		return defaultHTMLCostReport, nil
+	}
+	return strings.TrimLeft(defaultMarkdownCostReport, "\n"), nil
+}
+
+func init() {
+	CostCmd.AddCommand(costReportCmd)
+
+	costReportCmd.Flags().String("month", time.Now().AddDate(0, -1, 0).Format("2006-01"), "Month to report (YYYY-MM)")
+	costReportCmd.Flags().String("group-by", "project", "Field to group costs by (project, workspace, service_account, provider, region, product or any cost field)")
+	costReportCmd.Flags().String("format", "markdown", "Report format (markdown, html)")
+	costReportCmd.Flags().String("template", "", "Custom Go template file for the report")
+	costReportCmd.Flags().String("output-file", "", "Path of the report file (default cost-report-<month>.<ext>)")
+	costReportCmd.Flags().String("data-source", "", "Cost data source ID (default all data sources)")
+	costReportCmd.Flags().String("currency", "", "Convert costs to this currency (default currency.display setting)")
+}
@@ -0,0 +1,327 @@
+package other
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudforet-io/cfctl/internal/credstore"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+)
+
+// Entries of the credential store holding the keys encrypting saved passwords: the first key
+// is encryption-key, the ones made by 'cfctl setting rotate-key' encryption-key/<version>
+const (
+	encryptionKeyName        = "encryption-key"
+	encryptionKeyVersionName = "encryption-key-version"
+)
+
+var (
+	credentialStoreOnce sync.Once
+	openedStore         credstore.Store
+	openStoreErr        error
+
+	unresolvedMu       sync.Mutex
+	unresolvedReported = map[string]bool{}
+)
+
+// credentialStore opens the credential store chosen by credential_store in setting.yaml or
+// CFCTL_CREDENTIAL_STORE, once per command
+func credentialStore() (credstore.Store, error) {
+	credentialStoreOnce.Do(func() {
+		v, _ := configs.Setting()
+		openedStore, openStoreErr = credstore.Open(v, GetSettingDir())
+	})
+	return openedStore, openStoreErr
+}
+
+// ResolveCredential returns the secret of key in the credential store, for the references in
+// setting.yaml. A failure is reported once per key, as the reference then reads as empty.
+func ResolveCredential(key string) (string, error) {
+	store, err := credentialStore()
+	if err == nil {
+		var value string
+		if value, err = store.Get(key); err == nil {
+			return value, nil
+		}
+		err = fmt.Errorf("failed to read %s from %s: %v", key, store.Name(), err)
+	}
+	unresolvedMu.Lock()
+	defer unresolvedMu.Unlock()
+	if !unresolvedReported[key] {
+		unresolvedReported[key] = true
+		pterm.Warning.WithWriter(os.Stderr).Printf("%v\n", err)
+	}
+	return "", err
+}
+
+// StoreCredential keeps secret in the credential store under key for the tokens cached by login,
+// see secretSettingValue
+func StoreCredential(key, secret string) string {
+	return secretSettingValue(key, secret)
+}
+
+// DeleteCredential removes the secret of key from the credential store
+func DeleteCredential(key string) error {
+	store, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	return store.Delete(key)
+}
+
+// tokenSecretKey is the key of the selected token of an environment in the credential store
+func tokenSecretKey(env string) string {
+	return "token/" + env
+}
+
+// listedTokenSecretKey is the key of a token of the tokens list of an environment, named by
+// its hash so that the same token keeps its key
+func listedTokenSecretKey(env, token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("token/%s/%x", env, sum[:6])
+}
+
+// storeSecret keeps secret in the credential store under key and returns the reference to write
+// to setting.yaml in its place
+func storeSecret(key, secret string) (string, error) {
+	if secret == "" || configs.IsSecretRef(secret) {
+		return secret, nil
+	}
+	store, err := credentialStore()
+	if err != nil {
+		return "", err
+	}
+	if err := store.Set(key, secret); err != nil {
+		return "", fmt.Errorf("failed to store %s in %s: %v", key, store.Name(), err)
+	}
+	return configs.SecretRef(key), nil
+}
+
+// secretSettingValue is storeSecret for saving a token: when the store is disabled or fails,
+// the token itself is returned so that saving never fails because of the store
+func secretSettingValue(key, secret string) string {
+	ref, err := storeSecret(key, secret)
+	if err != nil {
+		if !errors.Is(err, credstore.ErrDisabled) {
+			pterm.Warning.Printf("Keeping the token in plaintext: %v\n", err)
+		}
+		return secret
+	}
+	return ref
+}
+
+// tokenListSetting returns the tokens list of an environment to write to setting.yaml, each
+// token replaced by its reference
+func tokenListSetting(env string, tokens []TokenInfo) []TokenInfo {
+	out := make([]TokenInfo, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Token == "" {
+			continue
+		}
+		out = append(out, TokenInfo{Token: secretSettingValue(listedTokenSecretKey(env, t.Token), t.Token)})
+	}
+	return out
+}
+
+// deleteSecrets removes the secrets that the settings of an environment refer to
+func deleteSecrets(envSettings map[string]interface{}) {
+	var refs []string
+	if token, ok := envSettings["token"].(string); ok && configs.IsSecretRef(token) {
+		refs = append(refs, token)
+	}
+	tokens, _ := envSettings["tokens"].([]interface{})
+	for _, entry := range tokens {
+		if item, ok := entry.(map[string]interface{}); ok {
+			if token, ok := item["token"].(string); ok && configs.IsSecretRef(token) {
+				refs = append(refs, token)
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return
+	}
+
+	store, err := credentialStore()
+	if err != nil {
+		return
+	}
+	for _, ref := range refs {
+		key := ref[len(configs.SecretRefPrefix):]
+		if err := store.Delete(key); err != nil {
+			pterm.Warning.Printf("Failed to remove %s from %s: %v\n", key, store.Name(), err)
+		}
+	}
+}
+
+// encryptionKeyVersion returns the version of the key encrypting new values, 1 until the key
+// was rotated
+func encryptionKeyVersion(store credstore.Store) (int, error) {
+	value, err := store.Get(encryptionKeyVersionName)
+	if errors.Is(err, credstore.ErrNotFound) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to access %s: %v", store.Name(), err)
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("%s holds an invalid %s '%s'", store.Name(), encryptionKeyVersionName, value)
+	}
+	return version, nil
+}
+
+// encryptionKeyEntry is the entry of the credential store holding the key of version
+func encryptionKeyEntry(version int) string {
+	if version == 1 {
+		return encryptionKeyName
+	}
+	return fmt.Sprintf("%s/%d", encryptionKeyName, version)
+}
+
+// getEncryptionKey returns the key of version, generating it when it does not exist yet and
+// create is set
+func getEncryptionKey(store credstore.Store, version int, create bool) ([]byte, error) {
+	key, err := store.Get(encryptionKeyEntry(version))
+	if errors.Is(err, credstore.ErrNotFound) && create {
+		newKey := make([]byte, 32)
+		if _, err := rand.Read(newKey); err != nil {
+			return nil, fmt.Errorf("failed to generate new key: %v", err)
+		}
+
+		encodedKey := base64.StdEncoding.EncodeToString(newKey)
+		if err := store.Set(encryptionKeyEntry(version), encodedKey); err != nil {
+			return nil, fmt.Errorf("failed to store key in %s: %v", store.Name(), err)
+		}
+
+		return newKey, nil
+	}
+	if errors.Is(err, credstore.ErrNotFound) {
+		return nil, fmt.Errorf("the encryption key version %d is missing from %s", version, store.Name())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to access %s: %v", store.Name(), err)
+	}
+
+	return base64.StdEncoding.DecodeString(key)
+}
+
+// encrypt encrypts text with AES-GCM under the current key. The result is prefixed with the
+// version of the key, e.g. "v2:...", so that values keep decrypting after a rotation.
+func encrypt(text string) (string, error) {
+	store, err := credentialStore()
+	if err != nil {
+		return "", err
+	}
+	version, err := encryptionKeyVersion(store)
+	if err != nil {
+		return "", err
+	}
+	return encryptWithKey(store, version, text)
+}
+
+func encryptWithKey(store credstore.Store, version int, text string) (string, error) {
+	key, err := getEncryptionKey(store, version, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to get encryption key: %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, []byte(text), nil)
+	return fmt.Sprintf("v%d:%s", version, base64.URLEncoding.EncodeToString(ciphertext)), nil
+}
+
+// decrypt decrypts a value of encrypt. Values without a version prefix were encrypted with
+// AES-CFB by earlier versions under the first key; encrypt them again to upgrade them.
+func decrypt(cryptoText string) (string, error) {
+	store, err := credentialStore()
+	if err != nil {
+		return "", err
+	}
+
+	version, encoded, versioned := parseCiphertext(cryptoText)
+	key, err := getEncryptionKey(store, version, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to get encryption key: %v", err)
+	}
+	ciphertext, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	if !versioned {
+		return decryptLegacy(key, ciphertext)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt, the value was changed or the key is wrong: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// parseCiphertext splits the key version off a value of encrypt. Legacy values have none and
+// use the first key.
+func parseCiphertext(cryptoText string) (version int, encoded string, versioned bool) {
+	prefix, rest, ok := strings.Cut(cryptoText, ":")
+	if ok && strings.HasPrefix(prefix, "v") {
+		if n, err := strconv.Atoi(prefix[1:]); err == nil && n > 0 {
+			return n, rest, true
+		}
+	}
+	return 1, cryptoText, false
+}
+
+// isLegacyCiphertext reports whether a value was encrypted with AES-CFB by earlier versions
+func isLegacyCiphertext(cryptoText string) bool {
+	_, _, versioned := parseCiphertext(cryptoText)
+	return !versioned
+}
+
+func decryptLegacy(key, ciphertext []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < aes.BlockSize {
+		return "", errors.New("ciphertext too short")
+	}
+
+	iv := ciphertext[:aes.BlockSize]
+	ciphertext = ciphertext[aes.BlockSize:]
+	stream := cipher.NewCFBDecrypter(block, iv)
+	stream.XORKeyStream(ciphertext, ciphertext)
+	return string(ciphertext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
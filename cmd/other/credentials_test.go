@@ -0,0 +1,245 @@
+package other
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudforet-io/cfctl/internal/credstore"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/spf13/viper"
+)
+
+// memoryStore is a credential store in memory. Set fails for the keys of failSet.
+type memoryStore struct {
+	secrets map[string]string
+	failSet map[string]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{secrets: map[string]string{}, failSet: map[string]bool{}}
+}
+
+func (s *memoryStore) Name() string { return "memory" }
+
+func (s *memoryStore) Get(key string) (string, error) {
+	value, ok := s.secrets[key]
+	if !ok {
+		return "", credstore.ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *memoryStore) Set(key, value string) error {
+	if s.failSet[key] {
+		return errors.New("store is read-only")
+	}
+	s.secrets[key] = value
+	return nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	delete(s.secrets, key)
+	return nil
+}
+
+// useCredentialStore makes credentialStore return store
+func useCredentialStore(t *testing.T, store credstore.Store) {
+	t.Helper()
+	credentialStoreOnce = sync.Once{}
+	credentialStoreOnce.Do(func() {
+		openedStore, openStoreErr = store, nil
+	})
+	t.Cleanup(func() {
+		credentialStoreOnce = sync.Once{}
+		openedStore, openStoreErr = nil, nil
+	})
+}
+
+// encryptLegacy encrypts text with AES-CFB like earlier versions did
+func encryptLegacy(t *testing.T, key []byte, text string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := make([]byte, aes.BlockSize+len(text))
+	iv := ciphertext[:aes.BlockSize]
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext[aes.BlockSize:], []byte(text))
+	return base64.URLEncoding.EncodeToString(ciphertext)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	store := newMemoryStore()
+	useCredentialStore(t, store)
+
+	for _, text := range []string{"secret", "", "pässwörd with spaces"} {
+		ciphertext, err := encrypt(text)
+		if err != nil {
+			t.Fatalf("encrypt(%q) error = %v", text, err)
+		}
+		if !strings.HasPrefix(ciphertext, "v1:") || isLegacyCiphertext(ciphertext) {
+			t.Errorf("encrypt(%q) = %q, want a v1: value", text, ciphertext)
+		}
+		plaintext, err := decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("decrypt(%q) error = %v", ciphertext, err)
+		}
+		if plaintext != text {
+			t.Errorf("decrypt(encrypt(%q)) = %q", text, plaintext)
+		}
+	}
+
+	ciphertext, _ := encrypt("secret")
+	tampered := ciphertext[:len(ciphertext)-2] + "AA"
+	if tampered == ciphertext {
+		tampered = ciphertext[:len(ciphertext)-2] + "BB"
+	}
+	if _, err := decrypt(tampered); err == nil {
+		t.Error("decrypt of a changed value succeeded")
+	}
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	store := newMemoryStore()
+	useCredentialStore(t, store)
+
+	key, err := getEncryptionKey(store, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := encryptLegacy(t, key, "legacy secret")
+	if !isLegacyCiphertext(ciphertext) {
+		t.Fatalf("isLegacyCiphertext(%q) = false", ciphertext)
+	}
+	plaintext, err := decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("decrypt error = %v", err)
+	}
+	if plaintext != "legacy secret" {
+		t.Errorf("decrypt = %q, want %q", plaintext, "legacy secret")
+	}
+}
+
+func TestRotateEncryptionKey(t *testing.T) {
+	store := newMemoryStore()
+	useCredentialStore(t, store)
+
+	key, err := getEncryptionKey(store, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := encryptLegacy(t, key, "legacy secret")
+	current, err := encrypt("current secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeSetting(t, legacy, current)
+
+	rotated, upgraded, err := rotateEncryptionKey(path, store, 1)
+	if err != nil {
+		t.Fatalf("rotateEncryptionKey error = %v", err)
+	}
+	if rotated != 2 || upgraded != 1 {
+		t.Errorf("rotateEncryptionKey = %d, %d, want 2, 1", rotated, upgraded)
+	}
+	if version, _ := encryptionKeyVersion(store); version != 2 {
+		t.Errorf("key version = %d, want 2", version)
+	}
+
+	passwords := readPasswords(t, path)
+	want := []string{"legacy secret", "current secret"}
+	if len(passwords) != len(want) {
+		t.Fatalf("passwords = %q, want %d", passwords, len(want))
+	}
+	for i, password := range passwords {
+		if !strings.HasPrefix(password, "v2:") {
+			t.Errorf("password %d = %q, want a v2: value", i, password)
+		}
+		plaintext, err := decrypt(password)
+		if err != nil {
+			t.Fatalf("decrypt(%q) error = %v", password, err)
+		}
+		if plaintext != want[i] {
+			t.Errorf("password %d decrypts to %q, want %q", i, plaintext, want[i])
+		}
+	}
+}
+
+func TestRotateEncryptionKeyVersionFailure(t *testing.T) {
+	store := newMemoryStore()
+	useCredentialStore(t, store)
+
+	current, err := encrypt("current secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeSetting(t, current)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.failSet[encryptionKeyVersionName] = true
+	if _, _, err := rotateEncryptionKey(path, store, 1); err == nil {
+		t.Fatal("rotateEncryptionKey succeeded without storing the key version")
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("setting file changed to:\n%s", after)
+	}
+	if version, _ := encryptionKeyVersion(store); version != 1 {
+		t.Errorf("key version = %d, want 1", version)
+	}
+	if plaintext, err := decrypt(current); err != nil || plaintext != "current secret" {
+		t.Errorf("decrypt = %q, %v, want the password to still decrypt", plaintext, err)
+	}
+}
+
+// writeSetting writes a setting file with a user per password
+func writeSetting(t *testing.T, passwords ...string) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("environments:\n  dev-user:\n    users:\n")
+	for i, password := range passwords {
+		b.WriteString("      - user_id: user" + string(rune('a'+i)) + "\n")
+		b.WriteString("        password: " + password + "\n")
+	}
+	path := filepath.Join(t.TempDir(), "setting.yaml")
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readPasswords returns the passwords of the users of the setting file, in order
+func readPasswords(t *testing.T, path string) []string {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := configs.ReadConfig(v); err != nil {
+		t.Fatal(err)
+	}
+	users, _ := v.Get("environments.dev-user.users").([]interface{})
+	var passwords []string
+	for _, entry := range users {
+		user, _ := entry.(map[string]interface{})
+		password, _ := user["password"].(string)
+		passwords = append(passwords, password)
+	}
+	return passwords
+}
@@ -0,0 +1,203 @@
+package other
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// describeTarget maps a describe resource name to its service, resource and ID field
+type describeTarget struct {
+	Service  string
+	Resource string
+	IDField  string
+}
+
+var describeTargets = map[string]describeTarget{
+	"cloud-service":      {Service: "inventory", Resource: "CloudService", IDField: "cloud_service_id"},
+	"cloud-service-type": {Service: "inventory", Resource: "CloudServiceType", IDField: "cloud_service_type_id"},
+	"collector":          {Service: "inventory", Resource: "Collector", IDField: "collector_id"},
+	"service-account":    {Service: "identity", Resource: "ServiceAccount", IDField: "service_account_id"},
+	"project":            {Service: "identity", Resource: "Project", IDField: "project_id"},
+}
+
+// DescribeCmd represents the describe command
+var DescribeCmd = &cobra.Command{
+	Use:   "describe <resource> <id>",
+	Short: "Show a resource with its nested data flattened",
+	Long: `Show a resource with its nested fields rendered as dotted keys or as a tree.
+
+Supported resources: cloud-service, cloud-service-type, collector, service-account, project
+
+The ID can be a unique prefix of an ID shown by a recent list command.`,
+	Example: `  $ cfctl describe cloud-service cloud-svc-1234567890ab
+  $ cfctl describe cloud-service cloud-svc-1234567890ab --view tree
+  $ cfctl describe cloud-service cloud-svc-1234567890ab --grep security_group
+  $ cfctl describe cloud-service cloud-svc-1a2`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		view, _ := cmd.Flags().GetString("view")
+		grep, _ := cmd.Flags().GetString("grep")
+
+		target, ok := describeTargets[args[0]]
+		if !ok {
+			pterm.Error.Printf("Unsupported resource '%s'.\n", args[0])
+			return
+		}
+
+		var pattern *regexp.Regexp
+		if grep != "" {
+			var err error
+			pattern, err = regexp.Compile("(?i)" + grep)
+			if err != nil {
+				pterm.Error.Printf("Invalid --grep pattern: %v\n", err)
+				return
+			}
+		}
+
+		resp, err := transport.FetchService(target.Service, "get", target.Resource, &transport.FetchOptions{
+			Parameters: []string{fmt.Sprintf("%s=%s", target.IDField, args[1])},
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to get %s: %v\n", args[0], err)
+			return
+		}
+		if resp == nil {
+			return
+		}
+
+		switch view {
+		case "tree":
+			root := buildDescribeTree(args[1], resp, pattern)
+			if len(root.Children) == 0 {
+				pterm.Info.Println("No matching fields.")
+				return
+			}
+			pterm.DefaultTree.WithRoot(root).Render()
+		case "flat":
+			rows := flattenFields("", resp)
+			tableData := pterm.TableData{{"Key", "Value"}}
+			for _, row := range rows {
+				if pattern != nil && !pattern.MatchString(row[0]) && !pattern.MatchString(row[1]) {
+					continue
+				}
+				tableData = append(tableData, row)
+			}
+			if len(tableData) == 1 {
+				pterm.Info.Println("No matching fields.")
+				return
+			}
+			pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		default:
+			pterm.Error.Printf("Unsupported view '%s', use flat or tree.\n", view)
+		}
+	},
+}
+
+// flattenFields converts nested maps and lists into sorted dotted-key rows
+func flattenFields(prefix string, value interface{}) [][]string {
+	var rows [][]string
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			rows = append(rows, flattenFields(joinFieldPath(prefix, key), v[key])...)
+		}
+		if len(v) == 0 && prefix != "" {
+			rows = append(rows, []string{prefix, "{}"})
+		}
+	case []interface{}:
+		for i, item := range v {
+			rows = append(rows, flattenFields(fmt.Sprintf("%s[%d]", prefix, i), item)...)
+		}
+		if len(v) == 0 && prefix != "" {
+			rows = append(rows, []string{prefix, "[]"})
+		}
+	default:
+		rows = append(rows, []string{prefix, stringValue(v)})
+	}
+
+	return rows
+}
+
+func joinFieldPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// buildDescribeTree converts the response into a tree, keeping only branches matching the pattern
+func buildDescribeTree(text string, value interface{}, pattern *regexp.Regexp) pterm.TreeNode {
+	node := pterm.TreeNode{Text: text}
+
+	var children []pterm.TreeNode
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if child, ok := describeTreeChild(key, v[key], pattern); ok {
+				children = append(children, child)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if child, ok := describeTreeChild(fmt.Sprintf("[%d]", i), item, pattern); ok {
+				children = append(children, child)
+			}
+		}
+	}
+
+	node.Children = children
+	return node
+}
+
+// describeTreeChild builds the node of a field and reports whether it or its children match
+func describeTreeChild(key string, value interface{}, pattern *regexp.Regexp) (pterm.TreeNode, bool) {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		if pattern != nil && pattern.MatchString(key) {
+			return buildDescribeTree(key, value, nil), true
+		}
+		child := buildDescribeTree(key, value, pattern)
+		return child, pattern == nil || len(child.Children) > 0
+	default:
+		text := fmt.Sprintf("%s: %s", key, stringValue(value))
+		return pterm.TreeNode{Text: text}, pattern == nil || pattern.MatchString(text)
+	}
+}
+
+// describeResourceNames returns the supported resource names for completion
+func describeResourceNames() []string {
+	names := make([]string, 0, len(describeTargets))
+	for name := range describeTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	DescribeCmd.Flags().String("view", "flat", "View of the nested fields (flat, tree)")
+	DescribeCmd.Flags().String("grep", "", "Only show fields whose key or value matches the pattern")
+
+	DescribeCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return describeResourceNames(), cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
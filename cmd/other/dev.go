@@ -0,0 +1,100 @@
+package other
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/mockserver"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+)
+
+// DevCmd groups the tools for developing cfctl and plugins
+var DevCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Tools for developing cfctl and plugins",
+}
+
+var (
+	mockServerPort     int
+	mockServerFixtures string
+)
+
+var devMockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Start a local mock SpaceONE server",
+	Long: `Start a gRPC server with reflection that serves identity and inventory resources from
+fixtures, so that cfctl and plugins can be developed offline against the local environment.
+
+The fixture directory holds a directory per service with a file per resource:
+
+  identity/Workspace.yaml         a list of records, served by list, get, create, update,
+                                  delete and stat
+  identity/UserProfile.get.yaml   the fixed response of one method, which can be any verb
+
+Bundled fixtures of a few resources are always served; the files of --fixtures add to them and
+replace those of the same name. Changes made by create, update and delete are kept in memory
+until the server stops.`,
+	Example: `  $ cfctl dev mock-server
+  $ cfctl dev mock-server --port 50052 --fixtures ./fixtures
+
+  # In another terminal
+  $ cfctl setting init static grpc://localhost:50051
+  $ cfctl identity list Workspace`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, err := mockserver.New(mockServerFixtures)
+		if err != nil {
+			pterm.Error.Println(err)
+			cleanup.Exit(1)
+		}
+		server.Log = func(method string, err error, elapsed time.Duration) {
+			result := pterm.FgGreen.Sprint("OK")
+			if err != nil {
+				result = pterm.FgRed.Sprint(status.Convert(err).Message())
+			}
+			pterm.Printf("%s %s %s %s\n", time.Now().Format("15:04:05"), method, result, elapsed.Round(time.Microsecond))
+		}
+
+		address := fmt.Sprintf("localhost:%d", mockServerPort)
+		lis, err := net.Listen("tcp", address)
+		if err != nil {
+			pterm.Error.Printf("Failed to listen on %s: %v\n", address, err)
+			cleanup.Exit(1)
+		}
+
+		services := server.Services()
+		pterm.Success.Printf("Mock server listening on grpc://%s with %d services.\n", address, len(services))
+		for _, service := range services {
+			pterm.Printf("  %s\n", service)
+		}
+		pterm.Info.Printf("Use it with 'cfctl setting init static grpc://%s', stop it with Ctrl+C.\n", address)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		defer cleanup.Intercept()()
+		go func() {
+			<-ctx.Done()
+			server.Stop()
+		}()
+
+		if err := server.Serve(lis); err != nil {
+			pterm.Error.Printf("Mock server failed: %v\n", err)
+			cleanup.Exit(1)
+		}
+	},
+}
+
+func init() {
+	devMockServerCmd.Flags().IntVar(&mockServerPort, "port", 50051, "Port to listen on")
+	devMockServerCmd.Flags().StringVar(&mockServerFixtures, "fixtures", "", "Directory of fixtures served in addition to the bundled ones")
+	devMockServerCmd.MarkFlagDirname("fixtures")
+	DevCmd.AddCommand(devMockServerCmd)
+}
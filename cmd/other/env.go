@@ -0,0 +1,82 @@
+package other
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// containerCacheDir is where the caches go in containers, whose home is often read-only
+const containerCacheDir = "/tmp/cfctl-cache"
+
+// containerSettingDir is where the image looks for a mounted setting directory
+const containerSettingDir = "/root/.cfctl"
+
+// EnvCmd prints environment variables for running cfctl elsewhere
+var EnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print environment variables to run cfctl elsewhere",
+}
+
+var envDockerCmd = &cobra.Command{
+	Use:   "docker",
+	Short: "Print the environment variables to run the current environment in a container",
+	Long: `Print the environment of the current environment as an env file for 'docker run --env-file'.
+The container then needs neither setting.yaml nor a terminal. The token is only written with
+--show-token; otherwise fill it in from 'cfctl token show --raw'. With --mount, the setting
+directory is mounted read-only instead and only the non-interactive settings are printed.`,
+	Example: `  # Write an env file with the token and run a command in a container
+  $ cfctl env docker --show-token > cfctl.env
+  $ docker run --rm --env-file cfctl.env cloudforet/cfctl:latest inventory list CloudService
+
+  # Mount the setting directory read-only instead
+  $ cfctl env docker --mount`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setting, err := configs.SetSettingFile()
+		if err != nil {
+			pterm.Error.Printf("Failed to load setting: %v\n", err)
+			return
+		}
+		currentEnv := setting.Environment
+		envSetting := setting.Environments[currentEnv]
+
+		showToken, _ := cmd.Flags().GetBool("show-token")
+		mount, _ := cmd.Flags().GetBool("mount")
+
+		fmt.Printf("%s=true\n", configs.EnvVarNonInteractive)
+		fmt.Printf("%s=%s\n", configs.EnvVarCacheDir, containerCacheDir)
+		if !mount {
+			fmt.Printf("%s=%s\n", configs.EnvVarEnvironment, currentEnv)
+			fmt.Printf("%s=%s\n", configs.EnvVarEndpoint, envSetting.Endpoint)
+			switch {
+			case envSetting.Token == "":
+				pterm.Warning.WithWriter(os.Stderr).Printf("No token found for '%s', run 'cfctl login' first.\n", currentEnv)
+				fmt.Printf("%s=\n", configs.EnvVarToken)
+			case showToken:
+				fmt.Printf("%s=%s\n", configs.EnvVarToken, envSetting.Token)
+			default:
+				fmt.Printf("%s=\n", configs.EnvVarToken)
+				pterm.Info.WithWriter(os.Stderr).Printf("Fill in %s with 'cfctl token show --raw' or use --show-token.\n", configs.EnvVarToken)
+			}
+			return
+		}
+
+		settingDir, err := configs.SettingDir()
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		pterm.Info.WithWriter(os.Stderr).Printf("Mount the setting directory with: -v %s:%s:ro\n", settingDir, containerSettingDir)
+	},
+}
+
+func init() {
+	EnvCmd.AddCommand(envDockerCmd)
+
+	envDockerCmd.Flags().Bool("show-token", false, "Include the access token of the environment")
+	envDockerCmd.Flags().Bool("mount", false, "Print the settings for mounting the setting directory read-only instead")
+}
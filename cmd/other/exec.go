@@ -0,0 +1,71 @@
+package other
+
+import (
+	"github.com/cloudforet-io/cfctl/pkg/output"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// ExecCmd represents the exec command
+var ExecCmd = &cobra.Command{
+	Use:   "exec <service> <resource> <verb>",
+	Short: "Call any API method by service, resource and verb",
+	Long: `Call any SpaceONE API method discovered through server reflection, including
+methods that have no dedicated command. The request body can be loaded from a YAML or
+JSON file with -f, or as JSON with -j; '-' reads either from stdin. Parameters given
+with -p are deep merged over the body, so a shared payload can be adjusted per call.`,
+	Example: `  $ cfctl exec identity Project get -p project_id=project-1234567890ab
+  $ cfctl exec inventory CloudService list -p query.filter[0].k=provider -p query.filter[0].v=aws -p query.filter[0].o=eq
+  $ echo '{"name": "sre"}' | cfctl exec identity Project create -j -
+  $ cfctl exec identity TrustedAccount create -f trusted_account.yaml -p name=prod-aws`,
+	Args:        cobra.ExactArgs(3),
+	Annotations: map[string]string{output.QueryAnnotation: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		parameters, _ := cmd.Flags().GetStringArray("parameter")
+		jsonParameter, _ := cmd.Flags().GetString("json-parameter")
+		fileParameter, _ := cmd.Flags().GetString("file-parameter")
+		outputFormat, _ := cmd.Flags().GetString("output")
+		refresh, _ := cmd.Flags().GetBool("refresh")
+
+		spec, err := output.Parse(outputFormat)
+		if err == nil {
+			err = spec.Validate(output.Table, output.JSON, output.YAML, output.CSV, output.ID)
+		}
+		if err == nil {
+			spec.Query, _ = cmd.Flags().GetString("query")
+			err = output.ValidateQuery(spec.Query)
+		}
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		serviceName, resourceName, verb := args[0], args[1], args[2]
+		resp, err := transport.Exec(serviceName, resourceName, verb, &transport.FetchOptions{
+			Parameters:    parameters,
+			JSONParameter: jsonParameter,
+			FileParameter: fileParameter,
+			Refresh:       refresh,
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to execute %s.%s.%s: %v\n", serviceName, resourceName, verb, err)
+			return
+		}
+
+		if spec.Format == output.ID {
+			spec.IDField = transport.IDField(serviceName, verb, resourceName)
+		}
+		if err := output.Print(resp, spec); err != nil {
+			pterm.Error.Printf("Failed to format the response: %v\n", err)
+		}
+	},
+}
+
+func init() {
+	ExecCmd.Flags().StringArrayP("parameter", "p", []string{}, "Input Parameter (-p <key>=<value> -p ..., nested fields: -p query.filter[0].k=name)")
+	ExecCmd.Flags().StringP("json-parameter", "j", "", "JSON type parameter, '-' reads it from stdin")
+	ExecCmd.Flags().StringP("file-parameter", "f", "", "YAML or JSON file with the request body, '-' reads it from stdin")
+	ExecCmd.Flags().Bool("refresh", false, "Resolve the service descriptors again instead of using the cached ones")
+	ExecCmd.Flags().StringP("output", "o", "yaml", "Output format (yaml, json, table, csv, id); table=col1,col2 selects columns")
+}
@@ -0,0 +1,255 @@
+package other
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/cron"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultImage is the image running cfctl in generated manifests, tagged with the version
+const defaultImage = "cloudforet/cfctl"
+
+// GenerateCmd generates manifests that run cfctl outside of a terminal
+var GenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate manifests that run cfctl commands in automation",
+}
+
+var generateK8sJobCmd = &cobra.Command{
+	Use:   "k8s-job -- <command> [args...]",
+	Short: "Generate a Kubernetes Job or CronJob running a cfctl command",
+	Long: `Print a Kubernetes manifest running the cfctl command after '--' against the current
+environment. With --schedule a CronJob is generated, otherwise a Job. The token is read from
+a Secret, which is not part of the manifest; create it with the command printed to stderr.`,
+	Example: `  # Export the costs every night at 02:00
+  $ cfctl generate k8s-job --schedule "0 2 * * *" -- exec cost list -o csv | kubectl apply -f -
+
+  # Run a command once in the monitoring namespace
+  $ cfctl generate k8s-job --name sync-check -n monitoring -- inventory list CloudService`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setting, err := configs.SetSettingFile()
+		if err != nil {
+			pterm.Error.Printf("Failed to load setting: %v\n", err)
+			return
+		}
+		currentEnv := setting.Environment
+		endpoint := setting.Environments[currentEnv].Endpoint
+		if endpoint == "" {
+			pterm.Error.Printf("No endpoint found for environment '%s'.\n", currentEnv)
+			return
+		}
+
+		schedule, _ := cmd.Flags().GetString("schedule")
+		if schedule != "" {
+			if _, err := cron.Parse(schedule); err != nil {
+				pterm.Error.Printf("Invalid schedule: %v\n", err)
+				return
+			}
+		}
+
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			name = jobName(args)
+		}
+		namespace, _ := cmd.Flags().GetString("namespace")
+		image, _ := cmd.Flags().GetString("image")
+		if image == "" {
+			image = defaultImageRef()
+		}
+		secretName, _ := cmd.Flags().GetString("token-secret")
+		secretKey, _ := cmd.Flags().GetString("token-key")
+		backoffLimit, _ := cmd.Flags().GetInt("backoff-limit")
+
+		job := k8sJobSpec{
+			BackoffLimit: backoffLimit,
+			Template: k8sPodTemplate{Spec: k8sPodSpec{
+				RestartPolicy: "Never",
+				Containers: []k8sContainer{{
+					Name:  "cfctl",
+					Image: image,
+					Args:  args,
+					Env: []k8sEnvVar{
+						{Name: configs.EnvVarEnvironment, Value: currentEnv},
+						{Name: configs.EnvVarEndpoint, Value: endpoint},
+						{Name: configs.EnvVarToken, ValueFrom: &k8sEnvSource{
+							SecretKeyRef: k8sSecretKeyRef{Name: secretName, Key: secretKey},
+						}},
+						{Name: configs.EnvVarNonInteractive, Value: "true"},
+						{Name: configs.EnvVarCacheDir, Value: containerCacheDir},
+					},
+					SecurityContext: k8sSecurityContext{ReadOnlyRootFilesystem: true, AllowPrivilegeEscalation: false},
+					VolumeMounts:    []k8sVolumeMount{{Name: "tmp", MountPath: "/tmp"}},
+				}},
+				Volumes: []k8sVolume{{Name: "tmp", EmptyDir: map[string]interface{}{}}},
+			}},
+		}
+
+		manifest := k8sManifest{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+			Metadata: k8sMetadata{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/name": "cfctl", "app.kubernetes.io/instance": name},
+			},
+			Spec: job,
+		}
+		if schedule != "" {
+			manifest.Kind = "CronJob"
+			manifest.Spec = k8sCronJobSpec{
+				Schedule:                   schedule,
+				ConcurrencyPolicy:          "Forbid",
+				SuccessfulJobsHistoryLimit: 3,
+				FailedJobsHistoryLimit:     3,
+				JobTemplate:                k8sJobTemplate{Spec: job},
+			}
+		}
+
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(manifest); err != nil {
+			pterm.Error.Printf("Failed to generate manifest: %v\n", err)
+			return
+		}
+		encoder.Close()
+		fmt.Print(buf.String())
+
+		createSecret := fmt.Sprintf("kubectl create secret generic %s --from-literal=%s=$(cfctl token show --raw)", secretName, secretKey)
+		if namespace != "" {
+			createSecret += " -n " + namespace
+		}
+		pterm.Info.WithWriter(os.Stderr).Printf("Create the token secret with: %s\n", createSecret)
+	},
+}
+
+// k8sManifest and the types below are the parts of the Kubernetes API used by generated manifests
+type k8sManifest struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   k8sMetadata `yaml:"metadata"`
+	Spec       interface{} `yaml:"spec"`
+}
+
+type k8sMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type k8sCronJobSpec struct {
+	Schedule                   string         `yaml:"schedule"`
+	ConcurrencyPolicy          string         `yaml:"concurrencyPolicy"`
+	SuccessfulJobsHistoryLimit int            `yaml:"successfulJobsHistoryLimit"`
+	FailedJobsHistoryLimit     int            `yaml:"failedJobsHistoryLimit"`
+	JobTemplate                k8sJobTemplate `yaml:"jobTemplate"`
+}
+
+type k8sJobTemplate struct {
+	Spec k8sJobSpec `yaml:"spec"`
+}
+
+type k8sJobSpec struct {
+	BackoffLimit int            `yaml:"backoffLimit"`
+	Template     k8sPodTemplate `yaml:"template"`
+}
+
+type k8sPodTemplate struct {
+	Spec k8sPodSpec `yaml:"spec"`
+}
+
+type k8sPodSpec struct {
+	RestartPolicy string         `yaml:"restartPolicy"`
+	Containers    []k8sContainer `yaml:"containers"`
+	Volumes       []k8sVolume    `yaml:"volumes,omitempty"`
+}
+
+type k8sContainer struct {
+	Name            string             `yaml:"name"`
+	Image           string             `yaml:"image"`
+	Args            []string           `yaml:"args"`
+	Env             []k8sEnvVar        `yaml:"env"`
+	SecurityContext k8sSecurityContext `yaml:"securityContext"`
+	VolumeMounts    []k8sVolumeMount   `yaml:"volumeMounts,omitempty"`
+}
+
+type k8sEnvVar struct {
+	Name      string        `yaml:"name"`
+	Value     string        `yaml:"value,omitempty"`
+	ValueFrom *k8sEnvSource `yaml:"valueFrom,omitempty"`
+}
+
+type k8sEnvSource struct {
+	SecretKeyRef k8sSecretKeyRef `yaml:"secretKeyRef"`
+}
+
+type k8sSecretKeyRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+type k8sSecurityContext struct {
+	ReadOnlyRootFilesystem   bool `yaml:"readOnlyRootFilesystem"`
+	AllowPrivilegeEscalation bool `yaml:"allowPrivilegeEscalation"`
+}
+
+type k8sVolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+}
+
+type k8sVolume struct {
+	Name     string                 `yaml:"name"`
+	EmptyDir map[string]interface{} `yaml:"emptyDir"`
+}
+
+// nonNameChars matches the characters not allowed in Kubernetes object names
+var nonNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// jobName derives an object name from the command, like cfctl-exec-cost-list. CronJob names
+// are limited to 52 characters, as the controller appends a suffix for its jobs.
+func jobName(args []string) string {
+	parts := []string{"cfctl"}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") || len(parts) == 4 {
+			break
+		}
+		if part := strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(arg), "-"), "-"); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	name := strings.Join(parts, "-")
+	if len(name) > 52 {
+		name = strings.TrimRight(name[:52], "-")
+	}
+	return name
+}
+
+// defaultImageRef returns the image of the running version, or latest for development builds
+func defaultImageRef() string {
+	if Version == "dev" || Version == "" {
+		return defaultImage + ":latest"
+	}
+	return defaultImage + ":" + Version
+}
+
+func init() {
+	GenerateCmd.AddCommand(generateK8sJobCmd)
+
+	generateK8sJobCmd.Flags().String("schedule", "", "Cron expression, e.g. \"0 2 * * *\", to generate a CronJob instead of a Job")
+	generateK8sJobCmd.Flags().String("name", "", "Name of the job (default derived from the command)")
+	generateK8sJobCmd.Flags().StringP("namespace", "n", "", "Namespace of the job")
+	generateK8sJobCmd.Flags().String("image", "", "Image running cfctl (default cloudforet/cfctl with the version of this binary)")
+	generateK8sJobCmd.Flags().String("token-secret", "cfctl-token", "Name of the Secret holding the access token")
+	generateK8sJobCmd.Flags().String("token-key", "token", "Key of the access token in the Secret")
+	generateK8sJobCmd.Flags().Int("backoff-limit", 2, "Number of retries before the job is marked failed")
+}
@@ -0,0 +1,282 @@
+package other
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/cron"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Authentication of generated GitHub Actions steps
+const (
+	ghaAuthSecret = "secret"
+	ghaAuthOIDC   = "oidc"
+)
+
+var generateGHACmd = &cobra.Command{
+	Use:   "gha -- <command> [args...]",
+	Short: "Generate GitHub Actions steps running a cfctl command",
+	Long: `Print GitHub Actions steps that install cfctl and run the command after '--' against the
+current environment, writing its output to the job summary. With --auth secret the token is read
+from a repository secret; with --auth oidc the OIDC token of the job is exchanged for one by
+'cfctl login --oidc'. With --workflow or --schedule a complete workflow is printed instead.`,
+	Example: `  # Steps using the CFCTL_TOKEN repository secret
+  $ cfctl generate gha -- inventory list CloudService -o table
+
+  # A nightly workflow logging in with the OIDC token of the job
+  $ cfctl generate gha --auth oidc --schedule "0 2 * * *" -- exec cost list -o csv > .github/workflows/cost.yaml`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		auth, _ := cmd.Flags().GetString("auth")
+		if auth != ghaAuthSecret && auth != ghaAuthOIDC {
+			pterm.Error.Printf("Unknown auth '%s', use %s or %s.\n", auth, ghaAuthSecret, ghaAuthOIDC)
+			return
+		}
+		schedule, _ := cmd.Flags().GetString("schedule")
+		if schedule != "" {
+			if _, err := cron.Parse(schedule); err != nil {
+				pterm.Error.Printf("Invalid schedule: %v\n", err)
+				return
+			}
+		}
+
+		setting, err := configs.SetSettingFile()
+		if err != nil {
+			pterm.Error.Printf("Failed to load setting: %v\n", err)
+			return
+		}
+		currentEnv := setting.Environment
+		endpoint := setting.Environments[currentEnv].Endpoint
+		if endpoint == "" {
+			pterm.Error.Printf("No endpoint found for environment '%s'.\n", currentEnv)
+			return
+		}
+
+		env := map[string]string{
+			configs.EnvVarNonInteractive: "true",
+		}
+		steps := []ghaStep{ghaInstallStep()}
+
+		secretName, _ := cmd.Flags().GetString("token-secret")
+		switch auth {
+		case ghaAuthSecret:
+			env[configs.EnvVarEnvironment] = currentEnv
+			env[configs.EnvVarEndpoint] = endpoint
+			env[configs.EnvVarToken] = fmt.Sprintf("${{ secrets.%s }}", secretName)
+		case ghaAuthOIDC:
+			v, err := readSettingViper()
+			if err != nil {
+				pterm.Error.Printf("Failed to read config file: %v\n", err)
+				return
+			}
+			exchangeEndpoint, _ := cmd.Flags().GetString("token-exchange-endpoint")
+			if exchangeEndpoint == "" {
+				exchangeEndpoint = v.GetString(fmt.Sprintf("environments.%s.oidc.token_exchange_endpoint", currentEnv))
+			}
+			if exchangeEndpoint == "" {
+				pterm.Error.Printf("No token exchange endpoint configured, use --token-exchange-endpoint or set environments.%s.oidc.token_exchange_endpoint.\n", currentEnv)
+				return
+			}
+			audience, _ := cmd.Flags().GetString("audience")
+			if audience == "" {
+				audience = v.GetString(fmt.Sprintf("environments.%s.oidc.audience", currentEnv))
+			}
+
+			loginStep, err := ghaOIDCLoginStep(currentEnv, endpoint, exchangeEndpoint, audience)
+			if err != nil {
+				pterm.Error.Printf("Failed to generate the login step: %v\n", err)
+				return
+			}
+			steps = append(steps, loginStep)
+		}
+
+		summary, _ := cmd.Flags().GetBool("summary")
+		steps = append(steps, ghaRunStep(args, summary))
+
+		workflow, _ := cmd.Flags().GetBool("workflow")
+		workflow = workflow || schedule != ""
+		var document interface{}
+		if workflow {
+			document = ghaWorkflowFor(args, auth, schedule, env, steps)
+		} else {
+			for i := range steps {
+				if i > 0 {
+					steps[i].Env = env
+				}
+			}
+			document = steps
+		}
+
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(document); err != nil {
+			pterm.Error.Printf("Failed to generate workflow: %v\n", err)
+			return
+		}
+		encoder.Close()
+		fmt.Print(buf.String())
+
+		switch auth {
+		case ghaAuthSecret:
+			pterm.Info.WithWriter(os.Stderr).Printf("Create the secret with: gh secret set %s --body \"$(cfctl token show --raw)\"\n", secretName)
+		case ghaAuthOIDC:
+			if workflow {
+				break
+			}
+			pterm.Info.WithWriter(os.Stderr).Println("The job needs 'permissions: id-token: write' to request its OIDC token.")
+		}
+	},
+}
+
+// ghaWorkflow and the types below are the parts of the GitHub Actions workflow syntax used
+type ghaWorkflow struct {
+	Name        string                 `yaml:"name"`
+	On          map[string]interface{} `yaml:"on"`
+	Permissions map[string]string      `yaml:"permissions"`
+	Jobs        map[string]ghaJob      `yaml:"jobs"`
+}
+
+type ghaJob struct {
+	RunsOn string            `yaml:"runs-on"`
+	Env    map[string]string `yaml:"env,omitempty"`
+	Steps  []ghaStep         `yaml:"steps"`
+}
+
+type ghaStep struct {
+	Name string            `yaml:"name"`
+	Env  map[string]string `yaml:"env,omitempty"`
+	Run  string            `yaml:"run"`
+}
+
+// ghaWorkflowFor wraps the steps in a workflow started manually, and by the schedule if any
+func ghaWorkflowFor(args []string, auth, schedule string, env map[string]string, steps []ghaStep) ghaWorkflow {
+	on := map[string]interface{}{"workflow_dispatch": map[string]interface{}{}}
+	if schedule != "" {
+		on["schedule"] = []map[string]string{{"cron": schedule}}
+	}
+	permissions := map[string]string{"contents": "read"}
+	if auth == ghaAuthOIDC {
+		permissions["id-token"] = "write"
+	}
+	return ghaWorkflow{
+		Name:        "cfctl " + strings.Join(commandWords(args), " "),
+		On:          on,
+		Permissions: permissions,
+		Jobs: map[string]ghaJob{
+			"cfctl": {RunsOn: "ubuntu-latest", Env: env, Steps: steps},
+		},
+	}
+}
+
+// ghaInstallStep downloads the release of the running version, or the latest for development builds
+func ghaInstallStep() ghaStep {
+	release := "latest/download"
+	if Version != "dev" && Version != "" {
+		release = "download/" + Version
+	}
+	return ghaStep{
+		Name: "Install cfctl",
+		Run: fmt.Sprintf(`ARCH=$(uname -m)
+if [ "$ARCH" = "aarch64" ]; then ARCH=arm64; fi
+mkdir -p "$HOME/.local/bin"
+curl -sSfL "https://github.com/cloudforet-io/cfctl/releases/%s/cfctl_Linux_${ARCH}.tar.gz" | tar xz -C "$HOME/.local/bin" cfctl
+echo "$HOME/.local/bin" >> "$GITHUB_PATH"`, release),
+	}
+}
+
+// ghaOIDCLoginStep writes a setting.yaml with the OIDC settings of the environment and logs in,
+// as the token exchanged by 'cfctl login --oidc' is kept in the setting directory
+func ghaOIDCLoginStep(currentEnv, endpoint, exchangeEndpoint, audience string) (ghaStep, error) {
+	oidc := map[string]string{"token_exchange_endpoint": exchangeEndpoint}
+	if audience != "" {
+		oidc["audience"] = audience
+	}
+	setting := map[string]interface{}{
+		"environment": currentEnv,
+		"environments": map[string]interface{}{
+			currentEnv: map[string]interface{}{"endpoint": endpoint, "oidc": oidc},
+		},
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(setting); err != nil {
+		return ghaStep{}, err
+	}
+	encoder.Close()
+
+	return ghaStep{
+		Name: "Log in to SpaceONE",
+		Run: fmt.Sprintf(`mkdir -p "$HOME/.cfctl"
+cat > "$HOME/.cfctl/setting.yaml" <<'EOF'
+%sEOF
+cfctl login --oidc`, buf.String()),
+	}, nil
+}
+
+// ghaRunStep runs the command, copying its output to the job summary when summary is set
+func ghaRunStep(args []string, summary bool) ghaStep {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellArg(arg)
+	}
+	command := "cfctl " + strings.Join(quoted, " ")
+	step := ghaStep{Name: "Run cfctl " + strings.Join(commandWords(args), " "), Run: command}
+	if !summary {
+		return step
+	}
+
+	step.Run = fmt.Sprintf(`set -o pipefail
+%s | tee cfctl-output.txt
+{
+  echo '### %s'
+  echo '%s'
+  cat cfctl-output.txt
+  echo '%s'
+} >> "$GITHUB_STEP_SUMMARY"`, command, strings.ReplaceAll(command, "'", `'\''`), "```", "```")
+	return step
+}
+
+// commandWords returns the leading words of the command, before any flag
+func commandWords(args []string) []string {
+	var words []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			break
+		}
+		words = append(words, arg)
+	}
+	return words
+}
+
+// plainShellArg matches arguments that need no quoting in a shell
+var plainShellArg = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellArg quotes the argument for a POSIX shell when needed
+func shellArg(arg string) string {
+	if plainShellArg.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func init() {
+	GenerateCmd.AddCommand(generateGHACmd)
+
+	generateGHACmd.Flags().String("auth", ghaAuthSecret, "How the job authenticates: secret (token in a repository secret) or oidc (token exchange)")
+	generateGHACmd.Flags().String("token-secret", "CFCTL_TOKEN", "Name of the repository secret holding the access token, for --auth secret")
+	generateGHACmd.Flags().String("token-exchange-endpoint", "", "Token exchange endpoint for --auth oidc (default from the oidc settings of the environment)")
+	generateGHACmd.Flags().String("audience", "", "Audience of the OIDC token for --auth oidc (default from the oidc settings of the environment)")
+	generateGHACmd.Flags().Bool("summary", true, "Write the output of the command to the job summary")
+	generateGHACmd.Flags().Bool("workflow", false, "Print a complete workflow instead of the steps")
+	generateGHACmd.Flags().String("schedule", "", "Cron expression, e.g. \"0 2 * * *\", running the workflow on a schedule (implies --workflow)")
+}
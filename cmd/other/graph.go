@@ -0,0 +1,234 @@
+package other
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// graphNode is a resource in the relationship graph
+type graphNode struct {
+	ID    string
+	Label string
+	Kind  string
+}
+
+// resourceGraph collects nodes and edges while walking related resources
+type resourceGraph struct {
+	nodes map[string]graphNode
+	edges map[[2]string]bool
+}
+
+func newResourceGraph() *resourceGraph {
+	return &resourceGraph{
+		nodes: make(map[string]graphNode),
+		edges: make(map[[2]string]bool),
+	}
+}
+
+func (g *resourceGraph) addNode(id, label, kind string) {
+	if _, ok := g.nodes[id]; !ok {
+		g.nodes[id] = graphNode{ID: id, Label: label, Kind: kind}
+	}
+}
+
+func (g *resourceGraph) addEdge(from, to string) {
+	g.edges[[2]string{from, to}] = true
+}
+
+func (g *resourceGraph) sortedNodes() []graphNode {
+	nodes := make([]graphNode, 0, len(g.nodes))
+	for _, node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Kind != nodes[j].Kind {
+			return nodes[i].Kind < nodes[j].Kind
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes
+}
+
+func (g *resourceGraph) sortedEdges() [][2]string {
+	edges := make([][2]string, 0, len(g.edges))
+	for edge := range g.edges {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
+
+// renderDOT renders the graph in Graphviz DOT format
+func (g *resourceGraph) renderDOT() string {
+	shapes := map[string]string{
+		"project":            "folder",
+		"service_account":    "box",
+		"collector":          "component",
+		"cloud_service_type": "ellipse",
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph cfctl {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	for _, node := range g.sortedNodes() {
+		sb.WriteString(fmt.Sprintf("  %q [label=%q, shape=%s];\n", node.ID, node.Label, shapes[node.Kind]))
+	}
+	for _, edge := range g.sortedEdges() {
+		sb.WriteString(fmt.Sprintf("  %q -> %q;\n", edge[0], edge[1]))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// renderMermaid renders the graph as a Mermaid flowchart
+func (g *resourceGraph) renderMermaid() string {
+	ids := make(map[string]string)
+	for i, node := range g.sortedNodes() {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	escape := func(label string) string {
+		return strings.ReplaceAll(label, `"`, "#quot;")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	for _, node := range g.sortedNodes() {
+		switch node.Kind {
+		case "project":
+			sb.WriteString(fmt.Sprintf("  %s[(\"%s\")]\n", ids[node.ID], escape(node.Label)))
+		case "collector":
+			sb.WriteString(fmt.Sprintf("  %s[[\"%s\"]]\n", ids[node.ID], escape(node.Label)))
+		case "cloud_service_type":
+			sb.WriteString(fmt.Sprintf("  %s([\"%s\"])\n", ids[node.ID], escape(node.Label)))
+		default:
+			sb.WriteString(fmt.Sprintf("  %s[\"%s\"]\n", ids[node.ID], escape(node.Label)))
+		}
+	}
+	for _, edge := range g.sortedEdges() {
+		sb.WriteString(fmt.Sprintf("  %s --> %s\n", ids[edge[0]], ids[edge[1]]))
+	}
+	return sb.String()
+}
+
+// GraphCmd represents the graph command
+var GraphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Emit the resource relationship graph of a project",
+	Long: `Walk the resources related to a project and emit them as a DOT or Mermaid graph:
+project -> service accounts -> collectors -> cloud service types`,
+	Example: `  $ cfctl graph --project project-1234567890ab > project.dot && dot -Tpng project.dot -o project.png
+  $ cfctl graph --project project-1234567890ab --format mermaid`,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectID, _ := cmd.Flags().GetString("project")
+		graphFormat, _ := cmd.Flags().GetString("format")
+
+		if graphFormat != "dot" && graphFormat != "mermaid" {
+			pterm.Error.Printf("Unsupported format '%s', use dot or mermaid.\n", graphFormat)
+			return
+		}
+
+		graph, err := buildProjectGraph(projectID)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		if graphFormat == "mermaid" {
+			fmt.Print(graph.renderMermaid())
+		} else {
+			fmt.Print(graph.renderDOT())
+		}
+	},
+}
+
+// buildProjectGraph walks project -> service accounts -> collectors -> cloud service types
+func buildProjectGraph(projectID string) (*resourceGraph, error) {
+	graph := newResourceGraph()
+
+	project, err := transport.FetchService("identity", "get", "Project", &transport.FetchOptions{
+		Parameters: []string{fmt.Sprintf("project_id=%s", projectID)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %v", err)
+	}
+	graph.addNode(projectID, fmt.Sprintf("%s\n%s", stringValue(project["name"]), projectID), "project")
+
+	serviceAccounts, err := fetchResults("identity", "ServiceAccount", []string{fmt.Sprintf("project_id=%s", projectID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %v", err)
+	}
+
+	providers := make(map[string][]string)
+	for _, sa := range serviceAccounts {
+		id := stringValue(sa["service_account_id"])
+		graph.addNode(id, fmt.Sprintf("%s\n%s", stringValue(sa["name"]), stringValue(sa["provider"])), "service_account")
+		graph.addEdge(projectID, id)
+		provider := stringValue(sa["provider"])
+		providers[provider] = append(providers[provider], id)
+	}
+
+	for provider, serviceAccountIDs := range providers {
+		collectors, err := fetchResults("inventory", "Collector", []string{fmt.Sprintf("provider=%s", provider)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list collectors for %s: %v", provider, err)
+		}
+
+		cloudServiceTypes, err := fetchResults("inventory", "CloudServiceType", []string{fmt.Sprintf("provider=%s", provider)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cloud service types for %s: %v", provider, err)
+		}
+
+		for _, collector := range collectors {
+			collectorID := stringValue(collector["collector_id"])
+			graph.addNode(collectorID, stringValue(collector["name"]), "collector")
+			for _, saID := range serviceAccountIDs {
+				graph.addEdge(saID, collectorID)
+			}
+
+			for _, cst := range cloudServiceTypes {
+				cstID := stringValue(cst["cloud_service_type_id"])
+				graph.addNode(cstID, fmt.Sprintf("%s.%s", stringValue(cst["group"]), stringValue(cst["name"])), "cloud_service_type")
+				graph.addEdge(collectorID, cstID)
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// fetchResults calls a list verb and returns its results as maps
+func fetchResults(service, resource string, parameters []string) ([]map[string]interface{}, error) {
+	resp, err := transport.FetchService(service, "list", resource, &transport.FetchOptions{
+		Parameters: parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var items []map[string]interface{}
+	if results, ok := resp["results"].([]interface{}); ok {
+		for _, result := range results {
+			if item, ok := result.(map[string]interface{}); ok {
+				items = append(items, item)
+			}
+		}
+	}
+	return items, nil
+}
+
+func init() {
+	GraphCmd.Flags().String("project", "", "Project ID to start from")
+	GraphCmd.Flags().String("format", "dot", "Graph format (dot, mermaid)")
+	GraphCmd.MarkFlagRequired("project")
+}
@@ -0,0 +1,235 @@
+package other
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// InventoryCmd holds the inventory commands that are merged into the inventory service command
+var InventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Work with the inventory service",
+}
+
+var inventoryExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export cloud services as an Ansible inventory or Terraform import blocks",
+	Long: `Convert the cloud services of the inventory for configuration management tools.
+
+  ansible    dynamic inventory JSON with a host per cloud service that has an IP address,
+             grouped by provider, region, project and cloud service type
+  terraform  import blocks for the cloud service types with a known Terraform resource type`,
+	Example: `  # Use the servers of a project as an Ansible inventory
+  $ cfctl inventory export --format ansible --project-id project-1234 > inventory.json
+  $ ansible -i inventory.json all -m ping
+
+  # Import the AWS EC2 instances into Terraform
+  $ cfctl inventory export --format terraform --provider aws --group EC2 > imports.tf`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		if format != "ansible" && format != "terraform" {
+			pterm.Error.Printf("Unknown format '%s', use ansible or terraform.\n", format)
+			return
+		}
+
+		var parameters []string
+		for _, flagName := range []string{"provider", "group", "type", "project-id", "region"} {
+			if value, _ := cmd.Flags().GetString(flagName); value != "" {
+				parameters = append(parameters, fmt.Sprintf("%s=%s", inventoryFilterFields[flagName], value))
+			}
+		}
+
+		services, err := fetchResults("inventory", "CloudService", parameters)
+		if err != nil {
+			pterm.Error.Printf("Failed to list cloud services: %v\n", err)
+			return
+		}
+
+		switch format {
+		case "ansible":
+			data, err := json.MarshalIndent(ansibleInventory(services), "", "  ")
+			if err != nil {
+				pterm.Error.Printf("Failed to encode inventory: %v\n", err)
+				return
+			}
+			fmt.Println(string(data))
+		case "terraform":
+			blocks, skipped := terraformImports(services)
+			fmt.Print(blocks)
+			if skipped > 0 {
+				pterm.Warning.WithWriter(os.Stderr).Printf("Skipped %d cloud services without a known Terraform resource type or import ID.\n", skipped)
+			}
+		}
+	},
+}
+
+// inventoryFilterFields maps the filter flags to the parameters of CloudService.list
+var inventoryFilterFields = map[string]string{
+	"provider":   "provider",
+	"group":      "cloud_service_group",
+	"type":       "cloud_service_type",
+	"project-id": "project_id",
+	"region":     "region_code",
+}
+
+// nonGroupChars matches the characters Ansible does not allow in group names
+var nonGroupChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// ansibleGroupName builds a valid group name like provider_aws from a prefix and a value
+func ansibleGroupName(prefix, value string) string {
+	return prefix + "_" + strings.Trim(nonGroupChars.ReplaceAllString(strings.ToLower(value), "_"), "_")
+}
+
+// ansibleInventory converts cloud services with an IP address into the JSON of a dynamic inventory
+func ansibleInventory(services []map[string]interface{}) map[string]interface{} {
+	hostvars := map[string]interface{}{}
+	groups := map[string][]string{}
+
+	for _, service := range services {
+		ips, _ := service["ip_addresses"].([]interface{})
+		if len(ips) == 0 {
+			continue
+		}
+
+		host := stringValue(service["name"])
+		if _, exists := hostvars[host]; exists || host == "" {
+			host = stringValue(service["cloud_service_id"])
+		}
+
+		vars := map[string]interface{}{
+			"ansible_host": stringValue(ips[0]),
+			"ip_addresses": ips,
+		}
+		for _, field := range []string{"cloud_service_id", "provider", "region_code", "account", "project_id", "cloud_service_group", "cloud_service_type", "tags"} {
+			if value, ok := service[field]; ok && value != nil {
+				vars[field] = value
+			}
+		}
+		hostvars[host] = vars
+
+		for prefix, value := range map[string]string{
+			"provider": stringValue(service["provider"]),
+			"region":   stringValue(service["region_code"]),
+			"project":  stringValue(service["project_id"]),
+			"type":     stringValue(service["cloud_service_group"]) + "_" + stringValue(service["cloud_service_type"]),
+		} {
+			if strings.Trim(value, "_") == "" {
+				continue
+			}
+			name := ansibleGroupName(prefix, value)
+			groups[name] = append(groups[name], host)
+		}
+	}
+
+	inventory := map[string]interface{}{
+		"_meta": map[string]interface{}{"hostvars": hostvars},
+	}
+	children := make([]string, 0, len(groups))
+	for name, hosts := range groups {
+		sort.Strings(hosts)
+		inventory[name] = map[string]interface{}{"hosts": hosts}
+		children = append(children, name)
+	}
+	sort.Strings(children)
+	inventory["all"] = map[string]interface{}{"children": children}
+	return inventory
+}
+
+// terraformResourceType describes how a cloud service type is imported into Terraform
+type terraformResourceType struct {
+	Type string
+	// IDField is the path of the import ID in the cloud service, reference.resource_id by default
+	IDField string
+}
+
+// terraformResourceTypes maps provider/group/type of cloud services to Terraform resource types
+var terraformResourceTypes = map[string]terraformResourceType{
+	"aws/EC2/Instance":                    {Type: "aws_instance", IDField: "data.compute.instance_id"},
+	"aws/EC2/SecurityGroup":               {Type: "aws_security_group", IDField: "data.group_id"},
+	"aws/VPC/VPC":                         {Type: "aws_vpc", IDField: "data.vpc_id"},
+	"aws/VPC/Subnet":                      {Type: "aws_subnet", IDField: "data.subnet_id"},
+	"aws/S3/Bucket":                       {Type: "aws_s3_bucket", IDField: "name"},
+	"aws/RDS/Instance":                    {Type: "aws_db_instance", IDField: "name"},
+	"aws/Lambda/Function":                 {Type: "aws_lambda_function", IDField: "name"},
+	"google_cloud/ComputeEngine/Instance": {Type: "google_compute_instance"},
+	"google_cloud/CloudStorage/Bucket":    {Type: "google_storage_bucket", IDField: "name"},
+	"azure/VirtualMachines/Instance":      {Type: "azurerm_virtual_machine"},
+	"azure/StorageAccounts/Instance":      {Type: "azurerm_storage_account"},
+}
+
+// nonLabelChars matches the characters Terraform does not allow in resource names
+var nonLabelChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// terraformImports renders import blocks for the cloud services of known types and returns
+// the number of cloud services that were skipped
+func terraformImports(services []map[string]interface{}) (string, int) {
+	var sb strings.Builder
+	labels := map[string]int{}
+	skipped := 0
+
+	for _, service := range services {
+		key := fmt.Sprintf("%s/%s/%s", stringValue(service["provider"]), stringValue(service["cloud_service_group"]), stringValue(service["cloud_service_type"]))
+		resourceType, ok := terraformResourceTypes[key]
+		if !ok {
+			skipped++
+			continue
+		}
+
+		idField := resourceType.IDField
+		if idField == "" {
+			idField = "reference.resource_id"
+		}
+		id := stringValue(lookupPath(service, idField))
+		if id == "" {
+			id = stringValue(lookupPath(service, "reference.resource_id"))
+		}
+		if id == "" {
+			skipped++
+			continue
+		}
+
+		label := strings.Trim(nonLabelChars.ReplaceAllString(strings.ToLower(stringValue(service["name"])), "_"), "_")
+		if label == "" || (label[0] >= '0' && label[0] <= '9') {
+			label = "r_" + label
+		}
+		labels[resourceType.Type+"."+label]++
+		if n := labels[resourceType.Type+"."+label]; n > 1 {
+			label = fmt.Sprintf("%s_%d", label, n)
+		}
+
+		fmt.Fprintf(&sb, "# %s (%s)\nimport {\n  to = %s.%s\n  id = %q\n}\n\n", stringValue(service["name"]), stringValue(service["cloud_service_id"]), resourceType.Type, label, id)
+	}
+	return sb.String(), skipped
+}
+
+// lookupPath returns the value at a dotted path like data.compute.instance_id
+func lookupPath(item map[string]interface{}, path string) interface{} {
+	var current interface{} = item
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+func init() {
+	InventoryCmd.AddCommand(inventoryExportCmd)
+
+	inventoryExportCmd.Flags().String("format", "ansible", "Export format (ansible, terraform)")
+	inventoryExportCmd.Flags().String("provider", "", "Only export cloud services of the provider (e.g. aws)")
+	inventoryExportCmd.Flags().String("group", "", "Only export cloud services of the cloud service group (e.g. EC2)")
+	inventoryExportCmd.Flags().String("type", "", "Only export cloud services of the cloud service type (e.g. Instance)")
+	inventoryExportCmd.Flags().String("project-id", "", "Only export cloud services of the project")
+	inventoryExportCmd.Flags().String("region", "", "Only export cloud services of the region code")
+}
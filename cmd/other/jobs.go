@@ -0,0 +1,132 @@
+package other
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// jobServices are the services exposing a Job resource
+var jobServices = []string{"inventory", "cost_analysis"}
+
+// jobRow is a job of any service shown in the overview
+type jobRow struct {
+	Service   string
+	JobID     string
+	Status    string
+	Source    string
+	CreatedAt time.Time
+	Total     float64
+	Remained  float64
+}
+
+// JobsCmd represents the jobs command
+var JobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Overview of jobs across services",
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List jobs with their age and progress",
+	Example: `  $ cfctl jobs list --status RUNNING --all-services
+  $ cfctl jobs list --service cost_analysis`,
+	Run: func(cmd *cobra.Command, args []string) {
+		status, _ := cmd.Flags().GetString("status")
+		allServices, _ := cmd.Flags().GetBool("all-services")
+		services, _ := cmd.Flags().GetStringSlice("service")
+
+		if allServices {
+			services = jobServices
+		}
+
+		var parameters []string
+		if status != "" {
+			parameters = append(parameters, fmt.Sprintf("status=%s", strings.ToUpper(status)))
+		}
+
+		var rows []jobRow
+		for _, service := range services {
+			jobs, err := fetchResults(service, "Job", parameters)
+			if err != nil {
+				pterm.Warning.Printf("Failed to list jobs of %s: %v\n", service, err)
+				continue
+			}
+			for _, job := range jobs {
+				row := jobRow{
+					Service:  service,
+					JobID:    stringValue(job["job_id"]),
+					Status:   stringValue(job["status"]),
+					Total:    toFloat(job["total_tasks"]),
+					Remained: toFloat(job["remained_tasks"]),
+				}
+				if row.Source = stringValue(job["collector_id"]); row.Source == "" {
+					row.Source = stringValue(job["data_source_id"])
+				}
+				row.CreatedAt, _ = time.Parse(time.RFC3339, stringValue(job["created_at"]))
+				rows = append(rows, row)
+			}
+		}
+
+		if len(rows) == 0 {
+			pterm.Info.Println("No jobs found.")
+			return
+		}
+
+		sort.Slice(rows, func(i, j int) bool {
+			return rows[i].CreatedAt.After(rows[j].CreatedAt)
+		})
+
+		tableData := pterm.TableData{{"Service", "Job ID", "Status", "Source", "Age", "Progress"}}
+		for _, row := range rows {
+			tableData = append(tableData, []string{
+				row.Service,
+				row.JobID,
+				row.Status,
+				row.Source,
+				formatAge(row.CreatedAt),
+				formatJobProgress(row.Total, row.Remained),
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	},
+}
+
+// formatAge renders the time elapsed since t in a compact form
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	elapsed := time.Since(t)
+	switch {
+	case elapsed < time.Minute:
+		return fmt.Sprintf("%ds", int(elapsed.Seconds()))
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(elapsed.Hours()), int(elapsed.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd", int(elapsed.Hours()/24))
+	}
+}
+
+// formatJobProgress renders the finished tasks of a job as a percentage
+func formatJobProgress(total, remained float64) string {
+	if total <= 0 {
+		return "-"
+	}
+	done := total - remained
+	return fmt.Sprintf("%.0f%% (%.0f/%.0f)", done/total*100, done, total)
+}
+
+func init() {
+	JobsCmd.AddCommand(jobsListCmd)
+
+	jobsListCmd.Flags().String("status", "", "Filter by status (e.g. RUNNING, SUCCESS, FAILURE)")
+	jobsListCmd.Flags().Bool("all-services", false, "List jobs of all services ("+strings.Join(jobServices, ", ")+")")
+	jobsListCmd.Flags().StringSlice("service", []string{"inventory"}, "Services to list jobs from")
+}
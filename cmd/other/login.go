@@ -3,57 +3,53 @@ package other
 import (
 	"bytes"
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"crypto/tls"
-	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
-	"github.com/AlecAivazis/survey/v2"
+	"github.com/cloudforet-io/cfctl/internal/apiclient"
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"github.com/cloudforet-io/cfctl/internal/ui"
 	"github.com/cloudforet-io/cfctl/pkg/configs"
-	"github.com/eiannone/keyboard"
+	"github.com/cloudforet-io/cfctl/pkg/token"
 
-	"google.golang.org/grpc/metadata"
-	"google.golang.org/protobuf/types/known/structpb"
-
-	"github.com/jhump/protoreflect/dynamic"
-
-	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/zalando/go-keyring"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"golang.org/x/sync/errgroup"
 )
 
-//const encryptionKey = "spaceone-cfctl-encryption-key-32byte"
-
-const (
-	keyringService = "cfctl-credentials"
-	keyringUser    = "encryption-key"
+var (
+	providedUrl       string
+	mfaCode           string
+	authType          string
+	apiKey            string
+	callbackPort      int
+	noSaveCredentials bool
+	loginNoSave       bool
+	removeUserID      string
+	userLabel         string
+	useOIDC           bool
+	loginUser         string
+	loginWorkspace    string
+	loginTokenIndex   int
+	loginScope        string
 )
 
-var providedUrl string
-
 // LoginCmd represents the login command
 var LoginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login to SpaceONE",
 	Long: `A command that allows you to login to SpaceONE.
 It will prompt you for your User ID, Password, and fetch the Domain ID automatically, then fetch the token.`,
+	Example: `  $ cfctl login
+  $ cfctl login --workspace my-ws --scope WORKSPACE
+  $ cfctl login --scope DOMAIN`,
 	Run: executeLogin,
 }
 
@@ -73,13 +69,7 @@ func (t *tokenAuth) RequireTransportSecurity() bool {
 }
 
 func executeLogin(cmd *cobra.Command, args []string) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		pterm.Error.Println("Failed to get user home directory:", err)
-		return
-	}
-
-	configPath := filepath.Join(homeDir, ".cfctl", "setting.yaml")
+	configPath := filepath.Join(GetSettingDir(), "setting.yaml")
 
 	// Check if config file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -89,18 +79,38 @@ func executeLogin(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	viper.SetConfigFile(configPath)
-	viper.SetConfigType("yaml")
-	if err := viper.ReadInConfig(); err != nil {
+	setting, err := readSettingViper()
+	if err != nil {
 		pterm.Error.Printf("Failed to read config file: %v\n", err)
 		return
 	}
 
-	currentEnv := viper.GetString("environment")
+	currentEnv := setting.GetString("environment")
 	if currentEnv == "" {
 		pterm.Error.Println("No environment selected")
 		return
 	}
+	if err := checkScopeFlags(); err != nil {
+		pterm.Error.Println(err)
+		exitWithError()
+	}
+	// A terminal working in a workspace logs in to it, keeping the token of the other terminals
+	if loginWorkspace == "" && strings.ToUpper(loginScope) != "DOMAIN" {
+		loginWorkspace = configs.SelectedWorkspace()
+	}
+
+	if useOIDC {
+		mainViper, err := readSettingViper()
+		if err != nil {
+			pterm.Error.Printf("Failed to read config file: %v\n", err)
+			exitWithError()
+		}
+		if err := executeOIDCLogin(mainViper, currentEnv); err != nil {
+			pterm.Error.Printf("OIDC login failed: %v\n", err)
+			exitWithError()
+		}
+		return
+	}
 
 	// Check if it's an app environment
 	if strings.HasSuffix(currentEnv, "-app") {
@@ -113,6 +123,47 @@ func executeLogin(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if removeUserID != "" {
+		executeRemoveUser(currentEnv, removeUserID)
+		return
+	}
+
+	if apiKey == "" {
+		apiKey = os.Getenv(apiKeyEnv)
+	}
+	if apiKey != "" {
+		mainViper, err := readSettingViper()
+		if err != nil {
+			pterm.Error.Printf("Failed to read config file: %v\n", err)
+			exitWithError()
+		}
+		if err := executeAPIKeyLogin(mainViper, currentEnv, apiKey); err != nil {
+			pterm.Error.Printf("API key login failed: %v\n", err)
+			exitWithError()
+		}
+		pterm.Success.Printf("Logged in to '%s' with the API key.\n", currentEnv)
+		return
+	}
+
+	switch strings.ToLower(authType) {
+	case authTypeLocal:
+	case authTypeExternal:
+		mainViper, err := readSettingViper()
+		if err != nil {
+			pterm.Error.Printf("Failed to read config file: %v\n", err)
+			exitWithError()
+		}
+		if err := executeExternalLogin(mainViper, currentEnv); err != nil {
+			pterm.Error.Printf("SSO login failed: %v\n", err)
+			exitWithError()
+		}
+		pterm.Success.Println("Successfully logged in and saved token.")
+		return
+	default:
+		pterm.Error.Printf("Unsupported auth type '%s', use %s or %s\n", authType, authTypeLocal, authTypeExternal)
+		exitWithError()
+	}
+
 	// Execute normal user login
 	executeUserLogin(currentEnv)
 }
@@ -123,79 +174,60 @@ type TokenInfo struct {
 
 // promptToken prompts for token input
 func promptToken() (string, error) {
-	prompt := &survey.Password{
-		Message: "Enter your token:",
+	if !configs.Interactive() {
+		return "", fmt.Errorf("no terminal to enter the token, set %s instead", configs.EnvVarToken)
 	}
-
-	var token string
-	err := survey.AskOne(prompt, &token, survey.WithValidator(survey.Required))
+	token, err := ui.Password("Enter your token")
 	if err != nil {
 		return "", err
 	}
+	if token = strings.TrimSpace(token); token == "" {
+		return "", fmt.Errorf("no token entered")
+	}
 
 	return token, nil
 }
 
 // saveAppToken saves the token
 func saveAppToken(currentEnv, token string) error {
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".cfctl", "config.yaml")
-
-	viper.SetConfigFile(configPath)
-	if err := viper.ReadInConfig(); err != nil && !os.IsNotExist(err) {
-		return err
-	}
-
-	envPath := fmt.Sprintf("environments.%s", currentEnv)
-	envSettings := viper.GetStringMap(envPath)
-	if envSettings == nil {
-		envSettings = make(map[string]interface{})
-	}
-
-	// Initialize tokens array if it doesn't exist
-	var tokens []TokenInfo
-	if existingTokens, ok := envSettings["tokens"]; ok {
-		if tokenList, ok := existingTokens.([]interface{}); ok {
-			for _, t := range tokenList {
-				if tokenMap, ok := t.(map[string]interface{}); ok {
-					tokenInfo := TokenInfo{
-						Token: tokenMap["token"].(string),
-					}
-					tokens = append(tokens, tokenInfo)
-				}
-			}
+	return configs.UpdateConfig(filepath.Join(GetSettingDir(), "setting.yaml"), func(v *viper.Viper) error {
+		envPath := fmt.Sprintf("environments.%s", currentEnv)
+		envSettings := v.GetStringMap(envPath)
+		if envSettings == nil {
+			envSettings = make(map[string]interface{})
 		}
-	}
 
-	// Add new token if it doesn't exist
-	tokenExists := false
-	for _, t := range tokens {
-		if t.Token == token {
-			tokenExists = true
-			break
+		envConfig, err := configs.DecodeEnvironment(v, currentEnv)
+		if err != nil {
+			return err
+		}
+		var tokens []TokenInfo
+		for _, t := range envConfig.Tokens {
+			tokens = append(tokens, TokenInfo{Token: t.Token})
 		}
-	}
 
-	if !tokenExists {
-		newToken := TokenInfo{
-			Token: token,
+		// Add new token if it doesn't exist
+		tokenExists := false
+		for _, t := range tokens {
+			if t.Token == token {
+				tokenExists = true
+				break
+			}
 		}
-		tokens = append(tokens, newToken)
-	}
 
-	// Update environment settings
-	envSettings["tokens"] = tokens
+		if !tokenExists {
+			newToken := TokenInfo{
+				Token: token,
+			}
+			tokens = append(tokens, newToken)
+		}
 
-	// Keep the existing endpoint and proxy settings
-	if endpoint, ok := envSettings["endpoint"]; ok {
-		envSettings["endpoint"] = endpoint
-	}
-	if proxy, ok := envSettings["proxy"]; ok {
-		envSettings["proxy"] = proxy
-	}
+		// Update environment settings, keeping the others
+		envSettings["tokens"] = tokenListSetting(currentEnv, tokens)
 
-	viper.Set(envPath, envSettings)
-	return viper.WriteConfig()
+		v.Set(envPath, envSettings)
+		return nil
+	})
 }
 
 // promptTokenSelection shows available tokens and lets user select one
@@ -203,57 +235,19 @@ func promptTokenSelection(tokens []TokenInfo) (string, error) {
 	if len(tokens) == 0 {
 		return "", fmt.Errorf("no tokens available")
 	}
-
-	if err := keyboard.Open(); err != nil {
-		return "", err
+	if !configs.Interactive() {
+		return "", fmt.Errorf("no terminal to select a token, pass --token-index or set %s instead", configs.EnvVarToken)
 	}
-	defer keyboard.Close()
-
-	selectedIndex := 0
-	for {
-		fmt.Print("\033[H\033[2J") // Clear screen
-
-		pterm.DefaultHeader.WithFullWidth().
-			WithBackgroundStyle(pterm.NewStyle(pterm.BgDarkGray)).
-			WithTextStyle(pterm.NewStyle(pterm.FgLightWhite)).
-			Println("Select a token:")
-
-		// Display available tokens
-		for i, token := range tokens {
-			maskedToken := maskToken(token.Token)
-			if i == selectedIndex {
-				pterm.Printf("→ %d: %s\n", i+1, maskedToken)
-			} else {
-				pterm.Printf("  %d: %s\n", i+1, maskedToken)
-			}
-		}
-
-		pterm.DefaultBasicText.WithStyle(pterm.NewStyle(pterm.FgGray)).
-			Println("\nNavigation: [j]down [k]up [Enter]select [q]quit")
-
-		char, key, err := keyboard.GetKey()
-		if err != nil {
-			return "", err
-		}
-
-		switch key {
-		case keyboard.KeyEnter:
-			return tokens[selectedIndex].Token, nil
-		}
 
-		switch char {
-		case 'j':
-			if selectedIndex < len(tokens)-1 {
-				selectedIndex++
-			}
-		case 'k':
-			if selectedIndex > 0 {
-				selectedIndex--
-			}
-		case 'q', 'Q':
-			return "", fmt.Errorf("selection cancelled")
-		}
+	token, err := ui.Select[TokenInfo]{
+		Title: "Select a token",
+		Items: tokens,
+		Label: func(t TokenInfo) string { return maskToken(t.Token) },
+	}.Choose()
+	if err != nil {
+		return "", err
 	}
+	return token.Token, nil
 }
 
 // maskToken returns a masked version of the token for display
@@ -266,35 +260,37 @@ func maskToken(token string) string {
 
 // executeAppLogin handles login for app environments
 func executeAppLogin(currentEnv string) error {
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".cfctl", "config.yaml")
-
-	viper.SetConfigFile(configPath)
-	if err := viper.ReadInConfig(); err != nil && !os.IsNotExist(err) {
+	v := viper.New()
+	if err := loadSetting(v, filepath.Join(GetSettingDir(), "setting.yaml")); err != nil {
 		return err
 	}
 
-	envPath := fmt.Sprintf("environments.%s.tokens", currentEnv)
+	envConfig, err := configs.DecodeEnvironment(v, currentEnv)
+	if err != nil {
+		return err
+	}
 	var tokens []TokenInfo
-	if tokensList := viper.Get(envPath); tokensList != nil {
-		if tokenList, ok := tokensList.([]interface{}); ok {
-			for _, t := range tokenList {
-				if tokenMap, ok := t.(map[string]interface{}); ok {
-					tokenInfo := TokenInfo{
-						Token: tokenMap["token"].(string),
-					}
-					tokens = append(tokens, tokenInfo)
-				}
-			}
-		}
+	for _, t := range envConfig.Tokens {
+		tokens = append(tokens, TokenInfo{Token: t.Token})
 	}
 
-	if err := keyboard.Open(); err != nil {
-		return err
+	if loginTokenIndex != 0 {
+		if loginTokenIndex < 1 || loginTokenIndex > len(tokens) {
+			return fmt.Errorf("--token-index must be between 1 and %d, the number of stored tokens", len(tokens))
+		}
+		selectedToken := tokens[loginTokenIndex-1].Token
+		if _, err := validateAndDecodeToken(selectedToken); err != nil {
+			return fmt.Errorf("token %d is invalid: %v", loginTokenIndex, err)
+		}
+		if err := saveSelectedToken(currentEnv, selectedToken); err != nil {
+			return fmt.Errorf("failed to save selected token: %v", err)
+		}
+		pterm.Success.Printf("Token successfully selected\n")
+		return nil
+	}
+	if !configs.Interactive() {
+		return fmt.Errorf("no terminal to select a token, pass --token-index or set %s instead", configs.EnvVarToken)
 	}
-	defer keyboard.Close()
-
-	selectedIndex := 0
 	options := []string{"Enter a new token"}
 	var validTokens []TokenInfo // New slice to store only valid tokens
 
@@ -318,79 +314,42 @@ func executeAppLogin(currentEnv string) error {
 		}
 	}
 
-	for {
-		fmt.Print("\033[H\033[2J") // Clear screen
-
-		pterm.DefaultHeader.WithFullWidth().
-			WithBackgroundStyle(pterm.NewStyle(pterm.BgDarkGray)).
-			WithTextStyle(pterm.NewStyle(pterm.FgLightWhite)).
-			Println("Choose an option:")
-
-		for i, option := range options {
-			if i == selectedIndex {
-				pterm.Printf("→ %d: %s\n", i, option)
-			} else {
-				pterm.Printf("  %d: %s\n", i, option)
-			}
-		}
-
-		pterm.DefaultBasicText.WithStyle(pterm.NewStyle(pterm.FgGray)).
-			Println("\nNavigation: [j]down [k]up [Enter]select [q]uit")
+	selectedIndex, err := ui.Select[string]{Title: "Choose an option", Items: options}.Run()
+	if err != nil {
+		return err
+	}
 
-		char, key, err := keyboard.GetKey()
+	if selectedIndex == 0 {
+		// Enter a new token
+		token, err := promptToken()
 		if err != nil {
 			return err
 		}
 
-		switch key {
-		case keyboard.KeyEnter:
-			if selectedIndex == 0 {
-				// Enter a new token
-				token, err := promptToken()
-				if err != nil {
-					return err
-				}
-
-				// Validate new token before saving
-				if _, err := validateAndDecodeToken(token); err != nil {
-					return fmt.Errorf("invalid token: %v", err)
-				}
-
-				// First save to tokens array
-				if err := saveAppToken(currentEnv, token); err != nil {
-					return err
-				}
-				// Then set as current token
-				if err := saveSelectedToken(currentEnv, token); err != nil {
-					return err
-				}
-				pterm.Success.Printf("Token successfully saved and selected\n")
-				return nil
-			} else {
-				// Use selected token from existing valid tokens
-				selectedToken := validTokens[selectedIndex-1].Token
-				if err := saveSelectedToken(currentEnv, selectedToken); err != nil {
-					return fmt.Errorf("failed to save selected token: %v", err)
-				}
-				pterm.Success.Printf("Token successfully selected\n")
-				return nil
-			}
+		// Validate new token before saving
+		if _, err := validateAndDecodeToken(token); err != nil {
+			return fmt.Errorf("invalid token: %v", err)
 		}
 
-		switch char {
-		case 'j':
-			if selectedIndex < len(options)-1 {
-				selectedIndex++
-			}
-		case 'k':
-			if selectedIndex > 0 {
-				selectedIndex--
-			}
-		case 'q', 'Q':
-			pterm.Error.Println("Selection cancelled.")
-			os.Exit(1)
+		// First save to tokens array
+		if err := saveAppToken(currentEnv, token); err != nil {
+			return err
 		}
+		// Then set as current token
+		if err := saveSelectedToken(currentEnv, token); err != nil {
+			return err
+		}
+		pterm.Success.Printf("Token successfully saved and selected\n")
+		return nil
+	}
+
+	// Use selected token from existing valid tokens
+	selectedToken := validTokens[selectedIndex-1].Token
+	if err := saveSelectedToken(currentEnv, selectedToken); err != nil {
+		return fmt.Errorf("failed to save selected token: %v", err)
 	}
+	pterm.Success.Printf("Token successfully selected\n")
+	return nil
 }
 
 func getTokenDisplayName(claims map[string]interface{}) string {
@@ -414,13 +373,8 @@ func executeUserLogin(currentEnv string) {
 		exitWithError()
 	}
 
-	homeDir, _ := os.UserHomeDir()
-	mainViper := viper.New()
-	settingPath := filepath.Join(homeDir, ".cfctl", "setting.yaml")
-	mainViper.SetConfigFile(settingPath)
-	mainViper.SetConfigType("yaml")
-
-	if err := mainViper.ReadInConfig(); err != nil {
+	mainViper, err := readSettingViper()
+	if err != nil {
 		pterm.Error.Printf("Failed to read config file: %v\n", err)
 		exitWithError()
 	}
@@ -440,31 +394,36 @@ func executeUserLogin(currentEnv string) {
 		exitWithError()
 	}
 
-	var scope string
-	if !hasIdentityService {
-		client := &http.Client{}
+	// The same identity calls go over gRPC or the REST gateway depending on the endpoint
+	identityClient, err := newIdentityClient(restIdentityEndpoint, identityEndpoint, hasIdentityService)
+	if err != nil {
+		pterm.Error.Printf("Failed to create identity client: %v\n", err)
+		exitWithError()
+	}
 
-		// Check for existing user_id in config
-		userID := mainViper.GetString(fmt.Sprintf("environments.%s.user_id", currentEnv))
+	if !hasIdentityService {
+		// Select one of the stored accounts, or enter a new user ID
+		userID := selectStoredUser(mainViper, currentEnv)
 		var tempUserID string
 		if userID == "" {
-			userIDInput := pterm.DefaultInteractiveTextInput
-			tempUserID, _ = userIDInput.Show("Enter your User ID")
+			tempUserID = promptUserID()
 		} else {
 			tempUserID = userID
 			pterm.Info.Printf("Logging in as: %s\n", userID)
 		}
 
-		var accessToken, refreshToken string
+		var accessToken, refreshToken, password string
 		existingAccessToken, existingRefreshToken, err := getValidTokens(currentEnv)
-		if err == nil && existingRefreshToken != "" && !isTokenExpired(existingRefreshToken) {
+		if err == nil && existingRefreshToken != "" && !token.IsExpired(existingRefreshToken) {
+			if regrantExpiredToken(currentEnv, identityClient, existingAccessToken, existingRefreshToken) {
+				recordUserLogin(mainViper, currentEnv, tempUserID)
+				return
+			}
 			accessToken = existingAccessToken
 			refreshToken = existingRefreshToken
+			pterm.Info.Println("Resuming login with the cached refresh token.")
 		} else {
-			passwordInput := pterm.DefaultInteractiveTextInput.WithMask("*")
-			password, _ := passwordInput.Show("Enter your password")
-
-			endpoint := mainViper.GetString(fmt.Sprintf("environments.%s.endpoint", currentEnv))
+			endpoint := configs.SettingString(mainViper, fmt.Sprintf("environments.%s.endpoint", currentEnv))
 			if endpoint == "" {
 				pterm.Error.Println("endpoint not found in configuration")
 				exitWithError()
@@ -480,77 +439,27 @@ func executeUserLogin(currentEnv string) {
 			}
 			domainName := parts[0]
 
-			domainPayload := map[string]string{"name": domainName}
-			jsonPayload, _ := json.Marshal(domainPayload)
-
-			req, err := http.NewRequest("POST", restIdentityEndpoint+"/domain/get-auth-info", bytes.NewBuffer(jsonPayload))
-			if err != nil {
-				pterm.Error.Printf("Failed to create request: %v\n", err)
-				exitWithError()
-			}
-			req.Header.Set("Content-Type", "application/json")
-
-			resp, err := client.Do(req)
+			domainID, err := fetchDomainID(identityClient, domainName)
 			if err != nil {
 				pterm.Error.Printf("Failed to fetch domain info: %v\n", err)
 				exitWithError()
 			}
-			defer resp.Body.Close()
-
-			var result map[string]interface{}
-			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-				pterm.Error.Printf("Failed to decode response: %v\n", err)
-				exitWithError()
-			}
-
-			domainID, ok := result["domain_id"].(string)
-			if !ok {
-				pterm.Error.Println("Domain ID not found in response")
-				exitWithError()
-			}
-
-			tokenPayload := map[string]interface{}{
-				"credentials": map[string]string{
-					"user_id":  tempUserID,
-					"password": password,
-				},
-				"auth_type": "LOCAL",
-				"domain_id": domainID,
-			}
-
-			jsonPayload, _ = json.Marshal(tokenPayload)
-			req, _ = http.NewRequest("POST", restIdentityEndpoint+"/token/issue", bytes.NewBuffer(jsonPayload))
-			req.Header.Set("Content-Type", "application/json")
 
-			resp, err = client.Do(req)
+			accessToken, refreshToken, password, err = issueUserToken(mainViper, currentEnv, identityClient, tempUserID, domainID)
 			if err != nil {
 				pterm.Error.Printf("Failed to issue token: %v\n", err)
 				exitWithError()
 			}
-			defer resp.Body.Close()
-
-			var tokenResult map[string]interface{}
-			if err := json.NewDecoder(resp.Body).Decode(&tokenResult); err != nil {
-				pterm.Error.Printf("Failed to decode token response: %v\n", err)
-				exitWithError()
-			}
-
-			accessToken, ok = tokenResult["access_token"].(string)
-			if !ok {
-				pterm.Error.Println("Access token not found in response")
-				exitWithError()
-			}
 
-			refreshToken, ok = tokenResult["refresh_token"].(string)
-			if !ok {
-				pterm.Error.Println("Refresh token not found in response")
+			// Persist the issued tokens so a failed grant can be resumed
+			if err := saveIssuedTokens(mainViper, currentEnv, accessToken, refreshToken); err != nil {
+				pterm.Error.Printf("Failed to save issued tokens: %v\n", err)
 				exitWithError()
 			}
 		}
 
-		if userID == "" {
-			mainViper.Set(fmt.Sprintf("environments.%s.user_id", currentEnv), tempUserID)
-			if err := mainViper.WriteConfig(); err != nil {
+		if userID == "" && shouldSaveCredentials(mainViper, currentEnv) {
+			if err := rememberUser(mainViper, currentEnv, tempUserID); err != nil {
 				pterm.Error.Printf("Failed to save user ID to config: %v\n", err)
 				exitWithError()
 			}
@@ -563,62 +472,37 @@ func executeUserLogin(currentEnv string) {
 			exitWithError()
 		}
 
-		// Create cache directory and save tokens
-		envCacheDir := filepath.Join(homeDir, ".cfctl", "cache", currentEnv)
-		if err := os.MkdirAll(envCacheDir, 0700); err != nil {
-			pterm.Error.Printf("Failed to create cache directory: %v\n", err)
-			exitWithError()
-		}
-
 		pterm.Info.Printf("Logged in as %s\n", tempUserID)
 
 		// Use the tokens to fetch workspaces and role
-		workspaces, err := fetchWorkspaces(restIdentityEndpoint, identityEndpoint, hasIdentityService, accessToken)
+		workspaces, domainID, roleType, err := fetchWorkspacesAndRole(identityClient, accessToken)
 		if err != nil {
-			pterm.Error.Println("Failed to fetch workspaces:", err)
+			pterm.Error.Println(err)
 			exitWithError()
 		}
 
-		domainID, roleType, err := fetchDomainIDAndRole(restIdentityEndpoint, identityEndpoint, hasIdentityService, accessToken)
+		// Determine scope and select workspace
+		scope, workspaceID, err := selectLoginScope(workspaces, roleType)
 		if err != nil {
-			pterm.Error.Println("Failed to fetch Domain ID and Role Type:", err)
+			pterm.Error.Println(err)
 			exitWithError()
 		}
 
-		// Determine scope and select workspace
-		scope = determineScope(roleType, len(workspaces))
-		var workspaceID string
-		if roleType == "DOMAIN_ADMIN" {
-			workspaceID = selectScopeOrWorkspace(workspaces, roleType)
-			if workspaceID == "0" {
-				scope = "DOMAIN"
-				workspaceID = ""
-			} else {
-				scope = "WORKSPACE"
-			}
-		} else {
-			workspaceID = selectWorkspaceOnly(workspaces)
-			scope = "WORKSPACE"
-		}
-
 		// Grant new token using the refresh token
-		newAccessToken, err := grantToken(restIdentityEndpoint, identityEndpoint, hasIdentityService, refreshToken, scope, domainID, workspaceID)
+		newAccessToken, err := grantToken(identityClient, refreshToken, scope, domainID, workspaceID)
 		if err != nil {
 			pterm.Error.Println("Failed to retrieve new access token:", err)
 			exitWithError()
 		}
 
-		// Save all tokens
-		if err := os.WriteFile(filepath.Join(envCacheDir, "refresh_token"), []byte(refreshToken), 0600); err != nil {
-			pterm.Error.Printf("Failed to save refresh token: %v\n", err)
+		recordUserLogin(mainViper, currentEnv, tempUserID)
+		if err := saveLoginTokens(mainViper, currentEnv, newAccessToken, refreshToken, workspaceLabel(workspaces, workspaceID)); err != nil {
+			pterm.Error.Println(err)
 			exitWithError()
 		}
-
-		if err := os.WriteFile(filepath.Join(envCacheDir, "access_token"), []byte(newAccessToken), 0600); err != nil {
-			pterm.Error.Printf("Failed to save access token: %v\n", err)
-			exitWithError()
+		if err := cachePassword(mainViper, currentEnv, tempUserID, password); err != nil {
+			pterm.Warning.Printf("Failed to save the password: %v\n", err)
 		}
-
 		pterm.Success.Println("Successfully logged in and saved token.")
 		return
 	} else {
@@ -630,99 +514,84 @@ func executeUserLogin(currentEnv string) {
 		}
 		name := nameParts[0]
 
-		// Check for existing user_id in config
-		userID := mainViper.GetString(fmt.Sprintf("environments.%s.user_id", currentEnv))
+		// Select one of the stored accounts, or enter a new user ID
+		userID := selectStoredUser(mainViper, currentEnv)
 		var tempUserID string
 
 		if userID == "" {
-			userIDInput := pterm.DefaultInteractiveTextInput
-			tempUserID, _ = userIDInput.Show("Enter your User ID")
+			tempUserID = promptUserID()
 		} else {
 			tempUserID = userID
 			pterm.Info.Printf("Logging in as: %s\n", userID)
 		}
 
 		// Fetch Domain ID
-		domainID, err := fetchDomainID(identityEndpoint, name)
+		domainID, err := fetchDomainID(identityClient, name)
 		if err != nil {
 			pterm.Error.Println("Failed to fetch Domain ID:", err)
 			exitWithError()
 		}
 
+		var password string
 		accessToken, refreshToken, err := getValidTokens(currentEnv)
-		if err != nil || refreshToken == "" || isTokenExpired(refreshToken) {
+		if err != nil || refreshToken == "" || token.IsExpired(refreshToken) {
 			// Get new tokens with password
-			password := promptPassword()
-			accessToken, refreshToken, err = issueToken(identityEndpoint, tempUserID, password, domainID)
+			accessToken, refreshToken, password, err = issueUserToken(mainViper, currentEnv, identityClient, tempUserID, domainID)
 			if err != nil {
 				pterm.Error.Printf("Failed to issue token: %v\n", err)
 				exitWithError()
 			}
 
+			// Persist the issued tokens so a failed grant can be resumed
+			if err := saveIssuedTokens(mainViper, currentEnv, accessToken, refreshToken); err != nil {
+				pterm.Error.Printf("Failed to save issued tokens: %v\n", err)
+				exitWithError()
+			}
+
 			// Only save user_id after successful token issue
-			if userID == "" {
-				mainViper.Set(fmt.Sprintf("environments.%s.user_id", currentEnv), tempUserID)
-				if err := mainViper.WriteConfig(); err != nil {
+			if userID == "" && shouldSaveCredentials(mainViper, currentEnv) {
+				if err := rememberUser(mainViper, currentEnv, tempUserID); err != nil {
 					pterm.Error.Printf("Failed to save user ID to config: %v\n", err)
 					exitWithError()
 				}
 			}
+		} else {
+			if regrantExpiredToken(currentEnv, identityClient, accessToken, refreshToken) {
+				recordUserLogin(mainViper, currentEnv, tempUserID)
+				return
+			}
+			pterm.Info.Println("Resuming login with the cached refresh token.")
 		}
 
 		// Use the tokens to fetch workspaces and role
-		workspaces, err := fetchWorkspaces(restIdentityEndpoint, identityEndpoint, hasIdentityService, accessToken)
+		workspaces, domainID, roleType, err := fetchWorkspacesAndRole(identityClient, accessToken)
 		if err != nil {
-			pterm.Error.Println("Failed to fetch workspaces:", err)
+			pterm.Error.Println(err)
 			exitWithError()
 		}
 
-		domainID, roleType, err := fetchDomainIDAndRole(restIdentityEndpoint, identityEndpoint, hasIdentityService, accessToken)
+		// Determine scope and select workspace
+		scope, workspaceID, err := selectLoginScope(workspaces, roleType)
 		if err != nil {
-			pterm.Error.Println("Failed to fetch Domain ID and Role Type:", err)
+			pterm.Error.Println(err)
 			exitWithError()
 		}
 
-		// Determine scope and select workspace
-		scope = determineScope(roleType, len(workspaces))
-		var workspaceID string
-		if roleType == "DOMAIN_ADMIN" {
-			workspaceID = selectScopeOrWorkspace(workspaces, roleType)
-			if workspaceID == "0" {
-				scope = "DOMAIN"
-				workspaceID = ""
-			} else {
-				scope = "WORKSPACE"
-			}
-		} else {
-			workspaceID = selectWorkspaceOnly(workspaces)
-			scope = "WORKSPACE"
-		}
-
 		// Grant new token using the refresh token
-		newAccessToken, err := grantToken("", identityEndpoint, hasIdentityService, refreshToken, scope, domainID, workspaceID)
+		newAccessToken, err := grantToken(identityClient, refreshToken, scope, domainID, workspaceID)
 		if err != nil {
 			pterm.Error.Println("Failed to retrieve new access token:", err)
 			exitWithError()
 		}
 
-		// Create cache directory
-		envCacheDir := filepath.Join(homeDir, ".cfctl", "cache", currentEnv)
-		if err := os.MkdirAll(envCacheDir, 0700); err != nil {
-			pterm.Error.Printf("Failed to create cache directory: %v\n", err)
-			exitWithError()
-		}
-
-		// Save tokens
-		if err := os.WriteFile(filepath.Join(envCacheDir, "refresh_token"), []byte(refreshToken), 0600); err != nil {
-			pterm.Error.Printf("Failed to save refresh token: %v\n", err)
+		recordUserLogin(mainViper, currentEnv, tempUserID)
+		if err := saveLoginTokens(mainViper, currentEnv, newAccessToken, refreshToken, workspaceLabel(workspaces, workspaceID)); err != nil {
+			pterm.Error.Println(err)
 			exitWithError()
 		}
-
-		if err := os.WriteFile(filepath.Join(envCacheDir, "access_token"), []byte(newAccessToken), 0600); err != nil {
-			pterm.Error.Printf("Failed to save access token: %v\n", err)
-			exitWithError()
+		if err := cachePassword(mainViper, currentEnv, tempUserID, password); err != nil {
+			pterm.Warning.Printf("Failed to save the password: %v\n", err)
 		}
-
 		pterm.Success.Println("Successfully logged in and saved token.")
 	}
 }
@@ -759,7 +628,7 @@ func GetIdentityEndpoint(apiEndpoint string) (string, bool, error) {
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := &http.Client{Transport: netproxy.Transport()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", false, fmt.Errorf("failed to fetch endpoints: %v", err)
@@ -790,94 +659,47 @@ func GetIdentityEndpoint(apiEndpoint string) (string, bool, error) {
 	return "", false, nil
 }
 
-// Prompt for password when token is expired
-func promptPassword() string {
-	passwordInput := pterm.DefaultInteractiveTextInput.WithMask("*")
-	password, _ := passwordInput.Show("Enter your password")
-	return password
-}
-
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
+// shouldSaveCredentials reports whether the user entry may be written to the setting file.
+// It is disabled by --no-save, 'credential_cache: none', the --no-save-credentials flag or by
+// setting 'save_credentials: false' on the environment.
+func shouldSaveCredentials(v *viper.Viper, currentEnv string) bool {
+	if noSaveCredentials || credentialCacheMode(v, currentEnv) == configs.CredentialCacheNone {
+		return false
 	}
-	return b
-}
-
-func getEncryptionKey() ([]byte, error) {
-	key, err := keyring.Get(keyringService, keyringUser)
-	if err == keyring.ErrNotFound {
-		newKey := make([]byte, 32)
-		if _, err := rand.Read(newKey); err != nil {
-			return nil, fmt.Errorf("failed to generate new key: %v", err)
-		}
-
-		encodedKey := base64.StdEncoding.EncodeToString(newKey)
-		if err := keyring.Set(keyringService, keyringUser, encodedKey); err != nil {
-			return nil, fmt.Errorf("failed to store key in keychain: %v", err)
-		}
 
-		return newKey, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to access keychain: %v", err)
+	envConfig, err := configs.DecodeEnvironment(v, currentEnv)
+	if err != nil || envConfig.SaveCredentials == nil {
+		return true
 	}
-
-	return base64.StdEncoding.DecodeString(key)
+	return *envConfig.SaveCredentials
 }
 
-func encrypt(text string) (string, error) {
-	key, err := getEncryptionKey()
-	if err != nil {
-		return "", fmt.Errorf("failed to get encryption key: %v", err)
-	}
-
-	plaintext := []byte(text)
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
-
-	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return "", err
+// promptUserID asks for the user ID to log in with
+func promptUserID() string {
+	if !configs.Interactive() {
+		pterm.Error.Println("No terminal to enter the user ID, pass it with --user.")
+		exitWithError()
 	}
-
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], plaintext)
-
-	return base64.URLEncoding.EncodeToString(ciphertext), nil
+	userID, _ := ui.Input("Enter your User ID", "")
+	return userID
 }
 
-func decrypt(cryptoText string) (string, error) {
-	key, err := getEncryptionKey()
-	if err != nil {
-		return "", fmt.Errorf("failed to get encryption key: %v", err)
-	}
-
-	ciphertext, err := base64.URLEncoding.DecodeString(cryptoText)
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
+// Prompt for password when token is expired
+func promptPassword() string {
+	if !configs.Interactive() {
+		pterm.Error.Printf("No terminal to enter the password. Log in from a terminal once to cache a refresh token, or use --api-key, --oidc or %s.\n", configs.EnvVarToken)
+		exitWithError()
 	}
+	password, _ := ui.Password("Enter your password")
+	return password
+}
 
-	if len(ciphertext) < aes.BlockSize {
-		return "", errors.New("ciphertext too short")
+// min returns the minimum of two integers
+func min(a, b int) int {
+	if a < b {
+		return a
 	}
-
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-
-	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(ciphertext, ciphertext)
-
-	return string(ciphertext), nil
+	return b
 }
 
 // Define a struct for user credentials
@@ -889,19 +711,9 @@ type UserCredentials struct {
 
 // saveCredentials saves the user's credentials to the configuration
 func saveCredentials(currentEnv, userID, encryptedPassword, accessToken, refreshToken, grantToken string) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		pterm.Error.Println("Failed to get home directory:", err)
-		exitWithError()
-	}
-
 	// Update main settings file
-	settingPath := filepath.Join(homeDir, ".cfctl", "setting.yaml")
-	mainViper := viper.New()
-	mainViper.SetConfigFile(settingPath)
-	mainViper.SetConfigType("yaml")
-
-	if err := mainViper.ReadInConfig(); err != nil {
+	mainViper, err := readSettingViper()
+	if err != nil {
 		pterm.Error.Printf("Failed to read config file: %v\n", err)
 		exitWithError()
 	}
@@ -909,66 +721,49 @@ func saveCredentials(currentEnv, userID, encryptedPassword, accessToken, refresh
 	// Save user_id to environment settings
 	envPath := fmt.Sprintf("environments.%s.user_id", currentEnv)
 	mainViper.Set(envPath, userID)
-
-	if err := mainViper.WriteConfig(); err != nil {
-		pterm.Error.Printf("Failed to save config file: %v\n", err)
-		exitWithError()
-	}
+	configs.MarkSettingDirty()
 
 	// Create cache directory
-	envCacheDir := filepath.Join(homeDir, ".cfctl", "cache", currentEnv)
-	if err := os.MkdirAll(envCacheDir, 0700); err != nil {
+	envCacheDir := filepath.Join(GetSettingDir(), "cache", currentEnv)
+	if err := configs.EnsureSecureDir(envCacheDir); err != nil {
 		pterm.Error.Printf("Failed to create cache directory: %v\n", err)
 		exitWithError()
 	}
 
 	// Save tokens to cache
-	if err := os.WriteFile(filepath.Join(envCacheDir, "access_token"), []byte(accessToken), 0600); err != nil {
-		pterm.Error.Printf("Failed to save access token: %v\n", err)
+	if err := configs.StoreAccessToken(currentEnv, accessToken, ""); err != nil {
+		pterm.Error.Println(err)
 		exitWithError()
 	}
 
 	if refreshToken != "" {
-		if err := os.WriteFile(filepath.Join(envCacheDir, "refresh_token"), []byte(refreshToken), 0600); err != nil {
+		if err := configs.WriteCachedToken(filepath.Join(envCacheDir, "refresh_token"), refreshToken); err != nil {
 			pterm.Error.Printf("Failed to save refresh token: %v\n", err)
 			exitWithError()
 		}
 	}
 
 	if grantToken != "" {
-		if err := os.WriteFile(filepath.Join(envCacheDir, "grant_token"), []byte(grantToken), 0600); err != nil {
+		if err := configs.WriteCachedToken(filepath.Join(envCacheDir, "grant_token"), grantToken); err != nil {
 			pterm.Error.Printf("Failed to save grant token: %v\n", err)
 			exitWithError()
 		}
 	}
 }
 
-func verifyAppToken(token string) (map[string]interface{}, bool) {
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		pterm.Error.Println("Invalid token format")
-		return nil, false
-	}
-
-	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+func verifyAppToken(appToken string) (map[string]interface{}, bool) {
+	claims, err := token.Decode(appToken)
 	if err != nil {
-		pterm.Error.Println("Failed to decode token payload:", err)
-		return nil, false
-	}
-
-	var claims map[string]interface{}
-	if err := json.Unmarshal(payload, &claims); err != nil {
-		pterm.Error.Println("Failed to parse token payload:", err)
+		pterm.Error.Println(err)
 		return nil, false
 	}
 
-	exp, ok := claims["exp"].(float64)
-	if !ok {
+	if _, ok := claims.ExpiresAt(); !ok {
 		pterm.Error.Println("Expiration time not found in token")
 		return nil, false
 	}
 
-	if time.Now().After(time.Unix(int64(exp), 0)) {
+	if claims.Expired() {
 		pterm.DefaultBox.WithTitle("Expired App Token").
 			WithTitleTopCenter().
 			WithRightPadding(4).
@@ -999,43 +794,26 @@ func verifyAppToken(token string) (map[string]interface{}, bool) {
 
 // Load environment-specific configuration based on the selected environment
 func loadEnvironmentConfig() {
-	homeDir, err := os.UserHomeDir()
+	v, err := readSettingViper()
 	if err != nil {
-		pterm.Error.Println("Failed to get user home directory:", err)
+		pterm.Error.Printf("Failed to read setting file: %v\n", err)
 		exitWithError()
 	}
 
-	settingPath := filepath.Join(homeDir, ".cfctl", "setting.yaml")
-	viper.SetConfigFile(settingPath)
-	viper.SetConfigType("yaml")
-
-	if err := viper.ReadInConfig(); err != nil {
-		pterm.Error.Printf("Failed to read setting file: %v\n", err)
-		exitWithError()
-	}
-
-	currentEnv := viper.GetString("environment")
+	currentEnv := v.GetString("environment")
 	if currentEnv == "" {
 		pterm.Error.Println("No environment selected")
 		exitWithError()
 	}
 
-	v := viper.New()
-	v.SetConfigFile(settingPath)
-	if err := v.ReadInConfig(); err == nil {
-		endpointKey := fmt.Sprintf("environments.%s.endpoint", currentEnv)
-		tokenKey := fmt.Sprintf("environments.%s.token", currentEnv)
-
-		if providedUrl == "" {
-			providedUrl = v.GetString(endpointKey)
-		}
-
-		if token := v.GetString(tokenKey); token != "" {
-			viper.Set("token", token)
-		}
+	if providedUrl == "" {
+		providedUrl = configs.SettingString(v, fmt.Sprintf("environments.%s.endpoint", currentEnv))
+	}
+	if token := configs.SettingString(v, fmt.Sprintf("environments.%s.token", currentEnv)); token != "" {
+		viper.Set("token", token)
 	}
 
-	isProxyEnabled := viper.GetBool(fmt.Sprintf("environments.%s.proxy", currentEnv))
+	isProxyEnabled := configs.SettingBool(v, fmt.Sprintf("environments.%s.proxy", currentEnv))
 	containsIdentity := strings.Contains(strings.ToLower(providedUrl), "identity")
 
 	if !isProxyEnabled && !containsIdentity {
@@ -1066,939 +844,362 @@ func determineScope(roleType string, workspaceCount int) string {
 	}
 }
 
-// isTokenExpired checks if the token is expired
-func isTokenExpired(token string) bool {
-	claims, err := decodeJWT(token)
-	if err != nil {
-		return true // 디코딩 실패 시 만료된 것으로 간주
-	}
-
-	if exp, ok := claims["exp"].(float64); ok {
-		return time.Now().Unix() > int64(exp)
-	}
-	return true
-}
-
 func verifyToken(token string) bool {
 	// This function should implement token verification logic, for example by making a request to an endpoint that requires authentication
 	// Returning true for simplicity in this example
 	return true
 }
 
+// requireInteractive stops a command that has to prompt when no terminal is attached, e.g. in
+// containers, instead of waiting for keys that never come
+func requireInteractive(action string) {
+	if configs.Interactive() {
+		return
+	}
+	pterm.Error.Printf("Cannot %s without a terminal.\n", action)
+	exitWithError()
+}
+
 func exitWithError() {
-	os.Exit(1)
+	// The setting changes made before the failure, e.g. the stored user, are kept on exit
+	cleanup.Exit(1)
 }
 
-func fetchDomainID(baseUrl string, name string) (string, error) {
-	// Parse the endpoint
-	parts := strings.Split(baseUrl, "://")
-	if len(parts) != 2 {
-		return "", fmt.Errorf("invalid endpoint format: %s", baseUrl)
+// newIdentityClient returns the client of the identity service, over gRPC when the console
+// exposes the identity endpoint and over its REST gateway otherwise
+func newIdentityClient(restIdentityEndpoint, identityEndpoint string, hasIdentityService bool) (apiclient.Client, error) {
+	if hasIdentityService {
+		return apiclient.New(identityEndpoint, "identity")
 	}
+	return apiclient.New(restIdentityEndpoint, "identity")
+}
 
-	hostPort := parts[1]
-
-	// Configure gRPC connection
-	var opts []grpc.DialOption
-	if strings.HasPrefix(baseUrl, "grpc+ssl://") {
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: false,
-		}
-		creds := credentials.NewTLS(tlsConfig)
-		opts = append(opts, grpc.WithTransportCredentials(creds))
-	} else {
-		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// identityClientFromSetting returns the identity client of the endpoint of the current environment
+func identityClientFromSetting() (apiclient.Client, error) {
+	loadEnvironmentConfig()
+	if providedUrl == "" {
+		return nil, fmt.Errorf("no endpoint specified in the configuration file")
 	}
 
-	// Establish connection
-	conn, err := grpc.Dial(hostPort, opts...)
+	apiEndpoint, err := configs.GetAPIEndpoint(providedUrl)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect: %v", err)
+		return nil, fmt.Errorf("failed to get API endpoint: %v", err)
 	}
-	defer conn.Close()
-
-	// Create reflection client
-	refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
-	defer refClient.Reset()
-
-	// Resolve the service
-	serviceName := "spaceone.api.identity.v2.Domain"
-	serviceDesc, err := refClient.ResolveService(serviceName)
+	identityEndpoint, hasIdentityService, err := configs.GetIdentityEndpoint(apiEndpoint)
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve service %s: %v", serviceName, err)
+		return nil, fmt.Errorf("failed to get identity endpoint: %v", err)
 	}
+	return newIdentityClient(apiEndpoint+"/identity", identityEndpoint, hasIdentityService)
+}
 
-	// Find the method descriptor
-	methodDesc := serviceDesc.FindMethodByName("get_auth_info")
-	if methodDesc == nil {
-		return "", fmt.Errorf("method get_auth_info not found")
+// fetchDomainID returns the ID of the domain with the name
+func fetchDomainID(client apiclient.Client, name string) (string, error) {
+	resp, err := client.Call("Domain", "get_auth_info", map[string]interface{}{"name": name}, "")
+	if err != nil {
+		return "", err
 	}
 
-	// Create request message
-	reqMsg := dynamic.NewMessage(methodDesc.GetInputType())
-	reqMsg.SetFieldByName("name", name)
-
-	// Make the gRPC call
-	fullMethod := fmt.Sprintf("/%s/%s", serviceName, "get_auth_info")
-	respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
+	domainID, ok := resp["domain_id"].(string)
+	if !ok {
+		return "", fmt.Errorf("domain_id not found in response")
+	}
+	return domainID, nil
+}
 
-	err = conn.Invoke(context.Background(), fullMethod, reqMsg, respMsg)
-	if err != nil {
-		return "", fmt.Errorf("RPC failed: %v", err)
+// issueToken issues access and refresh tokens with the local credentials of the user.
+// verifyCode is the MFA code of accounts with MFA enabled.
+func issueToken(client apiclient.Client, userID, password, domainID, verifyCode string) (string, string, error) {
+	params := map[string]interface{}{
+		"credentials": map[string]interface{}{
+			"user_id":  userID,
+			"password": password,
+		},
+		"auth_type": "LOCAL",
+		"domain_id": domainID,
+	}
+	if verifyCode != "" {
+		params["verify_code"] = verifyCode
 	}
 
-	// Extract domain_id from response
-	domainID, err := respMsg.TryGetFieldByName("domain_id")
+	resp, err := client.Call("Token", "issue", params, "")
 	if err != nil {
-		return "", fmt.Errorf("failed to get domain_id from response: %v", err)
+		return "", "", err
 	}
 
-	return domainID.(string), nil
+	accessToken, ok := resp["access_token"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("access_token not found in response")
+	}
+	refreshToken, ok := resp["refresh_token"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("refresh_token not found in response")
+	}
+	return accessToken, refreshToken, nil
 }
 
-func issueToken(baseUrl, userID, password, domainID string) (string, string, error) {
-	// Parse the endpoint
-	parts := strings.Split(baseUrl, "://")
-	if len(parts) != 2 {
-		return "", "", fmt.Errorf("invalid endpoint format: %s", baseUrl)
+// issueTokenWithMFA issues the tokens and, when the account has MFA enabled, asks for the
+// verify code and retries. A code given with --mfa-code is sent with the first request.
+func issueTokenWithMFA(client apiclient.Client, userID, password, domainID string) (string, string, error) {
+	accessToken, refreshToken, err := issueToken(client, userID, password, domainID, mfaCode)
+	if err == nil || !isMFARequired(err) {
+		return accessToken, refreshToken, err
 	}
-
-	hostPort := parts[1]
-
-	// Configure gRPC connection
-	var opts []grpc.DialOption
-	if strings.HasPrefix(baseUrl, "grpc+ssl://") {
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: false,
-		}
-		creds := credentials.NewTLS(tlsConfig)
-		opts = append(opts, grpc.WithTransportCredentials(creds))
-	} else {
-		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if mfaCode != "" {
+		return "", "", fmt.Errorf("the MFA code was not accepted: %v", err)
 	}
 
-	// Establish connection
-	conn, err := grpc.Dial(hostPort, opts...)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to connect: %v", err)
+	if !configs.Interactive() {
+		return "", "", fmt.Errorf("MFA is enabled for this account and there is no terminal to enter the code, pass it with --mfa-code")
+	}
+	pterm.Info.Println("MFA is enabled for this account. Enter the verification code sent to you or shown in your authenticator app.")
+	code, _ := ui.Input("Enter the MFA code", "")
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return "", "", fmt.Errorf("no MFA code entered")
 	}
-	defer conn.Close()
+	return issueToken(client, userID, password, domainID, code)
+}
 
-	// Create reflection client
-	refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
-	defer refClient.Reset()
+// isMFARequired reports whether Token.issue failed because the account needs a verify code
+func isMFARequired(err error) bool {
+	msg := strings.ToUpper(err.Error())
+	return strings.Contains(msg, "MFA_REQUIRED") || strings.Contains(msg, "MFA REQUIRED") ||
+		strings.Contains(msg, "MFA IS REQUIRED") || strings.Contains(msg, "VERIFY_CODE")
+}
 
-	// Resolve the service
-	serviceName := "spaceone.api.identity.v2.Token"
-	serviceDesc, err := refClient.ResolveService(serviceName)
+// fetchWorkspaces returns the workspaces the user can access
+func fetchWorkspaces(client apiclient.Client, accessToken string) ([]map[string]interface{}, error) {
+	resp, err := client.Call("UserProfile", "get_workspaces", nil, accessToken)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to resolve service %s: %v", serviceName, err)
-	}
-
-	// Find the method descriptor
-	methodDesc := serviceDesc.FindMethodByName("issue")
-	if methodDesc == nil {
-		return "", "", fmt.Errorf("method issue not found")
-	}
-
-	// Create request message
-	reqMsg := dynamic.NewMessage(methodDesc.GetInputType())
-
-	// Create credentials struct using protobuf types
-	structpb := &structpb.Struct{
-		Fields: map[string]*structpb.Value{
-			"user_id": {
-				Kind: &structpb.Value_StringValue{
-					StringValue: userID,
-				},
-			},
-			"password": {
-				Kind: &structpb.Value_StringValue{
-					StringValue: password,
-				},
-			},
-		},
+		return nil, err
 	}
 
-	// Set all fields in the request message
-	reqMsg.SetFieldByName("credentials", structpb)
-	reqMsg.SetFieldByName("auth_type", int32(1)) // LOCAL = 1
-	reqMsg.SetFieldByName("timeout", int32(0))
-	reqMsg.SetFieldByName("verify_code", "")
-	reqMsg.SetFieldByName("domain_id", domainID)
-
-	// Make the gRPC call
-	fullMethod := fmt.Sprintf("/%s/%s", serviceName, "issue")
-	respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
-
-	err = conn.Invoke(context.Background(), fullMethod, reqMsg, respMsg)
-	if err != nil {
-		return "", "", fmt.Errorf("RPC failed: %v", err)
+	workspaces, ok := resp["results"].([]interface{})
+	if !ok || len(workspaces) == 0 {
+		pterm.Warning.Println("There are no accessible workspaces. Ask your administrators or workspace owners for access.")
+		exitWithError()
 	}
 
-	// Extract tokens from response
-	accessToken, err := respMsg.TryGetFieldByName("access_token")
-	if err != nil {
-		return "", "", fmt.Errorf("failed to get access_token from response: %v", err)
+	var workspaceList []map[string]interface{}
+	for _, workspace := range workspaces {
+		workspaceMap, ok := workspace.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("failed to parse workspace data")
+		}
+		workspaceList = append(workspaceList, workspaceMap)
 	}
+	return workspaceList, nil
+}
 
-	refreshToken, err := respMsg.TryGetFieldByName("refresh_token")
+// fetchDomainIDAndRole returns the domain ID and role type of the user
+func fetchDomainIDAndRole(client apiclient.Client, accessToken string) (string, string, error) {
+	resp, err := client.Call("UserProfile", "get", nil, accessToken)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get refresh_token from response: %v", err)
+		return "", "", err
 	}
 
-	return accessToken.(string), refreshToken.(string), nil
+	domainID, ok := resp["domain_id"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("domain_id not found in response")
+	}
+	roleType, ok := resp["role_type"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("role_type not found in response")
+	}
+	return domainID, roleType, nil
 }
 
-func fetchWorkspaces(baseUrl string, identityEndpoint string, hasIdentityService bool, accessToken string) ([]map[string]interface{}, error) {
-	if !hasIdentityService {
-		payload := map[string]string{}
-		jsonPayload, err := json.Marshal(payload)
-		if err != nil {
-			return nil, err
-		}
-
-		getWorkspacesUrl := baseUrl + "/user-profile/get-workspaces"
-		req, err := http.NewRequest("POST", getWorkspacesUrl, bytes.NewBuffer(jsonPayload))
-		if err != nil {
-			return nil, err
-		}
-
-		req.Header.Set("accept", "application/json")
-		req.Header.Set("Authorization", "Bearer "+accessToken)
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-
-		responseBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response body: %v", err)
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to fetch workspaces, status code: %d", resp.StatusCode)
-		}
-
-		var result map[string]interface{}
-		if err := json.Unmarshal(responseBody, &result); err != nil {
-			return nil, err
-		}
-
-		workspaces, ok := result["results"].([]interface{})
-		if !ok || len(workspaces) == 0 {
-			pterm.Warning.Println("There are no accessible workspaces. Ask your administrators or workspace owners for access.")
-			exitWithError()
-		}
-
-		var workspaceList []map[string]interface{}
-		for _, workspace := range workspaces {
-			workspaceMap, ok := workspace.(map[string]interface{})
-			if !ok {
-				return nil, fmt.Errorf("failed to parse workspace data")
-			}
-			workspaceList = append(workspaceList, workspaceMap)
-		}
-
-		return workspaceList, nil
-	} else {
-		// Parse the endpoint
-		parts := strings.Split(identityEndpoint, "://")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid endpoint format: %s", identityEndpoint)
-		}
-
-		hostPort := parts[1]
-
-		// Configure gRPC connection
-		var opts []grpc.DialOption
-		if strings.HasPrefix(identityEndpoint, "grpc+ssl://") {
-			tlsConfig := &tls.Config{
-				InsecureSkipVerify: false,
-			}
-			creds := credentials.NewTLS(tlsConfig)
-			opts = append(opts, grpc.WithTransportCredentials(creds))
-		} else if strings.HasPrefix(identityEndpoint, "grpc://") {
-			tlsConfig := &tls.Config{
-				InsecureSkipVerify: true,
-			}
-			creds := credentials.NewTLS(tlsConfig)
-			opts = append(opts, grpc.WithTransportCredentials(creds))
-		} else {
-			opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		}
-
-		// Add token credentials
-		creds := &tokenAuth{
-			token: accessToken,
-		}
-		opts = append(opts, grpc.WithPerRPCCredentials(creds))
-
-		// Establish connection
-		conn, err := grpc.Dial(hostPort, opts...)
-		if err != nil {
-			return nil, fmt.Errorf("failed to connect: %v", err)
-		}
-		defer conn.Close()
-
-		// Create reflection client
-		refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
-		defer refClient.Reset()
-
-		// Resolve the service
-		serviceName := "spaceone.api.identity.v2.UserProfile"
-		serviceDesc, err := refClient.ResolveService(serviceName)
-		if err != nil {
-			return nil, fmt.Errorf("failed to resolve service %s: %v", serviceName, err)
-		}
-
-		// Find the method descriptor
-		methodDesc := serviceDesc.FindMethodByName("get_workspaces")
-		if methodDesc == nil {
-			return nil, fmt.Errorf("method get_workspaces not found")
-		}
-
-		// Create request message
-		reqMsg := dynamic.NewMessage(methodDesc.GetInputType())
-
-		// Create metadata with token
-		md := metadata.New(map[string]string{
-			"token": accessToken,
-		})
-		ctx := metadata.NewOutgoingContext(context.Background(), md)
-
-		// Make the gRPC call
-		fullMethod := "/spaceone.api.identity.v2.UserProfile/get_workspaces"
-		respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
-
-		err = conn.Invoke(ctx, fullMethod, reqMsg, respMsg)
+// fetchWorkspacesAndRole fetches the accessible workspaces and the domain ID and role type
+// of the user concurrently. For gRPC identity endpoints both calls share one connection.
+func fetchWorkspacesAndRole(client apiclient.Client, accessToken string) ([]map[string]interface{}, string, string, error) {
+	var (
+		workspaces []map[string]interface{}
+		domainID   string
+		roleType   string
+		g          errgroup.Group
+	)
+
+	g.Go(func() error {
+		var err error
+		workspaces, err = fetchWorkspaces(client, accessToken)
 		if err != nil {
-			return nil, fmt.Errorf("RPC failed: %v", err)
+			return fmt.Errorf("failed to fetch workspaces: %v", err)
 		}
+		return nil
+	})
 
-		// Extract results from response
-		results, err := respMsg.TryGetFieldByName("results")
+	g.Go(func() error {
+		var err error
+		domainID, roleType, err = fetchDomainIDAndRole(client, accessToken)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get results from response: %v", err)
+			return fmt.Errorf("failed to fetch Domain ID and Role Type: %v", err)
 		}
+		return nil
+	})
 
-		workspaces, ok := results.([]interface{})
-		if !ok || len(workspaces) == 0 {
-			pterm.Warning.Println("There are no accessible workspaces. Ask your administrators or workspace owners for access.")
-			exitWithError()
-		}
+	if err := g.Wait(); err != nil {
+		return nil, "", "", err
+	}
 
-		var workspaceList []map[string]interface{}
-		for _, workspace := range workspaces {
-			workspaceMsg, ok := workspace.(*dynamic.Message)
-			if !ok {
-				return nil, fmt.Errorf("failed to parse workspace message")
-			}
+	return workspaces, domainID, roleType, nil
+}
 
-			workspaceMap := make(map[string]interface{})
-			fields := workspaceMsg.GetKnownFields()
+// grantToken grants an access token of the scope with the refresh token
+func grantToken(client apiclient.Client, refreshToken, scope, domainID, workspaceID string) (string, error) {
+	switch scope {
+	case "DOMAIN", "WORKSPACE", "USER":
+	default:
+		return "", fmt.Errorf("unknown scope: %s", scope)
+	}
 
-			for _, field := range fields {
-				if value, err := workspaceMsg.TryGetFieldByName(field.GetName()); err == nil {
-					workspaceMap[field.GetName()] = value
-				}
-			}
+	params := map[string]interface{}{
+		"grant_type": "REFRESH_TOKEN",
+		"token":      refreshToken,
+		"scope":      scope,
+		"timeout":    10800,
+		"domain_id":  domainID,
+	}
+	if workspaceID != "" {
+		params["workspace_id"] = workspaceID
+	}
 
-			workspaceList = append(workspaceList, workspaceMap)
-		}
+	resp, err := client.Call("Token", "grant", params, "")
+	if err != nil {
+		return "", err
+	}
 
-		return workspaceList, nil
+	accessToken, ok := resp["access_token"].(string)
+	if !ok {
+		return "", fmt.Errorf("access token not found in response")
 	}
+	return accessToken, nil
 }
 
-func fetchDomainIDAndRole(baseUrl string, identityEndpoint string, hasIdentityService bool, accessToken string) (string, string, error) {
-	if !hasIdentityService {
-		payload := map[string]string{}
-		jsonPayload, err := json.Marshal(payload)
-		if err != nil {
-			return "", "", err
-		}
-
-		getUserProfileUrl := baseUrl + "/user-profile/get"
-		req, err := http.NewRequest("POST", getUserProfileUrl, bytes.NewBuffer(jsonPayload))
-		if err != nil {
-			return "", "", err
-		}
-
-		req.Header.Set("accept", "application/json")
-		req.Header.Set("Authorization", "Bearer "+accessToken)
-		req.Header.Set("Content-Type", "application/json")
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			return "", "", err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return "", "", fmt.Errorf("failed to fetch user profile, status code: %d", resp.StatusCode)
-		}
-
-		var result map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			return "", "", err
-		}
-
-		domainID, ok := result["domain_id"].(string)
-		if !ok {
-			return "", "", fmt.Errorf("domain_id not found in response")
-		}
-
-		roleType, ok := result["role_type"].(string)
-		if !ok {
-			return "", "", fmt.Errorf("role_type not found in response")
-		}
-
-		return domainID, roleType, nil
-	} else {
-		// Parse the endpoint
-		parts := strings.Split(identityEndpoint, "://")
-		if len(parts) != 2 {
-			return "", "", fmt.Errorf("invalid endpoint format: %s", identityEndpoint)
-		}
-
-		hostPort := parts[1]
-
-		// Configure gRPC connection
-		var opts []grpc.DialOption
-		if strings.HasPrefix(identityEndpoint, "grpc+ssl://") {
-			tlsConfig := &tls.Config{
-				InsecureSkipVerify: false,
-			}
-			creds := credentials.NewTLS(tlsConfig)
-			opts = append(opts, grpc.WithTransportCredentials(creds))
-		} else {
-			opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		}
-
-		// Add token to metadata
-		opts = append(opts, grpc.WithPerRPCCredentials(&tokenAuth{token: accessToken}))
-
-		// Establish connection
-		conn, err := grpc.Dial(hostPort, opts...)
-		if err != nil {
-			return "", "", fmt.Errorf("failed to connect: %v", err)
-		}
-		defer conn.Close()
-
-		// Create reflection client
-		refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
-		defer refClient.Reset()
-
-		// Resolve the service
-		serviceName := "spaceone.api.identity.v2.UserProfile"
-		serviceDesc, err := refClient.ResolveService(serviceName)
-		if err != nil {
-			return "", "", fmt.Errorf("failed to resolve service %s: %v", serviceName, err)
-		}
-
-		// Find the method descriptor
-		methodDesc := serviceDesc.FindMethodByName("get")
-		if methodDesc == nil {
-			return "", "", fmt.Errorf("method get not found")
-		}
-
-		// Create request message
-		reqMsg := dynamic.NewMessage(methodDesc.GetInputType())
-
-		// Make the gRPC call
-		fullMethod := fmt.Sprintf("/%s/%s", serviceName, "get")
-		respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
-
-		err = conn.Invoke(context.Background(), fullMethod, reqMsg, respMsg)
-		if err != nil {
-			return "", "", fmt.Errorf("RPC failed: %v", err)
-		}
-
-		// Extract domain_id and role_type from response
-		domainID, err := respMsg.TryGetFieldByName("domain_id")
-		if err != nil {
-			return "", "", fmt.Errorf("failed to get domain_id from response: %v", err)
-		}
+// saveSelectedToken saves the selected token as the current token for the environment
+func saveSelectedToken(currentEnv, selectedToken string) error {
+	return configs.UpdateConfig(filepath.Join(GetSettingDir(), "setting.yaml"), func(v *viper.Viper) error {
+		// Set the selected token as current token, keeping the other settings
+		v.Set(fmt.Sprintf("environments.%s.token", currentEnv), secretSettingValue(tokenSecretKey(currentEnv), selectedToken))
+		return nil
+	})
+}
 
-		roleType, err := respMsg.TryGetFieldByName("role_type")
-		if err != nil {
-			return "", "", fmt.Errorf("failed to get role_type from response: %v", err)
+// checkScopeFlags validates --scope and its combination with --workspace before any prompt
+func checkScopeFlags() error {
+	switch strings.ToUpper(loginScope) {
+	case "", "WORKSPACE":
+		return nil
+	case "DOMAIN":
+		if loginWorkspace != "" {
+			return fmt.Errorf("--workspace cannot be combined with --scope DOMAIN")
 		}
-
-		// Convert roleType to string based on enum value
-		var roleTypeStr string
-		switch v := roleType.(type) {
-		case int32:
-			switch v {
-			case 1:
-				roleTypeStr = "DOMAIN_ADMIN"
-			case 2:
-				roleTypeStr = "WORKSPACE_OWNER"
-			case 3:
-				roleTypeStr = "WORKSPACE_MEMBER"
-			default:
-				return "", "", fmt.Errorf("unknown role_type: %d", v)
-			}
-		case string:
-			roleTypeStr = v
-		default:
-			return "", "", fmt.Errorf("unexpected role_type type: %T", roleType)
+		if configs.SelectedWorkspace() != "" {
+			return fmt.Errorf("--scope DOMAIN cannot be used while %s selects a workspace", configs.EnvVarWorkspace)
 		}
-
-		return domainID.(string), roleTypeStr, nil
+		return nil
 	}
+	return fmt.Errorf("--scope must be DOMAIN or WORKSPACE, got '%s'", loginScope)
 }
 
-func grantToken(restIdentityEndpoint, identityEndpoint string, hasIdentityService bool, refreshToken, scope, domainID, workspaceID string) (string, error) {
-	if !hasIdentityService {
-		payload := map[string]interface{}{
-			"grant_type":   "REFRESH_TOKEN",
-			"token":        refreshToken,
-			"scope":        scope,
-			"timeout":      10800,
-			"domain_id":    domainID,
-			"workspace_id": workspaceID,
-		}
-		jsonPayload, err := json.Marshal(payload)
-		if err != nil {
-			return "", err
-		}
-
-		req, err := http.NewRequest("POST", restIdentityEndpoint+"/token/grant", bytes.NewBuffer(jsonPayload))
-		if err != nil {
-			return "", err
-		}
-		req.Header.Set("Content-Type", "application/json")
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			return "", err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("status code: %d", resp.StatusCode)
-		}
-
-		var result map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			return "", err
-		}
-
-		accessToken, ok := result["access_token"].(string)
-		if !ok {
-			return "", fmt.Errorf("access token not found in response")
-		}
-
-		return accessToken, nil
-	} else {
-		// Parse the endpoint
-		parts := strings.Split(identityEndpoint, "://")
-		if len(parts) != 2 {
-			return "", fmt.Errorf("invalid endpoint format: %s", identityEndpoint)
-		}
-
-		hostPort := parts[1]
-
-		// Configure gRPC connection
-		var opts []grpc.DialOption
-		if strings.HasPrefix(identityEndpoint, "grpc+ssl://") {
-			tlsConfig := &tls.Config{
-				InsecureSkipVerify: false,
-			}
-			creds := credentials.NewTLS(tlsConfig)
-			opts = append(opts, grpc.WithTransportCredentials(creds))
-		} else {
-			opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		}
-
-		// Establish connection
-		conn, err := grpc.Dial(hostPort, opts...)
-		if err != nil {
-			return "", fmt.Errorf("failed to connect: %v", err)
-		}
-		defer conn.Close()
-
-		// Create reflection client
-		refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
-		defer refClient.Reset()
-
-		// Resolve the service
-		serviceName := "spaceone.api.identity.v2.Token"
-		serviceDesc, err := refClient.ResolveService(serviceName)
-		if err != nil {
-			return "", fmt.Errorf("failed to resolve service %s: %v", serviceName, err)
-		}
-
-		// Find the method descriptor
-		methodDesc := serviceDesc.FindMethodByName("grant")
-		if methodDesc == nil {
-			return "", fmt.Errorf("method grant not found")
-		}
-
-		// Create request message
-		reqMsg := dynamic.NewMessage(methodDesc.GetInputType())
-
-		reqMsg.SetFieldByName("grant_type", int32(1))
-
-		var scopeEnum int32
-		switch scope {
-		case "DOMAIN":
-			scopeEnum = 2
-		case "WORKSPACE":
-			scopeEnum = 3
-		case "USER":
-			scopeEnum = 5
-		default:
-			return "", fmt.Errorf("unknown scope: %s", scope)
-		}
-
-		reqMsg.SetFieldByName("scope", scopeEnum)
-		reqMsg.SetFieldByName("token", refreshToken)
-		reqMsg.SetFieldByName("timeout", int32(10800))
-		reqMsg.SetFieldByName("domain_id", domainID)
-		if workspaceID != "" {
-			reqMsg.SetFieldByName("workspace_id", workspaceID)
-		}
-
-		// Make the gRPC call
-		fullMethod := "/spaceone.api.identity.v2.Token/grant"
-		respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
-
-		err = conn.Invoke(context.Background(), fullMethod, reqMsg, respMsg)
-		if err != nil {
-			return "", fmt.Errorf("RPC failed: %v", err)
+// scopeFromFlags returns the scope and workspace given by --scope and --workspace. The scope is
+// empty when no flag is given, and the workspace when --scope WORKSPACE leaves several to choose.
+func scopeFromFlags(workspaces []map[string]interface{}, roleType string) (string, string, error) {
+	if err := checkScopeFlags(); err != nil {
+		return "", "", err
+	}
+	scope := strings.ToUpper(loginScope)
+	if scope == "DOMAIN" {
+		if roleType != "DOMAIN_ADMIN" {
+			return "", "", fmt.Errorf("only domain admins can log in to the DOMAIN scope, the role is %s", roleType)
 		}
-
-		// Extract access_token from response
-		accessToken, err := respMsg.TryGetFieldByName("access_token")
+		return "DOMAIN", "", nil
+	}
+	if loginWorkspace != "" {
+		workspace, err := findWorkspace(workspaces, loginWorkspace)
 		if err != nil {
-			return "", fmt.Errorf("failed to get access_token from response: %v", err)
+			return "", "", err
 		}
-
-		return accessToken.(string), nil
+		return "WORKSPACE", stringValue(workspace["workspace_id"]), nil
 	}
-}
-
-// saveSelectedToken saves the selected token as the current token for the environment
-func saveSelectedToken(currentEnv, selectedToken string) error {
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".cfctl", "config.yaml")
-
-	viper.SetConfigFile(configPath)
-	if err := viper.ReadInConfig(); err != nil && !os.IsNotExist(err) {
-		return err
+	if scope == "WORKSPACE" && len(workspaces) == 1 {
+		return "WORKSPACE", stringValue(workspaces[0]["workspace_id"]), nil
 	}
+	return scope, "", nil
+}
 
-	envPath := fmt.Sprintf("environments.%s", currentEnv)
-	envSettings := viper.GetStringMap(envPath)
-	if envSettings == nil {
-		envSettings = make(map[string]interface{})
+// selectLoginScope returns the scope and workspace to grant the login token for: those given by
+// --scope and --workspace, the ones selected by the user, or without a terminal the only workspace
+func selectLoginScope(workspaces []map[string]interface{}, roleType string) (string, string, error) {
+	scope, workspaceID, err := scopeFromFlags(workspaces, roleType)
+	if err != nil || scope == "DOMAIN" || workspaceID != "" {
+		return scope, workspaceID, err
 	}
-
-	// Keep all existing settings
-	newEnvSettings := make(map[string]interface{})
-
-	// Keep endpoint and proxy settings
-	if endpoint, ok := envSettings["endpoint"]; ok {
-		newEnvSettings["endpoint"] = endpoint
+	if !configs.Interactive() {
+		if roleType != "DOMAIN_ADMIN" && len(workspaces) == 1 {
+			return "WORKSPACE", stringValue(workspaces[0]["workspace_id"]), nil
+		}
+		return "", "", fmt.Errorf("no terminal to select one of %d workspaces, pass its name or ID with --workspace", len(workspaces))
 	}
-	if proxy, ok := envSettings["proxy"]; ok {
-		newEnvSettings["proxy"] = proxy
+	if scope == "WORKSPACE" {
+		return "WORKSPACE", selectWorkspaceOnly(workspaces), nil
 	}
 
-	// Keep tokens array
-	if tokens, ok := envSettings["tokens"]; ok {
-		newEnvSettings["tokens"] = tokens
+	workspaceID = selectScopeOrWorkspace(workspaces, roleType)
+	if workspaceID == "0" {
+		return "DOMAIN", "", nil
 	}
-
-	// Set the selected token as current token
-	newEnvSettings["token"] = selectedToken
-
-	viper.Set(envPath, newEnvSettings)
-	return viper.WriteConfig()
+	return "WORKSPACE", workspaceID, nil
 }
 
 func selectScopeOrWorkspace(workspaces []map[string]interface{}, roleType string) string {
-	if err := keyboard.Open(); err != nil {
-		pterm.Error.Println("Failed to initialize keyboard:", err)
-		exitWithError()
-	}
-	defer keyboard.Close()
-
 	if roleType != "DOMAIN_ADMIN" {
 		return selectWorkspaceOnly(workspaces)
 	}
 
-	options := []string{"DOMAIN ADMIN", "WORKSPACES"}
-	selectedIndex := 0
-
-	for {
-		fmt.Print("\033[H\033[2J")
-
-		// Display scope selection
-		pterm.DefaultHeader.WithFullWidth().
-			WithBackgroundStyle(pterm.NewStyle(pterm.BgDarkGray)).
-			WithTextStyle(pterm.NewStyle(pterm.FgLightWhite)).
-			Println("Select Scope")
-
-		for i, option := range options {
-			if i == selectedIndex {
-				pterm.Printf("→ %d: %s\n", i, option)
-			} else {
-				pterm.Printf("  %d: %s\n", i, option)
-			}
-		}
-
-		// Show navigation help
-		pterm.DefaultBasicText.WithStyle(pterm.NewStyle(pterm.FgGray)).
-			Println("\nNavigation: [j]down [k]up, [Enter]select, [q]uit")
-
-		// Get keyboard input
-		char, key, err := keyboard.GetKey()
-		if err != nil {
-			pterm.Error.Println("Error reading keyboard input:", err)
-			exitWithError()
-		}
-
-		// Handle navigation and other commands
-		switch key {
-		case keyboard.KeyEnter:
-			if selectedIndex == 0 {
-				return "0"
-			} else {
-				return selectWorkspaceOnly(workspaces)
-			}
-		}
-
-		switch char {
-		case 'j': // Down
-			if selectedIndex < len(options)-1 {
-				selectedIndex++
-			}
-		case 'k': // Up
-			if selectedIndex > 0 {
-				selectedIndex--
-			}
-		case 'q', 'Q':
-			pterm.Error.Println("Selection cancelled.")
-			os.Exit(1)
-		}
+	if runSelector("Select Scope", []string{"DOMAIN ADMIN", "WORKSPACES"}, 0) == 0 {
+		return "0"
 	}
+	return selectWorkspaceOnly(workspaces)
 }
 
 // selectWorkspaceOnly handles workspace selection
 func selectWorkspaceOnly(workspaces []map[string]interface{}) string {
-	const pageSize = 15
-	currentPage := 0
-	searchMode := false
-	searchTerm := ""
-	selectedIndex := 0
-	inputBuffer := ""
-	filteredWorkspaces := workspaces
-
-	if err := keyboard.Open(); err != nil {
-		pterm.Error.Println("Failed to initialize keyboard:", err)
-		exitWithError()
-	}
-	defer keyboard.Close()
-
-	for {
-		// Clear screen
-		fmt.Print("\033[H\033[2J")
-
-		// Apply search filter
-		if searchTerm != "" {
-			filteredWorkspaces = filterWorkspaces(workspaces, searchTerm)
-			if len(filteredWorkspaces) == 0 {
-				filteredWorkspaces = workspaces
-			}
-		} else {
-			filteredWorkspaces = workspaces
-		}
-
-		// Calculate pagination
-		totalWorkspaces := len(filteredWorkspaces)
-		totalPages := (totalWorkspaces + pageSize - 1) / pageSize
-		startIndex := (currentPage % totalPages) * pageSize
-		endIndex := startIndex + pageSize
-		if endIndex > totalWorkspaces {
-			endIndex = totalWorkspaces
-		}
-
-		// Display header with page information
-		pterm.DefaultHeader.WithFullWidth().
-			WithBackgroundStyle(pterm.NewStyle(pterm.BgDarkGray)).
-			WithTextStyle(pterm.NewStyle(pterm.FgLightWhite)).
-			Printf("Accessible Workspaces (Page %d of %d)", currentPage+1, totalPages)
-
-		// Show workspace list
-		for i := startIndex; i < endIndex; i++ {
-			name := filteredWorkspaces[i]["name"].(string)
-			if i-startIndex == selectedIndex {
-				pterm.Printf("→ %d: %s\n", i+1, name)
-			} else {
-				pterm.Printf("  %d: %s\n", i+1, name)
-			}
-		}
-
-		// Show navigation help and search prompt
-		pterm.DefaultBasicText.WithStyle(pterm.NewStyle(pterm.FgGray)).
-			Println("\nNavigation: [h]prev-page [j]down [k]up  [l]next-page [/]search [q]uit")
-
-		// Show search or input prompt at the bottom
-		if searchMode {
-			fmt.Println()
-			pterm.Info.Printf("Search (ESC to cancel, Enter to confirm): %s", searchTerm)
-		} else {
-			fmt.Print("\nSelect a workspace above or input a number: ")
-			if inputBuffer != "" {
-				fmt.Print(inputBuffer)
-			}
-		}
-
-		// Get keyboard input
-		char, key, err := keyboard.GetKey()
-		if err != nil {
-			pterm.Error.Println("Error reading keyboard input:", err)
-			exitWithError()
-		}
-
-		// Handle search mode input
-		if searchMode {
-			switch key {
-			case keyboard.KeyEsc:
-				searchMode = false
-				searchTerm = ""
-			case keyboard.KeyBackspace, keyboard.KeyBackspace2:
-				if len(searchTerm) > 0 {
-					searchTerm = searchTerm[:len(searchTerm)-1]
-				}
-			case keyboard.KeyEnter:
-				searchMode = false
-			default:
-				if char != 0 {
-					searchTerm += string(char)
-				}
-			}
-			currentPage = 0
-			selectedIndex = 0
-			continue
-		}
-
-		// Handle normal mode input
-		switch key {
-		case keyboard.KeyEnter:
-			if inputBuffer != "" {
-				index, err := strconv.Atoi(inputBuffer)
-				if err == nil && index >= 1 && index <= len(filteredWorkspaces) {
-					return filteredWorkspaces[index-1]["workspace_id"].(string)
-				}
-				inputBuffer = ""
-			} else {
-				adjustedIndex := startIndex + selectedIndex
-				if adjustedIndex >= 0 && adjustedIndex < len(filteredWorkspaces) {
-					return filteredWorkspaces[adjustedIndex]["workspace_id"].(string)
-				}
-			}
-		case keyboard.KeyBackspace, keyboard.KeyBackspace2:
-			if len(inputBuffer) > 0 {
-				inputBuffer = inputBuffer[:len(inputBuffer)-1]
-			}
-		}
-
-		// Handle navigation and other commands
-		switch char {
-		case 'j': // Down
-			if selectedIndex < min(pageSize-1, endIndex-startIndex-1) {
-				selectedIndex++
-			}
-		case 'k': // Up
-			if selectedIndex > 0 {
-				selectedIndex--
-			}
-		case 'l': // Next page
-			currentPage = (currentPage + 1) % totalPages
-			selectedIndex = 0
-		case 'h': // Previous page
-			currentPage = (currentPage - 1 + totalPages) % totalPages
-			selectedIndex = 0
-		case 'q', 'Q':
-			fmt.Println()
-			pterm.Error.Println("Workspace selection cancelled.")
-			os.Exit(1)
-		case '/':
-			searchMode = true
-			searchTerm = ""
-			selectedIndex = 0
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			if !searchMode {
-				inputBuffer += string(char)
-			}
-		}
-	}
-}
-
-func filterWorkspaces(workspaces []map[string]interface{}, searchTerm string) []map[string]interface{} {
-	var filtered []map[string]interface{}
-	searchTerm = strings.ToLower(searchTerm)
-
-	for _, workspace := range workspaces {
-		name := strings.ToLower(workspace["name"].(string))
-		if strings.Contains(name, searchTerm) {
-			filtered = append(filtered, workspace)
-		}
+	requireInteractive("select a workspace")
+	workspace, err := ui.Select[map[string]interface{}]{
+		Title: "Accessible Workspaces",
+		Items: workspaces,
+		Label: func(w map[string]interface{}) string { return stringValue(w["name"]) },
+	}.Choose()
+	if err != nil {
+		exitOnSelectError(err)
 	}
-	return filtered
+	return stringValue(workspace["workspace_id"])
 }
 
 func init() {
 	LoginCmd.Flags().StringVarP(&providedUrl, "url", "u", "", "The URL to use for login (e.g. cfctl login -u https://example.com)")
-}
-
-// decodeJWT decodes a JWT token and returns the claims
-func decodeJWT(token string) (map[string]interface{}, error) {
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid token format")
-	}
-
-	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
-	if err != nil {
-		return nil, err
-	}
-
-	var claims map[string]interface{}
-	if err := json.Unmarshal(payload, &claims); err != nil {
-		return nil, err
-	}
-
-	return claims, nil
+	LoginCmd.Flags().BoolVar(&noSaveCredentials, "no-save-credentials", false, "Do not store the user entry in the setting file (deprecated, use --no-save)")
+	LoginCmd.Flags().BoolVar(&loginNoSave, "no-save", false, "Keep only the access token of this login, no refresh token, password or user entry (same as 'credential_cache: none')")
+	LoginCmd.Flags().StringVar(&removeUserID, "remove-user", "", "Remove a stored user from the current environment (e.g. cfctl login --remove-user user@example.com)")
+	LoginCmd.Flags().BoolVar(&useOIDC, "oidc", false, "Exchange the OIDC token of the CI job (GitHub Actions, GitLab) for a SpaceONE token")
+	LoginCmd.Flags().StringVar(&authType, "auth-type", authTypeLocal, "Authentication type: local (user ID and password) or external (SSO in the browser)")
+	LoginCmd.Flags().IntVar(&callbackPort, "callback-port", 0, "Port of the localhost callback for --auth-type external, random when 0")
+	LoginCmd.Flags().StringVar(&apiKey, "api-key", "", "Log in with an API key instead of a password, without prompts (or set "+apiKeyEnv+")")
+	LoginCmd.Flags().StringVar(&mfaCode, "mfa-code", "", "MFA verification code for accounts with MFA enabled, prompted for when needed")
+	LoginCmd.Flags().StringVar(&loginUser, "user", "", "User ID to log in as, instead of selecting a stored user or entering one")
+	LoginCmd.Flags().StringVar(&loginWorkspace, "workspace", "", "Name or ID of the workspace to log in to, instead of selecting it (default $CFCTL_WORKSPACE)")
+	LoginCmd.Flags().StringVar(&loginScope, "scope", "", "Scope of the login token, DOMAIN or WORKSPACE, instead of selecting it")
+	LoginCmd.Flags().IntVar(&loginTokenIndex, "token-index", 0, "Number of the stored token to use in an app environment, counting from 1, instead of selecting it")
+	LoginCmd.Flags().StringVar(&userLabel, "label", "", "Label shown next to the user in the account selector (e.g. \"prod admin\")")
+	LoginCmd.RegisterFlagCompletionFunc("workspace", completeWorkspaces)
+	LoginCmd.RegisterFlagCompletionFunc("scope", completeFrom([]string{"DOMAIN", "WORKSPACE"}))
 }
 
 // validateAndDecodeToken decodes a JWT token and validates its expiration
-func validateAndDecodeToken(token string) (map[string]interface{}, error) {
-	// Check if token has three parts (header.payload.signature)
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid token format: token must have three parts")
-	}
-
-	// Try to decode the payload
-	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+func validateAndDecodeToken(appToken string) (map[string]interface{}, error) {
+	claims, err := token.Decode(appToken)
 	if err != nil {
-		return nil, fmt.Errorf("invalid token format: failed to decode payload: %v", err)
-	}
-
-	var claims map[string]interface{}
-	if err := json.Unmarshal(payload, &claims); err != nil {
-		return nil, fmt.Errorf("invalid token format: failed to parse payload: %v", err)
+		return nil, err
 	}
 
 	// Check required fields
@@ -2010,7 +1211,7 @@ func validateAndDecodeToken(token string) (map[string]interface{}, error) {
 	}
 
 	// Check expiration
-	if isTokenExpired(token) {
+	if claims.Expired() {
 		return nil, fmt.Errorf("token has expired")
 	}
 
@@ -2019,58 +1220,93 @@ func validateAndDecodeToken(token string) (map[string]interface{}, error) {
 
 // clearInvalidTokens removes invalid tokens from the config
 func clearInvalidTokens(currentEnv string) error {
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".cfctl", "config.yaml")
+	return configs.UpdateConfig(filepath.Join(GetSettingDir(), "setting.yaml"), func(v *viper.Viper) error {
+		envPath := fmt.Sprintf("environments.%s", currentEnv)
+		envSettings := v.GetStringMap(envPath)
+		if envSettings == nil {
+			return nil
+		}
 
-	viper.SetConfigFile(configPath)
-	if err := viper.ReadInConfig(); err != nil {
-		return err
-	}
+		envConfig, err := configs.DecodeEnvironment(v, currentEnv)
+		if err != nil {
+			return err
+		}
+		var validTokens []TokenInfo
+		for _, t := range envConfig.Tokens {
+			if _, err := validateAndDecodeToken(t.Token); err == nil {
+				validTokens = append(validTokens, TokenInfo{Token: t.Token})
+			}
+		}
 
-	envPath := fmt.Sprintf("environments.%s", currentEnv)
-	envSettings := viper.GetStringMap(envPath)
-	if envSettings == nil {
+		// Update config with only valid tokens
+		envSettings["tokens"] = tokenListSetting(currentEnv, validTokens)
+		v.Set(envPath, envSettings)
 		return nil
+	})
+}
+
+// readTokenFromFile reads a token from the specified file in the environment cache directory
+func readTokenFromFile(envDir, tokenType string) (string, error) {
+	return configs.ReadCachedToken(filepath.Join(envDir, tokenType))
+}
+
+// saveIssuedTokens stores the tokens returned by Token.issue before the grant step.
+// If the grant fails afterwards, the next login finds a valid refresh token and
+// resumes at workspace selection instead of asking for credentials again.
+func saveIssuedTokens(v *viper.Viper, currentEnv, accessToken, refreshToken string) error {
+	if credentialCacheMode(v, currentEnv) == configs.CredentialCacheNone {
+		return nil
+	}
+	envCacheDir := filepath.Join(GetSettingDir(), "cache", currentEnv)
+	if err := configs.EnsureSecureDir(envCacheDir); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
 	}
 
-	var validTokens []TokenInfo
-	if tokensList := viper.Get(fmt.Sprintf("%s.tokens", envPath)); tokensList != nil {
-		if tokenList, ok := tokensList.([]interface{}); ok {
-			for _, t := range tokenList {
-				if tokenMap, ok := t.(map[string]interface{}); ok {
-					token := tokenMap["token"].(string)
-					if _, err := validateAndDecodeToken(token); err == nil {
-						validTokens = append(validTokens, TokenInfo{Token: token})
-					}
-				}
-			}
-		}
+	if err := configs.WriteCachedToken(filepath.Join(envCacheDir, "refresh_token"), refreshToken); err != nil {
+		return fmt.Errorf("failed to save refresh token: %v", err)
 	}
 
-	// Update config with only valid tokens
-	envSettings["tokens"] = validTokens
-	viper.Set(envPath, envSettings)
-	return viper.WriteConfig()
+	return configs.StoreAccessToken(currentEnv, accessToken, "")
 }
 
-// readTokenFromFile reads a token from the specified file in the environment cache directory
-func readTokenFromFile(envDir, tokenType string) (string, error) {
-	tokenPath := filepath.Join(envDir, tokenType)
-	data, err := os.ReadFile(tokenPath)
+// regrantExpiredToken grants a new access token with the scope and workspace of the expired
+// one, using the still valid refresh token. No password is needed in this case.
+// It returns false when the cached access token is still valid or was never granted, or when
+// --scope or --workspace ask for a scope, in which case the regular login flow continues.
+func regrantExpiredToken(currentEnv string, client apiclient.Client, accessToken, refreshToken string) bool {
+	if accessToken == "" || !token.IsExpired(accessToken) || loginScope != "" || loginWorkspace != "" {
+		return false
+	}
+
+	claims, err := token.Decode(accessToken)
 	if err != nil {
-		return "", err
+		return false
 	}
-	return string(data), nil
-}
 
-// getValidTokens checks for existing valid tokens in the environment cache directory
-func getValidTokens(currentEnv string) (accessToken, refreshToken string, err error) {
-	homeDir, err := os.UserHomeDir()
+	// Only granted tokens have a scope; issued tokens still need a workspace selection
+	if claims.Scope() == "" || claims.DomainID() == "" {
+		return false
+	}
+
+	pterm.Info.Println("Access token expired. Granting a new one with the cached refresh token.")
+	newAccessToken, err := grantToken(client, refreshToken, claims.Scope(), claims.DomainID(), claims.WorkspaceID())
 	if err != nil {
-		return "", "", err
+		pterm.Warning.Printf("Failed to re-grant token: %v\n", err)
+		return false
+	}
+
+	if err := configs.StoreAccessToken(currentEnv, newAccessToken, currentWorkspaceName(currentEnv, claims)); err != nil {
+		pterm.Error.Println(err)
+		exitWithError()
 	}
 
-	envCacheDir := filepath.Join(homeDir, ".cfctl", "cache", currentEnv)
+	pterm.Success.Println("Successfully logged in and saved token.")
+	return true
+}
+
+// getValidTokens checks for existing valid tokens in the environment cache directory
+func getValidTokens(currentEnv string) (accessToken, refreshToken string, err error) {
+	envCacheDir := filepath.Join(GetSettingDir(), "cache", currentEnv)
 
 	if refreshToken, err = readTokenFromFile(envCacheDir, "refresh_token"); err == nil {
 		claims, err := validateAndDecodeToken(refreshToken)
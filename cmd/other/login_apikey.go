@@ -0,0 +1,95 @@
+package other
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/apiclient"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// apiKeyEnv is read when --api-key is not given, so that the key stays out of the shell history
+const apiKeyEnv = "CFCTL_API_KEY"
+
+// apiKeyAuthType is the auth_type of Token.issue for API keys
+const apiKeyAuthType = "API_KEY"
+
+// executeAPIKeyLogin exchanges an API key for tokens with Token.issue and stores them without
+// any prompt, for automation that should not hold user passwords. Domain admins get a domain
+// scoped token and other users the token of their workspace.
+func executeAPIKeyLogin(v *viper.Viper, currentEnv, apiKey string) error {
+	identityClient, err := identityClientFromSetting()
+	if err != nil {
+		return err
+	}
+
+	domainID, err := fetchDomainID(identityClient, strings.Split(currentEnv, "-")[0])
+	if err != nil {
+		return fmt.Errorf("failed to fetch Domain ID: %v", err)
+	}
+
+	resp, err := identityClient.Call("Token", "issue", map[string]interface{}{
+		"credentials": map[string]interface{}{
+			"api_key": apiKey,
+		},
+		"auth_type": apiKeyAuthType,
+		"domain_id": domainID,
+	}, "")
+	if err != nil {
+		return fmt.Errorf("failed to issue token with the API key: %v", err)
+	}
+	accessToken, _ := resp["access_token"].(string)
+	if accessToken == "" {
+		return fmt.Errorf("access_token not found in response")
+	}
+
+	// Without a refresh token the issued token is used as is
+	refreshToken, _ := resp["refresh_token"].(string)
+	if refreshToken == "" {
+		return storeExchangedToken(v, currentEnv, accessToken)
+	}
+
+	scope, workspaceID, err := selectAPIKeyScope(identityClient, accessToken)
+	if err != nil {
+		return err
+	}
+	grantedToken, err := grantToken(identityClient, refreshToken, scope, domainID, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve new access token: %v", err)
+	}
+
+	if !strings.HasSuffix(currentEnv, "-user") {
+		return storeExchangedToken(v, currentEnv, grantedToken)
+	}
+	return saveLoginTokens(v, currentEnv, grantedToken, refreshToken, "")
+}
+
+// selectAPIKeyScope picks the scope of the granted token: the ones given by --scope and
+// --workspace, the domain for domain admins and the only workspace of other users. A workspace
+// is only asked for when there are several and a terminal.
+func selectAPIKeyScope(client apiclient.Client, accessToken string) (string, string, error) {
+	workspaces, _, roleType, err := fetchWorkspacesAndRole(client, accessToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	scope, workspaceID, err := scopeFromFlags(workspaces, roleType)
+	if err != nil || scope == "DOMAIN" || workspaceID != "" {
+		return scope, workspaceID, err
+	}
+	if scope == "" && determineScope(roleType, len(workspaces)) == "DOMAIN" {
+		return "DOMAIN", "", nil
+	}
+	if len(workspaces) == 1 {
+		workspaceID, _ := workspaces[0]["workspace_id"].(string)
+		return "WORKSPACE", workspaceID, nil
+	}
+	if !configs.Interactive() {
+		return "", "", fmt.Errorf("the API key has access to %d workspaces, pass its name or ID with --workspace", len(workspaces))
+	}
+
+	pterm.Info.Printf("The API key has access to %d workspaces.\n", len(workspaces))
+	return "WORKSPACE", selectWorkspaceOnly(workspaces), nil
+}
@@ -0,0 +1,136 @@
+package other
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cloudforet-io/cfctl/internal/apiclient"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// credentialCacheMode returns what a password login keeps for the environment: none with
+// --no-save, else environments.<env>.credential_cache, else the global credential_cache, else token
+func credentialCacheMode(v *viper.Viper, currentEnv string) string {
+	if loginNoSave {
+		return configs.CredentialCacheNone
+	}
+
+	mode := v.GetString(fmt.Sprintf("environments.%s.credential_cache", currentEnv))
+	if mode == "" {
+		mode = v.GetString("credential_cache")
+	}
+	if mode == "" {
+		return configs.CredentialCacheToken
+	}
+	if err := configs.ValidateCredentialCache(mode); err != nil {
+		pterm.Error.Printf("Invalid credential_cache: %v\n", err)
+		exitWithError()
+	}
+	return mode
+}
+
+// issueUserToken issues the tokens of the user with the password saved by
+// 'credential_cache: password', or else with the password entered at the prompt.
+// The password used is returned, so that it can be saved once the login succeeded.
+func issueUserToken(v *viper.Viper, currentEnv string, client apiclient.Client, userID, domainID string) (accessToken, refreshToken, password string, err error) {
+	if credentialCacheMode(v, currentEnv) == configs.CredentialCachePassword {
+		if saved := savedPassword(v, currentEnv, userID); saved != "" {
+			accessToken, refreshToken, err = issueTokenWithMFA(client, userID, saved, domainID)
+			if err == nil {
+				pterm.Info.Println("Logged in with the saved password.")
+				return accessToken, refreshToken, saved, nil
+			}
+			pterm.Warning.Printf("The saved password was not accepted: %v\n", err)
+		}
+	}
+
+	password = promptPassword()
+	accessToken, refreshToken, err = issueTokenWithMFA(client, userID, password, domainID)
+	return accessToken, refreshToken, password, err
+}
+
+// saveLoginTokens stores the granted access token in the cache of the environment, and the
+// refresh token unless credential_cache is none, in which case a cached one is removed too.
+// workspaceName names the workspace of the token, if any, for CFCTL_WORKSPACE.
+func saveLoginTokens(v *viper.Viper, currentEnv, accessToken, refreshToken, workspaceName string) error {
+	envCacheDir := filepath.Join(GetSettingDir(), "cache", currentEnv)
+	if err := configs.EnsureSecureDir(envCacheDir); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	if credentialCacheMode(v, currentEnv) == configs.CredentialCacheNone {
+		for _, tokenType := range []string{"refresh_token", "grant_token"} {
+			if err := configs.RemoveCachedToken(filepath.Join(envCacheDir, tokenType)); err != nil {
+				return fmt.Errorf("failed to remove %s: %v", tokenType, err)
+			}
+		}
+	} else if err := configs.WriteCachedToken(filepath.Join(envCacheDir, "refresh_token"), refreshToken); err != nil {
+		return fmt.Errorf("failed to save refresh token: %v", err)
+	}
+
+	return configs.StoreAccessToken(currentEnv, accessToken, workspaceName)
+}
+
+// cachePassword saves the encrypted password of the user with 'credential_cache: password', and
+// removes a saved one with the other modes
+func cachePassword(v *viper.Viper, currentEnv, userID, password string) error {
+	mode := credentialCacheMode(v, currentEnv)
+	if mode == configs.CredentialCachePassword && password != "" {
+		ciphertext, err := encrypt(password)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt password: %v", err)
+		}
+		return setSavedPassword(v, currentEnv, userID, ciphertext)
+	}
+	if mode != configs.CredentialCachePassword {
+		return setSavedPassword(v, currentEnv, userID, "")
+	}
+	return nil
+}
+
+// savedPassword returns the decrypted password saved for the user, or "" when there is none or
+// it cannot be decrypted
+func savedPassword(v *viper.Viper, currentEnv, userID string) string {
+	users, err := loadStoredUsers(v, currentEnv)
+	if err != nil {
+		return ""
+	}
+	for _, user := range users {
+		if user.UserID != userID || user.Password == "" {
+			continue
+		}
+		password, err := decrypt(user.Password)
+		if err != nil {
+			pterm.Warning.Printf("Failed to decrypt the saved password: %v\n", err)
+			return ""
+		}
+		return password
+	}
+	return ""
+}
+
+// setSavedPassword sets the encrypted password of the user entry, adding the entry if needed.
+// An empty ciphertext removes the saved password.
+func setSavedPassword(v *viper.Viper, currentEnv, userID, ciphertext string) error {
+	users, err := loadStoredUsers(v, currentEnv)
+	if err != nil {
+		return err
+	}
+
+	for i := range users {
+		if users[i].UserID != userID {
+			continue
+		}
+		if users[i].Password == ciphertext {
+			return nil
+		}
+		users[i].Password = ciphertext
+		return setStoredUsers(v, currentEnv, users)
+	}
+	if ciphertext == "" {
+		return nil
+	}
+	return setStoredUsers(v, currentEnv, append(users, storedUser{UserID: userID, Password: ciphertext}))
+}
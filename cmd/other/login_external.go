@@ -0,0 +1,232 @@
+package other
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/apiclient"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// Auth types selectable with --auth-type
+const (
+	authTypeLocal    = "local"
+	authTypeExternal = "external"
+)
+
+// externalLoginTimeout is how long the callback listener waits for the browser
+const externalLoginTimeout = 5 * time.Minute
+
+// externalCallback is what the SSO page redirects to the callback listener with
+type externalCallback struct {
+	accessToken   string
+	refreshToken  string
+	externalToken string
+	err           error
+}
+
+// executeExternalLogin signs in through the SSO page of the domain in the browser. The page
+// redirects to a listener on localhost with either SpaceONE tokens (access_token and refresh_token)
+// or the token of the identity provider (token or id_token), which is exchanged with Token.issue
+// using the EXTERNAL auth type.
+//
+// The SSO URL is environments.<env>.sso_url in setting.yaml, or the auth_url in the metadata
+// of the domain auth info.
+func executeExternalLogin(v *viper.Viper, currentEnv string) error {
+	identityClient, err := identityClientFromSetting()
+	if err != nil {
+		return err
+	}
+
+	domainName := strings.Split(currentEnv, "-")[0]
+	authInfo, err := identityClient.Call("Domain", "get_auth_info", map[string]interface{}{"name": domainName}, "")
+	if err != nil {
+		return fmt.Errorf("failed to fetch domain info: %v", err)
+	}
+	domainID, _ := authInfo["domain_id"].(string)
+	if domainID == "" {
+		return fmt.Errorf("domain_id not found in response")
+	}
+	if state, _ := authInfo["external_auth_state"].(string); state != "" && state != "ENABLED" {
+		return fmt.Errorf("external authentication is not enabled for domain '%s'", domainName)
+	}
+
+	ssoURL := v.GetString(fmt.Sprintf("environments.%s.sso_url", currentEnv))
+	if ssoURL == "" {
+		if metadata, ok := authInfo["metadata"].(map[string]interface{}); ok {
+			ssoURL, _ = metadata["auth_url"].(string)
+		}
+	}
+	if ssoURL == "" {
+		return fmt.Errorf("no SSO URL found for domain '%s', set environments.%s.sso_url", domainName, currentEnv)
+	}
+
+	callback, err := waitForExternalCallback(ssoURL, callbackPort)
+	if err != nil {
+		return err
+	}
+
+	accessToken, refreshToken := callback.accessToken, callback.refreshToken
+	if refreshToken == "" {
+		accessToken, refreshToken, err = issueExternalToken(identityClient, callback.externalToken, domainID)
+		if err != nil {
+			return fmt.Errorf("failed to issue token: %v", err)
+		}
+	}
+
+	return grantAndSaveTokens(v, currentEnv, identityClient, accessToken, refreshToken)
+}
+
+// waitForExternalCallback opens the SSO URL with a redirect to a listener on localhost and waits
+// until the browser comes back with a token. The state parameter ties the callback to this login.
+func waitForExternalCallback(ssoURL string, port int) (*externalCallback, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the callback listener: %v", err)
+	}
+
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	state := hex.EncodeToString(stateBytes)
+	redirectURI := fmt.Sprintf("http://%s/callback", listener.Addr().String())
+
+	loginURL, err := url.Parse(ssoURL)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("invalid SSO URL: %v", err)
+	}
+	query := loginURL.Query()
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	loginURL.RawQuery = query.Encode()
+
+	results := make(chan *externalCallback, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if params.Get("state") != state {
+			http.Error(w, "Invalid login state.", http.StatusBadRequest)
+			return
+		}
+
+		result := &externalCallback{accessToken: params.Get("access_token"), refreshToken: params.Get("refresh_token")}
+		if result.refreshToken == "" || result.accessToken == "" {
+			result.accessToken, result.refreshToken = "", ""
+			for _, key := range []string{"token", "id_token", "access_token"} {
+				if token := params.Get(key); token != "" {
+					result.externalToken = token
+					break
+				}
+			}
+		}
+		if errMsg := params.Get("error"); errMsg != "" {
+			result.err = fmt.Errorf("SSO login failed: %s %s", errMsg, params.Get("error_description"))
+		} else if result.refreshToken == "" && result.externalToken == "" {
+			result.err = fmt.Errorf("SSO login returned no token")
+		}
+
+		if result.err != nil {
+			http.Error(w, result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Login complete. You can close this window and return to cfctl.")
+		}
+		select {
+		case results <- result:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	pterm.Info.Println("Opening the SSO login page in your browser. If it does not open, visit:")
+	pterm.Println("  " + loginURL.String())
+	if err := openBrowser(loginURL.String()); err != nil {
+		pterm.Warning.Printf("Failed to open the browser: %v\n", err)
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start("Waiting for the SSO login to complete...")
+	select {
+	case result := <-results:
+		if result.err != nil {
+			spinner.Fail(result.err.Error())
+			return nil, result.err
+		}
+		spinner.Success("SSO login completed")
+		return result, nil
+	case <-time.After(externalLoginTimeout):
+		spinner.Fail("Timed out waiting for the SSO login")
+		return nil, fmt.Errorf("timed out after %s waiting for the SSO login", externalLoginTimeout)
+	}
+}
+
+// issueExternalToken exchanges the token of the identity provider for SpaceONE tokens
+func issueExternalToken(client apiclient.Client, externalToken, domainID string) (string, string, error) {
+	resp, err := client.Call("Token", "issue", map[string]interface{}{
+		"credentials": map[string]interface{}{
+			"access_token": externalToken,
+		},
+		"auth_type": "EXTERNAL",
+		"domain_id": domainID,
+	}, "")
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, ok := resp["access_token"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("access_token not found in response")
+	}
+	refreshToken, ok := resp["refresh_token"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("refresh_token not found in response")
+	}
+	return accessToken, refreshToken, nil
+}
+
+// grantAndSaveTokens selects the scope and workspace, grants the access token with the refresh
+// token and stores both in the cache of the environment like a password login
+func grantAndSaveTokens(v *viper.Viper, currentEnv string, client apiclient.Client, accessToken, refreshToken string) error {
+	workspaces, domainID, roleType, err := fetchWorkspacesAndRole(client, accessToken)
+	if err != nil {
+		return err
+	}
+
+	scope, workspaceID, err := selectLoginScope(workspaces, roleType)
+	if err != nil {
+		return err
+	}
+
+	grantedToken, err := grantToken(client, refreshToken, scope, domainID, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve new access token: %v", err)
+	}
+
+	return saveLoginTokens(v, currentEnv, grantedToken, refreshToken, "")
+}
+
+// openBrowser opens the URL in the default browser of the system
+func openBrowser(target string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", target).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", target).Start()
+	default:
+		return exec.Command("xdg-open", target).Start()
+	}
+}
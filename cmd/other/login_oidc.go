@@ -0,0 +1,183 @@
+package other
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+const (
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	jwtTokenType           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// oidcSettings are read from environments.<env>.oidc in setting.yaml
+//
+//	oidc:
+//	  token_exchange_endpoint: https://auth.example.com/token/exchange
+//	  audience: cfctl
+//	  token_env: CFCTL_ID_TOKEN   # GitLab id_tokens variable, optional
+type oidcSettings struct {
+	TokenExchangeEndpoint string
+	Audience              string
+	TokenEnv              string
+}
+
+// executeOIDCLogin exchanges the OIDC token of the CI job for a SpaceONE token and stores it
+func executeOIDCLogin(v *viper.Viper, currentEnv string) error {
+	envConfig, err := configs.DecodeEnvironment(v, currentEnv)
+	if err != nil {
+		return err
+	}
+	settings := oidcSettings(envConfig.OIDC)
+	if endpoint := os.Getenv("CFCTL_TOKEN_EXCHANGE_ENDPOINT"); endpoint != "" {
+		settings.TokenExchangeEndpoint = endpoint
+	}
+	if settings.TokenExchangeEndpoint == "" {
+		return fmt.Errorf("no token exchange endpoint configured, set environments.%s.oidc.token_exchange_endpoint", currentEnv)
+	}
+
+	ciToken, source, err := fetchCIToken(settings)
+	if err != nil {
+		return err
+	}
+	pterm.Info.Printf("Using the OIDC token from %s.\n", source)
+
+	accessToken, err := exchangeOIDCToken(settings, ciToken)
+	if err != nil {
+		return err
+	}
+
+	if err := storeExchangedToken(v, currentEnv, accessToken); err != nil {
+		return err
+	}
+
+	pterm.Success.Printf("Logged in to '%s' with the OIDC token of the CI job.\n", currentEnv)
+	return nil
+}
+
+// fetchCIToken returns the OIDC token provided by the CI system and a description of its source
+func fetchCIToken(settings oidcSettings) (string, string, error) {
+	if token := os.Getenv("CFCTL_OIDC_TOKEN"); token != "" {
+		return token, "CFCTL_OIDC_TOKEN", nil
+	}
+
+	// GitLab exposes id_tokens as variables named in .gitlab-ci.yml
+	if settings.TokenEnv != "" {
+		if token := os.Getenv(settings.TokenEnv); token != "" {
+			return token, settings.TokenEnv, nil
+		}
+		return "", "", fmt.Errorf("environment variable %s is empty", settings.TokenEnv)
+	}
+
+	// GitHub Actions requires 'permissions: id-token: write'
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL != "" && requestToken != "" {
+		token, err := fetchGitHubActionsToken(requestURL, requestToken, settings.Audience)
+		if err != nil {
+			return "", "", err
+		}
+		return token, "GitHub Actions", nil
+	}
+
+	return "", "", fmt.Errorf("no OIDC token found: set CFCTL_OIDC_TOKEN, oidc.token_env, or run in GitHub Actions with 'id-token: write' permission")
+}
+
+// fetchGitHubActionsToken requests an ID token from the GitHub Actions token service
+func fetchGitHubActionsToken(requestURL, requestToken, audience string) (string, error) {
+	if audience != "" {
+		separator := "?"
+		if strings.Contains(requestURL, "?") {
+			separator = "&"
+		}
+		requestURL += separator + "audience=" + url.QueryEscape(audience)
+	}
+
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+
+	client := &http.Client{Timeout: 30 * time.Second, Transport: netproxy.Transport()}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request GitHub Actions ID token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request GitHub Actions ID token: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode GitHub Actions ID token response: %v", err)
+	}
+	if result.Value == "" {
+		return "", fmt.Errorf("GitHub Actions returned an empty ID token")
+	}
+	return result.Value, nil
+}
+
+// exchangeOIDCToken performs an RFC 8693 token exchange
+func exchangeOIDCToken(settings oidcSettings, subjectToken string) (string, error) {
+	form := url.Values{
+		"grant_type":         {tokenExchangeGrantType},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {jwtTokenType},
+	}
+	if settings.Audience != "" {
+		form.Set("audience", settings.Audience)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second, Transport: netproxy.Transport()}
+	resp, err := client.PostForm(settings.TokenExchangeEndpoint, form)
+	if err != nil {
+		return "", fmt.Errorf("token exchange failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token exchange response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token exchange failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to decode token exchange response: %v", err)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("token exchange response has no access_token")
+	}
+	return result.AccessToken, nil
+}
+
+// storeExchangedToken saves the token where the environment type expects it
+func storeExchangedToken(v *viper.Viper, currentEnv, accessToken string) error {
+	if strings.HasSuffix(currentEnv, "-user") {
+		return configs.StoreAccessToken(currentEnv, accessToken, "")
+	}
+
+	v.Set(fmt.Sprintf("environments.%s.token", currentEnv), secretSettingValue(tokenSecretKey(currentEnv), accessToken))
+	configs.MarkSettingDirty()
+	return nil
+}
@@ -0,0 +1,319 @@
+package other
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/ui"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// storedUser is an account entry kept under environments.<env>.users in setting.yaml
+type storedUser struct {
+	UserID    string
+	Label     string
+	LastLogin time.Time
+	// Password is encrypted, it is only kept with 'credential_cache: password'
+	Password string
+}
+
+// displayName returns the user ID with its label and last login time for the selector
+func (u storedUser) displayName() string {
+	name := u.UserID
+	if u.Label != "" {
+		name = fmt.Sprintf("%s (%s)", name, u.Label)
+	}
+	if !u.LastLogin.IsZero() {
+		name = fmt.Sprintf("%s - last login %s", name, formatLastLogin(u.LastLogin))
+	}
+	return name
+}
+
+// formatLastLogin renders a last login time relative to now
+func formatLastLogin(t time.Time) string {
+	elapsed := time.Since(t)
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm ago", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(elapsed.Hours()))
+	case elapsed < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(elapsed.Hours()/24))
+	default:
+		return t.Local().Format("2006-01-02")
+	}
+}
+
+// loadStoredUsers returns the accounts stored for the environment.
+// A legacy user_id entry that is not yet part of the list is included as well.
+func loadStoredUsers(v *viper.Viper, currentEnv string) ([]storedUser, error) {
+	envConfig, err := configs.DecodeEnvironment(v, currentEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []storedUser
+	seen := make(map[string]bool)
+	for _, entry := range envConfig.Users {
+		if entry.UserID == "" || seen[entry.UserID] {
+			continue
+		}
+		seen[entry.UserID] = true
+
+		user := storedUser{UserID: entry.UserID, Label: entry.Label, Password: entry.Password}
+		if entry.LastLogin != "" {
+			user.LastLogin, _ = time.Parse(time.RFC3339, entry.LastLogin)
+		}
+		users = append(users, user)
+	}
+
+	if userID := envConfig.UserID; userID != "" && !seen[userID] {
+		users = append(users, storedUser{UserID: userID})
+	}
+
+	return users, nil
+}
+
+// setStoredUsers replaces the account list of the environment and writes the setting file
+func setStoredUsers(v *viper.Viper, currentEnv string, users []storedUser) error {
+	userList := make([]map[string]interface{}, 0, len(users))
+	for _, user := range users {
+		entry := map[string]interface{}{
+			"user_id": user.UserID,
+		}
+		if user.Label != "" {
+			entry["label"] = user.Label
+		}
+		if !user.LastLogin.IsZero() {
+			entry["last_login"] = user.LastLogin.UTC().Format(time.RFC3339)
+		}
+		if user.Password != "" {
+			entry["password"] = user.Password
+		}
+		userList = append(userList, entry)
+	}
+
+	v.Set(fmt.Sprintf("environments.%s.users", currentEnv), userList)
+	configs.MarkSettingDirty()
+	return nil
+}
+
+// rememberUser marks the user as the active account of the environment and adds it to the account list.
+// The last login time is refreshed, and the label is updated when --label is given.
+func rememberUser(v *viper.Viper, currentEnv, userID string) error {
+	users, err := loadStoredUsers(v, currentEnv)
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, user := range users {
+		if user.UserID == userID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		users = append(users, storedUser{UserID: userID})
+		index = len(users) - 1
+	}
+
+	users[index].LastLogin = time.Now()
+	if userLabel != "" {
+		users[index].Label = userLabel
+	}
+
+	v.Set(fmt.Sprintf("environments.%s.user_id", currentEnv), userID)
+	return setStoredUsers(v, currentEnv, users)
+}
+
+// recordUserLogin updates the account entry after a successful login when saving is allowed
+func recordUserLogin(v *viper.Viper, currentEnv, userID string) {
+	if userID == "" || !shouldSaveCredentials(v, currentEnv) {
+		return
+	}
+
+	if err := rememberUser(v, currentEnv, userID); err != nil {
+		pterm.Warning.Printf("Failed to update stored user: %v\n", err)
+	}
+}
+
+// sortUsersByRecency orders accounts from the most recently used one
+func sortUsersByRecency(users []storedUser) {
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].LastLogin.After(users[j].LastLogin)
+	})
+}
+
+// removeStoredUser deletes the account from the environment.
+// When the removed account is the active one, its cached tokens are removed too.
+func removeStoredUser(v *viper.Viper, currentEnv, userID string) error {
+	users, err := loadStoredUsers(v, currentEnv)
+	if err != nil {
+		return err
+	}
+
+	var remaining []storedUser
+	for _, user := range users {
+		if user.UserID != userID {
+			remaining = append(remaining, user)
+		}
+	}
+	if len(remaining) == len(users) {
+		return fmt.Errorf("user '%s' is not stored for environment '%s'", userID, currentEnv)
+	}
+
+	userIDKey := fmt.Sprintf("environments.%s.user_id", currentEnv)
+	if v.GetString(userIDKey) == userID {
+		v.Set(userIDKey, "")
+		if err := clearCachedTokens(currentEnv, userID); err != nil {
+			return fmt.Errorf("failed to remove cached tokens: %v", err)
+		}
+	}
+
+	return setStoredUsers(v, currentEnv, remaining)
+}
+
+// clearCachedTokens removes the access, refresh and grant tokens cached for the environment,
+// including the workspace tokens of the user
+func clearCachedTokens(currentEnv, userID string) error {
+	envCacheDir := filepath.Join(GetSettingDir(), "cache", currentEnv)
+	for _, tokenType := range []string{"access_token", "refresh_token", "grant_token"} {
+		if err := configs.RemoveCachedToken(filepath.Join(envCacheDir, tokenType)); err != nil {
+			return err
+		}
+	}
+
+	workspaceDir, err := configs.WorkspaceTokenDir(currentEnv, userID)
+	if err != nil {
+		return err
+	}
+	return configs.RemoveCachedTokens(workspaceDir)
+}
+
+// executeRemoveUser handles 'cfctl login --remove-user <id>'
+func executeRemoveUser(currentEnv, userID string) {
+	mainViper, err := readSettingViper()
+	if err != nil {
+		pterm.Error.Printf("Failed to read config file: %v\n", err)
+		exitWithError()
+	}
+
+	if err := removeStoredUser(mainViper, currentEnv, userID); err != nil {
+		pterm.Error.Printf("Failed to remove user: %v\n", err)
+		exitWithError()
+	}
+
+	pterm.Success.Printf("Removed user '%s' from environment '%s'.\n", userID, currentEnv)
+}
+
+// readSettingViper returns ~/.cfctl/setting.yaml from the shared setting store.
+// Changes are saved with configs.MarkSettingDirty at the end of the command.
+func readSettingViper() (*viper.Viper, error) {
+	return configs.Setting()
+}
+
+// selectStoredUser shows the account selector when accounts are stored for the environment.
+// It returns the selected user ID, or an empty string when a new user ID should be entered.
+// The user given by --user is taken as it is, and without a terminal the active user is kept.
+func selectStoredUser(v *viper.Viper, currentEnv string) string {
+	activeUserID := v.GetString(fmt.Sprintf("environments.%s.user_id", currentEnv))
+	if loginUser != "" {
+		switchActiveUser(v, currentEnv, activeUserID, loginUser)
+		return loginUser
+	}
+	if !configs.Interactive() {
+		return activeUserID
+	}
+
+	for {
+		users, err := loadStoredUsers(v, currentEnv)
+		if err != nil {
+			pterm.Error.Println(err)
+			exitWithError()
+		}
+		if len(users) == 0 {
+			return ""
+		}
+		sortUsersByRecency(users)
+
+		options := make([]string, 0, len(users)+2)
+		selectedIndex := 0
+		for i, user := range users {
+			options = append(options, user.displayName())
+			if user.UserID == activeUserID {
+				selectedIndex = i
+			}
+		}
+		newUserIndex := len(options)
+		removeUserIndex := newUserIndex + 1
+		options = append(options, "Enter a new user ID", "Remove a stored user")
+
+		choice := runSelector("Select User", options, selectedIndex)
+		switch choice {
+		case newUserIndex:
+			if err := clearCachedTokens(currentEnv, activeUserID); err != nil {
+				pterm.Warning.Printf("Failed to remove cached tokens: %v\n", err)
+			}
+			return ""
+		case removeUserIndex:
+			target := users[runSelector("Remove User", options[:newUserIndex], 0)].UserID
+			if err := removeStoredUser(v, currentEnv, target); err != nil {
+				pterm.Error.Printf("Failed to remove user: %v\n", err)
+				exitWithError()
+			}
+			pterm.Success.Printf("Removed user '%s'.\n", target)
+			if target == activeUserID {
+				activeUserID = ""
+			}
+			continue
+		}
+
+		selectedUserID := users[choice].UserID
+		switchActiveUser(v, currentEnv, activeUserID, selectedUserID)
+		return selectedUserID
+	}
+}
+
+// switchActiveUser makes userID the active user of the environment when it is another one
+func switchActiveUser(v *viper.Viper, currentEnv, activeUserID, userID string) {
+	if userID == activeUserID {
+		return
+	}
+	// Cached tokens belong to the previously active account
+	if err := clearCachedTokens(currentEnv, activeUserID); err != nil {
+		pterm.Warning.Printf("Failed to remove cached tokens: %v\n", err)
+	}
+	if shouldSaveCredentials(v, currentEnv) {
+		if err := rememberUser(v, currentEnv, userID); err != nil {
+			pterm.Warning.Printf("Failed to save active user: %v\n", err)
+		}
+	}
+}
+
+// runSelector shows a selector of options and returns the index of the chosen option
+func runSelector(title string, options []string, selectedIndex int) int {
+	requireInteractive(fmt.Sprintf("show '%s'", title))
+	index, err := ui.Select[string]{Title: title, Items: options, Selected: selectedIndex}.Run()
+	if err != nil {
+		exitOnSelectError(err)
+	}
+	return index
+}
+
+// exitOnSelectError ends the command after a selector was cancelled or failed
+func exitOnSelectError(err error) {
+	if errors.Is(err, ui.ErrCancelled) {
+		pterm.Error.Println("Selection cancelled.")
+	} else {
+		pterm.Error.Println(err)
+	}
+	exitWithError()
+}
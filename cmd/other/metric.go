@@ -0,0 +1,204 @@
+package other
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var sparklineTicks = []rune("▁▂▃▄▅▆▇█")
+
+// MetricCmd represents the metric command
+var MetricCmd = &cobra.Command{
+	Use:   "metric",
+	Short: "Query metrics of the monitoring service",
+}
+
+var metricGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Get metric datapoints of a cloud service",
+	Long: `Get metric datapoints of a cloud service from the monitoring service.
+The metric is matched case-insensitively against the metric keys and names of the data source,
+so '--metric cpu' selects e.g. 'CPUUtilization'.`,
+	Example: `  $ cfctl metric get --resource cloud-svc-1234567890ab --metric cpu --period 1h
+  $ cfctl metric get --resource cloud-svc-1234567890ab --metric network --period 24h --format csv > network.csv`,
+	Run: func(cmd *cobra.Command, args []string) {
+		resourceID, _ := cmd.Flags().GetString("resource")
+		metricQuery, _ := cmd.Flags().GetString("metric")
+		period, _ := cmd.Flags().GetDuration("period")
+		stat, _ := cmd.Flags().GetString("stat")
+		dataSourceID, _ := cmd.Flags().GetString("data-source")
+		outputFormat, _ := cmd.Flags().GetString("format")
+
+		if dataSourceID == "" {
+			dataSources, err := fetchResults("monitoring", "DataSource", []string{"monitoring_type=METRIC"})
+			if err != nil {
+				pterm.Error.Printf("Failed to list monitoring data sources: %v\n", err)
+				return
+			}
+			if len(dataSources) == 0 {
+				pterm.Error.Println("No metric data source found.")
+				return
+			}
+			dataSourceID = stringValue(dataSources[0]["data_source_id"])
+		}
+
+		metric, err := findMetric(dataSourceID, resourceID, metricQuery)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		end := time.Now().UTC()
+		start := end.Add(-period)
+		interval := int(math.Max(60, period.Seconds()/60))
+
+		jsonParameter, _ := json.Marshal(map[string]interface{}{
+			"data_source_id": dataSourceID,
+			"resource_type":  "inventory.CloudService",
+			"resources":      []string{resourceID},
+			"metric":         stringValue(metric["key"]),
+			"start":          start.Format(time.RFC3339),
+			"end":            end.Format(time.RFC3339),
+			"period":         interval,
+			"stat":           stat,
+		})
+
+		resp, err := transport.FetchService("monitoring", "get_data", "Metric", &transport.FetchOptions{
+			JSONParameter: string(jsonParameter),
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to get metric data: %v\n", err)
+			return
+		}
+
+		labels, _ := resp["labels"].([]interface{})
+		resourceValues, _ := resp["resource_values"].(map[string]interface{})
+		rawValues, _ := resourceValues[resourceID].([]interface{})
+
+		values := make([]float64, len(rawValues))
+		for i, value := range rawValues {
+			values[i] = toFloat(value)
+		}
+
+		if outputFormat == "csv" {
+			writer := csv.NewWriter(os.Stdout)
+			writer.Write([]string{"timestamp", stringValue(metric["key"])})
+			for i, value := range values {
+				timestamp := ""
+				if i < len(labels) {
+					timestamp = stringValue(labels[i])
+				}
+				writer.Write([]string{timestamp, fmt.Sprintf("%g", value)})
+			}
+			writer.Flush()
+			return
+		}
+
+		if len(values) == 0 {
+			pterm.Info.Println("No datapoints in the selected period.")
+			return
+		}
+
+		minValue, maxValue, sum := values[0], values[0], 0.0
+		for _, value := range values {
+			minValue = math.Min(minValue, value)
+			maxValue = math.Max(maxValue, value)
+			sum += value
+		}
+
+		unit := stringValue(metric["unit"])
+		pterm.DefaultSection.Printf("%s (%s) of %s, last %s\n", stringValue(metric["name"]), stat, resourceID, period)
+		fmt.Println(sparkline(values))
+		fmt.Printf("min %.2f%s  max %.2f%s  avg %.2f%s  (%d datapoints)\n",
+			minValue, unit, maxValue, unit, sum/float64(len(values)), unit, len(values))
+	},
+}
+
+// findMetric returns the metric of the resource whose key or name contains the query
+func findMetric(dataSourceID, resourceID, query string) (map[string]interface{}, error) {
+	jsonParameter, _ := json.Marshal(map[string]interface{}{
+		"data_source_id": dataSourceID,
+		"resource_type":  "inventory.CloudService",
+		"resources":      []string{resourceID},
+	})
+
+	resp, err := transport.FetchService("monitoring", "list", "Metric", &transport.FetchOptions{
+		JSONParameter: string(jsonParameter),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list metrics: %v", err)
+	}
+
+	metrics, _ := resp["metrics"].([]interface{})
+	query = strings.ToLower(query)
+
+	var matches []map[string]interface{}
+	for _, rawMetric := range metrics {
+		metric, ok := rawMetric.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key := stringValue(metric["key"])
+		name := stringValue(metric["name"])
+		if strings.EqualFold(key, query) {
+			return metric, nil
+		}
+		if strings.Contains(strings.ToLower(key), query) || strings.Contains(strings.ToLower(name), query) {
+			matches = append(matches, metric)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no metric matching '%s' found for %s", query, resourceID)
+	case 1:
+		return matches[0], nil
+	}
+
+	keys := make([]string, 0, len(matches))
+	for _, metric := range matches {
+		keys = append(keys, stringValue(metric["key"]))
+	}
+	return nil, fmt.Errorf("metric '%s' is ambiguous: %s", query, strings.Join(keys, ", "))
+}
+
+// sparkline renders values as a single line of block characters
+func sparkline(values []float64) string {
+	minValue, maxValue := values[0], values[0]
+	for _, value := range values {
+		minValue = math.Min(minValue, value)
+		maxValue = math.Max(maxValue, value)
+	}
+
+	var sb strings.Builder
+	for _, value := range values {
+		index := 0
+		if maxValue > minValue {
+			index = int((value - minValue) / (maxValue - minValue) * float64(len(sparklineTicks)-1))
+		}
+		sb.WriteRune(sparklineTicks[index])
+	}
+	return sb.String()
+}
+
+func init() {
+	MetricCmd.AddCommand(metricGetCmd)
+
+	metricGetCmd.Flags().String("resource", "", "Cloud service ID")
+	metricGetCmd.Flags().String("metric", "", "Metric key or part of its name (e.g. cpu)")
+	metricGetCmd.Flags().Duration("period", time.Hour, "Time range up to now (e.g. 1h, 24h)")
+	metricGetCmd.Flags().String("stat", "AVERAGE", "Statistic (AVERAGE, MAX, MIN, SUM)")
+	metricGetCmd.Flags().String("data-source", "", "Monitoring data source ID (default: first metric data source)")
+	metricGetCmd.Flags().String("format", "sparkline", "Output format (sparkline, csv)")
+	metricGetCmd.MarkFlagRequired("resource")
+	metricGetCmd.MarkFlagRequired("metric")
+}
@@ -0,0 +1,58 @@
+package other
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudforet-io/cfctl/internal/notices"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NoticesCmd lists the deprecated commands, flags and setting keys of this version
+var NoticesCmd = &cobra.Command{
+	Use:   "notices",
+	Short: "List the active deprecations",
+	Long: `List the commands, flags and setting keys that are deprecated in this version of cfctl,
+with what replaces them. Using one prints a warning on stderr; set CFCTL_NOTICES=json to get
+the warnings as JSON lines instead, or CFCTL_NOTICES=off to silence them.`,
+	Example: `  $ cfctl notices
+  $ cfctl notices -o json
+  $ CFCTL_NOTICES=json cfctl config show 2> notices.jsonl`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		all := notices.All()
+
+		switch outputFormat {
+		case "json":
+			data, err := json.MarshalIndent(all, "", "  ")
+			if err != nil {
+				pterm.Error.Println(err)
+				return
+			}
+			fmt.Println(string(data))
+		case "table":
+			if len(all) == 0 {
+				pterm.Info.Println("Nothing is deprecated.")
+				return
+			}
+			tableData := pterm.TableData{{"ID", "Kind", "Deprecated", "Replacement", "Removal"}}
+			for _, notice := range all {
+				removal := notice.Removal
+				if removal == "" {
+					removal = "-"
+				}
+				tableData = append(tableData, []string{notice.ID, notice.Kind, notice.Name, notice.Replacement, removal})
+			}
+			pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		default:
+			pterm.Error.Printf("Unsupported output format '%s', use table or json.\n", outputFormat)
+		}
+	},
+}
+
+func init() {
+	NoticesCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+	NoticesCmd.RegisterFlagCompletionFunc("output", completeFrom([]string{"table", "json"}))
+}
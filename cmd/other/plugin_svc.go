@@ -0,0 +1,242 @@
+package other
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/output"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// PluginSvcCmd represents the plugin-svc command
+var PluginSvcCmd = &cobra.Command{
+	Use:   "plugin-svc",
+	Short: "Manage SpaceONE plugins",
+	Long: `List plugins registered in the repository service and install, upgrade
+or verify them through the plugin service.`,
+}
+
+var pluginSvcListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List plugins registered in the repository",
+	Example: `  $ cfctl plugin-svc list
+  $ cfctl plugin-svc list --service-type inventory.Collector
+  $ cfctl plugin-svc list --query "results[].plugin_id"`,
+	SilenceUsage: true,
+	Annotations:  map[string]string{output.QueryAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serviceType, _ := cmd.Flags().GetString("service-type")
+		query, _ := cmd.Flags().GetString("query")
+		if err := output.ValidateQuery(query); err != nil {
+			return err
+		}
+
+		var parameters []string
+		if serviceType != "" {
+			parameters = append(parameters, fmt.Sprintf("service_type=%s", serviceType))
+		}
+
+		resp, err := transport.FetchService("repository", "list", "Plugin", &transport.FetchOptions{
+			Parameters: parameters,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list plugins: %v", err)
+		}
+		if resp == nil {
+			return fmt.Errorf("failed to list plugins: no response from the repository service")
+		}
+		if queried, err := printQueried(cmd, resp); queried {
+			return err
+		}
+
+		results, _ := resp["results"].([]interface{})
+		if len(results) == 0 {
+			pterm.Info.Println("No plugins found.")
+			return nil
+		}
+
+		tableData := pterm.TableData{{"Plugin ID", "Name", "Service Type", "Image", "Registry"}}
+		for _, result := range results {
+			plugin, ok := result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			tableData = append(tableData, []string{
+				fmt.Sprintf("%v", plugin["plugin_id"]),
+				fmt.Sprintf("%v", plugin["name"]),
+				fmt.Sprintf("%v", plugin["service_type"]),
+				fmt.Sprintf("%v", plugin["image"]),
+				fmt.Sprintf("%v", plugin["registry_type"]),
+			})
+		}
+
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		return nil
+	},
+}
+
+var pluginSvcInstallCmd = &cobra.Command{
+	Use:   "install <plugin_id>",
+	Short: "Install a plugin version",
+	Long: `Install a plugin through the plugin service and wait until its endpoint is ready.
+Without --version, the version can be selected from the versions registered in the repository.`,
+	Example: `  $ cfctl plugin-svc install plugin-aws-cloud-service-inven-collector --version 1.2.3
+  $ cfctl plugin-svc install plugin-aws-cloud-service-inven-collector`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPluginInstall(cmd, args[0], "MANUAL")
+	},
+}
+
+var pluginSvcUpgradeCmd = &cobra.Command{
+	Use:   "upgrade <plugin_id>",
+	Short: "Upgrade a plugin to another version",
+	Long: `Upgrade a plugin through the plugin service and wait until the new endpoint is ready.
+Without --version, the latest version registered in the repository is used.`,
+	Example: `  $ cfctl plugin-svc upgrade plugin-aws-cloud-service-inven-collector
+  $ cfctl plugin-svc upgrade plugin-aws-cloud-service-inven-collector --version 1.3.0`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPluginInstall(cmd, args[0], "AUTO")
+	},
+}
+
+var pluginSvcVerifyCmd = &cobra.Command{
+	Use:     "verify <plugin_id>",
+	Short:   "Verify that an installed plugin responds",
+	Example: `  $ cfctl plugin-svc verify plugin-aws-cloud-service-inven-collector --version 1.2.3`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pluginID := args[0]
+		version, _ := cmd.Flags().GetString("version")
+
+		parameters := []string{fmt.Sprintf("plugin_id=%s", pluginID)}
+		if version != "" {
+			parameters = append(parameters, fmt.Sprintf("version=%s", version))
+		}
+
+		spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Verifying plugin '%s'", pluginID))
+		resp, err := transport.FetchService("plugin", "get_plugin_metadata", "Plugin", &transport.FetchOptions{
+			Parameters: parameters,
+		})
+		if err != nil {
+			spinner.Fail(fmt.Sprintf("Plugin '%s' failed verification: %v", pluginID, err))
+			cleanup.Exit(1)
+		}
+		if resp == nil {
+			spinner.Fail(fmt.Sprintf("Plugin '%s' failed verification: no response from the plugin service", pluginID))
+			cleanup.Exit(1)
+		}
+
+		spinner.Success(fmt.Sprintf("Plugin '%s' responded with its metadata", pluginID))
+		if metadata, ok := resp["metadata"].(map[string]interface{}); ok {
+			keys := make([]string, 0, len(metadata))
+			for key := range metadata {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			pterm.Info.Printf("Metadata keys: %s\n", strings.Join(keys, ", "))
+		}
+	},
+}
+
+// runPluginInstall resolves the version and waits until the plugin service returns the plugin endpoint
+func runPluginInstall(cmd *cobra.Command, pluginID, upgradeMode string) {
+	version, _ := cmd.Flags().GetString("version")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	if version == "" {
+		versions, err := fetchPluginVersions(pluginID)
+		if err != nil {
+			pterm.Error.Printf("Failed to get versions of '%s': %v\n", pluginID, err)
+			return
+		}
+		if len(versions) == 0 {
+			pterm.Error.Printf("No versions registered for '%s'.\n", pluginID)
+			return
+		}
+
+		switch {
+		case upgradeMode == "AUTO":
+			version = versions[0]
+		case !configs.Interactive():
+			pterm.Error.Printf("No terminal to select a version of '%s', pass it with --version (latest: %s).\n", pluginID, versions[0])
+			return
+		default:
+			version = versions[runSelector("Select Version", versions, 0)]
+		}
+	}
+
+	parameters := []string{
+		fmt.Sprintf("plugin_id=%s", pluginID),
+		fmt.Sprintf("version=%s", version),
+		fmt.Sprintf("upgrade_mode=%s", upgradeMode),
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Installing '%s' version %s", pluginID, version))
+	deadline := time.Now().Add(timeout)
+	for attempt := 1; ; attempt++ {
+		resp, err := transport.FetchService("plugin", "get_plugin_endpoint", "Plugin", &transport.FetchOptions{
+			Parameters: parameters,
+		})
+		if err == nil && resp != nil {
+			if endpoint, ok := resp["endpoint"].(string); ok && endpoint != "" {
+				spinner.Success(fmt.Sprintf("Plugin '%s' version %s is ready at %s", pluginID, version, endpoint))
+				return
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				spinner.Fail(fmt.Sprintf("Timed out installing '%s': %v", pluginID, err))
+			} else {
+				spinner.Fail(fmt.Sprintf("Timed out installing '%s'", pluginID))
+			}
+			cleanup.Exit(1)
+		}
+
+		spinner.UpdateText(fmt.Sprintf("Installing '%s' version %s (attempt %d)", pluginID, version, attempt))
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// fetchPluginVersions returns the versions of a plugin from the repository, newest first
+func fetchPluginVersions(pluginID string) ([]string, error) {
+	resp, err := transport.FetchService("repository", "get_versions", "Plugin", &transport.FetchOptions{
+		Parameters: []string{fmt.Sprintf("plugin_id=%s", pluginID)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	if results, ok := resp["results"].([]interface{}); ok {
+		for _, result := range results {
+			if version, ok := result.(string); ok {
+				versions = append(versions, version)
+			}
+		}
+	}
+	return versions, nil
+}
+
+func init() {
+	PluginSvcCmd.AddCommand(pluginSvcListCmd)
+	PluginSvcCmd.AddCommand(pluginSvcInstallCmd)
+	PluginSvcCmd.AddCommand(pluginSvcUpgradeCmd)
+	PluginSvcCmd.AddCommand(pluginSvcVerifyCmd)
+
+	pluginSvcListCmd.Flags().String("service-type", "", "Filter by service type (e.g. inventory.Collector)")
+
+	for _, cmd := range []*cobra.Command{pluginSvcInstallCmd, pluginSvcUpgradeCmd} {
+		cmd.Flags().String("version", "", "Plugin version (default: select interactively, or latest for upgrade)")
+		cmd.Flags().Duration("timeout", 10*time.Minute, "Maximum time to wait for the plugin endpoint")
+	}
+	pluginSvcVerifyCmd.Flags().String("version", "", "Plugin version")
+}
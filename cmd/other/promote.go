@@ -0,0 +1,308 @@
+package other
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// promoteKind is a resource type that can be promoted. The resource is matched in the target
+// environment by KeyField, and updated when it exists there or created otherwise.
+type promoteKind struct {
+	Service  string
+	Resource string
+	IDField  string
+	KeyField string
+	// Fields are copied from the source resource when set
+	Fields []string
+	// Immutable fields are left out when updating an existing resource
+	Immutable []string
+	// References are IDs of other resources, remapped to the resource of the same name
+	References []promoteReference
+}
+
+// promoteReference is a field holding the ID of a resource matched by name between environments
+type promoteReference struct {
+	Field    string
+	Service  string
+	Resource string
+}
+
+// promoteKinds are the resource types of 'cfctl promote --resource'
+var promoteKinds = map[string]promoteKind{
+	"dashboard": {
+		Service:   "dashboard",
+		Resource:  "PublicDashboard",
+		IDField:   "dashboard_id",
+		KeyField:  "name",
+		Fields:    []string{"name", "version", "layouts", "vars", "vars_schema", "options", "labels", "tags", "resource_group"},
+		Immutable: []string{"version", "resource_group"},
+	},
+	"role": {
+		Service:   "identity",
+		Resource:  "Role",
+		IDField:   "role_id",
+		KeyField:  "name",
+		Fields:    []string{"name", "role_type", "permissions", "page_access", "tags"},
+		Immutable: []string{"role_type"},
+	},
+	"notification": {
+		Service:   "notification",
+		Resource:  "ProjectChannel",
+		IDField:   "project_channel_id",
+		KeyField:  "name",
+		Fields:    []string{"name", "protocol_id", "project_id", "data", "schema", "is_scheduled", "schedule", "notification_level", "tags"},
+		Immutable: []string{"protocol_id", "project_id", "schema", "is_scheduled", "schedule"},
+		References: []promoteReference{
+			{Field: "protocol_id", Service: "notification", Resource: "Protocol"},
+			{Field: "project_id", Service: "identity", Resource: "Project"},
+		},
+	},
+}
+
+// PromoteCmd copies a resource from one environment to another
+var PromoteCmd = &cobra.Command{
+	Use:   "promote <resource_id>",
+	Short: "Copy a resource from one environment to another",
+	Long: `Export a resource from the --from environment and apply it to the --to environment of
+setting.yaml. The resource is updated when one of the same name exists in the target
+environment, and created otherwise.
+
+IDs referring to other resources, like the project and protocol of a notification channel, are
+remapped to the resource of the same name in the target environment. Use --map to give the
+target ID yourself; it also replaces the ID wherever it appears in the resource, e.g. in the
+options of a dashboard.
+
+Resource types: ` + strings.Join(promoteKindNames(), ", "),
+	Example: `  # Promote a dashboard from dev to staging
+  $ cfctl promote --resource dashboard public-dash-1234567890ab --from dev-app --to stg-app
+
+  # Review the request without applying it, remapping a project by hand
+  $ cfctl promote --resource notification project-ch-1234567890ab --from dev-app --to stg-app \
+      --map project-1234567890ab=project-ba0987654321 --dry-run`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kindName, _ := cmd.Flags().GetString("resource")
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		mapFlags, _ := cmd.Flags().GetStringArray("map")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		kind, ok := promoteKinds[kindName]
+		if !ok {
+			pterm.Error.Printf("Unknown resource type '%s', use one of %s.\n", kindName, strings.Join(promoteKindNames(), ", "))
+			return
+		}
+		if from == to {
+			pterm.Error.Println("--from and --to must be different environments.")
+			return
+		}
+		mappings := make(map[string]string, len(mapFlags))
+		for _, m := range mapFlags {
+			oldID, newID, found := strings.Cut(m, "=")
+			if !found || oldID == "" || newID == "" {
+				pterm.Error.Printf("Invalid --map '%s', use <source_id>=<target_id>.\n", m)
+				return
+			}
+			mappings[oldID] = newID
+		}
+		defer transport.UnpinSession()
+
+		// Export the resource and the names of the resources it refers to from the source
+		if _, err := transport.PinEnvironment(from); err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		source, err := transport.FetchService(kind.Service, "get", kind.Resource, &transport.FetchOptions{
+			Parameters: []string{fmt.Sprintf("%s=%s", kind.IDField, args[0])},
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to get %s %s from %s: %v\n", kindName, args[0], from, err)
+			return
+		}
+		referenceNames := make(map[string]string)
+		for _, ref := range kind.References {
+			id := stringValue(source[ref.Field])
+			if id == "" || mappings[id] != "" {
+				continue
+			}
+			name, err := promoteReferenceName(ref, id)
+			if err != nil {
+				pterm.Error.Printf("Failed to resolve %s %s in %s: %v\n", ref.Field, id, from, err)
+				return
+			}
+			referenceNames[ref.Field] = name
+		}
+
+		// Remap the references and find the resource in the target
+		if _, err := transport.PinEnvironment(to); err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		for _, ref := range kind.References {
+			name, ok := referenceNames[ref.Field]
+			if !ok {
+				continue
+			}
+			id, err := promoteReferenceID(ref, name)
+			if err != nil {
+				pterm.Error.Printf("Failed to remap %s: %v\n", ref.Field, err)
+				pterm.Info.Printf("Pass --map %s=<id in %s> to map it by hand.\n", stringValue(source[ref.Field]), to)
+				return
+			}
+			mappings[stringValue(source[ref.Field])] = id
+		}
+
+		spec := promoteSpec(kind, source, mappings)
+		key := stringValue(source[kind.KeyField])
+		existing, err := fetchResults(kind.Service, kind.Resource, []string{fmt.Sprintf("%s=%s", kind.KeyField, key)})
+		if err != nil {
+			pterm.Error.Printf("Failed to look up %s '%s' in %s: %v\n", kindName, key, to, err)
+			return
+		}
+
+		verb := "create"
+		if len(existing) > 1 {
+			pterm.Error.Printf("%d %s resources named '%s' exist in %s, remove the duplicates first.\n", len(existing), kindName, key, to)
+			return
+		}
+		if len(existing) == 1 {
+			verb = "update"
+			for _, field := range kind.Immutable {
+				delete(spec, field)
+			}
+			spec[kind.IDField] = stringValue(existing[0][kind.IDField])
+		}
+
+		if dryRun {
+			encoder := yaml.NewEncoder(os.Stdout)
+			encoder.SetIndent(2)
+			if err := encoder.Encode(ResourceSpec{Service: kind.Service, Verb: verb, Resource: kind.Resource, Spec: spec}); err != nil {
+				pterm.Error.Printf("Failed to format the request: %v\n", err)
+			}
+			encoder.Close()
+			return
+		}
+
+		jsonParameter, err := json.Marshal(spec)
+		if err != nil {
+			pterm.Error.Printf("Failed to encode the request: %v\n", err)
+			return
+		}
+		resp, err := transport.FetchService(kind.Service, verb, kind.Resource, &transport.FetchOptions{
+			JSONParameter: string(jsonParameter),
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to %s %s '%s' in %s: %v\n", verb, kindName, key, to, firstLine(err.Error()))
+			cleanup.Exit(1)
+		}
+		action := "Created"
+		if verb == "update" {
+			action = "Updated"
+		}
+		pterm.Success.Printf("%s %s '%s' in %s as %s.\n", action, kindName, key, to, stringValue(resp[kind.IDField]))
+	},
+}
+
+// promoteSpec copies the fields of the resource, replacing the mapped IDs in every string
+func promoteSpec(kind promoteKind, source map[string]interface{}, mappings map[string]string) map[string]interface{} {
+	// Longer IDs first, so that an ID that is the prefix of another does not replace part of it
+	oldIDs := make([]string, 0, len(mappings))
+	for oldID := range mappings {
+		oldIDs = append(oldIDs, oldID)
+	}
+	sort.Slice(oldIDs, func(i, j int) bool { return len(oldIDs[i]) > len(oldIDs[j]) })
+	pairs := make([]string, 0, 2*len(oldIDs))
+	for _, oldID := range oldIDs {
+		pairs = append(pairs, oldID, mappings[oldID])
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	spec := make(map[string]interface{})
+	for _, field := range kind.Fields {
+		if value, ok := source[field]; ok && value != nil {
+			spec[field] = remapIDs(value, replacer)
+		}
+	}
+	return spec
+}
+
+// remapIDs replaces the mapped IDs in the strings of value, including nested ones
+func remapIDs(value interface{}, replacer *strings.Replacer) interface{} {
+	switch v := value.(type) {
+	case string:
+		return replacer.Replace(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = remapIDs(item, replacer)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = remapIDs(item, replacer)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// promoteReferenceName returns the name of the referenced resource in the pinned environment
+func promoteReferenceName(ref promoteReference, id string) (string, error) {
+	resp, err := transport.FetchService(ref.Service, "get", ref.Resource, &transport.FetchOptions{
+		Parameters: []string{fmt.Sprintf("%s=%s", ref.Field, id)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return stringValue(resp["name"]), nil
+}
+
+// promoteReferenceID returns the ID of the only resource of the name in the pinned environment
+func promoteReferenceID(ref promoteReference, name string) (string, error) {
+	results, err := fetchResults(ref.Service, ref.Resource, []string{fmt.Sprintf("name=%s", name)})
+	if err != nil {
+		return "", err
+	}
+	switch len(results) {
+	case 0:
+		return "", fmt.Errorf("no %s named '%s' found", ref.Resource, name)
+	case 1:
+		return stringValue(results[0][ref.Field]), nil
+	default:
+		return "", fmt.Errorf("%d resources of %s are named '%s'", len(results), ref.Resource, name)
+	}
+}
+
+func promoteKindNames() []string {
+	names := make([]string, 0, len(promoteKinds))
+	for name := range promoteKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	PromoteCmd.Flags().String("resource", "", "Type of the resource to promote")
+	PromoteCmd.Flags().String("from", "", "Environment to export the resource from")
+	PromoteCmd.Flags().String("to", "", "Environment to apply the resource to")
+	PromoteCmd.Flags().StringArray("map", nil, "Replace a source ID by a target ID, as <source_id>=<target_id> (repeatable)")
+	PromoteCmd.Flags().Bool("dry-run", false, "Print the request in the format of 'cfctl apply' instead of applying it")
+	PromoteCmd.MarkFlagRequired("resource")
+	PromoteCmd.MarkFlagRequired("from")
+	PromoteCmd.MarkFlagRequired("to")
+	PromoteCmd.RegisterFlagCompletionFunc("resource", completeFrom(promoteKindNames()))
+	PromoteCmd.RegisterFlagCompletionFunc("from", completeEnvironments)
+	PromoteCmd.RegisterFlagCompletionFunc("to", completeEnvironments)
+}
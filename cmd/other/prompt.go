@@ -0,0 +1,49 @@
+package other
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// PromptCmd prints the current environment for shell prompts. It runs on every prompt,
+// so it only reads the environment key of setting.yaml and prints nothing when it is unset.
+var PromptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print the current environment for the shell prompt",
+	Example: `  # bash
+  $ PS1='[$(cfctl prompt)] \w $ '
+
+  # zsh
+  $ PROMPT='$(cfctl prompt --format "cf:{env}") %~ %# '`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+
+		settingDir, err := configs.SettingDir()
+		if err != nil {
+			return
+		}
+		data, err := os.ReadFile(filepath.Join(settingDir, "setting.yaml"))
+		if err != nil {
+			return
+		}
+		var setting struct {
+			Environment string `yaml:"environment"`
+		}
+		if yaml.Unmarshal(data, &setting) != nil || setting.Environment == "" {
+			return
+		}
+
+		fmt.Print(strings.ReplaceAll(format, "{env}", setting.Environment))
+	},
+}
+
+func init() {
+	PromptCmd.Flags().String("format", "{env}", "Format of the output, {env} is replaced with the current environment")
+}
@@ -0,0 +1,191 @@
+package other
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// ProviderCmd represents the provider command
+var ProviderCmd = &cobra.Command{
+	Use:   "provider",
+	Short: "Browse providers and their schemas",
+	Long:  `Browse cloud providers and the schemas used to create service accounts and secrets.`,
+}
+
+var providerListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List providers",
+	Run: func(cmd *cobra.Command, args []string) {
+		resp, err := transport.FetchService("identity", "list", "Provider", &transport.FetchOptions{})
+		if err != nil {
+			pterm.Error.Printf("Failed to list providers: %v\n", err)
+			return
+		}
+
+		results, _ := resp["results"].([]interface{})
+		if len(results) == 0 {
+			pterm.Info.Println("No providers found.")
+			return
+		}
+
+		tableData := pterm.TableData{{"Provider", "Name", "Order", "Color"}}
+		for _, result := range results {
+			provider, ok := result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			tableData = append(tableData, []string{
+				stringValue(provider["provider"]),
+				stringValue(provider["name"]),
+				stringValue(provider["order"]),
+				stringValue(provider["color"]),
+			})
+		}
+
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	},
+}
+
+var providerSchemaCmd = &cobra.Command{
+	Use:   "schema <provider>",
+	Short: "Show the schemas of a provider",
+	Long: `Show the schemas of a provider as tables of fields.
+With --template, a YAML skeleton to fill in for service account creation is printed instead.`,
+	Example: `  $ cfctl provider schema aws
+  $ cfctl provider schema aws --schema-type SECRET
+  $ cfctl provider schema google_cloud --template`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		provider := args[0]
+		schemaType, _ := cmd.Flags().GetString("schema-type")
+		printTemplate, _ := cmd.Flags().GetBool("template")
+
+		schemas, err := fetchProviderSchemas(provider, schemaType)
+		if err != nil {
+			pterm.Error.Printf("Failed to list schemas: %v\n", err)
+			return
+		}
+		if len(schemas) == 0 {
+			pterm.Info.Printf("No schemas found for provider '%s'.\n", provider)
+			return
+		}
+
+		for _, schema := range schemas {
+			fields := schemaFields(schema)
+
+			if printTemplate {
+				fmt.Printf("# %s (%s)\n", stringValue(schema["name"]), stringValue(schema["schema_type"]))
+				fmt.Println("data:")
+				for _, field := range fields {
+					comment := field.Title
+					if field.Required {
+						comment = strings.TrimSpace(comment + " (required)")
+					}
+					fmt.Printf("  %s: \"\" # %s\n", field.Name, comment)
+				}
+				fmt.Println()
+				continue
+			}
+
+			pterm.DefaultSection.Printf("%s [%s] %s\n", stringValue(schema["name"]), stringValue(schema["schema_type"]), stringValue(schema["schema_id"]))
+			tableData := pterm.TableData{{"Field", "Title", "Type", "Required", "Description"}}
+			for _, field := range fields {
+				required := ""
+				if field.Required {
+					required = "yes"
+				}
+				tableData = append(tableData, []string{field.Name, field.Title, field.Type, required, field.Description})
+			}
+			pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		}
+	},
+}
+
+// schemaField is a property of a provider schema
+type schemaField struct {
+	Name        string
+	Title       string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// fetchProviderSchemas returns the schemas of a provider, optionally filtered by schema type
+func fetchProviderSchemas(provider, schemaType string) ([]map[string]interface{}, error) {
+	parameters := []string{fmt.Sprintf("provider=%s", provider)}
+	if schemaType != "" {
+		parameters = append(parameters, fmt.Sprintf("schema_type=%s", schemaType))
+	}
+
+	resp, err := transport.FetchService("identity", "list", "Schema", &transport.FetchOptions{
+		Parameters: parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var schemas []map[string]interface{}
+	if results, ok := resp["results"].([]interface{}); ok {
+		for _, result := range results {
+			if schema, ok := result.(map[string]interface{}); ok {
+				schemas = append(schemas, schema)
+			}
+		}
+	}
+	return schemas, nil
+}
+
+// schemaFields flattens the JSON schema properties of a schema, required fields first
+func schemaFields(schema map[string]interface{}) []schemaField {
+	jsonSchema, _ := schema["schema"].(map[string]interface{})
+	properties, _ := jsonSchema["properties"].(map[string]interface{})
+
+	required := make(map[string]bool)
+	if requiredList, ok := jsonSchema["required"].([]interface{}); ok {
+		for _, name := range requiredList {
+			required[stringValue(name)] = true
+		}
+	}
+
+	var fields []schemaField
+	for name, rawProperty := range properties {
+		property, _ := rawProperty.(map[string]interface{})
+		fields = append(fields, schemaField{
+			Name:        name,
+			Title:       stringValue(property["title"]),
+			Type:        stringValue(property["type"]),
+			Description: stringValue(property["description"]),
+			Required:    required[name],
+		})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].Required != fields[j].Required {
+			return fields[i].Required
+		}
+		return fields[i].Name < fields[j].Name
+	})
+
+	return fields
+}
+
+// stringValue formats a response value for display, returning an empty string for missing values
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func init() {
+	ProviderCmd.AddCommand(providerListCmd)
+	ProviderCmd.AddCommand(providerSchemaCmd)
+
+	providerSchemaCmd.Flags().String("schema-type", "", "Filter by schema type (e.g. SERVICE_ACCOUNT, TRUSTED_ACCOUNT, SECRET)")
+	providerSchemaCmd.Flags().Bool("template", false, "Print a YAML skeleton for service account creation")
+}
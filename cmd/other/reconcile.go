@@ -0,0 +1,325 @@
+package other
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/output"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Reconciliation statuses of an asset
+const (
+	reconcileMissing   = "missing"
+	reconcileExtra     = "extra"
+	reconcileMismatch  = "mismatch"
+	reconcileDuplicate = "duplicate"
+)
+
+// ReconcileCmd compares an external asset list with the inventory
+var ReconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Compare an asset list from a CMDB with the inventory",
+	Long: `Compare the rows of a CSV file exported from a CMDB with the cloud services of the inventory.
+Rows are matched by the --key column, against tags.<key> of the cloud services unless --field
+is given. The other columns are compared with the tags of the same name, or with the fields
+given by --compare. The report lists:
+
+  missing    rows without a cloud service
+  extra      cloud services without a row
+  mismatch   values that differ between the row and the cloud service
+  duplicate  keys found on more than one cloud service
+
+With --manifest, a manifest for 'cfctl apply' is written that sets the mismatched tags.`,
+	Example: `  # Report the differences of the AWS servers
+  $ cfctl reconcile -f cmdb.csv --key asset_id --provider aws --group EC2
+
+  # Match on the instance ID and compare the owner column with a tag of another name
+  $ cfctl reconcile -f cmdb.csv --key instance_id --field data.compute.instance_id --compare owner=tags.Owner
+
+  # Write a manifest fixing the tags and apply it
+  $ cfctl reconcile -f cmdb.csv --key asset_id --manifest fix-tags.yaml
+  $ cfctl apply -f fix-tags.yaml`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename, _ := cmd.Flags().GetString("filename")
+		key, _ := cmd.Flags().GetString("key")
+		field, _ := cmd.Flags().GetString("field")
+		if field == "" {
+			field = "tags." + key
+		}
+
+		spec, err := output.Parse(cmd.Flag("output").Value.String())
+		if err == nil {
+			err = spec.Validate()
+		}
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		rows, err := readCMDBRows(filename, key)
+		if err != nil {
+			pterm.Error.Printf("Failed to read %s: %v\n", filename, err)
+			return
+		}
+
+		compareFlags, _ := cmd.Flags().GetStringArray("compare")
+		comparisons, err := reconcileComparisons(rows, key, compareFlags)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		var parameters []string
+		for _, flagName := range []string{"provider", "group", "type", "project-id", "region"} {
+			if value, _ := cmd.Flags().GetString(flagName); value != "" {
+				parameters = append(parameters, fmt.Sprintf("%s=%s", inventoryFilterFields[flagName], value))
+			}
+		}
+		services, err := fetchResults("inventory", "CloudService", parameters)
+		if err != nil {
+			pterm.Error.Printf("Failed to list cloud services: %v\n", err)
+			return
+		}
+
+		report, fixes := reconcileAssets(rows, services, key, field, comparisons)
+
+		if manifest, _ := cmd.Flags().GetString("manifest"); manifest != "" {
+			if err := writeReconcileManifest(manifest, fixes); err != nil {
+				pterm.Error.Printf("Failed to write manifest: %v\n", err)
+				return
+			}
+			pterm.Info.WithWriter(os.Stderr).Printf("Wrote %d tag updates to %s, apply them with 'cfctl apply -f %s'.\n", len(fixes), manifest, manifest)
+		}
+
+		if len(report) == 0 {
+			pterm.Success.Printf("All %d assets match the inventory.\n", len(rows))
+			return
+		}
+		if spec.Format == output.Table && len(spec.Columns) == 0 {
+			spec.Columns = []string{"status", "key", "cloud_service_id", "name", "field", "cmdb", "spaceone"}
+		}
+		if err := output.Print(report, spec); err != nil {
+			pterm.Error.Printf("Failed to format the report: %v\n", err)
+		}
+	},
+}
+
+// reconcileComparison compares a CSV column with a field of the cloud services
+type reconcileComparison struct {
+	Column string
+	Field  string
+}
+
+// readCMDBRows reads the CSV file as maps keyed by the header, requiring the key column
+func readCMDBRows(filename, key string) ([]map[string]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the file is empty")
+	}
+
+	header := records[0]
+	hasKey := false
+	for _, column := range header {
+		if column == key {
+			hasKey = true
+		}
+	}
+	if !hasKey {
+		return nil, fmt.Errorf("no column '%s' in the header: %s", key, strings.Join(header, ", "))
+	}
+
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// reconcileComparisons returns the columns to compare: the --compare column=field pairs, or
+// every column but the key compared with the tag of the same name
+func reconcileComparisons(rows []map[string]string, key string, compareFlags []string) ([]reconcileComparison, error) {
+	var comparisons []reconcileComparison
+	for _, value := range compareFlags {
+		column, field, ok := strings.Cut(value, "=")
+		if !ok || column == "" || field == "" {
+			return nil, fmt.Errorf("invalid --compare '%s', use column=field like owner=tags.owner", value)
+		}
+		comparisons = append(comparisons, reconcileComparison{Column: column, Field: field})
+	}
+	if len(comparisons) > 0 || len(rows) == 0 {
+		return comparisons, nil
+	}
+
+	columns := make([]string, 0, len(rows[0]))
+	for column := range rows[0] {
+		if column != key {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		comparisons = append(comparisons, reconcileComparison{Column: column, Field: "tags." + column})
+	}
+	return comparisons, nil
+}
+
+// reconcileAssets matches the rows with the cloud services and returns the report rows and the
+// tags to set per cloud service ID
+func reconcileAssets(rows []map[string]string, services []map[string]interface{}, key, field string, comparisons []reconcileComparison) ([]interface{}, map[string]map[string]interface{}) {
+	byKey := map[string][]map[string]interface{}{}
+	for _, service := range services {
+		value := stringValue(lookupPath(service, field))
+		byKey[value] = append(byKey[value], service)
+	}
+
+	var report []interface{}
+	fixes := map[string]map[string]interface{}{}
+	entry := func(status, assetKey string, service map[string]interface{}) map[string]interface{} {
+		item := map[string]interface{}{"status": status, "key": assetKey}
+		if service != nil {
+			item["cloud_service_id"] = stringValue(service["cloud_service_id"])
+			item["name"] = stringValue(service["name"])
+		}
+		return item
+	}
+
+	seen := map[string]bool{}
+	for _, row := range rows {
+		assetKey := row[key]
+		if assetKey == "" || seen[assetKey] {
+			continue
+		}
+		seen[assetKey] = true
+
+		matches := byKey[assetKey]
+		switch {
+		case len(matches) == 0:
+			report = append(report, entry(reconcileMissing, assetKey, nil))
+			continue
+		case len(matches) > 1:
+			for _, service := range matches {
+				report = append(report, entry(reconcileDuplicate, assetKey, service))
+			}
+			continue
+		}
+
+		service := matches[0]
+		for _, comparison := range comparisons {
+			expected := row[comparison.Column]
+			actual := stringValue(lookupPath(service, comparison.Field))
+			if expected == actual {
+				continue
+			}
+			item := entry(reconcileMismatch, assetKey, service)
+			item["field"] = comparison.Field
+			item["cmdb"] = expected
+			item["spaceone"] = actual
+			report = append(report, item)
+
+			tag, isTag := strings.CutPrefix(comparison.Field, "tags.")
+			if !isTag || strings.Contains(tag, ".") {
+				continue
+			}
+			id := stringValue(service["cloud_service_id"])
+			if fixes[id] == nil {
+				// tags are replaced as a whole by update, so the other tags are kept
+				tags := map[string]interface{}{}
+				if existing, ok := service["tags"].(map[string]interface{}); ok {
+					for k, v := range existing {
+						tags[k] = v
+					}
+				}
+				fixes[id] = tags
+			}
+			fixes[id][tag] = expected
+		}
+	}
+
+	for value, matches := range byKey {
+		if seen[value] {
+			continue
+		}
+		for _, service := range matches {
+			report = append(report, entry(reconcileExtra, value, service))
+		}
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		a, b := report[i].(map[string]interface{}), report[j].(map[string]interface{})
+		if a["status"] != b["status"] {
+			return a["status"].(string) < b["status"].(string)
+		}
+		return a["key"].(string) < b["key"].(string)
+	})
+	return report, fixes
+}
+
+// writeReconcileManifest writes inventory.update documents for 'cfctl apply', sorted by ID
+func writeReconcileManifest(path string, fixes map[string]map[string]interface{}) error {
+	ids := make([]string, 0, len(fixes))
+	for id := range fixes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var sb strings.Builder
+	encoder := yaml.NewEncoder(&sb)
+	encoder.SetIndent(2)
+	for _, id := range ids {
+		document := ResourceSpec{
+			Service:  "inventory",
+			Verb:     "update",
+			Resource: "CloudService",
+			Spec: map[string]interface{}{
+				"cloud_service_id": id,
+				"tags":             fixes[id],
+			},
+		}
+		if err := encoder.Encode(document); err != nil {
+			return err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return configs.WriteFileAtomic(path, []byte(sb.String()), 0644)
+}
+
+func init() {
+	ReconcileCmd.Flags().StringP("filename", "f", "", "CSV file with a header row, exported from the CMDB")
+	ReconcileCmd.Flags().String("key", "", "Column identifying the assets")
+	ReconcileCmd.Flags().String("field", "", "Field of the cloud services holding the key (default tags.<key>)")
+	ReconcileCmd.Flags().StringArray("compare", nil, "Column to compare as column=field, e.g. owner=tags.owner (default every column with the tag of its name)")
+	ReconcileCmd.Flags().String("manifest", "", "Write a manifest for 'cfctl apply' setting the mismatched tags")
+	ReconcileCmd.Flags().String("provider", "", "Only compare cloud services of the provider (e.g. aws)")
+	ReconcileCmd.Flags().String("group", "", "Only compare cloud services of the cloud service group (e.g. EC2)")
+	ReconcileCmd.Flags().String("type", "", "Only compare cloud services of the cloud service type (e.g. Instance)")
+	ReconcileCmd.Flags().String("project-id", "", "Only compare cloud services of the project")
+	ReconcileCmd.Flags().String("region", "", "Only compare cloud services of the region code")
+	ReconcileCmd.Flags().StringP("output", "o", "table", "Output format of the report (table, json, yaml, csv)")
+	ReconcileCmd.MarkFlagRequired("filename")
+	ReconcileCmd.MarkFlagRequired("key")
+}
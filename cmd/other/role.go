@@ -0,0 +1,183 @@
+package other
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/ui"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/output"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// RoleCmd represents the role command
+var RoleCmd = &cobra.Command{
+	Use:   "role",
+	Short: "Manage identity roles",
+}
+
+// RoleBindingCmd represents the rolebinding command
+var RoleBindingCmd = &cobra.Command{
+	Use:   "rolebinding",
+	Short: "Manage identity role bindings",
+}
+
+var roleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List roles",
+	Example: `  $ cfctl role list
+  $ cfctl role list --role-type WORKSPACE_MEMBER
+  $ cfctl role list --query "results[?is_managed].role_id"`,
+	Annotations: map[string]string{output.QueryAnnotation: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		roleType, _ := cmd.Flags().GetString("role-type")
+		query, _ := cmd.Flags().GetString("query")
+		if err := output.ValidateQuery(query); err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		var parameters []string
+		if roleType != "" {
+			parameters = append(parameters, fmt.Sprintf("role_type=%s", roleType))
+		}
+
+		roles, err := listIdentityResources("Role", parameters)
+		if err != nil {
+			pterm.Error.Printf("Failed to list roles: %v\n", err)
+			return
+		}
+		// The query sees the roles like the response of 'cfctl identity list Role'
+		if queried, err := printQueried(cmd, map[string]interface{}{"results": roles}); queried {
+			if err != nil {
+				pterm.Error.Println(err)
+			}
+			return
+		}
+		if len(roles) == 0 {
+			pterm.Info.Println("No roles found.")
+			return
+		}
+
+		tableData := pterm.TableData{{"Role ID", "Name", "Role Type", "State", "Managed"}}
+		for _, role := range roles {
+			tableData = append(tableData, []string{
+				stringValue(role["role_id"]),
+				stringValue(role["name"]),
+				stringValue(role["role_type"]),
+				stringValue(role["state"]),
+				stringValue(role["is_managed"]),
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	},
+}
+
+var roleBindingGrantCmd = &cobra.Command{
+	Use:   "grant <user_id> <role>",
+	Short: "Grant a role to a user",
+	Long: `Bind a role to a user in a workspace, or in the domain when --workspace is omitted.
+The role and the workspace can be given by ID or by name.`,
+	Example: `  $ cfctl rolebinding grant user@example.com "Workspace Owner" --workspace dev
+  $ cfctl rolebinding grant user@example.com role-1234567890ab --workspace workspace-1234567890ab --yes`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		userID := args[0]
+		workspace, _ := cmd.Flags().GetString("workspace")
+		skipConfirm, _ := cmd.Flags().GetBool("yes")
+
+		role, err := resolveIdentityResource("Role", "role_id", args[1], nil)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		parameters := []string{
+			fmt.Sprintf("user_id=%s", userID),
+			fmt.Sprintf("role_id=%s", stringValue(role["role_id"])),
+		}
+		target := "the domain"
+
+		if workspace != "" {
+			ws, err := resolveIdentityResource("Workspace", "workspace_id", workspace, nil)
+			if err != nil {
+				pterm.Error.Println(err)
+				return
+			}
+			parameters = append(parameters,
+				"resource_group=WORKSPACE",
+				fmt.Sprintf("workspace_id=%s", stringValue(ws["workspace_id"])))
+			target = fmt.Sprintf("workspace '%s' (%s)", stringValue(ws["name"]), stringValue(ws["workspace_id"]))
+		} else {
+			parameters = append(parameters, "resource_group=DOMAIN")
+		}
+
+		pterm.Info.Printf("Grant role '%s' (%s, %s) to user '%s' in %s\n",
+			stringValue(role["name"]), stringValue(role["role_id"]), stringValue(role["role_type"]), userID, target)
+
+		if !skipConfirm {
+			if !configs.Interactive() {
+				pterm.Error.Println("No terminal to confirm the role binding, pass --yes to create it.")
+				cleanup.Exit(1)
+			}
+			confirmed, _ := ui.Confirm("Do you want to continue?", false)
+			if !confirmed {
+				pterm.Warning.Println("Cancelled.")
+				return
+			}
+		}
+
+		resp, err := transport.FetchService("identity", "create", "RoleBinding", &transport.FetchOptions{
+			Parameters: parameters,
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to grant role: %v\n", err)
+			return
+		}
+
+		pterm.Success.Printf("Created role binding %s.\n", stringValue(resp["role_binding_id"]))
+	},
+}
+
+// listIdentityResources lists identity resources and returns the results
+func listIdentityResources(resource string, parameters []string) ([]map[string]interface{}, error) {
+	return fetchResults("identity", resource, parameters)
+}
+
+// resolveIdentityResource finds an identity resource by its ID or by its name
+func resolveIdentityResource(resource, idField, idOrName string, parameters []string) (map[string]interface{}, error) {
+	items, err := listIdentityResources(resource, append(parameters, fmt.Sprintf("%s=%s", idField, idOrName)))
+	if err == nil && len(items) == 1 {
+		return items[0], nil
+	}
+
+	items, err = listIdentityResources(resource, append(parameters, fmt.Sprintf("name=%s", idOrName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s '%s': %v", strings.ToLower(resource), idOrName, err)
+	}
+
+	switch len(items) {
+	case 0:
+		return nil, fmt.Errorf("%s '%s' not found", strings.ToLower(resource), idOrName)
+	case 1:
+		return items[0], nil
+	default:
+		var ids []string
+		for _, item := range items {
+			ids = append(ids, stringValue(item[idField]))
+		}
+		return nil, fmt.Errorf("%s name '%s' is ambiguous, use one of the IDs: %s", strings.ToLower(resource), idOrName, strings.Join(ids, ", "))
+	}
+}
+
+func init() {
+	RoleCmd.AddCommand(roleListCmd)
+	RoleBindingCmd.AddCommand(roleBindingGrantCmd)
+
+	roleListCmd.Flags().String("role-type", "", "Filter by role type (e.g. DOMAIN_ADMIN, WORKSPACE_OWNER, WORKSPACE_MEMBER)")
+	roleBindingGrantCmd.Flags().String("workspace", "", "Workspace ID or name (default: domain scope)")
+	roleBindingGrantCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+}
@@ -0,0 +1,335 @@
+package other
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/cron"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// ScheduleCmd represents the schedule command
+var ScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage collection schedules and maintenance windows",
+	Long: `Manage the collection schedules of inventory collectors and the maintenance
+windows of the current environment. Schedules are given as cron expressions.`,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List collector schedules with their next run",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collectors, err := fetchResults("inventory", "Collector", nil)
+		if err != nil {
+			return fmt.Errorf("failed to list collectors: %v", err)
+		}
+
+		windows, _ := loadMaintenanceWindows()
+
+		tableData := pterm.TableData{{"Collector ID", "Name", "State", "Cron", "Next Run"}}
+		for _, collector := range collectors {
+			schedule, _ := collector["schedule"].(map[string]interface{})
+			state := stringValue(schedule["state"])
+
+			var hours []int
+			if rawHours, ok := schedule["hours"].([]interface{}); ok {
+				for _, hour := range rawHours {
+					hours = append(hours, int(toFloat(hour)))
+				}
+			}
+
+			expression := cron.FromHours(hours)
+			nextRun := "-"
+			if state == "ENABLED" && expression != "" {
+				if parsed, err := cron.Parse(expression); err == nil {
+					nextRun = formatNextRun(parsed.Next(time.Now()), windows)
+				}
+			}
+
+			tableData = append(tableData, []string{
+				stringValue(collector["collector_id"]),
+				stringValue(collector["name"]),
+				state,
+				expression,
+				nextRun,
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		return nil
+	},
+}
+
+var scheduleSetCmd = &cobra.Command{
+	Use:   "set <collector_id>",
+	Short: "Set the collection schedule of a collector",
+	Long: `Set the collection schedule of a collector from a cron expression.
+Collectors run at the start of the selected hours, so the expression must use minute 0
+and '*' for the day, month and weekday fields.`,
+	Example: `  $ cfctl schedule set collector-1234567890ab --cron "0 */6 * * *"
+  $ cfctl schedule set collector-1234567890ab --cron "0 2,14 * * *"`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expression, _ := cmd.Flags().GetString("cron")
+
+		schedule, err := cron.Parse(expression)
+		if err != nil {
+			return err
+		}
+		if !schedule.IsHourly() {
+			return fmt.Errorf("collector schedules only support hourly expressions like '0 */6 * * *', got '%s'", expression)
+		}
+
+		if err := updateCollectorSchedule(args[0], "ENABLED", schedule.Hours()); err != nil {
+			return fmt.Errorf("failed to update schedule: %v", err)
+		}
+
+		windows, _ := loadMaintenanceWindows()
+		pterm.Success.Printf("Scheduled %s with '%s'. Next run: %s\n", args[0], expression, formatNextRun(schedule.Next(time.Now()), windows))
+		return nil
+	},
+}
+
+var scheduleDeleteCmd = &cobra.Command{
+	Use:          "delete <collector_id>",
+	Short:        "Disable the collection schedule of a collector",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := updateCollectorSchedule(args[0], "DISABLED", []int{}); err != nil {
+			return fmt.Errorf("failed to disable schedule: %v", err)
+		}
+		pterm.Success.Printf("Disabled the schedule of %s.\n", args[0])
+		return nil
+	},
+}
+
+// updateCollectorSchedule updates the schedule of an inventory collector
+func updateCollectorSchedule(collectorID, state string, hours []int) error {
+	jsonParameter, err := json.Marshal(map[string]interface{}{
+		"collector_id": collectorID,
+		"schedule": map[string]interface{}{
+			"state": state,
+			"hours": hours,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = transport.FetchService("inventory", "update", "Collector", &transport.FetchOptions{
+		JSONParameter: string(jsonParameter),
+	})
+	return err
+}
+
+// maintenanceWindow is a recurring window stored under environments.<env>.maintenance_windows
+type maintenanceWindow struct {
+	Name     string
+	Cron     string
+	Duration time.Duration
+}
+
+var scheduleWindowCmd = &cobra.Command{
+	Use:   "window",
+	Short: "Manage maintenance windows of the current environment",
+}
+
+var scheduleWindowCreateCmd = &cobra.Command{
+	Use:          "create <name>",
+	Short:        "Create a maintenance window",
+	Example:      `  $ cfctl schedule window create weekly-patch --cron "0 22 * * 6" --duration 4h`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expression, _ := cmd.Flags().GetString("cron")
+		duration, _ := cmd.Flags().GetDuration("duration")
+
+		schedule, err := cron.Parse(expression)
+		if err != nil {
+			return err
+		}
+		if duration <= 0 {
+			return fmt.Errorf("duration must be positive")
+		}
+
+		windows, err := loadMaintenanceWindows()
+		if err != nil {
+			return fmt.Errorf("failed to read maintenance windows: %v", err)
+		}
+		for _, window := range windows {
+			if window.Name == args[0] {
+				return fmt.Errorf("maintenance window '%s' already exists", args[0])
+			}
+		}
+
+		windows = append(windows, maintenanceWindow{Name: args[0], Cron: expression, Duration: duration})
+		if err := saveMaintenanceWindows(windows); err != nil {
+			return fmt.Errorf("failed to save maintenance window: %v", err)
+		}
+
+		pterm.Success.Printf("Created maintenance window '%s'. Next start: %s\n", args[0], schedule.Next(time.Now()).Format("2006-01-02 15:04 MST"))
+		return nil
+	},
+}
+
+var scheduleWindowListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List maintenance windows",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		windows, err := loadMaintenanceWindows()
+		if err != nil {
+			return fmt.Errorf("failed to read maintenance windows: %v", err)
+		}
+		if len(windows) == 0 {
+			pterm.Info.Println("No maintenance windows configured.")
+			return nil
+		}
+
+		now := time.Now()
+		tableData := pterm.TableData{{"Name", "Cron", "Duration", "Next Start", "Active"}}
+		for _, window := range windows {
+			nextStart, active := "-", ""
+			if schedule, err := cron.Parse(window.Cron); err == nil {
+				next := schedule.Next(now)
+				nextStart = fmt.Sprintf("%s (in %s)", next.Format("2006-01-02 15:04 MST"), next.Sub(now).Round(time.Minute))
+				if windowActiveAt(window, now) {
+					active = "yes"
+				}
+			}
+			tableData = append(tableData, []string{window.Name, window.Cron, window.Duration.String(), nextStart, active})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		return nil
+	},
+}
+
+var scheduleWindowDeleteCmd = &cobra.Command{
+	Use:          "delete <name>",
+	Short:        "Delete a maintenance window",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		windows, err := loadMaintenanceWindows()
+		if err != nil {
+			return fmt.Errorf("failed to read maintenance windows: %v", err)
+		}
+
+		var remaining []maintenanceWindow
+		for _, window := range windows {
+			if window.Name != args[0] {
+				remaining = append(remaining, window)
+			}
+		}
+		if len(remaining) == len(windows) {
+			return fmt.Errorf("maintenance window '%s' not found", args[0])
+		}
+
+		if err := saveMaintenanceWindows(remaining); err != nil {
+			return fmt.Errorf("failed to save maintenance windows: %v", err)
+		}
+		pterm.Success.Printf("Deleted maintenance window '%s'.\n", args[0])
+		return nil
+	},
+}
+
+// loadMaintenanceWindows reads the maintenance windows of the current environment
+func loadMaintenanceWindows() ([]maintenanceWindow, error) {
+	v, err := readSettingViper()
+	if err != nil {
+		return nil, err
+	}
+
+	envConfig, err := configs.DecodeEnvironment(v, v.GetString("environment"))
+	if err != nil {
+		return nil, err
+	}
+
+	var windows []maintenanceWindow
+	for _, window := range envConfig.MaintenanceWindows {
+		duration, _ := time.ParseDuration(window.Duration)
+		windows = append(windows, maintenanceWindow{
+			Name:     window.Name,
+			Cron:     window.Cron,
+			Duration: duration,
+		})
+	}
+	return windows, nil
+}
+
+// saveMaintenanceWindows writes the maintenance windows of the current environment
+func saveMaintenanceWindows(windows []maintenanceWindow) error {
+	v, err := readSettingViper()
+	if err != nil {
+		return err
+	}
+
+	currentEnv := v.GetString("environment")
+	if currentEnv == "" {
+		return fmt.Errorf("no environment selected")
+	}
+
+	windowList := make([]map[string]interface{}, 0, len(windows))
+	for _, window := range windows {
+		windowList = append(windowList, map[string]interface{}{
+			"name":     window.Name,
+			"cron":     window.Cron,
+			"duration": window.Duration.String(),
+		})
+	}
+
+	v.Set(fmt.Sprintf("environments.%s.maintenance_windows", currentEnv), windowList)
+	configs.MarkSettingDirty()
+	return nil
+}
+
+// windowActiveAt reports whether the maintenance window covers t
+func windowActiveAt(window maintenanceWindow, t time.Time) bool {
+	schedule, err := cron.Parse(window.Cron)
+	if err != nil {
+		return false
+	}
+	// The window is active when it started within the last duration
+	start := schedule.Next(t.Add(-window.Duration - time.Minute))
+	return !start.IsZero() && !start.After(t) && t.Before(start.Add(window.Duration))
+}
+
+// formatNextRun renders the next run time and marks runs falling into a maintenance window
+func formatNextRun(next time.Time, windows []maintenanceWindow) string {
+	if next.IsZero() {
+		return "-"
+	}
+
+	text := fmt.Sprintf("%s (in %s)", next.Format("2006-01-02 15:04 MST"), time.Until(next).Round(time.Minute))
+	for _, window := range windows {
+		if windowActiveAt(window, next) {
+			text += fmt.Sprintf(" [in maintenance window '%s']", window.Name)
+			break
+		}
+	}
+	return text
+}
+
+func init() {
+	ScheduleCmd.AddCommand(scheduleListCmd)
+	ScheduleCmd.AddCommand(scheduleSetCmd)
+	ScheduleCmd.AddCommand(scheduleDeleteCmd)
+	ScheduleCmd.AddCommand(scheduleWindowCmd)
+	scheduleWindowCmd.AddCommand(scheduleWindowCreateCmd)
+	scheduleWindowCmd.AddCommand(scheduleWindowListCmd)
+	scheduleWindowCmd.AddCommand(scheduleWindowDeleteCmd)
+
+	scheduleSetCmd.Flags().String("cron", "", "Cron expression (e.g. \"0 */6 * * *\")")
+	scheduleSetCmd.MarkFlagRequired("cron")
+
+	scheduleWindowCreateCmd.Flags().String("cron", "", "Cron expression of the window start (e.g. \"0 22 * * 6\")")
+	scheduleWindowCreateCmd.Flags().Duration("duration", time.Hour, "Length of the window")
+	scheduleWindowCreateCmd.MarkFlagRequired("cron")
+}
@@ -0,0 +1,234 @@
+package other
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/ui"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// SecretCmd represents the secret command
+var SecretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage secrets of the secret service",
+	Long: `Create, list and delete secrets and trusted secrets.
+Secret data is only read from files and is never printed.`,
+}
+
+var secretCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a secret from a JSON data file",
+	Example: `  $ cfctl secret create aws-prod --data-file credentials.json --schema-id aws-secret-access-key --service-account-id sa-1234567890ab
+  $ cat credentials.json | cfctl secret create aws-prod --data-file - --trusted --provider aws`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dataFile, _ := cmd.Flags().GetString("data-file")
+
+		data, err := readSecretData(dataFile)
+		if err != nil {
+			pterm.Error.Printf("Failed to read secret data: %v\n", err)
+			return
+		}
+
+		payload := map[string]interface{}{
+			"name": args[0],
+			"data": data,
+		}
+		for _, flagName := range []string{"schema-id", "provider", "service-account-id", "trusted-secret-id"} {
+			if value, _ := cmd.Flags().GetString(flagName); value != "" {
+				payload[flagToField(flagName)] = value
+			}
+		}
+
+		jsonParameter, err := json.Marshal(payload)
+		if err != nil {
+			pterm.Error.Printf("Failed to encode secret: %v\n", err)
+			return
+		}
+
+		resource := secretResource(cmd)
+		resp, err := transport.FetchService("secret", "create", resource, &transport.FetchOptions{
+			JSONParameter: string(jsonParameter),
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to create %s: %v\n", resource, err)
+			return
+		}
+
+		idField := "secret_id"
+		if resource == "TrustedSecret" {
+			idField = "trusted_secret_id"
+		}
+		pterm.Success.Printf("Created %s '%s' (%s).\n", resource, args[0], stringValue(resp[idField]))
+	},
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list [Secret|TrustedSecret]",
+	Short: "List secrets without their data",
+	Example: `  $ cfctl secret list
+  $ cfctl secret list --trusted --provider aws
+  $ cfctl secret list TrustedSecret`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 && args[0] == "TrustedSecret" {
+			cmd.Flags().Set("trusted", "true")
+		}
+
+		var parameters []string
+		for _, flagName := range []string{"provider", "service-account-id", "schema-id"} {
+			if value, _ := cmd.Flags().GetString(flagName); value != "" {
+				parameters = append(parameters, fmt.Sprintf("%s=%s", flagToField(flagName), value))
+			}
+		}
+
+		resource := secretResource(cmd)
+		resp, err := transport.FetchService("secret", "list", resource, &transport.FetchOptions{
+			Parameters: parameters,
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to list %s: %v\n", resource, err)
+			return
+		}
+		transport.RedactSecretData(resp)
+
+		results, _ := resp["results"].([]interface{})
+		if len(results) == 0 {
+			pterm.Info.Println("No secrets found.")
+			return
+		}
+
+		idField := "secret_id"
+		if resource == "TrustedSecret" {
+			idField = "trusted_secret_id"
+		}
+
+		tableData := pterm.TableData{{"ID", "Name", "Schema ID", "Provider", "Service Account ID", "Created At"}}
+		for _, result := range results {
+			secret, ok := result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			tableData = append(tableData, []string{
+				stringValue(secret[idField]),
+				stringValue(secret["name"]),
+				stringValue(secret["schema_id"]),
+				stringValue(secret["provider"]),
+				stringValue(secret["service_account_id"]),
+				stringValue(secret["created_at"]),
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	},
+}
+
+var secretDeleteCmd = &cobra.Command{
+	Use:   "delete <secret_id>",
+	Short: "Delete a secret",
+	Example: `  $ cfctl secret delete secret-1234567890ab
+  $ cfctl secret delete trusted-secret-1234567890ab --trusted --yes`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		skipConfirm, _ := cmd.Flags().GetBool("yes")
+		resource := secretResource(cmd)
+
+		idField := "secret_id"
+		if resource == "TrustedSecret" {
+			idField = "trusted_secret_id"
+		}
+
+		if !skipConfirm {
+			if !configs.Interactive() {
+				pterm.Error.Printf("No terminal to confirm the delete of %s '%s', pass --yes to delete it.\n", resource, args[0])
+				cleanup.Exit(1)
+			}
+			confirmed, _ := ui.Confirm(fmt.Sprintf("Delete %s '%s'?", resource, args[0]), false)
+			if !confirmed {
+				pterm.Warning.Println("Cancelled.")
+				return
+			}
+		}
+
+		if _, err := transport.FetchService("secret", "delete", resource, &transport.FetchOptions{
+			Parameters: []string{fmt.Sprintf("%s=%s", idField, args[0])},
+		}); err != nil {
+			pterm.Error.Printf("Failed to delete %s: %v\n", resource, err)
+			return
+		}
+
+		pterm.Success.Printf("Deleted %s '%s'.\n", resource, args[0])
+	},
+}
+
+// readSecretData reads the JSON secret payload from a file or from stdin when the path is '-'
+func readSecretData(path string) (map[string]interface{}, error) {
+	var raw []byte
+	var err error
+	if path == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		// Do not include the payload in the error message
+		return nil, fmt.Errorf("data file is not a valid JSON object")
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("data file is empty")
+	}
+	return data, nil
+}
+
+// secretResource returns the secret service resource selected by --trusted
+func secretResource(cmd *cobra.Command) string {
+	if trusted, _ := cmd.Flags().GetBool("trusted"); trusted {
+		return "TrustedSecret"
+	}
+	return "Secret"
+}
+
+// flagToField converts a flag name like 'schema-id' to the API field 'schema_id'
+func flagToField(flagName string) string {
+	field := []rune(flagName)
+	for i, r := range field {
+		if r == '-' {
+			field[i] = '_'
+		}
+	}
+	return string(field)
+}
+
+func init() {
+	SecretCmd.AddCommand(secretCreateCmd)
+	SecretCmd.AddCommand(secretListCmd)
+	SecretCmd.AddCommand(secretDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{secretCreateCmd, secretListCmd, secretDeleteCmd} {
+		cmd.Flags().Bool("trusted", false, "Use trusted secrets instead of secrets")
+	}
+
+	secretCreateCmd.Flags().String("data-file", "", "JSON file with the secret data ('-' for stdin)")
+	secretCreateCmd.Flags().String("schema-id", "", "Schema ID of the secret data")
+	secretCreateCmd.Flags().String("provider", "", "Provider of the secret")
+	secretCreateCmd.Flags().String("service-account-id", "", "Service account to attach the secret to")
+	secretCreateCmd.Flags().String("trusted-secret-id", "", "Trusted secret used by the secret")
+	secretCreateCmd.MarkFlagRequired("data-file")
+
+	secretListCmd.Flags().String("provider", "", "Filter by provider")
+	secretListCmd.Flags().String("service-account-id", "", "Filter by service account")
+	secretListCmd.Flags().String("schema-id", "", "Filter by schema")
+
+	secretDeleteCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+}
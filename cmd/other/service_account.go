@@ -0,0 +1,214 @@
+package other
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/output"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Results of verifying the credentials of a service account
+const (
+	verifyOK      = "OK"
+	verifyFailed  = "FAILED"
+	verifyStarted = "STARTED"
+	verifySkipped = "SKIPPED"
+)
+
+// ServiceAccountCmd holds the service account commands
+var ServiceAccountCmd = &cobra.Command{
+	Use:   "service-account",
+	Short: "Work with the service accounts of cloud providers",
+}
+
+var serviceAccountVerifyCmd = &cobra.Command{
+	Use:   "verify [service_account_id]",
+	Short: "Check that the stored credentials of service accounts still work",
+	Long: `Verify the secret of a service account with the plugin of a collector of its provider.
+With --collect, a collection of the account is started instead, which also checks the
+permissions of the credentials; follow it with 'cfctl jobs list'.
+
+With --all, every service account of the current workspace is verified. Exits with code 1
+when the credentials of an account fail.`,
+	Example: `  $ cfctl service-account verify sa-1234567890ab
+  $ cfctl service-account verify --all --provider aws
+  $ cfctl service-account verify sa-1234567890ab --collect --collector-id collector-1234567890ab`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		if all == (len(args) == 1) {
+			pterm.Error.Println("Specify either a service account ID or --all.")
+			return
+		}
+
+		spec, err := output.Parse(cmd.Flag("output").Value.String())
+		if err == nil {
+			err = spec.Validate()
+		}
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		var accounts []map[string]interface{}
+		if all {
+			var parameters []string
+			for _, flagName := range []string{"provider", "project-id"} {
+				if value, _ := cmd.Flags().GetString(flagName); value != "" {
+					parameters = append(parameters, fmt.Sprintf("%s=%s", flagToField(flagName), value))
+				}
+			}
+			accounts, err = fetchResults("identity", "ServiceAccount", parameters)
+			if err != nil {
+				pterm.Error.Printf("Failed to list service accounts: %v\n", err)
+				return
+			}
+			if len(accounts) == 0 {
+				pterm.Info.Println("No service accounts found.")
+				return
+			}
+		} else {
+			account, err := transport.FetchService("identity", "get", "ServiceAccount", &transport.FetchOptions{
+				Parameters: []string{fmt.Sprintf("service_account_id=%s", args[0])},
+			})
+			if err != nil {
+				pterm.Error.Printf("Failed to get service account %s: %v\n", args[0], err)
+				return
+			}
+			accounts = []map[string]interface{}{account}
+		}
+
+		collectorID, _ := cmd.Flags().GetString("collector-id")
+		collect, _ := cmd.Flags().GetBool("collect")
+		verifier := &accountVerifier{collectorID: collectorID, collect: collect, collectors: map[string]string{}}
+
+		var report []interface{}
+		failed := 0
+		for i, account := range accounts {
+			if all && spec.Format == output.Table {
+				pterm.Info.WithWriter(os.Stderr).Printf("[%d/%d] Verifying %s...\n", i+1, len(accounts), stringValue(account["service_account_id"]))
+			}
+			result := verifier.verify(account)
+			if result["status"] == verifyFailed {
+				failed++
+			}
+			report = append(report, result)
+		}
+
+		if spec.Format == output.Table && len(spec.Columns) == 0 {
+			spec.Columns = []string{"service_account_id", "name", "provider", "collector_id", "status", "message"}
+		}
+		if err := output.Print(report, spec); err != nil {
+			pterm.Error.Printf("Failed to format the result: %v\n", err)
+			return
+		}
+		if failed > 0 {
+			pterm.Error.WithWriter(os.Stderr).Printf("Credentials of %d of %d service account(s) failed.\n", failed, len(accounts))
+			cleanup.Exit(1)
+		}
+	},
+}
+
+// accountVerifier verifies service accounts, remembering the collector found for each provider
+type accountVerifier struct {
+	collectorID string
+	collect     bool
+	collectors  map[string]string
+}
+
+// verify checks the credentials of one service account and returns a row of the report
+func (v *accountVerifier) verify(account map[string]interface{}) map[string]interface{} {
+	accountID := stringValue(account["service_account_id"])
+	provider := stringValue(account["provider"])
+	result := map[string]interface{}{
+		"service_account_id": accountID,
+		"name":               stringValue(account["name"]),
+		"provider":           provider,
+	}
+	finish := func(status, message string) map[string]interface{} {
+		result["status"] = status
+		result["message"] = message
+		return result
+	}
+
+	secretID := stringValue(account["secret_id"])
+	if secretID == "" && !v.collect {
+		return finish(verifySkipped, "no secret is attached to the service account")
+	}
+
+	collectorID, err := v.collectorFor(provider)
+	if err != nil {
+		return finish(verifyFailed, err.Error())
+	}
+	if collectorID == "" {
+		return finish(verifySkipped, fmt.Sprintf("no collector found for provider '%s', use --collector-id", provider))
+	}
+	result["collector_id"] = collectorID
+
+	if v.collect {
+		job, err := transport.FetchService("inventory", "collect", "Collector", &transport.FetchOptions{
+			Parameters: []string{
+				fmt.Sprintf("collector_id=%s", collectorID),
+				fmt.Sprintf("service_account_id=%s", accountID),
+			},
+		})
+		if err != nil {
+			return finish(verifyFailed, firstLine(err.Error()))
+		}
+		return finish(verifyStarted, fmt.Sprintf("collection job %s started", stringValue(job["job_id"])))
+	}
+
+	_, err = transport.FetchService("inventory", "verify_plugin", "Collector", &transport.FetchOptions{
+		Parameters: []string{
+			fmt.Sprintf("collector_id=%s", collectorID),
+			fmt.Sprintf("secret_id=%s", secretID),
+		},
+	})
+	if err != nil {
+		return finish(verifyFailed, firstLine(err.Error()))
+	}
+	return finish(verifyOK, "credentials are valid")
+}
+
+// collectorFor returns the collector given by --collector-id, or the first collector of the provider
+func (v *accountVerifier) collectorFor(provider string) (string, error) {
+	if v.collectorID != "" {
+		return v.collectorID, nil
+	}
+	if collectorID, ok := v.collectors[provider]; ok {
+		return collectorID, nil
+	}
+
+	collectors, err := fetchResults("inventory", "Collector", []string{fmt.Sprintf("provider=%s", provider)})
+	if err != nil {
+		return "", fmt.Errorf("failed to list collectors: %v", err)
+	}
+	collectorID := ""
+	if len(collectors) > 0 {
+		collectorID = stringValue(collectors[0]["collector_id"])
+	}
+	v.collectors[provider] = collectorID
+	return collectorID, nil
+}
+
+// firstLine keeps the first line of multi-line errors returned by plugins
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return line
+}
+
+func init() {
+	ServiceAccountCmd.AddCommand(serviceAccountVerifyCmd)
+
+	serviceAccountVerifyCmd.Flags().Bool("all", false, "Verify every service account of the current workspace")
+	serviceAccountVerifyCmd.Flags().String("provider", "", "With --all, only verify service accounts of the provider (e.g. aws)")
+	serviceAccountVerifyCmd.Flags().String("project-id", "", "With --all, only verify service accounts of the project")
+	serviceAccountVerifyCmd.Flags().String("collector-id", "", "Collector whose plugin verifies the credentials (default the first collector of the provider)")
+	serviceAccountVerifyCmd.Flags().Bool("collect", false, "Start a collection instead of only verifying the credentials")
+	serviceAccountVerifyCmd.Flags().StringP("output", "o", "table", "Output format (table, json, yaml, csv)")
+}
@@ -3,25 +3,27 @@ package other
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
 
+	"github.com/cloudforet-io/cfctl/internal/apiclient"
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/grpcconn"
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"github.com/cloudforet-io/cfctl/internal/ui"
 	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/format"
+	"github.com/cloudforet-io/cfctl/pkg/output"
 	"github.com/cloudforet-io/cfctl/pkg/transport"
 	"gopkg.in/yaml.v3"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 
 	"github.com/jhump/protoreflect/dynamic"
@@ -69,19 +71,15 @@ var settingInitStaticCmd = &cobra.Command{
 	Long: `Initialize configuration with a static service endpoint.
 This is useful for development or when connecting directly to specific service endpoints.`,
 	Example: `  cfctl setting init static grpc://localhost:50051
+  cfctl setting init static grpc://localhost:50051 --name local
   cfctl setting init static grpc[+ssl]://inventory-`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		// Get environment name from user input
-		result, err := pterm.DefaultInteractiveTextInput.
-			WithDefaultText("default").
-			WithDefaultValue("default").
-			WithMultiLine(false).
-			Show("Environment name")
-
+		// Get environment name from --name or user input
+		result, err := environmentNameInput(cmd)
 		if err != nil {
-			pterm.Error.Printf("Failed to get environment name: %v\n", err)
-			return
+			pterm.Error.Println(err)
+			cleanup.Exit(1)
 		}
 
 		// If user didn't input anything, use default
@@ -92,7 +90,7 @@ This is useful for development or when connecting directly to specific service e
 
 		endpoint := args[0]
 		settingDir := GetSettingDir()
-		if err := os.MkdirAll(settingDir, 0755); err != nil {
+		if err := configs.EnsureSecureDir(settingDir); err != nil {
 			pterm.Error.Printf("Failed to create setting directory: %v\n", err)
 			return
 		}
@@ -103,7 +101,7 @@ This is useful for development or when connecting directly to specific service e
 		v.SetConfigType("yaml")
 
 		// Check if environment already exists
-		if err := v.ReadInConfig(); err == nil {
+		if err := configs.ReadConfig(v); err == nil {
 			environments := v.GetStringMap("environments")
 			if existingEnv, exists := environments[envName]; exists {
 				currentConfig, _ := yaml.Marshal(map[string]interface{}{
@@ -137,18 +135,36 @@ This is useful for development or when connecting directly to specific service e
 		}
 
 		pterm.Success.Printf("Successfully initialized direct connection to %s\n", endpoint)
-		if err := v.ReadInConfig(); err == nil {
+		err = configs.UpdateConfig(mainSettingPath, func(v *viper.Viper) error {
+			v.Set("version", configs.SettingVersion)
 			v.Set(fmt.Sprintf("environments.%s.proxy", envName), false)
-			if err := v.WriteConfig(); err != nil {
-				pterm.Error.Printf("Failed to update proxy setting: %v\n", err)
-				return
-			}
+			return nil
+		})
+		if err != nil {
+			pterm.Error.Printf("Failed to update proxy setting: %v\n", err)
+			return
 		}
 
 		updateSetting(envName, endpoint, "", false)
 	},
 }
 
+// environmentNameInput returns the environment name given with --name, or asks for it
+func environmentNameInput(cmd *cobra.Command) (string, error) {
+	if name, _ := cmd.Flags().GetString("name"); name != "" {
+		return name, nil
+	}
+	if !configs.Interactive() {
+		return "", fmt.Errorf("no terminal to enter the environment name, pass it with --name")
+	}
+
+	result, err := ui.Input("Environment name", "default")
+	if err != nil {
+		return "", fmt.Errorf("failed to get environment name: %v", err)
+	}
+	return result, nil
+}
+
 // settingInitProxyCmd represents the setting init proxy command
 var settingInitProxyCmd = &cobra.Command{
 	Use:   "proxy [URL]",
@@ -186,16 +202,11 @@ var settingInitProxyCmd = &cobra.Command{
 			return
 		}
 
-		// Get environment name from user input
-		result, err := pterm.DefaultInteractiveTextInput.
-			WithDefaultText("default").
-			WithDefaultValue("default").
-			WithMultiLine(false).
-			Show("Environment name")
-
+		// Get environment name from --name or user input
+		result, err := environmentNameInput(cmd)
 		if err != nil {
-			pterm.Error.Printf("Failed to get environment name: %v\n", err)
-			return
+			pterm.Error.Println(err)
+			cleanup.Exit(1)
 		}
 
 		// If user didn't input anything, use default
@@ -220,7 +231,7 @@ var settingInitProxyCmd = &cobra.Command{
 		}
 
 		settingDir := GetSettingDir()
-		if err := os.MkdirAll(settingDir, 0755); err != nil {
+		if err := configs.EnsureSecureDir(settingDir); err != nil {
 			pterm.Error.Printf("Failed to create setting directory: %v\n", err)
 			return
 		}
@@ -233,7 +244,7 @@ var settingInitProxyCmd = &cobra.Command{
 		// Always set proxy to true
 		pterm.Success.Printf("Successfully initialized proxy connection to %s\n", endpointStr)
 
-		if err := v.ReadInConfig(); err == nil {
+		if err := configs.ReadConfig(v); err == nil {
 			environments := v.GetStringMap("environments")
 			if existingEnv, exists := environments[envName]; exists {
 				currentConfig, _ := yaml.Marshal(map[string]interface{}{
@@ -308,9 +319,8 @@ var envCmd = &cobra.Command{
 			}
 
 			if _, existsApp := appEnvMap[switchEnv]; !existsApp {
-				home, _ := os.UserHomeDir()
-				pterm.Error.Printf("Environment '%s' not found in %s/.cfctl/setting.yaml",
-					switchEnv, home)
+				pterm.Error.Printf("Environment '%s' not found in %s",
+					switchEnv, appSettingPath)
 				return
 			}
 
@@ -338,9 +348,12 @@ var envCmd = &cobra.Command{
 				targetViper = appV
 				targetSettingPath = appSettingPath
 			} else {
-				home, _ := os.UserHomeDir()
-				pterm.Error.Printf("Environment '%s' not found in %s/.cfctl/setting.yaml",
-					switchEnv, home)
+				pterm.Error.Printf("Environment '%s' not found in %s",
+					switchEnv, appSettingPath)
+				return
+			}
+			if included := settingIncludedFrom(appV, "environments."+removeEnv); included != "" {
+				pterm.Error.Printf("Environment '%s' comes from %s, remove it there.\n", removeEnv, included)
 				return
 			}
 
@@ -353,6 +366,9 @@ var envCmd = &cobra.Command{
 			if response == "y" {
 				// Remove the environment from the environments map
 				envMap := targetViper.GetStringMap("environments")
+				if envSettings, ok := envMap[removeEnv].(map[string]interface{}); ok {
+					deleteSecrets(envSettings)
+				}
 				delete(envMap, removeEnv)
 				targetViper.Set("environments", envMap)
 
@@ -422,9 +438,9 @@ var envCmd = &cobra.Command{
 					envType = "Static"
 				}
 
-				endpoint := envConfig["endpoint"]
+				endpoint := configs.ExpandEnv(envConfig["endpoint"])
 
-				proxyEnabled := appV.GetBool(fmt.Sprintf("environments.%s.proxy", envName))
+				proxyEnabled := configs.SettingBool(appV, fmt.Sprintf("environments.%s.proxy", envName))
 				proxyStatus := ""
 				if proxyEnabled {
 					proxyStatus = pterm.Sprint("enabled")
@@ -477,14 +493,17 @@ var envCmd = &cobra.Command{
 var showCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Display the current cfctl configuration",
+	Long: `Display the setting of the current environment, merged from setting.yaml and the files
+listed under its includes. --origin shows the file, or the environment variable, each value
+comes from.`,
+	Example: `  $ cfctl setting show
+  $ cfctl setting show --origin`,
+	Annotations: map[string]string{output.QueryAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		settingDir := GetSettingDir()
 		appSettingPath := filepath.Join(settingDir, "setting.yaml")
-		userSettingPath := filepath.Join(settingDir, "cache", "setting.yaml")
 
-		// Create separate Viper instances
 		appV := viper.New()
-		userV := viper.New()
 
 		// Load app configuration
 		if err := loadSetting(appV, appSettingPath); err != nil {
@@ -498,39 +517,95 @@ var showCmd = &cobra.Command{
 			return
 		}
 
-		// Try to get the environment from appViper
 		envSetting := appV.GetStringMap(fmt.Sprintf("environments.%s", currentEnv))
-
-		// If not found in appViper, try userViper
 		if len(envSetting) == 0 {
-			envSetting = userV.GetStringMap(fmt.Sprintf("environments.%s", currentEnv))
-			if len(envSetting) == 0 {
-				pterm.Error.Printf("Environment '%s' not found in %s or %s\n", currentEnv, appSettingPath, userSettingPath)
-				return
-			}
+			pterm.Error.Printf("Environment '%s' not found in %s\n", currentEnv, appSettingPath)
+			return
 		}
 
-		output, _ := cmd.Flags().GetString("output")
-
-		switch output {
-		case "json":
-			data, err := json.MarshalIndent(envSetting, "", "  ")
-			if err != nil {
-				log.Fatalf("Error formatting output as JSON: %v", err)
-			}
-			fmt.Println(string(data))
-		case "yaml":
-			data, err := yaml.Marshal(envSetting)
-			if err != nil {
-				log.Fatalf("Error formatting output as yaml: %v", err)
+		outputFormat, _ := cmd.Flags().GetString("output")
+		showOrigin, _ := cmd.Flags().GetBool("origin")
+		var data interface{} = envSetting
+		if showOrigin {
+			data = settingOriginRows(appV, currentEnv, envSetting)
+			if !cmd.Flags().Changed("output") {
+				outputFormat = "table"
 			}
-			fmt.Println(string(data))
-		default:
-			log.Fatalf("Unsupported output format: %v", output)
+		}
+
+		spec, err := output.Parse(outputFormat)
+		if err == nil {
+			err = spec.Validate()
+		}
+		if err == nil {
+			spec.Query, _ = cmd.Flags().GetString("query")
+			err = output.ValidateQuery(spec.Query)
+		}
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		if showOrigin && len(spec.Columns) == 0 {
+			spec.Columns = []string{"key", "value", "origin"}
+		}
+		if err := output.Print(data, spec); err != nil {
+			pterm.Error.Printf("Failed to format the setting: %v\n", err)
 		}
 	},
 }
 
+// settingIncludedFrom returns the included file defining key or a setting under it, if any
+func settingIncludedFrom(v *viper.Viper, key string) string {
+	key = strings.ToLower(key)
+	for setting, origin := range configs.SettingOrigins(v) {
+		if origin == v.ConfigFileUsed() || strings.HasPrefix(origin, "$") {
+			continue
+		}
+		if setting == key || strings.HasPrefix(setting, key+".") {
+			return origin
+		}
+	}
+	return ""
+}
+
+// settingOriginRows lists the settings of the environment with the file or variable each one
+// came from, setting.yaml or one of its includes
+func settingOriginRows(v *viper.Viper, env string, envSetting map[string]interface{}) []interface{} {
+	origins := configs.SettingOrigins(v)
+	home, _ := configs.HomeDir()
+	origin := func(key string) string {
+		path, ok := origins[key]
+		if !ok {
+			return "default"
+		}
+		if home != "" && strings.HasPrefix(path, home+string(filepath.Separator)) {
+			return "~" + strings.TrimPrefix(path, home)
+		}
+		return path
+	}
+
+	rows := []interface{}{map[string]interface{}{
+		"key":    "environment",
+		"value":  env,
+		"origin": origin("environment"),
+	}}
+	flat := format.Flatten(envSetting, math.MaxInt)
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fullKey := fmt.Sprintf("environments.%s.%s", env, key)
+		rows = append(rows, map[string]interface{}{
+			"key":    fullKey,
+			"value":  flat[key],
+			"origin": origin(strings.ToLower(fullKey)),
+		})
+	}
+	return rows
+}
+
 // settingEndpointCmd updates the endpoint for the current environment
 var settingEndpointCmd = &cobra.Command{
 	Use:   "endpoint",
@@ -566,7 +641,7 @@ You can either specify a new endpoint URL directly or use the service-based endp
 			// Check if the URL starts with grpc:// or grpc+ssl://
 			if strings.HasPrefix(urlFlag, "grpc://") || strings.HasPrefix(urlFlag, "grpc+ssl://") {
 				appV.Set(fmt.Sprintf("environments.%s.endpoint", currentEnv), urlFlag)
-				if err := appV.WriteConfig(); err != nil {
+				if err := configs.WriteConfig(appV); err != nil {
 					pterm.Error.Printf("Failed to update setting.yaml: %v\n", err)
 					return
 				}
@@ -589,7 +664,7 @@ You can either specify a new endpoint URL directly or use the service-based endp
 			appV.Set(fmt.Sprintf("environments.%s.endpoint", currentEnv), urlFlag)
 			appV.Set(fmt.Sprintf("environments.%s.proxy", currentEnv), true)
 
-			if err := appV.WriteConfig(); err != nil {
+			if err := configs.WriteConfig(appV); err != nil {
 				pterm.Error.Printf("Failed to update setting.yaml: %v\n", err)
 				return
 			}
@@ -639,7 +714,7 @@ You can either specify a new endpoint URL directly or use the service-based endp
 				return
 			}
 
-			isProxy := appV.GetBool(fmt.Sprintf("environments.%s.proxy", currentEnv))
+			isProxy := configs.SettingBool(appV, fmt.Sprintf("environments.%s.proxy", currentEnv))
 
 			if strings.HasPrefix(endpointName, "grpc://") || strings.HasPrefix(endpointName, "grpc+ssl://") {
 				if !isProxy {
@@ -662,30 +737,19 @@ You can either specify a new endpoint URL directly or use the service-based endp
 				endpointName = strings.Join(parts[:len(parts)-1], "/")
 				parts = strings.Split(endpointName, "://")
 				if len(parts) != 2 {
-					fmt.Errorf("invalid endpoint format: %s", endpointName)
+					pterm.Error.Printf("Invalid endpoint format: %s\n", endpointName)
+					return
 				}
 
 				scheme := parts[0]
 				hostPort := parts[1]
 
-				// Configure gRPC connection based on scheme
-				var opts []grpc.DialOption
-				if scheme == "grpc+ssl" {
-					tlsConfig := &tls.Config{
-						InsecureSkipVerify: false, // Enable server certificate verification
-					}
-					creds := credentials.NewTLS(tlsConfig)
-					opts = append(opts, grpc.WithTransportCredentials(creds))
-				} else {
-					opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-				}
-
-				// Establish the connection
-				conn, err := grpc.Dial(hostPort, opts...)
+				// Get the shared connection, over TLS for grpc+ssl
+				conn, err := grpcconn.Get(hostPort, scheme != "grpc+ssl")
 				if err != nil {
-					fmt.Errorf("connection failed: unable to connect to %s: %v", endpointName, err)
+					pterm.Error.Printf("Connection failed: unable to connect to %s: %v\n", endpointName, err)
+					return
 				}
-				defer conn.Close()
 
 				// Use Reflection to discover services
 				refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
@@ -697,12 +761,14 @@ You can either specify a new endpoint URL directly or use the service-based endp
 
 				serviceDesc, err := refClient.ResolveService(serviceName)
 				if err != nil {
-					fmt.Errorf("failed to resolve service %s: %v", serviceName, err)
+					pterm.Error.Printf("Failed to resolve service %s: %v\n", serviceName, err)
+					return
 				}
 
 				methodDesc := serviceDesc.FindMethodByName(methodName)
 				if methodDesc == nil {
-					fmt.Errorf("method not found: %s", methodName)
+					pterm.Error.Printf("Method not found: %s\n", methodName)
+					return
 				}
 
 				// Dynamically create the request message
@@ -725,14 +791,16 @@ You can either specify a new endpoint URL directly or use the service-based endp
 				// Invoke the gRPC method
 				err = conn.Invoke(context.Background(), fullMethod, reqMsg, respMsg)
 				if err != nil {
-					fmt.Errorf("failed to invoke method %s: %v", fullMethod, err)
+					pterm.Error.Printf("Failed to invoke method %s: %v\n", fullMethod, err)
+					return
 				}
 
 				// Process the response to extract `service` and `endpoint`
 				endpoints = make(map[string]string)
 				resultsField := respMsg.FindFieldDescriptorByName("results")
 				if resultsField == nil {
-					fmt.Errorf("'results' field not found in response")
+					pterm.Error.Println("'results' field not found in response")
+					return
 				}
 
 				results := respMsg.GetField(resultsField).([]interface{})
@@ -848,7 +916,7 @@ You can either specify a new endpoint URL directly or use the service-based endp
 		// Handle URL flag
 		if urlFlag != "" {
 			appV.Set(fmt.Sprintf("environments.%s.endpoint", currentEnv), urlFlag)
-			if err := appV.WriteConfig(); err != nil {
+			if err := configs.WriteConfig(appV); err != nil {
 				pterm.Error.Printf("Failed to update setting.yaml: %v\n", err)
 				return
 			}
@@ -875,73 +943,6 @@ You can either specify a new endpoint URL directly or use the service-based endp
 	},
 }
 
-func invokeGRPCEndpointList(hostPort string, opts []grpc.DialOption) (map[string]string, error) {
-	// Wrap the entire operation in a function that can recover from panic
-	var endpoints = make(map[string]string)
-	var err error
-
-	defer func() {
-		if r := recover(); r != nil {
-			switch x := r.(type) {
-			case string:
-				err = fmt.Errorf("error: %s", x)
-			case error:
-				err = x
-			default:
-				err = fmt.Errorf("unknown panic: %v", r)
-			}
-		}
-	}()
-
-	// Establish the connection
-	conn, err := grpc.Dial(hostPort, opts...)
-	if err != nil {
-		return nil, fmt.Errorf("connection failed: unable to connect to %s: %v", hostPort, err)
-	}
-	defer conn.Close()
-
-	// Use Reflection to discover services
-	refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
-	defer refClient.Reset()
-
-	serviceName := "spaceone.api.identity.v2.Endpoint"
-	methodName := "list"
-
-	serviceDesc, err := refClient.ResolveService(serviceName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve service %s: %v", serviceName, err)
-	}
-
-	methodDesc := serviceDesc.FindMethodByName(methodName)
-	if methodDesc == nil {
-		return nil, fmt.Errorf("method not found: %s", methodName)
-	}
-
-	reqMsg := dynamic.NewMessage(methodDesc.GetInputType())
-	respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
-	fullMethod := fmt.Sprintf("/%s/%s", serviceName, methodName)
-
-	err = conn.Invoke(context.Background(), fullMethod, reqMsg, respMsg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to invoke method %s: %v", fullMethod, err)
-	}
-
-	resultsField := respMsg.FindFieldDescriptorByName("results")
-	if resultsField == nil {
-		return nil, fmt.Errorf("'results' field not found in response")
-	}
-
-	results := respMsg.GetField(resultsField).([]interface{})
-	for _, result := range results {
-		resultMsg := result.(*dynamic.Message)
-		serviceName := resultMsg.GetFieldByName("service").(string)
-		serviceEndpoint := resultMsg.GetFieldByName("endpoint").(string)
-		endpoints[serviceName] = serviceEndpoint
-	}
-
-	return endpoints, nil
-}
-
 // settingTokenCmd updates the token for the current environment
 // settingTokenCmd updates the token for the current environment
 var settingTokenCmd = &cobra.Command{
@@ -954,28 +955,23 @@ var settingTokenCmd = &cobra.Command{
 		settingDir := GetSettingDir()
 		settingPath := filepath.Join(settingDir, "setting.yaml")
 
-		v := viper.New()
-		v.SetConfigFile(settingPath)
-		v.SetConfigType("yaml")
-
-		if err := v.ReadInConfig(); err != nil {
+		if _, err := os.Stat(settingPath); err != nil {
 			pterm.Error.Printf("Failed to read setting file: %v\n", err)
 			return
 		}
 
-		// Get current environment
-		currentEnv := v.GetString("environment")
-		if currentEnv == "" {
-			pterm.Error.Println("No environment is currently selected.")
-			return
-		}
-
-		// Update token
-		tokenKey := fmt.Sprintf("environments.%s.token", currentEnv)
-		v.Set(tokenKey, args[0])
-
-		// Save configuration
-		if err := v.WriteConfig(); err != nil {
+		var currentEnv string
+		err := configs.UpdateConfig(settingPath, func(v *viper.Viper) error {
+			currentEnv = v.GetString("environment")
+			if currentEnv == "" {
+				return fmt.Errorf("no environment is currently selected")
+			}
+			tokenKey := fmt.Sprintf("environments.%s.token", currentEnv)
+			v.Set("version", configs.SettingVersion)
+			v.Set(tokenKey, secretSettingValue(tokenSecretKey(currentEnv), args[0]))
+			return nil
+		})
+		if err != nil {
 			pterm.Error.Printf("Failed to update token: %v\n", err)
 			return
 		}
@@ -987,205 +983,36 @@ var settingTokenCmd = &cobra.Command{
 
 // fetchAvailableServices retrieves the list of services by calling the List method on the Endpoint service.
 func fetchAvailableServices(identityEndpoint, restIdentityEndpoint string, hasIdentityEndpoint bool, token string) (map[string]string, error) {
-	endpoints := make(map[string]string)
-
-	if !hasIdentityEndpoint {
-		// Create HTTP client and request
-		client := &http.Client{}
-
-		// Define response structure
-		type EndpointResponse struct {
-			Results []struct {
-				Name     string `json:"name"`
-				Service  string `json:"service"`
-				Endpoint string `json:"endpoint"`
-			} `json:"results"`
-			TotalCount int `json:"total_count"`
-		}
-
-		// Create and send request
-		req, err := http.NewRequest("POST", restIdentityEndpoint+"/endpoint/list", bytes.NewBuffer([]byte("{}")))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %v", err)
-		}
-
-		req.Header.Set("accept", "application/json")
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to send request: %v", err)
-		}
-		defer resp.Body.Close()
-
-		// Parse response
-		var response EndpointResponse
-		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			return nil, fmt.Errorf("failed to decode response: %v", err)
-		}
-
-		// Extract services
-		for _, result := range response.Results {
-			endpoints[result.Service] = result.Endpoint
-		}
-
-		return endpoints, nil
-	} else {
-		parsedURL, err := url.Parse(identityEndpoint)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse endpoint: %w", err)
-		}
-
-		host := parsedURL.Hostname()
-		port := parsedURL.Port()
-		if port == "" {
-			port = "443" // Default gRPC port
-		}
-
-		var opts []grpc.DialOption
-
-		// Set up TLS credentials if the scheme is grpc+ssl://
-		if strings.HasPrefix(identityEndpoint, "grpc+ssl://") {
-			tlsSetting := &tls.Config{
-				InsecureSkipVerify: false, // Set to true only if you want to skip TLS verification (not recommended)
-			}
-			creds := credentials.NewTLS(tlsSetting)
-			opts = append(opts, grpc.WithTransportCredentials(creds))
-		} else {
-			return nil, fmt.Errorf("unsupported scheme in endpoint: %s", identityEndpoint)
-		}
-
-		// Add token-based authentication if a token is provided
-		if token != "" {
-			opts = append(opts, grpc.WithPerRPCCredentials(&tokenCreds{token}))
-		}
-
-		// Establish a connection to the gRPC server
-		conn, err := grpc.Dial(fmt.Sprintf("%s:%s", host, port), opts...)
-		if err != nil {
-			return nil, fmt.Errorf("failed to dial gRPC endpoint: %w", err)
-		}
-		defer conn.Close()
-
-		ctx := context.Background()
-
-		// Create a reflection client to discover services and methods
-		refClient := grpcreflect.NewClient(ctx, grpc_reflection_v1alpha.NewServerReflectionClient(conn))
-		defer refClient.Reset()
-
-		// Resolve the service descriptor for "spaceone.api.identity.v2.Endpoint"
-		serviceName := "spaceone.api.identity.v2.Endpoint"
-		svcDesc, err := refClient.ResolveService(serviceName)
-		if err != nil {
-			return nil, fmt.Errorf("failed to resolve service %s: %w", serviceName, err)
-		}
-
-		// Resolve the method descriptor for the "List" method
-		methodName := "list"
-		methodDesc := svcDesc.FindMethodByName(methodName)
-		if methodDesc == nil {
-			return nil, fmt.Errorf("method '%s' not found in service '%s'", methodName, serviceName)
-		}
-
-		inputType := methodDesc.GetInputType()
-		if inputType == nil {
-			return nil, fmt.Errorf("input type not found for method '%s'", methodName)
-		}
-
-		// Get the request and response message descriptors
-		reqDesc := methodDesc.GetInputType()
-		respDesc := methodDesc.GetOutputType()
-
-		// Create a dynamic message for the request
-		reqMsg := dynamic.NewMessage(reqDesc)
-		// If ListRequest has required fields, set them here. For example:
-		// reqMsg.SetField("page_size", 100)
-
-		// Create a dynamic message for the response
-		respMsg := dynamic.NewMessage(respDesc)
-
-		// Invoke the RPC method
-		//err = grpc.Invoke(ctx, fmt.Sprintf("/%s/%s", serviceName, methodName), reqMsg, conn, respMsg)
-		err = conn.Invoke(ctx, fmt.Sprintf("/%s/%s", serviceName, methodName), reqMsg, respMsg)
-		if err != nil {
-			return nil, fmt.Errorf("failed to invoke RPC: %w", err)
-		}
-
-		// Extract the 'results' field from the response message
-		resultsFieldDesc := respDesc.FindFieldByName("results")
-		if resultsFieldDesc == nil {
-			return nil, fmt.Errorf("'results' field not found in response message")
-		}
+	// Endpoint list goes over gRPC or the REST gateway depending on the endpoint
+	endpoint := restIdentityEndpoint
+	if hasIdentityEndpoint {
+		endpoint = identityEndpoint
+	}
+	client, err := apiclient.New(endpoint, "identity")
+	if err != nil {
+		return nil, err
+	}
 
-		resultsField, err := respMsg.TryGetField(resultsFieldDesc)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get 'results' field: %w", err)
-		}
+	resp, err := client.Call("Endpoint", "list", nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list endpoints: %w", err)
+	}
 
-		// 'results' is expected to be a repeated field (list) of messages
-		resultsSlice, ok := resultsField.([]interface{})
+	endpoints := make(map[string]string)
+	results, _ := resp["results"].([]interface{})
+	for _, result := range results {
+		item, ok := result.(map[string]interface{})
 		if !ok {
-			return nil, fmt.Errorf("'results' field is not a list")
+			continue
 		}
-
-		for _, res := range resultsSlice {
-			resMsg, ok := res.(*dynamic.Message)
-			if !ok {
-				continue
-			}
-
-			// Extract service field
-			serviceFieldDesc := resMsg.GetMessageDescriptor().FindFieldByName("service")
-			if serviceFieldDesc == nil {
-				continue
-			}
-
-			serviceField, err := resMsg.TryGetField(serviceFieldDesc)
-			if err != nil {
-				continue
-			}
-
-			serviceStr, ok := serviceField.(string)
-			if !ok {
-				continue
-			}
-
-			// Extract endpoint field
-			endpointFieldDesc := resMsg.GetMessageDescriptor().FindFieldByName("endpoint")
-			if endpointFieldDesc == nil {
-				continue
-			}
-
-			endpointField, err := resMsg.TryGetField(endpointFieldDesc)
-			if err != nil {
-				continue
-			}
-
-			endpointStr, ok := endpointField.(string)
-			if !ok {
-				continue
-			}
-
-			endpoints[serviceStr] = endpointStr
+		service, _ := item["service"].(string)
+		serviceEndpoint, _ := item["endpoint"].(string)
+		if service != "" && serviceEndpoint != "" {
+			endpoints[service] = serviceEndpoint
 		}
-
-		return endpoints, nil
 	}
-}
 
-// tokenCreds implements grpc.PerRPCCredentials for token-based authentication.
-type tokenCreds struct {
-	token string
-}
-
-func (t *tokenCreds) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
-	return map[string]string{
-		"authorization": fmt.Sprintf("Bearer %s", t.token),
-	}, nil
-}
-
-func (t *tokenCreds) RequireTransportSecurity() bool {
-	return true
+	return endpoints, nil
 }
 
 // getBaseURL retrieves the base URL for the current environment from the given Viper instance.
@@ -1195,7 +1022,7 @@ func getEndpoint(v *viper.Viper) (string, error) {
 		return "", fmt.Errorf("no environment is set")
 	}
 
-	baseURL := v.GetString(fmt.Sprintf("environments.%s.endpoint", currentEnv))
+	baseURL := configs.SettingString(v, fmt.Sprintf("environments.%s.endpoint", currentEnv))
 
 	if baseURL == "" {
 		return "", fmt.Errorf("no endpoint found for environment '%s' in setting.yaml", currentEnv)
@@ -1213,7 +1040,7 @@ func getToken(v *viper.Viper) (string, error) {
 	}
 
 	if strings.HasSuffix(currentEnv, "-app") {
-		token := v.GetString(fmt.Sprintf("environments.%s.token", currentEnv))
+		token := configs.SettingString(v, fmt.Sprintf("environments.%s.token", currentEnv))
 		if token == "" {
 			return "", fmt.Errorf("token not found in settings for environment: %s", currentEnv)
 		}
@@ -1221,18 +1048,11 @@ func getToken(v *viper.Viper) (string, error) {
 	}
 
 	if strings.HasSuffix(currentEnv, "-user") {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return "", fmt.Errorf("failed to get home directory: %v", err)
-		}
-
-		tokenPath := filepath.Join(home, ".cfctl", "cache", currentEnv, "access_token")
-		tokenBytes, err := os.ReadFile(tokenPath)
+		token, err := configs.AccessToken(currentEnv)
 		if err != nil {
 			return "", fmt.Errorf("failed to read token: %v", err)
 		}
-
-		return strings.TrimSpace(string(tokenBytes)), nil
+		return token, nil
 	}
 
 	return "", fmt.Errorf("unsupported environment type: %s", currentEnv)
@@ -1240,11 +1060,12 @@ func getToken(v *viper.Viper) (string, error) {
 
 // GetSettingDir returns the directory where setting file are stored
 func GetSettingDir() string {
-	home, err := os.UserHomeDir()
+	settingDir, err := configs.SettingDir()
 	if err != nil {
-		log.Fatalf("Unable to find home directory: %v", err)
+		pterm.Error.Printf("Unable to find home directory: %v\n", err)
+		cleanup.Exit(1)
 	}
-	return filepath.Join(home, ".cfctl")
+	return settingDir
 }
 
 // loadSetting ensures that the setting directory and setting file exist.
@@ -1252,19 +1073,21 @@ func GetSettingDir() string {
 func loadSetting(v *viper.Viper, settingPath string) error {
 	// Ensure the setting directory exists
 	settingDir := filepath.Dir(settingPath)
-	if err := os.MkdirAll(settingDir, 0755); err != nil {
+	if err := configs.EnsureSecureDir(settingDir); err != nil {
 		return fmt.Errorf("failed to create setting directory '%s': %w", settingDir, err)
 	}
 
 	// Set the setting file
 	v.SetConfigFile(settingPath)
 	v.SetConfigType("yaml")
+	v.SetConfigPermissions(configs.SecureFileMode)
 
 	// Read the setting file
-	if err := v.ReadInConfig(); err != nil {
+	if err := configs.ReadConfig(v); err != nil {
 		if os.IsNotExist(err) {
 			// Initialize with default values if file doesn't exist
 			defaultSettings := map[string]interface{}{
+				"version":      configs.SettingVersion,
 				"environments": map[string]interface{}{},
 				"environment":  "",
 			}
@@ -1274,12 +1097,12 @@ func loadSetting(v *viper.Viper, settingPath string) error {
 				return fmt.Errorf("failed to merge default settings: %w", err)
 			}
 
-			if err := v.WriteConfig(); err != nil {
+			if err := configs.WriteConfig(v); err != nil {
 				return fmt.Errorf("failed to write default settings: %w", err)
 			}
 
 			// Read the newly created file
-			if err := v.ReadInConfig(); err != nil {
+			if err := configs.ReadConfig(v); err != nil {
 				return fmt.Errorf("failed to read newly created setting file: %w", err)
 			}
 		} else {
@@ -1302,7 +1125,7 @@ func updateGlobalSetting() {
 
 	v.SetConfigFile(settingPath)
 
-	if err := v.ReadInConfig(); err != nil {
+	if err := configs.ReadConfig(v); err != nil {
 		if os.IsNotExist(err) {
 			pterm.Success.WithShowLineNumber(false).Printfln("Global setting updated with existing environments. (default: %s/setting.yaml)", GetSettingDir())
 			return
@@ -1360,16 +1183,6 @@ func updateSetting(envName, endpoint, envSuffix string, internal bool) {
 	settingDir := GetSettingDir()
 	mainSettingPath := filepath.Join(settingDir, "setting.yaml")
 
-	v := viper.New()
-	v.SetConfigFile(mainSettingPath)
-	v.SetConfigType("yaml")
-
-	// Read existing config if it exists
-	_ = v.ReadInConfig()
-
-	// Set environment
-	v.Set("environment", envName)
-
 	if internal {
 		// Get internal endpoint
 		internalEndpoint, err := getInternalEndpoint(endpoint)
@@ -1389,32 +1202,33 @@ func updateSetting(envName, endpoint, envSuffix string, internal bool) {
 		}
 	}
 
-	// Set endpoint in environments map
-	envKey := fmt.Sprintf("environments.%s.endpoint", envName)
-	v.Set(envKey, endpoint)
-
-	proxyKey := fmt.Sprintf("environments.%s.proxy", envName)
+	proxy := true
 	if strings.HasPrefix(endpoint, "grpc+ssl://") {
 		isProxy, err := transport.CheckIdentityProxyAvailable(endpoint)
 		if err != nil {
 			pterm.Warning.Printf("Failed to check gRPC endpoint: %v\n", err)
-			v.Set(proxyKey, true)
 		} else {
-			v.Set(proxyKey, isProxy)
+			proxy = isProxy
 		}
 	} else if strings.HasPrefix(endpoint, "grpc://") {
-		v.Set(proxyKey, false)
-	} else {
-		v.Set(proxyKey, true)
+		proxy = false
 	}
 
-	// Set token for non-user environments
-	if envSuffix != "user" {
-		tokenKey := fmt.Sprintf("environments.%s.token", envName)
-		v.Set(tokenKey, "no_token")
-	}
+	// The endpoint is checked before the setting is locked, to keep the lock short
+	err := configs.UpdateConfig(mainSettingPath, func(v *viper.Viper) error {
+		// A new setting is written in the current layout, so that it is not migrated
+		v.Set("version", configs.SettingVersion)
+		v.Set("environment", envName)
+		v.Set(fmt.Sprintf("environments.%s.endpoint", envName), endpoint)
+		v.Set(fmt.Sprintf("environments.%s.proxy", envName), proxy)
 
-	if err := v.WriteConfig(); err != nil {
+		// Set token for non-user environments
+		if envSuffix != "user" {
+			v.Set(fmt.Sprintf("environments.%s.token", envName), "no_token")
+		}
+		return nil
+	})
+	if err != nil {
 		pterm.Error.Printf("Failed to write setting file: %v\n", err)
 		return
 	}
@@ -1430,7 +1244,6 @@ func getInternalEndpoint(endpoint string) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("failed to connect to gRPC server: %v", err)
 		}
-		defer conn.Close()
 
 		client := grpc_reflection_v1alpha.NewServerReflectionClient(conn)
 		refClient := grpcreflect.NewClient(context.Background(), client)
@@ -1476,7 +1289,7 @@ func getInternalEndpoint(endpoint string) (string, error) {
 	} else {
 		// Handle REST endpoint
 		// 1. First get the console API endpoint from config
-		client := &http.Client{}
+		client := &http.Client{Transport: netproxy.Transport()}
 		configResp, err := client.Get(endpoint + "/config/production.json")
 		if err != nil {
 			return "", fmt.Errorf("failed to get config: %v", err)
@@ -1570,7 +1383,10 @@ func convertToSlice(s []interface{}) []interface{} {
 }
 
 func WriteConfigPreservingKeyOrder(v *viper.Viper, path string) error {
-	allSettings := v.AllSettings()
+	allSettings := configs.OwnSettings(v)
+	if err := configs.KeepVerbatimSections(path, allSettings); err != nil {
+		return err
+	}
 
 	rawBytes, err := yaml.Marshal(allSettings)
 	if err != nil {
@@ -1589,7 +1405,7 @@ func WriteConfigPreservingKeyOrder(v *viper.Viper, path string) error {
 		return fmt.Errorf("failed to marshal reordered yaml.Node: %w", err)
 	}
 
-	if err := os.WriteFile(path, reorderedBytes, 0644); err != nil {
+	if err := configs.WriteSecureFile(path, reorderedBytes); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -1645,6 +1461,8 @@ func init() {
 	settingInitCmd.AddCommand(settingInitProxyCmd)
 	settingInitCmd.AddCommand(settingInitStaticCmd)
 
+	settingInitStaticCmd.Flags().String("name", "", "Environment name (default: prompted for)")
+	settingInitProxyCmd.Flags().String("name", "", "Environment name without the -app or -user suffix (default: prompted for)")
 	settingInitProxyCmd.Flags().Bool("app", false, "Initialize as application configuration")
 	settingInitProxyCmd.Flags().Bool("user", false, "Initialize as user-specific configuration")
 	settingInitProxyCmd.Flags().Bool("internal", false, "Use internal endpoint for the environment")
@@ -1652,8 +1470,11 @@ func init() {
 	envCmd.Flags().StringP("switch", "s", "", "Switch to a different environment")
 	envCmd.Flags().StringP("remove", "r", "", "Remove an environment")
 	envCmd.Flags().BoolP("list", "l", false, "List available environments")
+	envCmd.RegisterFlagCompletionFunc("switch", completeEnvironments)
+	envCmd.RegisterFlagCompletionFunc("remove", completeEnvironments)
 
-	showCmd.Flags().StringP("output", "o", "yaml", "Output format (yaml/json)")
+	showCmd.Flags().StringP("output", "o", "yaml", "Output format (yaml, json, table, csv)")
+	showCmd.Flags().Bool("origin", false, "Show the file each value comes from, setting.yaml or one of its includes")
 
 	settingEndpointCmd.Flags().StringP("url", "u", "", "Direct URL to set as endpoint")
 	settingEndpointCmd.Flags().BoolP("list", "l", false, "List available services")
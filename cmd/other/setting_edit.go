@@ -0,0 +1,178 @@
+package other
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/ui"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var settingEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the setting file in your editor",
+	Long: `Open setting.yaml in $VISUAL or $EDITOR (vi by default) and check it, like
+'cfctl setting validate', once the editor is closed. The changes are only saved when they add no
+errors; otherwise the errors are shown and the file can be opened again to fix them.`,
+	Example: `  $ cfctl setting edit
+  $ EDITOR="code --wait" cfctl setting edit`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !configs.Interactive() {
+			pterm.Error.Println("Editing the setting needs a terminal.")
+			return
+		}
+
+		settingPath := filepath.Join(GetSettingDir(), "setting.yaml")
+		if resolved, err := filepath.EvalSymlinks(settingPath); err == nil {
+			settingPath = resolved
+		}
+		original, err := os.ReadFile(settingPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				pterm.Error.Printf("%s does not exist, run 'cfctl setting init' first.\n", settingPath)
+			} else {
+				pterm.Error.Printf("Failed to read %s: %v\n", settingPath, err)
+			}
+			return
+		}
+		known, err := validateSettingFile(settingPath)
+		if err != nil {
+			pterm.Error.Printf("Failed to validate %s: %v\n", settingPath, err)
+			return
+		}
+
+		// The copy keeps the name of the file so that editors highlight it as YAML
+		tmpDir, err := os.MkdirTemp("", "cfctl-edit-")
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		defer os.RemoveAll(tmpDir)
+		defer cleanup.RemoveOnAbort(tmpDir)()
+		editPath := filepath.Join(tmpDir, "setting.yaml")
+		if err := configs.WriteSecureFile(editPath, original); err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		for {
+			if err := runEditor(editPath); err != nil {
+				pterm.Error.Println(err)
+				return
+			}
+			edited, err := os.ReadFile(editPath)
+			if err != nil {
+				pterm.Error.Println(err)
+				return
+			}
+			if bytes.Equal(edited, original) {
+				pterm.Info.Println("No changes made.")
+				return
+			}
+
+			problems, err := validateSettingFile(editPath)
+			if err != nil {
+				pterm.Error.Printf("Failed to validate the changes: %v\n", err)
+				return
+			}
+			added := newSettingErrors(known, problems)
+			if len(added) == 0 {
+				if err := saveEditedSetting(settingPath, original, edited); err != nil {
+					pterm.Error.Println(err)
+					return
+				}
+				pterm.Success.Printf("Saved %s.\n", settingPath)
+				return
+			}
+
+			printSettingProblems("setting.yaml", added)
+			pterm.Error.Printf("The changes add %d error(s) and were not saved.\n", len(added))
+			if !configs.Interactive() {
+				return
+			}
+			again, _ := ui.Confirm("Edit the file again?", true)
+			if !again {
+				pterm.Info.Println("Changes discarded.")
+				return
+			}
+		}
+	},
+}
+
+// runEditor opens path in the editor of the user and waits until it is closed
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// The editor may be given with arguments, like "code --wait"
+	fields := strings.Fields(editor)
+	editorCmd := exec.Command(fields[0], append(fields[1:], path)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("editor '%s' failed: %v", editor, err)
+	}
+	return nil
+}
+
+// newSettingErrors returns the errors of problems that were not among the known problems of
+// the file before editing, so that an expired token does not keep other changes from being saved
+func newSettingErrors(known, problems []settingProblem) []settingProblem {
+	seen := make(map[string]bool, len(known))
+	for _, problem := range known {
+		seen[problem.Key+"\x00"+problem.Message] = true
+	}
+
+	var added []settingProblem
+	for _, problem := range problems {
+		if problem.Level == levelError && !seen[problem.Key+"\x00"+problem.Message] {
+			added = append(added, problem)
+		}
+	}
+	return added
+}
+
+// saveEditedSetting replaces the setting with the edited content. When another command changed
+// it in the meantime, the edited content is written next to it instead.
+func saveEditedSetting(settingPath string, original, edited []byte) error {
+	unlock, err := configs.LockFile(settingPath, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := os.ReadFile(settingPath)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, original) {
+		keptPath := settingPath + ".edited"
+		if err := configs.WriteSecureFile(keptPath, edited); err != nil {
+			return fmt.Errorf("%s was changed by another command while editing, and the changes could not be kept: %v", settingPath, err)
+		}
+		return fmt.Errorf("%s was changed by another command while editing; the changes were kept in %s", settingPath, keptPath)
+	}
+	return configs.WriteFileAtomic(settingPath, edited, configs.SecureFileMode)
+}
+
+func init() {
+	SettingCmd.AddCommand(settingEditCmd)
+}
@@ -0,0 +1,213 @@
+package other
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/credstore"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Lint severities, ordered from the most severe
+const (
+	severityHigh   = "HIGH"
+	severityMedium = "MEDIUM"
+	severityLow    = "LOW"
+)
+
+var severityOrder = map[string]int{severityHigh: 0, severityMedium: 1, severityLow: 2}
+
+// lintFinding is a single insecure setting found by 'cfctl setting lint'
+type lintFinding struct {
+	Severity string
+	Check    string
+	Target   string
+	Message  string
+	Fix      func() error
+	Fixed    bool
+}
+
+var settingLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the setting for insecure configuration",
+	Long: `Check the setting files for insecure configuration:
+  - plaintext tokens in setting.yaml while a system keyring is available
+  - insecure grpc:// endpoints on non-local hosts
+  - setting and cache files readable by other users
+
+Exits with code 1 when HIGH severity findings remain. Use --fix to repair what can be fixed automatically.`,
+	Example: `  $ cfctl setting lint
+  $ cfctl setting lint --fix`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		settingDir := GetSettingDir()
+		findings, err := lintSetting(settingDir)
+		if err != nil {
+			pterm.Error.Printf("Failed to lint setting: %v\n", err)
+			return
+		}
+
+		if len(findings) == 0 {
+			pterm.Success.Println("No insecure settings found.")
+			return
+		}
+
+		if fix {
+			for i := range findings {
+				if findings[i].Fix == nil {
+					continue
+				}
+				if err := findings[i].Fix(); err != nil {
+					pterm.Warning.Printf("Failed to fix %s: %v\n", findings[i].Target, err)
+					continue
+				}
+				findings[i].Fixed = true
+			}
+		}
+
+		tableData := pterm.TableData{{"Severity", "Check", "Target", "Message", "Fix"}}
+		remainingHigh := 0
+		for _, finding := range findings {
+			fixStatus := "manual"
+			switch {
+			case finding.Fixed:
+				fixStatus = pterm.FgGreen.Sprint("fixed")
+			case finding.Fix != nil:
+				fixStatus = "--fix"
+			}
+			if finding.Severity == severityHigh && !finding.Fixed {
+				remainingHigh++
+			}
+			tableData = append(tableData, []string{
+				colorSeverity(finding.Severity),
+				finding.Check,
+				finding.Target,
+				finding.Message,
+				fixStatus,
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+		if remainingHigh > 0 {
+			pterm.Error.Printf("%d HIGH severity finding(s) remain.\n", remainingHigh)
+			cleanup.Exit(1)
+		}
+	},
+}
+
+// lintSetting runs all security checks against the setting directory
+func lintSetting(settingDir string) ([]lintFinding, error) {
+	v, err := readSettingViper()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := configs.DecodeConfig(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []lintFinding
+
+	envNames := make([]string, 0, len(config.Environments))
+	for name := range config.Environments {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+
+	keyringAvailable := credstore.KeyringAvailable()
+	for _, envName := range envNames {
+		envConfig := config.Environments[envName]
+		// DecodeConfig resolves the references, so the stored value is checked
+		tokenKey := fmt.Sprintf("environments.%s.token", envName)
+		if rawToken := v.GetString(tokenKey); isPlaintextSecret(rawToken) && keyringAvailable {
+			findings = append(findings, lintFinding{
+				Severity: severityHigh,
+				Check:    "plaintext-token",
+				Target:   tokenKey,
+				Message:  "token is stored in plaintext although a system keyring is available, run 'cfctl setting migrate-secrets'",
+			})
+		}
+
+		endpoint := envConfig.Endpoint
+		if strings.HasPrefix(endpoint, "grpc://") && !isLocalEndpoint(endpoint) {
+			findings = append(findings, lintFinding{
+				Severity: severityMedium,
+				Check:    "insecure-endpoint",
+				Target:   fmt.Sprintf("environments.%s.endpoint", envName),
+				Message:  fmt.Sprintf("%s is not encrypted, use grpc+ssl://", endpoint),
+			})
+		}
+	}
+
+	permissionFindings, err := lintFilePermissions(settingDir)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, permissionFindings...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityOrder[findings[i].Severity] < severityOrder[findings[j].Severity]
+	})
+
+	return findings, nil
+}
+
+// lintFilePermissions reports setting and cache files accessible by group or others
+func lintFilePermissions(settingDir string) ([]lintFinding, error) {
+	insecure, err := configs.FindInsecurePaths(settingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []lintFinding
+	for _, p := range insecure {
+		severity := severityMedium
+		if p.Mode&0004 != 0 || strings.Contains(p.Path, string(filepath.Separator)+"cache"+string(filepath.Separator)) {
+			severity = severityHigh
+		}
+
+		target := p
+		findings = append(findings, lintFinding{
+			Severity: severity,
+			Check:    "file-permission",
+			Target:   p.Path,
+			Message:  fmt.Sprintf("mode %04o is too open, expected %04o", p.Mode, p.Expected),
+			Fix: func() error {
+				return configs.FixInsecurePaths([]configs.InsecurePath{target})
+			},
+		})
+	}
+
+	return findings, nil
+}
+
+// isLocalEndpoint reports whether the endpoint points to the local machine or cluster
+func isLocalEndpoint(endpoint string) bool {
+	return strings.Contains(endpoint, "localhost") ||
+		strings.Contains(endpoint, "127.0.0.1") ||
+		strings.Contains(endpoint, ".svc.cluster.local")
+}
+
+func colorSeverity(severity string) string {
+	switch severity {
+	case severityHigh:
+		return pterm.FgRed.Sprint(severity)
+	case severityMedium:
+		return pterm.FgYellow.Sprint(severity)
+	default:
+		return severity
+	}
+}
+
+func init() {
+	SettingCmd.AddCommand(settingLintCmd)
+
+	settingLintCmd.Flags().Bool("fix", false, "Fix findings that can be repaired automatically")
+}
@@ -0,0 +1,198 @@
+package other
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var migrateSecretsDryRun bool
+
+var settingMigrateSecretsCmd = &cobra.Command{
+	Use:   "migrate-secrets",
+	Short: "Move the tokens of setting.yaml and the token cache to the credential store",
+	Long: `Move the app tokens kept in plaintext in setting.yaml into the credential store, leaving
+references like "credential:token/<environment>" in their place. Tokens taken from environment
+variables with ${...} and the values of included files are left as they are.
+
+The access, refresh and grant tokens cached by 'cfctl login' under cache/<environment> are moved
+too, their files then only hold references like "credential:cache/<environment>/refresh_token".
+
+New tokens are stored this way already. The store is chosen by credential_store in setting.yaml
+or CFCTL_CREDENTIAL_STORE, see 'cfctl setting lint' for the tokens still in plaintext.`,
+	Example: `  $ cfctl setting migrate-secrets --dry-run
+  $ cfctl setting migrate-secrets`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		settingPath := filepath.Join(GetSettingDir(), "setting.yaml")
+		v := viper.New()
+		v.SetConfigFile(settingPath)
+		v.SetConfigType("yaml")
+		if err := configs.ReadConfig(v); err != nil {
+			pterm.Error.Printf("Failed to read setting file: %v\n", err)
+			cleanup.Exit(1)
+		}
+
+		store, err := credentialStore()
+		if err != nil {
+			pterm.Error.Printf("No credential store to move the tokens to: %v\n", err)
+			cleanup.Exit(1)
+		}
+
+		if migrateSecretsDryRun {
+			moved, _ := migrateSecrets(v, true)
+			cached, _ := migrateCachedTokens(true)
+			if moved+cached > 0 {
+				pterm.Info.Printf("%d token(s) would be moved to %s.\n", moved+cached, store.Name())
+			} else {
+				pterm.Success.Println("No plaintext token in setting.yaml or the token cache.")
+			}
+			return
+		}
+
+		// The setting stays locked from the read to the write, so that changes made by other
+		// commands meanwhile are not lost
+		moved := 0
+		err = configs.UpdateConfig(settingPath, func(v *viper.Viper) error {
+			var err error
+			moved, err = migrateSecrets(v, false)
+			return err
+		})
+		if err != nil {
+			pterm.Error.Println(err)
+			cleanup.Exit(1)
+		}
+		cached, err := migrateCachedTokens(false)
+		moved += cached
+		switch {
+		case err != nil:
+			pterm.Error.Printf("Failed to move the cached tokens: %v\n", err)
+			cleanup.Exit(1)
+		case moved == 0:
+			pterm.Success.Println("No plaintext token in setting.yaml or the token cache.")
+		default:
+			pterm.Success.Printf("Moved %d token(s) to %s.\n", moved, store.Name())
+		}
+	},
+}
+
+// migrateSecrets moves the plaintext tokens of the setting of v to the credential store and
+// replaces them by their reference, or only lists them with dryRun. It returns how many
+// tokens there are.
+func migrateSecrets(v *viper.Viper, dryRun bool) (int, error) {
+	environments, _ := configs.OwnSettings(v)["environments"].(map[string]interface{})
+	envNames := make([]string, 0, len(environments))
+	for name := range environments {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+
+	moved := 0
+	for _, envName := range envNames {
+		envSettings, ok := environments[envName].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if token, _ := envSettings["token"].(string); isPlaintextSecret(token) {
+			tokenKey := fmt.Sprintf("environments.%s.token", envName)
+			ref := configs.SecretRef(tokenSecretKey(envName))
+			if !dryRun {
+				var err error
+				if ref, err = storeSecret(tokenSecretKey(envName), token); err != nil {
+					return moved, err
+				}
+				v.Set(tokenKey, ref)
+			}
+			pterm.Printf("  %s -> %s\n", tokenKey, ref)
+			moved++
+		}
+
+		tokens, _ := envSettings["tokens"].([]interface{})
+		changed := false
+		for i, entry := range tokens {
+			item, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			token, _ := item["token"].(string)
+			if !isPlaintextSecret(token) {
+				continue
+			}
+			ref := configs.SecretRef(listedTokenSecretKey(envName, token))
+			if !dryRun {
+				var err error
+				if ref, err = storeSecret(listedTokenSecretKey(envName, token), token); err != nil {
+					return moved, err
+				}
+				tokens[i] = map[string]interface{}{"token": ref}
+				changed = true
+			}
+			pterm.Printf("  environments.%s.tokens[%d] -> %s\n", envName, i, ref)
+			moved++
+		}
+		if changed {
+			v.Set(fmt.Sprintf("environments.%s.tokens", envName), tokens)
+		}
+	}
+	return moved, nil
+}
+
+// migrateCachedTokens moves the tokens cached in plaintext by login to the credential store and
+// leaves only the reference in their files, or only lists them with dryRun. It returns how many
+// tokens there are.
+func migrateCachedTokens(dryRun bool) (int, error) {
+	paths, err := configs.CachedTokenFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return moved, err
+		}
+		token := strings.TrimSpace(string(data))
+		if !isPlaintextSecret(token) {
+			continue
+		}
+
+		key, err := configs.CachedTokenKey(path)
+		if err != nil {
+			return moved, err
+		}
+		ref := configs.SecretRef(key)
+		if !dryRun {
+			if ref, err = storeSecret(key, token); err != nil {
+				return moved, err
+			}
+			// Replacing the file drops the plaintext token from the cache
+			if err := configs.WriteSecureFile(path, []byte(ref)); err != nil {
+				return moved, fmt.Errorf("failed to write %s: %v", path, err)
+			}
+		}
+		pterm.Printf("  %s -> %s\n", key, ref)
+		moved++
+	}
+	return moved, nil
+}
+
+// isPlaintextSecret reports whether a token is written in setting.yaml itself, rather than
+// referring to the credential store or an environment variable
+func isPlaintextSecret(value string) bool {
+	return value != "" && !configs.IsSecretRef(value) && !strings.Contains(value, "${")
+}
+
+func init() {
+	settingMigrateSecretsCmd.Flags().BoolVar(&migrateSecretsDryRun, "dry-run", false, "List the tokens to move without changing anything")
+	SettingCmd.AddCommand(settingMigrateSecretsCmd)
+}
@@ -0,0 +1,169 @@
+package other
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/credstore"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var settingRotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Encrypt the saved passwords under a new key",
+	Long: `Generate a new encryption key in the credential store and encrypt the passwords saved in
+setting.yaml again with it, then remove the previous keys. Passwords saved by earlier versions
+with AES-CFB are upgraded to AES-GCM on the way.
+
+Only setting.yaml is encrypted again; passwords in included files keep needing their key.`,
+	Example: `  $ cfctl setting rotate-key`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		settingPath := filepath.Join(GetSettingDir(), "setting.yaml")
+		if _, err := os.Stat(settingPath); err != nil {
+			pterm.Error.Printf("Failed to read setting file: %v\n", err)
+			cleanup.Exit(1)
+		}
+
+		store, err := credentialStore()
+		if err != nil {
+			pterm.Error.Printf("No credential store to keep the key in: %v\n", err)
+			cleanup.Exit(1)
+		}
+		version, err := encryptionKeyVersion(store)
+		if err != nil {
+			pterm.Error.Println(err)
+			cleanup.Exit(1)
+		}
+
+		newVersion := version + 1
+		rotated, upgraded, err := rotateEncryptionKey(settingPath, store, version)
+		if err != nil {
+			pterm.Error.Println(err)
+			cleanup.Exit(1)
+		}
+
+		for old := 1; old < newVersion; old++ {
+			if err := store.Delete(encryptionKeyEntry(old)); err != nil {
+				pterm.Warning.Printf("Failed to remove the key version %d from %s: %v\n", old, store.Name(), err)
+			}
+		}
+
+		pterm.Success.Printf("Rotated the encryption key to version %d in %s.\n", newVersion, store.Name())
+		if rotated > 0 {
+			pterm.Info.Printf("Encrypted %d password(s) again, %d upgraded from AES-CFB.\n", rotated, upgraded)
+		}
+	},
+}
+
+// rotateEncryptionKey encrypts the passwords of the setting file at path again under the key
+// following version and makes that key the current one. It returns how many passwords were
+// encrypted again and how many of them were upgraded from AES-CFB. The previous keys are left
+// to the caller to remove.
+func rotateEncryptionKey(path string, store credstore.Store, version int) (int, int, error) {
+	newVersion := version + 1
+	var rotated, upgraded int
+	versionStored := false
+
+	// The setting stays locked until the passwords are written, so that no login saves one
+	// under the previous key in the meantime. The new version is stored before the write, so
+	// that the file never holds passwords of a key that is not current; until the write
+	// succeeded, the previous keys still decrypt the passwords of the file.
+	err := configs.UpdateConfig(path, func(v *viper.Viper) error {
+		var err error
+		rotated, upgraded, err = reencryptPasswords(v, store, newVersion)
+		if err != nil {
+			return err
+		}
+		if err := store.Set(encryptionKeyVersionName, strconv.Itoa(newVersion)); err != nil {
+			return fmt.Errorf("failed to store the key version in %s: %v", store.Name(), err)
+		}
+		versionStored = true
+		return nil
+	})
+	if err != nil {
+		if versionStored {
+			if rollbackErr := store.Set(encryptionKeyVersionName, strconv.Itoa(version)); rollbackErr != nil {
+				return 0, 0, fmt.Errorf("%v; failed to restore the key version %d in %s: %v", err, version, store.Name(), rollbackErr)
+			}
+		}
+		return 0, 0, err
+	}
+	return rotated, upgraded, nil
+}
+
+// reencryptPasswords encrypts the passwords saved in the setting of v again with the key of
+// newVersion, which is created if needed. It returns how many were encrypted again and how many
+// of them were upgraded from AES-CFB.
+func reencryptPasswords(v *viper.Viper, store credstore.Store, newVersion int) (int, int, error) {
+	// Decrypt everything first, so that nothing changes when a password cannot be read
+	type savedPassword struct {
+		user      map[string]interface{}
+		plaintext string
+		legacy    bool
+	}
+	var passwords []savedPassword
+	usersByEnv := make(map[string][]interface{})
+
+	environments, _ := configs.OwnSettings(v)["environments"].(map[string]interface{})
+	envNames := make([]string, 0, len(environments))
+	for name := range environments {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+
+	for _, envName := range envNames {
+		envSettings, _ := environments[envName].(map[string]interface{})
+		users, _ := envSettings["users"].([]interface{})
+		for _, entry := range users {
+			user, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			password, _ := user["password"].(string)
+			if password == "" {
+				continue
+			}
+			plaintext, err := decrypt(password)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to decrypt the password of '%v' in '%s': %v", user["user_id"], envName, err)
+			}
+			passwords = append(passwords, savedPassword{user: user, plaintext: plaintext, legacy: isLegacyCiphertext(password)})
+			usersByEnv[envName] = users
+		}
+	}
+
+	upgraded := 0
+	for _, p := range passwords {
+		ciphertext, err := encryptWithKey(store, newVersion, p.plaintext)
+		if err != nil {
+			return 0, 0, err
+		}
+		p.user["password"] = ciphertext
+		if p.legacy {
+			upgraded++
+		}
+	}
+	if len(passwords) == 0 {
+		// Without passwords the new key is made here instead of by encryptWithKey
+		if _, err := getEncryptionKey(store, newVersion, true); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	for envName, users := range usersByEnv {
+		v.Set(fmt.Sprintf("environments.%s.users", envName), users)
+	}
+	return len(passwords), upgraded, nil
+}
+
+func init() {
+	SettingCmd.AddCommand(settingRotateKeyCmd)
+}
@@ -0,0 +1,89 @@
+package other
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var settingSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Download the environments managed remotely",
+	Long: `Download the environments section from remote.url of setting.yaml and cache it locally.
+The URL may be a presigned S3 URL or any HTTP(S) URL serving YAML with an environments section,
+so that a platform team can manage the endpoints of all users in one place:
+
+  remote:
+    url: ${CFCTL_REMOTE_URL}
+    headers:
+      Authorization: Bearer ${CFCTL_REMOTE_TOKEN}
+
+The synced environments are merged beneath setting.yaml and its includes, which can still
+override them, and are used until the next sync. An unchanged file is not downloaded again.`,
+	Example: `  $ cfctl setting sync
+  $ cfctl setting show --origin`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		settingDir := GetSettingDir()
+		v := viper.New()
+		v.SetConfigFile(filepath.Join(settingDir, "setting.yaml"))
+		v.SetConfigType("yaml")
+		if err := configs.ReadConfig(v); err != nil {
+			pterm.Error.Printf("Failed to read setting file: %v\n", err)
+			cleanup.Exit(1)
+		}
+		if v.GetString("remote.url") == "" {
+			pterm.Error.Println("No remote.url in setting.yaml, add the URL serving the environments first.")
+			cleanup.Exit(1)
+		}
+
+		lastSync := configs.RemoteSyncedAt(settingDir)
+		result, err := configs.SyncRemote(v, settingDir)
+		if err != nil {
+			pterm.Error.Println(err)
+			if !lastSync.IsZero() {
+				pterm.Info.Printf("The environments synced %s ago are still used.\n", time.Since(lastSync).Round(time.Second))
+			}
+			cleanup.Exit(1)
+		}
+
+		if result.NotModified {
+			pterm.Success.Printf("The environments are up to date with %s.\n", result.Source)
+			return
+		}
+		if len(result.Added)+len(result.Updated)+len(result.Removed) == 0 {
+			pterm.Success.Printf("Synced %s, no environment changed.\n", result.Source)
+			return
+		}
+		pterm.Success.Printf("Synced %s.\n", result.Source)
+		for _, name := range result.Added {
+			pterm.Printf("  %s %s\n", pterm.FgGreen.Sprint("+"), name)
+		}
+		for _, name := range result.Updated {
+			pterm.Printf("  %s %s\n", pterm.FgYellow.Sprint("~"), name)
+		}
+		for _, name := range result.Removed {
+			pterm.Printf("  %s %s\n", pterm.FgRed.Sprint("-"), name)
+		}
+
+		// The current environment may have been removed by the platform team
+		current := v.GetString("environment")
+		synced := viper.New()
+		synced.SetConfigFile(v.ConfigFileUsed())
+		synced.SetConfigType("yaml")
+		for _, name := range result.Removed {
+			if name == current && configs.ReadConfig(synced) == nil && !synced.IsSet("environments."+name) {
+				pterm.Warning.Printf("The current environment '%s' was removed, switch with 'cfctl setting environment -s <name>'.\n", current)
+			}
+		}
+	},
+}
+
+func init() {
+	SettingCmd.AddCommand(settingSyncCmd)
+}
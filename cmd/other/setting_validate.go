@@ -0,0 +1,493 @@
+package other
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"github.com/cloudforet-io/cfctl/internal/tlsconf"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/token"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Levels of the problems found by 'cfctl setting validate'
+const (
+	levelError   = "ERROR"
+	levelWarning = "WARNING"
+)
+
+var settingValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the setting file for structural problems",
+	Long: `Check setting.yaml for:
+  - syntax errors and keys defined twice, including environments differing only in case
+  - unknown keys, which are ignored by cfctl and usually misspelled
+  - values of the wrong type
+  - missing endpoints and endpoints with an unsupported scheme
+  - app environments without a token
+  - expired app tokens, and user environments whose login has expired
+
+Exits with code 1 when errors are found.`,
+	Example: `  $ cfctl setting validate`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		settingPath := filepath.Join(GetSettingDir(), "setting.yaml")
+		problems, err := validateSettingFile(settingPath)
+		if err != nil {
+			pterm.Error.Printf("Failed to validate %s: %v\n", settingPath, err)
+			return
+		}
+
+		if len(problems) == 0 {
+			pterm.Success.Printf("%s is valid.\n", settingPath)
+			return
+		}
+
+		errors := printSettingProblems(filepath.Base(settingPath), problems)
+		if errors > 0 {
+			pterm.Error.Printf("%d error(s) and %d warning(s) found.\n", errors, len(problems)-errors)
+			cleanup.Exit(1)
+		}
+		pterm.Warning.Printf("%d warning(s) found.\n", len(problems))
+	},
+}
+
+// printSettingProblems renders the problems of the named file as a table and returns the
+// number of errors among them
+func printSettingProblems(name string, problems []settingProblem) int {
+	tableData := pterm.TableData{{"Level", "Location", "Key", "Problem", "Fix"}}
+	errors := 0
+	for _, problem := range problems {
+		level := pterm.FgYellow.Sprint(problem.Level)
+		if problem.Level == levelError {
+			level = pterm.FgRed.Sprint(problem.Level)
+			errors++
+		}
+		location := name
+		if problem.Line > 0 {
+			location = fmt.Sprintf("%s:%d", location, problem.Line)
+		}
+		tableData = append(tableData, []string{level, location, problem.Key, problem.Message, problem.Fix})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	return errors
+}
+
+// settingProblem is a single problem found by 'cfctl setting validate'
+type settingProblem struct {
+	Level   string
+	Line    int
+	Key     string
+	Message string
+	Fix     string
+}
+
+// settingKeys describes the keys allowed in a mapping of setting.yaml. A nil value accepts
+// anything below the key, and "*" matches any key, like the names of the environments.
+type settingKeys map[string]settingKeys
+
+var environmentKeys = settingKeys{
+	"endpoint":            nil,
+	"proxy":               nil,
+	"network_proxy":       nil,
+	"tls":                 {"ca_file": nil, "cert_file": nil, "key_file": nil, "insecure_skip_verify": nil},
+	"token":               nil,
+	"tokens":              {"token": nil},
+	"user_id":             nil,
+	"users":               {"user_id": nil, "label": nil, "last_login": nil, "password": nil},
+	"save_credentials":    nil,
+	"credential_cache":    nil,
+	"sso_url":             nil,
+	"oidc":                {"token_exchange_endpoint": nil, "audience": nil, "token_env": nil},
+	"maintenance_windows": {"name": nil, "cron": nil, "duration": nil},
+	"command_defaults":    nil,
+}
+
+var settingSchema = settingKeys{
+	"version":            nil,
+	"environment":        nil,
+	"environments":       {"*": environmentKeys},
+	"aliases":            nil,
+	"command_defaults":   nil,
+	"includes":           nil,
+	"remote":             {"url": nil, "headers": nil},
+	"telemetry":          nil,
+	"telemetry_endpoint": nil,
+	"credential_store":   {"backend": nil, "helper": nil},
+	"credential_cache":   nil,
+	"id_fields":          nil,
+	"column_formats":     nil,
+	"currency":           {"display": nil, "rates": nil, "rate_source": nil},
+}
+
+// validateSettingFile checks the structure of the file first, as duplicate keys keep it from
+// being read at all, and then the decoded values of each environment
+func validateSettingFile(path string) ([]settingProblem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []settingProblem{{
+				Level:   levelError,
+				Message: "the setting file does not exist",
+				Fix:     "run 'cfctl setting init'",
+			}}, nil
+		}
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []settingProblem{{Level: levelError, Message: err.Error(), Fix: "fix the YAML syntax"}}, nil
+	}
+	if len(root.Content) == 0 {
+		return []settingProblem{{Level: levelError, Message: "the setting file is empty", Fix: "run 'cfctl setting init'"}}, nil
+	}
+
+	problems := checkSettingKeys(root.Content[0], settingSchema, "")
+	problems = append(problems, checkEnvironmentNames(root.Content[0])...)
+	for _, problem := range problems {
+		if problem.Level == levelError {
+			// the values cannot be decoded until the structure is fixed
+			return problems, nil
+		}
+	}
+
+	var raw map[string]interface{}
+	if err := root.Decode(&raw); err != nil {
+		return append(problems, settingProblem{Level: levelError, Message: err.Error(), Fix: "fix the YAML syntax"}), nil
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.MergeConfigMap(raw); err != nil {
+		return nil, err
+	}
+	if err := configs.ApplyIncludes(v, GetSettingDir()); err != nil {
+		return append(problems, settingProblem{Level: levelError, Key: "includes", Message: err.Error(), Fix: "fix the list of included files"}), nil
+	}
+	config, err := configs.DecodeConfig(v)
+	if err != nil {
+		return append(problems, settingProblem{Level: levelError, Message: err.Error(), Fix: "correct the types of the listed entries"}), nil
+	}
+
+	problems = append(problems, checkEnvironments(config, settingLines(root.Content[0]))...)
+	problems = append(problems, checkVariables(root.Content[0])...)
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Level != problems[j].Level {
+			return problems[i].Level == levelError
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// checkSettingKeys reports keys defined twice and keys that are not in the schema
+func checkSettingKeys(node *yaml.Node, schema settingKeys, path string) []settingProblem {
+	var problems []settingProblem
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			problems = append(problems, checkSettingKeys(item, schema, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case yaml.MappingNode:
+	default:
+		return nil
+	}
+
+	lines := map[string]int{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key := joinSettingKey(path, keyNode.Value)
+
+		if line, ok := lines[keyNode.Value]; ok {
+			problems = append(problems, settingProblem{
+				Level:   levelError,
+				Line:    keyNode.Line,
+				Key:     key,
+				Message: fmt.Sprintf("defined again, first defined at line %d", line),
+				Fix:     "remove or rename one of the entries",
+			})
+			continue
+		}
+		lines[keyNode.Value] = keyNode.Line
+
+		children, known := schema[keyNode.Value]
+		if !known {
+			children, known = schema["*"]
+		}
+		if !known {
+			problem := settingProblem{
+				Level:   levelWarning,
+				Line:    keyNode.Line,
+				Key:     key,
+				Message: "unknown key, it is ignored",
+				Fix:     "remove the key",
+			}
+			if suggestion := closestSettingKey(keyNode.Value, schema); suggestion != "" {
+				problem.Fix = fmt.Sprintf("did you mean '%s'?", suggestion)
+			}
+			problems = append(problems, problem)
+			continue
+		}
+		if children != nil {
+			problems = append(problems, checkSettingKeys(valueNode, children, key)...)
+		}
+	}
+	return problems
+}
+
+// checkEnvironmentNames reports environments whose names only differ in case, which are
+// merged when the file is read as keys are not case sensitive
+func checkEnvironmentNames(root *yaml.Node) []settingProblem {
+	environments := mappingValue(root, "environments")
+	if environments == nil || environments.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var problems []settingProblem
+	names := map[string]string{}
+	for i := 0; i+1 < len(environments.Content); i += 2 {
+		keyNode := environments.Content[i]
+		lower := strings.ToLower(keyNode.Value)
+		if first, ok := names[lower]; ok && first != keyNode.Value {
+			problems = append(problems, settingProblem{
+				Level:   levelError,
+				Line:    keyNode.Line,
+				Key:     "environments." + keyNode.Value,
+				Message: fmt.Sprintf("clashes with environment '%s', names are not case sensitive", first),
+				Fix:     "remove or rename one of the environments",
+			})
+			continue
+		}
+		names[lower] = keyNode.Value
+	}
+	return problems
+}
+
+// checkEnvironments reports the problems in the values of the environments
+func checkEnvironments(config *configs.Config, lines map[string]int) []settingProblem {
+	var problems []settingProblem
+	add := func(level, key, message, fix string) {
+		problems = append(problems, settingProblem{Level: level, Line: lines[key], Key: key, Message: message, Fix: fix})
+	}
+
+	if config.Environment == "" {
+		add(levelError, "environment", "no environment is selected", "run 'cfctl setting environment -s <name>'")
+	} else if _, ok := config.Environments[config.Environment]; !ok {
+		add(levelError, "environment", fmt.Sprintf("environment '%s' is not defined under environments", config.Environment), "run 'cfctl setting environment -s <name>' with a defined environment")
+	}
+
+	if err := configs.ValidateCredentialCache(config.CredentialCache); err != nil {
+		add(levelError, "credential_cache", err.Error(), "set it to none, password or token")
+	}
+
+	names := make([]string, 0, len(config.Environments))
+	for name := range config.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		envConfig := config.Environments[name]
+		prefix := "environments." + name
+		switchHint := ""
+		if name != config.Environment {
+			switchHint = fmt.Sprintf("run 'cfctl setting environment -s %s', then ", name)
+		}
+		// problems of other environments only show once they are selected
+		level := levelWarning
+		if name == config.Environment {
+			level = levelError
+		}
+
+		if envConfig.Endpoint == "" {
+			add(level, prefix, "no endpoint is set", switchHint+"run 'cfctl setting endpoint'")
+		} else if err := configs.ValidateEndpoint(envConfig.Endpoint); err != nil {
+			add(levelError, prefix+".endpoint", err.Error(), "use an endpoint like grpc+ssl://identity.example.com:443")
+		}
+
+		if err := netproxy.Validate(envConfig.NetworkProxy); err != nil {
+			add(levelError, prefix+".network_proxy", err.Error(), "use http://, https:// or socks5:// with a host, or none")
+		}
+		if settings, err := envConfig.TLS.Settings(); err != nil {
+			add(levelError, prefix+".tls", err.Error(), "fix the file paths")
+		} else if _, err := tlsconf.Build(settings); err != nil {
+			add(levelError, prefix+".tls", err.Error(), "point ca_file to a PEM bundle and cert_file and key_file to a matching pair")
+		} else if settings.InsecureSkipVerify {
+			add(levelWarning, prefix+".tls.insecure_skip_verify", "server certificates are not verified", "set ca_file to the CA of the endpoint instead")
+		}
+		if err := configs.ValidateCredentialCache(envConfig.CredentialCache); err != nil {
+			add(levelError, prefix+".credential_cache", err.Error(), "set it to none, password or token")
+		}
+
+		for i, user := range envConfig.Users {
+			if user.UserID == "" {
+				key := fmt.Sprintf("%s.users[%d]", prefix, i)
+				add(levelWarning, key, "user_id is missing", "add the user_id or remove the entry")
+			}
+		}
+
+		switch {
+		case strings.HasSuffix(name, "-app"):
+			if envConfig.Token == "" {
+				if name == config.Environment && configs.EnvToken() != "" {
+					break
+				}
+				add(level, prefix, "app environment has no token", switchHint+"run 'cfctl setting token <token>'")
+			} else if expired, detail := tokenExpired(envConfig.Token); expired {
+				add(level, prefix+".token", "token "+detail, switchHint+"issue a new app token and run 'cfctl setting token <token>'")
+			}
+		case strings.HasSuffix(name, "-user"):
+			refreshToken, err := readTokenFromFile(configs.CacheDir(name), "refresh_token")
+			if err != nil {
+				break
+			}
+			if expired, detail := tokenExpired(refreshToken); expired {
+				add(levelWarning, prefix, "login "+detail, switchHint+"run 'cfctl login'")
+			}
+		}
+
+		for i, t := range envConfig.Tokens {
+			if expired, detail := tokenExpired(t.Token); expired {
+				add(levelWarning, fmt.Sprintf("%s.tokens[%d]", prefix, i), "token "+detail, "remove the entry")
+			}
+		}
+	}
+	return problems
+}
+
+// tokenExpired reports whether the token is expired, with a description like "expired 3d ago"
+func tokenExpired(value string) (bool, string) {
+	claims, err := token.Decode(value)
+	if err != nil {
+		return true, "cannot be decoded"
+	}
+	expiresAt, ok := claims.ExpiresAt()
+	if !ok {
+		return false, ""
+	}
+	now := time.Now()
+	return now.After(expiresAt), token.Remaining(expiresAt, now)
+}
+
+// checkVariables reports environment variables in the endpoint, proxies and token of the
+// environments that are not set and have no default, as they are replaced by nothing
+func checkVariables(root *yaml.Node) []settingProblem {
+	environments := mappingValue(root, "environments")
+	if environments == nil || environments.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var problems []settingProblem
+	for i := 0; i+1 < len(environments.Content); i += 2 {
+		name, env := environments.Content[i].Value, environments.Content[i+1]
+		for _, key := range []string{"endpoint", "proxy", "network_proxy", "token"} {
+			value := mappingValue(env, key)
+			if value == nil || value.Kind != yaml.ScalarNode {
+				continue
+			}
+			for _, variable := range configs.MissingVariables(value.Value) {
+				problems = append(problems, settingProblem{
+					Level:   levelWarning,
+					Line:    value.Line,
+					Key:     fmt.Sprintf("environments.%s.%s", name, key),
+					Message: fmt.Sprintf("environment variable %s is not set", variable),
+					Fix:     fmt.Sprintf("export %s or give a default as ${%s:-<value>}", variable, variable),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// settingLines maps the dotted keys of the environments to their lines in the file
+func settingLines(root *yaml.Node) map[string]int {
+	lines := map[string]int{}
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := joinSettingKey(path, node.Content[i].Value)
+				lines[key] = node.Content[i].Line
+				walk(node.Content[i+1], key)
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				key := fmt.Sprintf("%s[%d]", path, i)
+				lines[key] = item.Line
+				walk(item, key)
+			}
+		}
+	}
+	walk(root, "")
+	return lines
+}
+
+// mappingValue returns the value of the key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func joinSettingKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closestSettingKey suggests a known key for a misspelled one, like endpont for endpoint
+func closestSettingKey(key string, schema settingKeys) string {
+	best, bestDistance := "", 3
+	for candidate := range schema {
+		if candidate == "*" {
+			continue
+		}
+		if d := editDistance(strings.ToLower(key), candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(min(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func init() {
+	SettingCmd.AddCommand(settingValidateCmd)
+}
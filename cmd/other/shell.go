@@ -0,0 +1,208 @@
+package other
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// shellBuiltins are the commands of the shell itself, besides the commands of cfctl
+var shellBuiltins = [][]string{
+	{"use <environment>", "Switch to another environment of setting.yaml for this session only"},
+	{"help", "Show this help, 'help <command>' shows the help of a command"},
+	{"exit", "Leave the shell (also quit or Ctrl-D)"},
+}
+
+// NewShellCmd returns 'cfctl shell', which runs the commands of root read from the terminal in
+// one process. The gRPC connections, the setting and the service descriptors are kept between
+// the commands instead of being set up again for every call.
+func NewShellCmd(root *cobra.Command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "shell",
+		Short: "Run several commands in an interactive shell",
+		Long: `Start an interactive shell running cfctl commands without the leading 'cfctl'. The
+connections to the services and their descriptors are kept for the whole session, so calls
+after the first one start right away.
+
+Besides the commands of cfctl, the shell understands:
+  use <environment>   switch to another environment of setting.yaml for this session only
+  help                show the commands of the shell
+  exit                leave the shell (also quit or Ctrl-D)
+
+A command failing returns to the prompt. 'use' leaves the environment of setting.yaml, and so
+of other terminals, as is; 'setting environment --switch' changes it for all of them.`,
+		Example: `  $ cfctl shell
+  cfctl dev-user> identity list Project -o table
+  cfctl dev-user> identity list User --browse
+  cfctl dev-user> use stg-user
+  cfctl stg-user> exit`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !configs.Interactive() {
+				pterm.Error.Println("The shell needs a terminal, run the commands directly instead.")
+				return
+			}
+			runShell(root)
+		},
+	}
+}
+
+// runShell reads and runs commands until exit or the end of the input
+func runShell(root *cobra.Command) {
+	pterm.Info.Println("Type 'help' for the commands of the shell and 'exit' to leave it.")
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print(shellPrompt())
+		if !scanner.Scan() {
+			fmt.Println()
+			return
+		}
+
+		args, err := splitShellLine(scanner.Text())
+		if err != nil {
+			pterm.Error.Println(err)
+			continue
+		}
+		if len(args) > 0 && args[0] == "cfctl" {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		switch args[0] {
+		case "exit", "quit":
+			return
+		case "shell":
+			pterm.Warning.Println("Already in the shell.")
+			continue
+		case "help":
+			if len(args) == 1 {
+				printShellHelp()
+				continue
+			}
+		case "use":
+			if len(args) != 2 {
+				pterm.Error.Println("Usage: use <environment>")
+				continue
+			}
+			if err := configs.SetSessionEnvironment(args[1]); err != nil {
+				pterm.Error.Println(err)
+			}
+			continue
+		}
+
+		root.SetArgs(args)
+		// Commands exiting early return to the prompt instead of ending the shell
+		cleanup.Guard(func() {
+			_ = root.Execute()
+		})
+		resetFlags(root)
+		if err := configs.FlushSetting(); err != nil {
+			pterm.Error.Println(err)
+		}
+	}
+}
+
+// shellPrompt shows the current environment
+func shellPrompt() string {
+	env := ""
+	if v, err := configs.Setting(); err == nil {
+		env = v.GetString("environment")
+	}
+	if env == "" {
+		return "cfctl> "
+	}
+	return fmt.Sprintf("cfctl %s> ", pterm.FgLightCyan.Sprint(env))
+}
+
+func printShellHelp() {
+	tableData := pterm.TableData{{"Command", "Description"}}
+	for _, builtin := range shellBuiltins {
+		tableData = append(tableData, builtin)
+	}
+	tableData = append(tableData, []string{"<command> ...", "Any cfctl command without 'cfctl', e.g. identity list Project"})
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// resetFlags restores the defaults of the flags given to the previous command, as cobra keeps
+// the parsed values in the commands
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			defaults := strings.TrimSuffix(strings.TrimPrefix(flag.DefValue, "["), "]")
+			if defaults == "" {
+				slice.Replace([]string{})
+			} else {
+				slice.Replace(strings.Split(defaults, ","))
+			}
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, child := range cmd.Commands() {
+		resetFlags(child)
+	}
+}
+
+// splitShellLine splits a line into arguments like a shell: on spaces, except within single or
+// double quotes, and with backslash escaping the next character outside single quotes
+func splitShellLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("line ends with an escape")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,160 @@
+package other
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/telemetry"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// TelemetryCmd manages the anonymous usage telemetry, which is off unless turned on
+var TelemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Manage anonymous usage telemetry",
+	Long: `Anonymous usage telemetry helps the maintainers see which commands are used. It is off
+unless turned on with 'cfctl telemetry on --endpoint <url>', and DO_NOT_TRACK=1 or
+CFCTL_TELEMETRY=off always turn it off. There is no built-in endpoint: events are only sent to
+the one given with --endpoint or CFCTL_TELEMETRY_ENDPOINT.
+
+Only the command name (e.g. "cfctl setting show"), the cfctl version, the OS, the duration
+and whether the command succeeded are reported. Arguments, flags, resource names, endpoints
+and identifiers of users or machines are never recorded. Events are sent at most once a day;
+'cfctl telemetry preview' shows exactly what would be sent.`,
+	Example: `  $ cfctl telemetry status
+  $ cfctl telemetry preview
+  $ cfctl telemetry on --endpoint https://telemetry.example.com/v1/cfctl/events`,
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether telemetry is on",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		v, _ := configs.Setting()
+		enabled, reason := telemetry.Status(v)
+		state := "off"
+		if enabled {
+			state = "on"
+		}
+		pending, _ := telemetry.Pending()
+
+		pterm.DefaultTable.WithData(pterm.TableData{
+			{"Telemetry", fmt.Sprintf("%s (%s)", state, reason)},
+			{"Endpoint", endpointLabel(telemetry.Endpoint(v))},
+			{"Queued events", fmt.Sprint(len(pending))},
+		}).Render()
+	},
+}
+
+var telemetryOnCmd = &cobra.Command{
+	Use:   "on",
+	Short: "Turn telemetry on",
+	Long: `Turn telemetry on, sending the events to the endpoint given with --endpoint. The endpoint
+is kept in setting.yaml, so it is only needed the first time; CFCTL_TELEMETRY_ENDPOINT takes
+precedence over it.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		endpoint, _ := cmd.Flags().GetString("endpoint")
+		setTelemetry(true, endpoint)
+	},
+}
+
+var telemetryOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Turn telemetry off and drop the queued events",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setTelemetry(false, "")
+		if err := telemetry.Clear(); err != nil {
+			pterm.Warning.Printf("Failed to drop the queued events: %v\n", err)
+		}
+	},
+}
+
+var telemetryPreviewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Show exactly what would be sent",
+	Long: `Print the request body the next send would post to the endpoint. When no events are
+queued, e.g. while telemetry is off, the event of this command is shown as an example.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		v, _ := configs.Setting()
+		events, err := telemetry.Pending()
+		if err != nil {
+			pterm.Error.Printf("Failed to read the queued events: %v\n", err)
+			return
+		}
+		if len(events) == 0 {
+			pterm.Info.Println("No events are queued. This command would be reported as:")
+			events = []telemetry.Event{telemetry.NewEvent(cmd.CommandPath(), BuildVersion(), time.Since(telemetry.Start), true)}
+		} else {
+			pterm.Info.Printf("POST %s\n", endpointLabel(telemetry.Endpoint(v)))
+		}
+
+		data, err := json.MarshalIndent(telemetry.Payload{Events: events}, "", "  ")
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		fmt.Println(string(data))
+	},
+}
+
+// endpointLabel shows the telemetry endpoint, or that there is none
+func endpointLabel(endpoint string) string {
+	if endpoint == "" {
+		return "(not configured)"
+	}
+	return endpoint
+}
+
+// setTelemetry saves the telemetry choice in setting.yaml, with the endpoint when one is given
+func setTelemetry(enabled bool, endpoint string) {
+	v, err := configs.Setting()
+	if err != nil {
+		if os.IsNotExist(err) {
+			pterm.Error.Println("No setting file found, run 'cfctl setting init' first.")
+		} else {
+			pterm.Error.Println(err)
+		}
+		return
+	}
+	if endpoint != "" {
+		parsed, err := url.Parse(endpoint)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			pterm.Error.Printf("Invalid telemetry endpoint '%s', use a URL like https://telemetry.example.com/v1/cfctl/events.\n", endpoint)
+			return
+		}
+		v.Set(telemetry.EndpointSettingKey, endpoint)
+	}
+	if enabled && telemetry.Endpoint(v) == "" {
+		pterm.Error.Printf("No telemetry endpoint is configured, pass it with --endpoint or set %s.\n", telemetry.EnvVarEndpoint)
+		return
+	}
+	v.Set(telemetry.SettingKey, enabled)
+	configs.MarkSettingDirty()
+
+	if !enabled {
+		pterm.Success.Println("Telemetry is off.")
+		return
+	}
+	pterm.Success.Println("Telemetry is on. Thank you for helping to improve cfctl!")
+	if effective, reason := telemetry.Status(v); !effective {
+		pterm.Warning.Printf("Nothing is sent while %s.\n", reason)
+	}
+}
+
+func init() {
+	TelemetryCmd.AddCommand(telemetryStatusCmd)
+	TelemetryCmd.AddCommand(telemetryOnCmd)
+	TelemetryCmd.AddCommand(telemetryOffCmd)
+	TelemetryCmd.AddCommand(telemetryPreviewCmd)
+
+	telemetryOnCmd.Flags().String("endpoint", "", "URL receiving the events, kept in setting.yaml")
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package other
+
+import "os"
+
+// openTerminal opens the controlling terminal for reading and writing, also when stdin and
+// stdout are redirected
+func openTerminal() (in, out *os.File, err error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err = os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		tty.Close()
+		return nil, nil, err
+	}
+	return tty, out, nil
+}
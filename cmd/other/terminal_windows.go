@@ -0,0 +1,20 @@
+//go:build windows
+
+package other
+
+import "os"
+
+// openTerminal opens the console for reading and writing, also when stdin and stdout are
+// redirected
+func openTerminal() (in, out *os.File, err error) {
+	in, err = os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err = os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+	return in, out, nil
+}
@@ -0,0 +1,278 @@
+package other
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/cloudforet-io/cfctl/internal/ui"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/token"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// TokenCmd represents the token command
+var TokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the access token of the current environment",
+	Long:  `Inspect, refresh and forget the access token used by the current environment.`,
+}
+
+var tokenShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the current access token",
+	Example: `  # Show a summary of the current access token
+  $ cfctl token show
+
+  # Print all claims of the access token
+  $ cfctl token show --claims
+
+  # Copy the access token to the clipboard without printing it
+  $ cfctl token show --copy
+
+  # Print only the access token, e.g. for use in scripts
+  $ export CFCTL_TOKEN=$(cfctl token show --raw)`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		setting, err := configs.SetSettingFile()
+		if err != nil {
+			return fmt.Errorf("failed to load setting: %v", err)
+		}
+
+		currentEnv := setting.Environment
+		accessToken := setting.Environments[currentEnv].Token
+		if accessToken == "" {
+			return fmt.Errorf("no token found for environment '%s', please run 'cfctl login' first", currentEnv)
+		}
+
+		raw, _ := cmd.Flags().GetBool("raw")
+		if raw {
+			fmt.Println(accessToken)
+			return nil
+		}
+
+		copyToClipboard, _ := cmd.Flags().GetBool("copy")
+		if copyToClipboard {
+			if err := clipboard.WriteAll(accessToken); err != nil {
+				return fmt.Errorf("failed to copy token to clipboard: %v", err)
+			}
+			pterm.Success.Printf("The access token of '%s' has been copied to your clipboard.\n", currentEnv)
+			return nil
+		}
+
+		claims, err := token.Decode(accessToken)
+		showClaims, _ := cmd.Flags().GetBool("claims")
+		if showClaims {
+			if err != nil {
+				return fmt.Errorf("failed to decode token: %v", err)
+			}
+			data, _ := json.MarshalIndent(claims, "", "  ")
+			fmt.Println(string(data))
+			return nil
+		}
+
+		tableData := pterm.TableData{
+			{"Field", "Value"},
+			{"Environment", currentEnv},
+			{"Token", maskToken(accessToken)},
+		}
+		if err == nil {
+			tableData = append(tableData, tokenClaimRows(claims)...)
+		}
+
+		return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	},
+}
+
+var tokenRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Grant a new access token with the cached refresh token",
+	Long: `Grant a new access token for the scope and workspace of the current one, using the
+refresh token cached by 'cfctl login'. No password is needed while the refresh token is valid.
+Only user environments have a refresh token; app tokens are regenerated in the console.`,
+	Example: `  # Renew the access token before a long running job
+  $ cfctl token refresh`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		setting, err := configs.SetSettingFile()
+		if err != nil {
+			return fmt.Errorf("failed to load setting: %v", err)
+		}
+
+		currentEnv := setting.Environment
+		if !strings.HasSuffix(currentEnv, "-user") {
+			return fmt.Errorf("environment '%s' has no refresh token, only user environments can be refreshed", currentEnv)
+		}
+
+		envCacheDir, err := tokenCacheDir(currentEnv)
+		if err != nil {
+			return err
+		}
+		refreshToken, err := readTokenFromFile(envCacheDir, "refresh_token")
+		if err != nil || token.IsExpired(refreshToken) {
+			return fmt.Errorf("no valid refresh token found, please run 'cfctl login' first")
+		}
+
+		claims, err := token.Decode(setting.Environments[currentEnv].Token)
+		if err != nil || claims.Scope() == "" || claims.DomainID() == "" {
+			return fmt.Errorf("the current access token was not granted to a domain or workspace, please run 'cfctl login' to select one")
+		}
+
+		identityClient, err := identityClientFromSetting()
+		if err != nil {
+			return fmt.Errorf("failed to connect to the identity service: %v", err)
+		}
+		newAccessToken, err := grantToken(identityClient, refreshToken, claims.Scope(), claims.DomainID(), claims.WorkspaceID())
+		if err != nil {
+			return fmt.Errorf("failed to grant a new access token: %v", err)
+		}
+		if err := configs.StoreAccessToken(currentEnv, newAccessToken, currentWorkspaceName(currentEnv, claims)); err != nil {
+			return err
+		}
+
+		message := "Granted a new access token"
+		if newClaims, err := token.Decode(newAccessToken); err == nil {
+			if expiresAt, ok := newClaims.ExpiresAt(); ok {
+				message += fmt.Sprintf(", %s", token.Remaining(expiresAt, time.Now()))
+			}
+		}
+		pterm.Success.Printf("%s.\n", message)
+		return nil
+	},
+}
+
+var tokenForgetCmd = &cobra.Command{
+	Use:   "forget",
+	Short: "Remove the tokens of the current environment from this machine",
+	Long: `Remove the access and refresh tokens of the current environment from this machine, e.g.
+before handing over a shared machine. User environments drop their cached tokens and app
+environments the token in setting.yaml.
+
+The tokens are not invalidated: they stay valid on the server until they expire, as the API
+offers no way to revoke them. Delete the app of a leaked app token in the console instead.`,
+	Example: `  # Remove the cached tokens and log in again later
+  $ cfctl token forget`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, err := readSettingViper()
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %v", err)
+		}
+		currentEnv := v.GetString("environment")
+		if currentEnv == "" {
+			return fmt.Errorf("no environment selected")
+		}
+
+		if strings.HasSuffix(currentEnv, "-user") {
+			if err := clearCachedTokens(currentEnv, v.GetString(fmt.Sprintf("environments.%s.user_id", currentEnv))); err != nil {
+				return fmt.Errorf("failed to remove cached tokens: %v", err)
+			}
+			pterm.Success.Printf("Removed the cached tokens of '%s'. Run 'cfctl login' to sign in again.\n", currentEnv)
+			return nil
+		}
+
+		tokenKey := fmt.Sprintf("environments.%s.token", currentEnv)
+		if v.GetString(tokenKey) == "" {
+			pterm.Info.Printf("No token stored for environment '%s'.\n", currentEnv)
+			return nil
+		}
+
+		skipConfirm, _ := cmd.Flags().GetBool("yes")
+		if !skipConfirm {
+			if !configs.Interactive() {
+				return fmt.Errorf("no terminal to confirm the removal of the token of '%s', pass --yes to remove it", currentEnv)
+			}
+			confirmed, _ := ui.Confirm(fmt.Sprintf("Remove the token of '%s' from setting.yaml?", currentEnv), false)
+			if !confirmed {
+				pterm.Warning.Println("Cancelled.")
+				return nil
+			}
+		}
+
+		deleteSecrets(map[string]interface{}{"token": v.GetString(tokenKey)})
+		v.Set(tokenKey, "")
+		configs.MarkSettingDirty()
+		pterm.Success.Printf("Removed the token of '%s'. Set a new one with 'cfctl setting token'.\n", currentEnv)
+		return nil
+	},
+}
+
+// currentWorkspaceName returns the name of the workspace the token was granted for, as cached
+// with its token or selected with --workspace or CFCTL_WORKSPACE, so that refreshing it keeps
+// the workspace selectable by name
+func currentWorkspaceName(currentEnv string, claims token.Claims) string {
+	workspaceID := claims.WorkspaceID()
+	if name := configs.WorkspaceName(currentEnv, workspaceID); name != "" {
+		return name
+	}
+	if selected := configs.SelectedWorkspace(); selected != workspaceID {
+		return selected
+	}
+	return ""
+}
+
+// tokenClaimRows describes the claims of a token for the show table
+func tokenClaimRows(claims token.Claims) [][]string {
+	var rows [][]string
+	if tokenType := claims.String("ttp"); tokenType != "" {
+		rows = append(rows, []string{"Type", tokenType})
+	}
+	if userID := claims.UserID(); userID != "" {
+		rows = append(rows, []string{"User", userID})
+	}
+	if role := claims.Role(); role != "" {
+		rows = append(rows, []string{"Role", role})
+	}
+	if scope := claims.Scope(); scope != "" {
+		rows = append(rows, []string{"Scope", scope})
+	}
+	if domainID := claims.DomainID(); domainID != "" {
+		rows = append(rows, []string{"Domain ID", domainID})
+	}
+	if workspaceID := claims.WorkspaceID(); workspaceID != "" {
+		rows = append(rows, []string{"Workspace ID", workspaceID})
+	}
+	if issuedAt, ok := claims.Time("iat"); ok {
+		rows = append(rows, []string{"Issued At", issuedAt.Format("2006-01-02 15:04:05")})
+	}
+	if expiresAt, ok := claims.ExpiresAt(); ok {
+		remaining := token.Remaining(expiresAt, time.Now())
+		if claims.Expired() {
+			remaining = pterm.Red(remaining)
+		}
+		rows = append(rows, []string{"Expires At", fmt.Sprintf("%s (%s)", expiresAt.Format("2006-01-02 15:04:05"), remaining)})
+	}
+	return rows
+}
+
+// tokenCacheDir returns the directory caching the tokens of a user environment
+func tokenCacheDir(currentEnv string) (string, error) {
+	settingDir, err := configs.SettingDir()
+	if err != nil {
+		return "", err
+	}
+	envCacheDir := filepath.Join(settingDir, "cache", currentEnv)
+	if _, err := os.Stat(envCacheDir); err != nil {
+		return "", fmt.Errorf("no cached tokens for environment '%s', please run 'cfctl login' first", currentEnv)
+	}
+	return envCacheDir, nil
+}
+
+func init() {
+	TokenCmd.AddCommand(tokenShowCmd)
+	TokenCmd.AddCommand(tokenRefreshCmd)
+	TokenCmd.AddCommand(tokenForgetCmd)
+
+	tokenShowCmd.Flags().BoolP("copy", "c", false, "Copy the access token to the clipboard without printing it")
+	tokenShowCmd.Flags().Bool("raw", false, "Print only the access token")
+	tokenShowCmd.Flags().Bool("claims", false, "Print all claims of the access token as JSON")
+	tokenForgetCmd.Flags().BoolP("yes", "y", false, "Remove the token of app environments without confirmation")
+}
@@ -0,0 +1,267 @@
+package other
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// UserCmd represents the user command
+var UserCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage identity users",
+}
+
+var roleTypePattern = regexp.MustCompile(`^[A-Z]+(_[A-Z]+)+$`)
+
+// inviteRow is a user read from the invitation CSV file
+type inviteRow struct {
+	Line   int
+	UserID string
+	Name   string
+	Email  string
+	Role   string
+}
+
+var userInviteCmd = &cobra.Command{
+	Use:   "invite",
+	Short: "Create users and role bindings from a CSV file",
+	Long: `Create users in bulk and bind a role to each of them.
+
+The CSV file needs a header row with a 'user_id' column. The optional columns 'name',
+'email' and 'role' are used when present; 'role' overrides --role for that row.
+The role can be a role ID, a role name or a role type such as WORKSPACE_MEMBER.`,
+	Example: `  # users.csv
+  user_id,name,email
+  alice@example.com,Alice,alice@example.com
+  bob@example.com,Bob,bob@example.com
+
+  $ cfctl user invite -f users.csv --role WORKSPACE_MEMBER --workspace dev --dry-run
+  $ cfctl user invite -f users.csv --role WORKSPACE_MEMBER --workspace dev`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename, _ := cmd.Flags().GetString("filename")
+		defaultRole, _ := cmd.Flags().GetString("role")
+		workspace, _ := cmd.Flags().GetString("workspace")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		rows, err := readInviteRows(filename)
+		if err != nil {
+			pterm.Error.Printf("Failed to read %s: %v\n", filename, err)
+			return
+		}
+		if len(rows) == 0 {
+			pterm.Warning.Println("No users found in the file.")
+			return
+		}
+
+		var bindingParameters []string
+		if workspace != "" {
+			ws, err := resolveIdentityResource("Workspace", "workspace_id", workspace, nil)
+			if err != nil {
+				pterm.Error.Println(err)
+				return
+			}
+			bindingParameters = append(bindingParameters,
+				"resource_group=WORKSPACE",
+				fmt.Sprintf("workspace_id=%s", stringValue(ws["workspace_id"])))
+		} else {
+			bindingParameters = append(bindingParameters, "resource_group=DOMAIN")
+		}
+
+		// Resolve every role once before creating anything
+		roleIDs := make(map[string]string)
+		for _, row := range rows {
+			role := row.Role
+			if role == "" {
+				role = defaultRole
+			}
+			if role == "" || roleIDs[role] != "" {
+				continue
+			}
+			roleID, err := resolveRoleID(role)
+			if err != nil {
+				pterm.Error.Printf("Line %d: %v\n", row.Line, err)
+				return
+			}
+			roleIDs[role] = roleID
+		}
+
+		tableData := pterm.TableData{{"Line", "User ID", "Role", "User", "Role Binding"}}
+		failed := 0
+		for _, row := range rows {
+			role := row.Role
+			if role == "" {
+				role = defaultRole
+			}
+
+			userResult, bindingResult := "-", "-"
+			if dryRun {
+				userResult = "would create"
+				if role != "" {
+					bindingResult = fmt.Sprintf("would bind %s", roleIDs[role])
+				}
+				tableData = append(tableData, []string{fmt.Sprint(row.Line), row.UserID, role, userResult, bindingResult})
+				continue
+			}
+
+			userParameters := []string{
+				fmt.Sprintf("user_id=%s", row.UserID),
+				"auth_type=LOCAL",
+				"reset_password=true",
+			}
+			if row.Name != "" {
+				userParameters = append(userParameters, fmt.Sprintf("name=%s", row.Name))
+			}
+			if row.Email != "" {
+				userParameters = append(userParameters, fmt.Sprintf("email=%s", row.Email))
+			}
+
+			if _, err := transport.FetchService("identity", "create", "User", &transport.FetchOptions{
+				Parameters: userParameters,
+			}); errors.Is(err, transport.ErrNoToken) {
+				pterm.Error.Println(err)
+				cleanup.Exit(1)
+			} else if err != nil {
+				userResult = pterm.FgRed.Sprintf("failed: %v", err)
+				failed++
+				tableData = append(tableData, []string{fmt.Sprint(row.Line), row.UserID, role, userResult, bindingResult})
+				continue
+			}
+			userResult = pterm.FgGreen.Sprint("created")
+
+			if role != "" {
+				parameters := append([]string{
+					fmt.Sprintf("user_id=%s", row.UserID),
+					fmt.Sprintf("role_id=%s", roleIDs[role]),
+				}, bindingParameters...)
+				if _, err := transport.FetchService("identity", "create", "RoleBinding", &transport.FetchOptions{
+					Parameters: parameters,
+				}); err != nil {
+					bindingResult = pterm.FgRed.Sprintf("failed: %v", err)
+					failed++
+				} else {
+					bindingResult = pterm.FgGreen.Sprint("bound")
+				}
+			}
+
+			tableData = append(tableData, []string{fmt.Sprint(row.Line), row.UserID, role, userResult, bindingResult})
+		}
+
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+		if dryRun {
+			pterm.Info.Printf("Dry run: %d user(s) would be invited.\n", len(rows))
+			return
+		}
+		if failed > 0 {
+			pterm.Error.Printf("%d of %d row(s) failed.\n", failed, len(rows))
+			cleanup.Exit(1)
+		}
+		pterm.Success.Printf("Invited %d user(s).\n", len(rows))
+	},
+}
+
+// readInviteRows parses the invitation CSV file
+func readInviteRows(filename string) ([]inviteRow, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["user_id"]; !ok {
+		return nil, fmt.Errorf("missing 'user_id' column")
+	}
+
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []inviteRow
+	seen := make(map[string]int)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := inviteRow{
+			Line:   line,
+			UserID: column(record, "user_id"),
+			Name:   column(record, "name"),
+			Email:  column(record, "email"),
+			Role:   column(record, "role"),
+		}
+		if row.UserID == "" {
+			return nil, fmt.Errorf("line %d: empty user_id", line)
+		}
+		if previous, ok := seen[row.UserID]; ok {
+			return nil, fmt.Errorf("line %d: user '%s' is already listed on line %d", line, row.UserID, previous)
+		}
+		seen[row.UserID] = line
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// resolveRoleID resolves a role ID, role name or role type to a role ID
+func resolveRoleID(role string) (string, error) {
+	if roleTypePattern.MatchString(role) {
+		roles, err := listIdentityResources("Role", []string{fmt.Sprintf("role_type=%s", role)})
+		if err != nil {
+			return "", fmt.Errorf("failed to look up role type '%s': %v", role, err)
+		}
+		for _, r := range roles {
+			if managed, _ := r["is_managed"].(bool); managed {
+				return stringValue(r["role_id"]), nil
+			}
+		}
+		if len(roles) > 0 {
+			return stringValue(roles[0]["role_id"]), nil
+		}
+	}
+
+	r, err := resolveIdentityResource("Role", "role_id", role, nil)
+	if err != nil {
+		return "", err
+	}
+	return stringValue(r["role_id"]), nil
+}
+
+func init() {
+	UserCmd.AddCommand(userInviteCmd)
+
+	userInviteCmd.Flags().StringP("filename", "f", "", "CSV file with the users to invite")
+	userInviteCmd.Flags().String("role", "", "Role ID, name or type to bind (e.g. WORKSPACE_MEMBER)")
+	userInviteCmd.Flags().String("workspace", "", "Workspace ID or name (default: domain scope)")
+	userInviteCmd.Flags().Bool("dry-run", false, "Show what would be created without calling the API")
+	userInviteCmd.MarkFlagRequired("filename")
+}
@@ -0,0 +1,55 @@
+package other
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/cloudforet-io/cfctl/internal/features"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Version is set at build time with -ldflags "-X github.com/cloudforet-io/cfctl/cmd/other.Version=v1.0.0"
+var Version = "dev"
+
+// VersionCmd prints the version without reading the setting or dialing any service
+var VersionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version of cfctl",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("cfctl %s (%s, %s/%s)\n", BuildVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+		if showFeatures, _ := cmd.Flags().GetBool("features"); showFeatures {
+			printFeatures()
+		}
+	},
+}
+
+func init() {
+	VersionCmd.Flags().Bool("features", false, "List the optional features and whether this build includes them")
+}
+
+// BuildVersion returns Version, or the module version when installed with go install
+func BuildVersion() string {
+	if Version == "dev" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			return info.Main.Version
+		}
+	}
+	return Version
+}
+
+// printFeatures lists the optional features with the build tag leaving each out, to audit builds
+func printFeatures() {
+	tableData := pterm.TableData{{"Feature", "Status", "Build Tag", "Description"}}
+	for _, feature := range features.All() {
+		status := pterm.FgGreen.Sprint("included")
+		if !feature.Enabled {
+			status = pterm.FgYellow.Sprint("excluded")
+		}
+		tableData = append(tableData, []string{feature.Name, status, feature.Tag, feature.Description})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
@@ -0,0 +1,356 @@
+package other
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/apiclient"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/output"
+	"github.com/cloudforet-io/cfctl/pkg/token"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// WorkspaceCmd represents the workspace command
+var WorkspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage the workspace of the current environment",
+}
+
+var workspaceSwitchCmd = &cobra.Command{
+	Use:   "switch [name|id]",
+	Short: "Switch to another workspace without logging in again",
+	Long: `Grant an access token for another workspace with the refresh token cached by 'cfctl login'
+and store it as the token of the current environment. Without an argument the workspace is
+selected interactively. Only user environments have a refresh token.`,
+	Example: `  # Select the workspace from a list
+  $ cfctl workspace switch
+
+  # Switch by name or ID
+  $ cfctl workspace switch Production
+  $ cfctl workspace switch workspace-a1b2c3d4
+
+  # Switch to the domain scope (domain admins only)
+  $ cfctl workspace switch --domain`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		toDomain, _ := cmd.Flags().GetBool("domain")
+		if toDomain && len(args) > 0 {
+			pterm.Error.Println("--domain cannot be combined with a workspace.")
+			return
+		}
+
+		setting, err := configs.SetSettingFile()
+		if err != nil {
+			pterm.Error.Printf("Failed to load setting: %v\n", err)
+			return
+		}
+		currentEnv := setting.Environment
+		if !strings.HasSuffix(currentEnv, "-user") {
+			pterm.Error.Printf("Environment '%s' has no refresh token, only user environments can switch workspaces.\n", currentEnv)
+			return
+		}
+
+		identityClient, err := identityClientFromSetting()
+		if err != nil {
+			pterm.Error.Printf("Failed to connect to the identity service: %v\n", err)
+			return
+		}
+
+		accessToken, refreshToken, err := userListingTokens(identityClient, currentEnv)
+		if err != nil {
+			pterm.Error.Println(err)
+			pterm.Info.Println("Please run 'cfctl login' first.")
+			return
+		}
+
+		workspaces, domainID, roleType, err := fetchWorkspacesAndRole(identityClient, accessToken)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		scope, workspaceID, label := "WORKSPACE", "", ""
+		switch {
+		case toDomain:
+			if roleType != "DOMAIN_ADMIN" {
+				pterm.Error.Println("Only domain admins can switch to the domain scope.")
+				return
+			}
+			if configs.SelectedWorkspace() != "" {
+				pterm.Error.Printf("%s selects a workspace in this terminal, unset it to switch to the domain scope.\n", configs.EnvVarWorkspace)
+				return
+			}
+			scope, label = "DOMAIN", "the domain scope"
+		case len(args) == 1:
+			workspace, err := findWorkspace(workspaces, args[0])
+			if err != nil {
+				pterm.Error.Println(err)
+				return
+			}
+			workspaceID = workspace["workspace_id"].(string)
+		case !configs.Interactive():
+			pterm.Error.Println("No terminal to select a workspace, pass its name or ID, or --domain.")
+			return
+		default:
+			workspaceID = selectWorkspaceOnly(workspaces)
+		}
+		if workspaceID != "" {
+			label = fmt.Sprintf("workspace '%s'", workspaceLabel(workspaces, workspaceID))
+		}
+
+		newAccessToken, err := grantToken(identityClient, refreshToken, scope, domainID, workspaceID)
+		if err != nil {
+			pterm.Error.Printf("Failed to grant a token for %s: %v\n", label, err)
+			return
+		}
+
+		if err := configs.StoreAccessToken(currentEnv, newAccessToken, workspaceLabel(workspaces, workspaceID)); err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		pterm.Success.Printf("Switched to %s.\n", label)
+		if selected := configs.SelectedWorkspace(); selected != "" && selected != workspaceID && !strings.EqualFold(selected, workspaceLabel(workspaces, workspaceID)) {
+			pterm.Info.Printf("%s=%s still selects the token of this terminal, set it to %s to use the new one.\n", configs.EnvVarWorkspace, selected, workspaceID)
+		}
+	},
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the workspaces accessible to the current environment",
+	Long: `List the workspaces the user of the current environment can access. The workspace the
+current token is scoped to is marked as current.`,
+	Example: `  # List the accessible workspaces
+  $ cfctl workspace list
+
+  # Print only the workspace IDs, e.g. for scripts
+  $ cfctl workspace list -o yaml --query "[].workspace_id"`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{output.QueryAnnotation: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		spec, err := workspaceOutputSpec(cmd)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		setting, err := configs.SetSettingFile()
+		if err != nil {
+			pterm.Error.Printf("Failed to load setting: %v\n", err)
+			return
+		}
+		currentEnv := setting.Environment
+		currentToken := setting.Environments[currentEnv].Token
+		if currentToken == "" {
+			pterm.Error.Printf("No token found for environment '%s'.\n", currentEnv)
+			pterm.Info.Println("Please run 'cfctl login' first.")
+			return
+		}
+
+		identityClient, err := identityClientFromSetting()
+		if err != nil {
+			pterm.Error.Printf("Failed to connect to the identity service: %v\n", err)
+			return
+		}
+
+		listingToken := currentToken
+		if strings.HasSuffix(currentEnv, "-user") {
+			if listingToken, _, err = userListingTokens(identityClient, currentEnv); err != nil {
+				pterm.Error.Println(err)
+				pterm.Info.Println("Please run 'cfctl login' first.")
+				return
+			}
+		}
+
+		workspaces, err := fetchWorkspaces(identityClient, listingToken)
+		if err != nil {
+			pterm.Error.Printf("Failed to fetch workspaces: %v\n", err)
+			return
+		}
+
+		var currentWorkspaceID string
+		if claims, err := token.Decode(currentToken); err == nil {
+			currentWorkspaceID = claims.WorkspaceID()
+		}
+
+		items := make([]interface{}, 0, len(workspaces))
+		for _, workspace := range workspaces {
+			id, _ := workspace["workspace_id"].(string)
+			workspace["current"] = id != "" && id == currentWorkspaceID
+			items = append(items, workspace)
+		}
+
+		if len(spec.Columns) == 0 && spec.Query == "" && (spec.Format == output.Table || spec.Format == output.CSV) {
+			spec.Columns = []string{"workspace_id", "name", "state", "current"}
+		}
+		if err := output.Print(items, spec); err != nil {
+			pterm.Error.Printf("Failed to format the workspaces: %v\n", err)
+		}
+	},
+}
+
+var workspaceCurrentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Show the scope and workspace of the current token",
+	Example: `  # Show the current scope and workspace
+  $ cfctl workspace current
+
+  # Print only the workspace ID
+  $ cfctl workspace current -o yaml --query workspace_id`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{output.QueryAnnotation: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		spec, err := workspaceOutputSpec(cmd)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+
+		setting, err := configs.SetSettingFile()
+		if err != nil {
+			pterm.Error.Printf("Failed to load setting: %v\n", err)
+			return
+		}
+		currentEnv := setting.Environment
+		currentToken := setting.Environments[currentEnv].Token
+		if currentToken == "" {
+			pterm.Error.Printf("No token found for environment '%s'.\n", currentEnv)
+			pterm.Info.Println("Please run 'cfctl login' first.")
+			return
+		}
+
+		claims, err := token.Decode(currentToken)
+		if err != nil {
+			pterm.Error.Printf("Failed to decode token: %v\n", err)
+			return
+		}
+
+		current := map[string]interface{}{
+			"environment":  currentEnv,
+			"scope":        claims.Scope(),
+			"domain_id":    claims.DomainID(),
+			"workspace_id": claims.WorkspaceID(),
+			"role":         claims.Role(),
+			"expired":      claims.Expired(),
+		}
+
+		// The name needs the identity service, the ID alone is shown when it cannot be reached
+		if workspaceID := claims.WorkspaceID(); workspaceID != "" && !claims.Expired() {
+			if identityClient, err := identityClientFromSetting(); err == nil {
+				if workspaces, err := fetchWorkspaces(identityClient, currentToken); err == nil {
+					current["workspace_name"] = workspaceLabel(workspaces, workspaceID)
+				}
+			}
+		}
+
+		if len(spec.Columns) == 0 && spec.Query == "" && spec.Format == output.Table {
+			spec.Columns = []string{"environment", "scope", "domain_id", "workspace_id", "workspace_name", "role", "expired"}
+		}
+		if err := output.Print(current, spec); err != nil {
+			pterm.Error.Printf("Failed to format the workspace: %v\n", err)
+		}
+	},
+}
+
+// userListingTokens returns the access and refresh tokens of a user environment. An expired
+// access token is replaced by a user scoped one, which is enough to list workspaces.
+func userListingTokens(identityClient apiclient.Client, currentEnv string) (string, string, error) {
+	accessToken, refreshToken, err := getValidTokens(currentEnv)
+	if err != nil {
+		return "", "", fmt.Errorf("no valid refresh token found")
+	}
+	if !token.IsExpired(accessToken) {
+		return accessToken, refreshToken, nil
+	}
+
+	claims, err := token.Decode(refreshToken)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode refresh token: %v", err)
+	}
+	accessToken, err = grantToken(identityClient, refreshToken, "USER", claims.DomainID(), "")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to grant a token: %v", err)
+	}
+	return accessToken, refreshToken, nil
+}
+
+// workspaceOutputSpec parses the -o and --query flags of the workspace commands
+func workspaceOutputSpec(cmd *cobra.Command) (output.Spec, error) {
+	outputFormat, _ := cmd.Flags().GetString("output")
+	spec, err := output.Parse(outputFormat)
+	if err == nil {
+		err = spec.Validate()
+	}
+	if err == nil {
+		spec.Query, _ = cmd.Flags().GetString("query")
+		err = output.ValidateQuery(spec.Query)
+	}
+	return spec, err
+}
+
+// printQueried prints data filtered by --query as a table, for the commands rendering their
+// own table otherwise. It reports whether a query was given.
+func printQueried(cmd *cobra.Command, data interface{}) (bool, error) {
+	query, _ := cmd.Flags().GetString("query")
+	if query == "" {
+		return false, nil
+	}
+	return true, output.Print(data, output.Spec{Format: output.Table, Query: query})
+}
+
+// findWorkspace returns the workspace with the ID, or else the one with the name.
+// Names are compared case-insensitively and must be unique.
+func findWorkspace(workspaces []map[string]interface{}, nameOrID string) (map[string]interface{}, error) {
+	for _, workspace := range workspaces {
+		if id, _ := workspace["workspace_id"].(string); id == nameOrID {
+			return workspace, nil
+		}
+	}
+
+	var matches []map[string]interface{}
+	for _, workspace := range workspaces {
+		if name, _ := workspace["name"].(string); strings.EqualFold(name, nameOrID) {
+			matches = append(matches, workspace)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no accessible workspace named '%s'", nameOrID)
+	case 1:
+		return matches[0], nil
+	}
+
+	ids := make([]string, 0, len(matches))
+	for _, workspace := range matches {
+		id, _ := workspace["workspace_id"].(string)
+		ids = append(ids, id)
+	}
+	return nil, fmt.Errorf("several workspaces are named '%s', use one of the IDs: %s", nameOrID, strings.Join(ids, ", "))
+}
+
+// workspaceLabel returns the name of the workspace, or its ID when it is not in the list
+func workspaceLabel(workspaces []map[string]interface{}, workspaceID string) string {
+	for _, workspace := range workspaces {
+		if id, _ := workspace["workspace_id"].(string); id == workspaceID {
+			if name, _ := workspace["name"].(string); name != "" {
+				return name
+			}
+		}
+	}
+	return workspaceID
+}
+
+func init() {
+	WorkspaceCmd.AddCommand(workspaceListCmd)
+	WorkspaceCmd.AddCommand(workspaceCurrentCmd)
+	WorkspaceCmd.AddCommand(workspaceSwitchCmd)
+
+	workspaceListCmd.Flags().StringP("output", "o", "table", "Output format (table, json, yaml, csv); table=col1,col2 selects columns")
+	workspaceCurrentCmd.Flags().StringP("output", "o", "table", "Output format (table, json, yaml, csv)")
+
+	workspaceSwitchCmd.Flags().Bool("domain", false, "Switch to the domain scope instead of a workspace (domain admins only)")
+	workspaceSwitchCmd.ValidArgsFunction = completeWorkspaces
+}
@@ -0,0 +1,416 @@
+package other
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/transport"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var workspaceBootstrapCmd = &cobra.Command{
+	Use:   "bootstrap",
+	Short: "Create a workspace with its projects, role bindings and service accounts",
+	Long: `Onboard a team in one step from a template: the workspace, its project groups and projects,
+the role bindings of its members and the service accounts of its cloud accounts.
+
+Resources that already exist with the same name are kept, so the template can be applied again
+after a failure or to add entries. Roles can be given by ID, name or type like WORKSPACE_OWNER,
+and secret_data_file paths are relative to the template.`,
+	Example: `  # team.yaml
+  workspace:
+    name: team-a
+    tags:
+      cost_center: "1234"
+  project_groups:
+    - name: Platform
+      projects:
+        - name: platform-dev
+        - name: platform-prod
+  projects:
+    - name: sandbox
+      project_type: PRIVATE
+  role_bindings:
+    - user_id: alice@example.com
+      role: WORKSPACE_OWNER
+    - user_id: bob@example.com
+      role: WORKSPACE_MEMBER
+  service_accounts:
+    - name: aws-platform-dev
+      provider: aws
+      project: platform-dev
+      data:
+        account_id: "123456789012"
+      secret_schema_id: aws-secret-access-key
+      secret_data_file: aws-platform-dev.json
+
+  $ cfctl workspace bootstrap --template team.yaml --dry-run
+  $ cfctl workspace bootstrap --template team.yaml
+  $ cfctl workspace bootstrap --template team.yaml --name team-b`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		templatePath, _ := cmd.Flags().GetString("template")
+		name, _ := cmd.Flags().GetString("name")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		template, err := readBootstrapTemplate(templatePath)
+		if err != nil {
+			pterm.Error.Printf("Failed to read %s: %v\n", templatePath, err)
+			return
+		}
+		if name != "" {
+			template.Workspace.Name = name
+		}
+		if err := template.validate(); err != nil {
+			pterm.Error.Printf("Invalid template %s: %v\n", templatePath, err)
+			return
+		}
+
+		// Resolve every role and read every secret before creating anything
+		roleIDs := make(map[string]string)
+		for _, binding := range template.RoleBindings {
+			if roleIDs[binding.Role] != "" {
+				continue
+			}
+			roleID, err := resolveRoleID(binding.Role)
+			if err != nil {
+				pterm.Error.Println(err)
+				return
+			}
+			roleIDs[binding.Role] = roleID
+		}
+		secrets := make(map[string]map[string]interface{})
+		for _, account := range template.ServiceAccounts {
+			if account.SecretDataFile == "" {
+				continue
+			}
+			path := account.SecretDataFile
+			if path != "-" && !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(templatePath), path)
+			}
+			data, err := readSecretData(path)
+			if err != nil {
+				pterm.Error.Printf("Failed to read secret data of service account '%s': %v\n", account.Name, err)
+				return
+			}
+			secrets[account.Name] = data
+		}
+
+		b := &bootstrapper{
+			dryRun:    dryRun,
+			tableData: pterm.TableData{{"Kind", "Name", "ID", "Result"}},
+			projects:  make(map[string]string),
+		}
+		b.run(template, roleIDs, secrets)
+		pterm.DefaultTable.WithHasHeader().WithData(b.tableData).Render()
+
+		switch {
+		case dryRun:
+			pterm.Info.Printf("Dry run: nothing was created in workspace '%s'.\n", template.Workspace.Name)
+		case b.failed > 0:
+			pterm.Error.Printf("%d step(s) failed, fix them and run the command again.\n", b.failed)
+			cleanup.Exit(1)
+		default:
+			pterm.Success.Printf("Bootstrapped workspace '%s' (%s).\n", template.Workspace.Name, b.workspaceID)
+			pterm.Info.Printf("Switch to it with 'cfctl workspace switch %s'.\n", b.workspaceID)
+		}
+	},
+}
+
+// bootstrapTemplate describes the resources of a workspace created by 'cfctl workspace bootstrap'
+type bootstrapTemplate struct {
+	Workspace struct {
+		Name string            `yaml:"name"`
+		Tags map[string]string `yaml:"tags"`
+	} `yaml:"workspace"`
+	ProjectGroups   []bootstrapProjectGroup   `yaml:"project_groups"`
+	Projects        []bootstrapProject        `yaml:"projects"`
+	RoleBindings    []bootstrapRoleBinding    `yaml:"role_bindings"`
+	ServiceAccounts []bootstrapServiceAccount `yaml:"service_accounts"`
+}
+
+type bootstrapProjectGroup struct {
+	Name          string                  `yaml:"name"`
+	ProjectGroups []bootstrapProjectGroup `yaml:"project_groups"`
+	Projects      []bootstrapProject      `yaml:"projects"`
+}
+
+type bootstrapProject struct {
+	Name        string `yaml:"name"`
+	ProjectType string `yaml:"project_type"`
+}
+
+type bootstrapRoleBinding struct {
+	UserID string `yaml:"user_id"`
+	Role   string `yaml:"role"`
+}
+
+type bootstrapServiceAccount struct {
+	Name           string                 `yaml:"name"`
+	Provider       string                 `yaml:"provider"`
+	Project        string                 `yaml:"project"`
+	Data           map[string]interface{} `yaml:"data"`
+	Tags           map[string]string      `yaml:"tags"`
+	SecretSchemaID string                 `yaml:"secret_schema_id"`
+	SecretDataFile string                 `yaml:"secret_data_file"`
+}
+
+// readBootstrapTemplate reads the template, rejecting unknown keys as they are usually typos
+func readBootstrapTemplate(path string) (*bootstrapTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var template bootstrapTemplate
+	if err := decoder.Decode(&template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// validate checks the template before anything is created. Project names must be unique as
+// service accounts refer to their project by name.
+func (t *bootstrapTemplate) validate() error {
+	if t.Workspace.Name == "" {
+		return fmt.Errorf("workspace.name is required")
+	}
+
+	projects := map[string]bool{}
+	var checkProjects func(groups []bootstrapProjectGroup, list []bootstrapProject) error
+	checkProjects = func(groups []bootstrapProjectGroup, list []bootstrapProject) error {
+		for _, project := range list {
+			if project.Name == "" {
+				return fmt.Errorf("a project has no name")
+			}
+			if projects[project.Name] {
+				return fmt.Errorf("project '%s' is defined more than once", project.Name)
+			}
+			projects[project.Name] = true
+		}
+		for _, group := range groups {
+			if group.Name == "" {
+				return fmt.Errorf("a project group has no name")
+			}
+			if err := checkProjects(group.ProjectGroups, group.Projects); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := checkProjects(t.ProjectGroups, t.Projects); err != nil {
+		return err
+	}
+
+	for i, binding := range t.RoleBindings {
+		if binding.UserID == "" || binding.Role == "" {
+			return fmt.Errorf("role_bindings[%d] needs a user_id and a role", i)
+		}
+	}
+	for i, account := range t.ServiceAccounts {
+		if account.Name == "" || account.Provider == "" || account.Project == "" {
+			return fmt.Errorf("service_accounts[%d] needs a name, a provider and a project", i)
+		}
+		if !projects[account.Project] {
+			return fmt.Errorf("service account '%s' refers to project '%s', which is not in the template", account.Name, account.Project)
+		}
+		if account.SecretDataFile != "" && account.SecretSchemaID == "" {
+			return fmt.Errorf("service account '%s' has a secret_data_file but no secret_schema_id", account.Name)
+		}
+	}
+	return nil
+}
+
+// bootstrapper creates the resources of a template in order, recording a row per resource
+type bootstrapper struct {
+	dryRun      bool
+	workspaceID string
+	projects    map[string]string
+	tableData   pterm.TableData
+	failed      int
+}
+
+func (b *bootstrapper) run(t *bootstrapTemplate, roleIDs map[string]string, secrets map[string]map[string]interface{}) {
+	workspace := map[string]interface{}{"name": t.Workspace.Name}
+	if len(t.Workspace.Tags) > 0 {
+		workspace["tags"] = t.Workspace.Tags
+	}
+	workspaceID, ok := b.ensure("Workspace", "workspace_id", t.Workspace.Name, []string{fmt.Sprintf("name=%s", t.Workspace.Name)}, workspace, true)
+	if !ok {
+		b.skipAll(t)
+		return
+	}
+	b.workspaceID = workspaceID
+
+	b.createProjects(t.ProjectGroups, t.Projects, "", b.workspaceID != "")
+
+	for _, binding := range t.RoleBindings {
+		label := fmt.Sprintf("%s (%s)", binding.UserID, binding.Role)
+		lookup := []string{
+			fmt.Sprintf("user_id=%s", binding.UserID),
+			fmt.Sprintf("role_id=%s", roleIDs[binding.Role]),
+		}
+		b.ensure("RoleBinding", "role_binding_id", label, lookup, map[string]interface{}{
+			"user_id":        binding.UserID,
+			"role_id":        roleIDs[binding.Role],
+			"resource_group": "WORKSPACE",
+		}, b.workspaceID != "")
+	}
+
+	for _, account := range t.ServiceAccounts {
+		projectID, created := b.projects[account.Project]
+		if !created {
+			b.record("ServiceAccount", account.Name, "", pterm.FgYellow.Sprintf("skipped: project '%s' failed", account.Project))
+			continue
+		}
+		payload := map[string]interface{}{
+			"name":       account.Name,
+			"provider":   account.Provider,
+			"project_id": projectID,
+			"data":       account.Data,
+		}
+		if payload["data"] == nil {
+			payload["data"] = map[string]interface{}{}
+		}
+		if len(account.Tags) > 0 {
+			payload["tags"] = account.Tags
+		}
+		if data, ok := secrets[account.Name]; ok {
+			payload["secret_schema_id"] = account.SecretSchemaID
+			payload["secret_data"] = data
+		}
+		lookup := []string{
+			fmt.Sprintf("name=%s", account.Name),
+			fmt.Sprintf("provider=%s", account.Provider),
+		}
+		b.ensure("ServiceAccount", "service_account_id", account.Name, lookup, payload, projectID != "")
+	}
+}
+
+// createProjects creates the project groups below parentGroupID and the projects in them.
+// Existing ones are only looked up when the parent exists, which is not the case in a dry run
+// when the parent would be created.
+func (b *bootstrapper) createProjects(groups []bootstrapProjectGroup, projects []bootstrapProject, parentGroupID string, lookUp bool) {
+	for _, group := range groups {
+		payload := map[string]interface{}{"name": group.Name}
+		lookup := []string{fmt.Sprintf("name=%s", group.Name)}
+		if parentGroupID != "" {
+			payload["parent_group_id"] = parentGroupID
+			lookup = append(lookup, fmt.Sprintf("parent_group_id=%s", parentGroupID))
+		}
+		groupID, ok := b.ensure("ProjectGroup", "project_group_id", group.Name, lookup, payload, lookUp)
+		if !ok {
+			b.skipProjects(group)
+			continue
+		}
+		b.createProjects(group.ProjectGroups, group.Projects, groupID, lookUp && groupID != "")
+	}
+
+	for _, project := range projects {
+		projectType := project.ProjectType
+		if projectType == "" {
+			projectType = "PUBLIC"
+		}
+		payload := map[string]interface{}{"name": project.Name, "project_type": projectType}
+		lookup := []string{fmt.Sprintf("name=%s", project.Name)}
+		if parentGroupID != "" {
+			payload["project_group_id"] = parentGroupID
+			lookup = append(lookup, fmt.Sprintf("project_group_id=%s", parentGroupID))
+		}
+		if id, ok := b.ensure("Project", "project_id", project.Name, lookup, payload, lookUp); ok {
+			b.projects[project.Name] = id
+		}
+	}
+}
+
+// ensure returns the ID of the resource with the name, creating it unless it exists. In a dry
+// run nothing is created and the ID of a resource that would be created is empty.
+func (b *bootstrapper) ensure(resource, idField, label string, lookup []string, payload map[string]interface{}, lookUp bool) (string, bool) {
+	if b.workspaceID != "" {
+		lookup = append(lookup, fmt.Sprintf("workspace_id=%s", b.workspaceID))
+		payload["workspace_id"] = b.workspaceID
+	}
+
+	if lookUp {
+		existing, err := fetchResults("identity", resource, lookup)
+		if err != nil {
+			b.record(resource, label, "", pterm.FgRed.Sprintf("failed to look up: %v", err))
+			b.failed++
+			return "", false
+		}
+		if len(existing) > 0 {
+			id := stringValue(existing[0][idField])
+			b.record(resource, label, id, "exists")
+			return id, true
+		}
+	}
+
+	if b.dryRun {
+		b.record(resource, label, "", "would create")
+		return "", true
+	}
+
+	jsonParameter, err := json.Marshal(payload)
+	if err != nil {
+		b.record(resource, label, "", pterm.FgRed.Sprintf("failed: %v", err))
+		b.failed++
+		return "", false
+	}
+	resp, err := transport.FetchService("identity", "create", resource, &transport.FetchOptions{
+		JSONParameter: string(jsonParameter),
+	})
+	if err != nil {
+		b.record(resource, label, "", pterm.FgRed.Sprintf("failed: %v", firstLine(err.Error())))
+		b.failed++
+		return "", false
+	}
+	id := stringValue(resp[idField])
+	b.record(resource, label, id, pterm.FgGreen.Sprint("created"))
+	return id, true
+}
+
+func (b *bootstrapper) record(resource, label, id, result string) {
+	if id == "" {
+		id = "-"
+	}
+	b.tableData = append(b.tableData, []string{resource, label, id, result})
+}
+
+// skipAll records every resource of the template as skipped after the workspace failed
+func (b *bootstrapper) skipAll(t *bootstrapTemplate) {
+	b.skipProjects(bootstrapProjectGroup{ProjectGroups: t.ProjectGroups, Projects: t.Projects})
+	for _, binding := range t.RoleBindings {
+		b.record("RoleBinding", fmt.Sprintf("%s (%s)", binding.UserID, binding.Role), "", pterm.FgYellow.Sprint("skipped"))
+	}
+	for _, account := range t.ServiceAccounts {
+		b.record("ServiceAccount", account.Name, "", pterm.FgYellow.Sprint("skipped"))
+	}
+}
+
+// skipProjects records the projects and groups below a failed group as skipped
+func (b *bootstrapper) skipProjects(group bootstrapProjectGroup) {
+	for _, project := range group.Projects {
+		b.record("Project", project.Name, "", pterm.FgYellow.Sprint("skipped"))
+	}
+	for _, child := range group.ProjectGroups {
+		b.record("ProjectGroup", child.Name, "", pterm.FgYellow.Sprint("skipped"))
+		b.skipProjects(child)
+	}
+}
+
+func init() {
+	WorkspaceCmd.AddCommand(workspaceBootstrapCmd)
+
+	workspaceBootstrapCmd.Flags().String("template", "", "YAML template describing the workspace")
+	workspaceBootstrapCmd.Flags().String("name", "", "Name of the workspace, overriding workspace.name of the template")
+	workspaceBootstrapCmd.Flags().Bool("dry-run", false, "Show what would be created without calling the create APIs")
+	workspaceBootstrapCmd.MarkFlagRequired("template")
+}
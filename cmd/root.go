@@ -3,14 +3,25 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/cloudforet-io/cfctl/cmd/common"
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/features"
+	"github.com/cloudforet-io/cfctl/internal/grpcconn"
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"github.com/cloudforet-io/cfctl/internal/notices"
+	"github.com/cloudforet-io/cfctl/internal/telemetry"
+	"github.com/cloudforet-io/cfctl/internal/timing"
+	"github.com/cloudforet-io/cfctl/internal/tlsconf"
+	"github.com/cloudforet-io/cfctl/internal/ui"
 	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/format"
+	"github.com/cloudforet-io/cfctl/pkg/output"
 	"github.com/cloudforet-io/cfctl/pkg/transport"
 	"github.com/jhump/protoreflect/grpcreflect"
 	"google.golang.org/grpc"
@@ -27,6 +38,24 @@ import (
 
 var cachedEndpointsMap map[string]string
 
+// lightweightCommands run without loading cached endpoints or registering the service
+// commands, so that they start fast enough for shell prompts and print nothing but their output
+var lightweightCommands = []string{"version", "prompt", "completion", "env", "generate", "notices", "dev"}
+
+// isLightweightCommand reports whether the invoked command is one of lightweightCommands
+func isLightweightCommand() bool {
+	return len(os.Args) > 1 && slices.Contains(lightweightCommands, os.Args[1])
+}
+
+// showTimings prints where the time of the command went after it ran
+var showTimings bool
+
+// noColor disables colors and makes the interactive selectors line based
+var noColor bool
+
+// strictPermissions refuses to run instead of fixing too open setting and cache files
+var strictPermissions bool
+
 // Config represents the configuration structure
 type Config struct {
 	Environment string
@@ -46,36 +75,265 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		executedCmd = cmd
+		if showTimings {
+			timing.Enable()
+		}
+		checkSettingPermissions()
+		applyCommandDefaults(cmd)
+		if noColor || os.Getenv("NO_COLOR") != "" {
+			pterm.DisableColor()
+		}
+		ui.SetPlain(noColor)
+		checkDeprecations(cmd)
+		checkQuerySupport(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if len(os.Args) == 2 {
+	// Aborted commands remove their partial files and release their locks
+	cleanup.Catch()
+	defer func() {
+		if r := recover(); r != nil {
+			cleanup.Run()
+			panic(r)
+		}
+	}()
+
+	if len(os.Args) == 2 && !isLightweightCommand() {
 		alias := os.Args[1]
 		if cmd := getAliasCommand(alias); cmd != "" {
 			os.Args = append([]string{os.Args[0]}, strings.Fields(cmd)...)
 		}
 	}
 
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	// Commands exiting early with cleanup.Exit are finished too
+	cleanup.AtExit(finishCommand)
+
+	executed, err := rootCmd.ExecuteC()
+	if executed != nil {
+		executedCmd = executed
+	}
+	code := 0
+	if err != nil {
+		code = 1
+	}
+	cleanup.Exit(code)
+}
+
+// executedCmd is the command being run, once its flags are parsed
+var executedCmd *cobra.Command
+
+// finishCommand saves the pending setting changes, closes the connections and reports the
+// command, when it returns or exits early. It returns the exit code, 1 when saving failed.
+func finishCommand(code int) int {
+	if err := configs.FlushSetting(); err != nil {
+		pterm.Error.Println(err)
+		code = 1
 	}
+	grpcconn.CloseAll()
+	timing.Report(os.Stderr)
+	recordTelemetry(executedCmd, code == 0)
+	return code
 }
 
-func getAliasCommand(alias string) string {
-	v := viper.New()
-	home, _ := os.UserHomeDir()
-	v.SetConfigFile(filepath.Join(home, ".cfctl", "setting.yaml"))
+// recordTelemetry queues the anonymous event of the command when telemetry is turned on. Commands
+// run by shell prompts and completion are left out, as they run far more often than the user does.
+func recordTelemetry(executed *cobra.Command, success bool) {
+	if executed == nil || isLightweightCommand() || (len(os.Args) > 1 && os.Args[1] == "__complete") {
+		return
+	}
+	v, err := configs.Setting()
+	if err != nil {
+		return
+	}
+	telemetry.Record(v, telemetry.NewEvent(executed.CommandPath(), other.BuildVersion(), time.Since(telemetry.Start), success))
+}
+
+// checkSettingPermissions restricts setting directory entries readable by group or others.
+// With --strict, it refuses to run and leaves the files untouched instead.
+func checkSettingPermissions() {
+	if isLightweightCommand() || (len(os.Args) > 1 && os.Args[1] == "__complete") {
+		return
+	}
+
+	settingDir, err := configs.SettingDir()
+	if err != nil {
+		return
+	}
+
+	insecure, err := configs.FindInsecurePaths(settingDir)
+	if err != nil || len(insecure) == 0 {
+		return
+	}
+
+	if strictPermissions {
+		pterm.Error.WithWriter(os.Stderr).Println("Refusing to run because setting files are accessible by other users:")
+		for _, p := range insecure {
+			fmt.Fprintf(os.Stderr, "  %s (mode %04o, expected %04o)\n", p.Path, p.Mode, p.Expected)
+		}
+		pterm.Info.WithWriter(os.Stderr).Println("Run 'cfctl setting lint --fix' or fix the permissions manually.")
+		cleanup.Exit(1)
+	}
+
+	if err := configs.FixInsecurePaths(insecure); err != nil {
+		pterm.Warning.WithWriter(os.Stderr).Printf("Setting files are accessible by other users: %v\n", err)
+		return
+	}
+	pterm.Warning.WithWriter(os.Stderr).Printf("Restricted permissions of %d setting file(s) that were accessible by other users.\n", len(insecure))
+}
 
-	if err := v.ReadInConfig(); err != nil {
+func getAliasCommand(alias string) string {
+	v, err := configs.Setting()
+	if err != nil {
 		return ""
 	}
 
 	return v.GetString(fmt.Sprintf("aliases.%s", alias))
 }
 
+// applyCommandDefaults sets the flags given under command_defaults in setting.yaml that were not
+// given on the command line. Defaults are keyed by the command without "cfctl", like "exec" or
+// "inventory list", or "*" for every command, and those of the current environment under
+// environments.<env>.command_defaults take precedence:
+//
+//	command_defaults:
+//	  "*":
+//	    timeout: 30s
+//	  exec:
+//	    output: json
+func applyCommandDefaults(cmd *cobra.Command) {
+	if isLightweightCommand() {
+		return
+	}
+	v, err := configs.Setting()
+	if err != nil {
+		return
+	}
+
+	path := strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()))
+	defaults := make(map[string]interface{})
+	sources := []string{"command_defaults"}
+	if env := v.GetString("environment"); env != "" {
+		sources = append(sources, fmt.Sprintf("environments.%s.command_defaults", env))
+	}
+	for _, source := range sources {
+		commands := v.GetStringMap(source)
+		for _, key := range []string{"*", path} {
+			flags, _ := commands[key].(map[string]interface{})
+			for name, value := range flags {
+				if key != "*" && cmd.Flags().Lookup(name) == nil {
+					pterm.Warning.WithWriter(os.Stderr).Printf("Ignoring %s of '%s': the command has no --%s flag.\n", source, key, name)
+					continue
+				}
+				defaults[name] = value
+			}
+		}
+	}
+
+	for name, value := range defaults {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		for _, item := range values {
+			if err := cmd.Flags().Set(name, fmt.Sprint(item)); err != nil {
+				pterm.Warning.WithWriter(os.Stderr).Printf("Ignoring the default of --%s: %v\n", name, err)
+				break
+			}
+		}
+	}
+}
+
+// applyConfigFlag points the setting directory to the value of --config before anything reads
+// it, and removes the flag so that the subcommand is still found at os.Args[1]. The flag stays
+// registered on the root command for the help and completion.
+func applyConfigFlag() {
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		if arg == "--" {
+			return
+		}
+		if value, ok := strings.CutPrefix(arg, "--config="); ok {
+			configs.SetSettingDir(value)
+			os.Args = append(os.Args[:i], os.Args[i+1:]...)
+			return
+		}
+		if arg == "--config" && i+1 < len(os.Args) {
+			configs.SetSettingDir(os.Args[i+1])
+			os.Args = append(os.Args[:i], os.Args[i+2:]...)
+			return
+		}
+	}
+}
+
+// applyDeprecatedCommands runs 'cfctl config', which was merged into 'cfctl setting', as the
+// latter with a notice
+func applyDeprecatedCommands() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		notices.Emit("command.config")
+		os.Args[1] = "setting"
+	}
+}
+
+// checkDeprecations emits the notices of the deprecated flags and setting keys in use
+func checkDeprecations(cmd *cobra.Command) {
+	if isLightweightCommand() || (len(os.Args) > 1 && os.Args[1] == "__complete") {
+		return
+	}
+	notices.CheckFlags(cmd)
+	if v, err := configs.Setting(); err == nil {
+		notices.CheckSetting(v.AllKeys())
+	}
+}
+
+// checkQuerySupport refuses --query on the commands rendering their result without it, which
+// would otherwise print the unfiltered result as if the query had matched everything
+func checkQuerySupport(cmd *cobra.Command) {
+	if !cmd.Flags().Changed("query") || cmd.Annotations[output.QueryAnnotation] != "" {
+		return
+	}
+	pterm.Error.Printf("'%s' does not support --query.\n", cmd.CommandPath())
+	cleanup.Exit(1)
+}
+
+// migrateSetting upgrades setting.yaml written by older versions before anything reads it
+func migrateSetting() {
+	if len(os.Args) > 1 && os.Args[1] == "__complete" {
+		return
+	}
+	result, err := configs.MigrateSetting()
+	if err != nil {
+		pterm.Warning.WithWriter(os.Stderr).Printf("Failed to migrate setting.yaml: %v\n", err)
+		return
+	}
+	if result == nil {
+		return
+	}
+	pterm.Info.WithWriter(os.Stderr).Printf("Migrated setting.yaml from version %d to %d.\n", result.From, result.To)
+	for _, change := range result.Changes {
+		pterm.Info.WithWriter(os.Stderr).Printf("  %s\n", change)
+	}
+	if result.Backup != "" {
+		pterm.Info.WithWriter(os.Stderr).Printf("The previous setting was kept as %s.\n", result.Backup)
+	}
+}
+
 func init() {
+	applyConfigFlag()
+	applyDeprecatedCommands()
+	configs.SetSecretResolver(other.ResolveCredential)
+	configs.SetSecretStorer(other.StoreCredential, other.DeleteCredential)
+	netproxy.SetSource(configs.NetworkProxy)
+	tlsconf.SetSource(configs.TLSSettings)
+
 	// Initialize available commands group
 	AvailableCommands := &cobra.Group{
 		ID:    "available",
@@ -83,6 +341,22 @@ func init() {
 	}
 	rootCmd.AddGroup(AvailableCommands)
 
+	rootCmd.PersistentFlags().BoolVar(&strictPermissions, "strict", false, "Refuse to run when setting or cache files are accessible by other users")
+	rootCmd.PersistentFlags().BoolVar(&showTimings, "timings", false, "Print the time spent in config load, dial, reflection, RPC and render after the command")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colors and select from numbered lists instead of with the keyboard (NO_COLOR only disables colors)")
+	rootCmd.PersistentFlags().String("config", "", "Directory of setting.yaml and the caches (default ~/.cfctl, or $CFCTL_CONFIG_DIR)")
+	rootCmd.PersistentFlags().StringP("query", "q", "", "JMESPath expression applied to the response before rendering (e.g. 'results[].name')")
+
+	if isLightweightCommand() {
+		if os.Args[1] == "completion" {
+			pterm.DisableColor()
+		}
+		addStaticCommands()
+		return
+	}
+
+	migrateSetting()
+
 	done := make(chan bool)
 	go func() {
 		if endpoints, err := loadCachedEndpoints(); err == nil {
@@ -103,6 +377,10 @@ func init() {
 	if len(os.Args) > 1 && (os.Args[1] == "__complete" || os.Args[1] == "completion") {
 		pterm.DisableColor()
 	}
+	if len(os.Args) > 1 && os.Args[1] == "__complete" {
+		// Messages and progress would end up among the completions
+		pterm.DisableOutput()
+	}
 
 	// Determine if the current command is 'setting environment -l'
 	skipDynamicCommands := false
@@ -117,6 +395,11 @@ func init() {
 		}
 	}
 
+	addStaticCommands()
+}
+
+// addStaticCommands registers the commands that do not depend on the services of the environment
+func addStaticCommands() {
 	// Initialize other commands group
 	OtherCommands := &cobra.Group{
 		ID:    "other",
@@ -128,6 +411,35 @@ func init() {
 	rootCmd.AddCommand(other.LoginCmd)
 	rootCmd.AddCommand(other.AliasCmd)
 	rootCmd.AddCommand(other.ApplyCmd)
+	rootCmd.AddCommand(other.TokenCmd)
+	rootCmd.AddCommand(other.WorkspaceCmd)
+	rootCmd.AddCommand(other.CostCmd)
+	rootCmd.AddCommand(other.PluginSvcCmd)
+	rootCmd.AddCommand(other.ProviderCmd)
+	rootCmd.AddCommand(other.RoleCmd)
+	rootCmd.AddCommand(other.RoleBindingCmd)
+	rootCmd.AddCommand(other.UserCmd)
+	addOrMergeCommand(other.SecretCmd)
+	addOrMergeCommand(other.InventoryCmd)
+	rootCmd.AddCommand(other.ServiceAccountCmd)
+	rootCmd.AddCommand(other.DescribeCmd)
+	rootCmd.AddCommand(other.ExecCmd)
+	rootCmd.AddCommand(other.BenchCmd)
+	rootCmd.AddCommand(other.GraphCmd)
+	rootCmd.AddCommand(other.MetricCmd)
+	rootCmd.AddCommand(other.JobsCmd)
+	rootCmd.AddCommand(other.ScheduleCmd)
+	rootCmd.AddCommand(other.VersionCmd)
+	rootCmd.AddCommand(other.PromptCmd)
+	rootCmd.AddCommand(other.EnvCmd)
+	rootCmd.AddCommand(other.GenerateCmd)
+	rootCmd.AddCommand(other.ReconcileCmd)
+	rootCmd.AddCommand(other.CompareCmd)
+	rootCmd.AddCommand(other.PromoteCmd)
+	rootCmd.AddCommand(other.TelemetryCmd)
+	rootCmd.AddCommand(other.NoticesCmd)
+	rootCmd.AddCommand(other.DevCmd)
+	rootCmd.AddCommand(other.NewShellCmd(rootCmd))
 
 	// Set default group for commands without a group
 	for _, cmd := range rootCmd.Commands() {
@@ -136,11 +448,12 @@ func init() {
 		}
 	}
 
-	home, err := os.UserHomeDir()
+	settingDir, err := configs.SettingDir()
 	if err != nil {
-		log.Fatalf("Unable to find home directory: %v", err)
+		pterm.Error.Printf("Unable to find home directory: %v\n", err)
+		cleanup.Exit(1)
 	}
-	viper.AddConfigPath(filepath.Join(home, ".cfctl"))
+	viper.AddConfigPath(settingDir)
 	viper.SetConfigName("setting")
 	viper.SetConfigType("yaml")
 }
@@ -156,18 +469,14 @@ func showInitializationGuide() {
 	}
 
 	// Get current environment from setting file
-	home, err := os.UserHomeDir()
+	settingFile, err := configs.GetSettingFilePath()
 	if err != nil {
 		pterm.Error.Printf("Unable to find home directory: %v\n", err)
 		return
 	}
 
-	settingFile := filepath.Join(home, ".cfctl", "setting.yaml")
-	mainV := viper.New()
-	mainV.SetConfigFile(settingFile)
-	mainV.SetConfigType("yaml")
-
-	if err := mainV.ReadInConfig(); err != nil {
+	mainV, err := configs.Setting()
+	if err != nil {
 		pterm.Warning.Printf("No valid configuration found.\n")
 		pterm.Info.Println("Please run 'cfctl setting init' to set up your configuration.")
 		return
@@ -183,7 +492,7 @@ func showInitializationGuide() {
 	// Check if current environment is app type and token is empty
 	if strings.HasSuffix(currentEnv, "-app") {
 		envConfig := mainV.Sub(fmt.Sprintf("environments.%s", currentEnv))
-		if envConfig == nil || envConfig.GetString("token") == "" {
+		if envConfig == nil || configs.SettingString(envConfig, "token") == "" {
 			// Get URL from environment config
 			url := envConfig.GetString("url")
 			if url == "" {
@@ -238,7 +547,7 @@ func showInitializationGuide() {
 			return
 		}
 
-		endpointName := envConfig.GetString("endpoint")
+		endpointName := configs.SettingString(envConfig, "endpoint")
 
 		// Skip authentication warning for gRPC+SSL endpoints
 		if strings.HasPrefix(endpointName, "grpc+ssl://") {
@@ -376,11 +685,15 @@ func addDynamicServiceCommands() error {
 		return nil
 	}
 
-	// If no cached endpoints, show progress with detailed messages
-	progressbar, _ := pterm.DefaultProgressbar.
-		WithTotal(4).
-		WithTitle(fmt.Sprintf("Environments up %s environment", config.Environment)).
-		Start()
+	// If no cached endpoints, show progress with detailed messages. It goes to stderr so that
+	// the output of the command is not mixed with it, and is not started during shell completion
+	// as it hides and shows the cursor on stdout.
+	progressbar := pterm.DefaultProgressbar.
+		WithWriter(os.Stderr).
+		WithTitle(fmt.Sprintf("Environments up %s environment", config.Environment))
+	if pterm.Output {
+		progressbar, _ = progressbar.WithTotal(4).Start()
+	}
 
 	progressbar.UpdateTitle("Fetching available service endpoints from the API server")
 	endpointsMap, err := configs.FetchEndpointsMap(apiEndpoint)
@@ -389,7 +702,7 @@ func addDynamicServiceCommands() error {
 	}
 	progressbar.Increment()
 
-	progressbar.UpdateTitle(fmt.Sprintf("Caching endpoints to %s/.cfctl/cache for faster access", os.Getenv("HOME")))
+	progressbar.UpdateTitle(fmt.Sprintf("Caching endpoints to %s for faster access", configs.CacheDir(config.Environment)))
 	cachedEndpointsMap = endpointsMap
 	if err := saveEndpointsCache(endpointsMap); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to cache endpoints: %v\n", err)
@@ -432,30 +745,17 @@ func addDynamicServiceCommands() error {
 }
 
 func loadCachedEndpoints() (map[string]string, error) {
-	home, err := os.UserHomeDir()
+	mainV, err := configs.Setting()
 	if err != nil {
 		return nil, err
 	}
 
-	settingFile := filepath.Join(home, ".cfctl", "setting.yaml")
-	settingData, err := os.ReadFile(settingFile)
-	if err != nil {
-		return nil, err
-	}
-
-	var settings struct {
-		Environment string `yaml:"environment"`
-	}
-
-	if err := yaml.Unmarshal(settingData, &settings); err != nil {
-		return nil, err
-	}
-
-	if settings.Environment == "" {
+	currentEnv := mainV.GetString("environment")
+	if currentEnv == "" {
 		return nil, fmt.Errorf("no environment set")
 	}
 
-	cacheFile := filepath.Join(home, ".cfctl", "cache", settings.Environment, "endpoints.yaml")
+	cacheFile := filepath.Join(configs.CacheDir(currentEnv), "endpoints.yaml")
 	data, err := os.ReadFile(cacheFile)
 	if err != nil {
 		return nil, err
@@ -479,16 +779,9 @@ func loadCachedEndpoints() (map[string]string, error) {
 }
 
 func saveEndpointsCache(endpoints map[string]string) error {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return err
-	}
-
 	// Get current environment from main setting file
-	mainV := viper.New()
-	mainV.SetConfigFile(filepath.Join(home, ".cfctl", "setting.yaml"))
-	mainV.SetConfigType("yaml")
-	if err := mainV.ReadInConfig(); err != nil {
+	mainV, err := configs.Setting()
+	if err != nil {
 		return err
 	}
 
@@ -498,8 +791,8 @@ func saveEndpointsCache(endpoints map[string]string) error {
 	}
 
 	// Create environment-specific cache directory
-	envCacheDir := filepath.Join(home, ".cfctl", "cache", currentEnv)
-	if err := os.MkdirAll(envCacheDir, 0755); err != nil {
+	envCacheDir := configs.CacheDir(currentEnv)
+	if err := configs.EnsureSecureDir(envCacheDir); err != nil {
 		return err
 	}
 
@@ -508,23 +801,14 @@ func saveEndpointsCache(endpoints map[string]string) error {
 		return err
 	}
 
-	return os.WriteFile(filepath.Join(envCacheDir, "endpoints.yaml"), data, 0644)
+	return configs.WriteSecureFile(filepath.Join(envCacheDir, "endpoints.yaml"), data)
 }
 
 // loadConfig loads configuration from both main and cache setting files
 func loadConfig() (*Config, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("unable to find home directory: %v", err)
-	}
-
-	settingFile := filepath.Join(home, ".cfctl", "setting.yaml")
-
 	// Read main setting file
-	mainV := viper.New()
-	mainV.SetConfigFile(settingFile)
-	mainV.SetConfigType("yaml")
-	if err := mainV.ReadInConfig(); err != nil {
+	mainV, err := configs.Setting()
+	if err != nil {
 		return nil, fmt.Errorf("failed to read setting file")
 	}
 
@@ -539,7 +823,7 @@ func loadConfig() (*Config, error) {
 		return nil, fmt.Errorf("environment %s not found", currentEnv)
 	}
 
-	endpointName := envConfig.GetString("endpoint")
+	endpointName := configs.SettingString(envConfig, "endpoint")
 	if endpointName == "" {
 		return nil, fmt.Errorf("no endpoint found in configuration")
 	}
@@ -550,7 +834,10 @@ func loadConfig() (*Config, error) {
 	}
 
 	if strings.HasSuffix(currentEnv, "-app") {
-		config.Token = envConfig.GetString("token")
+		config.Token = configs.SettingString(envConfig, "token")
+	}
+	if token := configs.EnvToken(); token != "" {
+		config.Token = token
 	}
 
 	return config, nil
@@ -558,10 +845,11 @@ func loadConfig() (*Config, error) {
 
 func createServiceCommand(serviceName string) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     serviceName + " [verb] [resource]",
-		Short:   fmt.Sprintf("Interact with the %s service", serviceName),
-		Long:    fmt.Sprintf("Use this command to interact with the %s service.", serviceName),
-		GroupID: "available",
+		Use:         serviceName + " [verb] [resource]",
+		Short:       fmt.Sprintf("Interact with the %s service", serviceName),
+		Long:        fmt.Sprintf("Use this command to interact with the %s service.", serviceName),
+		GroupID:     "available",
+		Annotations: map[string]string{output.QueryAnnotation: "true"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				pterm.Info.Println("To see available API resources, run:")
@@ -580,6 +868,19 @@ func createServiceCommand(serviceName string) *cobra.Command {
 				resource = args[1]
 			}
 
+			// An alias takes the flags of the command it stands for, e.g. --count-only of list
+			aliasVerb, aliasResource, isAlias, err := transport.ResolveAlias(serviceName, verb)
+			if err != nil {
+				return err
+			}
+			if isAlias {
+				verb, resource = aliasVerb, aliasResource
+			}
+
+			if workspace, _ := cmd.Flags().GetString("workspace"); workspace != "" {
+				configs.SelectWorkspace(workspace)
+			}
+
 			if verb == "api_resources" {
 				return common.ListAPIResources(serviceName)
 			}
@@ -589,6 +890,33 @@ func createServiceCommand(serviceName string) *cobra.Command {
 			fileParameter, _ := cmd.Flags().GetString("file-parameter")
 			outputFormat, _ := cmd.Flags().GetString("output")
 			copyToClipboard, _ := cmd.Flags().GetBool("copy")
+			printCurl, _ := cmd.Flags().GetBool("print-curl")
+			printGRPCurl, _ := cmd.Flags().GetBool("print-grpcurl")
+			assertions, _ := cmd.Flags().GetStringArray("assert")
+			changesOnly, _ := cmd.Flags().GetBool("changes-only")
+			reload, _ := cmd.Flags().GetBool("reload")
+			flatten, _ := cmd.Flags().GetBool("flatten")
+			flattenDepth, _ := cmd.Flags().GetInt("flatten-depth")
+			countOnly, _ := cmd.Flags().GetBool("count-only")
+			sample, _ := cmd.Flags().GetInt("sample")
+			sampleRandom, _ := cmd.Flags().GetBool("random")
+			groupCount, _ := cmd.Flags().GetString("group-count")
+			groupSum, _ := cmd.Flags().GetString("sum")
+			since, _ := cmd.Flags().GetString("since")
+			until, _ := cmd.Flags().GetString("until")
+			timeField, _ := cmd.Flags().GetString("time-field")
+			refresh, _ := cmd.Flags().GetBool("refresh")
+
+			switch {
+			case sample < 0:
+				return fmt.Errorf("--sample must be a positive number of results")
+			case sample > 0 && verb != "list":
+				return fmt.Errorf("--sample is only supported for list")
+			case sample > 0 && countOnly:
+				return fmt.Errorf("--sample cannot be combined with --count-only")
+			case sampleRandom && sample == 0:
+				return fmt.Errorf("--random needs --sample")
+			}
 
 			sortBy := ""
 			columns := ""
@@ -604,6 +932,20 @@ func createServiceCommand(serviceName string) *cobra.Command {
 				noPaging, _ = cmd.Flags().GetBool("no-paging")
 			}
 
+			// -o table=col1,col2 selects the columns like --columns
+			outputSpec, err := output.Parse(outputFormat)
+			if err != nil {
+				return err
+			}
+			outputFormat = outputSpec.Format
+			query, _ := cmd.Flags().GetString("query")
+			if err := output.ValidateQuery(query); err != nil {
+				return err
+			}
+			if len(outputSpec.Columns) > 0 {
+				columns = strings.Join(outputSpec.Columns, ",")
+			}
+
 			options := &transport.FetchOptions{
 				Parameters:           parameters,
 				JSONParameter:        jsonParameter,
@@ -617,22 +959,107 @@ func createServiceCommand(serviceName string) *cobra.Command {
 				Rows:                 rows,
 				PageSize:             pageSize,
 				NoPaging:             noPaging,
+				PrintCurl:            printCurl,
+				PrintGRPCurl:         printGRPCurl,
+				ChangesOnly:          changesOnly,
+				Reload:               reload,
+				Flatten:              flatten,
+				FlattenDepth:         flattenDepth,
+				CountOnly:            countOnly && verb == "list",
+				Sample:               sample,
+				SampleRandom:         sampleRandom,
+				GroupCount:           groupCount,
+				GroupSum:             groupSum,
+				Since:                since,
+				Until:                until,
+				TimeField:            timeField,
+				Query:                query,
+				Refresh:              refresh,
 			}
 
 			if verb == "list" && !cmd.Flags().Changed("output") {
 				options.OutputFormat = "table"
+				// Query results are usually consumed by scripts
+				if query != "" {
+					options.OutputFormat = "json"
+				}
+			}
+
+			// Assertions are used as probes, so only print the response when asked for
+			if len(assertions) > 0 && !cmd.Flags().Changed("output") {
+				options.OutputFormat = ""
+			}
+
+			idsFrom, _ := cmd.Flags().GetString("ids-from")
+			if idsFrom != "" {
+				concurrency, _ := cmd.Flags().GetInt("concurrency")
+				threshold, _ := cmd.Flags().GetInt("confirm-threshold")
+				if yes, _ := cmd.Flags().GetBool("yes"); yes {
+					threshold = -1
+				}
+				outputSpec.Query = query
+				runBatch(serviceName, verb, resource, idsFrom, concurrency, threshold, options, outputSpec, cmd.Flags().Changed("output"))
+				return nil
+			}
+
+			waitFor, _ := cmd.Flags().GetString("wait-for")
+			if waitFor != "" {
+				if verb != "get" && verb != "list" {
+					pterm.Error.Println("--wait-for is only supported for get and list")
+					cleanup.Exit(1)
+				}
+				interval, _ := cmd.Flags().GetDuration("interval")
+				timeout, _ := cmd.Flags().GetDuration("timeout")
+				if err := transport.WaitForCondition(serviceName, verb, resource, options, waitFor, interval, timeout); err != nil {
+					cleanup.Exit(1)
+				}
+				return nil
 			}
 
 			watch, _ := cmd.Flags().GetBool("watch")
-			if watch && verb == "list" {
+			if watch && verb == "list" && !printCurl && !printGRPCurl {
 				return transport.WatchResource(serviceName, verb, resource, options)
 			}
 
-			_, err := transport.FetchService(serviceName, verb, resource, options)
+			// The browser shows the results itself
+			browse, _ := cmd.Flags().GetBool("browse")
+			browse = browse && verb == "list" && !printCurl && !printGRPCurl
+			if browse && !features.Enabled(features.TUI) {
+				// Builds without the TUI print the results as usual instead
+				pterm.Warning.Println(features.Unavailable(features.TUI))
+				browse = false
+			}
+			if browse && !configs.Interactive() {
+				pterm.Warning.Println("The result browser needs a terminal, printing the results instead.")
+				browse = false
+			}
+			if browse {
+				options.OutputFormat = ""
+			}
+
+			respMap, err := transport.FetchService(serviceName, verb, resource, options)
 			if err != nil {
 				pterm.Error.Println(err.Error())
+				// Probes and scripts printing the call must see that it failed
+				if len(assertions) > 0 || printCurl || printGRPCurl {
+					cleanup.Exit(1)
+				}
 				return nil
 			}
+
+			if browse && respMap != nil {
+				other.BrowseResults(serviceName, resource, respMap)
+				return nil
+			}
+
+			if len(assertions) > 0 {
+				// A probe without a response must not pass
+				if respMap == nil {
+					pterm.Error.Println("No response to check the assertions against.")
+					cleanup.Exit(1)
+				}
+				checkAssertions(respMap, assertions)
+			}
 			return nil
 		},
 	}
@@ -642,19 +1069,145 @@ func createServiceCommand(serviceName string) *cobra.Command {
 
 	// Add list-specific flags
 	cmd.Flags().BoolP("watch", "w", false, "Watch for changes")
+	cmd.Flags().Bool("changes-only", false, "With --watch -o jsonl, emit only created/updated/deleted items")
+	cmd.Flags().Bool("reload", false, "With --watch, reload the setting when setting.yaml changes (SIGHUP always reloads)")
 	cmd.Flags().StringP("sort", "s", "", "Sort by field (e.g. 'name', 'created_at')")
 	cmd.Flags().BoolP("minimal", "m", false, "Show minimal columns")
 	cmd.Flags().StringP("columns", "c", "", "Specific columns (-c id,name)")
 	cmd.Flags().IntP("rows", "r", 0, "Number of rows")
 	cmd.Flags().IntP("rows-per-page", "n", 15, "Number of rows per page")
 	cmd.Flags().BoolP("no-paging", "", false, "Disable pagination and show all results")
+	cmd.Flags().Bool("flatten", false, "Flatten nested fields into dotted columns for table and csv output (data.compute.instance_type)")
+	cmd.Flags().Int("flatten-depth", format.DefaultFlattenDepth, "Maximum depth of nested fields expanded by --flatten")
+	cmd.Flags().Bool("count-only", false, "Print only the total number of items (list only)")
+	cmd.Flags().Int("sample", 0, "Fetch only this many results of a huge list, e.g. to explore it (list only)")
+	cmd.Flags().Bool("random", false, "With --sample, pick the results at random among 10 times as many")
+	cmd.Flags().String("group-count", "", "Count list results per value of a field (--group-count provider)")
+	cmd.Flags().String("sum", "", "With --group-count, also sum this numeric field per group")
+	cmd.Flags().Bool("refresh", false, "Resolve the service descriptors again instead of using the cached ones")
+	cmd.Flags().String("workspace", "", "Use the token cached for this workspace by 'cfctl login --workspace' (default $CFCTL_WORKSPACE)")
+	cmd.Flags().Bool("browse", false, "Pick an item of the results to get, describe or delete it (list only)")
+	cmd.Flags().String("since", "", "Only items whose time field is at or after this time (7d, 12h, today, 2024-01-31)")
+	cmd.Flags().String("until", "", "Only items whose time field is before this time (yesterday, 1d, 2024-02-01)")
+	cmd.Flags().String("time-field", transport.DefaultTimeField, "Time field filtered by --since and --until (e.g. updated_at)")
 
 	// Add existing flags
-	cmd.Flags().StringArrayP("parameter", "p", []string{}, "Input Parameter (-p <key>=<value> -p ...)")
-	cmd.Flags().StringP("json-parameter", "j", "", "JSON type parameter")
-	cmd.Flags().StringP("file-parameter", "f", "", "YAML file parameter")
-	cmd.Flags().StringP("output", "o", "yaml", "Output format (yaml, json, table, csv)")
+	cmd.Flags().StringArrayP("parameter", "p", []string{}, "Input Parameter (-p <key>=<value> -p ..., nested fields: -p query.filter[0].k=name)")
+	cmd.Flags().StringP("json-parameter", "j", "", "JSON type parameter, '-' reads it from stdin")
+	cmd.Flags().StringP("file-parameter", "f", "", "YAML or JSON file parameter, '-' reads it from stdin")
+	cmd.Flags().StringP("output", "o", "yaml", "Output format (yaml, json, table, csv, id, jsonl with --watch); table=col1,col2 selects columns")
 	cmd.Flags().BoolP("copy", "y", false, "Copy the output to the clipboard")
+	cmd.Flags().Bool("print-curl", false, "Print the equivalent curl command instead of calling the service")
+	cmd.Flags().Bool("print-grpcurl", false, "Print the equivalent grpcurl command instead of calling the service")
+	cmd.Flags().String("wait-for", "", "Poll get/list until the expression holds (--wait-for 'status == \"SUCCESS\"')")
+	cmd.Flags().Duration("interval", 10*time.Second, "Polling interval for --wait-for")
+	cmd.Flags().Duration("timeout", 10*time.Minute, "Maximum time to wait for --wait-for")
+	cmd.Flags().StringArray("assert", []string{}, "Exit with a non-zero code unless the expression holds (--assert 'total_count > 0')")
+	cmd.Flags().String("ids-from", "", "Run get, delete or update once per ID read from a file, '-' reads them from stdin")
+	cmd.Flags().Int("concurrency", transport.DefaultBatchConcurrency, "Maximum number of IDs from --ids-from processed at once")
+	cmd.Flags().Int("confirm-threshold", other.DefaultBulkDeleteThreshold, "Preview and type the count to confirm a delete of more IDs than this from --ids-from")
+	cmd.Flags().Bool("yes", false, "Skip the confirmation of a bulk delete with --ids-from")
+
+	// Suggest the verbs and resources known from the cached descriptors
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return transport.CompleteServiceArgs(serviceName, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// Suggest request fields of the method after -p, e.g. 'cfctl identity list User -p st<TAB>'
+	cmd.RegisterFlagCompletionFunc("parameter", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) < 2 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		completions := transport.CompleteParameter(serviceName, args[0], args[1], toComplete)
+		return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	})
 
 	return cmd
 }
+
+// runBatch calls the verb for every ID read from source and prints a per-ID summary.
+// A delete of more IDs than threshold is confirmed first, a negative threshold skips the confirmation.
+// Responses are printed for get, or for the other verbs when -o is given, and it exits with 1 when any ID failed.
+func runBatch(serviceName, verb, resource, source string, concurrency, threshold int, options *transport.FetchOptions, spec output.Spec, outputExplicit bool) {
+	if !slices.Contains(transport.BatchVerbs, verb) {
+		pterm.Error.Printf("--ids-from is only supported for %s\n", strings.Join(transport.BatchVerbs, ", "))
+		cleanup.Exit(1)
+	}
+	if err := spec.Validate(output.Table, output.JSON, output.YAML, output.CSV, output.ID); err != nil {
+		pterm.Error.Println(err.Error())
+		cleanup.Exit(1)
+	}
+
+	ids, err := transport.ReadIDs(source)
+	if err != nil {
+		pterm.Error.Println(err.Error())
+		cleanup.Exit(1)
+	}
+
+	if verb == "delete" && threshold >= 0 && len(ids) > threshold && !other.ConfirmBulkDelete(serviceName, resource, ids, source == "-") {
+		cleanup.Exit(1)
+	}
+
+	results, err := transport.ExecBatch(serviceName, resource, verb, ids, options, concurrency)
+	if err != nil {
+		pterm.Error.Println(err.Error())
+		cleanup.Exit(1)
+	}
+
+	if verb == "get" || outputExplicit {
+		var responses []interface{}
+		for _, result := range results {
+			if result.Err == nil && result.Response != nil {
+				responses = append(responses, result.Response)
+			}
+		}
+		if len(responses) > 0 {
+			if spec.Format == output.ID {
+				spec.IDField = transport.IDField(serviceName, verb, resource)
+			}
+			if err := output.Print(map[string]interface{}{"results": responses}, spec); err != nil {
+				pterm.Error.Println(err.Error())
+			}
+		}
+	}
+
+	if transport.PrintBatchSummary(verb, results) > 0 {
+		cleanup.Exit(1)
+	}
+}
+
+// checkAssertions evaluates each --assert expression against the response and exits with 1 on failure
+func checkAssertions(respMap map[string]interface{}, assertions []string) {
+	failed := false
+	for _, assertion := range assertions {
+		ok, err := transport.EvaluateCondition(respMap, assertion)
+		if err != nil {
+			pterm.Error.Printf("Invalid assertion: %v\n", err)
+			cleanup.Exit(2)
+		}
+		if ok {
+			pterm.Success.Printf("Assertion passed: %s\n", assertion)
+		} else {
+			pterm.Error.Printf("Assertion failed: %s\n", assertion)
+			failed = true
+		}
+	}
+
+	if failed {
+		cleanup.Exit(1)
+	}
+}
+
+// addOrMergeCommand adds a static command, or attaches its subcommands to the dynamic
+// service command of the same name so that generic verbs keep working.
+func addOrMergeCommand(staticCmd *cobra.Command) {
+	for _, existing := range rootCmd.Commands() {
+		if existing.Name() == staticCmd.Name() {
+			for _, sub := range staticCmd.Commands() {
+				existing.AddCommand(sub)
+			}
+			return
+		}
+	}
+	rootCmd.AddCommand(staticCmd)
+}
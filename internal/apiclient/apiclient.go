@@ -0,0 +1,181 @@
+// Package apiclient calls SpaceONE API methods over gRPC or over the REST gateway of the
+// console API, picked by the scheme of the endpoint, so that callers like login work the
+// same way for grpc+ssl:// identity endpoints and https:// console endpoints.
+package apiclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/grpcconn"
+	"github.com/cloudforet-io/cfctl/internal/invoker"
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+)
+
+// apiVersion is the version of the SpaceONE API called over gRPC
+const apiVersion = "v2"
+
+// requestTimeout bounds each REST request
+const requestTimeout = 30 * time.Second
+
+// Client calls methods of one service, e.g. Token issue of the identity service.
+// Parameters and responses are in their JSON form, with enums given by name.
+type Client interface {
+	Call(resource, verb string, params map[string]interface{}, token string) (map[string]interface{}, error)
+}
+
+// New returns the client for the endpoint of the service. grpc:// and grpc+ssl:// endpoints are
+// called over gRPC, while http:// and https:// endpoints are the REST gateway of the service,
+// e.g. https://console-v2.api.example.com/identity.
+func New(endpoint, service string) (Client, error) {
+	switch {
+	case strings.HasPrefix(endpoint, "grpc://"), strings.HasPrefix(endpoint, "grpc+ssl://"):
+		return &grpcClient{endpoint: endpoint, service: service}, nil
+	case strings.HasPrefix(endpoint, "http://"), strings.HasPrefix(endpoint, "https://"):
+		return &restClient{
+			baseURL:    strings.TrimSuffix(endpoint, "/"),
+			httpClient: &http.Client{Timeout: requestTimeout, Transport: netproxy.Transport()},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported scheme in endpoint: %s", endpoint)
+}
+
+// grpcClient resolves methods through server reflection on the shared connection
+type grpcClient struct {
+	endpoint string
+	service  string
+}
+
+func (c *grpcClient) Call(resource, verb string, params map[string]interface{}, token string) (map[string]interface{}, error) {
+	conn, err := grpcconn.GetEndpoint(c.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %v", err)
+	}
+
+	inv := invoker.New(conn, token)
+	defer inv.Close()
+
+	fullServiceName := fmt.Sprintf("spaceone.api.%s.%s.%s", c.service, apiVersion, resource)
+	methodDesc, err := inv.ResolveServiceMethod(fullServiceName, verb)
+	if err != nil {
+		return nil, err
+	}
+
+	reqMsg, _, err := invoker.NewRequest(methodDesc, params)
+	if err != nil {
+		return nil, err
+	}
+	respBytes, err := inv.Invoke(methodDesc, reqMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return resp, nil
+}
+
+// restClient posts JSON to <base>/<resource>/<verb> in kebab case, e.g. /user-profile/get-workspaces
+type restClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (c *restClient) Call(resource, verb string, params map[string]interface{}, token string) (map[string]interface{}, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/%s", c.baseURL, kebabCase(resource), kebabCase(verb))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s failed with status code %d: %s", resource, verb, resp.StatusCode, errorMessage(respBody))
+	}
+
+	var result map[string]interface{}
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return result, nil
+}
+
+// errorMessage extracts the code and message of a gateway error response, e.g.
+// "ERROR_MFA_REQUIRED: MFA is required", falling back to the raw body
+func errorMessage(body []byte) string {
+	var errResp struct {
+		Detail struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"detail"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &errResp) == nil {
+		code, message := errResp.Detail.Code, errResp.Detail.Message
+		if code == "" && message == "" {
+			code, message = errResp.Code, errResp.Message
+		}
+		switch {
+		case code != "" && message != "":
+			return code + ": " + message
+		case message != "":
+			return message
+		case code != "":
+			return code
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// kebabCase converts resource and method names to REST path segments,
+// e.g. UserProfile -> user-profile and get_workspaces -> get-workspaces
+func kebabCase(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_':
+			sb.WriteByte('-')
+		case r >= 'A' && r <= 'Z':
+			if i > 0 && name[i-1] != '_' {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r + ('a' - 'A'))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
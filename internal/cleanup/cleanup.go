@@ -0,0 +1,162 @@
+// Package cleanup undoes the work in progress of a command when cfctl is interrupted, exits
+// early or panics: temporary and partially written files are removed and file locks released,
+// so that an aborted command does not leave a corrupted setting or cache behind. Work registers
+// its cleanup when it starts and releases it once it completed.
+package cleanup
+
+import (
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+)
+
+var (
+	mu           sync.Mutex
+	nextID       int
+	pending      = make(map[int]func())
+	interceptors int
+	finish       func(code int) int
+	finishOnce   sync.Once
+	guards       int
+)
+
+// exitRequest is raised by Exit within Guard in place of exiting the process
+type exitRequest struct {
+	code int
+}
+
+// Register adds fn to the cleanups run on interruption, exit or panic. The returned function
+// removes it without running it, once the work it undoes completed.
+func Register(fn func()) func() {
+	mu.Lock()
+	defer mu.Unlock()
+	id := nextID
+	nextID++
+	pending[id] = fn
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(pending, id)
+	}
+}
+
+// RemoveOnAbort registers the removal of path, e.g. a temporary file or directory
+func RemoveOnAbort(path string) func() {
+	return Register(func() {
+		os.RemoveAll(path)
+	})
+}
+
+// Run runs the registered cleanups, the latest first, and removes them
+func Run() {
+	mu.Lock()
+	ids := make([]int, 0, len(pending))
+	fns := make(map[int]func(), len(pending))
+	for id, fn := range pending {
+		ids = append(ids, id)
+		fns[id] = fn
+	}
+	pending = make(map[int]func())
+	mu.Unlock()
+
+	slices.Sort(ids)
+	for i := len(ids) - 1; i >= 0; i-- {
+		fns[ids[i]]()
+	}
+}
+
+// AtExit sets fn to finish the command before Exit exits, e.g. to save the setting and report
+// the command. fn returns the exit code, which it may change when finishing fails.
+func AtExit(fn func(code int) int) {
+	mu.Lock()
+	defer mu.Unlock()
+	finish = fn
+}
+
+// Exit runs the cleanups, finishes the command and exits with code. It takes the place of
+// os.Exit, which skips them. The cleanups run first, so that the locks they release do not
+// keep the command from finishing.
+func Exit(code int) {
+	Run()
+	mu.Lock()
+	fn := finish
+	guarded := guards > 0
+	mu.Unlock()
+	if guarded {
+		panic(exitRequest{code: code})
+	}
+	if fn != nil {
+		finishOnce.Do(func() {
+			code = fn(code)
+		})
+	}
+	os.Exit(code)
+}
+
+// Guard runs fn and returns the code it exited with through Exit, which returns to Guard
+// instead of exiting the process, or 0. The caller finishes the command itself, e.g. the
+// interactive shell running the next one. Exit must not be called by other goroutines of fn.
+func Guard(fn func()) (code int) {
+	mu.Lock()
+	guards++
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		guards--
+		mu.Unlock()
+		if r := recover(); r != nil {
+			request, ok := r.(exitRequest)
+			if !ok {
+				panic(r)
+			}
+			code = request.code
+		}
+	}()
+
+	fn()
+	return 0
+}
+
+// Intercept tells that the caller stops on interrupts itself, e.g. to end a watch gracefully,
+// until the returned function is called. Catch leaves interrupts to it in the meantime.
+func Intercept() func() {
+	mu.Lock()
+	defer mu.Unlock()
+	interceptors++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			interceptors--
+		})
+	}
+}
+
+// Catch runs the cleanups and exits when cfctl is interrupted or terminated, without finishing
+// the command, so that its changes are not saved half done. Interrupts are left to the commands
+// that intercept them.
+func Catch() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			mu.Lock()
+			intercepted := interceptors > 0
+			mu.Unlock()
+			if sig == os.Interrupt && intercepted {
+				continue
+			}
+
+			// Exit codes of shells for processes killed by the signal
+			code := 130
+			if sig == syscall.SIGTERM {
+				code = 143
+			}
+			Run()
+			os.Exit(code)
+		}
+	}()
+}
@@ -0,0 +1,99 @@
+package credstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// helperPrefix is prepended to helpers given by their short name, like the credsStore of docker
+const helperPrefix = "docker-credential-"
+
+// helperUsername is the user name stored with each secret, which the helpers require
+const helperUsername = "cfctl"
+
+// commandStore keeps secrets with an external helper speaking the protocol of the docker
+// credential helpers: "store" reads {"ServerURL", "Username", "Secret"} from stdin, "get"
+// reads the server URL and prints that object, and "erase" reads the server URL. Each key is
+// stored under the server URL cfctl://<key>.
+type commandStore struct {
+	helper string
+}
+
+func newCommandStore(helper string) (Store, error) {
+	path, err := exec.LookPath(helper)
+	if err != nil && !strings.ContainsAny(helper, `/\`) {
+		path, err = exec.LookPath(helperPrefix + helper)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("credential helper '%s' not found: %v", helper, err)
+	}
+	return commandStore{helper: path}, nil
+}
+
+func (s commandStore) Name() string {
+	return fmt.Sprintf("%s (%s)", Command, s.helper)
+}
+
+func (s commandStore) Get(key string) (string, error) {
+	out, err := s.run("get", serverURL(key))
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "credentials not found") {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	var credentials struct {
+		Secret string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &credentials); err != nil {
+		return "", fmt.Errorf("credential helper %s returned invalid output: %v", s.helper, err)
+	}
+	return credentials.Secret, nil
+}
+
+func (s commandStore) Set(key, value string) error {
+	input, err := json.Marshal(map[string]string{
+		"ServerURL": serverURL(key),
+		"Username":  helperUsername,
+		"Secret":    value,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.run("store", string(input))
+	return err
+}
+
+func (s commandStore) Delete(key string) error {
+	_, err := s.run("erase", serverURL(key))
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "credentials not found") {
+		return nil
+	}
+	return err
+}
+
+// run runs the helper with action and input on stdin, returning its stdout
+func (s commandStore) run(action, input string) ([]byte, error) {
+	cmd := exec.Command(s.helper, action)
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// The helpers report errors on stdout, e.g. "credentials not found in native keychain"
+		message := strings.TrimSpace(stdout.String() + " " + stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("credential helper %s %s failed: %s", s.helper, action, message)
+	}
+	return stdout.Bytes(), nil
+}
+
+func serverURL(key string) string {
+	return "cfctl://" + key
+}
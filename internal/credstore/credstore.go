@@ -0,0 +1,113 @@
+// Package credstore keeps the secrets of cfctl in a pluggable store, so that login also works on
+// servers without a system keyring. The backends are:
+//
+//	keyring  the keychain of macOS, the credential manager of Windows or the secret service of Linux
+//	file     an AES-GCM encrypted file in the setting directory, for headless machines
+//	command  an external helper speaking the protocol of the docker credential helpers
+//	none     no store, tokens stay in setting.yaml
+//
+// The backend is chosen by CFCTL_CREDENTIAL_STORE or credential_store in setting.yaml, and by
+// default is the keyring when it can be reached and the file otherwise.
+package credstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ErrNotFound is returned by Get when the store holds no secret for the key
+var ErrNotFound = errors.New("credential not found")
+
+// ErrDisabled is returned by New for the none backend, which keeps tokens in setting.yaml
+var ErrDisabled = errors.New("the credential store is disabled")
+
+// Backends of the store
+const (
+	Auto    = "auto"
+	Keyring = "keyring"
+	File    = "file"
+	Command = "command"
+	None    = "none"
+)
+
+// Environment variables choosing the store, taking precedence over setting.yaml
+const (
+	EnvVarStore  = "CFCTL_CREDENTIAL_STORE"
+	EnvVarHelper = "CFCTL_CREDENTIAL_HELPER"
+	// EnvVarKey is a passphrase encrypting the file store instead of its key file
+	EnvVarKey = "CFCTL_CREDENTIAL_KEY"
+)
+
+// Store holds secrets by key
+type Store interface {
+	// Name names the backend for messages, e.g. "file (~/.cfctl/credentials.enc)"
+	Name() string
+	// Get returns the secret of key, or ErrNotFound
+	Get(key string) (string, error)
+	Set(key, value string) error
+	// Delete removes the secret of key; a missing secret is not an error
+	Delete(key string) error
+}
+
+// Config selects the backend of the store
+type Config struct {
+	Backend string `mapstructure:"backend"`
+	// Helper is the command of the command backend, e.g. docker-credential-pass or just pass
+	Helper string `mapstructure:"helper"`
+}
+
+// ConfigOf returns the store configuration of credential_store of v, overridden by the
+// environment variables
+func ConfigOf(v *viper.Viper) Config {
+	var config Config
+	if v != nil {
+		config.Backend = v.GetString("credential_store.backend")
+		config.Helper = v.GetString("credential_store.helper")
+	}
+	if backend := os.Getenv(EnvVarStore); backend != "" {
+		config.Backend = backend
+	}
+	if helper := os.Getenv(EnvVarHelper); helper != "" {
+		config.Helper = helper
+	}
+	config.Backend = strings.ToLower(strings.TrimSpace(config.Backend))
+	if config.Backend == "" {
+		config.Backend = Auto
+	}
+	return config
+}
+
+// Open returns the store configured for v, whose files are kept in dir
+func Open(v *viper.Viper, dir string) (Store, error) {
+	return New(ConfigOf(v), dir)
+}
+
+// New returns the store of config, whose files are kept in dir
+func New(config Config, dir string) (Store, error) {
+	switch config.Backend {
+	case Auto:
+		if KeyringAvailable() {
+			return keyringStore{}, nil
+		}
+		return newFileStore(dir), nil
+	case Keyring:
+		if !KeyringAvailable() {
+			return nil, fmt.Errorf("the system keyring cannot be reached, set %s=file to keep credentials in an encrypted file", EnvVarStore)
+		}
+		return keyringStore{}, nil
+	case File:
+		return newFileStore(dir), nil
+	case Command:
+		if config.Helper == "" {
+			return nil, fmt.Errorf("the command credential store needs a helper, set credential_store.helper or %s", EnvVarHelper)
+		}
+		return newCommandStore(config.Helper)
+	case None:
+		return nil, ErrDisabled
+	}
+	return nil, fmt.Errorf("unknown credential store '%s', use auto, keyring, file, command or none", config.Backend)
+}
@@ -0,0 +1,170 @@
+package credstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+)
+
+// fileStore keeps secrets in credentials.enc, a JSON object encrypted with AES-GCM. The key is
+// the passphrase of CFCTL_CREDENTIAL_KEY, or else the random key of credentials.key, which is
+// only readable by the user like the cached tokens.
+type fileStore struct {
+	path    string
+	keyPath string
+}
+
+func newFileStore(dir string) fileStore {
+	return fileStore{
+		path:    filepath.Join(dir, "credentials.enc"),
+		keyPath: filepath.Join(dir, "credentials.key"),
+	}
+}
+
+func (s fileStore) Name() string {
+	return fmt.Sprintf("%s (%s)", File, s.path)
+}
+
+func (s fileStore) Get(key string) (string, error) {
+	unlock, err := configs.LockFile(s.path, false)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	secrets, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s fileStore) Set(key, value string) error {
+	return s.update(func(secrets map[string]string) {
+		secrets[key] = value
+	})
+}
+
+func (s fileStore) Delete(key string) error {
+	return s.update(func(secrets map[string]string) {
+		delete(secrets, key)
+	})
+}
+
+// update changes the secrets under an exclusive lock
+func (s fileStore) update(change func(map[string]string)) error {
+	if err := configs.EnsureSecureDir(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+	unlock, err := configs.LockFile(s.path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, err := s.read()
+	if err != nil {
+		return err
+	}
+	change(secrets)
+	return s.write(secrets)
+}
+
+func (s fileStore) read() (map[string]string, error) {
+	secrets := map[string]string{}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%s is corrupted", s.path)
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s, the key may have changed: %v", s.path, err)
+	}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("%s is corrupted: %v", s.path, err)
+	}
+	return secrets, nil
+}
+
+func (s fileStore) write(secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	gcm, err := s.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	return configs.WriteFileAtomic(s.path, gcm.Seal(nonce, nonce, plaintext, nil), configs.SecureFileMode)
+}
+
+// cipher returns the AES-GCM cipher of the store, creating the key file when create is set
+func (s fileStore) cipher(create bool) (cipher.AEAD, error) {
+	key, err := s.key(create)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s fileStore) key(create bool) ([]byte, error) {
+	if passphrase := os.Getenv(EnvVarKey); passphrase != "" {
+		sum := sha256.Sum256([]byte(passphrase))
+		return sum[:], nil
+	}
+
+	data, err := os.ReadFile(s.keyPath)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s does not hold a valid key", s.keyPath)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) || !create {
+		return nil, fmt.Errorf("failed to read the key of the credential file: %v", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate a key: %v", err)
+	}
+	if err := configs.WriteSecureFile(s.keyPath, []byte(base64.StdEncoding.EncodeToString(key))); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,48 @@
+//go:build !nokeyring
+
+package credstore
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService names the entries of cfctl in the system keyring
+const keyringService = "cfctl-credentials"
+
+// keyringProbe is the key looked up to check that the keyring can be reached
+const keyringProbe = "encryption-key"
+
+// keyringStore keeps secrets in the system keyring
+type keyringStore struct{}
+
+func (keyringStore) Name() string {
+	return Keyring
+}
+
+func (keyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(keyringService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (keyringStore) Set(key, value string) error {
+	return keyring.Set(keyringService, key, value)
+}
+
+func (keyringStore) Delete(key string) error {
+	if err := keyring.Delete(keyringService, key); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// KeyringAvailable reports whether the system keyring can be used, which fails e.g. on Linux
+// servers without D-Bus or a secret service
+func KeyringAvailable() bool {
+	_, err := keyring.Get(keyringService, keyringProbe)
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
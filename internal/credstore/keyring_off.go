@@ -0,0 +1,29 @@
+//go:build nokeyring
+
+package credstore
+
+import "github.com/cloudforet-io/cfctl/internal/features"
+
+// keyringStore stands in for the system keyring, which is left out of this build
+type keyringStore struct{}
+
+func (keyringStore) Name() string {
+	return Keyring
+}
+
+func (keyringStore) Get(key string) (string, error) {
+	return "", features.Unavailable(features.Keyring)
+}
+
+func (keyringStore) Set(key, value string) error {
+	return features.Unavailable(features.Keyring)
+}
+
+func (keyringStore) Delete(key string) error {
+	return features.Unavailable(features.Keyring)
+}
+
+// KeyringAvailable reports whether the system keyring can be used
+func KeyringAvailable() bool {
+	return false
+}
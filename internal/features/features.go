@@ -0,0 +1,56 @@
+// Package features lists the optional features of cfctl and whether they are compiled in.
+// Builds for automation leave them out with build tags, e.g.
+//
+//	go build -tags "notui nokeyring nosearch" .
+//
+// and commands check Enabled to fall back instead of failing. The tags drop eiannone/keyboard
+// and go-keyring; pterm links its own keyboard and fuzzy search libraries in every build.
+package features
+
+import "fmt"
+
+// Optional features
+const (
+	TUI     = "tui"
+	Keyring = "keyring"
+	Search  = "search"
+)
+
+// Feature is an optional feature and the build tag leaving it out
+type Feature struct {
+	Name        string
+	Description string
+	Tag         string
+	Enabled     bool
+}
+
+var registry = []Feature{
+	{Name: TUI, Description: "interactive result browser (--browse), keyboard driven selectors, prompts and table paging; the tag drops eiannone/keyboard", Tag: "notui", Enabled: tuiEnabled},
+	{Name: Keyring, Description: "system keyring as credential store; the tag drops go-keyring", Tag: "nokeyring", Enabled: keyringEnabled},
+	{Name: Search, Description: "fuzzy search in selectors; the tag drops no dependency, pterm links the search library", Tag: "nosearch", Enabled: searchEnabled},
+}
+
+// All returns the optional features in a stable order
+func All() []Feature {
+	return append([]Feature(nil), registry...)
+}
+
+// Enabled reports whether the feature is compiled into this binary
+func Enabled(name string) bool {
+	for _, feature := range registry {
+		if feature.Name == name {
+			return feature.Enabled
+		}
+	}
+	return false
+}
+
+// Unavailable returns the error explaining that the feature was left out of this build
+func Unavailable(name string) error {
+	for _, feature := range registry {
+		if feature.Name == name {
+			return fmt.Errorf("the %s is not included in this build of cfctl (built with -tags %s)", feature.Description, feature.Tag)
+		}
+	}
+	return fmt.Errorf("unknown feature: %s", name)
+}
@@ -0,0 +1,5 @@
+//go:build !nokeyring
+
+package features
+
+const keyringEnabled = true
@@ -0,0 +1,5 @@
+//go:build !nosearch
+
+package features
+
+const searchEnabled = true
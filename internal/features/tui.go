@@ -0,0 +1,5 @@
+//go:build !notui
+
+package features
+
+const tuiEnabled = true
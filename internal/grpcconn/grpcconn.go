@@ -0,0 +1,143 @@
+// Package grpcconn keeps one gRPC connection per endpoint for the whole process, so that
+// commands making several calls, like login, reuse a connection instead of dialing again.
+package grpcconn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"github.com/cloudforet-io/cfctl/internal/timing"
+	"github.com/cloudforet-io/cfctl/internal/tlsconf"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// maxMessageSize is the largest request or response accepted on the connections
+const maxMessageSize = 10 * 1024 * 1024
+
+// readyTimeout bounds the wait for a connection timed by --timings
+const readyTimeout = 10 * time.Second
+
+var (
+	mu    sync.Mutex
+	conns = make(map[connKey]*grpc.ClientConn)
+)
+
+// connKey identifies a connection by its endpoint and the proxy and tls settings it was opened
+// with, so that the environments of a pinned session do not share connections of another CA,
+// client certificate or proxy
+type connKey struct {
+	hostPort  string
+	plaintext bool
+	route     netproxy.Route
+}
+
+// Endpoint is the address of a gRPC server and whether it is reached without TLS
+type Endpoint struct {
+	HostPort  string
+	Plaintext bool
+}
+
+// ParseEndpoint parses endpoints like grpc+ssl://identity.example.com:443/v1 or grpc://localhost:50051.
+// Only grpc+ssl:// uses TLS, and the path after host:port is ignored.
+func ParseEndpoint(endpoint string) (Endpoint, error) {
+	scheme, rest, ok := strings.Cut(endpoint, "://")
+	if !ok || rest == "" {
+		return Endpoint{}, fmt.Errorf("invalid endpoint format: %s", endpoint)
+	}
+	hostPort, _, _ := strings.Cut(rest, "/")
+	return Endpoint{HostPort: hostPort, Plaintext: scheme != "grpc+ssl"}, nil
+}
+
+// Get returns the connection to hostPort with the proxy and tls settings of the current
+// environment, dialing it on first use. Connections are shared, so callers must not close them;
+// CloseAll closes them when the process ends.
+func Get(hostPort string, plaintext bool) (*grpc.ClientConn, error) {
+	route, err := netproxy.CurrentRoute()
+	if err != nil {
+		return nil, err
+	}
+	key := connKey{hostPort: hostPort, plaintext: plaintext, route: route}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if conn, ok := conns[key]; ok {
+		return conn, nil
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(maxMessageSize),
+			grpc.MaxCallSendMsgSize(maxMessageSize),
+		),
+		// Replaces the proxy support of grpc, which only knows HTTPS_PROXY
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return route.DialContext(ctx, addr, !plaintext)
+		}),
+	}
+	if plaintext {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		tlsConfig, err := tlsconf.ConfigFor(route.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	done := timing.Track(timing.Dial)
+	conn, err := grpc.Dial(hostPort, opts...)
+	if err != nil {
+		done()
+		return nil, fmt.Errorf("unable to connect to %s: %v", hostPort, err)
+	}
+	// Dialing is lazy, so the connection is only established up front when it is timed
+	if timing.Enabled() {
+		waitReady(conn)
+	}
+	done()
+	conns[key] = conn
+	return conn, nil
+}
+
+// waitReady waits until the connection is established or fails, for at most readyTimeout
+func waitReady(conn *grpc.ClientConn) {
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready || state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			return
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+// GetEndpoint returns the connection of a grpc:// or grpc+ssl:// endpoint
+func GetEndpoint(endpoint string) (*grpc.ClientConn, error) {
+	target, err := ParseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return Get(target.HostPort, target.Plaintext)
+}
+
+// CloseAll closes every connection opened by the process
+func CloseAll() {
+	mu.Lock()
+	defer mu.Unlock()
+	for key, conn := range conns {
+		conn.Close()
+		delete(conns, key)
+	}
+}
@@ -0,0 +1,144 @@
+package invoker
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"gopkg.in/yaml.v3"
+)
+
+// descriptorCache keeps the service names of a host and the descriptors resolved from it on disk:
+// <dir>/<service>.yaml lists the services and <service>.pb holds a FileDescriptorSet.
+// Both are dropped once the service list is older than the TTL.
+type descriptorCache struct {
+	dir     string
+	ttl     time.Duration
+	refresh bool
+}
+
+// descriptorIndex is the content of <service>.yaml
+type descriptorIndex struct {
+	Services []string `yaml:"services"`
+}
+
+// UseDescriptorCache makes the invoker reuse descriptors cached in dir for ttl instead of
+// asking the reflection API on every call. refresh ignores the cache and rebuilds it.
+func (i *Invoker) UseDescriptorCache(dir string, ttl time.Duration, refresh bool) {
+	i.cache = &descriptorCache{dir: dir, ttl: ttl, refresh: refresh}
+}
+
+// loadedDescriptors keeps the descriptors read from a cache directory for the rest of the
+// process, like 'cfctl shell', keyed by the path of the service list
+var loadedDescriptors sync.Map
+
+// loadedEntry is what was read from the cache, and the state of the files when it was read
+type loadedEntry struct {
+	indexModTime time.Time
+	pbModTime    time.Time
+	services     []string
+	files        map[string]*desc.FileDescriptor
+}
+
+// load returns the cached services and descriptors of the service, or nil when they are stale
+func (c *descriptorCache) load(serviceName string) ([]string, map[string]*desc.FileDescriptor) {
+	if c == nil || c.refresh {
+		return nil, nil
+	}
+
+	indexPath := filepath.Join(c.dir, serviceName+".yaml")
+	info, err := os.Stat(indexPath)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return nil, nil
+	}
+	var pbModTime time.Time
+	if pbInfo, err := os.Stat(filepath.Join(c.dir, serviceName+".pb")); err == nil {
+		pbModTime = pbInfo.ModTime()
+	}
+	if value, ok := loadedDescriptors.Load(indexPath); ok {
+		entry := value.(loadedEntry)
+		if entry.indexModTime.Equal(info.ModTime()) && entry.pbModTime.Equal(pbModTime) {
+			return entry.services, copyFiles(entry.files)
+		}
+	}
+
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, nil
+	}
+	var index descriptorIndex
+	if err := yaml.Unmarshal(data, &index); err != nil || len(index.Services) == 0 {
+		return nil, nil
+	}
+
+	files := make(map[string]*desc.FileDescriptor)
+	if data, err := os.ReadFile(filepath.Join(c.dir, serviceName+".pb")); err == nil {
+		var set descriptorpb.FileDescriptorSet
+		if proto.Unmarshal(data, &set) == nil && len(set.GetFile()) > 0 {
+			if created, err := desc.CreateFileDescriptorsFromSet(&set); err == nil {
+				files = created
+			}
+		}
+	}
+	loadedDescriptors.Store(indexPath, loadedEntry{
+		indexModTime: info.ModTime(),
+		pbModTime:    pbModTime,
+		services:     index.Services,
+		files:        files,
+	})
+	return index.Services, copyFiles(files)
+}
+
+// copyFiles copies the descriptor map, as callers add the descriptors they resolve to it
+func copyFiles(files map[string]*desc.FileDescriptor) map[string]*desc.FileDescriptor {
+	copied := make(map[string]*desc.FileDescriptor, len(files))
+	for name, fd := range files {
+		copied[name] = fd
+	}
+	return copied
+}
+
+// CachedDescriptors returns the services and descriptors of the service cached in dir however
+// old they are, for shell completion which must not wait for the server
+func CachedDescriptors(dir, serviceName string) ([]string, map[string]*desc.FileDescriptor) {
+	cache := &descriptorCache{dir: dir, ttl: time.Duration(math.MaxInt64)}
+	return cache.load(serviceName)
+}
+
+// save writes the descriptors, and the service list when it was just fetched which restarts the TTL
+func (c *descriptorCache) save(serviceName string, services []string, files map[string]*desc.FileDescriptor, listed bool) {
+	if c == nil {
+		return
+	}
+
+	if listed {
+		data, err := yaml.Marshal(descriptorIndex{Services: services})
+		if err != nil || configs.WriteSecureFile(filepath.Join(c.dir, serviceName+".yaml"), data) != nil {
+			return
+		}
+	}
+
+	fds := make([]*desc.FileDescriptor, 0, len(files))
+	for _, fd := range files {
+		fds = append(fds, fd)
+	}
+	if data, err := proto.Marshal(desc.ToFileDescriptorSet(fds...)); err == nil {
+		_ = configs.WriteSecureFile(filepath.Join(c.dir, serviceName+".pb"), data)
+	}
+}
+
+// findService looks up a service in cached descriptors
+func findService(files map[string]*desc.FileDescriptor, fullServiceName string) *desc.ServiceDescriptor {
+	for _, fd := range files {
+		if serviceDesc, ok := fd.FindSymbol(fullServiceName).(*desc.ServiceDescriptor); ok {
+			return serviceDesc
+		}
+	}
+	return nil
+}
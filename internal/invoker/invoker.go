@@ -0,0 +1,217 @@
+// Package invoker calls any SpaceONE API method by name using server reflection,
+// building requests and decoding responses as dynamic messages.
+package invoker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/grpcconn"
+	"github.com/cloudforet-io/cfctl/internal/timing"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// Invoker resolves and calls methods on one gRPC connection
+type Invoker struct {
+	conn      *grpc.ClientConn
+	ctx       context.Context
+	refClient *grpcreflect.Client
+	cache     *descriptorCache
+}
+
+// New returns an invoker using an established connection. Calls carry the token as metadata when given.
+func New(conn *grpc.ClientConn, token string) *Invoker {
+	ctx := context.Background()
+	if token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "token", token)
+	}
+	return &Invoker{
+		conn:      conn,
+		ctx:       ctx,
+		refClient: grpcreflect.NewClient(ctx, grpc_reflection_v1alpha.NewServerReflectionClient(conn)),
+	}
+}
+
+// Dial returns an invoker on the shared connection to hostPort, over TLS unless plaintext
+// is set for grpc:// endpoints
+func Dial(hostPort, token string, plaintext bool) (*Invoker, error) {
+	conn, err := grpcconn.Get(hostPort, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %v", err)
+	}
+	return New(conn, token), nil
+}
+
+// Close releases the reflection client. The connection stays open for later calls.
+func (i *Invoker) Close() {
+	i.refClient.Reset()
+}
+
+// FindService picks the full name of the gRPC service serving the resource, e.g.
+// 'spaceone.api.identity.v2.Project' for identity and Project. Plugin services take precedence.
+func FindService(services []string, serviceName, resourceName string) (string, error) {
+	for _, service := range services {
+		if strings.Contains(service, ".plugin.") && strings.HasSuffix(service, resourceName) {
+			return service, nil
+		}
+	}
+
+	for _, service := range services {
+		if strings.Contains(service, fmt.Sprintf("spaceone.api.%s", serviceName)) &&
+			strings.HasSuffix(service, resourceName) {
+			return service, nil
+		}
+	}
+
+	return "", fmt.Errorf("service not found for %s.%s", serviceName, resourceName)
+}
+
+// ResolveMethod returns the descriptor of the verb on the resource of the service.
+// With a descriptor cache, the reflection API is only asked for what is not cached yet.
+func (i *Invoker) ResolveMethod(serviceName, resourceName, verb string) (*desc.MethodDescriptor, error) {
+	defer timing.Track(timing.Reflection)()
+
+	services, files := i.cache.load(serviceName)
+	fullServiceName, err := FindService(services, serviceName, resourceName)
+
+	// Services added since the list was cached are only found by listing them again
+	listed := err != nil
+	if listed {
+		services, err = i.refClient.ListServices()
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover service: failed to list services: %v", err)
+		}
+		if files == nil {
+			files = make(map[string]*desc.FileDescriptor)
+		}
+		fullServiceName, err = FindService(services, serviceName, resourceName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover service: %v", err)
+		}
+	}
+
+	serviceDesc := findService(files, fullServiceName)
+	if serviceDesc == nil {
+		serviceDesc, err = i.refClient.ResolveService(fullServiceName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve service %s: %v", fullServiceName, err)
+		}
+		files[serviceDesc.GetFile().GetName()] = serviceDesc.GetFile()
+		i.cache.save(serviceName, services, files, listed)
+	}
+
+	methodDesc := serviceDesc.FindMethodByName(verb)
+	if methodDesc == nil {
+		return nil, fmt.Errorf("method not found: %s", verb)
+	}
+	return methodDesc, nil
+}
+
+// ResolveServiceMethod returns the descriptor of the verb of a service given by its full name,
+// e.g. 'spaceone.api.identity.v2.Token' and issue
+func (i *Invoker) ResolveServiceMethod(fullServiceName, verb string) (*desc.MethodDescriptor, error) {
+	defer timing.Track(timing.Reflection)()
+
+	serviceDesc, err := i.refClient.ResolveService(fullServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve service %s: %v", fullServiceName, err)
+	}
+	methodDesc := serviceDesc.FindMethodByName(verb)
+	if methodDesc == nil {
+		return nil, fmt.Errorf("method not found: %s", verb)
+	}
+	return methodDesc, nil
+}
+
+// NewRequest builds the request message of the method from parameters already converted
+// to their JSON form. It also returns the JSON body, used to print equivalent commands.
+func NewRequest(methodDesc *desc.MethodDescriptor, params map[string]interface{}) (*dynamic.Message, []byte, error) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal input parameters to JSON: %v", err)
+	}
+
+	reqMsg := dynamic.NewMessage(methodDesc.GetInputType())
+	if err := reqMsg.UnmarshalJSON(body); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal JSON into request message: %v", err)
+	}
+	return reqMsg, body, nil
+}
+
+// Call calls a unary method and discards the response, for measuring the call itself
+func (i *Invoker) Call(ctx context.Context, methodDesc *desc.MethodDescriptor, reqMsg *dynamic.Message) error {
+	if methodDesc.IsClientStreaming() || methodDesc.IsServerStreaming() {
+		return fmt.Errorf("streaming method %s is not supported", methodDesc.GetFullyQualifiedName())
+	}
+	if md, ok := metadata.FromOutgoingContext(i.ctx); ok {
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	fullMethod := fmt.Sprintf("/%s/%s", methodDesc.GetService().GetFullyQualifiedName(), methodDesc.GetName())
+	return i.conn.Invoke(ctx, fullMethod, reqMsg, dynamic.NewMessage(methodDesc.GetOutputType()))
+}
+
+// Invoke calls the method and returns the response as JSON. The responses of a server
+// streaming method are combined into {"results": [...]} unless there is only one.
+func (i *Invoker) Invoke(methodDesc *desc.MethodDescriptor, reqMsg *dynamic.Message) ([]byte, error) {
+	defer timing.Track(timing.RPC)()
+
+	fullMethod := fmt.Sprintf("/%s/%s", methodDesc.GetService().GetFullyQualifiedName(), methodDesc.GetName())
+
+	if methodDesc.IsClientStreaming() {
+		return nil, fmt.Errorf("client streaming method %s is not supported", fullMethod)
+	}
+
+	if !methodDesc.IsServerStreaming() {
+		respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
+		if err := i.conn.Invoke(i.ctx, fullMethod, reqMsg, respMsg); err != nil {
+			return nil, fmt.Errorf("failed to invoke method %s: %w", fullMethod, err)
+		}
+		return respMsg.MarshalJSON()
+	}
+
+	streamDesc := &grpc.StreamDesc{
+		StreamName:    methodDesc.GetName(),
+		ServerStreams: true,
+	}
+	stream, err := i.conn.NewStream(i.ctx, streamDesc, fullMethod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream: %v", err)
+	}
+	if err := stream.SendMsg(reqMsg); err != nil {
+		return nil, fmt.Errorf("failed to send request message: %v", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, fmt.Errorf("failed to close send: %v", err)
+	}
+
+	var responses []string
+	for {
+		respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
+		err := stream.RecvMsg(respMsg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive response: %v", err)
+		}
+
+		jsonBytes, err := respMsg.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal response: %v", err)
+		}
+		responses = append(responses, string(jsonBytes))
+	}
+
+	if len(responses) == 1 {
+		return []byte(responses[0]), nil
+	}
+	return []byte(fmt.Sprintf("{\"results\": [%s]}", strings.Join(responses, ","))), nil
+}
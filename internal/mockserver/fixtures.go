@@ -0,0 +1,144 @@
+package mockserver
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fixtures
+var bundled embed.FS
+
+// loadFixtures reads the bundled fixtures, then those of dir replacing files of the same name
+func loadFixtures(dir string) (map[string]*resource, error) {
+	files := make(map[string][]byte)
+	sub, err := fs.Sub(bundled, "fixtures")
+	if err != nil {
+		return nil, err
+	}
+	if err := readFixtures(sub, files); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixtures: %v", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("fixtures %s is not a directory", dir)
+		}
+		if err := readFixtures(os.DirFS(dir), files); err != nil {
+			return nil, err
+		}
+	}
+
+	resources := make(map[string]*resource)
+	for name, data := range files {
+		if err := addFixture(resources, name, data); err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// readFixtures reads the YAML and JSON files of <service>/ directories of fsys into files, by
+// their path
+func readFixtures(fsys fs.FS, files map[string][]byte) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.Count(name, "/") != 1 {
+			return nil
+		}
+		switch path.Ext(name) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files[strings.TrimSuffix(name, path.Ext(name))] = data
+		return nil
+	})
+}
+
+// addFixture adds the file <service>/<Resource>[.<verb>] to its resource
+func addFixture(resources map[string]*resource, name string, data []byte) error {
+	service, base, _ := strings.Cut(name, "/")
+	resourceName, verb, hasVerb := strings.Cut(base, ".")
+	if resourceName == "" || !unicode.IsUpper(rune(resourceName[0])) {
+		return fmt.Errorf("fixture %s: the file name must start with the resource, e.g. Workspace.yaml", name)
+	}
+
+	key := service + "." + resourceName
+	r, ok := resources[key]
+	if !ok {
+		r = &resource{
+			service:   service,
+			name:      resourceName,
+			idField:   snakeCase(resourceName) + "_id",
+			responses: make(map[string]map[string]interface{}),
+		}
+		resources[key] = r
+	}
+
+	var content interface{}
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		return fmt.Errorf("fixture %s: %v", name, err)
+	}
+	// A JSON round trip leaves only the types of google.protobuf.Struct, e.g. no time.Time
+	normalized, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("fixture %s: %v", name, err)
+	}
+
+	if hasVerb {
+		var response map[string]interface{}
+		if err := json.Unmarshal(normalized, &response); err != nil {
+			return fmt.Errorf("fixture %s: the response of %s must be an object", name, verb)
+		}
+		r.responses[verb] = response
+		return nil
+	}
+
+	var records []map[string]interface{}
+	if err := json.Unmarshal(normalized, &records); err != nil {
+		return fmt.Errorf("fixture %s: the records must be a list of objects", name)
+	}
+	if records == nil {
+		records = []map[string]interface{}{}
+	}
+	for _, record := range records {
+		if id, _ := record[r.idField].(string); id == "" {
+			record[r.idField] = newID(resourceName)
+		}
+	}
+	r.records = records
+	return nil
+}
+
+// snakeCase converts names like 'CloudService' to 'cloud_service'
+func snakeCase(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,325 @@
+// Package mockserver serves a SpaceONE-like gRPC API from fixture files, so that cfctl and
+// plugins can be developed without a cluster. Every resource becomes a service
+// spaceone.api.<service>.<version>.<Resource> exposed with server reflection, whose methods
+// take and return google.protobuf.Struct:
+//
+//	identity/Workspace.yaml       records served by list, get, create, update, delete and stat
+//	identity/UserProfile.get.yaml the fixed response of one method, which can be any verb
+//
+// A few identity and inventory resources are bundled; the files of a fixture directory are
+// added to them and replace the bundled files of the same name.
+package mockserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// crudVerbs are served for every resource with records
+var crudVerbs = []string{"list", "get", "create", "update", "delete", "stat"}
+
+// serviceVersions are the API versions of the services that are not at v1
+var serviceVersions = map[string]string{
+	"identity": "v2",
+}
+
+// Server is the mock server of the resources of a fixture directory
+type Server struct {
+	// Log is called after each call with the full method name, e.g.
+	// spaceone.api.identity.v2.Workspace/list
+	Log func(method string, err error, elapsed time.Duration)
+
+	grpcServer *grpc.Server
+	resources  map[string]*resource
+}
+
+// resource is a resource of a service with its records and fixed responses
+type resource struct {
+	service string
+	name    string
+	idField string
+
+	mu        sync.Mutex
+	records   []map[string]interface{}
+	responses map[string]map[string]interface{}
+}
+
+// New returns a server of the bundled fixtures and those of dir, which may be empty
+func New(dir string) (*Server, error) {
+	resources, err := loadFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		grpcServer: grpc.NewServer(),
+		resources:  resources,
+	}
+	files, err := s.register()
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := fallbackResolver{files, protoregistry.GlobalFiles}
+	reflectionServer := reflection.NewServerV1(reflection.ServerOptions{
+		Services:           s.grpcServer,
+		DescriptorResolver: resolver,
+	})
+	grpc_reflection_v1.RegisterServerReflectionServer(s.grpcServer, reflectionServer)
+	grpc_reflection_v1alpha.RegisterServerReflectionServer(s.grpcServer, reflection.NewServer(reflection.ServerOptions{
+		Services:           s.grpcServer,
+		DescriptorResolver: resolver,
+	}))
+	return s, nil
+}
+
+// Services returns the full names of the served services, sorted
+func (s *Server) Services() []string {
+	var names []string
+	for _, r := range s.resources {
+		names = append(names, r.fullName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Serve serves the API on lis until Stop is called
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpcServer.Serve(lis)
+}
+
+// Stop stops the server after the running calls finished
+func (s *Server) Stop() {
+	s.grpcServer.GracefulStop()
+}
+
+func (r *resource) packageName() string {
+	version, ok := serviceVersions[r.service]
+	if !ok {
+		version = "v1"
+	}
+	return fmt.Sprintf("spaceone.api.%s.%s", r.service, version)
+}
+
+func (r *resource) fullName() string {
+	return r.packageName() + "." + r.name
+}
+
+// verbs returns the methods of the resource, sorted
+func (r *resource) verbs() []string {
+	verbs := make(map[string]bool)
+	if r.records != nil {
+		for _, verb := range crudVerbs {
+			verbs[verb] = true
+		}
+	}
+	for verb := range r.responses {
+		verbs[verb] = true
+	}
+	sorted := make([]string, 0, len(verbs))
+	for verb := range verbs {
+		sorted = append(sorted, verb)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// register builds a proto file per service and registers the handlers of its resources
+func (s *Server) register() (*protoregistry.Files, error) {
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(structpb.File_google_protobuf_struct_proto); err != nil {
+		return nil, err
+	}
+
+	byPackage := make(map[string][]*resource)
+	for _, r := range s.resources {
+		byPackage[r.packageName()] = append(byPackage[r.packageName()], r)
+	}
+	packages := make([]string, 0, len(byPackage))
+	for pkg := range byPackage {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+
+	for _, pkg := range packages {
+		resources := byPackage[pkg]
+		sort.Slice(resources, func(i, j int) bool { return resources[i].name < resources[j].name })
+
+		fileProto := &descriptorpb.FileDescriptorProto{
+			Name:       ptr(strings.ReplaceAll(pkg, ".", "/") + "/mock.proto"),
+			Package:    ptr(pkg),
+			Dependency: []string{structpb.File_google_protobuf_struct_proto.Path()},
+			Syntax:     ptr("proto3"),
+		}
+		for _, r := range resources {
+			serviceProto := &descriptorpb.ServiceDescriptorProto{Name: ptr(r.name)}
+			serviceDesc := grpc.ServiceDesc{
+				ServiceName: r.fullName(),
+				HandlerType: (*interface{})(nil),
+				Metadata:    fileProto.GetName(),
+			}
+			for _, verb := range r.verbs() {
+				serviceProto.Method = append(serviceProto.Method, &descriptorpb.MethodDescriptorProto{
+					Name:       ptr(verb),
+					InputType:  ptr(".google.protobuf.Struct"),
+					OutputType: ptr(".google.protobuf.Struct"),
+				})
+				serviceDesc.Methods = append(serviceDesc.Methods, grpc.MethodDesc{
+					MethodName: verb,
+					Handler:    s.handler(r, verb),
+				})
+			}
+			fileProto.Service = append(fileProto.Service, serviceProto)
+			s.grpcServer.RegisterService(&serviceDesc, struct{}{})
+		}
+
+		file, err := protodesc.NewFile(fileProto, files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build the descriptors of %s: %v", pkg, err)
+		}
+		if err := files.RegisterFile(file); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// handler returns the gRPC handler of a method of the resource
+func (s *Server) handler(r *resource, verb string) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	method := r.fullName() + "/" + verb
+	return func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		start := time.Now()
+		request := new(structpb.Struct)
+		err := dec(request)
+		var response *structpb.Struct
+		if err == nil {
+			response, err = r.respond(verb, request.AsMap())
+		}
+		if s.Log != nil {
+			s.Log(method, err, time.Since(start))
+		}
+		return response, err
+	}
+}
+
+// respond answers a method, converting the response while the records are locked
+func (r *resource) respond(verb string, params map[string]interface{}) (*structpb.Struct, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	response, err := r.call(verb, params)
+	if err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(response)
+}
+
+// call answers a method with its fixed response, or else from the records
+func (r *resource) call(verb string, params map[string]interface{}) (map[string]interface{}, error) {
+	if response, ok := r.responses[verb]; ok {
+		return response, nil
+	}
+
+	switch verb {
+	case "list":
+		results, total := r.list(params)
+		return map[string]interface{}{"results": results, "total_count": total}, nil
+	case "stat":
+		_, total := r.list(params)
+		return map[string]interface{}{"total_count": total}, nil
+	case "get":
+		i, err := r.find(params)
+		if err != nil {
+			return nil, err
+		}
+		return r.records[i], nil
+	case "create":
+		record := make(map[string]interface{}, len(params))
+		for key, value := range params {
+			record[key] = value
+		}
+		if id, _ := record[r.idField].(string); id == "" {
+			record[r.idField] = newID(r.name)
+		} else if _, err := r.find(record); err == nil {
+			return nil, status.Errorf(codes.AlreadyExists, "ERROR_ALREADY_EXIST: %s = %s", r.idField, id)
+		}
+		if _, ok := record["created_at"]; !ok {
+			record["created_at"] = time.Now().UTC().Format(time.RFC3339)
+		}
+		r.records = append(r.records, record)
+		return record, nil
+	case "update":
+		i, err := r.find(params)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range params {
+			r.records[i][key] = value
+		}
+		return r.records[i], nil
+	case "delete":
+		i, err := r.find(params)
+		if err != nil {
+			return nil, err
+		}
+		r.records = append(r.records[:i], r.records[i+1:]...)
+		return map[string]interface{}{}, nil
+	}
+	return nil, status.Errorf(codes.Unimplemented, "ERROR_NOT_IMPLEMENTED: %s", verb)
+}
+
+// find returns the index of the record with the ID of params
+func (r *resource) find(params map[string]interface{}) (int, error) {
+	id, _ := params[r.idField].(string)
+	if id == "" {
+		return -1, status.Errorf(codes.InvalidArgument, "ERROR_REQUIRED_PARAMETER: %s", r.idField)
+	}
+	for i, record := range r.records {
+		if record[r.idField] == id {
+			return i, nil
+		}
+	}
+	return -1, status.Errorf(codes.NotFound, "ERROR_NOT_FOUND: %s = %s", r.idField, id)
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+// fallbackResolver looks descriptors up in each resolver in turn
+type fallbackResolver []protodesc.Resolver
+
+func (f fallbackResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	for _, resolver := range f {
+		if file, err := resolver.FindFileByPath(path); err == nil {
+			return file, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (f fallbackResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	for _, resolver := range f {
+		if d, err := resolver.FindDescriptorByName(name); err == nil {
+			return d, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
@@ -0,0 +1,214 @@
+package mockserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// pagingParams are the parameters of list that page the results instead of filtering them
+var pagingParams = map[string]bool{"query": true, "page": true, "page_size": true}
+
+// list returns the records matching params, paged and sorted by the query, and the number of
+// matching records. Top level parameters must equal the fields of the records, and the query
+// supports filter with the eq, not, in, not_in and contain operators, keyword, sort, page
+// and count_only.
+func (r *resource) list(params map[string]interface{}) ([]interface{}, int) {
+	query, _ := params["query"].(map[string]interface{})
+
+	var matched []map[string]interface{}
+	for _, record := range r.records {
+		if matchRecord(record, params, query) {
+			matched = append(matched, record)
+		}
+	}
+	total := len(matched)
+
+	if sorts, ok := query["sort"].([]interface{}); ok {
+		sortRecords(matched, sorts)
+	} else if sortQuery, ok := query["sort"].(map[string]interface{}); ok {
+		sortRecords(matched, []interface{}{sortQuery})
+	}
+
+	if countOnly, _ := query["count_only"].(bool); countOnly {
+		return []interface{}{}, total
+	}
+
+	start, limit := 1, 0
+	if page, ok := query["page"].(map[string]interface{}); ok {
+		start, limit = intValue(page["start"], 1), intValue(page["limit"], 0)
+	} else if pageSize := intValue(params["page_size"], 0); pageSize > 0 {
+		start, limit = (intValue(params["page"], 1)-1)*pageSize+1, pageSize
+	}
+	if start < 1 {
+		start = 1
+	}
+	if start > len(matched) {
+		matched = nil
+	} else {
+		matched = matched[start-1:]
+	}
+	if limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+
+	results := make([]interface{}, 0, len(matched))
+	for _, record := range matched {
+		results = append(results, record)
+	}
+	return results, total
+}
+
+func matchRecord(record, params, query map[string]interface{}) bool {
+	for key, value := range params {
+		if pagingParams[key] {
+			continue
+		}
+		if !equalValue(lookup(record, key), value) {
+			return false
+		}
+	}
+
+	filters, _ := query["filter"].([]interface{})
+	for _, f := range filters {
+		condition, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := condition["k"].(string)
+		if key == "" {
+			key, _ = condition["key"].(string)
+		}
+		value, ok := condition["v"]
+		if !ok {
+			value = condition["value"]
+		}
+		operator, _ := condition["o"].(string)
+		if operator == "" {
+			operator, _ = condition["operator"].(string)
+		}
+		if !matchCondition(lookup(record, key), value, operator) {
+			return false
+		}
+	}
+
+	if keyword, _ := query["keyword"].(string); keyword != "" && !containsKeyword(record, strings.ToLower(keyword)) {
+		return false
+	}
+	return true
+}
+
+func matchCondition(field, value interface{}, operator string) bool {
+	switch operator {
+	case "", "eq":
+		return equalValue(field, value)
+	case "not":
+		return !equalValue(field, value)
+	case "in", "not_in":
+		values, _ := value.([]interface{})
+		in := false
+		for _, v := range values {
+			if equalValue(field, v) {
+				in = true
+				break
+			}
+		}
+		return in == (operator == "in")
+	case "contain":
+		return strings.Contains(strings.ToLower(fmt.Sprint(field)), strings.ToLower(fmt.Sprint(value)))
+	}
+	// Operators the mock does not know about filter nothing out
+	return true
+}
+
+// lookup returns the value of a dotted key of the record, e.g. data.region
+func lookup(record map[string]interface{}, key string) interface{} {
+	var value interface{} = record
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[part]
+	}
+	return value
+}
+
+// equalValue compares values loosely, as parameters given on the command line may be strings
+func equalValue(a, b interface{}) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func containsKeyword(value interface{}, keyword string) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.Contains(strings.ToLower(v), keyword)
+	case map[string]interface{}:
+		for _, item := range v {
+			if containsKeyword(item, keyword) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if containsKeyword(item, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortRecords sorts by the keys of the sort query, the first key taking precedence
+func sortRecords(records []map[string]interface{}, sorts []interface{}) {
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, s := range sorts {
+			condition, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key, _ := condition["key"].(string)
+			desc, _ := condition["desc"].(bool)
+			a, b := lookup(records[i], key), lookup(records[j], key)
+			if equalValue(a, b) {
+				continue
+			}
+			if desc {
+				return lessValue(b, a)
+			}
+			return lessValue(a, b)
+		}
+		return false
+	})
+}
+
+func lessValue(a, b interface{}) bool {
+	x, xok := a.(float64)
+	y, yok := b.(float64)
+	if xok && yok {
+		return x < y
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+func intValue(value interface{}, fallback int) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case string:
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+// newID returns an ID in the form of SpaceONE, e.g. workspace-1f2e3d4c5b6a for Workspace
+func newID(resourceName string) string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return strings.ReplaceAll(snakeCase(resourceName), "_", "-") + "-" + hex.EncodeToString(b)
+}
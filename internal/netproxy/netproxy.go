@@ -0,0 +1,279 @@
+// Package netproxy routes the gRPC and HTTP connections of cfctl through an HTTP or SOCKS5
+// proxy: the network_proxy of the current environment, or else the one of HTTPS_PROXY,
+// HTTP_PROXY or ALL_PROXY, except for the hosts of NO_PROXY and localhost. gRPC connections
+// go through HTTP proxies by CONNECT tunneling, so TLS stays end to end.
+package netproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/tlsconf"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// Direct is the network_proxy value connecting directly even when a proxy is set by the
+// environment variables
+const Direct = "none"
+
+// schemes are the proxy URL schemes that can be dialed, with their default port
+var schemes = map[string]string{"http": "80", "https": "443", "socks5": "1080", "socks5h": "1080"}
+
+var (
+	mu     sync.Mutex
+	source func() string
+)
+
+// SetSource sets the function returning the network_proxy of the current environment. It is
+// called on each connection, so that the setting is read once it is loaded.
+func SetSource(fn func() string) {
+	mu.Lock()
+	defer mu.Unlock()
+	source = fn
+}
+
+// configured returns the proxy set by the setting, if any
+func configured() string {
+	mu.Lock()
+	fn := source
+	mu.Unlock()
+	if fn == nil {
+		return ""
+	}
+	return fn()
+}
+
+// Validate checks a network_proxy value. An empty value is accepted and means the environment
+// variables decide.
+func Validate(rawURL string) error {
+	if rawURL == "" || rawURL == Direct {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	if _, ok := schemes[parsed.Scheme]; !ok || parsed.Host == "" {
+		return fmt.Errorf("proxy '%s' is not supported, use a URL like http://proxy.example.com:3128 or socks5://localhost:1080, or %s", parsed.Redacted(), Direct)
+	}
+	return nil
+}
+
+// ForURL returns the proxy to reach target through, or nil to connect directly
+func ForURL(target *url.URL) (*url.URL, error) {
+	return forURL(target, configured())
+}
+
+// forURL returns the proxy to reach target through with the network_proxy setting
+func forURL(target *url.URL, setting string) (*url.URL, error) {
+	config := httpproxy.FromEnvironment()
+	allProxy := os.Getenv("ALL_PROXY")
+	if allProxy == "" {
+		allProxy = os.Getenv("all_proxy")
+	}
+	if config.HTTPSProxy == "" {
+		config.HTTPSProxy = allProxy
+	}
+	if config.HTTPProxy == "" {
+		config.HTTPProxy = allProxy
+	}
+
+	switch setting {
+	case "":
+	case Direct:
+		return nil, nil
+	default:
+		if err := Validate(setting); err != nil {
+			return nil, fmt.Errorf("network_proxy: %v", err)
+		}
+		config.HTTPSProxy, config.HTTPProxy = setting, setting
+	}
+	return config.ProxyFunc()(target)
+}
+
+// Route is what a connection goes through: the network_proxy setting and the tls settings of
+// an HTTPS proxy. Connections kept for later are dialed with the route of the environment they
+// were opened for, not the one current when they reconnect.
+type Route struct {
+	Proxy string
+	TLS   tlsconf.Settings
+}
+
+// CurrentRoute returns the route of the current environment
+func CurrentRoute() (Route, error) {
+	settings, err := tlsconf.Current()
+	if err != nil {
+		return Route{}, err
+	}
+	return Route{Proxy: configured(), TLS: settings}, nil
+}
+
+// DialContext connects to the host:port address through the proxy of the route, if any.
+// secure tells whether TLS is used over the connection, which selects HTTPS_PROXY or HTTP_PROXY.
+func (r Route) DialContext(ctx context.Context, addr string, secure bool) (net.Conn, error) {
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+	proxyURL, err := forURL(&url.URL{Scheme: scheme, Host: addr}, r.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	if proxyURL == nil {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	proxyAddr, err := hostPort(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if user := proxyURL.User; user != nil {
+			password, _ := user.Password()
+			auth = &proxy.Auth{User: user.Username(), Password: password}
+		}
+		socks, err := proxy.SOCKS5("tcp", proxyAddr, auth, &dialer)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := socks.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("proxy %s: %v", proxyAddr, err)
+		}
+		return conn, nil
+	default:
+		return connect(ctx, &dialer, proxyURL, proxyAddr, addr, r.TLS)
+	}
+}
+
+// connect opens a tunnel to addr through the HTTP proxy with a CONNECT request
+func connect(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, proxyAddr, addr string, tlsSettings tlsconf.Settings) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %v", proxyAddr, err)
+	}
+	if proxyURL.Scheme == "https" {
+		// Proxies of private networks are often signed by the same CA as the endpoints
+		tlsConfig, err := tlsconf.ConfigFor(tlsSettings)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = proxyURL.Hostname()
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s: %v", proxyAddr, err)
+		}
+		conn = tlsConn
+	}
+
+	// The deadline of ctx bounds the CONNECT exchange, not the tunnel
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %v", proxyAddr, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %v", proxyAddr, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused the tunnel to %s: %s", proxyAddr, addr, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// hostPort returns the address of the proxy, with the default port of its scheme when it has none
+func hostPort(proxyURL *url.URL) (string, error) {
+	defaultPort, ok := schemes[proxyURL.Scheme]
+	if !ok {
+		return "", fmt.Errorf("proxy scheme '%s' is not supported, use http, https or socks5", proxyURL.Scheme)
+	}
+	if port := proxyURL.Port(); port != "" {
+		return proxyURL.Host, nil
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), defaultPort), nil
+}
+
+// bufferedConn reads the bytes the proxy sent after its response before the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+var (
+	transportsMu sync.Mutex
+	transports   = make(map[*tls.Config]*http.Transport)
+)
+
+// roundTripper sends requests with the transport of the current TLS settings
+type roundTripper struct{}
+
+func (roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	tlsConfig, err := tlsconf.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	transportsMu.Lock()
+	t, ok := transports[tlsConfig]
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = func(req *http.Request) (*url.URL, error) {
+			return ForURL(req.URL)
+		}
+		t.TLSClientConfig = tlsConfig.Clone()
+		transports[tlsConfig] = t
+	}
+	transportsMu.Unlock()
+	return t.RoundTrip(req)
+}
+
+// Transport returns the shared HTTP transport going through the proxy of each request, with
+// the TLS settings of tlsconf
+func Transport() http.RoundTripper {
+	return roundTripper{}
+}
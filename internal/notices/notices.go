@@ -0,0 +1,179 @@
+// Package notices announces deprecated commands, flags and setting keys when they are used, so
+// that breaking changes can be rolled out over several releases. Every deprecation is registered
+// here with what replaces it, listed by 'cfctl notices' and emitted at most once per command,
+// as a warning or as a JSON line on stderr for scripts.
+package notices
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Kinds of deprecated things
+const (
+	KindCommand = "command"
+	KindFlag    = "flag"
+	KindSetting = "setting"
+)
+
+// EnvVarFormat selects how notices are emitted: text (default), json or off
+const EnvVarFormat = "CFCTL_NOTICES"
+
+// Notice is a deprecation and what replaces it
+type Notice struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Name is the command, the flag as "<command path> --<flag>", or the dotted setting key with *
+	// for any name, e.g. environments.*.url
+	Name        string `json:"name"`
+	Replacement string `json:"replacement,omitempty"`
+	// Since is the version deprecating it and Removal the one removing it, when planned
+	Since   string `json:"since,omitempty"`
+	Removal string `json:"removal,omitempty"`
+	// Message explains the change further, e.g. how to migrate
+	Message string `json:"message,omitempty"`
+}
+
+// registry lists the active deprecations
+var registry = []Notice{
+	{
+		ID:          "command.config",
+		Kind:        KindCommand,
+		Name:        "cfctl config",
+		Replacement: "cfctl setting",
+		Message:     "Its subcommands work the same under setting.",
+	},
+	{
+		ID:          "flag.login.no-save-credentials",
+		Kind:        KindFlag,
+		Name:        "cfctl login --no-save-credentials",
+		Replacement: "cfctl login --no-save",
+		Message:     "--no-save keeps no refresh token either, 'credential_cache' sets it for good.",
+	},
+	{
+		ID:          "setting.save_credentials",
+		Kind:        KindSetting,
+		Name:        "environments.*.save_credentials",
+		Replacement: "environments.*.credential_cache",
+		Message:     "'credential_cache: none' keeps neither the user entry nor the refresh token.",
+	},
+}
+
+var (
+	mu      sync.Mutex
+	emitted = map[string]bool{}
+)
+
+// All returns the active deprecations ordered by ID
+func All() []Notice {
+	all := append([]Notice(nil), registry...)
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all
+}
+
+// Lookup returns the notice of id
+func Lookup(id string) (Notice, bool) {
+	for _, notice := range registry {
+		if notice.ID == id {
+			return notice, true
+		}
+	}
+	return Notice{}, false
+}
+
+// Emit writes the notice of id unless it was already emitted by this command or notices are off
+func Emit(id string) {
+	notice, ok := Lookup(id)
+	if !ok {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if emitted[id] {
+		return
+	}
+	emitted[id] = true
+
+	switch strings.ToLower(os.Getenv(EnvVarFormat)) {
+	case "off":
+	case "json":
+		line, err := json.Marshal(struct {
+			Type string `json:"type"`
+			Notice
+		}{Type: "deprecation", Notice: notice})
+		if err == nil {
+			fmt.Fprintln(os.Stderr, string(line))
+		}
+	default:
+		pterm.Warning.WithWriter(os.Stderr).Println(notice.Text())
+	}
+}
+
+// Text is the notice as a sentence for people
+func (n Notice) Text() string {
+	text := fmt.Sprintf("%s %q is deprecated", strings.ToUpper(n.Kind[:1])+n.Kind[1:], n.Name)
+	if n.Removal != "" {
+		text += fmt.Sprintf(" and will be removed in %s", n.Removal)
+	}
+	if n.Replacement != "" {
+		text += fmt.Sprintf(", use %q instead", n.Replacement)
+	}
+	text += "."
+	if n.Message != "" {
+		text += " " + n.Message
+	}
+	return text
+}
+
+// CheckFlags emits the notices of the deprecated flags set on cmd
+func CheckFlags(cmd *cobra.Command) {
+	for _, notice := range registry {
+		if notice.Kind != KindFlag {
+			continue
+		}
+		path, flag, ok := strings.Cut(notice.Name, " --")
+		if !ok || path != cmd.CommandPath() {
+			continue
+		}
+		if f := cmd.Flags().Lookup(flag); f != nil && f.Changed {
+			Emit(notice.ID)
+		}
+	}
+}
+
+// CheckSetting emits the notices of the deprecated keys among the dotted keys of the settings
+func CheckSetting(keys []string) {
+	for _, notice := range registry {
+		if notice.Kind != KindSetting {
+			continue
+		}
+		pattern := settingPattern(notice.Name)
+		for _, key := range keys {
+			if pattern.MatchString(key) {
+				Emit(notice.ID)
+				break
+			}
+		}
+	}
+}
+
+// settingPattern matches a key and the keys under it, * standing for one name
+func settingPattern(name string) *regexp.Regexp {
+	parts := strings.Split(strings.ToLower(name), ".")
+	for i, part := range parts {
+		if part == "*" {
+			parts[i] = `[^.]+`
+		} else {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+	}
+	return regexp.MustCompile(`^` + strings.Join(parts, `\.`) + `(\..+)?$`)
+}
@@ -0,0 +1,228 @@
+// Package telemetry reports anonymous usage of cfctl to help the maintainers prioritize. It is
+// strictly opt-in: nothing is recorded or sent unless telemetry is turned on with
+// 'cfctl telemetry on --endpoint <url>'. There is no built-in endpoint, the events only go
+// where they are configured to. Events hold the command name, the version, the OS and the duration,
+// never arguments, resource names, endpoints or any identifier of the user or the machine.
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/spf13/viper"
+)
+
+// Environment variables overriding the setting
+const (
+	EnvVarTelemetry = "CFCTL_TELEMETRY"
+	EnvVarEndpoint  = "CFCTL_TELEMETRY_ENDPOINT"
+	// EnvVarDoNotTrack is the convention of https://consoledonottrack.com, always respected
+	EnvVarDoNotTrack = "DO_NOT_TRACK"
+)
+
+// Keys of setting.yaml turning telemetry on and naming the endpoint receiving the events
+const (
+	SettingKey         = "telemetry"
+	EndpointSettingKey = "telemetry_endpoint"
+)
+
+const (
+	// sendInterval is how often the queued events are sent
+	sendInterval = 24 * time.Hour
+	// sendTimeout bounds the time a command may spend sending the events
+	sendTimeout = 2 * time.Second
+	// maxQueued is the number of events kept when they cannot be sent, the oldest are dropped
+	maxQueued = 500
+)
+
+// Start approximates the start of the process, as packages are initialized first
+var Start = time.Now()
+
+// Event is all that is reported about a command
+type Event struct {
+	Command    string `json:"command"`
+	Version    string `json:"version"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	DurationMs int64  `json:"duration_ms"`
+	Success    bool   `json:"success"`
+}
+
+// Payload is the body sent to the endpoint
+type Payload struct {
+	Events []Event `json:"events"`
+}
+
+// NewEvent returns the event of a command, named by its path like "cfctl setting show"
+func NewEvent(command, version string, duration time.Duration, success bool) Event {
+	return Event{
+		Command:    command,
+		Version:    version,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		DurationMs: duration.Milliseconds(),
+		Success:    success,
+	}
+}
+
+// Status reports whether telemetry is on and what decided it: DO_NOT_TRACK, CFCTL_TELEMETRY,
+// the telemetry key of the setting, or the default of being off. Telemetry turned on without an
+// endpoint stays off.
+func Status(v *viper.Viper) (bool, string) {
+	enabled, reason := status(v)
+	if enabled && Endpoint(v) == "" {
+		return false, fmt.Sprintf("no endpoint is configured, set %s or '%s' in setting.yaml", EnvVarEndpoint, EndpointSettingKey)
+	}
+	return enabled, reason
+}
+
+// status is Status regardless of the endpoint
+func status(v *viper.Viper) (bool, string) {
+	if value := os.Getenv(EnvVarDoNotTrack); value != "" && value != "0" {
+		return false, EnvVarDoNotTrack + " is set"
+	}
+	if value := os.Getenv(EnvVarTelemetry); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			enabled = !strings.EqualFold(value, "off")
+		}
+		return enabled, fmt.Sprintf("%s=%s", EnvVarTelemetry, value)
+	}
+	if v != nil && v.IsSet(SettingKey) {
+		return v.GetBool(SettingKey), fmt.Sprintf("'%s' in setting.yaml", SettingKey)
+	}
+	return false, "off by default"
+}
+
+// Endpoint returns where the events are sent: CFCTL_TELEMETRY_ENDPOINT, or else the
+// telemetry_endpoint of the setting. It is empty when neither is set, and nothing is sent then.
+func Endpoint(v *viper.Viper) string {
+	if endpoint := os.Getenv(EnvVarEndpoint); endpoint != "" {
+		return endpoint
+	}
+	if v != nil {
+		return v.GetString(EndpointSettingKey)
+	}
+	return ""
+}
+
+// queuePath returns the file of the events not sent yet
+func queuePath() string {
+	return filepath.Join(configs.CacheDir(""), "telemetry.jsonl")
+}
+
+// sentPath returns the file whose modification time is the last time events were sent
+func sentPath() string {
+	return filepath.Join(configs.CacheDir(""), "telemetry.sent")
+}
+
+// Pending returns the queued events that the next send would report
+func Pending() ([]Event, error) {
+	f, err := os.Open(queuePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if json.Unmarshal(scanner.Bytes(), &event) == nil {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Record queues event and sends the queue once a day. It does nothing when telemetry is off,
+// and failures are never reported, so that telemetry cannot break a command.
+func Record(v *viper.Viper, event Event) {
+	if enabled, _ := Status(v); !enabled {
+		return
+	}
+
+	events, _ := Pending()
+	events = append(events, event)
+	if len(events) > maxQueued {
+		events = events[len(events)-maxQueued:]
+	}
+	if info, err := os.Stat(sentPath()); err == nil && time.Since(info.ModTime()) < sendInterval {
+		_ = writeQueue(events)
+		return
+	}
+	if err := send(Endpoint(v), events); err != nil {
+		_ = writeQueue(events)
+		return
+	}
+	_ = writeQueue(nil)
+}
+
+// send posts events to the endpoint and notes the time on success
+func send(endpoint string, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(Payload{Events: events})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: sendTimeout, Transport: netproxy.Transport()}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sentPath()), 0700); err != nil {
+		return nil
+	}
+	_ = os.WriteFile(sentPath(), nil, 0600)
+	_ = os.Chtimes(sentPath(), time.Now(), time.Now())
+	return nil
+}
+
+// Clear drops the queued events, when telemetry is turned off
+func Clear() error {
+	if err := os.Remove(queuePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func writeQueue(events []Event) error {
+	if len(events) == 0 {
+		return Clear()
+	}
+	var buf bytes.Buffer
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(queuePath()), 0700); err != nil {
+		return err
+	}
+	return configs.WriteFileAtomic(queuePath(), buf.Bytes(), configs.SecureFileMode)
+}
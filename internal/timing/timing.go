@@ -0,0 +1,107 @@
+// Package timing measures where the time of a command goes, for the --timings summary.
+package timing
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Phases reported by the summary, in the order they usually happen
+const (
+	Config     = "config load"
+	Dial       = "dial"
+	Reflection = "reflection"
+	RPC        = "rpc"
+	Render     = "render"
+)
+
+var phaseOrder = []string{Config, Dial, Reflection, RPC, Render}
+
+// processStart approximates the start of the process, as packages are initialized first
+var processStart = time.Now()
+
+var (
+	mu       sync.Mutex
+	enabled  bool
+	enableAt time.Time
+	totals   = make(map[string]time.Duration)
+	counts   = make(map[string]int)
+)
+
+// Enable starts recording. Phases tracked before are not recorded, and the time since
+// the process started is reported as startup.
+func Enable() {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = true
+	enableAt = time.Now()
+}
+
+// Enabled reports whether timings are recorded
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return enabled
+}
+
+// Track starts timing a phase and returns the function ending it, e.g. defer timing.Track(timing.RPC)().
+// Concurrent calls of a phase add up, so its time may exceed the wall time.
+func Track(phase string) func() {
+	if !Enabled() {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		mu.Lock()
+		defer mu.Unlock()
+		totals[phase] += elapsed
+		counts[phase]++
+	}
+}
+
+// Report writes the time of each phase, the startup and the rest of the wall time
+func Report(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !enabled {
+		return
+	}
+
+	total := time.Since(processStart)
+	startup := enableAt.Sub(processStart)
+	tableData := pterm.TableData{
+		{"Phase", "Calls", "Time", "Share"},
+		{"startup", "", formatDuration(startup), share(startup, total)},
+	}
+
+	tracked := startup
+	for _, phase := range phaseOrder {
+		if counts[phase] == 0 {
+			continue
+		}
+		tracked += totals[phase]
+		tableData = append(tableData, []string{phase, fmt.Sprint(counts[phase]), formatDuration(totals[phase]), share(totals[phase], total)})
+	}
+	if other := total - tracked; other > 0 {
+		tableData = append(tableData, []string{"other", "", formatDuration(other), share(other, total)})
+	}
+	tableData = append(tableData, []string{"total", "", formatDuration(total), "100%"})
+
+	pterm.DefaultTable.WithHasHeader().WithWriter(w).WithData(tableData).Render()
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+func share(d, total time.Duration) string {
+	if total <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f%%", float64(d)/float64(total)*100)
+}
@@ -0,0 +1,111 @@
+// Package tlsconf builds the TLS configuration of the gRPC and HTTPS connections from the tls
+// settings of the current environment: a CA bundle trusted in addition to the system roots, a
+// client certificate for mutual TLS, and whether server certificates are verified at all.
+package tlsconf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Settings are the tls settings of an environment, with absolute file paths
+type Settings struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+var (
+	mu     sync.Mutex
+	source func() (Settings, error)
+	built  = make(map[Settings]*tls.Config)
+)
+
+// SetSource sets the function returning the tls settings of the current environment. It is
+// called on each connection, so that the setting is read once it is loaded.
+func SetSource(fn func() (Settings, error)) {
+	mu.Lock()
+	defer mu.Unlock()
+	source = fn
+}
+
+// Current returns the tls settings of the current environment
+func Current() (Settings, error) {
+	mu.Lock()
+	fn := source
+	mu.Unlock()
+
+	if fn == nil {
+		return Settings{}, nil
+	}
+	return fn()
+}
+
+// Config returns the TLS configuration of the current environment. Callers must not change it.
+func Config() (*tls.Config, error) {
+	settings, err := Current()
+	if err != nil {
+		return nil, err
+	}
+	return ConfigFor(settings)
+}
+
+// ConfigFor returns the TLS configuration of the settings, built once per settings. Callers
+// must not change it.
+func ConfigFor(settings Settings) (*tls.Config, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if config, ok := built[settings]; ok {
+		return config, nil
+	}
+	config, err := Build(settings)
+	if err != nil {
+		return nil, err
+	}
+	built[settings] = config
+	return config, nil
+}
+
+// Validate checks the settings that do not need the files to be read
+func (s Settings) Validate() error {
+	if (s.CertFile == "") != (s.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// Build reads the files of the settings into a TLS configuration
+func Build(s Settings) (*tls.Config, error) {
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("tls: %v", err)
+	}
+	config := &tls.Config{InsecureSkipVerify: s.InsecureSkipVerify}
+
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls.ca_file: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls.ca_file %s has no PEM certificate", s.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if s.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls.cert_file and tls.key_file: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
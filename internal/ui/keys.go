@@ -0,0 +1,184 @@
+//go:build !notui
+
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/eiannone/keyboard"
+	"github.com/pterm/pterm"
+)
+
+// keysEnabled tells whether the components can be driven by single keys
+const keysEnabled = true
+
+// selectKeys is the keyboard driven selector redrawing the screen after every key
+func selectKeys(title string, labels []string, selected, pageSize int) (int, error) {
+	if err := keyboard.Open(); err != nil {
+		return 0, fmt.Errorf("failed to initialize keyboard: %v", err)
+	}
+	defer keyboard.Close()
+
+	searchMode := false
+	searchTerm := ""
+	number := ""
+	for {
+		visible := filterLabels(labels, searchTerm)
+		cursor := 0
+		for i, index := range visible {
+			if index == selected {
+				cursor = i
+			}
+		}
+		page := cursor / pageSize
+		totalPages := max((len(visible)+pageSize-1)/pageSize, 1)
+		start := page * pageSize
+		end := min(start+pageSize, len(visible))
+
+		fmt.Print("\033[H\033[2J")
+		header := pterm.DefaultHeader.WithFullWidth().
+			WithBackgroundStyle(pterm.NewStyle(pterm.BgDarkGray)).
+			WithTextStyle(pterm.NewStyle(pterm.FgLightWhite))
+		if totalPages > 1 {
+			header.Printf("%s (Page %d of %d)", title, page+1, totalPages)
+		} else {
+			header.Println(title)
+		}
+
+		for i := start; i < end; i++ {
+			marker := " "
+			if i == cursor {
+				marker = "→"
+			}
+			pterm.Printf("%s %d: %s\n", marker, visible[i]+1, labels[visible[i]])
+		}
+		if len(visible) == 0 {
+			pterm.DefaultBasicText.WithStyle(pterm.NewStyle(pterm.FgGray)).Println("  No matches")
+		}
+
+		help := "\nNavigation: [j]down [k]up [Enter]select [/]search [q]uit"
+		if totalPages > 1 {
+			help = "\nNavigation: [h]prev-page [j]down [k]up [l]next-page [Enter]select [/]search [q]uit"
+		}
+		pterm.DefaultBasicText.WithStyle(pterm.NewStyle(pterm.FgGray)).Println(help)
+		switch {
+		case searchMode:
+			pterm.Info.Printf("Search (Esc to clear, Enter to confirm): %s", searchTerm)
+		case searchTerm != "":
+			pterm.Info.Printf("Filtered by '%s' (%d of %d), Esc to clear", searchTerm, len(visible), len(labels))
+		case number != "":
+			fmt.Printf("Item number: %s", number)
+		}
+
+		char, key, err := keyboard.GetKey()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the keyboard: %v", err)
+		}
+
+		if searchMode {
+			switch key {
+			case keyboard.KeyEsc:
+				searchMode = false
+				searchTerm = ""
+			case keyboard.KeyEnter:
+				searchMode = false
+			case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+				if len(searchTerm) > 0 {
+					searchTerm = string([]rune(searchTerm)[:len([]rune(searchTerm))-1])
+				}
+			case keyboard.KeySpace:
+				searchTerm += " "
+			case keyboard.KeyCtrlC:
+				return 0, ErrCancelled
+			default:
+				if char != 0 {
+					searchTerm += string(char)
+				}
+			}
+			if matches := filterLabels(labels, searchTerm); len(matches) > 0 {
+				selected = matches[0]
+			}
+			continue
+		}
+
+		switch {
+		case key == keyboard.KeyEnter:
+			if number != "" {
+				n, _ := strconv.Atoi(number)
+				number = ""
+				if n >= 1 && n <= len(labels) {
+					return n - 1, nil
+				}
+				continue
+			}
+			if len(visible) > 0 {
+				return visible[cursor], nil
+			}
+		case key == keyboard.KeyBackspace || key == keyboard.KeyBackspace2:
+			if number != "" {
+				number = number[:len(number)-1]
+			}
+		case key == keyboard.KeyEsc:
+			if searchTerm == "" && number == "" {
+				return 0, ErrCancelled
+			}
+			searchTerm = ""
+			number = ""
+		case key == keyboard.KeyCtrlC || char == 'q' || char == 'Q':
+			return 0, ErrCancelled
+		case char >= '0' && char <= '9':
+			number += string(char)
+		case char == '/':
+			searchMode = true
+			searchTerm = ""
+			number = ""
+		case len(visible) == 0:
+		case char == 'j' || key == keyboard.KeyArrowDown:
+			if cursor < len(visible)-1 {
+				selected = visible[cursor+1]
+			}
+		case char == 'k' || key == keyboard.KeyArrowUp:
+			if cursor > 0 {
+				selected = visible[cursor-1]
+			}
+		case char == 'l' || key == keyboard.KeyArrowRight || key == keyboard.KeyPgdn:
+			if end < len(visible) {
+				selected = visible[end]
+			}
+		case char == 'h' || key == keyboard.KeyArrowLeft || key == keyboard.KeyPgup:
+			if start > 0 {
+				selected = visible[start-pageSize]
+			}
+		}
+	}
+}
+
+// waitKey shows message and waits for any key
+func waitKey(message string) error {
+	pterm.DefaultBasicText.WithStyle(pterm.NewStyle(pterm.FgGray)).Printf("\n%s\n", message)
+	_, _, err := keyboard.GetSingleKey()
+	return err
+}
+
+// OpenKeys starts reading single keys for ReadKey, until CloseKeys
+func OpenKeys() error {
+	if err := keyboard.Open(); err != nil {
+		return fmt.Errorf("failed to initialize keyboard: %v", err)
+	}
+	return nil
+}
+
+// CloseKeys stops reading single keys, e.g. to read a line in between
+func CloseKeys() {
+	keyboard.Close()
+}
+
+// ReadKey returns the character of the next key pressed, 0 for keys without one
+func ReadKey() (rune, error) {
+	char, _, err := keyboard.GetKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the keyboard: %v", err)
+	}
+	return char, nil
+}
@@ -0,0 +1,30 @@
+//go:build notui
+
+package ui
+
+import "github.com/cloudforet-io/cfctl/internal/features"
+
+// keysEnabled tells whether the components can be driven by single keys. Builds without the
+// TUI are line based.
+const keysEnabled = false
+
+func selectKeys(title string, labels []string, selected, pageSize int) (int, error) {
+	return 0, features.Unavailable(features.TUI)
+}
+
+func waitKey(message string) error {
+	return features.Unavailable(features.TUI)
+}
+
+// OpenKeys reports that single keys cannot be read in this build
+func OpenKeys() error {
+	return features.Unavailable(features.TUI)
+}
+
+// CloseKeys does nothing in this build
+func CloseKeys() {}
+
+// ReadKey reports that single keys cannot be read in this build
+func ReadKey() (rune, error) {
+	return 0, features.Unavailable(features.TUI)
+}
@@ -0,0 +1,24 @@
+//go:build !notui
+
+package ui
+
+import "github.com/pterm/pterm"
+
+// Confirm asks a yes or no question, answered with defaultValue on Enter
+func Confirm(message string, defaultValue bool) (bool, error) {
+	return pterm.DefaultInteractiveConfirm.WithDefaultValue(defaultValue).Show(message)
+}
+
+// Input asks for a line of text, defaultValue when nothing is entered
+func Input(message, defaultValue string) (string, error) {
+	input := pterm.DefaultInteractiveTextInput.WithMultiLine(false)
+	if defaultValue != "" {
+		input = input.WithDefaultText(defaultValue).WithDefaultValue(defaultValue)
+	}
+	return input.Show(message)
+}
+
+// Password asks for a secret without echoing it
+func Password(message string) (string, error) {
+	return pterm.DefaultInteractiveTextInput.WithMask("*").Show(message)
+}
@@ -0,0 +1,74 @@
+//go:build notui
+
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdin is shared by the prompts so that lines read ahead are not lost between them
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine reads a line of stdin without its line break
+func readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Println()
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Confirm asks a yes or no question, answered with defaultValue on Enter
+func Confirm(message string, defaultValue bool) (bool, error) {
+	hint := "y/N"
+	if defaultValue {
+		hint = "Y/n"
+	}
+	for {
+		fmt.Printf("%s [%s]: ", message, hint)
+		line, err := readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "":
+			return defaultValue, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// Input asks for a line of text, defaultValue when nothing is entered
+func Input(message, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", message, defaultValue)
+	} else {
+		fmt.Printf("%s: ", message)
+	}
+	line, err := readLine()
+	if err != nil {
+		return "", err
+	}
+	if line == "" {
+		return defaultValue, nil
+	}
+	return line, nil
+}
+
+// Password asks for a secret without echoing it
+func Password(message string) (string, error) {
+	fmt.Printf("%s: ", message)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	return string(secret), err
+}
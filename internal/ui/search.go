@@ -0,0 +1,29 @@
+//go:build !nosearch
+
+package ui
+
+import (
+	"sort"
+
+	"github.com/lithammer/fuzzysearch/fuzzy"
+)
+
+// matchLabels returns the indexes of the labels matching the lower case search fuzzily, the
+// closest matches first
+func matchLabels(labels []string, search string) []int {
+	ranks := fuzzy.RankFindFold(search, labels)
+	sort.SliceStable(ranks, func(i, j int) bool {
+		if ci, cj := closeness(ranks[i].Target, search), closeness(ranks[j].Target, search); ci != cj {
+			return ci < cj
+		}
+		if ranks[i].Distance != ranks[j].Distance {
+			return ranks[i].Distance < ranks[j].Distance
+		}
+		return ranks[i].OriginalIndex < ranks[j].OriginalIndex
+	})
+	indexes := make([]int, len(ranks))
+	for i, rank := range ranks {
+		indexes[i] = rank.OriginalIndex
+	}
+	return indexes
+}
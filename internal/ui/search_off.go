@@ -0,0 +1,20 @@
+//go:build nosearch
+
+package ui
+
+import "sort"
+
+// matchLabels returns the indexes of the labels containing the lower case search, those
+// starting with it first
+func matchLabels(labels []string, search string) []int {
+	var indexes []int
+	for i, label := range labels {
+		if closeness(label, search) < 2 {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return closeness(labels[indexes[i]], search) < closeness(labels[indexes[j]], search)
+	})
+	return indexes
+}
@@ -0,0 +1,174 @@
+// Package ui holds the interactive components shared by the commands of cfctl.
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrCancelled is returned when the user leaves a selector without choosing
+var ErrCancelled = errors.New("selection cancelled")
+
+// defaultPageSize is the number of items on a page when Select has no PageSize
+const defaultPageSize = 15
+
+var plain bool
+
+// SetPlain makes the components line based, reading a number or a filter from stdin instead of
+// single keys and redrawing the screen. It suits screen readers, dumb terminals and pipes.
+func SetPlain(enabled bool) {
+	plain = enabled
+}
+
+// Plain reports whether the components are line based: the build has no keyboard driven
+// components, SetPlain was called, TERM is dumb, or stdin or stdout is not a terminal
+func Plain() bool {
+	return !keysEnabled || plain || os.Getenv("TERM") == "dumb" ||
+		!term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Select lets the user choose one of Items, shown by Label. The list is paged and can be
+// searched; the keys are the same in every selector of cfctl:
+//
+//	j, ↓ / k, ↑    next / previous item
+//	l, → / h, ←    next / previous page
+//	/              fuzzy search, Enter keeps the filter and Esc clears it
+//	1-9…, Enter    choose the item of the typed number
+//	Enter          choose the highlighted item
+//	q, Esc         cancel
+type Select[T any] struct {
+	Title string
+	Items []T
+	Label func(T) string
+	// Selected is the index of the item highlighted first
+	Selected int
+	// PageSize is the number of items on a page, 15 when not set
+	PageSize int
+}
+
+// Choose runs the selector and returns the chosen item
+func (s Select[T]) Choose() (T, error) {
+	index, err := s.Run()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return s.Items[index], nil
+}
+
+// Run runs the selector and returns the index of the chosen item. It returns ErrCancelled when
+// the user quits.
+func (s Select[T]) Run() (int, error) {
+	if len(s.Items) == 0 {
+		return 0, fmt.Errorf("nothing to select for '%s'", s.Title)
+	}
+	labels := make([]string, len(s.Items))
+	for i, item := range s.Items {
+		if s.Label != nil {
+			labels[i] = s.Label(item)
+		} else {
+			labels[i] = fmt.Sprint(item)
+		}
+	}
+	pageSize := s.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	selected := s.Selected
+	if selected < 0 || selected >= len(labels) {
+		selected = 0
+	}
+
+	if Plain() {
+		return selectLines(os.Stdin, os.Stdout, s.Title, labels, selected)
+	}
+	return selectKeys(s.Title, labels, selected, pageSize)
+}
+
+// filterLabels returns the indexes of the labels matching search, the closest matches first
+func filterLabels(labels []string, search string) []int {
+	if search == "" {
+		indexes := make([]int, len(labels))
+		for i := range labels {
+			indexes[i] = i
+		}
+		return indexes
+	}
+	return matchLabels(labels, strings.ToLower(search))
+}
+
+// closeness ranks how a label matches a lower case search: labels starting with it come
+// first, then labels containing it, then labels only matching fuzzily
+func closeness(label, search string) int {
+	label = strings.ToLower(label)
+	switch {
+	case strings.HasPrefix(label, search):
+		return 0
+	case strings.Contains(label, search):
+		return 1
+	}
+	return 2
+}
+
+// selectLines is the line based selector: it lists the items and reads a number, or a search
+// term narrowing the list, until one item is chosen
+func selectLines(in io.Reader, out io.Writer, title string, labels []string, selected int) (int, error) {
+	reader := bufio.NewReader(in)
+	visible := filterLabels(labels, "")
+	for {
+		fmt.Fprintf(out, "%s:\n", title)
+		for _, index := range visible {
+			fmt.Fprintf(out, "  %d: %s\n", index+1, labels[index])
+		}
+		if len(visible) == 0 {
+			fmt.Fprintln(out, "  No matches")
+		}
+		fmt.Fprintf(out, "Enter a number (default %d), text to search, or q to quit: ", selected+1)
+
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Fprintln(out)
+			if err == io.EOF {
+				return 0, fmt.Errorf("no input to select from '%s'", title)
+			}
+			return 0, err
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			return selected, nil
+		case line == "q" || line == "Q":
+			return 0, ErrCancelled
+		}
+		if n, err := strconv.Atoi(line); err == nil {
+			if n >= 1 && n <= len(labels) {
+				return n - 1, nil
+			}
+			fmt.Fprintf(out, "No item %d.\n", n)
+			continue
+		}
+
+		visible = filterLabels(labels, line)
+		if len(visible) > 0 {
+			selected = visible[0]
+		}
+	}
+}
+
+// Pause shows message and waits for any key, or for Enter when the components are line based
+func Pause(message string) error {
+	if Plain() {
+		fmt.Printf("\n%s (Enter) ", message)
+		_, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		return err
+	}
+	return waitKey(message)
+}
@@ -5,17 +5,16 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
 
 func AddAlias(service, key, value string) error {
-	home, err := os.UserHomeDir()
+	settingDir, err := SettingDir()
 	if err != nil {
 		return fmt.Errorf("failed to get home directory: %v", err)
 	}
 
-	settingPath := filepath.Join(home, ".cfctl", "setting.yaml")
+	settingPath := filepath.Join(settingDir, "setting.yaml")
 
 	data, err := os.ReadFile(settingPath)
 	if err != nil && !os.IsNotExist(err) {
@@ -56,7 +55,7 @@ func AddAlias(service, key, value string) error {
 
 	finalData := append(newData, aliasData...)
 
-	if err := os.WriteFile(settingPath, finalData, 0644); err != nil {
+	if err := WriteSecureFile(settingPath, finalData); err != nil {
 		return fmt.Errorf("failed to write config: %v", err)
 	}
 
@@ -64,12 +63,12 @@ func AddAlias(service, key, value string) error {
 }
 
 func RemoveAlias(service, key string) error {
-	home, err := os.UserHomeDir()
+	settingDir, err := SettingDir()
 	if err != nil {
 		return fmt.Errorf("failed to get home directory: %v", err)
 	}
 
-	settingPath := filepath.Join(home, ".cfctl", "setting.yaml")
+	settingPath := filepath.Join(settingDir, "setting.yaml")
 
 	data, err := os.ReadFile(settingPath)
 	if err != nil {
@@ -112,7 +111,7 @@ func RemoveAlias(service, key string) error {
 		if err != nil {
 			return fmt.Errorf("failed to encode config: %v", err)
 		}
-		if err := os.WriteFile(settingPath, newData, 0644); err != nil {
+		if err := WriteSecureFile(settingPath, newData); err != nil {
 			return fmt.Errorf("failed to write config: %v", err)
 		}
 	} else {
@@ -131,7 +130,7 @@ func RemoveAlias(service, key string) error {
 		}
 
 		finalData := append(newData, aliasData...)
-		if err := os.WriteFile(settingPath, finalData, 0644); err != nil {
+		if err := WriteSecureFile(settingPath, finalData); err != nil {
 			return fmt.Errorf("failed to write config: %v", err)
 		}
 	}
@@ -140,21 +139,12 @@ func RemoveAlias(service, key string) error {
 }
 
 func ListAliases() (map[string]interface{}, error) {
-	home, err := os.UserHomeDir()
+	v, err := Setting()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %v", err)
-	}
-
-	settingPath := filepath.Join(home, ".cfctl", "setting.yaml")
-	v := viper.New()
-	v.SetConfigFile(settingPath)
-	v.SetConfigType("yaml")
-
-	if err := v.ReadInConfig(); err != nil {
 		if os.IsNotExist(err) {
 			return make(map[string]interface{}), nil
 		}
-		return nil, fmt.Errorf("failed to read config: %v", err)
+		return nil, err
 	}
 
 	aliases := v.Get("aliases")
@@ -171,21 +161,12 @@ func ListAliases() (map[string]interface{}, error) {
 }
 
 func LoadAliases() (map[string]interface{}, error) {
-	home, err := os.UserHomeDir()
+	v, err := Setting()
 	if err != nil {
-		return nil, fmt.Errorf("unable to find home directory: %v", err)
-	}
-
-	settingPath := filepath.Join(home, ".cfctl", "setting.yaml")
-	v := viper.New()
-	v.SetConfigFile(settingPath)
-	v.SetConfigType("yaml")
-
-	if err := v.ReadInConfig(); err != nil {
 		if os.IsNotExist(err) {
 			return make(map[string]interface{}), nil
 		}
-		return nil, fmt.Errorf("failed to read config: %v", err)
+		return nil, err
 	}
 
 	aliases := v.Get("aliases")
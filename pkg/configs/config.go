@@ -0,0 +1,281 @@
+package configs
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// Config is the typed form of setting.yaml. Keys that are not described here are kept by viper
+// and ignored, so that new fields can be added without breaking older files.
+type Config struct {
+	Environment  string                       `mapstructure:"environment"`
+	Environments map[string]EnvironmentConfig `mapstructure:"environments"`
+	// CredentialCache is the default of the environments that do not set their own
+	CredentialCache string `mapstructure:"credential_cache"`
+}
+
+// What 'cfctl login' keeps after logging in with a password, see credential_cache
+const (
+	// CredentialCacheNone keeps only the access token in use, nothing to log in again with
+	CredentialCacheNone = "none"
+	// CredentialCachePassword keeps the tokens and the encrypted password
+	CredentialCachePassword = "password"
+	// CredentialCacheToken keeps the tokens and the user entry, the default
+	CredentialCacheToken = "token"
+)
+
+// CredentialCacheModes are the values of credential_cache
+var CredentialCacheModes = []string{CredentialCacheNone, CredentialCachePassword, CredentialCacheToken}
+
+// EnvironmentConfig is an entry under environments in setting.yaml, or in the legacy config.yaml
+type EnvironmentConfig struct {
+	Endpoint           string                    `mapstructure:"endpoint"`
+	Proxy              string                    `mapstructure:"proxy"`
+	NetworkProxy       string                    `mapstructure:"network_proxy"`
+	TLS                TLSConfig                 `mapstructure:"tls"`
+	Token              string                    `mapstructure:"token"`
+	Tokens             []TokenConfig             `mapstructure:"tokens"`
+	UserID             string                    `mapstructure:"user_id"`
+	Users              []UserConfig              `mapstructure:"users"`
+	SaveCredentials    *bool                     `mapstructure:"save_credentials"`
+	CredentialCache    string                    `mapstructure:"credential_cache"`
+	SSOURL             string                    `mapstructure:"sso_url"`
+	OIDC               OIDCConfig                `mapstructure:"oidc"`
+	MaintenanceWindows []MaintenanceWindowConfig `mapstructure:"maintenance_windows"`
+}
+
+// UserConfig is an account stored by 'cfctl login' under users
+type UserConfig struct {
+	UserID    string `mapstructure:"user_id"`
+	Label     string `mapstructure:"label"`
+	LastLogin string `mapstructure:"last_login"`
+	// Password is encrypted with the key of the credential store, see 'cfctl setting rotate-key'
+	Password string `mapstructure:"password"`
+}
+
+// TokenConfig is an app token of the legacy tokens list
+type TokenConfig struct {
+	Token string `mapstructure:"token"`
+}
+
+// OIDCConfig configures 'cfctl login --oidc'
+type OIDCConfig struct {
+	TokenExchangeEndpoint string `mapstructure:"token_exchange_endpoint"`
+	Audience              string `mapstructure:"audience"`
+	TokenEnv              string `mapstructure:"token_env"`
+}
+
+// MaintenanceWindowConfig is a recurring window added by 'cfctl schedule'
+type MaintenanceWindowConfig struct {
+	Name     string `mapstructure:"name"`
+	Cron     string `mapstructure:"cron"`
+	Duration string `mapstructure:"duration"`
+}
+
+// LoadConfig decodes and validates setting.yaml from the shared setting store
+func LoadConfig() (*Config, error) {
+	v, err := Setting()
+	if err != nil {
+		return nil, err
+	}
+	config, err := DecodeConfig(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// NetworkProxy returns the network_proxy of the pinned or current environment, which netproxy
+// dials through
+func NetworkProxy() string {
+	v, err := Setting()
+	if err != nil {
+		return ""
+	}
+	return SettingString(v, fmt.Sprintf("environments.%s.network_proxy", connectionEnvironment(v)))
+}
+
+// DecodeConfig decodes the settings of v, expanding the environment variables of the endpoint,
+// proxy and token of each environment. Entries of the wrong type, like a user that is a
+// string instead of a map, are reported with their path instead of being dropped or panicking.
+func DecodeConfig(v *viper.Viper) (*Config, error) {
+	var config Config
+	if err := decodeSetting(v.AllSettings(), &config, configName(v), ""); err != nil {
+		return nil, err
+	}
+	for name, envConfig := range config.Environments {
+		expandEnvironment(&envConfig)
+		config.Environments[name] = envConfig
+	}
+	return &config, nil
+}
+
+// DecodeEnvironment decodes environments.<env> of v like DecodeConfig. A missing environment
+// decodes as empty.
+func DecodeEnvironment(v *viper.Viper, env string) (EnvironmentConfig, error) {
+	var envConfig EnvironmentConfig
+	key := fmt.Sprintf("environments.%s", env)
+	if err := decodeSetting(v.Get(key), &envConfig, configName(v), key); err != nil {
+		return envConfig, err
+	}
+	expandEnvironment(&envConfig)
+	return envConfig, nil
+}
+
+// configName names the file of v in errors, setting.yaml when it has none
+func configName(v *viper.Viper) string {
+	if path := v.ConfigFileUsed(); path != "" {
+		return filepath.Base(path)
+	}
+	return "setting.yaml"
+}
+
+// decodeSetting decodes input into output, converting scalars to strings where a string is
+// expected as YAML reads unquoted values like 'false' or '8080' as other types
+func decodeSetting(input, output interface{}, name, path string) error {
+	if input == nil {
+		return nil
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: scalarHook,
+		Result:     output,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(input); err != nil {
+		return settingError(err, name, path)
+	}
+	return nil
+}
+
+// scalarHook turns booleans and numbers into strings for string fields, and strings like
+// "false" into booleans for boolean fields
+func scalarHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	switch to.Kind() {
+	case reflect.String:
+		switch from.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int64, reflect.Uint64, reflect.Float64:
+			return fmt.Sprint(data), nil
+		}
+	case reflect.Bool:
+		if from.Kind() == reflect.String {
+			return strconv.ParseBool(data.(string))
+		}
+	}
+	return data, nil
+}
+
+// settingError rewrites decoding errors into one line per malformed entry of the file
+func settingError(err error, name, path string) error {
+	var messages []string
+	if decodeErr, ok := err.(*mapstructure.Error); ok {
+		messages = decodeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "invalid %s", name)
+	if path != "" {
+		fmt.Fprintf(&sb, " at %s", path)
+	}
+	sb.WriteString(", fix these entries in the file:")
+	for _, message := range messages {
+		sb.WriteString("\n  - ")
+		sb.WriteString(message)
+	}
+	return fmt.Errorf("%s", sb.String())
+}
+
+// endpointSchemes are the endpoint schemes cfctl can connect to
+var endpointSchemes = []string{"grpc", "grpc+ssl", "http", "https"}
+
+// Validate checks the values that cannot be checked by their types: the current environment
+// exists and the endpoints have a supported scheme
+func (c *Config) Validate() error {
+	var problems []string
+	if c.Environment != "" && len(c.Environments) > 0 {
+		if _, ok := c.Environments[c.Environment]; !ok {
+			problems = append(problems, fmt.Sprintf("environment '%s' is selected but not defined under environments, run 'cfctl setting environment -s <name>'", c.Environment))
+		}
+	}
+
+	if err := ValidateCredentialCache(c.CredentialCache); err != nil {
+		problems = append(problems, fmt.Sprintf("credential_cache: %v", err))
+	}
+
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ValidateEndpoint(c.Environments[name].Endpoint); err != nil {
+			problems = append(problems, fmt.Sprintf("environments.%s.endpoint: %v", name, err))
+		}
+		if err := netproxy.Validate(c.Environments[name].NetworkProxy); err != nil {
+			problems = append(problems, fmt.Sprintf("environments.%s.network_proxy: %v", name, err))
+		}
+		if err := c.Environments[name].TLS.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("environments.%s.tls: %v", name, err))
+		}
+		if err := ValidateCredentialCache(c.Environments[name].CredentialCache); err != nil {
+			problems = append(problems, fmt.Sprintf("environments.%s.credential_cache: %v", name, err))
+		}
+		for i, user := range c.Environments[name].Users {
+			if user.UserID == "" {
+				problems = append(problems, fmt.Sprintf("environments.%s.users[%d]: user_id is missing", name, i))
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid setting.yaml:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// ValidateCredentialCache checks a credential_cache value. An empty value is accepted and means
+// the default
+func ValidateCredentialCache(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, m := range CredentialCacheModes {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown mode '%s', use one of %s", mode, strings.Join(CredentialCacheModes, ", "))
+}
+
+// ValidateEndpoint checks the scheme of an endpoint. An empty endpoint is accepted, as it is
+// set later by 'cfctl setting endpoint'
+func ValidateEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return nil
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	for _, scheme := range endpointSchemes {
+		if parsed.Scheme == scheme {
+			return nil
+		}
+	}
+	return fmt.Errorf("'%s' has no supported scheme, use one of %s://", endpoint, strings.Join(endpointSchemes, "://, "))
+}
@@ -0,0 +1,99 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"gopkg.in/yaml.v3"
+)
+
+// exchangeRateCacheTTL is how long rates fetched from the rate source are reused
+const exchangeRateCacheTTL = 24 * time.Hour
+
+// CurrencySetting is the currency section of setting.yaml:
+//
+//	currency:
+//	  display: KRW
+//	  rates:          # static rates against a common base
+//	    USD: 1
+//	    KRW: 1380
+//	  rate_source: https://open.er-api.com/v6/latest/USD
+type CurrencySetting struct {
+	Display    string             `yaml:"display"`
+	Rates      map[string]float64 `yaml:"rates"`
+	RateSource string             `yaml:"rate_source"`
+}
+
+// LoadCurrencySetting reads the currency section of setting.yaml
+func LoadCurrencySetting() (*CurrencySetting, error) {
+	settingPath, err := GetSettingFilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(settingPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &CurrencySetting{}, nil
+		}
+		return nil, err
+	}
+
+	// Read without viper, which lowercases the currency codes
+	var setting struct {
+		Currency CurrencySetting `yaml:"currency"`
+	}
+	if err := yaml.Unmarshal(data, &setting); err != nil {
+		return nil, fmt.Errorf("failed to parse setting: %v", err)
+	}
+	return &setting.Currency, nil
+}
+
+// LoadExchangeRates returns the rates of the source, cached in CacheDir for a day.
+// The source must return JSON with a "rates" object, as most exchange rate APIs do.
+func LoadExchangeRates(source string) (map[string]float64, error) {
+	cachePath := filepath.Join(CacheDir(""), "exchange_rates.json")
+
+	var cached struct {
+		Source string             `json:"source"`
+		Rates  map[string]float64 `json:"rates"`
+	}
+	if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < exchangeRateCacheTTL {
+		if data, err := os.ReadFile(cachePath); err == nil && json.Unmarshal(data, &cached) == nil &&
+			cached.Source == source && len(cached.Rates) > 0 {
+			return cached.Rates, nil
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second, Transport: netproxy.Transport()}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rate source returned %s", resp.Status)
+	}
+
+	var body struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse rates: %v", err)
+	}
+	if len(body.Rates) == 0 {
+		return nil, fmt.Errorf("rate source returned no rates")
+	}
+
+	cached.Source = source
+	cached.Rates = body.Rates
+	if data, err := json.Marshal(cached); err == nil {
+		_ = WriteSecureFile(cachePath, data)
+	}
+
+	return body.Rates, nil
+}
@@ -3,19 +3,15 @@ package configs
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
+	"github.com/cloudforet-io/cfctl/internal/grpcconn"
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
 	"github.com/jhump/protoreflect/dynamic"
 	"github.com/jhump/protoreflect/grpcreflect"
-	"github.com/pterm/pterm"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
@@ -35,7 +31,8 @@ func GetAPIEndpoint(endpoint string) (string, error) {
 	configURL := fmt.Sprintf("https://%s/config/production.json", endpoint)
 
 	// Make HTTP request
-	resp, err := http.Get(configURL)
+	client := &http.Client{Transport: netproxy.Transport()}
+	resp, err := client.Get(configURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch config: %v", err)
 	}
@@ -95,7 +92,7 @@ func GetIdentityEndpoint(apiEndpoint string) (string, bool, error) {
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := &http.Client{Transport: netproxy.Transport()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", false, fmt.Errorf("failed to fetch endpoints: %v", err)
@@ -142,12 +139,14 @@ func GetServiceEndpoint(config *Environments, serviceName string) (string, error
 	// Get console API endpoint
 	apiEndpoint, err := GetAPIEndpoint(envConfig.Endpoint)
 	if err != nil {
-		pterm.Error.Printf("Failed to get API endpoint: %v\n", err)
-		os.Exit(1)
+		return "", fmt.Errorf("failed to get API endpoint: %v", err)
 	}
 
 	// Get identity endpoint
 	identityEndpoint, _, err := GetIdentityEndpoint(apiEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to get identity endpoint: %v", err)
+	}
 
 	// Fetch endpoints map
 	endpointsMap, err := FetchEndpointsMap(identityEndpoint)
@@ -181,8 +180,7 @@ func FetchEndpointsMap(endpoint string) (map[string]string, error) {
 	listEndpointsUrl := endpoint + "/identity/endpoint/list"
 
 	if err != nil {
-		pterm.Error.Printf("Failed to get identity endpoint: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to get identity endpoint: %v", err)
 	}
 
 	if !hasIdentityService {
@@ -205,18 +203,10 @@ func FetchEndpointsMap(endpoint string) (map[string]string, error) {
 			svc := hostParts[0]
 			baseDomain := strings.Join(hostParts[1:], ".")
 
-			// Configure TLS
-			tlsConfig := &tls.Config{
-				InsecureSkipVerify: false,
-			}
-			opts := []grpc.DialOption{
-				grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
-			}
-
 			//If current service is not identity, modify hostPort to use identity service
 			if svc != "identity" {
 				hostPort := fmt.Sprintf("identity.%s", baseDomain)
-				endpoints, err := invokeGRPCEndpointList(hostPort, opts)
+				endpoints, err := invokeGRPCEndpointList(hostPort, false)
 				if err != nil {
 					return nil, fmt.Errorf("failed to get endpoints from gRPC: %v", err)
 				}
@@ -238,7 +228,7 @@ func FetchEndpointsMap(endpoint string) (map[string]string, error) {
 		req.Header.Set("accept", "application/json")
 		req.Header.Set("Content-Type", "application/json")
 
-		client := &http.Client{}
+		client := &http.Client{Transport: netproxy.Transport()}
 		resp, err := client.Do(req)
 		if err != nil {
 			return nil, err
@@ -277,24 +267,11 @@ func FetchEndpointsMap(endpoint string) (map[string]string, error) {
 		scheme := parts[0]
 		hostPort := parts[1]
 
-		// Configure gRPC connection based on scheme
-		var opts []grpc.DialOption
-		if scheme == "grpc+ssl" {
-			tlsConfig := &tls.Config{
-				InsecureSkipVerify: false, // Enable server certificate verification
-			}
-			creds := credentials.NewTLS(tlsConfig)
-			opts = append(opts, grpc.WithTransportCredentials(creds))
-		} else {
-			opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		}
-
-		// Establish the connection
-		conn, err := grpc.Dial(hostPort, opts...)
+		// Reuse the shared connection, TLS is only used for grpc+ssl
+		conn, err := grpcconn.Get(hostPort, scheme != "grpc+ssl")
 		if err != nil {
 			return nil, fmt.Errorf("connection failed: unable to connect to %s: %v", identityEndpoint, err)
 		}
-		defer conn.Close()
 
 		// Use Reflection to discover services
 		refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
@@ -356,7 +333,7 @@ func FetchEndpointsMap(endpoint string) (map[string]string, error) {
 	}
 }
 
-func invokeGRPCEndpointList(hostPort string, opts []grpc.DialOption) (map[string]string, error) {
+func invokeGRPCEndpointList(hostPort string, plaintext bool) (map[string]string, error) {
 	// Wrap the entire operation in a function that can recover from panic
 	var endpoints = make(map[string]string)
 	var err error
@@ -374,12 +351,11 @@ func invokeGRPCEndpointList(hostPort string, opts []grpc.DialOption) (map[string
 		}
 	}()
 
-	// Establish the connection
-	conn, err := grpc.Dial(hostPort, opts...)
+	// Reuse the shared connection
+	conn, err := grpcconn.Get(hostPort, plaintext)
 	if err != nil {
 		return nil, fmt.Errorf("connection failed: unable to connect to %s: %v", hostPort, err)
 	}
-	defer conn.Close()
 
 	// Use Reflection to discover services
 	refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
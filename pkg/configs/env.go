@@ -0,0 +1,127 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// Environment variables configuring cfctl without setting.yaml, e.g. in containers.
+// CFCTL_ENDPOINT and CFCTL_TOKEN set the endpoint and token of the environment named by
+// CFCTL_ENVIRONMENT, which is the one of setting.yaml or "default" without it.
+const (
+	EnvVarEnvironment    = "CFCTL_ENVIRONMENT"
+	EnvVarEndpoint       = "CFCTL_ENDPOINT"
+	EnvVarToken          = "CFCTL_TOKEN"
+	EnvVarCacheDir       = "CFCTL_CACHE_DIR"
+	EnvVarNonInteractive = "CFCTL_NON_INTERACTIVE"
+	EnvVarConfigDir      = "CFCTL_CONFIG_DIR"
+)
+
+// defaultEnvEnvironment names the environment configured only by environment variables
+const defaultEnvEnvironment = "default"
+
+// EnvConfigured reports whether environment variables override the setting file. Setting
+// changes are not saved in that case, as the configuration is not the one of the file.
+func EnvConfigured() bool {
+	return os.Getenv(EnvVarEnvironment) != "" || os.Getenv(EnvVarEndpoint) != "" || os.Getenv(EnvVarToken) != ""
+}
+
+// EnvToken returns the token given by CFCTL_TOKEN, which takes precedence over the cached
+// tokens of user environments and the token of app environments
+func EnvToken() string {
+	return os.Getenv(EnvVarToken)
+}
+
+// applyEnvOverrides sets the environment and its endpoint and token from the environment variables
+func applyEnvOverrides(v *viper.Viper) {
+	env := os.Getenv(EnvVarEnvironment)
+	if env == "" {
+		env = v.GetString("environment")
+	}
+	if env == "" {
+		env = defaultEnvEnvironment
+	}
+	v.Set("environment", env)
+	if os.Getenv(EnvVarEnvironment) != "" {
+		setOrigin(v, "environment", "$"+EnvVarEnvironment)
+	}
+
+	if endpoint := os.Getenv(EnvVarEndpoint); endpoint != "" {
+		v.Set(fmt.Sprintf("environments.%s.endpoint", env), endpoint)
+		setOrigin(v, fmt.Sprintf("environments.%s.endpoint", env), "$"+EnvVarEndpoint)
+	}
+	if token := os.Getenv(EnvVarToken); token != "" {
+		v.Set(fmt.Sprintf("environments.%s.token", env), token)
+		setOrigin(v, fmt.Sprintf("environments.%s.token", env), "$"+EnvVarToken)
+	}
+}
+
+var (
+	interactiveOnce sync.Once
+	interactive     bool
+)
+
+// Interactive reports whether cfctl may prompt and read keys: stdin and stdout are terminals
+// and CFCTL_NON_INTERACTIVE is not set. Prompts fail or fall back otherwise, e.g. in containers.
+func Interactive() bool {
+	interactiveOnce.Do(func() {
+		if PromptsDisabled() {
+			return
+		}
+		interactive = term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+	})
+	return interactive
+}
+
+// PromptsDisabled reports whether CFCTL_NON_INTERACTIVE turns the prompts off, for prompts that
+// read the terminal directly because stdin carries data
+func PromptsDisabled() bool {
+	disabled, err := strconv.ParseBool(os.Getenv(EnvVarNonInteractive))
+	return err == nil && disabled
+}
+
+var (
+	cacheRootOnce sync.Once
+	cacheRoot     string
+)
+
+// CacheDir returns the directory for caches of the environment that can be rebuilt, like
+// endpoints and descriptors: CFCTL_CACHE_DIR, the cache directory of SettingDir when it is
+// writable, or a directory under /tmp on read-only file systems. Tokens always stay in SettingDir.
+func CacheDir(env string) string {
+	cacheRootOnce.Do(func() {
+		if dir := os.Getenv(EnvVarCacheDir); dir != "" {
+			cacheRoot = dir
+			return
+		}
+		if settingDir, err := SettingDir(); err == nil {
+			dir := filepath.Join(settingDir, "cache")
+			if isWritableDir(dir) {
+				cacheRoot = dir
+				return
+			}
+		}
+		cacheRoot = filepath.Join(os.TempDir(), fmt.Sprintf("cfctl-cache-%d", os.Getuid()))
+	})
+	return filepath.Join(cacheRoot, env)
+}
+
+// isWritableDir reports whether files can be created in the directory, creating it if needed
+func isWritableDir(dir string) bool {
+	if err := os.MkdirAll(dir, SecureDirMode); err != nil {
+		return false
+	}
+	f, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return true
+}
@@ -0,0 +1,201 @@
+package configs
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// includeLayer is what the includes of a setting file added to its viper, kept so that the
+// included values are not written back into the file and their origin can be shown
+type includeLayer struct {
+	// included are the merged settings of the included files
+	included map[string]interface{}
+	// own are the settings of the file itself
+	own map[string]interface{}
+	// origins maps the dotted key of each value to the file or variable it came from
+	origins map[string]string
+}
+
+var includeLayers sync.Map
+
+// ApplyIncludes merges the files listed under includes of the settings of v beneath them.
+// Paths are relative to dir, may start with ~/ and may refer to environment variables. The
+// files are merged in order, later ones overriding earlier ones, and the settings of v
+// override them all. Included files may include other files in turn. The environments synced
+// from remote.url come beneath the included files.
+func ApplyIncludes(v *viper.Viper, dir string) error {
+	own := v.AllSettings()
+	layer := &includeLayer{own: own, origins: map[string]string{}}
+	includeLayers.Store(v, layer)
+
+	acc := viper.New()
+	if err := mergeRemote(acc, own, dir, layer.origins); err != nil {
+		return err
+	}
+	if err := mergeIncludes(acc, own["includes"], dir, layer.origins, nil); err != nil {
+		return err
+	}
+	layer.included = acc.AllSettings()
+
+	name := v.ConfigFileUsed()
+	if name == "" {
+		name = "setting.yaml"
+	}
+	for _, key := range leafKeys(own, "") {
+		layer.origins[key] = name
+	}
+	if len(layer.included) == 0 {
+		return nil
+	}
+
+	if err := acc.MergeConfigMap(own); err != nil {
+		return err
+	}
+	return v.MergeConfigMap(acc.AllSettings())
+}
+
+// mergeIncludes merges the files of includes into acc, chain being the files including them
+func mergeIncludes(acc *viper.Viper, includes interface{}, dir string, origins map[string]string, chain []string) error {
+	if includes == nil {
+		return nil
+	}
+	list, ok := includes.([]interface{})
+	if !ok {
+		return fmt.Errorf("includes must be a list of files, got %v", includes)
+	}
+
+	for _, item := range list {
+		path, ok := item.(string)
+		if !ok || strings.TrimSpace(path) == "" {
+			return fmt.Errorf("includes must be a list of files, got %v", item)
+		}
+		path, err := includePath(path, dir)
+		if err != nil {
+			return err
+		}
+		for _, including := range chain {
+			if including == path {
+				return fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), path)
+			}
+		}
+
+		fv := viper.New()
+		fv.SetConfigFile(path)
+		fv.SetConfigType("yaml")
+		if err := fv.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read included file %s: %v", path, err)
+		}
+		settings := fv.AllSettings()
+		if err := mergeIncludes(acc, settings["includes"], filepath.Dir(path), origins, append(chain, path)); err != nil {
+			return err
+		}
+		delete(settings, "includes")
+		for _, key := range leafKeys(settings, "") {
+			origins[key] = path
+		}
+		if err := acc.MergeConfigMap(settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// includePath resolves an entry of includes to an absolute path
+func includePath(path, dir string) (string, error) {
+	path = ExpandEnv(strings.TrimSpace(path))
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := HomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// OwnSettings returns the settings of v to be written to its file: all settings except the
+// values of included files that were not changed
+func OwnSettings(v *viper.Viper) map[string]interface{} {
+	all := v.AllSettings()
+	value, ok := includeLayers.Load(v)
+	if !ok {
+		return all
+	}
+	layer := value.(*includeLayer)
+	if len(layer.included) == 0 {
+		return all
+	}
+	return withoutIncluded(all, layer.included, layer.own)
+}
+
+// withoutIncluded removes the values of settings equal to those of included, unless own, the
+// file itself, set them
+func withoutIncluded(settings, included, own map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		includedValue, isIncluded := included[key]
+		ownValue, isOwn := own[key]
+		if !isIncluded {
+			out[key] = value
+			continue
+		}
+
+		valueMap, isMap := value.(map[string]interface{})
+		includedMap, includedIsMap := includedValue.(map[string]interface{})
+		if isMap && includedIsMap {
+			ownMap, _ := ownValue.(map[string]interface{})
+			if rest := withoutIncluded(valueMap, includedMap, ownMap); len(rest) > 0 || isOwn {
+				out[key] = rest
+			}
+			continue
+		}
+		if isOwn || !reflect.DeepEqual(value, includedValue) {
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// SettingOrigins maps the dotted key of each setting of v to the file it came from, or to the
+// environment variable overriding it
+func SettingOrigins(v *viper.Viper) map[string]string {
+	origins := map[string]string{}
+	if value, ok := includeLayers.Load(v); ok {
+		for key, origin := range value.(*includeLayer).origins {
+			origins[key] = origin
+		}
+	}
+	return origins
+}
+
+// setOrigin records where the value of key of v came from
+func setOrigin(v *viper.Viper, key, origin string) {
+	value, _ := includeLayers.LoadOrStore(v, &includeLayer{origins: map[string]string{}})
+	value.(*includeLayer).origins[strings.ToLower(key)] = origin
+}
+
+// leafKeys returns the dotted keys of the values of settings that are not maps, lists included
+func leafKeys(settings map[string]interface{}, prefix string) []string {
+	var keys []string
+	for key, value := range settings {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			keys = append(keys, leafKeys(nested, key)...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
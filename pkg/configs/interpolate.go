@@ -0,0 +1,125 @@
+package configs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ExpandEnv replaces the environment variables in a value of setting.yaml, so that one file can
+// be shared between machines:
+//
+//	${VAR}           the value of VAR, empty when it is not set
+//	${VAR:-default}  default when VAR is not set or empty
+//	${VAR-default}   default when VAR is not set
+//	$$               a literal $
+//
+// Anything else, including a lone $, is kept as it is.
+func ExpandEnv(value string) string {
+	expanded, _ := expandEnv(value)
+	return expanded
+}
+
+// MissingVariables returns the variables of value that are not set and have no default
+func MissingVariables(value string) []string {
+	_, missing := expandEnv(value)
+	return missing
+}
+
+// SettingString returns the string at key of v with its environment variables expanded, or
+// the secret it refers to in the credential store
+func SettingString(v *viper.Viper, key string) string {
+	return resolveSecret(ExpandEnv(v.GetString(key)))
+}
+
+// SettingBool returns the boolean at key of v. A string is expanded first, so that a flag like
+// proxy can be given as ${CFCTL_PROXY:-true}.
+func SettingBool(v *viper.Viper, key string) bool {
+	value, ok := v.Get(key).(string)
+	if !ok {
+		return v.GetBool(key)
+	}
+	enabled, _ := strconv.ParseBool(strings.TrimSpace(ExpandEnv(value)))
+	return enabled
+}
+
+// expandEnvironment expands the values of an environment that may differ between machines and
+// resolves the tokens kept in the credential store
+func expandEnvironment(envConfig *EnvironmentConfig) {
+	envConfig.Endpoint = ExpandEnv(envConfig.Endpoint)
+	envConfig.Proxy = ExpandEnv(envConfig.Proxy)
+	envConfig.NetworkProxy = resolveSecret(ExpandEnv(envConfig.NetworkProxy))
+	envConfig.TLS.CAFile = ExpandEnv(envConfig.TLS.CAFile)
+	envConfig.TLS.CertFile = ExpandEnv(envConfig.TLS.CertFile)
+	envConfig.TLS.KeyFile = ExpandEnv(envConfig.TLS.KeyFile)
+	envConfig.Token = resolveSecret(ExpandEnv(envConfig.Token))
+	for i := range envConfig.Tokens {
+		envConfig.Tokens[i].Token = resolveSecret(envConfig.Tokens[i].Token)
+	}
+}
+
+func expandEnv(value string) (string, []string) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	var sb strings.Builder
+	var missing []string
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			sb.WriteByte(value[i])
+			continue
+		}
+		if value[i+1] == '$' {
+			sb.WriteByte('$')
+			i++
+			continue
+		}
+		end := strings.IndexByte(value[i:], '}')
+		if value[i+1] != '{' || end < 0 {
+			sb.WriteByte(value[i])
+			continue
+		}
+
+		expr := value[i+2 : i+end]
+		name, fallback, hasDefault := expr, "", false
+		emptyIsUnset := false
+		if idx := strings.Index(expr, ":-"); idx >= 0 {
+			name, fallback, hasDefault, emptyIsUnset = expr[:idx], expr[idx+2:], true, true
+		} else if idx := strings.IndexByte(expr, '-'); idx >= 0 {
+			name, fallback, hasDefault = expr[:idx], expr[idx+1:], true
+		}
+		if !validVariableName(name) {
+			sb.WriteByte(value[i])
+			continue
+		}
+
+		resolved, set := os.LookupEnv(name)
+		switch {
+		case set && (resolved != "" || !emptyIsUnset):
+			sb.WriteString(resolved)
+		case hasDefault:
+			sb.WriteString(fallback)
+		default:
+			missing = append(missing, name)
+		}
+		i += end
+	}
+	return sb.String(), missing
+}
+
+// validVariableName accepts the names of environment variables, letters, digits and
+// underscores not starting with a digit
+func validVariableName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
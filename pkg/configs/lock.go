@@ -0,0 +1,213 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// lockPath returns the lock file of path, a hidden file next to it. The lock is not taken on
+// the file itself, as it is replaced on every atomic write.
+func lockPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")
+}
+
+var (
+	heldMu sync.Mutex
+	// held counts the exclusive locks the process holds per lock file
+	held = make(map[string]int)
+)
+
+// LockFile takes an advisory lock of path shared by all cfctl processes, exclusive for writers
+// and shared for readers, and waits until it is available. The returned function releases it,
+// and so does an aborted command. A shared lock of a path the process holds exclusively, e.g.
+// a read of the setting while UpdateConfig runs, is granted at once. Exclusive locks of the
+// same path must not be nested, as the second one would wait for the first.
+func LockFile(path string, exclusive bool) (func(), error) {
+	lock := lockPath(path)
+	heldMu.Lock()
+	if !exclusive && held[lock] > 0 {
+		heldMu.Unlock()
+		return func() {}, nil
+	}
+	heldMu.Unlock()
+
+	f, err := os.OpenFile(lock, os.O_CREATE|os.O_RDWR, SecureFileMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+	}
+
+	if exclusive {
+		heldMu.Lock()
+		held[lock]++
+		heldMu.Unlock()
+	}
+	var once sync.Once
+	unlock := func() {
+		once.Do(func() {
+			if exclusive {
+				heldMu.Lock()
+				held[lock]--
+				heldMu.Unlock()
+			}
+			unlockFile(f)
+			f.Close()
+		})
+	}
+	release := cleanup.Register(unlock)
+	return func() {
+		release()
+		unlock()
+	}, nil
+}
+
+// WriteFileAtomic writes data to a temporary file in the directory of path and renames it over
+// path, so that readers see either the old or the new content and never a partial file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer cleanup.RemoveOnAbort(tmpPath)()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// ReadConfig reads the config file of v under a shared lock, and the files it includes. The
+// file is read without the lock when the lock cannot be created, e.g. in a setting directory
+// mounted read-only.
+func ReadConfig(v *viper.Viper) error {
+	path := v.ConfigFileUsed()
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if path != "" {
+		if unlock, err := LockFile(path, false); err == nil {
+			defer unlock()
+		}
+	}
+	return readConfig(v)
+}
+
+// readConfig reads the config file of v and the files it includes, without locking it
+func readConfig(v *viper.Viper) error {
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+	return ApplyIncludes(v, filepath.Dir(v.ConfigFileUsed()))
+}
+
+// WriteConfig writes the settings of v to its config file under an exclusive lock, replacing
+// the file atomically. Values of included files are left out, see OwnSettings. It takes the
+// place of viper's WriteConfig, which truncates the file first.
+func WriteConfig(v *viper.Viper) error {
+	path := v.ConfigFileUsed()
+	if path == "" {
+		return fmt.Errorf("no config file to write")
+	}
+
+	settings := OwnSettings(v)
+	if err := KeepVerbatimSections(path, settings); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+	return writeLockedFile(path, data)
+}
+
+// UpdateConfig reads the config file at path, applies update and writes the result, holding
+// the exclusive lock of the file from the read to the rename, so that concurrent cfctl
+// processes do not lose each other's changes. A missing file is started empty.
+func UpdateConfig(path string, update func(v *viper.Viper) error) error {
+	return updateLocked(path, func(v *viper.Viper) (map[string]interface{}, error) {
+		if err := update(v); err != nil {
+			return nil, err
+		}
+		return OwnSettings(v), nil
+	})
+}
+
+// updateLocked replaces the config file at path with the settings returned by update for its
+// current content, under its exclusive lock
+func updateLocked(path string, update func(v *viper.Viper) (map[string]interface{}, error)) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if err := EnsureSecureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	unlock, err := LockFile(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	v.SetConfigPermissions(SecureFileMode)
+	if _, err := os.Stat(path); err == nil {
+		if err := readConfig(v); err != nil {
+			return fmt.Errorf("failed to read config file: %v", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	settings, err := update(v)
+	if err != nil {
+		return err
+	}
+	if err := KeepVerbatimSections(path, settings); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+	return WriteFileAtomic(path, data, SecureFileMode)
+}
+
+// writeLockedFile replaces path with data under an exclusive lock. A symlinked file, e.g. from
+// a dotfiles repository, is written through to its target instead of replacing the link.
+func writeLockedFile(path string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if err := EnsureSecureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	unlock, err := LockFile(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return WriteFileAtomic(path, data, SecureFileMode)
+}
@@ -0,0 +1,285 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SettingVersion is the layout of setting.yaml written by this version of cfctl, stored under
+// version. Files without it are version 0.
+const SettingVersion = 2
+
+// settingMigration upgrades setting.yaml by one version. It returns what was changed, and the
+// legacy files it merged, which are kept as <file>.bak once the new setting is written.
+type settingMigration struct {
+	Description string
+	Migrate     func(settingDir string, setting map[string]interface{}) (changes []string, merged []string, err error)
+}
+
+// settingMigrations[i] upgrades version i to i+1
+var settingMigrations = []settingMigration{
+	{Description: "merge config.yaml and cache/setting.yaml into setting.yaml", Migrate: mergeLegacyFiles},
+	{Description: "list the selected token under tokens and the user under users", Migrate: normalizeCredentials},
+}
+
+// MigrationResult describes an upgrade of setting.yaml by MigrateSetting
+type MigrationResult struct {
+	From    int
+	To      int
+	Backup  string
+	Changes []string
+}
+
+// MigrateSetting upgrades setting.yaml to SettingVersion, after copying it to
+// setting.yaml.v<version>.bak. It returns nil when the file is current or there is nothing to
+// migrate, and an error when the file was written by a newer version of cfctl.
+func MigrateSetting() (*MigrationResult, error) {
+	settingDir, err := SettingDir()
+	if err != nil {
+		return nil, err
+	}
+	settingPath := filepath.Join(settingDir, "setting.yaml")
+
+	data, err := os.ReadFile(settingPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	exists := err == nil
+	if !exists && !hasLegacyFiles(settingDir) {
+		return nil, nil
+	}
+
+	setting := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &setting); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", settingPath, err)
+	}
+	if setting == nil {
+		setting = map[string]interface{}{}
+	}
+
+	version, ok := setting["version"].(int)
+	if _, set := setting["version"]; set && (!ok || version < 0) {
+		return nil, fmt.Errorf("version of %s must be a number, got %v", settingPath, setting["version"])
+	}
+	if version > SettingVersion {
+		return nil, fmt.Errorf("%s has version %d, but this cfctl supports up to version %d; upgrade cfctl", settingPath, version, SettingVersion)
+	}
+	if version == SettingVersion {
+		return nil, nil
+	}
+
+	result := &MigrationResult{From: version, To: SettingVersion}
+	if exists {
+		result.Backup = fmt.Sprintf("%s.v%d.bak", settingPath, version)
+		// Only this migration writes the backup, so it takes no lock of its own
+		if err := WriteFileAtomic(result.Backup, data, SecureFileMode); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %v", settingPath, err)
+		}
+	}
+
+	var merged []string
+	for v := version; v < SettingVersion; v++ {
+		changes, files, err := settingMigrations[v].Migrate(settingDir, setting)
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s: %v", settingMigrations[v].Description, err)
+		}
+		result.Changes = append(result.Changes, changes...)
+		merged = append(merged, files...)
+	}
+	setting["version"] = SettingVersion
+
+	out, err := yaml.Marshal(setting)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %v", err)
+	}
+	if err := writeLockedFile(settingPath, out); err != nil {
+		return nil, err
+	}
+	for _, path := range merged {
+		if err := os.Rename(path, path+".bak"); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// legacyFiles are the files older versions kept environments in besides setting.yaml: app
+// tokens in config.yaml and user environments in cache/setting.yaml
+func legacyFiles(settingDir string) []string {
+	return []string{
+		filepath.Join(settingDir, "config.yaml"),
+		filepath.Join(settingDir, "cache", "setting.yaml"),
+	}
+}
+
+func hasLegacyFiles(settingDir string) bool {
+	for _, path := range legacyFiles(settingDir) {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeLegacyFiles merges the legacy files into the setting. Settings of setting.yaml win over
+// those of the legacy files, and the token lists are combined.
+func mergeLegacyFiles(settingDir string, setting map[string]interface{}) ([]string, []string, error) {
+	var changes, merged []string
+	for _, path := range legacyFiles(settingDir) {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var legacy map[string]interface{}
+		if err := yaml.Unmarshal(data, &legacy); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		name, _ := filepath.Rel(settingDir, path)
+		for _, env := range mergeLegacySetting(setting, legacy) {
+			changes = append(changes, fmt.Sprintf("merged environment '%s' of %s", env, name))
+		}
+		merged = append(merged, path)
+	}
+	return changes, merged, nil
+}
+
+// mergeLegacySetting adds the environments and settings of legacy missing from setting and
+// returns the names of the environments changed
+func mergeLegacySetting(setting, legacy map[string]interface{}) []string {
+	if env, ok := legacy["environment"].(string); ok && env != "" {
+		if current, _ := setting["environment"].(string); current == "" {
+			setting["environment"] = env
+		}
+	}
+
+	legacyEnvs, _ := legacy["environments"].(map[string]interface{})
+	envs, _ := setting["environments"].(map[string]interface{})
+	if envs == nil {
+		envs = map[string]interface{}{}
+	}
+
+	var changed []string
+	for name, value := range legacyEnvs {
+		legacyEnv, _ := value.(map[string]interface{})
+		if len(legacyEnv) == 0 {
+			continue
+		}
+		env, ok := envs[name].(map[string]interface{})
+		if !ok {
+			envs[name] = legacyEnv
+			changed = append(changed, name)
+			continue
+		}
+
+		updated := false
+		for key, legacyValue := range legacyEnv {
+			current, exists := env[key]
+			switch {
+			case !exists || current == nil || current == "":
+				env[key] = legacyValue
+				updated = true
+			case key == "tokens":
+				if tokens, added := mergeTokenLists(current, legacyValue); added {
+					env[key] = tokens
+					updated = true
+				}
+			}
+		}
+		if updated {
+			changed = append(changed, name)
+		}
+	}
+
+	if len(changed) > 0 {
+		setting["environments"] = envs
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// mergeTokenLists appends the tokens of legacy missing from current, reporting whether any was
+func mergeTokenLists(current, legacy interface{}) ([]interface{}, bool) {
+	tokens, _ := current.([]interface{})
+	seen := make(map[string]bool, len(tokens))
+	for _, t := range tokens {
+		if entry, ok := t.(map[string]interface{}); ok {
+			seen[fmt.Sprint(entry["token"])] = true
+		}
+	}
+
+	added := false
+	legacyTokens, _ := legacy.([]interface{})
+	for _, t := range legacyTokens {
+		entry, ok := t.(map[string]interface{})
+		if !ok || seen[fmt.Sprint(entry["token"])] {
+			continue
+		}
+		seen[fmt.Sprint(entry["token"])] = true
+		tokens = append(tokens, entry)
+		added = true
+	}
+	return tokens, added
+}
+
+// normalizeCredentials brings the credentials of each environment to the current layout: the
+// selected token of an app environment is one of its tokens, an app environment with only a
+// tokens list selects the first one, and the single user_id of older versions is under users.
+func normalizeCredentials(_ string, setting map[string]interface{}) ([]string, []string, error) {
+	envs, _ := setting["environments"].(map[string]interface{})
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []string
+	for _, name := range names {
+		env, ok := envs[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		selected, _ := env["token"].(string)
+		tokens, _ := env["tokens"].([]interface{})
+		if selected == "" && len(tokens) > 0 {
+			if first, ok := tokens[0].(map[string]interface{}); ok {
+				if t, _ := first["token"].(string); t != "" {
+					env["token"] = t
+					changes = append(changes, fmt.Sprintf("selected the first token of '%s'", name))
+				}
+			}
+		} else if selected != "" && (len(tokens) > 0 || strings.HasSuffix(name, "-app")) {
+			if merged, added := mergeTokenLists(tokens, []interface{}{map[string]interface{}{"token": selected}}); added {
+				env["tokens"] = merged
+				changes = append(changes, fmt.Sprintf("listed the selected token of '%s' under tokens", name))
+			}
+		}
+
+		userID, _ := env["user_id"].(string)
+		if userID == "" {
+			continue
+		}
+		users, _ := env["users"].([]interface{})
+		listed := false
+		for _, u := range users {
+			if entry, ok := u.(map[string]interface{}); ok && entry["user_id"] == userID {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			env["users"] = append(users, map[string]interface{}{"user_id": userID})
+			changes = append(changes, fmt.Sprintf("listed user '%s' of '%s' under users", userID, name))
+		}
+	}
+	return changes, nil, nil
+}
@@ -0,0 +1,89 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// SecureFileMode is the mode used for setting, token and cache files
+	SecureFileMode fs.FileMode = 0600
+	// SecureDirMode is the mode used for the setting and cache directories
+	SecureDirMode fs.FileMode = 0700
+)
+
+// InsecurePath is a file or directory under the setting directory accessible by group or others
+type InsecurePath struct {
+	Path     string
+	Mode     fs.FileMode
+	Expected fs.FileMode
+	IsDir    bool
+}
+
+// EnsureSecureDir creates the directory if needed and restricts it to the current user.
+// The mode is applied explicitly so the result does not depend on the process umask.
+func EnsureSecureDir(dir string) error {
+	if err := os.MkdirAll(dir, SecureDirMode); err != nil {
+		return err
+	}
+	return os.Chmod(dir, SecureDirMode)
+}
+
+// WriteSecureFile writes data to path with 0600 permissions, creating the parent directory with 0700.
+// The file is replaced atomically under a lock, so concurrent cfctl processes never see it half written.
+func WriteSecureFile(path string, data []byte) error {
+	return writeLockedFile(path, data)
+}
+
+// FindInsecurePaths walks the setting directory and returns entries whose permissions are too open
+func FindInsecurePaths(settingDir string) ([]InsecurePath, error) {
+	var insecure []InsecurePath
+
+	err := filepath.WalkDir(settingDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		mode := info.Mode().Perm()
+		if mode&0077 == 0 {
+			return nil
+		}
+
+		expected := SecureFileMode
+		if d.IsDir() {
+			expected = SecureDirMode
+		}
+		insecure = append(insecure, InsecurePath{
+			Path:     path,
+			Mode:     mode,
+			Expected: expected,
+			IsDir:    d.IsDir(),
+		})
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return insecure, err
+}
+
+// FixInsecurePaths restricts the given entries to their expected mode
+func FixInsecurePaths(paths []InsecurePath) error {
+	for _, p := range paths {
+		if err := os.Chmod(p.Path, p.Expected); err != nil {
+			return fmt.Errorf("failed to fix permissions of '%s': %v", p.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/netproxy"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// remoteTimeout bounds the download of the remote environments
+const remoteTimeout = 30 * time.Second
+
+// remoteMaxSize is the largest remote file accepted
+const remoteMaxSize = 10 << 20
+
+// remoteState is what is kept next to the cached remote environments to revalidate them. The
+// URL is kept without its query, as presigned URLs get a new signature on every renewal.
+type remoteState struct {
+	URL      string    `json:"url"`
+	ETag     string    `json:"etag,omitempty"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// RemoteSyncResult describes what SyncRemote changed
+type RemoteSyncResult struct {
+	// Source is the URL without its query, which may hold the signature of a presigned URL
+	Source string
+	// NotModified is set when the server reported the cached environments as current
+	NotModified bool
+	Added       []string
+	Updated     []string
+	Removed     []string
+}
+
+// remoteFiles returns the cached environments and the state of the remote of the setting in dir
+func remoteFiles(dir string) (string, string) {
+	remoteDir := filepath.Join(dir, "remote")
+	return filepath.Join(remoteDir, "environments.yaml"), filepath.Join(remoteDir, "state.json")
+}
+
+// remoteSource returns rawURL without its query and fragment, to be shown to the user
+func remoteSource(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	parsed.User = nil
+	return parsed.String()
+}
+
+// SyncRemote downloads the environments of remote.url of v into the remote directory under
+// dir. The URL may be a presigned S3 URL or any HTTP(S) URL returning YAML with an environments
+// section; remote.headers are sent with the request, e.g. for authorization. The ETag of the
+// last download is sent along, so an unchanged file is not downloaded again.
+func SyncRemote(v *viper.Viper, dir string) (*RemoteSyncResult, error) {
+	rawURL := SettingString(v, "remote.url")
+	if rawURL == "" {
+		return nil, fmt.Errorf("no remote.url in the setting")
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("remote.url must be an http or https URL, got %s", remoteSource(rawURL))
+	}
+	result := &RemoteSyncResult{Source: remoteSource(rawURL)}
+	cachePath, statePath := remoteFiles(dir)
+
+	var state remoteState
+	if data, err := os.ReadFile(statePath); err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	previous, err := readRemoteEnvironments(cachePath)
+	if err != nil || state.URL != result.Source {
+		previous, state.ETag = nil, ""
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range v.GetStringMapString("remote.headers") {
+		req.Header.Set(name, ExpandEnv(value))
+	}
+	if state.ETag != "" {
+		req.Header.Set("If-None-Match", state.ETag)
+	}
+
+	client := &http.Client{Timeout: remoteTimeout, Transport: netproxy.Transport()}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error of the client repeats the URL with its signature
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to fetch %s: %v", result.Source, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		result.NotModified = true
+		state.SyncedAt = time.Now()
+		return result, writeRemoteState(statePath, state)
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("%s returned %s", result.Source, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, remoteMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", result.Source, err)
+	}
+	if len(data) > remoteMaxSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", result.Source, remoteMaxSize>>20)
+	}
+	var remote struct {
+		Environments map[string]interface{} `yaml:"environments"`
+	}
+	if err := yaml.Unmarshal(data, &remote); err != nil {
+		return nil, fmt.Errorf("%s is not valid YAML: %v", result.Source, err)
+	}
+	if len(remote.Environments) == 0 {
+		return nil, fmt.Errorf("%s has no environments", result.Source)
+	}
+	for name, env := range remote.Environments {
+		if _, ok := env.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("environment '%s' of %s is not a map", name, result.Source)
+		}
+	}
+
+	out, err := yaml.Marshal(map[string]interface{}{"environments": remote.Environments})
+	if err != nil {
+		return nil, err
+	}
+	if err := EnsureSecureDir(filepath.Dir(cachePath)); err != nil {
+		return nil, err
+	}
+	if err := WriteFileAtomic(cachePath, out, SecureFileMode); err != nil {
+		return nil, err
+	}
+	state = remoteState{URL: result.Source, ETag: resp.Header.Get("ETag"), SyncedAt: time.Now()}
+	if err := writeRemoteState(statePath, state); err != nil {
+		return nil, err
+	}
+
+	for name, env := range remote.Environments {
+		old, ok := previous[name]
+		switch {
+		case !ok:
+			result.Added = append(result.Added, name)
+		case !equalYAML(old, env):
+			result.Updated = append(result.Updated, name)
+		}
+	}
+	for name := range previous {
+		if _, ok := remote.Environments[name]; !ok {
+			result.Removed = append(result.Removed, name)
+		}
+	}
+	sort.Strings(result.Added)
+	sort.Strings(result.Updated)
+	sort.Strings(result.Removed)
+	return result, nil
+}
+
+// RemoteSyncedAt returns when the remote environments of the setting in dir were last synced,
+// or the zero time when they never were
+func RemoteSyncedAt(dir string) time.Time {
+	_, statePath := remoteFiles(dir)
+	var state remoteState
+	if data, err := os.ReadFile(statePath); err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	return state.SyncedAt
+}
+
+// mergeRemote merges the cached environments of remote.url of own into acc. Nothing is merged
+// before the first 'cfctl setting sync', or when remote.url points elsewhere since.
+func mergeRemote(acc *viper.Viper, own map[string]interface{}, dir string, origins map[string]string) error {
+	remote, _ := own["remote"].(map[string]interface{})
+	rawURL, _ := remote["url"].(string)
+	rawURL = ExpandEnv(rawURL)
+	if rawURL == "" {
+		return nil
+	}
+
+	cachePath, statePath := remoteFiles(dir)
+	var state remoteState
+	if data, err := os.ReadFile(statePath); err != nil || json.Unmarshal(data, &state) != nil || state.URL != remoteSource(rawURL) {
+		return nil
+	}
+	environments, err := readRemoteEnvironments(cachePath)
+	if err != nil {
+		return fmt.Errorf("failed to read the environments synced from %s: %v", remoteSource(rawURL), err)
+	}
+
+	settings := map[string]interface{}{"environments": environments}
+	source := remoteSource(rawURL)
+	for _, key := range leafKeys(settings, "") {
+		origins[key] = source
+	}
+	return acc.MergeConfigMap(settings)
+}
+
+// readRemoteEnvironments reads the cached remote environments, nil when there are none
+func readRemoteEnvironments(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cached struct {
+		Environments map[string]interface{} `yaml:"environments"`
+	}
+	if err := yaml.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	return cached.Environments, nil
+}
+
+func writeRemoteState(path string, state remoteState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := EnsureSecureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, data, SecureFileMode)
+}
+
+// equalYAML compares two decoded YAML values by their encoding
+func equalYAML(a, b interface{}) bool {
+	encodedA, errA := yaml.Marshal(a)
+	encodedB, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
@@ -0,0 +1,93 @@
+package configs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SecretRefPrefix marks a value of setting.yaml kept in the credential store instead of the
+// file, e.g. "token: credential:token/dev-app"
+const SecretRefPrefix = "credential:"
+
+var (
+	secretMu       sync.Mutex
+	secretResolver func(key string) (string, error)
+	secretStorer   func(key, secret string) string
+	secretRemover  func(key string) error
+)
+
+// SetSecretResolver sets the function looking up the keys of references in the credential
+// store. References resolve to an empty value until it is set.
+func SetSecretResolver(resolve func(key string) (string, error)) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secretResolver = resolve
+}
+
+// SetSecretStorer sets the functions keeping secrets in the credential store and removing them,
+// for the tokens cached by login. store returns the reference to write in place of the secret,
+// or the secret itself when it cannot be stored. Cached tokens are written as they are until it
+// is set.
+func SetSecretStorer(store func(key, secret string) string, remove func(key string) error) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secretStorer = store
+	secretRemover = remove
+}
+
+// storeSecret keeps secret under key with the storer and returns what to write in its place
+func storeSecret(key, secret string) string {
+	secretMu.Lock()
+	store := secretStorer
+	secretMu.Unlock()
+	if store == nil || secret == "" {
+		return secret
+	}
+	return store(key, secret)
+}
+
+// removeSecret removes the secret value refers to, if it is a reference
+func removeSecret(value string) error {
+	secretMu.Lock()
+	remove := secretRemover
+	secretMu.Unlock()
+	if !IsSecretRef(value) || remove == nil {
+		return nil
+	}
+	return remove(strings.TrimPrefix(value, SecretRefPrefix))
+}
+
+// SecretRef returns the reference to key to be written to setting.yaml
+func SecretRef(key string) string {
+	return SecretRefPrefix + key
+}
+
+// IsSecretRef reports whether value refers to the credential store
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretRefPrefix)
+}
+
+// ResolveSecret returns the secret value refers to, or value itself when it is no reference
+func ResolveSecret(value string) (string, error) {
+	if !IsSecretRef(value) {
+		return value, nil
+	}
+	secretMu.Lock()
+	resolve := secretResolver
+	secretMu.Unlock()
+	if resolve == nil {
+		return "", fmt.Errorf("no credential store to resolve %s", value)
+	}
+	return resolve(strings.TrimPrefix(value, SecretRefPrefix))
+}
+
+// resolveSecret is ResolveSecret for reading settings, where an unresolved reference reads as
+// empty like a missing value. The resolver reports why.
+func resolveSecret(value string) string {
+	resolved, err := ResolveSecret(value)
+	if err != nil {
+		return ""
+	}
+	return resolved
+}
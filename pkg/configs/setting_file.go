@@ -2,11 +2,8 @@ package configs
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/spf13/viper"
 )
 
 // Environments represents the complete configuration structure
@@ -24,12 +21,7 @@ type Environment struct {
 
 // SetSettingFile loads the setting from the default location (~/.cfctl/setting.yaml)
 func SetSettingFile() (*Environments, error) {
-	settingPath, err := GetSettingFilePath()
-	if err != nil {
-		return nil, err
-	}
-
-	currentEnvName, err := getCurrentEnvName(settingPath)
+	currentEnvName, err := getCurrentEnvName()
 	if err != nil {
 		return nil, err
 	}
@@ -49,17 +41,17 @@ func SetSettingFile() (*Environments, error) {
 
 // GetSettingFilePath returns the path to the setting file in the .cfctl directory
 func GetSettingFilePath() (string, error) {
-	home, err := os.UserHomeDir()
+	settingDir, err := SettingDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %v", err)
+		return "", err
 	}
 
-	return filepath.Join(home, ".cfctl", "setting.yaml"), nil
+	return filepath.Join(settingDir, "setting.yaml"), nil
 }
 
-// getCurrentEnvName loads the main setting file using viper
-func getCurrentEnvName(settingPath string) (*Environments, error) {
-	v, err := setViperWithSetting(settingPath)
+// getCurrentEnvName loads the main setting file
+func getCurrentEnvName() (*Environments, error) {
+	v, err := Setting()
 	if err != nil {
 		return nil, err
 	}
@@ -74,19 +66,22 @@ func getCurrentEnvName(settingPath string) (*Environments, error) {
 
 // getCurrentEnvValues loads environment-specific setting
 func getCurrentEnvValues(env string) (*Environment, error) {
-	settingPath, err := GetSettingFilePath()
+	v, err := Setting()
 	if err != nil {
 		return nil, err
 	}
 
-	v, err := setViperWithSetting(settingPath)
+	envConfig, err := DecodeEnvironment(v, env)
 	if err != nil {
 		return nil, err
 	}
+	if err := ValidateEndpoint(envConfig.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid setting.yaml: environments.%s.endpoint: %v", env, err)
+	}
 
 	envSetting := &Environment{
-		Endpoint: v.GetString(fmt.Sprintf("environments.%s.endpoint", env)),
-		Proxy:    v.GetString(fmt.Sprintf("environments.%s.proxy", env)),
+		Endpoint: envConfig.Endpoint,
+		Proxy:    envConfig.Proxy,
 	}
 
 	if err := loadToken(env, envSetting); err != nil {
@@ -98,6 +93,10 @@ func getCurrentEnvValues(env string) (*Environment, error) {
 
 // loadToken loads the appropriate token based on environment type
 func loadToken(env string, envSetting *Environment) error {
+	if token := EnvToken(); token != "" {
+		envSetting.Token = token
+		return nil
+	}
 	if strings.HasSuffix(env, "-user") {
 		return loadUserToken(env, envSetting)
 	}
@@ -107,15 +106,11 @@ func loadToken(env string, envSetting *Environment) error {
 
 // loadUserToken loads token for user environments from access_token file
 func loadUserToken(env string, envSetting *Environment) error {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %v", err)
-	}
-
-	tokenPath := filepath.Join(home, ".cfctl", "cache", env, "access_token")
-	tokenBytes, err := os.ReadFile(tokenPath)
+	accessToken, err := AccessToken(env)
 	if err == nil {
-		envSetting.Token = strings.TrimSpace(string(tokenBytes))
+		envSetting.Token = accessToken
+	} else if SelectedWorkspace() != "" {
+		return err
 	}
 
 	return nil
@@ -123,29 +118,12 @@ func loadUserToken(env string, envSetting *Environment) error {
 
 // loadAppToken loads token for app environments from main setting
 func loadAppToken(env string, envSetting *Environment) error {
-	settingPath, err := GetSettingFilePath()
-	if err != nil {
-		return err
-	}
-
-	v, err := setViperWithSetting(settingPath)
+	v, err := Setting()
 	if err != nil {
 		return err
 	}
 
-	envSetting.Token = v.GetString(fmt.Sprintf("environments.%s.token", env))
+	envSetting.Token = SettingString(v, fmt.Sprintf("environments.%s.token", env))
 
 	return nil
 }
-
-// setViperWithSetting creates a new viper instance with the given config file
-func setViperWithSetting(settingPath string) (*viper.Viper, error) {
-	v := viper.New()
-	v.SetConfigFile(settingPath)
-	v.SetConfigType("yaml")
-	if err := v.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %v", err)
-	}
-
-	return v, nil
-}
@@ -0,0 +1,300 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	homeOnce sync.Once
+	homeDir  string
+	homeErr  error
+)
+
+// HomeDir returns the home directory of the user, resolved once per process
+func HomeDir() (string, error) {
+	homeOnce.Do(func() {
+		homeDir, homeErr = os.UserHomeDir()
+	})
+	return homeDir, homeErr
+}
+
+// settingDirOverride is the directory given by the --config flag
+var settingDirOverride string
+
+// SetSettingDir makes SettingDir return dir instead of the default, for the --config flag
+func SetSettingDir(dir string) {
+	settingDirOverride = dir
+}
+
+// SettingDir returns the directory holding setting.yaml and the caches: the --config flag,
+// CFCTL_CONFIG_DIR or ~/.cfctl, in that order
+func SettingDir() (string, error) {
+	dir := settingDirOverride
+	if dir == "" {
+		dir = os.Getenv(EnvVarConfigDir)
+	}
+	if dir != "" {
+		return filepath.Abs(dir)
+	}
+
+	home, err := HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".cfctl"), nil
+}
+
+// settingStore keeps setting.yaml in memory for the whole command. Changes are marked dirty
+// and written once by FlushSetting instead of on every update.
+type settingStore struct {
+	mu      sync.Mutex
+	v       *viper.Viper
+	modTime time.Time
+	size    int64
+	dirty   bool
+	// base are the settings of the file as read, which FlushSetting compares v with to find
+	// the changes of the command
+	base map[string]interface{}
+}
+
+var store settingStore
+
+// sessionEnvironment takes the place of the environment of setting.yaml in this process only,
+// for 'use' in 'cfctl shell'
+var sessionEnvironment string
+
+// SetSessionEnvironment makes env the current environment of this process without writing it
+// to setting.yaml, so that other terminals keep their environment. An empty env goes back to
+// the environment of the file.
+func SetSessionEnvironment(env string) error {
+	if err := FlushSetting(); err != nil {
+		return err
+	}
+	v, err := Setting()
+	if err != nil {
+		return err
+	}
+	if env != "" && !v.IsSet(fmt.Sprintf("environments.%s", env)) {
+		return fmt.Errorf("environment '%s' not found in setting.yaml", env)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	sessionEnvironment = env
+	// The file is read again with the environment of the session
+	store.v = nil
+	return nil
+}
+
+// Setting returns setting.yaml, read once and shared by all callers of the command. The file is
+// read again only when it was changed on disk, e.g. by a command writing it directly, and the
+// shared copy holds no pending changes. A missing file is reported as is, for os.IsNotExist.
+func Setting() (*viper.Viper, error) {
+	settingPath, err := GetSettingFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	info, err := os.Stat(settingPath)
+	if err != nil {
+		if store.v != nil && store.dirty {
+			return store.v, nil
+		}
+		if !os.IsNotExist(err) || !EnvConfigured() {
+			return nil, err
+		}
+		// Containers may be configured by environment variables alone
+		if store.v == nil {
+			store.v = viper.New()
+			applyEnvOverrides(store.v)
+		}
+		return store.v, nil
+	}
+	if store.v != nil && (store.dirty || (info.ModTime().Equal(store.modTime) && info.Size() == store.size)) {
+		return store.v, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(settingPath)
+	v.SetConfigType("yaml")
+	v.SetConfigPermissions(SecureFileMode)
+	if err := ReadConfig(v); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	store.base = copySettings(OwnSettings(v))
+	if sessionEnvironment != "" {
+		v.Set("environment", sessionEnvironment)
+	}
+	if EnvConfigured() {
+		applyEnvOverrides(v)
+	}
+
+	store.v = v
+	store.modTime = info.ModTime()
+	store.size = info.Size()
+	return v, nil
+}
+
+// MarkSettingDirty records that the setting returned by Setting was changed, so that
+// FlushSetting writes it at the end of the command
+func MarkSettingDirty() {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.dirty = store.v != nil
+}
+
+// FlushSetting writes the pending changes of the setting, if any. The file is read again under
+// its lock and only the keys the command changed are applied to it, so that changes made by
+// other processes since the command started are kept. Nothing is written while environment
+// variables override the setting, so that they do not end up in the file.
+func FlushSetting() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if !store.dirty || EnvConfigured() {
+		return nil
+	}
+
+	current := OwnSettings(store.v)
+	if sessionEnvironment != "" {
+		if current["environment"] == sessionEnvironment {
+			// The environment of the session is not saved
+			restoreSetting(current, store.base, "environment")
+		} else {
+			// The command switched the environment of the file, which the session follows again
+			sessionEnvironment = ""
+		}
+	}
+	err := updateLocked(store.v.ConfigFileUsed(), func(disk *viper.Viper) (map[string]interface{}, error) {
+		settings := OwnSettings(disk)
+		applySettingChanges(settings, store.base, current)
+		return settings, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save config file: %v", err)
+	}
+	store.dirty = false
+	store.base = copySettings(current)
+	// The file may now hold changes of other processes, so the next Setting reads it again
+	store.modTime = time.Time{}
+	return nil
+}
+
+// restoreSetting sets the key of settings back to its value in base, or removes it
+func restoreSetting(settings, base map[string]interface{}, key string) {
+	if value, ok := base[key]; ok {
+		settings[key] = value
+	} else {
+		delete(settings, key)
+	}
+}
+
+// applySettingChanges applies to settings the values that changed from base to current: the
+// values added or changed are set and the removed ones deleted
+func applySettingChanges(settings, base, current map[string]interface{}) {
+	baseLeaves := settingLeaves(base, nil)
+	currentLeaves := settingLeaves(current, nil)
+
+	for key, leaf := range baseLeaves {
+		if _, ok := currentLeaves[key]; !ok {
+			deleteSetting(settings, leaf.path)
+		}
+	}
+	for key, leaf := range currentLeaves {
+		if baseLeaf, ok := baseLeaves[key]; !ok || !reflect.DeepEqual(baseLeaf.value, leaf.value) {
+			setSetting(settings, leaf.path, leaf.value)
+		}
+	}
+}
+
+// settingLeaf is a value of the settings that is not a non-empty map, with its path
+type settingLeaf struct {
+	path  []string
+	value interface{}
+}
+
+// settingLeaves returns the leaves of settings keyed by their path. Keys are joined with a
+// NUL, as keys such as environment names may contain dots.
+func settingLeaves(settings map[string]interface{}, prefix []string) map[string]settingLeaf {
+	leaves := make(map[string]settingLeaf)
+	for key, value := range settings {
+		path := append(append([]string(nil), prefix...), key)
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			for nestedKey, leaf := range settingLeaves(nested, path) {
+				leaves[nestedKey] = leaf
+			}
+			continue
+		}
+		leaves[strings.Join(path, "\x00")] = settingLeaf{path: path, value: value}
+	}
+	return leaves
+}
+
+// setSetting sets the value at path, replacing values that are in the way by maps. An empty
+// map leaves a map already at path as is.
+func setSetting(settings map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		nested, ok := settings[key].(map[string]interface{})
+		if !ok {
+			nested = make(map[string]interface{})
+			settings[key] = nested
+		}
+		settings = nested
+	}
+	key := path[len(path)-1]
+	if empty, ok := value.(map[string]interface{}); ok && len(empty) == 0 {
+		if _, ok := settings[key].(map[string]interface{}); ok {
+			return
+		}
+	}
+	settings[key] = copyValue(value)
+}
+
+// deleteSetting deletes the value at path, and the maps it leaves empty
+func deleteSetting(settings map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(settings, path[0])
+		return
+	}
+	nested, ok := settings[path[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	deleteSetting(nested, path[1:])
+	if len(nested) == 0 {
+		delete(settings, path[0])
+	}
+}
+
+// copySettings returns a deep copy of settings, so that later changes do not affect it
+func copySettings(settings map[string]interface{}) map[string]interface{} {
+	return copyValue(settings).(map[string]interface{})
+}
+
+func copyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			out[key] = copyValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			out[i] = copyValue(item)
+		}
+		return out
+	}
+	return value
+}
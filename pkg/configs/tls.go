@@ -0,0 +1,93 @@
+package configs
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/cloudforet-io/cfctl/internal/tlsconf"
+	"github.com/spf13/viper"
+)
+
+// TLSConfig is the tls entry of an environment. The files may start with ~ and be relative to
+// the setting directory.
+type TLSConfig struct {
+	CAFile             string `mapstructure:"ca_file"`
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+}
+
+// Validate checks the entry without reading the files
+func (c TLSConfig) Validate() error {
+	return tlsconf.Settings{CertFile: c.CertFile, KeyFile: c.KeyFile}.Validate()
+}
+
+// Settings resolves the file paths of the entry for tlsconf
+func (c TLSConfig) Settings() (tlsconf.Settings, error) {
+	settings := tlsconf.Settings{InsecureSkipVerify: c.InsecureSkipVerify}
+	dir, err := SettingDir()
+	if err != nil {
+		return settings, err
+	}
+	for _, file := range []struct {
+		value  string
+		target *string
+	}{
+		{c.CAFile, &settings.CAFile},
+		{c.CertFile, &settings.CertFile},
+		{c.KeyFile, &settings.KeyFile},
+	} {
+		if file.value == "" {
+			continue
+		}
+		if *file.target, err = includePath(file.value, dir); err != nil {
+			return settings, err
+		}
+	}
+	return settings, nil
+}
+
+var (
+	connectionMu  sync.RWMutex
+	connectionEnv string
+)
+
+// PinConnectionEnvironment makes TLSSettings and NetworkProxy read the named environment instead
+// of the current one, for sessions pinned to an environment like 'cfctl compare'. An empty env
+// goes back to the current environment.
+func PinConnectionEnvironment(env string) {
+	connectionMu.Lock()
+	defer connectionMu.Unlock()
+	connectionEnv = env
+}
+
+// connectionEnvironment returns the pinned environment, or else the current one of v
+func connectionEnvironment(v *viper.Viper) string {
+	connectionMu.RLock()
+	defer connectionMu.RUnlock()
+	if connectionEnv != "" {
+		return connectionEnv
+	}
+	return v.GetString("environment")
+}
+
+// TLSSettings returns the tls settings of the pinned or current environment, which tlsconf
+// connects with
+func TLSSettings() (tlsconf.Settings, error) {
+	v, err := Setting()
+	if err != nil {
+		return tlsconf.Settings{}, nil
+	}
+	return environmentTLS(v, connectionEnvironment(v)).Settings()
+}
+
+// environmentTLS reads environments.<env>.tls of v, without decoding the whole environment
+func environmentTLS(v *viper.Viper, env string) TLSConfig {
+	prefix := fmt.Sprintf("environments.%s.tls.", env)
+	return TLSConfig{
+		CAFile:             ExpandEnv(v.GetString(prefix + "ca_file")),
+		CertFile:           ExpandEnv(v.GetString(prefix + "cert_file")),
+		KeyFile:            ExpandEnv(v.GetString(prefix + "key_file")),
+		InsecureSkipVerify: SettingBool(v, prefix+"insecure_skip_verify"),
+	}
+}
@@ -0,0 +1,268 @@
+package configs
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/token"
+)
+
+// EnvVarWorkspace selects, by name or ID, the workspace whose cached token the commands of a
+// terminal use, so that terminals can work in different workspaces of an environment at once
+const EnvVarWorkspace = "CFCTL_WORKSPACE"
+
+var selectedWorkspace string
+
+// SelectWorkspace makes the commands of the process use the token cached for the workspace,
+// taking precedence over CFCTL_WORKSPACE
+func SelectWorkspace(workspace string) {
+	selectedWorkspace = workspace
+}
+
+// SelectedWorkspace returns the workspace selected by SelectWorkspace or CFCTL_WORKSPACE, if any
+func SelectedWorkspace() string {
+	if selectedWorkspace != "" {
+		return selectedWorkspace
+	}
+	return os.Getenv(EnvVarWorkspace)
+}
+
+// TokenDir returns the directory of the tokens of a user environment. Unlike CacheDir, it is
+// always in the setting directory.
+func TokenDir(env string) (string, error) {
+	settingDir, err := SettingDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(settingDir, "cache", env), nil
+}
+
+// WorkspaceTokenDir returns the directory of the workspace tokens of a user in a user environment,
+// so that the users of an environment do not pick up each other's workspace tokens. Environments
+// without a user_id keep them under workspaces of the environment.
+func WorkspaceTokenDir(env, userID string) (string, error) {
+	dir, err := TokenDir(env)
+	if err != nil {
+		return "", err
+	}
+	if userID == "" {
+		return filepath.Join(dir, "workspaces"), nil
+	}
+	return filepath.Join(dir, "users", url.PathEscape(userID), "workspaces"), nil
+}
+
+// environmentUser returns the user_id the user environment is logged in with, if any
+func environmentUser(env string) string {
+	v, err := Setting()
+	if err != nil {
+		return ""
+	}
+	return SettingString(v, fmt.Sprintf("environments.%s.user_id", env))
+}
+
+// StoreAccessToken caches an access token granted for a user environment. A workspace token
+// is also kept under the workspace directory of the user (see WorkspaceTokenDir), with the
+// workspace name when known. The token becomes the default of the environment unless a
+// workspace is selected, so that a session working in its own workspace does not replace the
+// token of the other sessions.
+func StoreAccessToken(env, accessToken, workspaceName string) error {
+	dir, err := TokenDir(env)
+	if err != nil {
+		return err
+	}
+	if err := EnsureSecureDir(dir); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	workspaceID := ""
+	if claims, err := token.Decode(accessToken); err == nil {
+		workspaceID = claims.WorkspaceID()
+	}
+	if workspaceID != "" {
+		root, err := WorkspaceTokenDir(env, environmentUser(env))
+		if err != nil {
+			return err
+		}
+		workspaceDir := filepath.Join(root, workspaceID)
+		if err := EnsureSecureDir(workspaceDir); err != nil {
+			return fmt.Errorf("failed to create cache directory: %v", err)
+		}
+		if err := WriteCachedToken(filepath.Join(workspaceDir, "access_token"), accessToken); err != nil {
+			return fmt.Errorf("failed to save access token: %v", err)
+		}
+		if workspaceName != "" {
+			if err := WriteSecureFile(filepath.Join(workspaceDir, "name"), []byte(workspaceName)); err != nil {
+				return fmt.Errorf("failed to save workspace name: %v", err)
+			}
+		}
+	}
+
+	if SelectedWorkspace() != "" {
+		return nil
+	}
+	if err := WriteCachedToken(filepath.Join(dir, "access_token"), accessToken); err != nil {
+		return fmt.Errorf("failed to save access token: %v", err)
+	}
+	return nil
+}
+
+// WorkspaceName returns the name cached with the token of a workspace for the user of a user
+// environment by StoreAccessToken, if any
+func WorkspaceName(env, workspaceID string) string {
+	if workspaceID == "" {
+		return ""
+	}
+	root, err := WorkspaceTokenDir(env, environmentUser(env))
+	if err != nil {
+		return ""
+	}
+	name, err := os.ReadFile(filepath.Join(root, workspaceID, "name"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(name))
+}
+
+// AccessTokenPath returns the file of the access token the commands of a user environment use:
+// the one of the selected workspace for the user of the environment, or else the default one of
+// the environment
+func AccessTokenPath(env string) (string, error) {
+	dir, err := TokenDir(env)
+	if err != nil {
+		return "", err
+	}
+	workspace := SelectedWorkspace()
+	if workspace == "" {
+		return filepath.Join(dir, "access_token"), nil
+	}
+
+	root, err := WorkspaceTokenDir(env, environmentUser(env))
+	if err != nil {
+		return "", err
+	}
+	if workspace == filepath.Base(workspace) {
+		path := filepath.Join(root, workspace, "access_token")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		name, err := os.ReadFile(filepath.Join(root, entry.Name(), "name"))
+		if err == nil && strings.EqualFold(strings.TrimSpace(string(name)), workspace) {
+			return filepath.Join(root, entry.Name(), "access_token"), nil
+		}
+	}
+	return "", fmt.Errorf("no token is cached for workspace '%s' of %s, run 'cfctl login --workspace %s'", workspace, env, workspace)
+}
+
+// AccessToken returns the cached access token of a user environment, see AccessTokenPath
+func AccessToken(env string) (string, error) {
+	path, err := AccessTokenPath(env)
+	if err != nil {
+		return "", err
+	}
+	return ReadCachedToken(path)
+}
+
+// cachedTokenNames are the files of the tokens cached for user environments
+var cachedTokenNames = []string{"access_token", "refresh_token", "grant_token"}
+
+// CachedTokenKey returns the key of the token cached in the file at path in the credential store,
+// named after the file in the setting directory, e.g. cache/dev-user/refresh_token
+func CachedTokenKey(path string) (string, error) {
+	settingDir, err := SettingDir()
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(settingDir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// WriteCachedToken caches a token of a user environment in the file at path. The token is kept in
+// the credential store and the file only holds the reference to it, or the token itself when it
+// cannot be stored, e.g. with 'credential_store: none'.
+func WriteCachedToken(path, token string) error {
+	key, err := CachedTokenKey(path)
+	if err != nil {
+		return err
+	}
+	return WriteSecureFile(path, []byte(storeSecret(key, token)))
+}
+
+// ReadCachedToken returns the token cached in the file at path by WriteCachedToken
+func ReadCachedToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return ResolveSecret(strings.TrimSpace(string(data)))
+}
+
+// RemoveCachedToken removes the file of a cached token and the secret it refers to. A missing
+// file is not an error.
+func RemoveCachedToken(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := removeSecret(strings.TrimSpace(string(data))); err != nil {
+		return fmt.Errorf("failed to remove %s from the credential store: %v", filepath.Base(path), err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// RemoveCachedTokens removes dir with the cached tokens under it and the secrets they refer to
+func RemoveCachedTokens(dir string) error {
+	paths, err := cachedTokenFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := RemoveCachedToken(path); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+// CachedTokenFiles returns the files of the tokens cached for the user environments
+func CachedTokenFiles() ([]string, error) {
+	settingDir, err := SettingDir()
+	if err != nil {
+		return nil, err
+	}
+	return cachedTokenFiles(filepath.Join(settingDir, "cache"))
+}
+
+// cachedTokenFiles returns the files of cached tokens under dir
+func cachedTokenFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() && slices.Contains(cachedTokenNames, entry.Name()) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
@@ -0,0 +1,37 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// verbatimSections are the sections of setting.yaml keyed by names that viper would lowercase
+// and split on dots: resource names like inventory.CloudService, column paths like
+// data.disk_size and currency codes. Their readers parse the file without viper.
+var verbatimSections = []string{"id_fields", "column_formats", "currency"}
+
+// KeepVerbatimSections replaces the verbatim sections of settings read through viper by those
+// of the file at path as written, so that writing the settings back keeps their keys. Sections
+// the file does not have are left as they are.
+func KeepVerbatimSections(path string, settings map[string]interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	for _, section := range verbatimSections {
+		if value, ok := raw[section]; ok {
+			settings[section] = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// Package cron parses standard five-field cron expressions and calculates their next run times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	Expression string
+	minutes    map[int]bool
+	hours      map[int]bool
+	days       map[int]bool
+	months     map[int]bool
+	weekdays   map[int]bool
+	anyDay     bool
+	anyWeekday bool
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// fieldSpec describes the allowed range of a cron field
+type fieldSpec struct {
+	name     string
+	min, max int
+}
+
+var fieldSpecs = []fieldSpec{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Parse validates a cron expression like '0 */6 * * *' and returns its schedule
+func Parse(expression string) (*Schedule, error) {
+	expanded := strings.TrimSpace(expression)
+	if macro, ok := macros[expanded]; ok {
+		expanded = macro
+	}
+
+	fields := strings.Fields(expanded)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression '%s': expected 5 fields (minute hour day-of-month month day-of-week), got %d", expression, len(fields))
+	}
+
+	values := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		parsed, err := parseField(field, fieldSpecs[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%s': %v", expression, err)
+		}
+		values[i] = parsed
+	}
+
+	// Sunday can be written as 0 or 7
+	if values[4][7] {
+		values[4][0] = true
+		delete(values[4], 7)
+	}
+
+	schedule := &Schedule{
+		Expression: expression,
+		minutes:    values[0],
+		hours:      values[1],
+		days:       values[2],
+		months:     values[3],
+		weekdays:   values[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("invalid cron expression '%s': it never runs", expression)
+	}
+
+	return schedule, nil
+}
+
+// parseField parses a comma separated list of values, ranges and steps
+func parseField(field string, spec fieldSpec) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if slash := strings.Index(part, "/"); slash >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[slash+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %s field '%s'", spec.name, part)
+			}
+			part = part[:slash]
+		}
+
+		start, end := spec.min, spec.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			start, err1 = strconv.Atoi(bounds[0])
+			end, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || start > end {
+				return nil, fmt.Errorf("invalid range in %s field '%s'", spec.name, part)
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value in %s field '%s'", spec.name, part)
+			}
+			start = value
+			if step == 1 {
+				end = value
+			}
+		}
+
+		if start < spec.min || end > spec.max {
+			return nil, fmt.Errorf("%s field '%s' is out of range %d-%d", spec.name, field, spec.min, spec.max)
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+// Next returns the first run time after t, or the zero time when there is none within five years
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+		if !s.months[int(next.Month())] {
+			next = after(next, time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location()))
+			continue
+		}
+		if !s.matchesDay(next) {
+			next = after(next, time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location()))
+			continue
+		}
+		if !s.hours[next.Hour()] {
+			// The next hour is built in local time, as zones may be offset by half an hour
+			next = after(next, time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location()))
+			continue
+		}
+		if !s.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+
+	return time.Time{}
+}
+
+// after returns candidate, moved by hours until it is after t. time.Date normalizes the wall
+// clock times skipped by daylight saving time to before the skip, which may be before t.
+func after(t, candidate time.Time) time.Time {
+	for !candidate.After(t) {
+		candidate = candidate.Add(time.Hour)
+	}
+	return candidate
+}
+
+// Hours returns the hours of the schedule in ascending order
+func (s *Schedule) Hours() []int {
+	var hours []int
+	for hour := 0; hour < 24; hour++ {
+		if s.hours[hour] {
+			hours = append(hours, hour)
+		}
+	}
+	return hours
+}
+
+// IsHourly reports whether the schedule runs at minute 0 of some hours on every day
+func (s *Schedule) IsHourly() bool {
+	return len(s.minutes) == 1 && s.minutes[0] && s.anyDay && s.anyWeekday && len(s.months) == 12
+}
+
+// matchesDay applies the cron rule that day of month and day of week are OR-ed when both are restricted
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dayMatch := s.days[t.Day()]
+	weekdayMatch := s.weekdays[int(t.Weekday())]
+
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekdayMatch
+	case s.anyWeekday:
+		return dayMatch
+	default:
+		return dayMatch || weekdayMatch
+	}
+}
+
+// FromHours builds the cron expression running at minute 0 of the given hours
+func FromHours(hours []int) string {
+	if len(hours) == 0 {
+		return ""
+	}
+	parts := make([]string, len(hours))
+	for i, hour := range hours {
+		parts[i] = strconv.Itoa(hour)
+	}
+	return fmt.Sprintf("0 %s * * *", strings.Join(parts, ","))
+}
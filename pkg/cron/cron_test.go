@@ -0,0 +1,149 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    bool
+	}{
+		{expression: "0 9 * * *"},
+		{expression: "*/15 * * * *"},
+		{expression: "0 2,14 * * *"},
+		{expression: "0 22 * * 6"},
+		{expression: "0 0 * * 7"},
+		{expression: "30 1-5/2 1 * *"},
+		{expression: "@daily"},
+		{expression: "@hourly"},
+		{expression: "0 0 29 2 *"},
+		{expression: "0 9 * *", wantErr: true},
+		{expression: "60 * * * *", wantErr: true},
+		{expression: "0 24 * * *", wantErr: true},
+		{expression: "0 0 0 * *", wantErr: true},
+		{expression: "0 0 * 13 *", wantErr: true},
+		{expression: "0 5-1 * * *", wantErr: true},
+		{expression: "*/0 * * * *", wantErr: true},
+		{expression: "x * * * *", wantErr: true},
+		{expression: "0 0 31 2 *", wantErr: true},
+	}
+
+	locations := []string{"UTC", "Asia/Kolkata", "Asia/Kathmandu", "America/St_Johns"}
+	for _, name := range locations {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Skipf("time zone %s not available: %v", name, err)
+		}
+
+		local := time.Local
+		time.Local = loc
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.expression, func(t *testing.T) {
+				_, err := Parse(tt.expression)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expression, err, tt.wantErr)
+				}
+			})
+		}
+		time.Local = local
+	}
+}
+
+func TestNext(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skipf("time zone Asia/Kolkata not available: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone America/New_York not available: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		from       time.Time
+		want       time.Time
+	}{
+		{
+			name:       "later the same day",
+			expression: "0 9 * * *",
+			from:       time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "next day",
+			expression: "0 9 * * *",
+			from:       time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "half-hour offset zone",
+			expression: "0 9 * * *",
+			from:       time.Date(2024, 1, 15, 10, 15, 0, 0, kolkata),
+			want:       time.Date(2024, 1, 16, 9, 0, 0, 0, kolkata),
+		},
+		{
+			name:       "minutes within the hour in a half-hour offset zone",
+			expression: "45 */6 * * *",
+			from:       time.Date(2024, 1, 15, 0, 50, 0, 0, kolkata),
+			want:       time.Date(2024, 1, 15, 6, 45, 0, 0, kolkata),
+		},
+		{
+			name:       "seconds are skipped",
+			expression: "*/15 * * * *",
+			from:       time.Date(2024, 1, 15, 8, 14, 59, 0, time.UTC),
+			want:       time.Date(2024, 1, 15, 8, 15, 0, 0, time.UTC),
+		},
+		{
+			name:       "weekday",
+			expression: "0 22 * * 6",
+			from:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 20, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "day of month or weekday",
+			expression: "0 0 1 * 5",
+			from:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "next month",
+			expression: "0 0 1 * *",
+			from:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "leap day",
+			expression: "0 0 29 2 *",
+			from:       time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			want:       time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "skipped hour of daylight saving time",
+			expression: "30 2 * * *",
+			from:       time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+			want:       time.Date(2024, 3, 11, 2, 30, 0, 0, newYork),
+		},
+		{
+			name:       "after daylight saving time starts",
+			expression: "0 3 * * *",
+			from:       time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+			want:       time.Date(2024, 3, 10, 3, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expression, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+)
+
+// CurrencyConverter converts amounts to the display currency.
+// Rates are the value of one unit of a base currency in each currency, e.g. {"USD": 1, "KRW": 1380}.
+type CurrencyConverter struct {
+	Display string
+	Rates   map[string]float64
+	Source  string
+}
+
+// Convert converts an amount in the given currency to the display currency.
+// It returns false when a rate of either currency is missing.
+func (c *CurrencyConverter) Convert(amount float64, from string) (float64, bool) {
+	from = strings.ToUpper(from)
+	if from == "" || from == c.Display {
+		return amount, true
+	}
+
+	fromRate, ok := c.Rates[from]
+	if !ok || fromRate == 0 {
+		return 0, false
+	}
+	toRate, ok := c.Rates[c.Display]
+	if !ok {
+		return 0, false
+	}
+	return amount / fromRate * toRate, true
+}
+
+// Converts reports whether amounts in the currency are shown converted
+func (c *CurrencyConverter) Converts(from string) bool {
+	return c != nil && from != "" && !strings.EqualFold(from, c.Display)
+}
+
+// Note describes the rate used to convert from the currency, e.g. "1 USD = 1,380 KRW (static rate)"
+func (c *CurrencyConverter) Note(from string) string {
+	rate, ok := c.Convert(1, from)
+	if !ok {
+		return ""
+	}
+	formatted := FormatNumber(rate)
+	if rate < 1 {
+		formatted = fmt.Sprintf("%.6g", rate)
+	}
+	return fmt.Sprintf("1 %s = %s %s (%s)", strings.ToUpper(from), formatted, c.Display, c.Source)
+}
+
+// NewCurrencyConverter returns the converter to the display currency, or nil when no display
+// currency is set. displayOverride replaces the configured display currency when given.
+// Static rates take precedence over the ones fetched from the rate source.
+func NewCurrencyConverter(displayOverride string) (*CurrencyConverter, error) {
+	setting, err := configs.LoadCurrencySetting()
+	if err != nil {
+		return nil, err
+	}
+
+	display := strings.ToUpper(setting.Display)
+	if displayOverride != "" {
+		display = strings.ToUpper(displayOverride)
+	}
+	if display == "" {
+		return nil, nil
+	}
+
+	rates := make(map[string]float64)
+	source := "static rate"
+	if setting.RateSource != "" {
+		fetched, err := configs.LoadExchangeRates(setting.RateSource)
+		if err != nil {
+			if len(setting.Rates) == 0 {
+				return nil, fmt.Errorf("failed to fetch exchange rates: %v", err)
+			}
+		} else {
+			for code, rate := range fetched {
+				rates[strings.ToUpper(code)] = rate
+			}
+			source = "rate from " + setting.RateSource
+		}
+	}
+	for code, rate := range setting.Rates {
+		rates[strings.ToUpper(code)] = rate
+	}
+
+	if _, ok := rates[display]; !ok {
+		return nil, fmt.Errorf("no exchange rate for display currency '%s'", display)
+	}
+
+	return &CurrencyConverter{Display: display, Rates: rates, Source: source}, nil
+}
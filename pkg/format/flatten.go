@@ -0,0 +1,38 @@
+package format
+
+// DefaultFlattenDepth is how many levels of nested messages are expanded by --flatten
+const DefaultFlattenDepth = 3
+
+// Flatten converts nested messages and Structs into dotted keys, e.g.
+// {"data": {"compute": {"instance_type": "t3.large"}}} becomes {"data.compute.instance_type": "t3.large"}.
+// Maps nested deeper than maxDepth and lists are kept as values so they render as JSON.
+func Flatten(item map[string]interface{}, maxDepth int) map[string]interface{} {
+	flat := make(map[string]interface{})
+	flattenInto(flat, "", item, maxDepth)
+	return flat
+}
+
+// FlattenResults flattens every item of a list response in place
+func FlattenResults(results []interface{}, maxDepth int) {
+	for i, result := range results {
+		if item, ok := result.(map[string]interface{}); ok {
+			results[i] = Flatten(item, maxDepth)
+		}
+	}
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, item map[string]interface{}, depth int) {
+	for key, value := range item {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		nested, ok := value.(map[string]interface{})
+		if !ok || len(nested) == 0 || depth <= 0 {
+			flat[path] = value
+			continue
+		}
+		flattenInto(flat, path, nested, depth-1)
+	}
+}
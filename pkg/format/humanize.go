@@ -0,0 +1,141 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Column formats usable in the column_formats setting
+const (
+	ColumnFormatBytes    = "bytes"
+	ColumnFormatNumber   = "number"
+	ColumnFormatCurrency = "currency"
+)
+
+// currencySymbols maps ISO 4217 codes to the symbol shown in front of amounts
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"KRW": "₩",
+	"JPY": "¥",
+	"EUR": "€",
+	"GBP": "£",
+	"CNY": "¥",
+}
+
+// currencyDecimals lists currencies displayed without minor units
+var currencyDecimals = map[string]int{
+	"KRW": 0,
+	"JPY": 0,
+}
+
+// HumanizeBytes renders a byte count with binary units, e.g. 1610612736 -> "1.5 GiB"
+func HumanizeBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	value := bytes
+	unit := 0
+	for math.Abs(value) >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%.0f %s", value, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// FormatNumber renders a number with thousand separators, keeping up to two decimals
+func FormatNumber(value float64) string {
+	decimals := 0
+	if value != math.Trunc(value) {
+		decimals = 2
+	}
+	return groupThousands(strconv.FormatFloat(value, 'f', decimals, 64))
+}
+
+// FormatCurrency renders an amount with the symbol of the currency, e.g. "$1,234.50" or "₩12,000".
+// Unknown currencies are shown with their code instead.
+func FormatCurrency(value float64, currency string) string {
+	currency = strings.ToUpper(currency)
+	decimals, ok := currencyDecimals[currency]
+	if !ok {
+		decimals = 2
+	}
+	amount := groupThousands(strconv.FormatFloat(math.Abs(value), 'f', decimals, 64))
+
+	sign := ""
+	if value < 0 {
+		sign = "-"
+	}
+	if symbol, ok := currencySymbols[currency]; ok {
+		return sign + symbol + amount
+	}
+	if currency == "" {
+		return sign + amount
+	}
+	return fmt.Sprintf("%s%s %s", sign, amount, currency)
+}
+
+// ApplyColumnFormat formats a value with a column format such as "bytes", "number" or "currency:KRW".
+// It returns false when the value is not numeric or the format is unknown, so the caller keeps its own rendering.
+func ApplyColumnFormat(value interface{}, columnFormat string) (string, bool) {
+	number, ok := toNumber(value)
+	if !ok {
+		return "", false
+	}
+
+	name, arg, _ := strings.Cut(columnFormat, ":")
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case ColumnFormatBytes:
+		return HumanizeBytes(number), true
+	case ColumnFormatNumber:
+		return FormatNumber(number), true
+	case ColumnFormatCurrency:
+		currency := strings.TrimSpace(arg)
+		if currency == "" {
+			currency = "USD"
+		}
+		return FormatCurrency(number, currency), true
+	}
+	return "", false
+}
+
+// groupThousands inserts commas into the integer part of a formatted number
+func groupThousands(formatted string) string {
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(formatted, ".")
+
+	var sb strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+	if hasFraction {
+		sb.WriteString("." + fraction)
+	}
+	return sign + sb.String()
+}
+
+// toNumber converts JSON numbers and numeric strings (int64 fields are strings in JSON) to float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -2,18 +2,11 @@ package format
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
 	"strings"
 
+	"github.com/cloudforet-io/cfctl/internal/grpcconn"
 	"github.com/cloudforet-io/cfctl/pkg/configs"
-	"github.com/spf13/viper"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -22,15 +15,8 @@ import (
 // ValidateServiceCommand checks if the given verb and resource are valid for the service
 func ValidateServiceCommand(service, verb, resourceName string) error {
 	// Get current environment from main setting file
-	home, err := os.UserHomeDir()
+	mainV, err := configs.Setting()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %v", err)
-	}
-
-	mainV := viper.New()
-	mainV.SetConfigFile(filepath.Join(home, ".cfctl", "setting.yaml"))
-	mainV.SetConfigType("yaml")
-	if err := mainV.ReadInConfig(); err != nil {
 		return fmt.Errorf("failed to read config: %v", err)
 	}
 
@@ -45,7 +31,7 @@ func ValidateServiceCommand(service, verb, resourceName string) error {
 		return fmt.Errorf("environment %s not found", currentEnv)
 	}
 
-	endpointName := envConfig.GetString("endpoint")
+	endpointName := configs.SettingString(envConfig, "endpoint")
 	if endpointName == "" {
 		return fmt.Errorf("no endpoint found in configuration")
 	}
@@ -74,11 +60,10 @@ func ValidateServiceCommand(service, verb, resourceName string) error {
 	resourceFound := false
 	verbFound := false
 
-	for _, row := range resources {
-		if row[2] == resourceName {
+	for _, resource := range resources {
+		if resource.Resource == resourceName {
 			resourceFound = true
-			verbs := strings.Split(row[1], ", ")
-			for _, v := range verbs {
+			for _, v := range resource.Verbs {
 				if v == verb {
 					verbFound = true
 					break
@@ -99,31 +84,22 @@ func ValidateServiceCommand(service, verb, resourceName string) error {
 	return nil
 }
 
-func FetchServiceResources(service, endpoint string, shortNamesMap map[string]string) ([][]string, error) {
-	parts := strings.Split(endpoint, "://")
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("invalid endpoint format: %s", endpoint)
-	}
-
-	scheme := parts[0]
-	hostPort := strings.SplitN(parts[1], "/", 2)[0]
-
-	var opts []grpc.DialOption
-	if scheme == "grpc+ssl" {
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: false,
-		}
-		creds := credentials.NewTLS(tlsConfig)
-		opts = append(opts, grpc.WithTransportCredentials(creds))
-	} else {
-		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	}
+// ServiceResource is a resource of a service with the verbs listed for it. A resource is listed
+// once for its verbs without an alias and once per verb with an alias.
+type ServiceResource struct {
+	Service  string
+	Verbs    []string
+	Resource string
+	Alias    string
+	FullName string // fully qualified gRPC service name, e.g. spaceone.api.inventory.v1.CloudService
+}
 
-	conn, err := grpc.Dial(hostPort, opts...)
+// FetchServiceResources lists the resources of a service with gRPC reflection
+func FetchServiceResources(service, endpoint string, shortNamesMap map[string]string) ([]ServiceResource, error) {
+	conn, err := grpcconn.GetEndpoint(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("connection failed: unable to connect to %s: %v", endpoint, err)
 	}
-	defer conn.Close()
 
 	client := grpc_reflection_v1alpha.NewServerReflectionClient(conn)
 	stream, err := client.ServerReflectionInfo(context.Background())
@@ -152,13 +128,16 @@ func FetchServiceResources(service, endpoint string, shortNamesMap map[string]st
 		return nil, fmt.Errorf("failed to load aliases: %v", err)
 	}
 
-	data := [][]string{}
+	var data []ServiceResource
 	for _, s := range services {
 		if strings.HasPrefix(s.Name, "grpc.reflection.v1alpha.") {
 			continue
 		}
 		resourceName := s.Name[strings.LastIndex(s.Name, ".")+1:]
-		verbs := getServiceMethods(client, s.Name)
+		verbs, err := getServiceMethods(client, s.Name)
+		if err != nil {
+			return nil, err
+		}
 
 		// Group verbs by alias
 		verbsWithAlias := make(map[string]string)
@@ -187,22 +166,22 @@ func FetchServiceResources(service, endpoint string, shortNamesMap map[string]st
 
 		// Add row for verbs without aliases
 		if len(remainingVerbs) > 0 {
-			data = append(data, []string{service, strings.Join(remainingVerbs, ", "), resourceName, ""})
+			data = append(data, ServiceResource{Service: service, Verbs: remainingVerbs, Resource: resourceName, FullName: s.Name})
 		}
 
 		// Add separate rows for each verb with an alias
 		for verb, alias := range verbsWithAlias {
-			data = append(data, []string{service, verb, resourceName, alias})
+			data = append(data, ServiceResource{Service: service, Verbs: []string{verb}, Resource: resourceName, Alias: alias, FullName: s.Name})
 		}
 	}
 
 	return data, nil
 }
 
-func getServiceMethods(client grpc_reflection_v1alpha.ServerReflectionClient, serviceName string) []string {
+func getServiceMethods(client grpc_reflection_v1alpha.ServerReflectionClient, serviceName string) ([]string, error) {
 	stream, err := client.ServerReflectionInfo(context.Background())
 	if err != nil {
-		log.Fatalf("Failed to create reflection client: %v", err)
+		return nil, fmt.Errorf("failed to create reflection client: %v", err)
 	}
 
 	req := &grpc_reflection_v1alpha.ServerReflectionRequest{
@@ -210,24 +189,24 @@ func getServiceMethods(client grpc_reflection_v1alpha.ServerReflectionClient, se
 	}
 
 	if err := stream.Send(req); err != nil {
-		log.Fatalf("Failed to send reflection request: %v", err)
+		return nil, fmt.Errorf("failed to send reflection request: %v", err)
 	}
 
 	resp, err := stream.Recv()
 	if err != nil {
-		log.Fatalf("Failed to receive reflection response: %v", err)
+		return nil, fmt.Errorf("failed to receive reflection response: %v", err)
 	}
 
 	fileDescriptor := resp.GetFileDescriptorResponse()
 	if fileDescriptor == nil {
-		return []string{}
+		return []string{}, nil
 	}
 
 	methods := []string{}
 	for _, fdBytes := range fileDescriptor.FileDescriptorProto {
 		fd := &descriptorpb.FileDescriptorProto{}
 		if err := proto.Unmarshal(fdBytes, fd); err != nil {
-			log.Fatalf("Failed to unmarshal file descriptor: %v", err)
+			return nil, fmt.Errorf("failed to unmarshal file descriptor: %v", err)
 		}
 		for _, service := range fd.GetService() {
 			if service.GetName() == serviceName[strings.LastIndex(serviceName, ".")+1:] {
@@ -238,5 +217,5 @@ func getServiceMethods(client grpc_reflection_v1alpha.ServerReflectionClient, se
 		}
 	}
 
-	return methods
+	return methods, nil
 }
@@ -0,0 +1,349 @@
+// Package output renders command results as table, json, yaml or csv for every command.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/timing"
+	"github.com/jmespath/go-jmespath"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats selectable with -o
+const (
+	Table = "table"
+	JSON  = "json"
+	YAML  = "yaml"
+	CSV   = "csv"
+	ID    = "id"
+)
+
+// QueryAnnotation marks the commands applying the global --query flag to their result. The
+// flag is refused on the other commands instead of being ignored.
+const QueryAnnotation = "cfctl/query"
+
+// Formats lists the formats every command supports
+var Formats = []string{Table, JSON, YAML, CSV}
+
+// Spec is a parsed -o value such as 'yaml' or 'table=name,state', with the --query expression.
+// IDField is the identifier printed by the id format.
+type Spec struct {
+	Format  string
+	Columns []string
+	Query   string
+	IDField string
+}
+
+// Parse parses an -o value. Columns can be selected for table and csv as 'table=col1,col2'.
+func Parse(value string) (Spec, error) {
+	name, columns, hasColumns := strings.Cut(value, "=")
+	spec := Spec{Format: strings.ToLower(strings.TrimSpace(name))}
+	if !hasColumns {
+		return spec, nil
+	}
+
+	if spec.Format != Table && spec.Format != CSV {
+		return spec, fmt.Errorf("column selection is only supported for table and csv, not '%s'", spec.Format)
+	}
+	for _, column := range strings.Split(columns, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			spec.Columns = append(spec.Columns, column)
+		}
+	}
+	if len(spec.Columns) == 0 {
+		return spec, fmt.Errorf("no columns given in '%s'", value)
+	}
+	return spec, nil
+}
+
+// Validate returns an error when the format is not one of the supported formats
+func (s Spec) Validate(supported ...string) error {
+	if len(supported) == 0 {
+		supported = Formats
+	}
+	for _, format := range supported {
+		if s.Format == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format '%s', use one of %s", s.Format, strings.Join(supported, ", "))
+}
+
+// Print writes data to stdout in the format of the spec
+func Print(data interface{}, spec Spec) error {
+	defer timing.Track(timing.Render)()
+	return Write(os.Stdout, data, spec)
+}
+
+// Write renders data, a list response with results, a list of items or a single item.
+// Lists are rendered as one row per item, a single item as field/value rows.
+// The query of the spec is applied first.
+func Write(w io.Writer, data interface{}, spec Spec) error {
+	if spec.Query != "" {
+		var err error
+		if data, err = Query(data, spec.Query); err != nil {
+			return err
+		}
+	}
+
+	switch spec.Format {
+	case JSON:
+		out, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal to JSON: %v", err)
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+
+	case YAML, "":
+		out, err := YAMLString(data)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, out)
+		return err
+
+	case Table:
+		header, rows := tabulate(data, spec.Columns)
+		if len(rows) == 0 {
+			pterm.Info.Println("No results found.")
+			return nil
+		}
+		out, err := pterm.DefaultTable.WithHasHeader().WithData(append(pterm.TableData{header}, rows...)).Srender()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, out)
+		return err
+
+	case CSV:
+		header, rows := tabulate(data, spec.Columns)
+		if len(rows) == 0 {
+			return nil
+		}
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		writer.WriteAll(rows)
+		return writer.Error()
+
+	case ID:
+		for _, id := range IDs(data, spec.IDField) {
+			if _, err := fmt.Fprintln(w, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return spec.Validate()
+}
+
+// Query applies a JMESPath expression such as 'results[].cloud_service_id' to data
+func Query(data interface{}, expression string) (interface{}, error) {
+	// Round-trip through JSON so that typed maps and lists are searchable
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare data for the query: %v", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, fmt.Errorf("failed to prepare data for the query: %v", err)
+	}
+
+	result, err := jmespath.Search(expression, generic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query '%s': %v", expression, err)
+	}
+	return result, nil
+}
+
+// ValidateQuery reports a syntax error in a query before any request is made
+func ValidateQuery(expression string) error {
+	if expression == "" {
+		return nil
+	}
+	if _, err := jmespath.Compile(expression); err != nil {
+		return fmt.Errorf("invalid query '%s': %v", expression, err)
+	}
+	return nil
+}
+
+// YAMLString renders data as YAML, with one document per item of a list response
+func YAMLString(data interface{}) (string, error) {
+	if m, ok := data.(map[string]interface{}); ok {
+		if results, ok := m["results"].([]interface{}); ok && len(results) > 0 {
+			var sb strings.Builder
+			for i, item := range results {
+				if i > 0 {
+					sb.WriteString("---\n")
+				}
+				doc, err := yamlDoc(item)
+				if err != nil {
+					return "", err
+				}
+				sb.WriteString(doc)
+			}
+			return sb.String(), nil
+		}
+	}
+	return yamlDoc(data)
+}
+
+func yamlDoc(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return "", fmt.Errorf("failed to marshal to YAML: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// IDs returns the identifiers of the items of data, one per item that has the field.
+// Values left by a query, like the ones of 'results[].name', are returned as they are.
+func IDs(data interface{}, idField string) []string {
+	var ids []string
+	if scalars, ok := scalarValues(data); ok {
+		for _, value := range scalars {
+			ids = append(ids, Value(value))
+		}
+		return ids
+	}
+
+	items, isList := listItems(data)
+	if !isList {
+		item, _ := data.(map[string]interface{})
+		items = []map[string]interface{}{item}
+	}
+	for _, item := range items {
+		if id := Value(item[idField]); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Value renders a single value for a table cell or csv field, nested values as JSON
+func Value(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case map[string]interface{}, []interface{}:
+		out, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return string(out)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// tabulate turns data into a header and rows. Without selected columns a list uses the
+// sorted union of the item keys, and a single item is shown as Field/Value rows.
+func tabulate(data interface{}, columns []string) ([]string, [][]string) {
+	items, isList := listItems(data)
+
+	var header []string
+	var rows [][]string
+	if scalars, ok := scalarValues(data); ok {
+		header = []string{"Value"}
+		for _, value := range scalars {
+			rows = append(rows, []string{Value(value)})
+		}
+	} else if !isList {
+		item, _ := data.(map[string]interface{})
+		fields := columns
+		if len(fields) == 0 {
+			fields = sortedKeys(item)
+		}
+		header = []string{"Field", "Value"}
+		for _, field := range fields {
+			rows = append(rows, []string{field, Value(item[field])})
+		}
+	} else {
+		header = columns
+		if len(header) == 0 {
+			keySet := make(map[string]interface{})
+			for _, item := range items {
+				for key := range item {
+					keySet[key] = nil
+				}
+			}
+			header = sortedKeys(keySet)
+		}
+		for _, item := range items {
+			row := make([]string, len(header))
+			for i, column := range header {
+				row[i] = Value(item[column])
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return header, rows
+}
+
+// scalarValues returns the values of a query result that has no fields, a single value
+// or a list of values like the one of 'results[].name'
+func scalarValues(data interface{}) ([]interface{}, bool) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		return nil, false
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				return nil, false
+			}
+		}
+		return v, true
+	case nil:
+		return nil, true
+	default:
+		return []interface{}{v}, true
+	}
+}
+
+// listItems returns the items of a list response or a list, and whether data is a list at all
+func listItems(data interface{}) ([]map[string]interface{}, bool) {
+	var list []interface{}
+	switch v := data.(type) {
+	case []interface{}:
+		list = v
+	case map[string]interface{}:
+		results, ok := v["results"].([]interface{})
+		if !ok {
+			return nil, false
+		}
+		list = results
+	default:
+		return nil, false
+	}
+
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, entry := range list {
+		if item, ok := entry.(map[string]interface{}); ok {
+			items = append(items, item)
+		}
+	}
+	return items, true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+// Package token decodes SpaceONE access and refresh tokens. The signature is not verified,
+// which is left to the server; the claims are only read to show a token, check its expiry
+// and grant a new one with the same scope.
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Claims are the decoded payload of a token
+type Claims map[string]interface{}
+
+// Decode returns the claims of a JWT without verifying its signature
+func Decode(token string) (Claims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid token format: token must have three parts")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token format: failed to decode payload: %v", err)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("invalid token format: failed to parse payload: %v", err)
+	}
+
+	return claims, nil
+}
+
+// IsExpired reports whether the token is expired. Tokens that cannot be decoded or have
+// no expiry are treated as expired.
+func IsExpired(token string) bool {
+	claims, err := Decode(token)
+	if err != nil {
+		return true
+	}
+	return claims.Expired()
+}
+
+// String returns the claim as a string, or an empty string when it is missing
+func (c Claims) String(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+// Time returns a claim holding Unix seconds, like exp and iat
+func (c Claims) Time(key string) (time.Time, bool) {
+	seconds, ok := c[key].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// ExpiresAt returns the expiry of the token
+func (c Claims) ExpiresAt() (time.Time, bool) {
+	return c.Time("exp")
+}
+
+// Expired reports whether the token is past its expiry, or has none
+func (c Claims) Expired() bool {
+	expiresAt, ok := c.ExpiresAt()
+	return !ok || time.Now().After(expiresAt)
+}
+
+// Role returns the role type of a granted token, e.g. DOMAIN_ADMIN. Issued tokens have none.
+func (c Claims) Role() string {
+	return c.String("rol")
+}
+
+// DomainID returns the domain of the token
+func (c Claims) DomainID() string {
+	return c.String("did")
+}
+
+// WorkspaceID returns the workspace of a workspace scoped token
+func (c Claims) WorkspaceID() string {
+	return c.String("wid")
+}
+
+// UserID returns the user or app the token was issued to
+func (c Claims) UserID() string {
+	return c.String("aud")
+}
+
+// Scope returns the scope a granted token was granted with, WORKSPACE when it carries a
+// workspace and DOMAIN otherwise. Issued tokens, which have no role yet, have no scope.
+func (c Claims) Scope() string {
+	switch {
+	case c.Role() == "":
+		return ""
+	case c.WorkspaceID() != "":
+		return "WORKSPACE"
+	default:
+		return "DOMAIN"
+	}
+}
+
+// Remaining formats the time left until the expiry, e.g. "expires in 2h13m" or "expired 5m ago"
+func Remaining(expiresAt time.Time, now time.Time) string {
+	left := expiresAt.Sub(now)
+	if left <= 0 {
+		return fmt.Sprintf("expired %s ago", formatDuration(-left))
+	}
+	return fmt.Sprintf("expires in %s", formatDuration(left))
+}
+
+// formatDuration rounds to the largest two units, e.g. 2d3h, 2h13m or 45s
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	seconds := int(d % time.Minute / time.Second)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
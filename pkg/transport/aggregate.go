@@ -0,0 +1,93 @@
+package transport
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cloudforet-io/cfctl/pkg/format"
+	"github.com/pterm/pterm"
+)
+
+// groupAggregate is the count and sum of the list items sharing a value of the group field
+type groupAggregate struct {
+	Key   string
+	Count int
+	Sum   float64
+}
+
+// aggregateResults groups the list results by the field, a dotted path like 'data.region',
+// counting the items of each group and summing sumField when it is given.
+func aggregateResults(results []interface{}, field, sumField string) []groupAggregate {
+	groups := make(map[string]*groupAggregate)
+	for _, result := range results {
+		item, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		key := "(none)"
+		if value, ok := lookupFieldPath(item, field); ok && value != nil && fmt.Sprintf("%v", value) != "" {
+			key = fmt.Sprintf("%v", value)
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &groupAggregate{Key: key}
+			groups[key] = group
+		}
+		group.Count++
+		if sumField != "" {
+			if value, ok := lookupFieldPath(item, sumField); ok {
+				if number, ok := toConditionNumber(value); ok {
+					group.Sum += number
+				}
+			}
+		}
+	}
+
+	aggregates := make([]groupAggregate, 0, len(groups))
+	for _, group := range groups {
+		aggregates = append(aggregates, *group)
+	}
+	sort.Slice(aggregates, func(i, j int) bool {
+		if aggregates[i].Count == aggregates[j].Count {
+			return aggregates[i].Key < aggregates[j].Key
+		}
+		return aggregates[i].Count > aggregates[j].Count
+	})
+	return aggregates
+}
+
+// printGroupCount prints the aggregate table of --group-count
+func printGroupCount(data map[string]interface{}, field, sumField string) {
+	results, _ := data["results"].([]interface{})
+	aggregates := aggregateResults(results, field, sumField)
+
+	header := []string{field, "Count"}
+	if sumField != "" {
+		header = append(header, "Sum of "+sumField)
+	}
+	tableData := pterm.TableData{header}
+
+	var total groupAggregate
+	for _, group := range aggregates {
+		row := []string{group.Key, fmt.Sprintf("%d", group.Count)}
+		if sumField != "" {
+			row = append(row, format.FormatNumber(group.Sum))
+		}
+		tableData = append(tableData, row)
+		total.Count += group.Count
+		total.Sum += group.Sum
+	}
+
+	totalRow := []string{"Total", fmt.Sprintf("%d", total.Count)}
+	if sumField != "" {
+		totalRow = append(totalRow, format.FormatNumber(total.Sum))
+	}
+	tableData = append(tableData, totalRow)
+
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	if serverTotal := resultCount(data); serverTotal > len(results) {
+		pterm.Warning.Printf("Aggregated %d of %d items on the server.\n", len(results), serverTotal)
+	}
+}
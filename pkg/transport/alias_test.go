@@ -0,0 +1,99 @@
+package transport
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudforet-io/cfctl/internal/mockserver"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+)
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestFetchServiceAliasKeepsOptions(t *testing.T) {
+	server, err := mockserver.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(lis)
+	defer server.Stop()
+
+	dir := t.TempDir()
+	configs.SetSettingDir(dir)
+	defer configs.SetSettingDir("")
+	setting := fmt.Sprintf(`environment: local
+environments:
+  local:
+    endpoint: grpc://%s
+    token: test
+aliases:
+  identity:
+    ws: list Workspace
+version: %d
+`, lis.Addr(), configs.SettingVersion)
+	if err := os.WriteFile(filepath.Join(dir, "setting.yaml"), []byte(setting), configs.SecureFileMode); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		options FetchOptions
+		want    string
+	}{
+		{
+			name:    "query",
+			options: FetchOptions{OutputFormat: "yaml", Query: "results[].name"},
+			want:    "[\n  \"Development\",\n  \"Production\"\n]\n",
+		},
+		{
+			name:    "count only",
+			options: FetchOptions{OutputFormat: "yaml", CountOnly: true},
+			want:    "2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetchErr error
+			got := captureStdout(t, func() {
+				_, fetchErr = FetchService("identity", "ws", "", &tt.options)
+			})
+			if fetchErr != nil {
+				t.Fatalf("FetchService() error = %v", fetchErr)
+			}
+			if strings.TrimLeft(got, "\n") != tt.want {
+				t.Errorf("FetchService() printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package transport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pterm/pterm"
+)
+
+// DefaultBatchConcurrency is how many IDs given with --ids-from are processed at once
+const DefaultBatchConcurrency = 4
+
+// BatchVerbs are the verbs that accept --ids-from
+var BatchVerbs = []string{"get", "delete", "update"}
+
+// BatchResult is the outcome of the call made for one ID of a batch
+type BatchResult struct {
+	ID       string
+	Response map[string]interface{}
+	Err      error
+}
+
+// ReadIDs reads IDs separated by whitespace or newlines from a file, or from stdin when source is '-',
+// so that the output of 'cfctl ... -o id' or xargs-style lists can be piped in.
+// Lines starting with '#' are skipped and duplicates are dropped.
+func ReadIDs(source string) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IDs: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, id := range strings.Fields(line) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IDs: %v", err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no IDs given in %s", source)
+	}
+	return ids, nil
+}
+
+// ExecBatch calls the verb once per ID, setting <resource>_id to the ID on top of the parameters
+// of the options, with at most concurrency calls in flight. Results are in the order of the IDs.
+func ExecBatch(serviceName, resourceName, verb string, ids []string, options *FetchOptions, concurrency int) ([]BatchResult, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if options.FileParameter == "-" || options.JSONParameter == "-" {
+		return nil, fmt.Errorf("stdin cannot be read for parameters together with the IDs")
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	idField := ResourceIDField(resourceName)
+	results := make([]BatchResult, len(ids))
+	run := func(i int, refresh bool) {
+		idOptions := *options
+		idOptions.Refresh = refresh
+		idOptions.Parameters = append(append([]string{}, options.Parameters...), idField+"="+ids[i])
+		resp, err := Exec(serviceName, resourceName, verb, &idOptions)
+		results[i] = BatchResult{ID: ids[i], Response: resp, Err: err}
+	}
+
+	// The first call resolves and caches the method, so the others only read the descriptor cache
+	run(0, options.Refresh)
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 1; i < len(ids); i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run(i, false)
+		}(i)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// PrintBatchSummary prints the success or failure of each ID to stderr, leaving stdout to the responses,
+// and returns the number of failed IDs.
+func PrintBatchSummary(verb string, results []BatchResult) int {
+	tableData := pterm.TableData{{"ID", "Result", "Error"}}
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			tableData = append(tableData, []string{result.ID, pterm.Red("FAILED"), result.Err.Error()})
+			continue
+		}
+		tableData = append(tableData, []string{result.ID, pterm.Green("OK"), ""})
+	}
+
+	pterm.DefaultTable.WithHasHeader().WithWriter(os.Stderr).WithData(tableData).Render()
+	if failed > 0 {
+		pterm.Error.WithWriter(os.Stderr).Printf("%s failed for %d of %d IDs\n", verb, failed, len(results))
+	} else {
+		pterm.Success.WithWriter(os.Stderr).Printf("%s succeeded for %d IDs\n", verb, len(results))
+	}
+	return failed
+}
@@ -0,0 +1,208 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/invoker"
+	"google.golang.org/grpc/status"
+)
+
+// benchVerbs are the read-only verbs Bench calls; other verbs could change resources
+var benchVerbs = []string{"list", "get", "stat", "analyze", "search"}
+
+// BenchOptions configures Bench
+type BenchOptions struct {
+	// Requests is the number of calls, unless Duration is set
+	Requests    int
+	Concurrency int
+	// Duration keeps calling for this long instead of a number of calls
+	Duration time.Duration
+	// Progress is called after each call with the number of finished calls
+	Progress func(done int)
+}
+
+// BenchResult is the outcome of Bench
+type BenchResult struct {
+	Method      string          `json:"method"`
+	Concurrency int             `json:"concurrency"`
+	Requests    int             `json:"requests"`
+	Elapsed     time.Duration   `json:"-"`
+	Latencies   []time.Duration `json:"-"`
+	// Errors counts the failed calls by gRPC status code
+	Errors map[string]int `json:"errors,omitempty"`
+}
+
+// IsBenchVerb reports whether Bench may call the verb
+func IsBenchVerb(verb string) bool {
+	for _, v := range benchVerbs {
+		if verb == v || strings.HasPrefix(verb, v+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// Bench calls a read-only method concurrently and measures the latency of each call. It stops
+// early when ctx is done and returns what was measured until then.
+func Bench(ctx context.Context, serviceName, resourceName, verb string, fetch *FetchOptions, options BenchOptions) (*BenchResult, error) {
+	if !IsBenchVerb(verb) {
+		return nil, fmt.Errorf("only read-only methods can be benchmarked (%s), not '%s'", strings.Join(benchVerbs, ", "), verb)
+	}
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	if options.Duration <= 0 && options.Requests < 1 {
+		return nil, fmt.Errorf("the number of requests must be positive")
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %v. Please run 'cfctl login' first", err)
+	}
+	hostPort, _, _, _, err := resolveServiceEndpoint(config, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := strings.HasPrefix(config.Environments[config.Environment].Endpoint, "grpc://")
+	inv, err := invoker.Dial(hostPort, config.Environments[config.Environment].Token, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	defer inv.Close()
+	useDescriptorCache(inv, config.Environment, fetch.Refresh)
+
+	methodDesc, err := inv.ResolveMethod(serviceName, resourceName, verb)
+	if err != nil {
+		return nil, err
+	}
+	params, err := parseParameters(fetch)
+	if err != nil {
+		return nil, err
+	}
+	if err := expandIDParameters(config.Environment, params); err != nil {
+		return nil, err
+	}
+	if err := coerceParameters(methodDesc.GetInputType(), params, ""); err != nil {
+		return nil, err
+	}
+	reqMsg, _, err := invoker.NewRequest(methodDesc, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Duration)
+		defer cancel()
+	}
+
+	result := &BenchResult{
+		Method:      methodDesc.GetFullyQualifiedName(),
+		Concurrency: options.Concurrency,
+		Errors:      make(map[string]int),
+	}
+	var (
+		mu      sync.Mutex
+		started int64
+		done    int
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	for w := 0; w < options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if options.Duration <= 0 && atomic.AddInt64(&started, 1) > int64(options.Requests) {
+					return
+				}
+				callStart := time.Now()
+				err := inv.Call(ctx, methodDesc, reqMsg)
+				latency := time.Since(callStart)
+				// Calls cut short by the end of the run are not counted
+				if err != nil && ctx.Err() != nil {
+					return
+				}
+
+				mu.Lock()
+				result.Latencies = append(result.Latencies, latency)
+				if err != nil {
+					result.Errors[status.Code(err).String()]++
+				}
+				done++
+				finished := done
+				mu.Unlock()
+				if options.Progress != nil {
+					options.Progress(finished)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	result.Elapsed = time.Since(start)
+	result.Requests = len(result.Latencies)
+	sort.Slice(result.Latencies, func(i, j int) bool { return result.Latencies[i] < result.Latencies[j] })
+	return result, nil
+}
+
+// ErrorCount returns the number of failed calls
+func (r *BenchResult) ErrorCount() int {
+	count := 0
+	for _, n := range r.Errors {
+		count += n
+	}
+	return count
+}
+
+// ErrorRate returns the share of failed calls, between 0 and 1
+func (r *BenchResult) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.ErrorCount()) / float64(r.Requests)
+}
+
+// Throughput returns the calls per second
+func (r *BenchResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// Percentile returns the latency below which p percent of the calls finished, by the
+// nearest-rank method
+func (r *BenchResult) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(r.Latencies))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(r.Latencies) {
+		rank = len(r.Latencies)
+	}
+	return r.Latencies[rank-1]
+}
+
+// Mean returns the average latency
+func (r *BenchResult) Mean() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, latency := range r.Latencies {
+		total += latency
+	}
+	return total / time.Duration(len(r.Latencies))
+}
@@ -0,0 +1,349 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// coerceParameters converts the string values given with -p to what the request fields expect.
+// Struct fields such as tags, options and data take JSON objects, string fields keep the raw value
+// even when it looks like a number, and numbers, bools, enums and timestamps are validated
+// client-side so that a typo is reported with the expected type instead of a server error.
+func coerceParameters(msgDesc *desc.MessageDescriptor, params map[string]interface{}, prefix string) error {
+	for key, value := range params {
+		path := prefix + key
+		fieldDesc := msgDesc.FindFieldByName(key)
+
+		// A key given more than once only builds a list for repeated fields, otherwise the last one wins
+		if list, ok := value.(parameterList); ok && (fieldDesc == nil || !fieldDesc.IsRepeated() || fieldDesc.IsMap()) {
+			value = list[len(list)-1]
+		}
+
+		if fieldDesc == nil {
+			// Unknown fields are reported by the server, keep the previous behavior for them
+			params[key] = parseJSONValue(value)
+			continue
+		}
+
+		coerced, err := coerceField(fieldDesc, value, path)
+		if err != nil {
+			return err
+		}
+		params[key] = coerced
+	}
+
+	return nil
+}
+
+// coerceField converts a single value for the field, descending into nested messages and lists
+func coerceField(fieldDesc *desc.FieldDescriptor, value interface{}, path string) (interface{}, error) {
+	if fieldDesc.IsRepeated() && !fieldDesc.IsMap() {
+		// Messages are given as JSON objects, whose commas do not separate items
+		split := splitListParameter
+		if msgDesc := fieldDesc.GetMessageType(); msgDesc != nil && msgDesc.GetFullyQualifiedName() != "google.protobuf.Timestamp" {
+			split = splitMessageListParameter
+		}
+
+		switch v := value.(type) {
+		case string:
+			items, err := split(v, path)
+			if err != nil {
+				return nil, err
+			}
+			value = items
+		case parameterList:
+			var items []interface{}
+			for _, s := range v {
+				parsed, err := split(s, path)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, parsed...)
+			}
+			value = items
+		}
+
+		items, ok := value.([]interface{})
+		if !ok {
+			return value, nil
+		}
+		for i, item := range items {
+			coerced, err := coerceSingular(fieldDesc, item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = coerced
+		}
+		return items, nil
+	}
+
+	return coerceSingular(fieldDesc, value, path)
+}
+
+// parameterList holds the values of a -p key given more than once
+type parameterList []string
+
+// splitListParameter parses a value of a repeated field, either a JSON list or a comma
+// separated list like 'ws-1,ws-2'. A comma inside a value is escaped as '\,' and a
+// backslash as '\\'.
+func splitListParameter(s, path string) ([]interface{}, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "[") {
+		var items []interface{}
+		if err := json.Unmarshal([]byte(s), &items); err != nil {
+			return nil, fmt.Errorf("parameter '%s' expects a JSON list: %v", path, err)
+		}
+		return items, nil
+	}
+
+	var items []interface{}
+	var current strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if r != ',' && r != '\\' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			items = append(items, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	items = append(items, current.String())
+
+	return items, nil
+}
+
+// splitMessageListParameter parses a value of a repeated message field, either a JSON list
+// or a single item like '{"k":"name","v":"web"}'
+func splitMessageListParameter(s, path string) ([]interface{}, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "[") {
+		return splitListParameter(s, path)
+	}
+	return []interface{}{s}, nil
+}
+
+// coerceSingular converts a non-repeated value, or one element of a repeated field
+func coerceSingular(fieldDesc *desc.FieldDescriptor, value interface{}, path string) (interface{}, error) {
+	msgDesc := fieldDesc.GetMessageType()
+
+	switch {
+	case msgDesc != nil && msgDesc.GetFullyQualifiedName() == "google.protobuf.Struct":
+		return coerceStruct(value, path)
+
+	case msgDesc != nil && msgDesc.GetFullyQualifiedName() == "google.protobuf.ListValue":
+		if s, ok := value.(string); ok {
+			var list []interface{}
+			if err := json.Unmarshal([]byte(s), &list); err != nil {
+				return nil, fmt.Errorf("parameter '%s' expects a JSON list: %v", path, err)
+			}
+			value = list
+		}
+		if list, ok := value.([]interface{}); ok {
+			if _, err := structpb.NewList(list); err != nil {
+				return nil, fmt.Errorf("parameter '%s' is not a valid list: %v", path, err)
+			}
+		}
+		return value, nil
+
+	case msgDesc != nil && msgDesc.GetFullyQualifiedName() == "google.protobuf.Value":
+		return parseJSONValue(value), nil
+
+	case msgDesc != nil && !fieldDesc.IsMap() && !isWellKnownMessage(msgDesc):
+		if s, ok := value.(string); ok {
+			var nested map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &nested); err != nil {
+				return nil, fmt.Errorf("parameter '%s' expects a JSON object or nested fields like %s.<field>=...", path, path)
+			}
+			value = nested
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			if err := coerceParameters(msgDesc, nested, path+"."); err != nil {
+				return nil, err
+			}
+		}
+		return value, nil
+
+	case fieldDesc.IsMap():
+		if s, ok := value.(string); ok {
+			var m map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &m); err != nil {
+				return nil, fmt.Errorf("parameter '%s' expects a JSON object: %v", path, err)
+			}
+			return m, nil
+		}
+		return value, nil
+
+	case msgDesc != nil && msgDesc.GetFullyQualifiedName() == "google.protobuf.Timestamp":
+		return coerceTimestamp(value, path)
+
+	case msgDesc != nil:
+		// Other well-known types take their JSON form as is
+		return value, nil
+	}
+
+	return coerceScalar(fieldDesc, value, path)
+}
+
+// timestampLayouts are the accepted forms of Timestamp parameters besides RFC 3339
+var timestampLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// coerceScalar converts a string value to the scalar type of the field and explains
+// what was expected when it cannot be converted, instead of leaving it to the server.
+func coerceScalar(fieldDesc *desc.FieldDescriptor, value interface{}, path string) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	invalid := func(expected, example string) error {
+		return fmt.Errorf("invalid value '%s' for parameter '%s': expected %s (e.g. %s=%s)", s, path, expected, path, example)
+	}
+
+	switch fieldDesc.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		return s, nil
+
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, invalid("int32", "10")
+		}
+		return n, nil
+
+	case descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, invalid("int64", "10")
+		}
+		return n, nil
+
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_FIXED32:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, invalid("uint32", "10")
+		}
+		return n, nil
+
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT64, descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, invalid("uint64", "10")
+		}
+		return n, nil
+
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, invalid("a number", "0.5")
+		}
+		return f, nil
+
+	case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, invalid("true or false", "true")
+		}
+		return b, nil
+
+	case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		return coerceEnum(fieldDesc.GetEnumType(), s, path)
+	}
+
+	return parseJSONValue(s), nil
+}
+
+// coerceEnum matches an enum value by name, case-insensitively, or by number
+func coerceEnum(enumDesc *desc.EnumDescriptor, s, path string) (interface{}, error) {
+	var names []string
+	for _, value := range enumDesc.GetValues() {
+		if strings.EqualFold(value.GetName(), s) {
+			return value.GetName(), nil
+		}
+		names = append(names, value.GetName())
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+		if value := enumDesc.FindValueByNumber(int32(n)); value != nil {
+			return value.GetName(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid value '%s' for parameter '%s': expected one of %s", s, path, strings.Join(names, ", "))
+}
+
+// coerceTimestamp converts dates and times to the RFC 3339 form of google.protobuf.Timestamp.
+// Values without a time zone are taken as local time.
+func coerceTimestamp(value interface{}, path string) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC().Format(time.RFC3339Nano), nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.UTC().Format(time.RFC3339Nano), nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid value '%s' for parameter '%s': expected a timestamp (e.g. %s=2024-01-31 or %s=2024-01-31T09:00:00Z)", s, path, path, path)
+}
+
+// coerceStruct parses a JSON object given for a google.protobuf.Struct field and checks
+// that it can be represented as a Struct, like the credentials built by login.
+func coerceStruct(value interface{}, path string) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			return nil, fmt.Errorf("parameter '%s' expects a JSON object, e.g. %s='{\"team\":\"sre\"}'", path, path)
+		}
+		value = m
+	}
+
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("parameter '%s' expects a JSON object", path)
+	}
+	if _, err := structpb.NewStruct(m); err != nil {
+		return nil, fmt.Errorf("parameter '%s' is not a valid Struct: %v", path, err)
+	}
+	return m, nil
+}
+
+// parseJSONValue parses a string value as JSON, keeping it as a string when it is not JSON
+func parseJSONValue(value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	var jsonValue interface{}
+	if err := json.Unmarshal([]byte(s), &jsonValue); err == nil {
+		return jsonValue
+	}
+	return s
+}
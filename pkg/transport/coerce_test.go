@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/builder"
+)
+
+// listRequestDescriptor builds a request with repeated scalar and message fields, like the
+// list requests of the services
+func listRequestDescriptor(t *testing.T) *desc.MessageDescriptor {
+	t.Helper()
+
+	filter := builder.NewMessage("Filter").
+		AddField(builder.NewField("k", builder.FieldTypeString())).
+		AddField(builder.NewField("v", builder.FieldTypeString())).
+		AddField(builder.NewField("o", builder.FieldTypeString()))
+	request := builder.NewMessage("ListRequest").
+		AddField(builder.NewField("workspace_ids", builder.FieldTypeString()).SetRepeated()).
+		AddField(builder.NewField("sizes", builder.FieldTypeInt32()).SetRepeated()).
+		AddField(builder.NewField("filter", builder.FieldTypeMessage(filter)).SetRepeated())
+
+	file := builder.NewFile("list_request.proto").SetPackageName("test").SetProto3(true).
+		AddMessage(filter).
+		AddMessage(request)
+	fileDesc, err := file.Build()
+	if err != nil {
+		t.Fatalf("failed to build descriptor: %v", err)
+	}
+	return fileDesc.FindMessage("test.ListRequest")
+}
+
+func TestCoerceRepeatedParameters(t *testing.T) {
+	msgDesc := listRequestDescriptor(t)
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:   "comma separated strings",
+			params: map[string]interface{}{"workspace_ids": "ws-1,ws-2"},
+			want:   map[string]interface{}{"workspace_ids": []interface{}{"ws-1", "ws-2"}},
+		},
+		{
+			name:   "escaped comma",
+			params: map[string]interface{}{"workspace_ids": `a\,b,c`},
+			want:   map[string]interface{}{"workspace_ids": []interface{}{"a,b", "c"}},
+		},
+		{
+			name:   "repeated strings",
+			params: map[string]interface{}{"workspace_ids": parameterList{"ws-1", "ws-2,ws-3"}},
+			want:   map[string]interface{}{"workspace_ids": []interface{}{"ws-1", "ws-2", "ws-3"}},
+		},
+		{
+			name:   "comma separated numbers",
+			params: map[string]interface{}{"sizes": "1,2"},
+			want:   map[string]interface{}{"sizes": []interface{}{int64(1), int64(2)}},
+		},
+		{
+			name:    "invalid number",
+			params:  map[string]interface{}{"sizes": "1,x"},
+			wantErr: true,
+		},
+		{
+			name:   "JSON object for a repeated message",
+			params: map[string]interface{}{"filter": `{"k":"name","v":"web","o":"eq"}`},
+			want: map[string]interface{}{"filter": []interface{}{
+				map[string]interface{}{"k": "name", "v": "web", "o": "eq"},
+			}},
+		},
+		{
+			name: "repeated JSON objects for a repeated message",
+			params: map[string]interface{}{"filter": parameterList{
+				`{"k":"name","v":"web"}`,
+				`{"k":"state","v":"ACTIVE"}`,
+			}},
+			want: map[string]interface{}{"filter": []interface{}{
+				map[string]interface{}{"k": "name", "v": "web"},
+				map[string]interface{}{"k": "state", "v": "ACTIVE"},
+			}},
+		},
+		{
+			name:   "JSON list for a repeated message",
+			params: map[string]interface{}{"filter": `[{"k":"name","v":"web"},{"k":"state","v":"ACTIVE"}]`},
+			want: map[string]interface{}{"filter": []interface{}{
+				map[string]interface{}{"k": "name", "v": "web"},
+				map[string]interface{}{"k": "state", "v": "ACTIVE"},
+			}},
+		},
+		{
+			name:    "not a JSON object for a repeated message",
+			params:  map[string]interface{}{"filter": "name,web"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := coerceParameters(msgDesc, tt.params, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("coerceParameters(%v) error = %v, wantErr %v", tt.params, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.params, tt.want) {
+				t.Errorf("coerceParameters() = %v, want %v", tt.params, tt.want)
+			}
+		})
+	}
+}
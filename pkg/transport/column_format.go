@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/format"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+)
+
+// loadColumnFormats reads the column formats of the resource from the column_formats setting:
+//
+//	column_formats:
+//	  "*":
+//	    size: bytes
+//	  cost_analysis.Cost:
+//	    cost: currency:USD
+//	  inventory.CloudService:
+//	    data.disk_size: bytes
+//	    data.count: number
+//
+// Formats of the resource override those of the service, which override the "*" ones.
+func loadColumnFormats(serviceName, resourceName string) map[string]string {
+	settingDir, err := configs.SettingDir()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(settingDir, "setting.yaml"))
+	if err != nil {
+		return nil
+	}
+
+	// Read without viper, which lowercases keys and splits them on dots
+	var setting struct {
+		ColumnFormats map[string]map[string]string `yaml:"column_formats"`
+	}
+	if err := yaml.Unmarshal(data, &setting); err != nil || len(setting.ColumnFormats) == 0 {
+		return nil
+	}
+
+	formats := make(map[string]string)
+	for _, key := range []string{"*", serviceName, fmt.Sprintf("%s.%s", serviceName, resourceName)} {
+		for column, columnFormat := range setting.ColumnFormats[key] {
+			formats[column] = columnFormat
+		}
+	}
+	return formats
+}
+
+// currencyConverterFor returns the display currency converter when a column is formatted as currency
+func currencyConverterFor(formats map[string]string) *format.CurrencyConverter {
+	for _, columnFormat := range formats {
+		if strings.HasPrefix(strings.ToLower(columnFormat), format.ColumnFormatCurrency) {
+			converter, err := format.NewCurrencyConverter("")
+			if err != nil {
+				pterm.Warning.Printf("Currency conversion disabled: %v\n", err)
+				return nil
+			}
+			return converter
+		}
+	}
+	return nil
+}
+
+// formatColumnValue renders a table cell, using the configured column format when there is one.
+// Currency columns are converted to the display currency and marked with '≈'.
+func formatColumnValue(options *FetchOptions, column string, value interface{}) string {
+	columnFormat, ok := options.ColumnFormats[column]
+	if !ok {
+		return FormatTableValue(value)
+	}
+
+	name, currency, _ := strings.Cut(columnFormat, ":")
+	if strings.EqualFold(name, format.ColumnFormatCurrency) && options.currencyConverter.Converts(currency) {
+		if amount, err := strconv.ParseFloat(fmt.Sprintf("%v", value), 64); err == nil {
+			if converted, ok := options.currencyConverter.Convert(amount, currency); ok {
+				return "≈ " + format.FormatCurrency(converted, options.currencyConverter.Display)
+			}
+		}
+	}
+
+	if formatted, ok := format.ApplyColumnFormat(value, columnFormat); ok {
+		return formatted
+	}
+	return FormatTableValue(value)
+}
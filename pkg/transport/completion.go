@@ -0,0 +1,229 @@
+package transport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/invoker"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/jhump/protoreflect/desc"
+	"gopkg.in/yaml.v3"
+)
+
+// parameterCacheTTL is how long the request fields of a method are reused for completion
+const parameterCacheTTL = 24 * time.Hour
+
+// maxParameterDepth limits how deep nested message fields are expanded
+const maxParameterDepth = 4
+
+// ParameterField is a request field path usable with -p, e.g. 'query.filter[0].k'
+type ParameterField struct {
+	Path string   `yaml:"path"`
+	Type string   `yaml:"type"`
+	Enum []string `yaml:"enum,omitempty"`
+}
+
+// CompleteParameter returns shell completions for a -p value of the method.
+// Before '=' it suggests field paths, after it the values of enum and bool fields.
+func CompleteParameter(serviceName, verb, resourceName, toComplete string) []string {
+	fields, err := parameterFields(serviceName, verb, resourceName)
+	if err != nil {
+		return nil
+	}
+
+	var completions []string
+	if key, value, found := strings.Cut(toComplete, "="); found {
+		for _, field := range fields {
+			if field.Path != key {
+				continue
+			}
+			values := field.Enum
+			if field.Type == "bool" {
+				values = []string{"true", "false"}
+			}
+			for _, v := range values {
+				if strings.HasPrefix(v, value) {
+					completions = append(completions, fmt.Sprintf("%s=%s", key, v))
+				}
+			}
+		}
+		return completions
+	}
+
+	for _, field := range fields {
+		if strings.HasPrefix(field.Path, toComplete) {
+			completions = append(completions, fmt.Sprintf("%s=\t%s", field.Path, field.Type))
+		}
+	}
+	return completions
+}
+
+// CompleteServiceArgs returns shell completions for the verb and resource of
+// 'cfctl <service> <verb> <resource>'. They come from the descriptors cached for the current
+// environment by earlier calls, so nothing is suggested before the service was used once.
+func CompleteServiceArgs(serviceName string, args []string, toComplete string) []string {
+	if len(args) > 1 {
+		return nil
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	services, files := invoker.CachedDescriptors(filepath.Join(configs.CacheDir(config.Environment), "descriptors"), serviceName)
+
+	candidates := make(map[string]bool)
+	for _, fullName := range services {
+		if !strings.Contains(fullName, fmt.Sprintf("spaceone.api.%s.", serviceName)) && !strings.Contains(fullName, ".plugin.") {
+			continue
+		}
+		resource := fullName[strings.LastIndex(fullName, ".")+1:]
+		var methods []*desc.MethodDescriptor
+		for _, fd := range files {
+			if serviceDesc := fd.FindService(fullName); serviceDesc != nil {
+				methods = serviceDesc.GetMethods()
+				break
+			}
+		}
+
+		if len(args) == 0 {
+			// Verbs of the services resolved so far
+			for _, method := range methods {
+				candidates[method.GetName()] = true
+			}
+			continue
+		}
+		// Resources having the verb, or every resource not resolved yet
+		if len(methods) == 0 {
+			candidates[resource] = true
+		}
+		for _, method := range methods {
+			if method.GetName() == args[0] {
+				candidates[resource] = true
+			}
+		}
+	}
+
+	var completions []string
+	for candidate := range candidates {
+		if strings.HasPrefix(candidate, toComplete) {
+			completions = append(completions, candidate)
+		}
+	}
+	sort.Strings(completions)
+	return completions
+}
+
+// parameterFields returns the request field paths of the method, from the cache when it is fresh
+func parameterFields(serviceName, verb, resourceName string) ([]ParameterField, error) {
+	cachePath, err := parameterCachePath(serviceName, verb, resourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < parameterCacheTTL {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var fields []ParameterField
+			if err := yaml.Unmarshal(data, &fields); err == nil {
+				return fields, nil
+			}
+		}
+	}
+
+	methodDesc, err := ResolveMethod(serviceName, verb, resourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := collectParameterFields(methodDesc.GetInputType(), "", 0, map[string]bool{})
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Path < fields[j].Path
+	})
+
+	if data, err := yaml.Marshal(fields); err == nil {
+		_ = configs.WriteSecureFile(cachePath, data)
+	}
+
+	return fields, nil
+}
+
+// parameterCachePath returns <cache dir>/<env>/parameters/<service>.<resource>.<verb>.yaml
+func parameterCachePath(serviceName, verb, resourceName string) (string, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	fileName := fmt.Sprintf("%s.%s.%s.yaml", serviceName, resourceName, verb)
+	return filepath.Join(configs.CacheDir(config.Environment), "parameters", fileName), nil
+}
+
+// collectParameterFields walks the message and returns the paths of its fields.
+// Repeated messages are addressed by their first element, e.g. 'filter[0].k'.
+func collectParameterFields(msgDesc *desc.MessageDescriptor, prefix string, depth int, visiting map[string]bool) []ParameterField {
+	if depth >= maxParameterDepth || visiting[msgDesc.GetFullyQualifiedName()] {
+		return nil
+	}
+	visiting[msgDesc.GetFullyQualifiedName()] = true
+	defer delete(visiting, msgDesc.GetFullyQualifiedName())
+
+	var fields []ParameterField
+	for _, fieldDesc := range msgDesc.GetFields() {
+		path := prefix + fieldDesc.GetName()
+		field := ParameterField{Path: path, Type: parameterFieldType(fieldDesc)}
+
+		if enumDesc := fieldDesc.GetEnumType(); enumDesc != nil {
+			for _, value := range enumDesc.GetValues() {
+				field.Enum = append(field.Enum, value.GetName())
+			}
+		}
+		fields = append(fields, field)
+
+		nested := fieldDesc.GetMessageType()
+		if nested == nil || fieldDesc.IsMap() || isWellKnownMessage(nested) {
+			continue
+		}
+		if fieldDesc.IsRepeated() {
+			path += "[0]"
+		}
+		fields = append(fields, collectParameterFields(nested, path+".", depth+1, visiting)...)
+	}
+
+	return fields
+}
+
+// parameterFieldType returns a short type name of the field shown next to its completion
+func parameterFieldType(fieldDesc *desc.FieldDescriptor) string {
+	var typeName string
+	switch {
+	case fieldDesc.IsMap():
+		return "map"
+	case fieldDesc.GetEnumType() != nil:
+		typeName = "enum"
+	case fieldDesc.GetMessageType() != nil:
+		switch fieldDesc.GetMessageType().GetFullyQualifiedName() {
+		case "google.protobuf.Struct":
+			typeName = "struct"
+		case "google.protobuf.ListValue":
+			typeName = "list"
+		case "google.protobuf.Timestamp":
+			typeName = "timestamp"
+		default:
+			typeName = "message"
+		}
+	default:
+		typeName = strings.ToLower(strings.TrimPrefix(fieldDesc.GetType().String(), "TYPE_"))
+	}
+
+	if fieldDesc.IsRepeated() {
+		return "repeated " + typeName
+	}
+	return typeName
+}
+
+// isWellKnownMessage reports whether the message is a google.protobuf type set as a whole value
+func isWellKnownMessage(msgDesc *desc.MessageDescriptor) bool {
+	return strings.HasPrefix(msgDesc.GetFullyQualifiedName(), "google.protobuf.")
+}
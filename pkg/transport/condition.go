@@ -0,0 +1,205 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// conditionOperators is ordered so that two-character operators are matched first
+var conditionOperators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// EvaluateCondition evaluates an expression like 'total_count > 0' or 'status == "SUCCESS"'
+// against a response. The left side is a dotted field path (e.g. 'results.0.state')
+// or 'len(<path>)', the right side is a number, a quoted string, true, false or null.
+func EvaluateCondition(data map[string]interface{}, expr string) (bool, error) {
+	left, operator, right, err := splitCondition(expr)
+	if err != nil {
+		return false, err
+	}
+
+	actual, err := resolveConditionOperand(data, left)
+	if err != nil {
+		return false, err
+	}
+
+	expected, err := parseConditionLiteral(right)
+	if err != nil {
+		return false, err
+	}
+
+	return compareConditionValues(actual, operator, expected)
+}
+
+// splitCondition splits the expression at the first operator found outside of quotes
+func splitCondition(expr string) (string, string, string, error) {
+	inQuote := rune(0)
+	for i, r := range expr {
+		if r == '"' || r == '\'' {
+			if inQuote == 0 {
+				inQuote = r
+			} else if inQuote == r {
+				inQuote = 0
+			}
+			continue
+		}
+		if inQuote != 0 {
+			continue
+		}
+
+		for _, op := range conditionOperators {
+			if strings.HasPrefix(expr[i:], op) {
+				left := strings.TrimSpace(expr[:i])
+				right := strings.TrimSpace(expr[i+len(op):])
+				if left == "" || right == "" {
+					return "", "", "", fmt.Errorf("invalid expression '%s'", expr)
+				}
+				return left, op, right, nil
+			}
+		}
+	}
+
+	return "", "", "", fmt.Errorf("invalid expression '%s': expected one of %s", expr, strings.Join(conditionOperators, ", "))
+}
+
+// resolveConditionOperand looks up the left side of the expression in the response
+func resolveConditionOperand(data map[string]interface{}, operand string) (interface{}, error) {
+	if strings.HasPrefix(operand, "len(") && strings.HasSuffix(operand, ")") {
+		value, found := lookupFieldPath(data, strings.TrimSuffix(strings.TrimPrefix(operand, "len("), ")"))
+		if !found {
+			return float64(0), nil
+		}
+		switch v := value.(type) {
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		case string:
+			return float64(len(v)), nil
+		default:
+			return nil, fmt.Errorf("len() is not supported for field '%s'", operand)
+		}
+	}
+
+	value, _ := lookupFieldPath(data, operand)
+	return value, nil
+}
+
+// lookupFieldPath resolves a dotted path, where numeric segments index into lists
+func lookupFieldPath(data interface{}, path string) (interface{}, bool) {
+	current := data
+	for _, segment := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			current = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// parseConditionLiteral parses the right side of the expression
+func parseConditionLiteral(literal string) (interface{}, error) {
+	if len(literal) >= 2 && (literal[0] == '\'' && literal[len(literal)-1] == '\'') {
+		return literal[1 : len(literal)-1], nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(literal), &value); err != nil {
+		// Allow bare words such as SUCCESS
+		return literal, nil
+	}
+	return value, nil
+}
+
+// compareConditionValues compares the response value with the expected literal
+func compareConditionValues(actual interface{}, operator string, expected interface{}) (bool, error) {
+	switch e := expected.(type) {
+	case nil:
+		switch operator {
+		case "==":
+			return actual == nil, nil
+		case "!=":
+			return actual != nil, nil
+		}
+		return false, fmt.Errorf("operator '%s' is not supported for null", operator)
+
+	case bool:
+		a, ok := actual.(bool)
+		switch operator {
+		case "==":
+			return ok && a == e, nil
+		case "!=":
+			return !ok || a != e, nil
+		}
+		return false, fmt.Errorf("operator '%s' is not supported for booleans", operator)
+
+	case float64:
+		a, ok := toConditionNumber(actual)
+		if !ok {
+			return operator == "!=", nil
+		}
+		switch operator {
+		case "==":
+			return a == e, nil
+		case "!=":
+			return a != e, nil
+		case ">":
+			return a > e, nil
+		case ">=":
+			return a >= e, nil
+		case "<":
+			return a < e, nil
+		case "<=":
+			return a <= e, nil
+		}
+
+	case string:
+		a := ""
+		if actual != nil {
+			a = fmt.Sprintf("%v", actual)
+		}
+		switch operator {
+		case "==":
+			return a == e, nil
+		case "!=":
+			return a != e, nil
+		case ">":
+			return a > e, nil
+		case ">=":
+			return a >= e, nil
+		case "<":
+			return a < e, nil
+		case "<=":
+			return a <= e, nil
+		}
+	}
+
+	return false, fmt.Errorf("unsupported comparison '%v %s %v'", actual, operator, expected)
+}
+
+// toConditionNumber converts response values to numbers. 64-bit integers are encoded as strings in JSON.
+func toConditionNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	case nil:
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,60 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// setCountOnly asks the server for the total only, through Query.count_only where the request supports it
+func setCountOnly(msgDesc *desc.MessageDescriptor, params map[string]interface{}) error {
+	queryField := msgDesc.FindFieldByName("query")
+	if queryField == nil || queryField.GetMessageType() == nil {
+		return nil
+	}
+	if queryField.GetMessageType().FindFieldByName("count_only") == nil &&
+		queryField.GetMessageType().GetFullyQualifiedName() != "google.protobuf.Struct" {
+		return nil
+	}
+
+	query, ok := params["query"].(map[string]interface{})
+	if !ok {
+		query = make(map[string]interface{})
+		if raw, ok := params["query"].(string); ok {
+			if err := json.Unmarshal([]byte(raw), &query); err != nil {
+				return fmt.Errorf("parameter 'query' expects a JSON object: %v", err)
+			}
+		}
+	}
+	query["count_only"] = true
+	params["query"] = query
+
+	return nil
+}
+
+// resultCount returns total_count of a list response, or the number of results when it is missing
+func resultCount(data map[string]interface{}) int {
+	switch total := data["total_count"].(type) {
+	case float64:
+		return int(total)
+	case string:
+		var n int
+		if _, err := fmt.Sscanf(total, "%d", &n); err == nil {
+			return n
+		}
+	}
+	if results, ok := data["results"].([]interface{}); ok {
+		return len(results)
+	}
+	return 0
+}
+
+// countFooter summarizes how many of the items on the server the table holds
+func countFooter(shown int, data map[string]interface{}) string {
+	total := resultCount(data)
+	if total > shown {
+		return fmt.Sprintf("Showing %d of %d items on the server. Use --no-paging or --rows to fetch more.", shown, total)
+	}
+	return fmt.Sprintf("Showing all %d items.", shown)
+}
@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/internal/invoker"
+)
+
+// Exec calls any method of the service by resource and verb, e.g. identity Project list,
+// with the parameters of the options, and returns the response without printing it.
+func Exec(serviceName, resourceName, verb string, options *FetchOptions) (map[string]interface{}, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %v. Please run 'cfctl login' first", err)
+	}
+
+	token := config.Environments[config.Environment].Token
+	if token == "" {
+		return nil, fmt.Errorf("no token found for environment '%s'. Please run 'cfctl login' first", config.Environment)
+	}
+
+	hostPort, _, _, _, err := resolveServiceEndpoint(config, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := strings.HasPrefix(config.Environments[config.Environment].Endpoint, "grpc://")
+	inv, err := invoker.Dial(hostPort, token, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	defer inv.Close()
+	useDescriptorCache(inv, config.Environment, options.Refresh)
+
+	methodDesc, err := inv.ResolveMethod(serviceName, resourceName, verb)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := parseParameters(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := expandIDParameters(config.Environment, params); err != nil {
+		return nil, err
+	}
+	if err := coerceParameters(methodDesc.GetInputType(), params, ""); err != nil {
+		return nil, err
+	}
+
+	reqMsg, _, err := invoker.NewRequest(methodDesc, params)
+	if err != nil {
+		return nil, err
+	}
+
+	respBytes, err := inv.Invoke(methodDesc, reqMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	// Secret data is never printed
+	if serviceName == "secret" {
+		RedactSecretData(resp)
+	}
+
+	if verb == "list" {
+		rememberIDs(config.Environment, resourceName, resp)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// loadIDFields reads the configured ID fields of resources, for resources whose ID does not
+// follow the <resource>_id naming convention:
+//
+//	id_fields:
+//	  inventory.CloudServiceType: cloud_service_type_id
+//	  cost_analysis.Cost: cost_id
+func loadIDFields() map[string]string {
+	settingDir, err := configs.SettingDir()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(settingDir, "setting.yaml"))
+	if err != nil {
+		return nil
+	}
+
+	// Read without viper, which lowercases keys and splits them on dots
+	var setting struct {
+		IDFields map[string]string `yaml:"id_fields"`
+	}
+	if err := yaml.Unmarshal(data, &setting); err != nil {
+		return nil
+	}
+	return setting.IDFields
+}
+
+// idFieldOf returns the primary identifier field of the items the method returns: the configured
+// field, else <resource>_id, else the first *_id field of the item message, else 'id'.
+func idFieldOf(methodDesc *desc.MethodDescriptor, serviceName, resourceName string) string {
+	if field, ok := loadIDFields()[fmt.Sprintf("%s.%s", serviceName, resourceName)]; ok {
+		return field
+	}
+
+	conventional := ResourceIDField(resourceName)
+	if methodDesc == nil {
+		return conventional
+	}
+
+	itemDesc := methodDesc.GetOutputType()
+	if results := itemDesc.FindFieldByName("results"); results != nil && results.GetMessageType() != nil {
+		itemDesc = results.GetMessageType()
+	}
+	if itemDesc.FindFieldByName(conventional) != nil {
+		return conventional
+	}
+	for _, field := range itemDesc.GetFields() {
+		if strings.HasSuffix(field.GetName(), "_id") {
+			return field.GetName()
+		}
+	}
+	if itemDesc.FindFieldByName("id") != nil {
+		return "id"
+	}
+	return conventional
+}
+
+// resolveIDField finds the method with the reflection client and returns the ID field of its items
+func resolveIDField(refClient *grpcreflect.Client, serviceName, verb, resourceName string) string {
+	var methodDesc *desc.MethodDescriptor
+	if fullServiceName, err := discoverService(refClient, serviceName, resourceName); err == nil {
+		if serviceDesc, err := refClient.ResolveService(fullServiceName); err == nil {
+			methodDesc = serviceDesc.FindMethodByName(verb)
+		}
+	}
+	return idFieldOf(methodDesc, serviceName, resourceName)
+}
+
+// IDField returns the ID field of the items the verb returns, for commands printing IDs only
+func IDField(serviceName, verb, resourceName string) string {
+	// Without the descriptor the naming convention still applies
+	methodDesc, _ := ResolveMethod(serviceName, verb, resourceName)
+	return idFieldOf(methodDesc, serviceName, resourceName)
+}
@@ -0,0 +1,99 @@
+package transport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/spf13/viper"
+)
+
+const roundTripSetting = `environment: dev-user
+environments:
+  dev-user:
+    endpoint: grpc+ssl://identity.dev.example.com:443
+  stg-user:
+    endpoint: grpc+ssl://identity.stg.example.com:443
+id_fields:
+  inventory.CloudServiceType: cloud_service_type_id
+column_formats:
+  "*":
+    size: bytes
+  inventory.CloudService:
+    data.disk_size: bytes
+currency:
+  display: KRW
+  rates:
+    USD: 1
+    KRW: 1380
+`
+
+func TestSettingSectionsSurviveViperWrites(t *testing.T) {
+	dir := t.TempDir()
+	configs.SetSettingDir(dir)
+	defer configs.SetSettingDir("")
+	settingPath := filepath.Join(dir, "setting.yaml")
+	if err := os.WriteFile(settingPath, []byte(roundTripSetting), configs.SecureFileMode); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := []struct {
+		name  string
+		write func() error
+	}{
+		{name: "FlushSetting", write: func() error {
+			v, err := configs.Setting()
+			if err != nil {
+				return err
+			}
+			v.Set("environment", "stg-user")
+			configs.MarkSettingDirty()
+			return configs.FlushSetting()
+		}},
+		{name: "UpdateConfig", write: func() error {
+			return configs.UpdateConfig(settingPath, func(v *viper.Viper) error {
+				v.Set("environment", "dev-user")
+				return nil
+			})
+		}},
+		{name: "WriteConfig", write: func() error {
+			v := viper.New()
+			v.SetConfigFile(settingPath)
+			v.SetConfigType("yaml")
+			if err := configs.ReadConfig(v); err != nil {
+				return err
+			}
+			v.Set("environment", "stg-user")
+			return configs.WriteConfig(v)
+		}},
+	}
+
+	for _, w := range writes {
+		t.Run(w.name, func(t *testing.T) {
+			if err := w.write(); err != nil {
+				t.Fatalf("failed to write setting: %v", err)
+			}
+
+			wantIDFields := map[string]string{"inventory.CloudServiceType": "cloud_service_type_id"}
+			if got := loadIDFields(); !reflect.DeepEqual(got, wantIDFields) {
+				t.Errorf("loadIDFields() = %v, want %v", got, wantIDFields)
+			}
+
+			wantFormats := map[string]string{"size": "bytes", "data.disk_size": "bytes"}
+			if got := loadColumnFormats("inventory", "CloudService"); !reflect.DeepEqual(got, wantFormats) {
+				t.Errorf("loadColumnFormats() = %v, want %v", got, wantFormats)
+			}
+
+			currency, err := configs.LoadCurrencySetting()
+			if err != nil {
+				t.Fatalf("LoadCurrencySetting() error = %v", err)
+			}
+			wantRates := map[string]float64{"USD": 1, "KRW": 1380}
+			if currency.Display != "KRW" || !reflect.DeepEqual(currency.Rates, wantRates) {
+				t.Errorf("LoadCurrencySetting() = %+v, want display KRW and rates %v", currency, wantRates)
+			}
+		})
+	}
+}
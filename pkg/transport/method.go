@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/internal/invoker"
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/cloudforet-io/cfctl/pkg/format"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// descriptorCacheTTL is how long service descriptors resolved through reflection are reused
+const descriptorCacheTTL = 24 * time.Hour
+
+// useDescriptorCache makes the invoker cache descriptors under <cache dir>/<env>/descriptors.
+// refresh is set by --refresh to resolve them again.
+func useDescriptorCache(inv *invoker.Invoker, env string, refresh bool) {
+	inv.UseDescriptorCache(filepath.Join(configs.CacheDir(env), "descriptors"), descriptorCacheTTL, refresh)
+}
+
+// resolveServiceEndpoint returns the gRPC host:port of the service in the current environment,
+// together with the REST and identity endpoints it was derived from.
+func resolveServiceEndpoint(config *Config, serviceName string) (hostPort, apiEndpoint, identityEndpoint string, hasIdentityService bool, err error) {
+	endpoint := config.Environments[config.Environment].Endpoint
+	if strings.HasPrefix(endpoint, "grpc://") {
+		return strings.TrimPrefix(endpoint, "grpc://"), "", "", false, nil
+	}
+
+	apiEndpoint, err = configs.GetAPIEndpoint(endpoint)
+	if err != nil {
+		return "", "", "", false, fmt.Errorf("failed to get API endpoint: %v", err)
+	}
+	// Get identity service endpoint
+	identityEndpoint, hasIdentityService, err = configs.GetIdentityEndpoint(apiEndpoint)
+	if err != nil {
+		return "", "", "", false, fmt.Errorf("failed to get identity endpoint: %v", err)
+	}
+
+	if !hasIdentityService && strings.HasPrefix(endpoint, "grpc+ssl://") {
+		// The service is a sibling host of the endpoint, e.g. grpc+ssl://inventory.example.com:443
+		// for grpc+ssl://console-api.example.com:443/v1
+		host, _, _ := strings.Cut(strings.TrimPrefix(endpoint, "grpc+ssl://"), "/")
+		hostParts := strings.Split(host, ".")
+		if len(hostParts) < 4 {
+			return "", "", "", false, fmt.Errorf("invalid endpoint format: %s", endpoint)
+		}
+		hostParts[0] = format.ConvertServiceName(serviceName)
+		hostPort = strings.Join(hostParts, ".")
+	} else if !hasIdentityService {
+		urlParts := strings.Split(apiEndpoint, "//")
+		if len(urlParts) != 2 {
+			return "", "", "", false, fmt.Errorf("invalid API endpoint format: %s", apiEndpoint)
+		}
+
+		domainParts := strings.Split(urlParts[1], ".")
+		if len(domainParts) > 0 {
+			port := extractPortFromParts(domainParts)
+			if strings.Contains(domainParts[len(domainParts)-1], ":") {
+				parts := strings.Split(domainParts[len(domainParts)-1], ":")
+				domainParts[len(domainParts)-1] = parts[0]
+			}
+
+			domainParts[0] = format.ConvertServiceName(serviceName)
+			hostPort = strings.Join(domainParts, ".") + port
+		}
+	} else {
+		trimmedEndpoint := strings.TrimPrefix(identityEndpoint, "grpc+ssl://")
+		parts := strings.Split(trimmedEndpoint, ".")
+		if len(parts) < 4 {
+			return "", "", "", false, fmt.Errorf("invalid endpoint format: %s", trimmedEndpoint)
+		}
+
+		// Replace 'identity' with the converted service name
+		parts[0] = format.ConvertServiceName(serviceName)
+		hostPort = strings.Join(parts, ".")
+	}
+
+	return hostPort, apiEndpoint, identityEndpoint, hasIdentityService, nil
+}
+
+// ResolveMethod returns the descriptor of the method implementing verb on the resource
+// of the service in the current environment, using server reflection.
+func ResolveMethod(serviceName, verb, resourceName string) (*desc.MethodDescriptor, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	hostPort, _, _, _, err := resolveServiceEndpoint(config, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := strings.HasPrefix(config.Environments[config.Environment].Endpoint, "grpc://")
+	inv, err := invoker.Dial(hostPort, config.Environments[config.Environment].Token, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	defer inv.Close()
+	useDescriptorCache(inv, config.Environment, false)
+
+	return inv.ResolveMethod(serviceName, resourceName, verb)
+}
@@ -0,0 +1,160 @@
+package transport
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxParameterIndex guards against typos like 'filter[1000]' creating huge lists
+const maxParameterIndex = 100
+
+// pathSegment is one step of a parameter path, a field name optionally followed by a list index
+type pathSegment struct {
+	Name  string
+	Index int // -1 when the segment has no index
+}
+
+// parseParameterPath splits a path like 'query.filter[0].k' into its segments. A '.' that is
+// part of a field name, e.g. of a label key, is escaped as '\.'.
+// Protobuf has no lists of lists, so a segment takes at most one index.
+func parseParameterPath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for _, part := range splitParameterPath(path) {
+		segment := pathSegment{Name: part, Index: -1}
+
+		if open := strings.Index(part, "["); open >= 0 {
+			if !strings.HasSuffix(part, "]") {
+				return nil, fmt.Errorf("invalid parameter path '%s': missing ']' in '%s'", path, part)
+			}
+			index, err := strconv.Atoi(part[open+1 : len(part)-1])
+			if err != nil || index < 0 || index > maxParameterIndex {
+				return nil, fmt.Errorf("invalid parameter path '%s': bad index in '%s'", path, part)
+			}
+			segment.Name = part[:open]
+			segment.Index = index
+		}
+
+		if segment.Name == "" {
+			return nil, fmt.Errorf("invalid parameter path '%s': empty field name", path)
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, nil
+}
+
+// splitParameterPath splits a path at the dots that are not escaped as '\.'
+func splitParameterPath(path string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			part.WriteByte('.')
+			i++
+		case path[i] == '.':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(path[i])
+		}
+	}
+	return append(parts, part.String())
+}
+
+// setParameterPath sets value at a nested path like 'query.filter[0].k', creating the
+// intermediate messages and list elements. Plain keys are set as top-level fields. The list
+// elements added before the index, which must be set by other paths, are recorded in gaps.
+func setParameterPath(params map[string]interface{}, path string, value interface{}, gaps map[string]bool) error {
+	segments, err := parseParameterPath(path)
+	if err != nil {
+		return err
+	}
+
+	current := params
+	prefix := ""
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		name := strings.ReplaceAll(segment.Name, ".", "\\.")
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		if segment.Index < 0 {
+			if last {
+				current[segment.Name] = value
+				return nil
+			}
+			next, ok := current[segment.Name].(map[string]interface{})
+			if !ok {
+				if current[segment.Name] != nil {
+					return fmt.Errorf("invalid parameter path '%s': '%s' is already set to a value", path, segment.Name)
+				}
+				next = make(map[string]interface{})
+				current[segment.Name] = next
+			}
+			current = next
+			prefix = name
+			continue
+		}
+
+		list, ok := current[segment.Name].([]interface{})
+		if !ok && current[segment.Name] != nil {
+			return fmt.Errorf("invalid parameter path '%s': '%s' is not a list", path, segment.Name)
+		}
+		for len(list) <= segment.Index {
+			gaps[fmt.Sprintf("%s[%d]", name, len(list))] = true
+			list = append(list, nil)
+		}
+		current[segment.Name] = list
+		prefix = fmt.Sprintf("%s[%d]", name, segment.Index)
+		delete(gaps, prefix)
+
+		if last {
+			list[segment.Index] = value
+			return nil
+		}
+		next, ok := list[segment.Index].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			list[segment.Index] = next
+		}
+		current = next
+	}
+
+	return nil
+}
+
+// checkParameterLists returns an error when a list built from indexed paths has a gap,
+// e.g. 'filter[1].k' was given without 'filter[0]'. Lists given as JSON or YAML are left as is.
+func checkParameterLists(gaps map[string]bool) error {
+	if len(gaps) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(gaps))
+	for path := range gaps {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return fmt.Errorf("parameter '%s' is missing; list indices must start at 0 without gaps", paths[0])
+}
+
+// mergeParameters deep merges src into dst. Nested objects are merged key by key,
+// while lists and scalar values of src replace the ones in dst.
+func mergeParameters(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, ok := value.(map[string]interface{})
+		if !ok {
+			dst[key] = value
+			continue
+		}
+		dstMap, ok := dst[key].(map[string]interface{})
+		if !ok {
+			dstMap = make(map[string]interface{})
+			dst[key] = dstMap
+		}
+		mergeParameters(dstMap, srcMap)
+	}
+}
@@ -0,0 +1,160 @@
+package transport
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseParameterPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []pathSegment
+		wantErr bool
+	}{
+		{path: "name", want: []pathSegment{{Name: "name", Index: -1}}},
+		{path: "query.filter[0].k", want: []pathSegment{
+			{Name: "query", Index: -1},
+			{Name: "filter", Index: 0},
+			{Name: "k", Index: -1},
+		}},
+		{path: `tags.app\.kubernetes\.io/name`, want: []pathSegment{
+			{Name: "tags", Index: -1},
+			{Name: "app.kubernetes.io/name", Index: -1},
+		}},
+		{path: `a\b`, want: []pathSegment{{Name: `a\b`, Index: -1}}},
+		{path: "filter[100]", want: []pathSegment{{Name: "filter", Index: 100}}},
+		{path: "filter[101]", wantErr: true},
+		{path: "filter[-1]", wantErr: true},
+		{path: "filter[x]", wantErr: true},
+		{path: "filter[0", wantErr: true},
+		{path: "query..k", wantErr: true},
+		{path: "[0]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseParameterPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseParameterPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseParameterPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetParameterPath(t *testing.T) {
+	type param struct {
+		path  string
+		value interface{}
+	}
+	tests := []struct {
+		name    string
+		initial map[string]interface{}
+		params  []param
+		want    map[string]interface{}
+		wantErr bool
+		wantGap bool
+	}{
+		{
+			name:   "top-level field",
+			params: []param{{"name", "web"}},
+			want:   map[string]interface{}{"name": "web"},
+		},
+		{
+			name:   "nested fields",
+			params: []param{{"query.page.limit", "10"}, {"query.page.start", "1"}},
+			want: map[string]interface{}{
+				"query": map[string]interface{}{
+					"page": map[string]interface{}{"limit": "10", "start": "1"},
+				},
+			},
+		},
+		{
+			name:   "list elements",
+			params: []param{{"query.filter[0].k", "name"}, {"query.filter[0].v", "web"}, {"query.filter[1].k", "state"}},
+			want: map[string]interface{}{
+				"query": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{"k": "name", "v": "web"},
+						map[string]interface{}{"k": "state"},
+					},
+				},
+			},
+		},
+		{
+			name:   "escaped dot",
+			params: []param{{`tags.app\.kubernetes\.io/name`, "web"}},
+			want: map[string]interface{}{
+				"tags": map[string]interface{}{"app.kubernetes.io/name": "web"},
+			},
+		},
+		{
+			name:    "merged into a body",
+			initial: map[string]interface{}{"query": map[string]interface{}{"only": []interface{}{"name"}}},
+			params:  []param{{"query.filter[0].k", "name"}},
+			want: map[string]interface{}{
+				"query": map[string]interface{}{
+					"only":   []interface{}{"name"},
+					"filter": []interface{}{map[string]interface{}{"k": "name"}},
+				},
+			},
+		},
+		{
+			name:    "null elements of a body",
+			initial: map[string]interface{}{"labels": []interface{}{nil}},
+			want:    map[string]interface{}{"labels": []interface{}{nil}},
+		},
+		{
+			name:    "gap in a list",
+			params:  []param{{"query.filter[1].k", "name"}},
+			wantGap: true,
+		},
+		{
+			name:    "gap in a list of a body",
+			initial: map[string]interface{}{"filter": []interface{}{"a"}},
+			params:  []param{{"filter[2]", "c"}},
+			wantGap: true,
+		},
+		{
+			name:    "field set to a value",
+			params:  []param{{"query", "x"}, {"query.k", "name"}},
+			wantErr: true,
+		},
+		{
+			name:    "field not a list",
+			params:  []param{{"query", "x"}, {"query[0]", "name"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.initial
+			if params == nil {
+				params = make(map[string]interface{})
+			}
+			gaps := make(map[string]bool)
+			for _, p := range tt.params {
+				if err := setParameterPath(params, p.path, p.value, gaps); err != nil {
+					if !tt.wantErr {
+						t.Fatalf("setParameterPath(%q) error = %v", p.path, err)
+					}
+					return
+				}
+			}
+			if tt.wantErr {
+				t.Fatal("setParameterPath succeeded, want an error")
+			}
+
+			err := checkParameterLists(gaps)
+			if (err != nil) != tt.wantGap {
+				t.Fatalf("checkParameterLists error = %v, wantGap %v", err, tt.wantGap)
+			}
+			if !tt.wantGap && !reflect.DeepEqual(params, tt.want) {
+				t.Errorf("params = %v, want %v", params, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenPlaceholder is written instead of the real token in printed commands
+const tokenPlaceholder = "$CFCTL_TOKEN"
+
+// errCommandPrinted is returned when the call was printed instead of being executed
+var errCommandPrinted = errors.New("command printed")
+
+// buildGRPCurlCommand returns the grpcurl command equivalent to the dynamic call
+func buildGRPCurlCommand(hostPort, fullServiceName, verb string, body []byte, plaintext bool) string {
+	args := []string{"grpcurl"}
+	if plaintext {
+		args = append(args, "-plaintext")
+	}
+	args = append(args,
+		"-H", shellQuote("token: "+tokenPlaceholder),
+		"-d", shellQuote(string(body)),
+		hostPort,
+		fmt.Sprintf("%s/%s", fullServiceName, verb),
+	)
+
+	return strings.Join(args, " ")
+}
+
+// buildCurlCommand returns the curl command calling the REST gateway equivalent to the dynamic call
+func buildCurlCommand(apiEndpoint, serviceName, resourceName, verb string, body []byte) (string, error) {
+	if !strings.HasPrefix(apiEndpoint, "http://") && !strings.HasPrefix(apiEndpoint, "https://") {
+		return "", fmt.Errorf("--print-curl requires an HTTP API endpoint, use --print-grpcurl instead")
+	}
+
+	url := fmt.Sprintf("%s/%s/%s/%s",
+		strings.TrimSuffix(apiEndpoint, "/"),
+		serviceName,
+		toKebabCase(resourceName),
+		toKebabCase(verb))
+
+	args := []string{
+		"curl", "-X", "POST", shellQuote(url),
+		"-H", shellQuote("Content-Type: application/json"),
+		"-H", shellQuote("Authorization: Bearer " + tokenPlaceholder),
+		"-d", shellQuote(string(body)),
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// toKebabCase converts names like 'UserProfile' or 'get_workspaces' to 'user-profile' and 'get-workspaces'
+func toKebabCase(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_':
+			sb.WriteRune('-')
+		case unicode.IsUpper(r):
+			if i > 0 && name[i-1] != '_' {
+				sb.WriteRune('-')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// shellQuote wraps the value in single quotes while keeping the token placeholder expandable
+func shellQuote(value string) string {
+	quoted := "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+	return strings.ReplaceAll(quoted, tokenPlaceholder, "'\""+tokenPlaceholder+"\"'")
+}
@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+)
+
+// maxRecentIDs is how many IDs are remembered per ID field
+const maxRecentIDs = 500
+
+// maxIDCandidates is how many candidates an ambiguity error lists
+const maxIDCandidates = 10
+
+// recentIDsPath returns <cache dir>/<env>/recent_ids.yaml, see configs.CacheDir
+func recentIDsPath(env string) (string, error) {
+	return filepath.Join(configs.CacheDir(env), "recent_ids.yaml"), nil
+}
+
+// ResourceIDField returns the ID field of a resource, e.g. cloud_service_id for CloudService
+func ResourceIDField(resourceName string) string {
+	return toSnakeCase(resourceName) + "_id"
+}
+
+// loadRecentIDs returns the remembered IDs by ID field, e.g. project_id, most recent first
+func loadRecentIDs(env string) map[string][]string {
+	ids := make(map[string][]string)
+	path, err := recentIDsPath(env)
+	if err != nil {
+		return ids
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = yaml.Unmarshal(data, &ids)
+	}
+	return ids
+}
+
+// rememberIDs records the IDs of a list response so that later commands can take unique prefixes
+func rememberIDs(env, resourceName string, data map[string]interface{}) {
+	results, ok := data["results"].([]interface{})
+	if !ok || len(results) == 0 {
+		return
+	}
+
+	idField := ResourceIDField(resourceName)
+	var listed []string
+	for _, result := range results {
+		if item, ok := result.(map[string]interface{}); ok {
+			if id, ok := item[idField].(string); ok && id != "" {
+				listed = append(listed, id)
+			}
+		}
+	}
+	if len(listed) == 0 {
+		return
+	}
+
+	ids := loadRecentIDs(env)
+	seen := make(map[string]bool)
+	var merged []string
+	for _, id := range append(listed, ids[idField]...) {
+		if !seen[id] && len(merged) < maxRecentIDs {
+			seen[id] = true
+			merged = append(merged, id)
+		}
+	}
+	ids[idField] = merged
+
+	path, err := recentIDsPath(env)
+	if err != nil {
+		return
+	}
+	if out, err := yaml.Marshal(ids); err == nil {
+		_ = configs.WriteSecureFile(path, out)
+	}
+}
+
+// expandIDParameters replaces ID parameters given as a prefix, like project_id=project-1a2,
+// with the full ID when exactly one recently listed ID starts with it.
+func expandIDParameters(env string, params map[string]interface{}) error {
+	var ids map[string][]string
+	for key, value := range params {
+		prefix, ok := value.(string)
+		if !ok || prefix == "" || !strings.HasSuffix(key, "_id") {
+			continue
+		}
+		if ids == nil {
+			ids = loadRecentIDs(env)
+		}
+
+		var candidates []string
+		exact := false
+		for _, id := range ids[key] {
+			if id == prefix {
+				exact = true
+				break
+			}
+			if strings.HasPrefix(id, prefix) {
+				candidates = append(candidates, id)
+			}
+		}
+		if exact {
+			continue
+		}
+
+		switch {
+		case len(candidates) == 1:
+			params[key] = candidates[0]
+			pterm.Info.WithWriter(os.Stderr).Printf("Expanded %s '%s' to '%s'\n", key, prefix, candidates[0])
+		case len(candidates) > 1:
+			sort.Strings(candidates)
+			shown := candidates
+			if len(shown) > maxIDCandidates {
+				shown = shown[:maxIDCandidates]
+			}
+			more := ""
+			if len(candidates) > len(shown) {
+				more = fmt.Sprintf(" and %d more", len(candidates)-len(shown))
+			}
+			return fmt.Errorf("%s '%s' is ambiguous, it matches %s%s", key, prefix, strings.Join(shown, ", "), more)
+		}
+	}
+	return nil
+}
@@ -2,15 +2,13 @@ package transport
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
-	"log"
 	"net/url"
 	"strings"
 
+	"github.com/cloudforet-io/cfctl/internal/grpcconn"
 	"github.com/jhump/protoreflect/grpcreflect"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
@@ -47,16 +45,11 @@ func ListGRPCServices(endpoint string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if err := conn.Close(); err != nil {
-			log.Printf("failed to close gRPC connection: %v", err)
-		}
-	}()
 
 	return listServices(conn)
 }
 
-// GetGrpcConnection establishes a gRPC connection with the specified endpoint
+// GetGrpcConnection returns the shared gRPC connection of the specified endpoint, which must not be closed
 func GetGrpcConnection(endpoint string) (*grpc.ClientConn, error) {
 	parsedURL, err := url.Parse(endpoint)
 	if err != nil {
@@ -91,20 +84,13 @@ func CheckIdentityProxyAvailable(endpoint string) (bool, error) {
 	return checkRequiredServices(services)
 }
 
-// dialGRPC establishes a gRPC connection with the specified endpoint
+// dialGRPC returns the shared connection to the specified endpoint
 func dialGRPC(endpoint, host, port string) (*grpc.ClientConn, error) {
-	var opts []grpc.DialOption
-	if strings.HasPrefix(endpoint, "grpc+ssl://") {
-		tlsSetting := &tls.Config{
-			InsecureSkipVerify: false,
-		}
-		credential := credentials.NewTLS(tlsSetting)
-		opts = append(opts, grpc.WithTransportCredentials(credential))
-	} else {
+	if !strings.HasPrefix(endpoint, "grpc+ssl://") {
 		return nil, fmt.Errorf("unsupported scheme in endpoint: %s", endpoint)
 	}
 
-	conn, err := grpc.Dial(fmt.Sprintf("%s:%s", host, port), opts...)
+	conn, err := grpcconn.Get(fmt.Sprintf("%s:%s", host, port), false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial gRPC endpoint: %w", err)
 	}
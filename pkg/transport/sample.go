@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// samplePoolFactor is how many more results than the sample a random sample is picked from
+const samplePoolFactor = 10
+
+// maxSamplePool bounds the results fetched for a random sample
+const maxSamplePool = 10000
+
+// samplePoolSize returns how many results the server is asked for to take a sample of n
+func samplePoolSize(n int, random bool) int {
+	if !random {
+		return n
+	}
+	pool := n * samplePoolFactor
+	if pool > maxSamplePool {
+		pool = maxSamplePool
+	}
+	if pool < n {
+		pool = n
+	}
+	return pool
+}
+
+// setPageLimit asks the server for the first limit results, through Query.page where the
+// request supports it. Parameters that already page the results are kept.
+func setPageLimit(msgDesc *desc.MessageDescriptor, params map[string]interface{}, limit int) error {
+	queryField := msgDesc.FindFieldByName("query")
+	if queryField == nil || queryField.GetMessageType() == nil {
+		return nil
+	}
+	if queryField.GetMessageType().FindFieldByName("page") == nil &&
+		queryField.GetMessageType().GetFullyQualifiedName() != "google.protobuf.Struct" {
+		return nil
+	}
+
+	query, ok := params["query"].(map[string]interface{})
+	if !ok {
+		query = make(map[string]interface{})
+		if raw, ok := params["query"].(string); ok {
+			if err := json.Unmarshal([]byte(raw), &query); err != nil {
+				return fmt.Errorf("parameter 'query' expects a JSON object: %v", err)
+			}
+		}
+	}
+	if _, ok := query["page"]; !ok {
+		query["page"] = map[string]interface{}{"start": 1, "limit": limit}
+	}
+	params["query"] = query
+
+	return nil
+}
+
+// sampleResults keeps n of the results of a list response: the first n, or n picked at random
+// in their original order
+func sampleResults(data map[string]interface{}, n int, random bool) {
+	results, ok := data["results"].([]interface{})
+	if !ok || len(results) <= n {
+		return
+	}
+	if !random {
+		data["results"] = results[:n]
+		return
+	}
+
+	picked := rand.Perm(len(results))[:n]
+	keep := make([]bool, len(results))
+	for _, i := range picked {
+		keep[i] = true
+	}
+	sampled := make([]interface{}, 0, n)
+	for i, result := range results {
+		if keep[i] {
+			sampled = append(sampled, result)
+		}
+	}
+	data["results"] = sampled
+}
@@ -3,32 +3,31 @@ package transport
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
-	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/atotto/clipboard"
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/internal/features"
+	"github.com/cloudforet-io/cfctl/internal/grpcconn"
+	"github.com/cloudforet-io/cfctl/internal/invoker"
+	"github.com/cloudforet-io/cfctl/internal/timing"
+	"github.com/cloudforet-io/cfctl/internal/ui"
 	"github.com/cloudforet-io/cfctl/pkg/configs"
 	"github.com/cloudforet-io/cfctl/pkg/format"
-	"github.com/eiannone/keyboard"
+	"github.com/cloudforet-io/cfctl/pkg/output"
 	"github.com/pterm/pterm"
-	"github.com/spf13/viper"
 
 	"google.golang.org/grpc/metadata"
 
-	"github.com/jhump/protoreflect/dynamic"
 	"github.com/jhump/protoreflect/grpcreflect"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 
 	"gopkg.in/yaml.v3"
@@ -61,46 +60,68 @@ type FetchOptions struct {
 	Page                 int
 	PageSize             int
 	NoPaging             bool
+	PrintCurl            bool
+	PrintGRPCurl         bool
+	ChangesOnly          bool
+	Reload               bool
+	Flatten              bool
+	FlattenDepth         int
+	ColumnFormats        map[string]string
+	CountOnly            bool
+	// Sample keeps this many list results, asking the server for no more than needed
+	Sample int
+	// SampleRandom picks the sample at random among more results instead of taking the first
+	SampleRandom bool
+	GroupCount   string
+	GroupSum     string
+	Since        string
+	Until        string
+	TimeField    string
+	Query        string
+	Refresh      bool
+
+	currencyConverter *format.CurrencyConverter
 }
 
-// FetchService handles the execution of gRPC commands for all services
-func FetchService(serviceName string, verb string, resourceName string, options *FetchOptions) (map[string]interface{}, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user home directory: %v", err)
-	}
+// ErrNoToken is returned by FetchService when the current environment has no token.
+// The guidance on how to get one has already been printed.
+var ErrNoToken = errors.New("no token found for authentication")
 
-	// Read configuration file
-	mainViper := viper.New()
-	mainViper.SetConfigFile(filepath.Join(homeDir, ".cfctl", "setting.yaml"))
-	mainViper.SetConfigType("yaml")
-	if err := mainViper.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read configuration file. Please run 'cfctl login' first")
+// ResolveAlias returns the verb and resource that an alias of the service stands for, like
+// 'list Workspace' for 'cfctl identity ws'. ok is false when verb is not an alias.
+func ResolveAlias(serviceName, verb string) (aliasVerb, resourceName string, ok bool, err error) {
+	aliases, err := configs.ListAliases()
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to load aliases: %v", err)
 	}
 
-	// Check current environment
-	currentEnv := mainViper.GetString("environment")
-	if currentEnv == "" {
-		return nil, fmt.Errorf("no environment set. Please run 'cfctl login' first")
+	serviceAliases, _ := aliases[serviceName].(map[string]interface{})
+	command, _ := serviceAliases[verb].(string)
+	parts := strings.Fields(command)
+	if len(parts) < 2 {
+		return "", "", false, nil
 	}
+	return parts[0], parts[1], true, nil
+}
 
+// FetchService handles the execution of gRPC commands for all services
+func FetchService(serviceName string, verb string, resourceName string, options *FetchOptions) (map[string]interface{}, error) {
 	// Load configuration first
 	config, err := loadConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %v", err)
+		return nil, fmt.Errorf("failed to load config: %v. Please run 'cfctl login' first", err)
 	}
+	currentEnv := config.Environment
 
 	token := config.Environments[config.Environment].Token
 	if token == "" {
-		pterm.Error.Println("No token found for authentication.")
-
 		// Get current endpoint
 		endpoint := config.Environments[config.Environment].Endpoint
 
 		if config.Environment == "local" {
 			// Local environment message
 			pterm.Info.Printf("Using endpoint: %s\n", endpoint)
-			return nil, nil
+			return nil, ErrNoToken
 		} else if strings.HasSuffix(config.Environment, "-app") {
 			// App environment message
 			headerBox := pterm.DefaultBox.WithTitle("App Guide").
@@ -175,130 +196,61 @@ func FetchService(serviceName string, verb string, resourceName string, options
 			instructionBox.Println(strings.Join(steps, "\n\n"))
 		}
 
-		return nil, nil
+		return nil, ErrNoToken
 	}
 
 	// Get hostPort based on environment prefix
-	var hostPort string
-	var apiEndpoint string
-	var identityEndpoint string
-	var hasIdentityService bool
-	if strings.HasPrefix(config.Environments[config.Environment].Endpoint, "grpc://") {
-		hostPort = strings.TrimPrefix(config.Environments[config.Environment].Endpoint, "grpc://")
-	} else {
-		apiEndpoint, err = configs.GetAPIEndpoint(config.Environments[config.Environment].Endpoint)
-		if err != nil {
-			pterm.Error.Printf("Failed to get API endpoint: %v\n", err)
-			os.Exit(1)
-		}
-		// Get identity service endpoint
-		identityEndpoint, hasIdentityService, err = configs.GetIdentityEndpoint(apiEndpoint)
-		if err != nil {
-			pterm.Error.Printf("Failed to get identity endpoint: %v\n", err)
-			os.Exit(1)
-		}
-
-		if !hasIdentityService {
-			urlParts := strings.Split(apiEndpoint, "//")
-			if len(urlParts) != 2 {
-				return nil, fmt.Errorf("invalid API endpoint format: %s", apiEndpoint)
-			}
-
-			domainParts := strings.Split(urlParts[1], ".")
-			if len(domainParts) > 0 {
-				port := extractPortFromParts(domainParts)
-				if strings.Contains(domainParts[len(domainParts)-1], ":") {
-					parts := strings.Split(domainParts[len(domainParts)-1], ":")
-					domainParts[len(domainParts)-1] = parts[0]
-				}
-
-				domainParts[0] = format.ConvertServiceName(serviceName)
-				hostPort = strings.Join(domainParts, ".") + port
-			}
-		} else {
-			trimmedEndpoint := strings.TrimPrefix(identityEndpoint, "grpc+ssl://")
-			parts := strings.Split(trimmedEndpoint, ".")
-			if len(parts) < 4 {
-				return nil, fmt.Errorf("invalid endpoint format: %s", trimmedEndpoint)
-			}
-
-			// Replace 'identity' with the converted service name
-			parts[0] = format.ConvertServiceName(serviceName)
-			hostPort = strings.Join(parts, ".")
-		}
+	hostPort, apiEndpoint, _, _, err := resolveServiceEndpoint(config, serviceName)
+	if err != nil {
+		return nil, err
 	}
 
-	// Configure gRPC connection
-	var conn *grpc.ClientConn
-	if strings.HasPrefix(config.Environments[config.Environment].Endpoint, "grpc://") {
-		hostPort := strings.TrimPrefix(config.Environments[config.Environment].Endpoint, "grpc://")
-		// For local environment, use insecure connection
-		conn, err = grpc.Dial(hostPort, grpc.WithInsecure())
-		if err != nil {
+	// The connection is shared with the call made by fetchJSONResponse
+	plaintext := strings.HasPrefix(config.Environments[config.Environment].Endpoint, "grpc://")
+	conn, err := grpcconn.Get(hostPort, plaintext)
+	if err != nil {
+		if plaintext {
 			pterm.Error.Printf("Cannot connect to local gRPC server (%s)\n", hostPort)
 			pterm.Info.Println("Please check if your gRPC server is running")
-			return nil, fmt.Errorf("failed to connect to local server: %v", err)
-		}
-	} else {
-		// Existing SSL connection logic for non-local environments
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: false,
-		}
-		creds := credentials.NewTLS(tlsConfig)
-		conn, err = grpc.Dial(hostPort, grpc.WithTransportCredentials(creds))
-		if err != nil {
-			return nil, fmt.Errorf("connection failed: %v", err)
 		}
+		return nil, fmt.Errorf("connection failed: %v", err)
 	}
-	defer conn.Close()
 
 	// Create reflection client for both service calls and minimal fields detection
 	ctx := metadata.AppendToOutgoingContext(context.Background(), "token", config.Environments[config.Environment].Token)
 	refClient := grpcreflect.NewClient(ctx, grpc_reflection_v1alpha.NewServerReflectionClient(conn))
 	defer refClient.Reset()
 
-	// Check for alias
-	aliases, err := configs.ListAliases()
+	// Check if the verb is an alias
+	aliasVerb, aliasResource, isAlias, err := ResolveAlias(serviceName, verb)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load aliases: %v", err)
+		return nil, err
 	}
-
-	// Check if the verb is an alias
-	if serviceAliases, ok := aliases[serviceName].(map[string]interface{}); ok {
-		if cmd, ok := serviceAliases[verb].(string); ok {
-			// Split the alias command
-			parts := strings.Fields(cmd)
-			if len(parts) >= 2 {
-				verb = parts[0]
-				resourceName = parts[1]
-
-				// If the command from alias is 'list'
-				if verb == "list" {
-					if !options.OutputFormatExplicit {
-						options.OutputFormat = "table"
-					}
-
-					// Create new options for list command
-					newOptions := &FetchOptions{
-						Parameters:           options.Parameters,
-						JSONParameter:        options.JSONParameter,
-						FileParameter:        options.FileParameter,
-						APIVersion:           options.APIVersion,
-						OutputFormat:         options.OutputFormat,
-						OutputFormatExplicit: options.OutputFormatExplicit,
-						CopyToClipboard:      options.CopyToClipboard,
-						MinimalColumns:       false, // Always show all columns for alias
-						PageSize:             15,    // Default page size
-					}
-
-					options = newOptions
+	if isAlias {
+		verb = aliasVerb
+		resourceName = aliasResource
+
+		// If the command from alias is 'list'
+		if verb == "list" {
+			aliasOptions := *options
+			if !options.OutputFormatExplicit {
+				aliasOptions.OutputFormat = "table"
+				// Query results are usually consumed by scripts
+				if options.Query != "" {
+					aliasOptions.OutputFormat = "json"
 				}
 			}
+			aliasOptions.MinimalColumns = false // Always show all columns for alias
+			aliasOptions.PageSize = 15          // Default page size
+			options = &aliasOptions
 		}
 	}
 
 	// Call the service
-	jsonBytes, err := fetchJSONResponse(config, serviceName, verb, resourceName, options, apiEndpoint, identityEndpoint, hasIdentityService)
+	jsonBytes, err := fetchJSONResponse(config, hostPort, apiEndpoint, serviceName, verb, resourceName, options)
+	if errors.Is(err, errCommandPrinted) {
+		return nil, nil
+	}
 	if err != nil {
 		// Check if the error is about missing required parameters
 		if strings.Contains(err.Error(), "ERROR_REQUIRED_PARAMETER") {
@@ -317,6 +269,29 @@ func FetchService(serviceName string, verb string, resourceName string, options
 		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
 	}
 
+	// Secret data is never printed
+	if serviceName == "secret" {
+		RedactSecretData(respMap)
+	}
+
+	if verb == "list" && options.Sample > 0 {
+		sampleResults(respMap, options.Sample, options.SampleRandom)
+	}
+
+	if verb == "list" {
+		rememberIDs(currentEnv, resourceName, respMap)
+	}
+
+	if options.CountOnly {
+		fmt.Println(resultCount(respMap))
+		return respMap, nil
+	}
+
+	if options.GroupCount != "" {
+		printGroupCount(respMap, options.GroupCount, options.GroupSum)
+		return respMap, nil
+	}
+
 	// Print the data if not in watch mode
 	if options.OutputFormat != "" {
 		if options.SortBy != "" && verb == "list" {
@@ -362,6 +337,19 @@ func FetchService(serviceName string, verb string, resourceName string, options
 			}
 		}
 
+		// Flatten nested fields into dotted columns for spreadsheet-like outputs
+		if options.Flatten && (options.OutputFormat == "table" || options.OutputFormat == "csv") {
+			depth := options.FlattenDepth
+			if depth <= 0 {
+				depth = format.DefaultFlattenDepth
+			}
+			if results, ok := respMap["results"].([]interface{}); ok {
+				format.FlattenResults(results, depth)
+			} else {
+				respMap = format.Flatten(respMap, depth)
+			}
+		}
+
 		// Filter columns if specified
 		if options.Columns != "" && verb == "list" {
 			if results, ok := respMap["results"].([]interface{}); ok {
@@ -383,6 +371,11 @@ func FetchService(serviceName string, verb string, resourceName string, options
 			}
 		}
 
+		if options.OutputFormat == "table" {
+			options.ColumnFormats = loadColumnFormats(serviceName, resourceName)
+			options.currencyConverter = currencyConverterFor(options.ColumnFormats)
+		}
+
 		printData(respMap, options, serviceName, verb, resourceName, refClient)
 	}
 
@@ -419,26 +412,38 @@ func extractPortFromParts(parts []string) string {
 
 // promptForParameter prompts the user to enter a value for the given parameter
 func promptForParameter(paramName string) (string, error) {
+	if !configs.Interactive() {
+		return "", fmt.Errorf("no terminal to enter '%s', pass it with -p %s=<value>", paramName, paramName)
+	}
 	prompt := fmt.Sprintf("Please enter value for '%s'", paramName)
-	result, err := pterm.DefaultInteractiveTextInput.WithDefaultText("").Show(prompt)
+	result, err := ui.Input(prompt, "")
 	if err != nil {
 		return "", fmt.Errorf("failed to read input: %v", err)
 	}
 	return result, nil
 }
 
+// loadConfig returns the pinned session of a long-running mode, or reads the setting
 func loadConfig() (*Config, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %v", err)
+	defer timing.Track(timing.Config)()
+
+	if config := pinnedSession(); config != nil {
+		return config, nil
 	}
+	return readConfig()
+}
+
+// readConfig reads the current environment from setting.yaml and its token
+func readConfig() (*Config, error) {
+	return readEnvironmentConfig("")
+}
 
+// readEnvironmentConfig reads the named environment from setting.yaml and its token, or the
+// current environment when env is empty. CFCTL_TOKEN only applies to the current environment.
+func readEnvironmentConfig(env string) (*Config, error) {
 	// Load main configuration file
-	mainV := viper.New()
-	mainConfigPath := filepath.Join(home, ".cfctl", "setting.yaml")
-	mainV.SetConfigFile(mainConfigPath)
-	mainV.SetConfigType("yaml")
-	if err := mainV.ReadInConfig(); err != nil {
+	mainV, err := configs.Setting()
+	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %v", err)
 	}
 
@@ -446,28 +451,40 @@ func loadConfig() (*Config, error) {
 	if currentEnv == "" {
 		return nil, fmt.Errorf("no environment set in config")
 	}
+	envToken := configs.EnvToken()
+	if env != "" && env != currentEnv {
+		if !mainV.IsSet(fmt.Sprintf("environments.%s", env)) {
+			return nil, fmt.Errorf("environment '%s' not found in setting.yaml", env)
+		}
+		currentEnv = env
+		envToken = ""
+	}
 
 	// Get environment config from main config file
 	envConfig := &Environment{
-		Endpoint: mainV.GetString(fmt.Sprintf("environments.%s.endpoint", currentEnv)),
-		Proxy:    mainV.GetString(fmt.Sprintf("environments.%s.proxy", currentEnv)),
-		Token:    mainV.GetString(fmt.Sprintf("environments.%s.token", currentEnv)),
+		Endpoint: configs.SettingString(mainV, fmt.Sprintf("environments.%s.endpoint", currentEnv)),
+		Proxy:    configs.SettingString(mainV, fmt.Sprintf("environments.%s.proxy", currentEnv)),
+		Token:    configs.SettingString(mainV, fmt.Sprintf("environments.%s.token", currentEnv)),
 	}
 
 	// Handle token based on environment type
 	if strings.HasSuffix(currentEnv, "-user") {
 		// For user environments, read from access_token file (Actual token is grant_token)
-		grantTokenPath := filepath.Join(home, ".cfctl", "cache", currentEnv, "access_token")
-		tokenBytes, err := os.ReadFile(grantTokenPath)
+		accessToken, err := configs.AccessToken(currentEnv)
 		if err == nil {
-			envConfig.Token = strings.TrimSpace(string(tokenBytes))
+			envConfig.Token = accessToken
+		} else if envToken == "" && configs.SelectedWorkspace() != "" {
+			return nil, err
 		}
 	} else if strings.HasSuffix(currentEnv, "-app") {
 		// For app environments, get token from main config
-		envConfig.Token = mainV.GetString(fmt.Sprintf("environments.%s.token", currentEnv))
+		envConfig.Token = configs.SettingString(mainV, fmt.Sprintf("environments.%s.token", currentEnv))
 	} else if currentEnv == "local" {
 		// For local environment, get token from main config
-		envConfig.Token = mainV.GetString(fmt.Sprintf("environments.%s.token", currentEnv))
+		envConfig.Token = configs.SettingString(mainV, fmt.Sprintf("environments.%s.token", currentEnv))
+	}
+	if envToken != "" {
+		envConfig.Token = envToken
 	}
 
 	if envConfig == nil {
@@ -482,197 +499,94 @@ func loadConfig() (*Config, error) {
 	}, nil
 }
 
-func fetchJSONResponse(config *Config, serviceName string, verb string, resourceName string, options *FetchOptions, apiEndpoint, identityEndpoint string, hasIdentityService bool) ([]byte, error) {
-	var conn *grpc.ClientConn
-	var err error
-	var hostPort string
-
+// fetchJSONResponse calls verb on the resource of the service at hostPort, with the REST
+// endpoint used for --print-curl, both as resolved by resolveServiceEndpoint, and returns the
+// response as JSON
+func fetchJSONResponse(config *Config, hostPort, apiEndpoint, serviceName, verb, resourceName string, options *FetchOptions) ([]byte, error) {
 	if verb == "list" && options.Page > 0 {
 		options.Parameters = append(options.Parameters,
 			fmt.Sprintf("page=%d", options.Page),
 			fmt.Sprintf("page_size=%d", options.PageSize))
 	}
 
-	if strings.HasPrefix(config.Environments[config.Environment].Endpoint, "grpc://") {
-		hostPort = strings.TrimPrefix(config.Environments[config.Environment].Endpoint, "grpc://")
-		conn, err = grpc.Dial(hostPort, grpc.WithInsecure(),
-			grpc.WithDefaultCallOptions(
-				grpc.MaxCallRecvMsgSize(10*1024*1024),
-				grpc.MaxCallSendMsgSize(10*1024*1024),
-			))
-		if err != nil {
+	plaintext := strings.HasPrefix(config.Environments[config.Environment].Endpoint, "grpc://")
+	conn, err := grpcconn.Get(hostPort, plaintext)
+	if err != nil {
+		if plaintext {
 			return nil, fmt.Errorf("connection failed: unable to connect to local server: %v", err)
 		}
-	} else {
-		if !hasIdentityService {
-			// Handle gRPC+SSL protocol directly
-			if strings.HasPrefix(config.Environments[config.Environment].Endpoint, "grpc+ssl://") {
-				endpoint := config.Environments[config.Environment].Endpoint
-				parts := strings.Split(endpoint, "/")
-				endpoint = strings.Join(parts[:len(parts)-1], "/")
-				parts = strings.Split(endpoint, "://")
-				if len(parts) != 2 {
-					return nil, fmt.Errorf("invalid endpoint format: %s", endpoint)
-				}
-
-				hostParts := strings.Split(parts[1], ".")
-				if len(hostParts) < 4 {
-					return nil, fmt.Errorf("invalid endpoint format: %s", endpoint)
-				}
-
-				// Replace service name
-				hostParts[0] = format.ConvertServiceName(serviceName)
-				hostPort = strings.Join(hostParts, ".")
-			} else {
-				// Original HTTP/HTTPS handling
-				urlParts := strings.Split(apiEndpoint, "//")
-				if len(urlParts) != 2 {
-					return nil, fmt.Errorf("invalid API endpoint format: %s", apiEndpoint)
-				}
-
-				domainParts := strings.Split(urlParts[1], ".")
-				if len(domainParts) > 0 {
-					port := extractPortFromParts(domainParts)
-					if strings.Contains(domainParts[len(domainParts)-1], ":") {
-						parts := strings.Split(domainParts[len(domainParts)-1], ":")
-						domainParts[len(domainParts)-1] = parts[0]
-					}
-
-					domainParts[0] = format.ConvertServiceName(serviceName)
-					hostPort = strings.Join(domainParts, ".") + port
-				}
-			}
-		} else {
-			trimmedEndpoint := strings.TrimPrefix(identityEndpoint, "grpc+ssl://")
-			parts := strings.Split(trimmedEndpoint, ".")
-			if len(parts) < 4 {
-				return nil, fmt.Errorf("invalid endpoint format: %s", trimmedEndpoint)
-			}
-
-			// Replace 'identity' with the converted service name
-			parts[0] = format.ConvertServiceName(serviceName)
-			hostPort = strings.Join(parts, ".")
-		}
-
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: false,
-		}
-		creds := credentials.NewTLS(tlsConfig)
-
-		conn, err = grpc.Dial(hostPort,
-			grpc.WithTransportCredentials(creds),
-			grpc.WithDefaultCallOptions(
-				grpc.MaxCallRecvMsgSize(10*1024*1024),
-				grpc.MaxCallSendMsgSize(10*1024*1024),
-			))
-		if err != nil {
-			return nil, fmt.Errorf("connection failed: unable to connect to %s: %v", hostPort, err)
-		}
+		return nil, fmt.Errorf("connection failed: unable to connect to %s: %v", hostPort, err)
 	}
 
-	defer func(conn *grpc.ClientConn) {
-		err := conn.Close()
-		if err != nil {
-
-		}
-	}(conn)
-
-	ctx := metadata.AppendToOutgoingContext(context.Background(), "token", config.Environments[config.Environment].Token)
-	refClient := grpcreflect.NewClient(ctx, grpc_reflection_v1alpha.NewServerReflectionClient(conn))
-	defer refClient.Reset()
-
-	fullServiceName, err := discoverService(refClient, serviceName, resourceName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to discover service: %v", err)
-	}
+	inv := invoker.New(conn, config.Environments[config.Environment].Token)
+	defer inv.Close()
+	useDescriptorCache(inv, config.Environment, options.Refresh)
 
-	serviceDesc, err := refClient.ResolveService(fullServiceName)
+	methodDesc, err := inv.ResolveMethod(serviceName, resourceName, verb)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve service %s: %v", fullServiceName, err)
-	}
-
-	methodDesc := serviceDesc.FindMethodByName(verb)
-	if methodDesc == nil {
-		return nil, fmt.Errorf("method not found: %s", verb)
+		return nil, err
 	}
-
-	// Create request and response messages
-	reqMsg := dynamic.NewMessage(methodDesc.GetInputType())
-	respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
+	fullServiceName := methodDesc.GetService().GetFullyQualifiedName()
 
 	// Parse and set input parameters
 	inputParams, err := parseParameters(options)
 	if err != nil {
 		return nil, err
 	}
-
-	// Marshal the inputParams map to JSON
-	jsonBytes, err := json.Marshal(inputParams)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal input parameters to JSON: %v", err)
+	if err := expandIDParameters(config.Environment, inputParams); err != nil {
+		return nil, err
 	}
-
-	// Unmarshal the JSON into the dynamic.Message
-	if err := reqMsg.UnmarshalJSON(jsonBytes); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON into request message: %v", err)
+	if err := coerceParameters(methodDesc.GetInputType(), inputParams, ""); err != nil {
+		return nil, err
 	}
 
-	fullMethod := fmt.Sprintf("/%s/%s", fullServiceName, verb)
+	if err := applyTimeRange(methodDesc.GetInputType(), inputParams, options.TimeField, options.Since, options.Until); err != nil {
+		return nil, err
+	}
 
-	// Handle client streaming
-	if !methodDesc.IsClientStreaming() && methodDesc.IsServerStreaming() {
-		streamDesc := &grpc.StreamDesc{
-			StreamName:    verb,
-			ServerStreams: true,
-			ClientStreams: false,
+	if options.CountOnly {
+		if err := setCountOnly(methodDesc.GetInputType(), inputParams); err != nil {
+			return nil, err
 		}
+	}
 
-		stream, err := conn.NewStream(ctx, streamDesc, fullMethod)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create stream: %v", err)
+	if verb == "list" && options.Sample > 0 {
+		if err := setPageLimit(methodDesc.GetInputType(), inputParams, samplePoolSize(options.Sample, options.SampleRandom)); err != nil {
+			return nil, err
 		}
+	}
 
-		if err := stream.SendMsg(reqMsg); err != nil {
-			return nil, fmt.Errorf("failed to send request message: %v", err)
-		}
+	reqMsg, jsonBytes, err := invoker.NewRequest(methodDesc, inputParams)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := stream.CloseSend(); err != nil {
-			return nil, fmt.Errorf("failed to close send: %v", err)
+	// Print the equivalent external-tool command instead of calling the service
+	if options.PrintCurl || options.PrintGRPCurl {
+		if options.PrintGRPCurl {
+			fmt.Println(buildGRPCurlCommand(hostPort, fullServiceName, verb, jsonBytes, plaintext))
 		}
-
-		var allResponses []string
-		for {
-			respMsg := dynamic.NewMessage(methodDesc.GetOutputType())
-			err := stream.RecvMsg(respMsg)
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return nil, fmt.Errorf("failed to receive response: %v", err)
-			}
-
-			jsonBytes, err := respMsg.MarshalJSON()
+		if options.PrintCurl {
+			curlCommand, err := buildCurlCommand(apiEndpoint, serviceName, resourceName, verb, jsonBytes)
 			if err != nil {
-				return nil, fmt.Errorf("failed to marshal response: %v", err)
+				return nil, err
 			}
-
-			allResponses = append(allResponses, string(jsonBytes))
-		}
-
-		if len(allResponses) == 1 {
-			return []byte(allResponses[0]), nil
+			fmt.Println(curlCommand)
 		}
-
-		combinedJSON := fmt.Sprintf("{\"results\": [%s]}", strings.Join(allResponses, ","))
-		return []byte(combinedJSON), nil
+		pterm.Info.Printf("Export the token before running the command: export CFCTL_TOKEN=$(cfctl token show --raw)\n")
+		return nil, errCommandPrinted
 	}
 
-	// Regular unary call
-	err = conn.Invoke(ctx, fullMethod, reqMsg, respMsg)
+	respBytes, err := inv.Invoke(methodDesc, reqMsg)
 	if err != nil {
 		if strings.Contains(err.Error(), "ERROR_AUTHENTICATE_FAILURE") ||
 			strings.Contains(err.Error(), "Token is invalid or expired") {
 
+			// Long-running sessions wait for a new token instead of printing the guide on every poll
+			if pinnedSession() != nil {
+				return nil, errAuthenticationRequired
+			}
+
 			// Check if current environment is app type
 			if strings.HasSuffix(config.Environment, "-app") {
 				headerBox := pterm.DefaultBox.WithTitle("App Token Required").
@@ -732,21 +646,31 @@ func fetchJSONResponse(config *Config, serviceName string, verb string, resource
 
 				instructionBox.Println(strings.Join(steps, "\n\n"))
 
-				return nil, fmt.Errorf("authentication required")
+				return nil, errAuthenticationRequired
 			}
 		}
-		return nil, fmt.Errorf("failed to invoke method %s: %v", fullMethod, err)
+		return nil, err
 	}
 
-	return respMsg.MarshalJSON()
+	return respBytes, nil
 }
 
 func parseParameters(options *FetchOptions) (map[string]interface{}, error) {
 	parsed := make(map[string]interface{})
 
-	// Load from file parameter if provided
+	if options.FileParameter == "-" && options.JSONParameter == "-" {
+		return nil, fmt.Errorf("only one of the file and JSON parameters can be read from stdin")
+	}
+
+	// Load from file parameter if provided, a YAML or JSON file or '-' for stdin
 	if options.FileParameter != "" {
-		data, err := os.ReadFile(options.FileParameter)
+		var data []byte
+		var err error
+		if options.FileParameter == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(options.FileParameter)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to read file parameter: %v", err)
 		}
@@ -755,46 +679,59 @@ func parseParameters(options *FetchOptions) (map[string]interface{}, error) {
 		if err := yaml.Unmarshal(data, &yamlData); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal YAML file: %v", err)
 		}
+		mergeParameters(parsed, yamlData)
+	}
 
-		for key, value := range yamlData {
-			switch v := value.(type) {
-			case map[string]interface{}:
-				// Retain as map instead of converting to Struct
-				parsed[key] = v
-			case []interface{}:
-				// Retain lists as is
-				parsed[key] = v
-			default:
-				parsed[key] = value
-			}
+	// Load from JSON parameter if provided, '-' reads it from stdin
+	jsonParameter := options.JSONParameter
+	if jsonParameter == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON parameter from stdin: %v", err)
 		}
+		jsonParameter = strings.TrimSpace(string(data))
 	}
-
-	// Load from JSON parameter if provided
-	if options.JSONParameter != "" {
-		if err := json.Unmarshal([]byte(options.JSONParameter), &parsed); err != nil {
+	if jsonParameter != "" {
+		var jsonData map[string]interface{}
+		if err := json.Unmarshal([]byte(jsonParameter), &jsonData); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal JSON parameter: %v", err)
 		}
+		mergeParameters(parsed, jsonData)
 	}
 
-	// Parse key=value parameters
+	// Parse key=value parameters, collecting the values of keys given more than once
+	var keys []string
+	values := make(map[string][]string)
 	for _, param := range options.Parameters {
 		parts := strings.SplitN(param, "=", 2)
 		if len(parts) != 2 {
 			return nil, fmt.Errorf("invalid parameter format. Use key=value")
 		}
 		key := parts[0]
-		value := parts[1]
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], parts[1])
+	}
 
-		// Attempt to parse value as JSON
-		var jsonValue interface{}
-		if err := json.Unmarshal([]byte(value), &jsonValue); err == nil {
-			parsed[key] = jsonValue
-		} else {
-			parsed[key] = value
+	gaps := make(map[string]bool)
+	for _, key := range keys {
+		// Values are kept as strings and converted by coerceParameters using the field types.
+		// Keys may address nested fields, e.g. 'query.filter[0].k=name', which are merged into
+		// the request body loaded from the file or JSON parameter.
+		var value interface{} = values[key][0]
+		if len(values[key]) > 1 {
+			value = parameterList(values[key])
+		}
+		if err := setParameterPath(parsed, key, value, gaps); err != nil {
+			return nil, err
 		}
 	}
 
+	if err := checkParameterLists(gaps); err != nil {
+		return nil, err
+	}
+
 	return parsed, nil
 }
 
@@ -804,92 +741,113 @@ func discoverService(refClient *grpcreflect.Client, serviceName string, resource
 		return "", fmt.Errorf("failed to list services: %v", err)
 	}
 
-	for _, service := range services {
-		if strings.Contains(service, ".plugin.") && strings.HasSuffix(service, resourceName) {
-			return service, nil
-		}
-	}
-
-	for _, service := range services {
-		if strings.Contains(service, fmt.Sprintf("spaceone.api.%s", serviceName)) &&
-			strings.HasSuffix(service, resourceName) {
-			return service, nil
-		}
-	}
-
-	return "", fmt.Errorf("service not found for %s.%s", serviceName, resourceName)
+	return invoker.FindService(services, serviceName, resourceName)
 }
 
 // WatchResource monitors a resource for changes and prints updates
 func WatchResource(serviceName, verb, resource string, options *FetchOptions) error {
+	if options.OutputFormat == "jsonl" {
+		return watchChangeFeed(serviceName, verb, resource, options)
+	}
+
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt)
+	defer cleanup.Intercept()()
 
-	seenItems := make(map[string]bool)
-
-	initialData, err := FetchService(serviceName, verb, resource, &FetchOptions{
-		Parameters:      options.Parameters,
-		JSONParameter:   options.JSONParameter,
-		FileParameter:   options.FileParameter,
-		APIVersion:      options.APIVersion,
-		OutputFormat:    "",
-		CopyToClipboard: false,
-	})
+	reloader, err := newSessionReloader(options.Reload)
 	if err != nil {
 		return err
 	}
+	defer reloader.stop()
+
+	fetchItems := func() ([]map[string]interface{}, error) {
+		data, err := FetchService(serviceName, verb, resource, &FetchOptions{
+			Parameters:      options.Parameters,
+			JSONParameter:   options.JSONParameter,
+			FileParameter:   options.FileParameter,
+			APIVersion:      options.APIVersion,
+			OutputFormat:    "",
+			CopyToClipboard: false,
+			Since:           options.Since,
+			Until:           options.Until,
+			TimeField:       options.TimeField,
+		})
+		if err != nil {
+			return nil, err
+		}
 
-	if results, ok := initialData["results"].([]interface{}); ok {
-		var recentItems []map[string]interface{}
-
-		for _, item := range results {
-			if m, ok := item.(map[string]interface{}); ok {
-				identifier := format.GenerateIdentifier(m)
-				seenItems[identifier] = true
-
-				recentItems = append(recentItems, m)
-				if len(recentItems) > 20 {
-					recentItems = recentItems[1:]
+		var items []map[string]interface{}
+		if results, ok := data["results"].([]interface{}); ok {
+			for _, item := range results {
+				if m, ok := item.(map[string]interface{}); ok {
+					items = append(items, m)
 				}
 			}
 		}
+		return items, nil
+	}
+
+	seenItems := make(map[string]bool)
+
+	// rebaseline marks the items of a newly reloaded environment as seen without printing them
+	rebaseline := func() {
+		items, err := fetchItems()
+		if err != nil {
+			return
+		}
+		seenItems = make(map[string]bool)
+		for _, item := range items {
+			seenItems[format.GenerateIdentifier(item)] = true
+		}
+	}
+
+	initialItems, err := fetchItems()
+	if err != nil {
+		return err
+	}
 
-		if len(recentItems) > 0 {
-			fmt.Printf("Recent items:\n")
-			format.PrintNewItems(recentItems)
+	var recentItems []map[string]interface{}
+	for _, m := range initialItems {
+		identifier := format.GenerateIdentifier(m)
+		seenItems[identifier] = true
+
+		recentItems = append(recentItems, m)
+		if len(recentItems) > 20 {
+			recentItems = recentItems[1:]
 		}
 	}
 
-	fmt.Printf("\nWatching for changes... (Ctrl+C to quit)\n\n")
+	if len(recentItems) > 0 {
+		fmt.Printf("Recent items:\n")
+		format.PrintNewItems(recentItems)
+	}
+
+	fmt.Printf("\nWatching for changes... (Ctrl+C to quit, SIGHUP to reload setting)\n\n")
 
 	for {
 		select {
 		case <-ticker.C:
-			newData, err := FetchService(serviceName, verb, resource, &FetchOptions{
-				Parameters:      options.Parameters,
-				JSONParameter:   options.JSONParameter,
-				FileParameter:   options.FileParameter,
-				APIVersion:      options.APIVersion,
-				OutputFormat:    "",
-				CopyToClipboard: false,
-			})
+			if reloader.settingChanged() && reloader.reload("setting.yaml changed") {
+				rebaseline()
+				continue
+			}
+
+			reloader.pickUpToken()
+			items, err := fetchItems()
 			if err != nil {
+				reloader.reportFetchError(err)
 				continue
 			}
 
 			var newItems []map[string]interface{}
-			if results, ok := newData["results"].([]interface{}); ok {
-				for _, item := range results {
-					if m, ok := item.(map[string]interface{}); ok {
-						identifier := format.GenerateIdentifier(m)
-						if !seenItems[identifier] {
-							newItems = append(newItems, m)
-							seenItems[identifier] = true
-						}
-					}
+			for _, m := range items {
+				identifier := format.GenerateIdentifier(m)
+				if !seenItems[identifier] {
+					newItems = append(newItems, m)
+					seenItems[identifier] = true
 				}
 			}
 
@@ -902,6 +860,11 @@ func WatchResource(serviceName, verb, resource string, options *FetchOptions) er
 				fmt.Println()
 			}
 
+		case <-reloader.signals:
+			if reloader.reload("SIGHUP") {
+				rebaseline()
+			}
+
 		case <-sigChan:
 			fmt.Println("\nStopping watch...")
 			return nil
@@ -910,64 +873,43 @@ func WatchResource(serviceName, verb, resource string, options *FetchOptions) er
 }
 
 func printData(data map[string]interface{}, options *FetchOptions, serviceName, verbName, resourceName string, refClient *grpcreflect.Client) {
-	var output string
+	defer timing.Track(timing.Render)()
 
-	switch options.OutputFormat {
-	case "json":
-		dataBytes, err := json.MarshalIndent(data, "", "  ")
-		if err != nil {
-			log.Fatalf("Failed to marshal response to JSON: %v", err)
-		}
-		output = string(dataBytes)
-		fmt.Println(output)
+	var rendered string
 
-	case "yaml":
-		if results, ok := data["results"].([]interface{}); ok && len(results) > 0 {
-			var sb strings.Builder
+	switch {
+	case options.OutputFormat == "table" && options.Query == "":
+		rendered = printTable(data, options, serviceName, verbName, resourceName, refClient)
 
-			for i, item := range results {
-				if i > 0 {
-					sb.WriteString("---\n")
-				}
-				sb.WriteString(printYAMLDoc(item))
-			}
-			output = sb.String()
-			fmt.Print(output)
-		} else {
-			output = printYAMLDoc(data)
-			fmt.Print(output)
+	default:
+		// Query results and json, yaml and csv are rendered by the shared output package, anything else as yaml
+		spec := output.Spec{Format: options.OutputFormat, Query: options.Query}
+		if spec.Validate(output.Table, output.JSON, output.YAML, output.CSV, output.ID) != nil {
+			spec.Format = output.YAML
+		}
+		if spec.Format == output.ID {
+			spec.IDField = resolveIDField(refClient, serviceName, verbName, resourceName)
 		}
 
-	case "table":
-		output = printTable(data, options, serviceName, verbName, resourceName, refClient)
-
-	case "csv":
-		output = printCSV(data)
-
-	default:
-		output = printYAMLDoc(data)
-		fmt.Print(output)
+		var buf bytes.Buffer
+		if err := output.Write(&buf, data, spec); err != nil {
+			pterm.Error.Printf("Failed to render response: %v\n", err)
+			cleanup.Exit(1)
+		}
+		rendered = buf.String()
+		fmt.Print(rendered)
 	}
 
 	// Copy to clipboard if requested
-	if options.CopyToClipboard && output != "" {
-		if err := clipboard.WriteAll(output); err != nil {
-			log.Fatalf("Failed to copy to clipboard: %v", err)
+	if options.CopyToClipboard && rendered != "" {
+		if err := clipboard.WriteAll(rendered); err != nil {
+			pterm.Error.Printf("Failed to copy to clipboard: %v\n", err)
+			cleanup.Exit(1)
 		}
 		pterm.Success.Println("The output has been copied to your clipboard.")
 	}
 }
 
-func printYAMLDoc(v interface{}) string {
-	var buf bytes.Buffer
-	encoder := yaml.NewEncoder(&buf)
-	encoder.SetIndent(2)
-	if err := encoder.Encode(v); err != nil {
-		log.Fatalf("Failed to marshal response to YAML: %v", err)
-	}
-	return buf.String()
-}
-
 func getMinimalFields(serviceName, resourceName string, refClient *grpcreflect.Client) []string {
 	// Default minimal fields that should always be included if they exist
 	defaultFields := []string{"name", "created_at"}
@@ -1056,13 +998,6 @@ func printTable(data map[string]interface{}, options *FetchOptions, serviceName,
 			options.PageSize = len(results)
 		}
 
-		// Initialize keyboard
-		if err := keyboard.Open(); err != nil {
-			fmt.Println("Failed to initialize keyboard:", err)
-			return ""
-		}
-		defer keyboard.Close()
-
 		currentPage := 0
 		searchTerm := ""
 		filteredResults := results
@@ -1098,6 +1033,31 @@ func printTable(data map[string]interface{}, options *FetchOptions, serviceName,
 			}
 		}
 
+		// Without a terminal, e.g. in containers or pipes, or a TUI, all results are printed at once
+		if !configs.Interactive() || !features.Enabled(features.TUI) {
+			tableData := pterm.TableData{headerSlice}
+			for _, result := range results {
+				if row, ok := result.(map[string]interface{}); ok {
+					rowData := make([]string, len(headerSlice))
+					for i, key := range headerSlice {
+						rowData[i] = formatColumnValue(options, key, row[key])
+					}
+					tableData = append(tableData, rowData)
+				}
+			}
+			pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+			// The footer is kept out of the rows read by scripts
+			fmt.Fprintln(os.Stderr, countFooter(len(results), data))
+			return ""
+		}
+
+		// Initialize keyboard
+		if err := ui.OpenKeys(); err != nil {
+			fmt.Println(err)
+			return ""
+		}
+		defer ui.CloseKeys()
+
 		for {
 			if searchTerm != "" {
 				filteredResults = filterResults(results, searchTerm)
@@ -1130,7 +1090,7 @@ func printTable(data map[string]interface{}, options *FetchOptions, serviceName,
 				if row, ok := result.(map[string]interface{}); ok {
 					rowData := make([]string, len(headerSlice))
 					for i, key := range headerSlice {
-						rowData[i] = FormatTableValue(row[key])
+						rowData[i] = formatColumnValue(options, key, row[key])
 					}
 					tableData = append(tableData, rowData)
 				}
@@ -1140,12 +1100,13 @@ func printTable(data map[string]interface{}, options *FetchOptions, serviceName,
 			pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
 
 			fmt.Printf("\nPage %d of %d (Total items: %d)\n", currentPage+1, totalPages, totalItems)
+			fmt.Println(countFooter(len(results), data))
 			fmt.Println("Navigation: [h]previous page, [l]next page, [/]search, [c]lear search, [q]uit")
 
 			// Handle keyboard input
-			char, _, err := keyboard.GetKey()
+			char, err := ui.ReadKey()
 			if err != nil {
-				fmt.Println("Error reading keyboard input:", err)
+				fmt.Println(err)
 				return ""
 			}
 
@@ -1161,12 +1122,12 @@ func printTable(data map[string]interface{}, options *FetchOptions, serviceName,
 				currentPage = 0
 			case '/':
 				fmt.Print("\nEnter search term: ")
-				keyboard.Close()
+				ui.CloseKeys()
 				var input string
 				fmt.Scanln(&input)
 				searchTerm = input
 				currentPage = 0
-				keyboard.Open()
+				ui.OpenKeys()
 			}
 		}
 	}
@@ -1183,7 +1144,7 @@ func printTable(data map[string]interface{}, options *FetchOptions, serviceName,
 	}
 
 	for _, header := range headers {
-		value := FormatTableValue(data[header])
+		value := formatColumnValue(options, header, data[header])
 		tableData = append(tableData, []string{header, value})
 	}
 
@@ -1242,69 +1203,78 @@ func FormatTableValue(val interface{}) string {
 	}
 }
 
-func printCSV(data map[string]interface{}) string {
-	writer := csv.NewWriter(os.Stdout)
-	defer writer.Flush()
+// WaitForCondition polls the resource until the condition holds or the timeout expires
+func WaitForCondition(serviceName, verb, resource string, options *FetchOptions, condition string, interval, timeout time.Duration) error {
+	if _, _, _, err := splitCondition(condition); err != nil {
+		return err
+	}
 
-	if results, ok := data["results"].([]interface{}); ok {
-		if len(results) == 0 {
-			return ""
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer cleanup.Intercept()()
+	defer signal.Stop(sigChan)
+
+	deadline := time.Now().Add(timeout)
+	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Waiting for '%s'", condition))
+
+	// Each poll requests the same results as the caller, e.g. with --since, but prints nothing
+	pollOptions := *options
+	pollOptions.OutputFormat = ""
+	pollOptions.CopyToClipboard = false
+	pollOptions.CountOnly = false
+	pollOptions.GroupCount = ""
+	pollOptions.PrintCurl = false
+	pollOptions.PrintGRPCurl = false
+
+	for attempt := 1; ; attempt++ {
+		data, err := FetchService(serviceName, verb, resource, &pollOptions)
+		if errors.Is(err, ErrNoToken) {
+			spinner.Fail(err.Error())
+			return err
 		}
-
-		headers := make([]string, 0)
-		if firstRow, ok := results[0].(map[string]interface{}); ok {
-			for key := range firstRow {
-				headers = append(headers, key)
+		if err != nil {
+			spinner.UpdateText(fmt.Sprintf("Waiting for '%s' (attempt %d failed: %v)", condition, attempt, err))
+		} else if data != nil {
+			ok, err := EvaluateCondition(data, condition)
+			if err != nil {
+				spinner.Fail(err.Error())
+				return err
 			}
-			sort.Strings(headers)
-			writer.Write(headers)
-		}
-
-		for _, result := range results {
-			if row, ok := result.(map[string]interface{}); ok {
-				rowData := make([]string, len(headers))
-				for i, header := range headers {
-					rowData[i] = FormatTableValue(row[header])
-				}
-				writer.Write(rowData)
+			if ok {
+				spinner.Success(fmt.Sprintf("Condition '%s' met after %d attempt(s)", condition, attempt))
+				return nil
 			}
+			spinner.UpdateText(fmt.Sprintf("Waiting for '%s' (attempt %d)", condition, attempt))
 		}
-	} else {
-		headers := []string{"Field", "Value"}
-		writer.Write(headers)
 
-		fields := make([]string, 0)
-		for field := range data {
-			fields = append(fields, field)
+		if time.Now().Add(interval).After(deadline) {
+			spinner.Fail(fmt.Sprintf("Timed out after %s waiting for '%s'", timeout, condition))
+			return fmt.Errorf("timed out waiting for '%s'", condition)
 		}
-		sort.Strings(fields)
 
-		for _, field := range fields {
-			row := []string{field, FormatTableValue(data[field])}
-			writer.Write(row)
+		select {
+		case <-time.After(interval):
+		case <-sigChan:
+			spinner.Warning("Stopped waiting")
+			return fmt.Errorf("interrupted while waiting for '%s'", condition)
 		}
 	}
-
-	return ""
 }
 
-func formatCSVValue(val interface{}) string {
-	switch v := val.(type) {
-	case nil:
-		return ""
-	case string:
-		return v
-	case float64, float32, int, int32, int64, uint, uint32, uint64:
-		return fmt.Sprintf("%v", v)
-	case bool:
-		return fmt.Sprintf("%v", v)
-	case map[string]interface{}, []interface{}:
-		jsonBytes, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Sprintf("%v", v)
+// RedactSecretData replaces every 'data' field in the response so that secret values are never printed
+func RedactSecretData(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if key == "data" {
+				v[key] = "<redacted>"
+				continue
+			}
+			RedactSecretData(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			RedactSecretData(child)
 		}
-		return string(jsonBytes)
-	default:
-		return fmt.Sprintf("%v", v)
 	}
 }
@@ -0,0 +1,219 @@
+package transport
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloudforet-io/cfctl/pkg/configs"
+	"github.com/pterm/pterm"
+)
+
+var (
+	sessionMu     sync.RWMutex
+	sessionConfig *Config
+)
+
+// errAuthenticationRequired is returned when the service rejects the access token
+var errAuthenticationRequired = errors.New("authentication required")
+
+// ReloadSession reads setting.yaml and the token cache into the pinned session.
+// Long-running modes pin the session so that every poll uses the same environment,
+// and call ReloadSession again to pick up endpoint or token changes.
+// The previous session is kept when the setting cannot be read.
+func ReloadSession() (*Config, error) {
+	config, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionMu.Lock()
+	sessionConfig = config
+	sessionMu.Unlock()
+	configs.PinConnectionEnvironment(config.Environment)
+
+	return config, nil
+}
+
+// PinEnvironment pins the session to the named environment instead of the current one, so
+// that a command can call the services of several environments, like 'cfctl compare'
+func PinEnvironment(env string) (*Config, error) {
+	config, err := readEnvironmentConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionMu.Lock()
+	sessionConfig = config
+	sessionMu.Unlock()
+	configs.PinConnectionEnvironment(config.Environment)
+
+	return config, nil
+}
+
+// UnpinSession makes every call read the setting itself again
+func UnpinSession() {
+	sessionMu.Lock()
+	sessionConfig = nil
+	sessionMu.Unlock()
+	configs.PinConnectionEnvironment("")
+}
+
+// pinnedSession returns the pinned session, or nil when every call reads the setting itself
+func pinnedSession() *Config {
+	sessionMu.RLock()
+	defer sessionMu.RUnlock()
+	return sessionConfig
+}
+
+// sessionReloader reloads the pinned session on SIGHUP, and with --reload whenever setting.yaml changes
+type sessionReloader struct {
+	signals  chan os.Signal
+	auto     bool
+	path     string
+	modified time.Time
+
+	// The token file is watched so that a token stored by another process (e.g. 'cfctl login')
+	// is handed off to this session instead of the session failing with Unauthenticated
+	tokenPath       string
+	tokenModified   time.Time
+	waitingForToken bool
+}
+
+// newSessionReloader pins the session and starts listening for SIGHUP
+func newSessionReloader(auto bool) (*sessionReloader, error) {
+	if _, err := ReloadSession(); err != nil {
+		return nil, err
+	}
+
+	r := &sessionReloader{
+		signals: make(chan os.Signal, 1),
+		auto:    auto,
+	}
+	if settingDir, err := configs.SettingDir(); err == nil {
+		r.path = filepath.Join(settingDir, "setting.yaml")
+		r.modified = modTime(r.path)
+	}
+	r.watchToken()
+	signal.Notify(r.signals, syscall.SIGHUP)
+
+	return r, nil
+}
+
+// stop stops listening for SIGHUP and unpins the session
+func (r *sessionReloader) stop() {
+	signal.Stop(r.signals)
+	UnpinSession()
+}
+
+// settingChanged reports whether setting.yaml was modified since the last reload, when --reload is set
+func (r *sessionReloader) settingChanged() bool {
+	if !r.auto || r.path == "" {
+		return false
+	}
+	return !modTime(r.path).Equal(r.modified)
+}
+
+// reload re-reads the session and reports whether the environment or endpoint changed,
+// in which case the caller should take a new baseline instead of reporting differences.
+func (r *sessionReloader) reload(reason string) bool {
+	previous := pinnedSession()
+	if r.path != "" {
+		r.modified = modTime(r.path)
+	}
+
+	config, err := ReloadSession()
+	if err != nil {
+		pterm.Warning.WithWriter(os.Stderr).Printf("Failed to reload setting (%s), keeping the current one: %v\n", reason, err)
+		return false
+	}
+	r.watchToken()
+	r.waitingForToken = false
+
+	current := config.Environments[config.Environment]
+	pterm.Info.WithWriter(os.Stderr).Printf("Reloaded setting (%s): environment '%s', endpoint %s\n", reason, config.Environment, current.Endpoint)
+
+	if previous == nil {
+		return true
+	}
+	return previous.Environment != config.Environment ||
+		previous.Environments[previous.Environment].Endpoint != current.Endpoint
+}
+
+// watchToken starts watching the token file of the pinned environment.
+// User environments cache the token under cache/<env> of the setting directory, others keep it in setting.yaml.
+func (r *sessionReloader) watchToken() {
+	config := pinnedSession()
+	settingDir, err := configs.SettingDir()
+	if config == nil || err != nil {
+		return
+	}
+
+	r.tokenPath = filepath.Join(settingDir, "setting.yaml")
+	if strings.HasSuffix(config.Environment, "-user") {
+		if r.tokenPath, err = configs.AccessTokenPath(config.Environment); err != nil {
+			return
+		}
+	}
+	r.tokenModified = modTime(r.tokenPath)
+}
+
+// pickUpToken hands a token stored by another process over to the pinned session.
+// Only the token is replaced; switching environments still needs SIGHUP or --reload.
+func (r *sessionReloader) pickUpToken() {
+	if r.tokenPath == "" || modTime(r.tokenPath).Equal(r.tokenModified) {
+		return
+	}
+	r.tokenModified = modTime(r.tokenPath)
+
+	pinned := pinnedSession()
+	config, err := readConfig()
+	if pinned == nil || err != nil || config.Environment != pinned.Environment {
+		return
+	}
+
+	env := pinned.Environments[pinned.Environment]
+	newToken := config.Environments[config.Environment].Token
+	if newToken == "" || newToken == env.Token {
+		return
+	}
+	env.Token = newToken
+
+	sessionMu.Lock()
+	sessionConfig = &Config{
+		Environment:  pinned.Environment,
+		Environments: map[string]Environment{pinned.Environment: env},
+	}
+	sessionMu.Unlock()
+
+	if r.waitingForToken {
+		pterm.Success.WithWriter(os.Stderr).Println("Picked up the new access token, resuming.")
+		r.waitingForToken = false
+	} else {
+		pterm.Info.WithWriter(os.Stderr).Println("Picked up a new access token stored by another session.")
+	}
+}
+
+// reportFetchError tells the user once that the session waits for a new token,
+// instead of failing on every poll while the token is expired.
+func (r *sessionReloader) reportFetchError(err error) {
+	if !errors.Is(err, errAuthenticationRequired) || r.waitingForToken {
+		return
+	}
+	r.waitingForToken = true
+	pterm.Warning.WithWriter(os.Stderr).Println("Access token is expired or invalid. Waiting for a new one, run 'cfctl login' in another terminal.")
+}
+
+// modTime returns the modification time of the file, or the zero time when it does not exist
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
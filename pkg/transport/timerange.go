@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// DefaultTimeField is the field --since and --until filter on unless --time-field is given
+const DefaultTimeField = "created_at"
+
+// relativeUnits are the units of relative times like '30m', '12h', '7d' or '2w'
+var relativeUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseTimeExpression parses the value of --since or --until relative to now. It accepts
+// 'now', 'today' and 'yesterday' (the start of the day in local time), relative times
+// such as '90m', '7d' or '2w ago', and dates or timestamps like 2024-01-31 or RFC 3339.
+func ParseTimeExpression(s string, now time.Time) (time.Time, error) {
+	expr := strings.ToLower(strings.TrimSpace(s))
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch expr {
+	case "now":
+		return now, nil
+	case "today":
+		return startOfToday, nil
+	case "yesterday":
+		return startOfToday.AddDate(0, 0, -1), nil
+	}
+
+	relative := strings.TrimSpace(strings.TrimSuffix(expr, "ago"))
+	if len(relative) > 1 {
+		if unit, ok := relativeUnits[relative[len(relative)-1:]]; ok {
+			if n, err := strconv.Atoi(relative[:len(relative)-1]); err == nil && n >= 0 {
+				return now.Add(-time.Duration(n) * unit), nil
+			}
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time '%s': use a relative time like 7d, 12h or 30m, today, yesterday, or a date like 2024-01-31", s)
+}
+
+// applyTimeRange adds query filters on the time field for --since (inclusive) and --until (exclusive)
+func applyTimeRange(msgDesc *desc.MessageDescriptor, params map[string]interface{}, field, since, until string) error {
+	if since == "" && until == "" {
+		return nil
+	}
+
+	queryField := msgDesc.FindFieldByName("query")
+	if queryField == nil || queryField.GetMessageType() == nil {
+		return fmt.Errorf("--since and --until are only supported by requests with a query")
+	}
+	if field == "" {
+		field = DefaultTimeField
+	}
+
+	now := time.Now()
+	var filters []interface{}
+	for _, bound := range []struct{ value, operator string }{{since, "datetime_gte"}, {until, "datetime_lt"}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := ParseTimeExpression(bound.value, now)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, map[string]interface{}{
+			"k": field,
+			"v": t.UTC().Format(time.RFC3339),
+			"o": bound.operator,
+		})
+	}
+
+	query, ok := params["query"].(map[string]interface{})
+	if !ok {
+		query = make(map[string]interface{})
+		params["query"] = query
+	}
+	existing, _ := query["filter"].([]interface{})
+	query["filter"] = append(existing, filters...)
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cloudforet-io/cfctl/internal/cleanup"
+	"github.com/cloudforet-io/cfctl/pkg/format"
+)
+
+// ChangeEvent is a single line emitted by 'list --watch -o jsonl'
+type ChangeEvent struct {
+	Type      string                 `json:"type"`
+	Key       string                 `json:"key"`
+	Timestamp string                 `json:"timestamp"`
+	Item      map[string]interface{} `json:"item"`
+}
+
+const (
+	changeSnapshot = "snapshot"
+	changeCreated  = "created"
+	changeUpdated  = "updated"
+	changeDeleted  = "deleted"
+)
+
+// watchChangeFeed polls the list endpoint and writes created/updated/deleted items as JSON lines
+func watchChangeFeed(serviceName, verb, resource string, options *FetchOptions) error {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer cleanup.Intercept()()
+
+	reloader, err := newSessionReloader(options.Reload)
+	if err != nil {
+		return err
+	}
+	defer reloader.stop()
+
+	encoder := json.NewEncoder(os.Stdout)
+
+	fetchItems := func() (map[string]map[string]interface{}, error) {
+		data, err := FetchService(serviceName, verb, resource, &FetchOptions{
+			Parameters:    options.Parameters,
+			JSONParameter: options.JSONParameter,
+			FileParameter: options.FileParameter,
+			APIVersion:    options.APIVersion,
+			OutputFormat:  "",
+			Since:         options.Since,
+			Until:         options.Until,
+			TimeField:     options.TimeField,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			return nil, fmt.Errorf("no response from %s", serviceName)
+		}
+
+		items := make(map[string]map[string]interface{})
+		if results, ok := data["results"].([]interface{}); ok {
+			for _, result := range results {
+				if m, ok := result.(map[string]interface{}); ok {
+					items[resourceKey(m, resource)] = m
+				}
+			}
+		}
+		return items, nil
+	}
+
+	emit := func(changeType, key string, item map[string]interface{}) {
+		encoder.Encode(ChangeEvent{
+			Type:      changeType,
+			Key:       key,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Item:      item,
+		})
+	}
+
+	previous, err := fetchItems()
+	if err != nil {
+		return err
+	}
+
+	// rebaseline takes the items of a newly reloaded environment as the previous state,
+	// so switching environments is not reported as every item being deleted and created
+	rebaseline := func() {
+		if items, err := fetchItems(); err == nil {
+			previous = items
+		}
+	}
+
+	if !options.ChangesOnly {
+		for _, key := range sortedKeys(previous) {
+			emit(changeSnapshot, key, previous[key])
+		}
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if reloader.settingChanged() && reloader.reload("setting.yaml changed") {
+				rebaseline()
+				continue
+			}
+
+			reloader.pickUpToken()
+			current, err := fetchItems()
+			if err != nil {
+				reloader.reportFetchError(err)
+				continue
+			}
+
+			for _, key := range sortedKeys(current) {
+				before, existed := previous[key]
+				if !existed {
+					emit(changeCreated, key, current[key])
+				} else if !reflect.DeepEqual(before, current[key]) {
+					emit(changeUpdated, key, current[key])
+				}
+			}
+			for _, key := range sortedKeys(previous) {
+				if _, exists := current[key]; !exists {
+					emit(changeDeleted, key, previous[key])
+				}
+			}
+
+			previous = current
+
+		case <-reloader.signals:
+			if reloader.reload("SIGHUP") {
+				rebaseline()
+			}
+
+		case <-sigChan:
+			return nil
+		}
+	}
+}
+
+// resourceKey returns a stable key of a list item, preferring the resource ID field
+// (e.g. 'project_id' for Project) so that updated items are not reported as new ones.
+func resourceKey(item map[string]interface{}, resource string) string {
+	candidates := []string{ResourceIDField(resource), "job_task_id", "id"}
+	for _, field := range candidates {
+		if id, ok := item[field]; ok && id != nil && id != "" {
+			return fmt.Sprintf("%v", id)
+		}
+	}
+
+	return format.GenerateIdentifier(item)
+}
+
+// toSnakeCase converts names like 'CloudService' to 'cloud_service'
+func toSnakeCase(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func sortedKeys(items map[string]map[string]interface{}) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}